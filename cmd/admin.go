@@ -0,0 +1,120 @@
+package main
+
+import (
+	"net/http"
+
+	handlers "payment-system/internal/api"
+	service "payment-system/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// registerAdminRoutes регистрирует административные маршруты с проверкой токена ADMIN_TOKEN
+// на основном маршрутизаторе или на маршрутизаторе отдельного административного порта (ADMIN_PORT).
+// Маршруты регистрируются полными путями, а не через Subrouter: иначе gorilla/mux теряет
+// несовпадение метода и вместо 405 отвечает 404.
+//
+// Параметры:
+//   - router: Маршрутизатор, на котором регистрируются маршруты.
+//   - token: Токен администратора.
+//   - svc: Сервис.
+//   - maintenance: Режим обслуживания; изменяющие маршруты отклоняются в режиме только для чтения.
+//   - sendLimiter: Ограничитель одновременных переводов.
+//   - reloader: Перечитывание CONFIG_FILE; nil, если файл не задан.
+func registerAdminRoutes(router *mux.Router, token string, svc *service.Service, maintenance *handlers.MaintenanceMode,
+	sendLimiter *handlers.ConcurrencyLimiter, reloader *configReloader) {
+	admin := handlers.AdminAuthMiddleware(token)
+
+	// - GET/PUT /api/admin/read-only: Просмотр и переключение режима только для чтения
+	router.Handle("/api/admin/read-only", admin(handlers.ReadOnlyHandler(maintenance))).Methods("GET", "PUT")
+
+	// - GET/PUT /api/admin/limits/send: Просмотр и изменение ограничения одновременных переводов
+	router.Handle("/api/admin/limits/send", admin(handlers.ConcurrencyLimitHandler(sendLimiter))).Methods("GET", "PUT")
+
+	// - POST /api/admin/config/reload: Перечитывание CONFIG_FILE, как по SIGHUP
+	if reloader != nil {
+		reload := func() (handlers.ConfigReload, error) { return reloader.reload("api") }
+		router.Handle("/api/admin/config/reload", admin(handlers.ConfigReloadHandler(reload))).Methods("POST")
+	}
+
+	// - POST /api/admin/import: Импорт исторических транзакций без изменения балансов
+	router.Handle("/api/admin/import", admin(maintenance.Middleware(handlers.ImportHandler(svc)))).Methods("POST")
+
+	// - POST /api/admin/wallets: Создание кошелька с меткой и тегами
+	router.Handle("/api/admin/wallets", admin(maintenance.Middleware(handlers.CreateWalletHandler(svc)))).Methods("POST")
+
+	// - POST /api/admin/wallets/bulk: Массовое создание кошельков со случайными адресами
+	router.Handle("/api/admin/wallets/bulk", admin(maintenance.Middleware(handlers.BulkWalletsHandler(svc)))).Methods("POST")
+
+	// - GET /api/wallets/top?limit=N: Кошельки с наибольшим балансом; только для администратора,
+	//   так как раскрывает адреса и балансы чужих кошельков
+	router.Handle("/api/wallets/top", admin(handlers.TopWalletsHandler(svc))).Methods("GET")
+
+	// - PATCH /api/wallet/{address}, /api/v1/wallet/{address}: Изменение метки, тегов и адреса
+	//   для уведомлений; только для администратора, так как по метке выбирается получатель
+	//   перевода "@label", а на адрес для уведомлений приходят сведения о поступлениях
+	// - DELETE /api/wallet/{address}, /api/v1/wallet/{address}: Архивация кошелька с нулевым
+	//   балансом; только для администратора, как и восстановление
+	for _, prefix := range []string{"/api", "/api/v1"} {
+		router.Handle(prefix+"/wallet/{address}", admin(maintenance.Middleware(handlers.UpdateWalletHandler(svc)))).Methods("PATCH")
+		router.Handle(prefix+"/wallet/{address}", admin(maintenance.Middleware(handlers.ArchiveWalletHandler(svc)))).Methods("DELETE")
+	}
+
+	// - POST /api/admin/wallets/{address}/restore: Восстановление архивного кошелька
+	router.Handle("/api/admin/wallets/{address}/restore", admin(maintenance.Middleware(handlers.RestoreWalletHandler(svc)))).Methods("POST")
+
+	// - POST /api/wallet/{address}/rotate: Замена адреса кошелька с переносом баланса;
+	//   только для администратора, так как ответ содержит закрытый ключ нового кошелька
+	router.Handle("/api/wallet/{address}/rotate", admin(maintenance.Middleware(handlers.RotateWalletHandler(svc)))).Methods("POST")
+
+	// - POST /api/admin/wallets/{address}/anonymize: Удаление персональных данных кошелька
+	//   (dry_run=true - только подсчет записей)
+	// - GET /api/admin/audit-log: Журнал действий администратора
+	router.Handle("/api/admin/wallets/{address}/anonymize", admin(maintenance.Middleware(handlers.AnonymizeWalletHandler(svc)))).Methods("POST")
+	router.Handle("/api/admin/audit-log", admin(handlers.AuditLogHandler(svc))).Methods("GET")
+
+	// - GET /api/admin/reconcile: Проверка инвариантов хранилища и сверка балансов с историей переводов
+	router.Handle("/api/admin/reconcile", admin(handlers.ReconcileHandler(svc))).Methods("GET")
+
+	// - POST /api/admin/system-transfers: Операция с системным счетом (выпуск, изъятие, комиссия,
+	//   удержание и возврат удержания)
+	router.Handle("/api/admin/system-transfers", admin(maintenance.Middleware(handlers.SystemTransferHandler(svc)))).Methods("POST")
+
+	// - GET /api/admin/risk-events: Последние срабатывания правил проверки переводов
+	router.Handle("/api/admin/risk-events", admin(handlers.RiskEventsHandler(svc))).Methods("GET")
+
+	// - GET /api/admin/approvals: Переводы, ожидающие подтверждения, и решения по ним
+	// - POST /api/admin/approvals/{id}/approve, /reject: Подтверждение (выполняет перевод) или отклонение
+	router.Handle("/api/admin/approvals", admin(handlers.ApprovalsHandler(svc))).Methods("GET")
+	router.Handle("/api/admin/approvals/{id}/approve", admin(maintenance.Middleware(handlers.ApproveHandler(svc)))).Methods("POST")
+	router.Handle("/api/admin/approvals/{id}/reject", admin(maintenance.Middleware(handlers.RejectHandler(svc)))).Methods("POST")
+
+	// - GET /api/admin/notifications: Уведомления о поступлении средств и результаты их доставки
+	router.Handle("/api/admin/notifications", admin(handlers.NotificationsHandler(svc))).Methods("GET")
+}
+
+// newAdminRouter создает маршрутизатор отдельного административного порта (ADMIN_PORT).
+// Кроме административных маршрутов (их регистрирует registerAdminRoutes), на нем доступны
+// /healthz и метрики /metrics, которые тогда не публикуются на основном порту, а если порт
+// слушает только локальный интерфейс - и отладочные маршруты /debug/pprof/ и /debug/vars.
+//
+// Параметры:
+//   - addr: Адрес административного порта (ADMIN_HOST:ADMIN_PORT).
+//   - info: Сведения о сборке для /healthz.
+//   - metricsHandler: Обработчик /metrics.
+//
+// Возвращает:
+//   - Маршрутизатор с ответами 404 и 405 в формате ошибок API.
+func newAdminRouter(addr string, info handlers.BuildInfo, metricsHandler http.Handler) *mux.Router {
+	router := mux.NewRouter()
+	router.NotFoundHandler = handlers.NotFoundHandler()
+	router.MethodNotAllowedHandler = handlers.MethodNotAllowedHandler(router)
+
+	router.HandleFunc("/healthz", handlers.HealthHandler(info)).Methods("GET")
+	router.Handle("/metrics", metricsHandler).Methods("GET")
+	// pprof не требует токена, поэтому, как и DEBUG_ADDR, публикуется только локально
+	if isLoopbackAddr(addr) {
+		router.PathPrefix("/debug/").Handler(handlers.DebugHandler())
+	}
+	return router
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"payment-system/pkg/address"
+	"payment-system/pkg/client"
+
+	"github.com/shopspring/decimal"
+)
+
+// balance выводит баланс кошелька: balance <адрес>.
+func (c *cli) balance(ctx context.Context, args []string) int {
+	flags := c.newFlagSet("balance")
+	positional, code, ok := parseArgs(flags, args)
+	if !ok {
+		return code
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(c.stderr, "Использование: payment-cli balance <адрес>")
+		return exitUsage
+	}
+	if !c.checkChecksum(positional[0]) {
+		return exitUsage
+	}
+
+	balance, err := c.client.GetBalance(ctx, positional[0])
+	if err != nil {
+		return c.fail(err)
+	}
+	if c.json {
+		c.printJSON(c.stdout, balance)
+		return exitOK
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Баланс:\t%s\n", balance.Total)
+	fmt.Fprintf(w, "Зарезервировано:\t%s\n", balance.Reserved)
+	fmt.Fprintf(w, "Доступно:\t%s\n", balance.Available)
+	if balance.Label != "" {
+		fmt.Fprintf(w, "Метка:\t%s\n", balance.Label)
+	}
+	if len(balance.Tags) > 0 {
+		fmt.Fprintf(w, "Теги:\t%s\n", formatTags(balance.Tags))
+	}
+	w.Flush()
+	return exitOK
+}
+
+// send выполняет перевод: send <от> <кому> <сумма> [--memo] [--yes].
+// Перевод на сумму больше confirmAbove выполняется только после подтверждения
+// или с флагом --yes.
+func (c *cli) send(ctx context.Context, args []string) int {
+	flags := c.newFlagSet("send")
+	memo := flags.String("memo", "", "комментарий к переводу")
+	yes := flags.Bool("yes", false, "не запрашивать подтверждение крупного перевода")
+	positional, code, ok := parseArgs(flags, args)
+	if !ok {
+		return code
+	}
+	if len(positional) != 3 {
+		fmt.Fprintln(c.stderr, "Использование: payment-cli send <от> <кому> <сумма> [--memo текст] [--yes]")
+		return exitUsage
+	}
+	from, to := positional[0], positional[1]
+	if !c.checkChecksum(from) || !c.checkChecksum(to) {
+		return exitUsage
+	}
+	amount, err := decimal.NewFromString(positional[2])
+	if err != nil || !amount.IsPositive() {
+		fmt.Fprintf(c.stderr, "Некорректная сумма %q: ожидается положительное число\n", positional[2])
+		return exitUsage
+	}
+
+	if amount.GreaterThan(c.confirmAbove) && !*yes {
+		if !c.confirm(fmt.Sprintf("Перевести %s с %s на %s?", amount, from, to)) {
+			fmt.Fprintln(c.stderr, "Перевод отменен")
+			return exitAborted
+		}
+	}
+
+	var opts []client.SendOption
+	if *memo != "" {
+		opts = append(opts, client.WithMemo(*memo))
+	}
+	transfer, err := c.client.SendMoney(ctx, from, to, amount, opts...)
+	if err != nil {
+		return c.fail(err)
+	}
+	if c.json {
+		c.printJSON(c.stdout, transfer)
+		return exitOK
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	if transfer.ApprovalID != 0 {
+		fmt.Fprintf(w, "Перевод ожидает подтверждения администратора\n")
+		fmt.Fprintf(w, "Номер:\t%d\n", transfer.ApprovalID)
+		fmt.Fprintf(w, "Статус:\t%s\n", transfer.StatusURL)
+		w.Flush()
+		return exitOK
+	}
+	fmt.Fprintf(w, "Транзакция:\t%d\n", transfer.TransactionID)
+	fmt.Fprintf(w, "От:\t%s\n", formatParty(transfer.From))
+	fmt.Fprintf(w, "Кому:\t%s\n", formatParty(transfer.To))
+	fmt.Fprintf(w, "Сумма:\t%s\n", amount)
+	fmt.Fprintf(w, "Баланс отправителя:\t%s\n", transfer.SenderBalance)
+	if transfer.ReceiverBalance != nil {
+		fmt.Fprintf(w, "Баланс получателя:\t%s\n", transfer.ReceiverBalance)
+	}
+	fmt.Fprintf(w, "Время:\t%s\n", formatTime(transfer.CreatedAt))
+	w.Flush()
+	return exitOK
+}
+
+// transactions выводит последние транзакции: transactions [--count N].
+func (c *cli) transactions(ctx context.Context, args []string) int {
+	flags := c.newFlagSet("transactions")
+	count := flags.Int("count", 0, "количество транзакций (по умолчанию - как у сервиса)")
+	positional, code, ok := parseArgs(flags, args)
+	if !ok {
+		return code
+	}
+	if len(positional) > 0 || *count < 0 {
+		fmt.Fprintln(c.stderr, "Использование: payment-cli transactions [--count N]")
+		return exitUsage
+	}
+
+	transactions, err := c.client.ListTransactions(ctx, client.ListOptions{Count: *count})
+	if err != nil {
+		return c.fail(err)
+	}
+	if c.json {
+		c.printJSON(c.stdout, transactions)
+		return exitOK
+	}
+	if len(transactions) == 0 {
+		fmt.Fprintln(c.stdout, "Транзакций нет")
+		return exitOK
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tВРЕМЯ\tОТ\tКОМУ\tСУММА\tКОММЕНТАРИЙ")
+	for _, tx := range transactions {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+			tx.ID, formatTime(tx.CreatedAt), shortAddress(tx.From), shortAddress(tx.To), tx.Amount, tx.Memo)
+	}
+	w.Flush()
+	return exitOK
+}
+
+// wallet выполняет операции с кошельками: wallet create [--balance] [--label].
+func (c *cli) wallet(ctx context.Context, args []string) int {
+	if len(args) == 0 || args[0] != "create" {
+		fmt.Fprintln(c.stderr, "Использование: payment-cli wallet create [--balance сумма] [--label метка]")
+		return exitUsage
+	}
+
+	flags := c.newFlagSet("wallet create")
+	var balance decimal.Decimal
+	flags.TextVar(&balance, "balance", decimal.Zero, "начальный баланс")
+	label := flags.String("label", "", "метка кошелька")
+	positional, code, ok := parseArgs(flags, args[1:])
+	if !ok {
+		return code
+	}
+	if len(positional) > 0 || balance.IsNegative() {
+		fmt.Fprintln(c.stderr, "Использование: payment-cli wallet create [--balance сумма] [--label метка]")
+		return exitUsage
+	}
+
+	wallet, err := c.client.CreateWallet(ctx, balance, *label, nil)
+	if err != nil {
+		return c.fail(err)
+	}
+	if c.json {
+		c.printJSON(c.stdout, wallet)
+		return exitOK
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Адрес:\t%s\n", wallet.Address)
+	fmt.Fprintf(w, "Баланс:\t%s\n", wallet.Balance)
+	if wallet.Label != "" {
+		fmt.Fprintf(w, "Метка:\t%s\n", wallet.Label)
+	}
+	fmt.Fprintf(w, "Открытый ключ:\t%s\n", wallet.PublicKey)
+	fmt.Fprintf(w, "Закрытый ключ:\t%s\n", wallet.PrivateKey)
+	w.Flush()
+	fmt.Fprintln(c.stderr, "Сохраните закрытый ключ: сервис его не хранит и больше не покажет")
+	return exitOK
+}
+
+// checkChecksum проверяет контрольную сумму адреса до запроса к сервису, чтобы опечатка
+// в адресе с контрольной суммой не ушла в перевод. Метки ("@ops-float") и адреса в одном
+// регистре не проверяются; остальные ошибки формата сообщит сервис.
+func (c *cli) checkChecksum(party string) bool {
+	if strings.HasPrefix(party, "@") || !errors.Is(address.Validate(party), address.ErrChecksum) {
+		return true
+	}
+	fmt.Fprintf(c.stderr, "Контрольная сумма адреса %s не совпадает: вероятна опечатка\n", party)
+	return false
+}
+
+// formatParty выводит участника перевода: адрес и метку, если она указана.
+func formatParty(party client.Party) string {
+	if party.Label == "" {
+		return party.Address
+	}
+	return fmt.Sprintf("%s (%s)", party.Address, party.Label)
+}
+
+// shortAddress сокращает адрес для таблицы: первые и последние 8 символов.
+func shortAddress(address string) string {
+	if len(address) <= 19 {
+		return address
+	}
+	return address[:8] + "..." + address[len(address)-8:]
+}
+
+// formatTags выводит теги в виде "ключ=значение" через запятую, по возрастанию ключей.
+func formatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for key, value := range tags {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
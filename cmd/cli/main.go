@@ -0,0 +1,229 @@
+// Команда payment-cli - клиент платежной системы для терминала: баланс, перевод, последние
+// транзакции и создание кошелька без ручных запросов curl. Построена на пакете pkg/client.
+//
+// Пример использования:
+//
+//	export PAYMENT_API_URL=http://localhost:8080
+//	payment-cli balance 0f3a...
+//	payment-cli send @ops-float 0f3a... 10.50 --memo "invoice 42"
+//	payment-cli --json transactions --count 5
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"payment-system/pkg/client"
+
+	"github.com/shopspring/decimal"
+)
+
+// Коды возврата команд.
+const (
+	exitOK      = 0 // Команда выполнена
+	exitError   = 1 // Ошибка API или сети
+	exitUsage   = 2 // Неизвестная команда или некорректные аргументы
+	exitAborted = 3 // Перевод не подтвержден
+)
+
+// usage - описание команд для вывода при неизвестной команде.
+const usage = `Использование: payment-cli [флаги] команда [аргументы]
+
+Команды:
+  balance <адрес>                       баланс кошелька
+  send <от> <кому> <сумма> [--memo] [--yes]
+                                        перевод; крупный перевод требует подтверждения
+  transactions [--count N]              последние транзакции
+  wallet create [--balance] [--label]   создание кошелька (нужен токен администратора)
+
+Флаги:
+  --url           адрес сервиса (PAYMENT_API_URL, по умолчанию http://localhost:8080)
+  --api-key       токен в заголовке Authorization (PAYMENT_API_KEY)
+  --json          вывод в JSON вместо таблиц
+  --confirm-above сумма перевода, выше которой запрашивается подтверждение
+                  (PAYMENT_CONFIRM_ABOVE, по умолчанию 1000)
+  --timeout       ограничение времени запроса (по умолчанию 10s)
+`
+
+// cli - общие настройки команд.
+type cli struct {
+	client       *client.Client
+	json         bool            // Вывод в JSON
+	confirmAbove decimal.Decimal // Порог суммы, выше которого перевод подтверждается
+	stdin        io.Reader       // Источник ответа на запрос подтверждения
+	stdout       io.Writer
+	stderr       io.Writer
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run разбирает общие флаги и выполняет команду.
+//
+// Параметры:
+//   - args: Аргументы командной строки без имени программы.
+//   - stdin: Источник ответа на запрос подтверждения.
+//   - stdout, stderr: Вывод результата и ошибок.
+//
+// Возвращает:
+//   - Код возврата программы (exitOK, exitError, exitUsage, exitAborted).
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("payment-cli", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { fmt.Fprint(stderr, usage) }
+	baseURL := flags.String("url", getEnv("PAYMENT_API_URL", "http://localhost:8080"), "адрес сервиса")
+	apiKey := flags.String("api-key", os.Getenv("PAYMENT_API_KEY"), "токен в заголовке Authorization")
+	jsonOutput := flags.Bool("json", false, "вывод в JSON")
+	timeout := flags.Duration("timeout", client.DefaultTimeout, "ограничение времени запроса")
+	confirmAbove := decimal.NewFromInt(1000)
+	if value := os.Getenv("PAYMENT_CONFIRM_ABOVE"); value != "" {
+		parsed, err := decimal.NewFromString(value)
+		if err != nil {
+			fmt.Fprintf(stderr, "Некорректное значение PAYMENT_CONFIRM_ABOVE=%q\n", value)
+			return exitUsage
+		}
+		confirmAbove = parsed
+	}
+	flags.TextVar(&confirmAbove, "confirm-above", confirmAbove, "порог подтверждения перевода")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+
+	c, err := client.New(*baseURL, client.WithAPIKey(*apiKey), client.WithTimeout(*timeout))
+	if err != nil {
+		fmt.Fprintf(stderr, "Некорректный адрес сервиса: %v\n", err)
+		return exitUsage
+	}
+	app := &cli{
+		client:       c,
+		json:         *jsonOutput,
+		confirmAbove: confirmAbove,
+		stdin:        stdin,
+		stdout:       stdout,
+		stderr:       stderr,
+	}
+
+	command, args := flags.Arg(0), flags.Args()[1:]
+	var runCommand func(context.Context, []string) int
+	switch command {
+	case "balance":
+		runCommand = app.balance
+	case "send":
+		runCommand = app.send
+	case "transactions":
+		runCommand = app.transactions
+	case "wallet":
+		runCommand = app.wallet
+	case "help":
+		fmt.Fprint(stderr, usage)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "Неизвестная команда %q\n\n%s", command, usage)
+		return exitUsage
+	}
+	return runCommand(context.Background(), args)
+}
+
+// getEnv возвращает значение переменной окружения или значение по умолчанию.
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// newFlagSet создает набор флагов команды, который возвращает ошибку разбора, а не завершает программу.
+// Флаг --json принимается и после имени команды.
+func (c *cli) newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	flags.BoolVar(&c.json, "json", c.json, "вывод в JSON")
+	return flags
+}
+
+// parseArgs разбирает флаги команды, стоящие до, между или после позиционных аргументов
+// ("send A B 10 --memo x"), и возвращает позиционные аргументы.
+//
+// Возвращает:
+//   - Позиционные аргументы.
+//   - Код возврата и false, если разбор не удался или запрошена справка.
+func parseArgs(flags *flag.FlagSet, args []string) ([]string, int, bool) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				return nil, exitOK, false
+			}
+			return nil, exitUsage, false
+		}
+		if flags.NArg() == 0 {
+			return positional, exitOK, true
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+// fail выводит ошибку команды и возвращает код возврата exitError.
+// Для ошибок сервиса выводятся код ошибки и нарушения схемы запроса.
+func (c *cli) fail(err error) int {
+	var apiErr *client.Error
+	if c.json && errors.As(err, &apiErr) {
+		// Тот же формат, которым отвечает сервис, и код ответа HTTP
+		var out struct {
+			Error struct {
+				Status     int                `json:"status"`
+				Code       string             `json:"code,omitempty"`
+				Message    string             `json:"message"`
+				Violations []client.Violation `json:"violations,omitempty"`
+			} `json:"error"`
+		}
+		out.Error.Status, out.Error.Code = apiErr.StatusCode, apiErr.Code
+		out.Error.Message, out.Error.Violations = apiErr.Message, apiErr.Violations
+		c.printJSON(c.stderr, out)
+		return exitError
+	}
+	fmt.Fprintf(c.stderr, "Ошибка: %v\n", err)
+	if errors.As(err, &apiErr) {
+		for _, violation := range apiErr.Violations {
+			fmt.Fprintf(c.stderr, "  %s: %s\n", violation.Field, violation.Message)
+		}
+	}
+	return exitError
+}
+
+// printJSON выводит значение в JSON с отступами.
+func (c *cli) printJSON(w io.Writer, v interface{}) {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+// confirm запрашивает подтверждение и возвращает true, если ответ - "y" или "yes".
+func (c *cli) confirm(prompt string) bool {
+	fmt.Fprintf(c.stderr, "%s [y/N]: ", prompt)
+	var answer string
+	fmt.Fscanln(c.stdin, &answer)
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// formatTime выводит время в локальном часовом поясе без долей секунды.
+func formatTime(t time.Time) string {
+	return t.Local().Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	api "payment-system/internal/api"
+	db "payment-system/internal/db"
+	models "payment-system/internal/models"
+	service "payment-system/internal/service"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+// testAPI - маршруты API v1, запущенные в httptest.Server поверх хранилища в памяти,
+// и количество полученных ими запросов.
+type testAPI struct {
+	url      string
+	svc      *service.Service
+	requests atomic.Int32
+}
+
+// newTestAPI запускает тестовый сервер и настраивает окружение команд без PAYMENT_*,
+// заданных вне теста.
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	t.Setenv("PAYMENT_API_URL", "")
+	t.Setenv("PAYMENT_API_KEY", "")
+	t.Setenv("PAYMENT_CONFIRM_ABOVE", "")
+
+	a := &testAPI{svc: service.NewService(db.NewMemoryRepository())}
+	router := mux.NewRouter()
+	api.RegisterV1(router, a.svc, api.NewMaintenanceMode(false), nil, api.RoutesConfig{
+		MaxTransactionsCount: 100,
+		BalanceScale:         2,
+		IdempotencyTTL:       api.DefaultIdempotencyTTL,
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.requests.Add(1)
+		router.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	a.url = server.URL
+	return a
+}
+
+// wallet создает кошелек с балансом balance и возвращает его адрес.
+func (a *testAPI) wallet(t *testing.T, balance string) string {
+	t.Helper()
+	wallet, _, err := a.svc.CreateWallet(context.Background(), decimal.RequireFromString(balance), models.WalletMetadata{})
+	if err != nil {
+		t.Fatalf("CreateWallet(%s): %v", balance, err)
+	}
+	return wallet.Address
+}
+
+// run выполняет команду с адресом тестового сервера и ответом stdin на запрос подтверждения.
+func (a *testAPI) run(stdin string, args ...string) (code int, stdout, stderr string) {
+	var out, errOut bytes.Buffer
+	code = run(append([]string{"--url", a.url}, args...), strings.NewReader(stdin), &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+// TestRunExitCodes проверяет коды возврата команд: ошибка API - exitError, некорректные
+// аргументы - exitUsage без запроса к сервису, отказ от подтверждения крупного перевода -
+// exitAborted без перевода.
+func TestRunExitCodes(t *testing.T) {
+	a := newTestAPI(t)
+	from := a.wallet(t, "100")
+	to := a.wallet(t, "0")
+
+	tests := []struct {
+		name         string
+		stdin        string
+		args         []string
+		want         int
+		wantRequests int32
+	}{
+		{"balance", "", []string{"balance", from}, exitOK, 1},
+		{"insufficient funds", "", []string{"send", from, to, "500", "--yes"}, exitError, 1},
+		{"declined confirmation", "n\n", []string{"--confirm-above", "5", "send", from, to, "10"}, exitAborted, 0},
+		{"no answer", "", []string{"--confirm-above", "5", "send", from, to, "10"}, exitAborted, 0},
+		{"confirmed", "y\n", []string{"--confirm-above", "5", "send", from, to, "10"}, exitOK, 1},
+		{"--yes skips confirmation", "", []string{"--confirm-above", "5", "send", from, to, "10", "--yes"}, exitOK, 1},
+		{"at the threshold", "", []string{"--confirm-above", "5", "send", from, to, "5"}, exitOK, 1},
+		{"unknown command", "", []string{"refund", from}, exitUsage, 0},
+		{"no command", "", nil, exitUsage, 0},
+		{"unknown wallet subcommand", "", []string{"wallet", "delete"}, exitUsage, 0},
+		{"amount not a number", "", []string{"send", from, to, "ten"}, exitUsage, 0},
+		{"zero amount", "", []string{"send", from, to, "0"}, exitUsage, 0},
+		{"negative amount", "", []string{"send", from, to, "--", "-5"}, exitUsage, 0},
+		{"missing amount", "", []string{"send", from, to}, exitUsage, 0},
+		{"unknown flag", "", []string{"send", from, to, "1", "--force"}, exitUsage, 0},
+		{"negative count", "", []string{"transactions", "--count", "-1"}, exitUsage, 0},
+		{"bad checksum", "", []string{"balance", strings.ToUpper(from[:1]) + from[1:]}, exitUsage, 0},
+		{"help", "", []string{"help"}, exitOK, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := a.requests.Load()
+			code, _, stderr := a.run(tt.stdin, tt.args...)
+			if code != tt.want {
+				t.Errorf("run(%q) = %d, want %d; stderr: %s", tt.args, code, tt.want, stderr)
+			}
+			if got := a.requests.Load() - before; got != tt.wantRequests {
+				t.Errorf("run(%q) sent %d requests, want %d", tt.args, got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+// TestRunOutput проверяет вывод команд: с --json (до или после имени команды) - JSON,
+// который разбирается без ошибок, в том числе ошибка в stderr, без него - таблица.
+func TestRunOutput(t *testing.T) {
+	a := newTestAPI(t)
+	from := a.wallet(t, "100")
+	to := a.wallet(t, "0")
+
+	t.Run("send json", func(t *testing.T) {
+		code, stdout, stderr := a.run("", "--json", "send", from, to, "10.5", "--memo", "invoice 42")
+		if code != exitOK {
+			t.Fatalf("exit code %d; stderr: %s", code, stderr)
+		}
+		var transfer struct {
+			TransactionID int    `json:"transaction_id"`
+			SenderBalance string `json:"sender_balance"`
+		}
+		if err := json.Unmarshal([]byte(stdout), &transfer); err != nil {
+			t.Fatalf("decode %q: %v", stdout, err)
+		}
+		if transfer.TransactionID == 0 || transfer.SenderBalance != "89.5" {
+			t.Errorf("transfer = %+v, want a transaction and sender balance 89.5", transfer)
+		}
+	})
+
+	t.Run("balance json", func(t *testing.T) {
+		code, stdout, stderr := a.run("", "balance", to, "--json")
+		if code != exitOK {
+			t.Fatalf("exit code %d; stderr: %s", code, stderr)
+		}
+		var balance struct {
+			Total     string `json:"total"`
+			Available string `json:"available"`
+		}
+		if err := json.Unmarshal([]byte(stdout), &balance); err != nil {
+			t.Fatalf("decode %q: %v", stdout, err)
+		}
+		if balance.Total != "10.5" || balance.Available != "10.5" {
+			t.Errorf("balance = %+v, want 10.5", balance)
+		}
+	})
+
+	t.Run("transactions json", func(t *testing.T) {
+		code, stdout, stderr := a.run("", "--json", "transactions", "--count", "5")
+		if code != exitOK {
+			t.Fatalf("exit code %d; stderr: %s", code, stderr)
+		}
+		var transactions []struct {
+			Memo string `json:"memo"`
+		}
+		if err := json.Unmarshal([]byte(stdout), &transactions); err != nil {
+			t.Fatalf("decode %q: %v", stdout, err)
+		}
+		if len(transactions) != 1 || transactions[0].Memo != "invoice 42" {
+			t.Errorf("transactions = %+v, want the one transfer", transactions)
+		}
+	})
+
+	t.Run("error json", func(t *testing.T) {
+		code, stdout, stderr := a.run("", "--json", "send", from, to, "500", "--yes")
+		if code != exitError || stdout != "" {
+			t.Fatalf("exit code %d, stdout %q, want %d and no output", code, stdout, exitError)
+		}
+		var out struct {
+			Error struct {
+				Status int    `json:"status"`
+				Code   string `json:"code"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(stderr), &out); err != nil {
+			t.Fatalf("decode %q: %v", stderr, err)
+		}
+		if out.Error.Status != http.StatusBadRequest || out.Error.Code != "insufficient_funds" {
+			t.Errorf("error = %+v, want 400 insufficient_funds", out.Error)
+		}
+	})
+
+	t.Run("balance table", func(t *testing.T) {
+		code, stdout, stderr := a.run("", "balance", to)
+		if code != exitOK {
+			t.Fatalf("exit code %d; stderr: %s", code, stderr)
+		}
+		for _, want := range []string{"Баланс:", "Доступно:", "10.5"} {
+			if !strings.Contains(stdout, want) {
+				t.Errorf("output %q does not contain %q", stdout, want)
+			}
+		}
+		if json.Valid([]byte(stdout)) {
+			t.Errorf("table output %q is JSON", stdout)
+		}
+	})
+
+	t.Run("transactions table", func(t *testing.T) {
+		code, stdout, stderr := a.run("", "transactions")
+		if code != exitOK {
+			t.Fatalf("exit code %d; stderr: %s", code, stderr)
+		}
+		lines := strings.Split(strings.TrimSpace(stdout), "\n")
+		if len(lines) != 2 || !strings.HasPrefix(lines[0], "ID") || !strings.Contains(lines[1], "invoice 42") {
+			t.Errorf("output %q, want a header and one row", stdout)
+		}
+	})
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	service "payment-system/internal/service"
+
+	"github.com/shopspring/decimal"
+)
+
+// Коды возврата команд.
+const (
+	exitOK    = 0 // Команда выполнена
+	exitError = 1 // Ошибка выполнения (база недоступна, запрос завершился ошибкой)
+	exitUsage = 2 // Неизвестная команда или некорректные флаги
+	exitDrift = 3 // reconcile обнаружил нарушение инвариантов хранилища
+)
+
+// commandUsage - описание команд для вывода при неизвестной команде.
+const commandUsage = `Использование: payment-system [команда] [флаги]
+
+Команды:
+  serve       запуск HTTP-сервера (по умолчанию)
+  migrate     применение схемы базы данных и выход
+  seed        создание кошельков: --count, --balance
+  reconcile   проверка инвариантов хранилища; код 3 при расхождении
+  purge       очистка истории транзакций: --period, --archive
+
+Настройки читаются из переменных окружения (см. README.md).
+`
+
+// run выполняет команду, заданную первым аргументом, и возвращает код возврата.
+// Без аргументов или с флагом вместо команды запускается сервер, как до появления команд.
+//
+// Параметры:
+//   - args: Аргументы командной строки без имени программы.
+//
+// Возвращает:
+//   - Код возврата программы (exitOK, exitError, exitUsage, exitDrift).
+//
+// Пример использования:
+//
+//	os.Exit(run([]string{"seed", "--count", "100"}))
+func run(args []string) int {
+	command := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	var runCommand func(Config, []string) int
+	switch command {
+	case "serve":
+		runCommand = runServe
+	case "migrate":
+		runCommand = runMigrate
+	case "seed":
+		runCommand = runSeed
+	case "reconcile":
+		runCommand = runReconcile
+	case "purge":
+		runCommand = runPurge
+	case "help":
+		fmt.Fprint(os.Stderr, commandUsage)
+		return exitOK
+	default:
+		fmt.Fprintf(os.Stderr, "Неизвестная команда %q\n\n%s", command, commandUsage)
+		return exitUsage
+	}
+	cfg := loadConfig()
+	// Журнал по умолчанию: в него пишут slog всех пакетов и стандартный log
+	slog.SetDefault(cfg.Logger)
+	return runCommand(cfg, args)
+}
+
+// newFlagSet создает набор флагов команды, который возвращает ошибку разбора, а не завершает программу.
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	return flags
+}
+
+// usageError сообщает об ошибке разбора флагов или лишних аргументах команды.
+// Запрос справки (-h) не считается ошибкой.
+func usageError(flags *flag.FlagSet, err error) int {
+	if err == flag.ErrHelp {
+		return exitOK
+	}
+	if err == nil {
+		fmt.Fprintf(os.Stderr, "Лишние аргументы команды %s: %s\n", flags.Name(), strings.Join(flags.Args(), " "))
+	}
+	return exitUsage
+}
+
+// runMigrate применяет схему базы данных и завершает работу, не запуская сервер.
+// Схема (таблицы, индексы, перевод старых столбцов сумм) применяется при создании
+// хранилища и не меняет уже примененные части, поэтому команду можно запускать повторно,
+// например перед развертыванием новой версии.
+//
+// Параметры:
+//   - cfg: Конфигурация из окружения.
+//   - args: Аргументы командной строки после "migrate" (флагов нет).
+//
+// Возвращает:
+//   - Код возврата программы.
+func runMigrate(cfg Config, args []string) int {
+	flags := newFlagSet("migrate")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 {
+		return usageError(flags, err)
+	}
+
+	start := time.Now()
+	newRepository(cfg)
+	log.Printf("Схема базы данных (%s) применена за %s", cfg.DBDriver, time.Since(start).Round(time.Millisecond))
+	return exitOK
+}
+
+// runSeed создает заданное количество кошельков и завершает работу, не запуская сервер.
+//
+// Параметры:
+//   - cfg: Конфигурация из окружения.
+//   - args: Аргументы командной строки после "seed" (--count, --balance).
+//
+// Возвращает:
+//   - Код возврата программы.
+func runSeed(cfg Config, args []string) int {
+	flags := newFlagSet("seed")
+	count := flags.Int("count", 1000, "количество создаваемых кошельков")
+	var balance decimal.Decimal
+	flags.TextVar(&balance, "balance", decimal.NewFromInt(100), "начальный баланс каждого кошелька")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 {
+		return usageError(flags, err)
+	}
+	if *count <= 0 || balance.IsNegative() {
+		fmt.Fprintln(os.Stderr, "Некорректные параметры: --count должен быть больше 0, --balance не может быть отрицательным")
+		return exitUsage
+	}
+
+	repo := newRepository(cfg)
+	start := time.Now()
+	created, err := repo.CreateWallets(context.Background(), *count, balance)
+	if err != nil {
+		log.Printf("Создано %d кошельков из %d: %v", created, *count, err)
+		return exitError
+	}
+	log.Printf("Создано %d кошельков с балансом %s за %s", created, balance, time.Since(start).Round(time.Millisecond))
+	return exitOK
+}
+
+// runReconcile проверяет инварианты хранилища и завершает работу, не запуская сервер.
+// Нарушения выводятся в журнал; код exitDrift позволяет остановить развертывание
+// или поднять тревогу из cron.
+//
+// Параметры:
+//   - cfg: Конфигурация из окружения.
+//   - args: Аргументы командной строки после "reconcile" (флагов нет).
+//
+// Возвращает:
+//   - Код возврата программы: exitDrift при нарушении инвариантов.
+func runReconcile(cfg Config, args []string) int {
+	flags := newFlagSet("reconcile")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 {
+		return usageError(flags, err)
+	}
+
+	repo := newRepository(cfg)
+	start := time.Now()
+	report, err := repo.Reconcile(context.Background())
+	if err != nil {
+		log.Printf("Ошибка при проверке хранилища: %v", err)
+		return exitError
+	}
+	log.Printf("Проверено кошельков: %d, сумма балансов %s, за %s",
+		report.Wallets, report.TotalBalance, time.Since(start).Round(time.Millisecond))
+	log.Printf("Выпущено %s, изъято %s, ожидаемая сумма балансов %s", report.Minted, report.Burned, report.ExpectedSupply)
+
+	if !report.Drift() {
+		log.Println("Нарушений не найдено")
+		return exitOK
+	}
+	if len(report.NegativeBalances) > 0 {
+		log.Printf("Кошельки с отрицательным балансом: %s", strings.Join(report.NegativeBalances, ", "))
+	}
+	if len(report.OverReserved) > 0 {
+		log.Printf("Кошельки, у которых отложенные переводы превышают баланс: %s", strings.Join(report.OverReserved, ", "))
+	}
+	if len(report.ArchivedWithBalance) > 0 {
+		log.Printf("Архивные кошельки с ненулевым балансом: %s", strings.Join(report.ArchivedWithBalance, ", "))
+	}
+	if report.OrphanTransactions > 0 {
+		log.Printf("Транзакций с несуществующими кошельками: %d", report.OrphanTransactions)
+	}
+	if !report.TotalBalance.Equal(report.ExpectedSupply) {
+		log.Printf("Сумма балансов %s не сходится с ожидаемой %s", report.TotalBalance, report.ExpectedSupply)
+	}
+	for _, m := range report.BalanceMismatches {
+		log.Printf("Баланс кошелька %s не сходится с историей переводов: %s, ожидается %s", m.Address, m.Stored, m.Expected)
+	}
+	return exitDrift
+}
+
+// runPurge однократно очищает историю транзакций и завершает работу, не запуская сервер.
+// По умолчанию используются RETENTION_PERIOD и RETENTION_ARCHIVE.
+//
+// Параметры:
+//   - cfg: Конфигурация из окружения.
+//   - args: Аргументы командной строки после "purge" (--period, --archive).
+//
+// Возвращает:
+//   - Код возврата программы.
+func runPurge(cfg Config, args []string) int {
+	policy := cfg.Retention
+	flags := newFlagSet("purge")
+	flags.DurationVar(&policy.Period, "period", policy.Period, "срок хранения транзакций, например 2160h")
+	flags.BoolVar(&policy.Archive, "archive", policy.Archive, "переносить транзакции в transactions_archive (false - удалять)")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 {
+		return usageError(flags, err)
+	}
+	if policy.Period <= 0 {
+		fmt.Fprintln(os.Stderr, "Некорректные параметры: --period (или RETENTION_PERIOD) должен быть больше 0")
+		return exitUsage
+	}
+
+	repo := newRepository(cfg)
+	start := time.Now()
+	purged, err := service.NewService(repo).PurgeTransactions(context.Background(), policy)
+	if err != nil {
+		log.Printf("Очищено %d транзакций: %v", purged, err)
+		return exitError
+	}
+	log.Printf("Очищено транзакций старше %s: %d (в архив: %t) за %s",
+		policy.Period, purged, policy.Archive, time.Since(start).Round(time.Millisecond))
+	return exitOK
+}
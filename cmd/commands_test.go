@@ -0,0 +1,88 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// setupCommandEnv настраивает окружение команд на файл SQLite во временном каталоге
+// и возвращает путь к нему.
+func setupCommandEnv(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "payment-system.db")
+	t.Setenv("DB_DRIVER", "sqlite")
+	t.Setenv("DB_PATH", path)
+	t.Setenv("LOG_LEVEL", "error")
+	return path
+}
+
+// TestRunCommands вызывает команды одну за другой на одной базе: migrate создает схему,
+// seed - кошельки, reconcile на согласованной базе завершается exitOK, а после изменения
+// баланса в обход переводов - exitDrift.
+func TestRunCommands(t *testing.T) {
+	path := setupCommandEnv(t)
+
+	steps := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"migrate"}, exitOK},
+		{[]string{"seed", "--count", "5", "--balance", "10.5"}, exitOK},
+		{[]string{"reconcile"}, exitOK},
+		{[]string{"purge", "--period", "24h"}, exitOK},
+	}
+	for _, step := range steps {
+		if got := run(step.args); got != step.want {
+			t.Fatalf("run(%q) = %d, want %d", step.args, got, step.want)
+		}
+	}
+
+	conn, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	defer conn.Close()
+	// Кроме созданных кошельков, в базе есть системные счета с нулевым балансом
+	var seeded int
+	if err := conn.QueryRow("SELECT COUNT(*) FROM wallets WHERE balance = '10.5'").Scan(&seeded); err != nil {
+		t.Fatalf("count wallets: %v", err)
+	}
+	if seeded != 5 {
+		t.Fatalf("seeded %d wallets with balance 10.5, want 5", seeded)
+	}
+
+	if _, err := conn.Exec("UPDATE wallets SET balance = '11.5' WHERE rowid = (SELECT MIN(rowid) FROM wallets WHERE balance = '10.5')"); err != nil {
+		t.Fatalf("corrupt balance: %v", err)
+	}
+	if got := run([]string{"reconcile"}); got != exitDrift {
+		t.Errorf("run(reconcile) after a balance change without a transfer = %d, want %d", got, exitDrift)
+	}
+}
+
+// TestRunUsage проверяет коды возврата при неизвестной команде и некорректных флагах:
+// такие вызовы не обращаются к базе.
+func TestRunUsage(t *testing.T) {
+	setupCommandEnv(t)
+
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"help"}, exitOK},
+		{[]string{"unknown"}, exitUsage},
+		{[]string{"migrate", "extra"}, exitUsage},
+		{[]string{"seed", "--count", "0"}, exitUsage},
+		{[]string{"seed", "--balance", "-1"}, exitUsage},
+		{[]string{"seed", "--balance", "abc"}, exitUsage},
+		{[]string{"seed", "--unknown"}, exitUsage},
+		{[]string{"seed", "-h"}, exitOK},
+		{[]string{"reconcile", "extra"}, exitUsage},
+		{[]string{"purge", "--period", "0s"}, exitUsage},
+	}
+	for _, tt := range tests {
+		if got := run(tt.args); got != tt.want {
+			t.Errorf("run(%q) = %d, want %d", tt.args, got, tt.want)
+		}
+	}
+}
@@ -1,102 +1,954 @@
-// Package main отвечает за инициализацию основных компонентов программы и запуск основного цикла выполнения.
-// Пакет предоставляет REST API для отправки денег, получения информации о транзакциях и проверки баланса кошельков
-// Ссылка на git: https://github.com/Arkadiy-GO/payment-system/tree/master
-package main
-
-import (
-	"context"
-	"log"
-	"net/http"
-	"os"
-	"os/signal"
-	"syscall"
-	"time"
-
-	handlers "payment-system/internal/api"
-	repository "payment-system/internal/db"
-	service "payment-system/internal/service"
-
-	"github.com/gorilla/mux"
-)
-
-// Config содержит конфигурационные параметры приложения.
-type Config struct {
-	Port string // Порт, на котором будет запущен сервер
-}
-
-// main инициализирует репозиторий, сервис и маршрутизатор для обработки HTTP-запросов.
-// Репозиторий отвечает за взаимодействие с базой данных, сервис — за бизнес-логику.
-// С помощью библиотеки Gorilla Mux создаются маршруты и привязываются соответствующие обработчики.
-// Функция также запускает HTTP-сервер с поддержкой graceful shutdown.
-func main() {
-	// Инициализация конфигурации
-	cfg := Config{
-		Port: getEnv("PORT", "8080"), // Порт по умолчанию: 8080
-	}
-
-	// Инициализация репозитория для работы с базой данных
-	repo := repository.NewPostgresRepository()
-
-	// Проверка подключения к базе данных
-	if err := repo.Ping(context.Background()); err != nil {
-		log.Fatalf("Ошибка подключения к базе данных: %v", err)
-	}
-
-	// Инициализация сервиса, который содержит бизнес-логику приложения
-	svc := service.NewService(repo)
-
-	// Создание маршрутизатора с использованием библиотеки Gorilla Mux
-	router := mux.NewRouter()
-
-	// Регистрация обработчиков для API:
-	// - POST /api/send: Отправляет деньги с одного кошелька на другой
-	router.HandleFunc("/api/send", handlers.SendHandler(svc)).Methods("POST")
-
-	// - GET /api/transactions: Возвращает информацию о последних N транзакциях
-	router.HandleFunc("/api/transactions", handlers.GetLastHandler(svc)).Methods("GET")
-
-	// - GET /api/wallet/{address}/balance: Возвращает баланс указанного кошелька
-	router.HandleFunc("/api/wallet/{address}/balance", handlers.GetBalanceHandler(svc)).Methods("GET")
-
-	// Создание HTTP-сервера
-	server := &http.Server{
-		Addr:    ":" + cfg.Port,
-		Handler: router,
-	}
-
-	// Канал для graceful shutdown
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	// Запуск сервера в отдельной горутине
-	go func() {
-		log.Printf("Запуск сервера на порту %s", cfg.Port)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Ошибка при запуске сервера: %v", err)
-		}
-	}()
-
-	// Ожидание сигнала для graceful shutdown
-	<-done
-	log.Println("Сервер завершает работу...")
-
-	// Создание контекста с таймаутом для graceful shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	// Завершение работы сервера
-	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Ошибка при завершении работы сервера: %v", err)
-	}
-
-	log.Println("Сервер успешно завершил работу")
-}
-
-// getEnv возвращает значение переменной окружения или значение по умолчанию.
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
-	}
-	return value
-}
+// Package main отвечает за инициализацию основных компонентов программы и запуск основного цикла выполнения.
+// Пакет предоставляет REST API для отправки денег, получения информации о транзакциях и проверки баланса кошельков
+// Ссылка на git: https://github.com/Arkadiy-GO/payment-system/tree/master
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	handlers "payment-system/internal/api"
+	repository "payment-system/internal/db"
+	"payment-system/internal/logging"
+	metrics "payment-system/internal/metrics"
+	models "payment-system/internal/models"
+	"payment-system/internal/notify"
+	"payment-system/internal/risk"
+	service "payment-system/internal/service"
+	"payment-system/internal/tracing"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+// Сведения о сборке; задаются при сборке через -ldflags:
+//
+//	go build -ldflags "-X main.version=v1.4.0 -X main.commit=$(git rev-parse HEAD) -X main.buildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildTime = "unknown"
+)
+
+// buildInfo возвращает сведения о сборке и публикует их в метрике payment_build_info.
+func buildInfo(cfg Config) handlers.BuildInfo {
+	info := handlers.BuildInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildTime: buildTime,
+		GoVersion: runtime.Version(),
+		DBDriver:  cfg.DBDriver,
+	}
+	metrics.BuildInfo.WithLabelValues(info.Version, info.Commit, info.GoVersion, info.DBDriver).Set(1)
+	return info
+}
+
+// Режимы запуска APP_ENV.
+const (
+	appEnvDevelopment = "development" // по умолчанию: пустое хранилище заполняется демонстрационными кошельками
+	appEnvProduction  = "production"  // кошельки создаются только явно (TREASURY_ADDRESS, /api/admin/wallets, seed)
+)
+
+// Демонстрационные кошельки, создаваемые при запуске в пустом хранилище (SEED_ON_START).
+const (
+	seedWalletCount   = 10
+	seedWalletBalance = 100
+)
+
+// transactionsCacheEntries - наибольшее количество закэшированных списков транзакций (разных count).
+const transactionsCacheEntries = 64
+
+// Config содержит конфигурационные параметры приложения.
+type Config struct {
+	Port     string // Порт, на котором будет запущен сервер
+	BindAddr string // Интерфейс основного порта (BIND_ADDR), например 127.0.0.1; если пуст, все интерфейсы
+	Addr     string // Адрес основного сервера BIND_ADDR:PORT, проверенный при запуске
+	DBDriver string // Тип хранилища: "postgres", "sqlite" или "memory"
+	DBPath   string // Путь к файлу базы данных SQLite
+
+	BasePath         string // Префикс маршрутов основного порта (API_BASE_PATH), например "/payments/v1"; пусто - без префикса
+	OpsUnderBasePath bool   // Служебные маршруты /healthz, /readyz, /version и /metrics тоже под префиксом
+
+	AppEnv      string // Режим запуска: appEnvDevelopment или appEnvProduction
+	SeedOnStart bool   // Создавать демонстрационные кошельки при запуске, если кошельков нет
+
+	API handlers.RoutesConfig // Настройки обработчиков API (лимит списка транзакций, формат баланса)
+
+	BreakerThreshold int           // Количество подряд идущих сбоев базы, открывающее автомат отключения
+	BreakerCooldown  time.Duration // Время, в течение которого автомат отключения остается открытым
+
+	RedisAddr  string        // Адрес Redis для кэша балансов; если пуст, кэш не используется
+	BalanceTTL time.Duration // Время жизни баланса в кэше
+
+	TransactionsCacheTTL time.Duration // Время жизни списка последних транзакций в кэше; 0 - кэш выключен
+
+	TransferScale int             // Знаков после запятой, с которыми сумма перевода не должна округляться до нуля
+	MaxTransfer   decimal.Decimal // Наибольшая сумма одного перевода; 0 - без ограничения
+	Categories    []string        // Допустимые категории переводов; пустой список - любые
+
+	RequireSignatures bool // Переводы без подписи ключом кошелька отправителя отклоняются
+
+	AddressChecksum bool // Адреса новых кошельков возвращаются с контрольной суммой в регистре букв
+
+	Risk risk.Config // Правила проверки переводов на мошенничество; нулевые пороги выключают правила
+
+	ApprovalThreshold      decimal.Decimal // Переводы больше этой суммы ждут подтверждения администратора; 0 - без подтверждения
+	ApprovalTTL            time.Duration   // Время, через которое нерассмотренный перевод истекает
+	ApprovalExpiryInterval time.Duration   // Период проверки истекших отложенных переводов
+
+	Retention         service.RetentionPolicy // Очистка истории транзакций; нулевой Period выключает ее
+	RetentionInterval time.Duration           // Период фоновой очистки истории
+
+	HealthCheckInterval time.Duration // Период фоновой проверки доступности базы для /readyz; 0 - проверка при каждом запросе
+
+	SMTP           notify.SMTPConfig          // Почтовый сервер уведомлений о поступлении средств; если Host пуст, письма не отправляются
+	Notifications  service.NotificationPolicy // Повторные попытки доставки уведомлений
+	NotifyInterval time.Duration              // Период проверки очереди уведомлений
+
+	TreasuryAddress string          // Адрес кошелька казначейства, создаваемого при запуске; если пуст, не создается
+	TreasuryBalance decimal.Decimal // Начальный баланс кошелька казначейства
+
+	ReadOnly   bool   // Запуск в режиме обслуживания: изменяющие операции отклоняются
+	AdminToken string // Токен для /api/admin; если пуст, административные маршруты не регистрируются
+
+	AdminAddr       string // Адрес отдельного административного порта (ADMIN_HOST:ADMIN_PORT); если пуст, порта нет
+	AdminOnMainPort bool   // Регистрировать административные маршруты на основном порту, если ADMIN_PORT не задан
+
+	SendMaxInFlight  int           // Наибольшее количество одновременных переводов; 0 - без ограничения
+	SendQueueTimeout time.Duration // Сколько перевод сверх ограничения ждет места, прежде чем получить 503
+
+	Security handlers.SecurityConfig // Заголовки безопасности и CORS
+	BodyLog  handlers.BodyLogConfig  // Журналирование тел запросов с ошибочным ответом (для отладки)
+
+	DebugAddr string // Локальный адрес для pprof и /debug/vars; если пуст, отладочные маршруты не запускаются
+
+	TLS TLSConfig // Сертификаты HTTPS; если не заданы, сервер слушает обычный HTTP
+
+	Logger *slog.Logger // Журнал приложения с уровнем LOG_LEVEL в формате LOG_FORMAT
+
+	ConfigFile   string            // Файл настроек KEY=VALUE (CONFIG_FILE); если пуст, перечитывание выключено
+	ConfigValues map[string]string // Значения из ConfigFile, примененные при запуске
+}
+
+// main выполняет команду из аргументов командной строки (см. run) и завершает программу
+// с ее кодом возврата.
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// loadConfig читает конфигурацию из переменных окружения (значения CONFIG_FILE важнее) и проверяет ее.
+// Общая для всех команд; завершает программу, если значение задано некорректно.
+func loadConfig() Config {
+	configFile, configValues := applyConfigFile()
+	cfg := Config{
+		Port:     getEnv("PORT", "8080"), // Порт по умолчанию: 8080
+		BindAddr: os.Getenv("BIND_ADDR"),
+		// Завершающий "/" не нужен: "/payments/v1/" и "/payments/v1" - один префикс, "/" - без префикса
+		BasePath:         strings.TrimSuffix(os.Getenv("API_BASE_PATH"), "/"),
+		OpsUnderBasePath: getEnv("OPS_UNDER_BASE_PATH", "false") == "true",
+		// REPO оставлен для совместимости с ранними конфигурациями
+		DBDriver: getEnv("DB_DRIVER", getEnv("REPO", "postgres")),
+		DBPath:   getEnv("DB_PATH", "payment-system.db"),
+
+		AppEnv: getEnv("APP_ENV", appEnvDevelopment),
+
+		API: handlers.RoutesConfig{
+			MaxTransactionsCount: getEnvInt("MAX_TRANSACTIONS_COUNT", 100),
+			BalanceScale:         getEnvInt("BALANCE_SCALE", 2),
+			DisplayCurrency:      os.Getenv("DISPLAY_CURRENCY"),
+			IdempotencyTTL:       getEnvDuration("IDEMPOTENCY_TTL", handlers.DefaultIdempotencyTTL),
+			AdminToken:           os.Getenv("ADMIN_TOKEN"),
+			GraphQLMaxDepth:      getEnvInt("GRAPHQL_MAX_DEPTH", handlers.DefaultGraphQLMaxDepth),
+			GraphQLMaxComplexity: getEnvInt("GRAPHQL_MAX_COMPLEXITY", handlers.DefaultGraphQLMaxComplexity),
+		},
+
+		BreakerThreshold: getEnvInt("DB_BREAKER_THRESHOLD", 5),
+		BreakerCooldown:  getEnvDuration("DB_BREAKER_COOLDOWN", 10*time.Second),
+
+		RedisAddr:  os.Getenv("REDIS_ADDR"),
+		BalanceTTL: getEnvDuration("REDIS_BALANCE_TTL", 2*time.Second),
+
+		TransactionsCacheTTL: getEnvDuration("TRANSACTIONS_CACHE_TTL", time.Second),
+
+		TransferScale: getEnvInt("TRANSFER_SCALE", 2),
+		MaxTransfer:   getEnvDecimal("MAX_TRANSFER", decimal.Zero),
+		Categories:    splitList(os.Getenv("TRANSACTION_CATEGORIES")),
+
+		RequireSignatures: getEnv("REQUIRE_SIGNATURES", "false") == "true",
+
+		AddressChecksum: getEnv("ADDRESS_CHECKSUM", "false") == "true",
+
+		Risk: risk.Config{
+			VelocityMaxTransfers: getEnvInt("RISK_VELOCITY_MAX_TRANSFERS", 0),
+			VelocityWindow:       getEnvDuration("RISK_VELOCITY_WINDOW", 10*time.Minute),
+			VelocityAction:       getEnv("RISK_VELOCITY_ACTION", risk.ActionBlock),
+			AmountMultiplier:     getEnvFloat("RISK_AMOUNT_MULTIPLIER", 0),
+			AmountAction:         getEnv("RISK_AMOUNT_ACTION", risk.ActionFlag),
+		},
+
+		ApprovalThreshold:      getEnvDecimal("APPROVAL_THRESHOLD", decimal.Zero),
+		ApprovalTTL:            getEnvDuration("APPROVAL_TTL", 24*time.Hour),
+		ApprovalExpiryInterval: getEnvDuration("APPROVAL_EXPIRY_INTERVAL", time.Minute),
+
+		Retention: service.RetentionPolicy{
+			Period:     getEnvDuration("RETENTION_PERIOD", 0),
+			Archive:    getEnv("RETENTION_ARCHIVE", "true") == "true",
+			BatchSize:  getEnvInt("RETENTION_BATCH_SIZE", 1000),
+			BatchPause: getEnvDuration("RETENTION_BATCH_PAUSE", 100*time.Millisecond),
+		},
+		RetentionInterval: getEnvDuration("RETENTION_INTERVAL", time.Hour),
+
+		HealthCheckInterval: getEnvDuration("DB_HEALTH_CHECK_INTERVAL", 10*time.Second),
+
+		SMTP: notify.SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     getEnvInt("SMTP_PORT", 587),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+		},
+		Notifications: service.NotificationPolicy{
+			MaxAttempts: getEnvInt("NOTIFY_MAX_ATTEMPTS", 5),
+			RetryDelay:  getEnvDuration("NOTIFY_RETRY_DELAY", time.Minute),
+			Timeout:     getEnvDuration("NOTIFY_TIMEOUT", 10*time.Second),
+		},
+		NotifyInterval: getEnvDuration("NOTIFY_INTERVAL", 5*time.Second),
+
+		TreasuryAddress: os.Getenv("TREASURY_ADDRESS"),
+		TreasuryBalance: getEnvDecimal("TREASURY_BALANCE", decimal.Zero),
+
+		ReadOnly:   getEnv("READ_ONLY", "false") == "true",
+		AdminToken: os.Getenv("ADMIN_TOKEN"),
+
+		AdminOnMainPort: getEnv("ADMIN_ON_MAIN_PORT", "true") == "true",
+
+		SendMaxInFlight:  getEnvInt("SEND_MAX_IN_FLIGHT", 0),
+		SendQueueTimeout: getEnvDuration("SEND_QUEUE_TIMEOUT", defaultSendQueueTimeout),
+
+		Security: handlers.SecurityConfig{
+			AllowedOrigins:      splitList(os.Getenv("CORS_ALLOWED_ORIGINS")),
+			HSTSMaxAge:          getEnvInt("HSTS_MAX_AGE", 0),
+			TrustForwardedProto: getEnv("TRUST_FORWARDED_PROTO", "false") == "true",
+		},
+		BodyLog: handlers.BodyLogConfig{
+			Enabled:      getEnv("DEBUG_LOG_BODIES", "false") == "true",
+			MaxBytes:     getEnvInt("DEBUG_LOG_BODY_LIMIT", 4096),
+			RedactFields: splitList(getEnv("DEBUG_REDACT_FIELDS", "memo")),
+		},
+		DebugAddr: os.Getenv("DEBUG_ADDR"),
+		TLS: TLSConfig{
+			CertFile:     os.Getenv("TLS_CERT_FILE"),
+			KeyFile:      os.Getenv("TLS_KEY_FILE"),
+			ClientCAFile: os.Getenv("TLS_CLIENT_CA_FILE"),
+		},
+	}
+
+	if cfg.AppEnv != appEnvDevelopment && cfg.AppEnv != appEnvProduction {
+		log.Fatalf("Некорректное значение APP_ENV=%q: ожидается %s или %s", cfg.AppEnv, appEnvDevelopment, appEnvProduction)
+	}
+	// В разработке кошельки создаются по умолчанию, в эксплуатации - никогда: бесплатные
+	// деньги на кошельках со случайными адресами недопустимы
+	cfg.SeedOnStart = getEnv("SEED_ON_START", strconv.FormatBool(cfg.AppEnv == appEnvDevelopment)) == "true"
+	if cfg.SeedOnStart && cfg.AppEnv == appEnvProduction {
+		log.Fatalf("Некорректное значение SEED_ON_START=true: в APP_ENV=%s демонстрационные кошельки не создаются", appEnvProduction)
+	}
+
+	logLevel, logFormat := getEnv("LOG_LEVEL", defaultLogLevel), getEnv("LOG_FORMAT", logging.FormatText)
+	logger, err := logging.New(logLevel, logFormat, os.Stderr)
+	if err != nil {
+		log.Fatalf("Некорректные значения LOG_LEVEL=%q, LOG_FORMAT=%q: ожидаются debug, info, warn или error и text или json",
+			logLevel, logFormat)
+	}
+	cfg.Logger = logger
+	cfg.ConfigFile, cfg.ConfigValues = configFile, configValues
+
+	if cfg.API.MaxTransactionsCount <= 0 {
+		log.Fatalf("Некорректное значение MAX_TRANSACTIONS_COUNT=%d: ожидается положительное число", cfg.API.MaxTransactionsCount)
+	}
+	if utf8.RuneCountInString(cfg.API.DisplayCurrency) > 8 || strings.IndexFunc(cfg.API.DisplayCurrency, unicode.IsControl) >= 0 {
+		log.Fatalf("Некорректное значение DISPLAY_CURRENCY=%q: ожидается код или символ валюты не длиннее 8 символов", cfg.API.DisplayCurrency)
+	}
+	if cfg.API.BalanceScale < 0 {
+		log.Fatalf("Некорректное значение BALANCE_SCALE=%d: ожидается неотрицательное число", cfg.API.BalanceScale)
+	}
+	if cfg.API.IdempotencyTTL <= 0 {
+		log.Fatalf("Некорректное значение IDEMPOTENCY_TTL=%s: ожидается положительная длительность", cfg.API.IdempotencyTTL)
+	}
+	if cfg.TransferScale < 0 || cfg.TransferScale > models.AmountScale {
+		log.Fatalf("Некорректное значение TRANSFER_SCALE=%d: ожидается число от 0 до %d", cfg.TransferScale, models.AmountScale)
+	}
+	if cfg.MaxTransfer.IsNegative() || repository.AmountOverflows(cfg.MaxTransfer) {
+		log.Fatalf("Некорректное значение MAX_TRANSFER=%s: ожидается неотрицательное число меньше 10^30", cfg.MaxTransfer)
+	}
+	for _, category := range cfg.Categories {
+		if err := models.ValidateCategory(category); err != nil {
+			log.Fatalf("Некорректное значение TRANSACTION_CATEGORIES=%q: %v", category, err)
+		}
+	}
+	if err := cfg.Risk.Validate(); err != nil {
+		log.Fatalf("Некорректная настройка правил RISK_*: %v", err)
+	}
+	if cfg.ApprovalThreshold.IsNegative() {
+		log.Fatalf("Некорректное значение APPROVAL_THRESHOLD=%s: ожидается неотрицательное число", cfg.ApprovalThreshold)
+	}
+	if cfg.ApprovalTTL <= 0 || cfg.ApprovalExpiryInterval <= 0 {
+		log.Fatalf("Некорректные значения APPROVAL_TTL=%s, APPROVAL_EXPIRY_INTERVAL=%s: ожидаются положительные длительности",
+			cfg.ApprovalTTL, cfg.ApprovalExpiryInterval)
+	}
+	if cfg.Retention.Period < 0 || cfg.Retention.BatchSize <= 0 || cfg.Retention.BatchPause < 0 || cfg.RetentionInterval <= 0 {
+		log.Fatalf("Некорректные значения RETENTION_PERIOD=%s, RETENTION_BATCH_SIZE=%d, RETENTION_BATCH_PAUSE=%s, RETENTION_INTERVAL=%s: "+
+			"ожидаются неотрицательные срок и пауза, положительные размер пачки и период",
+			cfg.Retention.Period, cfg.Retention.BatchSize, cfg.Retention.BatchPause, cfg.RetentionInterval)
+	}
+	if cfg.TreasuryAddress != "" && !handlers.IsValidAddress(cfg.TreasuryAddress) {
+		log.Fatalf("Некорректное значение TREASURY_ADDRESS=%q: ожидается %d шестнадцатеричных символов",
+			cfg.TreasuryAddress, 2*repository.AddressBytes())
+	}
+	// Адрес с контрольной суммой хранится, как и все адреса, в нижнем регистре
+	cfg.TreasuryAddress = strings.ToLower(cfg.TreasuryAddress)
+	if cfg.TreasuryBalance.IsNegative() {
+		log.Fatalf("Некорректное значение TREASURY_BALANCE=%s: ожидается неотрицательное число", cfg.TreasuryBalance)
+	}
+	if cfg.HealthCheckInterval < 0 {
+		log.Fatalf("Некорректное значение DB_HEALTH_CHECK_INTERVAL=%s: ожидается неотрицательная длительность", cfg.HealthCheckInterval)
+	}
+	if cfg.SMTP.Host != "" {
+		if err := cfg.SMTP.Validate(); err != nil {
+			log.Fatalf("Некорректная настройка почтового сервера SMTP_*: %v", err)
+		}
+	}
+	if cfg.Notifications.MaxAttempts <= 0 || cfg.Notifications.RetryDelay <= 0 || cfg.Notifications.Timeout <= 0 || cfg.NotifyInterval <= 0 {
+		log.Fatalf("Некорректные значения NOTIFY_MAX_ATTEMPTS=%d, NOTIFY_RETRY_DELAY=%s, NOTIFY_TIMEOUT=%s, NOTIFY_INTERVAL=%s: ожидаются положительные значения",
+			cfg.Notifications.MaxAttempts, cfg.Notifications.RetryDelay, cfg.Notifications.Timeout, cfg.NotifyInterval)
+	}
+	if cfg.SendMaxInFlight < 0 || cfg.SendQueueTimeout < 0 {
+		log.Fatalf("Некорректные значения SEND_MAX_IN_FLIGHT=%d, SEND_QUEUE_TIMEOUT=%s: ожидаются неотрицательные значения",
+			cfg.SendMaxInFlight, cfg.SendQueueTimeout)
+	}
+	if cfg.BasePath != "" && !isValidBasePath(cfg.BasePath) {
+		log.Fatalf("Некорректное значение API_BASE_PATH=%q: ожидается путь вида /payments/v1 из латинских букв, цифр и символов -._~", cfg.BasePath)
+	}
+	addr, err := listenAddr(cfg.BindAddr, cfg.Port)
+	if err != nil {
+		log.Fatalf("Некорректные значения BIND_ADDR=%q, PORT=%q: %v", cfg.BindAddr, cfg.Port, err)
+	}
+	cfg.Addr = addr
+	if cfg.DebugAddr != "" && !isLoopbackAddr(cfg.DebugAddr) {
+		log.Fatalf("Некорректное значение DEBUG_ADDR=%q: ожидается локальный адрес, например 127.0.0.1:6060", cfg.DebugAddr)
+	}
+	if adminPort := os.Getenv("ADMIN_PORT"); adminPort != "" {
+		if adminPort == cfg.Port {
+			log.Fatalf("Некорректное значение ADMIN_PORT=%s: совпадает с PORT", adminPort)
+		}
+		// По умолчанию только локальный интерфейс; ADMIN_HOST=0.0.0.0 открывает порт для сети
+		cfg.AdminAddr = net.JoinHostPort(getEnv("ADMIN_HOST", "127.0.0.1"), adminPort)
+	}
+	if cfg.TLS.Enabled() && (cfg.TLS.CertFile == "" || cfg.TLS.KeyFile == "") {
+		log.Fatalf("Некорректные значения TLS_CERT_FILE=%q, TLS_KEY_FILE=%q: ожидаются оба файла", cfg.TLS.CertFile, cfg.TLS.KeyFile)
+	}
+	if cfg.TLS.ClientCAFile != "" && !cfg.TLS.Enabled() {
+		log.Fatalf("Некорректное значение TLS_CLIENT_CA_FILE=%q: проверка клиентов требует TLS_CERT_FILE и TLS_KEY_FILE", cfg.TLS.ClientCAFile)
+	}
+	return cfg
+}
+
+// runServe инициализирует репозиторий, сервис и маршрутизатор для обработки HTTP-запросов.
+// Репозиторий отвечает за взаимодействие с базой данных, сервис — за бизнес-логику.
+// С помощью библиотеки Gorilla Mux создаются маршруты и привязываются соответствующие обработчики.
+// Функция также запускает HTTP-сервер с поддержкой graceful shutdown.
+//
+// Параметры:
+//   - cfg: Конфигурация из окружения.
+//   - args: Аргументы командной строки после "serve" (флагов нет).
+//
+// Возвращает:
+//   - Код возврата программы.
+func runServe(cfg Config, args []string) int {
+	flags := newFlagSet("serve")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 {
+		return usageError(flags, err)
+	}
+
+	// Трассировка OpenTelemetry: контекст из заголовка traceparent передается всегда,
+	// а спаны экспортируются, только если задан адрес сборщика OTLP
+	shutdownTracing, err := tracing.Setup(context.Background())
+	if err != nil {
+		log.Fatalf("Ошибка настройки трассировки: %v", err)
+	}
+	if tracing.Enabled() {
+		log.Printf("Спаны трассировки экспортируются по OTLP")
+	}
+
+	// Инициализация репозитория для работы с базой данных
+	repo := newRepository(cfg)
+
+	// Автомат отключения: при серии сбоев базы запросы сразу получают 503,
+	// а не копятся в ожидании ответа
+	repo = repository.NewCircuitBreaker(repo, cfg.BreakerThreshold, cfg.BreakerCooldown)
+
+	// Кэш балансов в Redis: частые запросы баланса не доходят до базы
+	if cfg.RedisAddr != "" {
+		log.Printf("Балансы кэшируются в Redis %s на %s", cfg.RedisAddr, cfg.BalanceTTL)
+		repo = repository.NewBalanceCache(repo, cfg.RedisAddr, cfg.BalanceTTL)
+	}
+
+	// Инициализация сервиса, который содержит бизнес-логику приложения
+	svc := service.NewService(repo)
+
+	// Суммы, которые округляются до нуля с TRANSFER_SCALE знаками, отклоняются:
+	// перевод 0.0000001 ничего не меняет для получателя, но засоряет историю
+	svc.SetTransferScale(int32(cfg.TransferScale))
+
+	// Наибольшая сумма одного перевода: ошибка в сумме (лишние нули) не должна опустошать кошелек
+	if cfg.MaxTransfer.IsPositive() {
+		svc.SetMaxTransfer(cfg.MaxTransfer)
+		log.Printf("Переводы больше %s отклоняются", cfg.MaxTransfer)
+	}
+
+	// Категории переводов для отчетов: опечатка ("salery") создала бы отдельную категорию
+	if len(cfg.Categories) > 0 {
+		svc.SetCategories(cfg.Categories)
+		log.Printf("Допустимые категории переводов: %s", strings.Join(cfg.Categories, ", "))
+	}
+
+	// Адреса новых кошельков с контрольной суммой: опечатка в адресе получателя обнаруживается
+	// при проверке запроса, а не приводит к 404 или переводу на чужой кошелек
+	if cfg.AddressChecksum {
+		svc.EnableAddressChecksum()
+		log.Printf("Адреса новых кошельков выдаются с контрольной суммой")
+	}
+
+	// Демонстрационные кошельки создаются до казначейства: иначе хранилище уже не пусто
+	if cfg.SeedOnStart {
+		seedWallets(svc)
+	}
+
+	// Кошелек казначейства с известным адресом - источник средств для интеграций и тестов
+	if cfg.TreasuryAddress != "" {
+		ensureTreasury(repo, cfg.TreasuryAddress, cfg.TreasuryBalance)
+	}
+
+	// Без кошельков переводы невозможны: в эксплуатации их нужно создать явно
+	if exists, err := svc.HasWallets(context.Background()); err != nil {
+		log.Fatalf("Ошибка при проверке кошельков: %v", err)
+	} else if !exists {
+		slog.Warn("no wallets found: provision them with TREASURY_ADDRESS, POST /api/admin/wallets or the seed command",
+			"app_env", cfg.AppEnv)
+	}
+
+	// Кэш списка последних транзакций: панели мониторинга запрашивают его постоянно,
+	// а меняется он только при переводе
+	if cfg.TransactionsCacheTTL > 0 {
+		svc.EnableTransactionsCache(cfg.TransactionsCacheTTL, transactionsCacheEntries)
+	}
+
+	// Обязательные подписи: без них перевод выполняется любым, кто знает адрес отправителя
+	if cfg.RequireSignatures {
+		svc.RequireSignatures()
+		log.Println("Переводы принимаются только с подписью ключом кошелька отправителя")
+	}
+
+	// Правила проверки переводов: подозрительные переводы записываются в журнал или отклоняются
+	if cfg.Risk.Enabled() {
+		engine, err := risk.NewEngine(repo, cfg.Risk)
+		if err != nil {
+			log.Fatalf("Ошибка при настройке правил проверки переводов: %v", err)
+		}
+		svc.AddSendInterceptor(engine)
+		log.Printf("Включены правила проверки переводов (velocity: %d за %s, %s; amount: x%g, %s)",
+			cfg.Risk.VelocityMaxTransfers, cfg.Risk.VelocityWindow, cfg.Risk.VelocityAction,
+			cfg.Risk.AmountMultiplier, cfg.Risk.AmountAction)
+	}
+
+	// Подтверждение крупных переводов: они ждут решения администратора в /api/admin/approvals,
+	// а фоновая проверка завершает не рассмотренные за APPROVAL_TTL
+	// Фоновые задачи останавливаются отменой workers; при завершении сервер ждет их в running,
+	// чтобы начатая пачка очистки истории успела зафиксироваться
+	workers, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var running sync.WaitGroup
+	startWorker := func(run func()) {
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			run()
+		}()
+	}
+	if cfg.ApprovalThreshold.IsPositive() {
+		svc.RequireApproval(cfg.ApprovalThreshold)
+		startWorker(func() { svc.RunApprovalExpiry(workers, cfg.ApprovalExpiryInterval, cfg.ApprovalTTL) })
+		log.Printf("Переводы больше %s ожидают подтверждения администратора (не дольше %s)", cfg.ApprovalThreshold, cfg.ApprovalTTL)
+	}
+
+	// Очистка истории: транзакции старше RETENTION_PERIOD переносятся в transactions_archive
+	// (или удаляются), чтобы таблица transactions не росла без ограничений
+	if cfg.Retention.Period > 0 {
+		startWorker(func() { svc.RunRetention(workers, cfg.RetentionInterval, cfg.Retention) })
+		log.Printf("Транзакции старше %s переносятся в архив: %t (проверка раз в %s)",
+			cfg.Retention.Period, cfg.Retention.Archive, cfg.RetentionInterval)
+	}
+
+	// Фоновая проверка базы: устаревшие после перезапуска PostgreSQL подключения обнаруживаются
+	// до запроса клиента, а /readyz отвечает по результату последней проверки
+	if cfg.HealthCheckInterval > 0 {
+		startWorker(func() { svc.RunHealthCheck(workers, cfg.HealthCheckInterval) })
+	}
+
+	// Уведомления о поступлении средств: переводы ставят их в очередь, а фоновая задача
+	// отправляет письма. Без SMTP_HOST очередь все равно разбирается, чтобы не расти
+	var notifier notify.Notifier = notify.Nop{}
+	if cfg.SMTP.Host != "" {
+		smtpNotifier, err := notify.NewSMTP(cfg.SMTP)
+		if err != nil {
+			log.Fatalf("Ошибка при настройке почтового сервера: %v", err)
+		}
+		notifier = smtpNotifier
+		log.Printf("Уведомления о поступлении средств отправляются через %s:%d", cfg.SMTP.Host, cfg.SMTP.Port)
+	}
+	startWorker(func() { svc.RunNotifications(workers, cfg.NotifyInterval, notifier, cfg.Notifications) })
+
+	// Режим обслуживания: при включении переводы отклоняются, чтение продолжает работать
+	maintenance := handlers.NewMaintenanceMode(cfg.ReadOnly)
+	if cfg.ReadOnly {
+		log.Println("Сервер запущен в режиме только для чтения")
+	}
+
+	// Ограничение одновременных переводов: при всплеске переводы не занимают все подключения
+	// к базе, и чтение с проверками живости продолжают обслуживаться. Без ограничения (0)
+	// ограничитель все равно создается, чтобы его можно было включить через /api/admin/limits/send
+	sendLimiter := handlers.NewConcurrencyLimiter("send", cfg.SendMaxInFlight, cfg.SendQueueTimeout)
+	if cfg.SendMaxInFlight > 0 {
+		log.Printf("Одновременных переводов не больше %d, ожидание места до %s", cfg.SendMaxInFlight, cfg.SendQueueTimeout)
+	}
+
+	// Перечитывание настроек без перезапуска: по SIGHUP (и через /api/admin/config/reload)
+	// CONFIG_FILE читается заново, уровень журнала и ограничение переводов применяются сразу.
+	// Переменные окружения запущенного процесса извне не меняются, поэтому без файла
+	// перечитывать нечего
+	var reloader *configReloader
+	if cfg.ConfigFile != "" {
+		reloader = &configReloader{path: cfg.ConfigFile, values: cfg.ConfigValues, sendLimiter: sendLimiter, svc: svc}
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		startWorker(func() {
+			defer signal.Stop(hup)
+			for {
+				select {
+				case <-hup:
+					if _, err := reloader.reload("SIGHUP"); err != nil {
+						slog.Error("configuration reload failed", "source", "SIGHUP", "error", err)
+					}
+				case <-workers.Done():
+					return
+				}
+			}
+		})
+		log.Printf("Настройки перечитываются из %s по сигналу SIGHUP", cfg.ConfigFile)
+	}
+
+	// Создание маршрутизатора с использованием библиотеки Gorilla Mux.
+	// Неизвестные пути и методы получают ответ в том же JSON-формате, что и остальные ошибки API
+	router := mux.NewRouter()
+	router.NotFoundHandler = handlers.NotFoundHandler()
+	router.MethodNotAllowedHandler = handlers.MethodNotAllowedHandler(router)
+
+	// Префикс API_BASE_PATH для развертывания за шлюзом: маршруты API регистрируются
+	// в подмаршрутизаторе, и /api/send становится, например, /payments/v1/api/send.
+	// Подмаршрутизатор отвечает на неизвестные пути и методы под префиксом так же, как основной
+	api := router
+	if cfg.BasePath != "" {
+		api = router.PathPrefix(cfg.BasePath).Subrouter()
+		api.NotFoundHandler = handlers.SubrouterNotFoundHandler(router)
+		log.Printf("Маршруты API доступны с префиксом %s (служебные маршруты под префиксом: %t)", cfg.BasePath, cfg.OpsUnderBasePath)
+	}
+
+	// Регистрация обработчиков для API: версия v1 (/api/v1/...) и устаревшие маршруты без версии
+	// (/api/send, /api/transactions, /api/wallet/{address}/balance, /api/wallet/{address}/sendable),
+	// сохраняющие прежний формат ответов для существующих клиентов
+	handlers.RegisterV1(api, svc, maintenance, sendLimiter, cfg.API)
+	handlers.RegisterLegacy(api, svc, maintenance, sendLimiter, cfg.API)
+
+	// Служебные маршруты: проверки живости и готовности, версия сборки; метрики Prometheus -
+	// на основном порту или на административном, если задан ADMIN_PORT (см. ниже). По умолчанию
+	// они остаются без префикса, чтобы проверки оркестратора не зависели от настройки шлюза
+	ops := router
+	if cfg.OpsUnderBasePath {
+		ops = api
+	}
+	info := buildInfo(cfg)
+	ops.HandleFunc("/healthz", handlers.HealthHandler(info)).Methods("GET")
+	ops.HandleFunc("/readyz", handlers.ReadyHandler(svc, info)).Methods("GET")
+	ops.HandleFunc("/version", handlers.VersionHandler(info)).Methods("GET")
+	api.HandleFunc("/api/version", handlers.VersionHandler(info)).Methods("GET")
+
+	// Административные маршруты доступны только при заданном ADMIN_TOKEN: на отдельном порту
+	// ADMIN_PORT, если он задан, иначе на основном (если это не выключено ADMIN_ON_MAIN_PORT=false).
+	// Отдельный порт по умолчанию слушает только локальный интерфейс, и на нем же публикуются
+	// метрики, чтобы административные маршруты не были доступны через публичный адрес
+	// Отдельный порт не публикуется через шлюз, поэтому его маршруты префикса не получают
+	var adminServer *http.Server
+	adminRoutes := api
+	if cfg.AdminAddr != "" {
+		adminRoutes = newAdminRouter(cfg.AdminAddr, info, metrics.Handler())
+		handler := handlers.RequestIDMiddleware(handlers.AccessLogMiddleware(adminRoutes)(handlers.RecoveryMiddleware(adminRoutes)))
+		handler = handlers.TracingMiddleware(adminRoutes)(handler)
+		adminServer = &http.Server{
+			Addr:    cfg.AdminAddr,
+			Handler: handlers.SecurityHeadersMiddleware(cfg.Security)(handler),
+		}
+	} else {
+		ops.Handle("/metrics", metrics.Handler()).Methods("GET")
+	}
+	if cfg.AdminToken != "" && (adminServer != nil || cfg.AdminOnMainPort) {
+		registerAdminRoutes(adminRoutes, cfg.AdminToken, svc, maintenance, sendLimiter, reloader)
+	}
+
+	// Паника обработчика записывается в журнал и превращается в ответ 500, а не обрыв соединения.
+	// Заголовки безопасности и CORS применяются ко всем ответам, включая 404, 405 и 500
+	handler := handlers.BodyLogMiddleware(cfg.BodyLog)(handlers.RecoveryMiddleware(router))
+	handler = handlers.SecurityHeadersMiddleware(cfg.Security)(handlers.CORSMiddleware(cfg.Security)(handler))
+	// Журнал доступа - внешний слой, чтобы длительность учитывала все остальные;
+	// снаружи от него только присвоение идентификатора запроса для всех журналов
+	// и спан трассировки, идентификатор которого тоже попадает в журналы
+	handler = handlers.RequestIDMiddleware(handlers.AccessLogMiddleware(router)(handler))
+	handler = handlers.TracingMiddleware(router)(handler)
+	if cfg.BodyLog.Enabled {
+		log.Printf("Тела запросов с ошибочным ответом записываются в журнал (скрываются поля: %s)",
+			strings.Join(cfg.BodyLog.RedactFields, ", "))
+	}
+
+	// Создание HTTP-сервера
+	server := &http.Server{
+		Addr:    cfg.Addr,
+		Handler: handler,
+	}
+
+	// HTTPS без sidecar: сертификат перечитывается при изменении файлов, а с TLS_CLIENT_CA_FILE
+	// сервер принимает только клиентов с сертификатом этого УЦ (mTLS между внутренними сервисами)
+	if cfg.TLS.Enabled() {
+		tlsConfig, err := newServerTLSConfig(cfg.TLS)
+		if err != nil {
+			log.Fatalf("Ошибка настройки TLS: %v", err)
+		}
+		server.TLSConfig = tlsConfig
+		if adminServer != nil {
+			adminServer.TLSConfig = tlsConfig
+		}
+		log.Printf("Сервер принимает HTTPS (сертификат %s, проверка клиентов: %t)", cfg.TLS.CertFile, cfg.TLS.ClientCAFile != "")
+	}
+
+	// Отладочный сервер (pprof, /debug/vars) слушает отдельный локальный порт,
+	// чтобы профилировщик не был доступен через основной адрес сервиса
+	var debugServer *http.Server
+	if cfg.DebugAddr != "" {
+		debugServer = &http.Server{
+			Addr:    cfg.DebugAddr,
+			Handler: handlers.DebugHandler(),
+		}
+		go func() {
+			log.Printf("Запуск отладочного сервера на %s", cfg.DebugAddr)
+			if err := debugServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("Ошибка при запуске отладочного сервера: %v", err)
+			}
+		}()
+	}
+
+	if adminServer != nil {
+		go func() {
+			log.Printf("Запуск административного сервера на %s", cfg.AdminAddr)
+			if err := listenAndServe(adminServer); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("Ошибка при запуске административного сервера: %v", err)
+			}
+		}()
+	}
+
+	// Канал для graceful shutdown
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+
+	// Запуск сервера в отдельной горутине
+	go func() {
+		log.Printf("Запуск сервера %s (коммит %s, собран %s, %s, хранилище %s) на %s",
+			info.Version, info.Commit, info.BuildTime, info.GoVersion, info.DBDriver, cfg.Addr)
+		if err := listenAndServe(server); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Ошибка при запуске сервера: %v", err)
+		}
+	}()
+
+	// Ожидание сигнала для graceful shutdown
+	<-done
+	log.Println("Сервер завершает работу...")
+
+	// Создание контекста с таймаутом для graceful shutdown
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Завершение работы сервера и фоновых проверок
+	stopWorkers()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Fatalf("Ошибка при завершении работы сервера: %v", err)
+	}
+	if adminServer != nil {
+		adminServer.Shutdown(ctx)
+	}
+	if debugServer != nil {
+		debugServer.Shutdown(ctx)
+	}
+	stopped := make(chan struct{})
+	go func() {
+		running.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		log.Println("Фоновые задачи не завершились за отведенное время")
+	}
+	// Спаны отправляются после завершения запросов и фоновых задач, чтобы не потерять последние
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("Ошибка при отправке спанов трассировки: %v", err)
+	}
+
+	log.Println("Сервер успешно завершил работу")
+	return exitOK
+}
+
+// newRepository создает хранилище, выбранное в cfg.DBDriver, и проверяет подключение к нему.
+// Завершает программу, если тип хранилища неизвестен или база недоступна.
+func newRepository(cfg Config) repository.Repository {
+	var repo repository.Repository
+	switch cfg.DBDriver {
+	case "postgres":
+		log.Println("Используется хранилище PostgreSQL")
+		pg := repository.NewPostgresRepository()
+		// Статистика пулов подключений в /metrics, с меткой роли
+		metrics.RegisterDBPool("primary", pg.Stats)
+		if pg.HasReplica() {
+			metrics.RegisterDBPool("replica", pg.ReplicaStats)
+		}
+		repo = pg
+	case "sqlite":
+		log.Printf("Используется хранилище SQLite (%s)", cfg.DBPath)
+		sqlite := repository.NewSQLiteRepository(cfg.DBPath)
+		metrics.RegisterDBPool("primary", sqlite.Stats)
+		repo = sqlite
+	case "memory":
+		log.Println("Используется хранилище в памяти, данные не сохраняются между запусками")
+		repo = repository.NewMemoryRepository()
+	default:
+		log.Fatalf("Неизвестный тип хранилища DB_DRIVER=%q", cfg.DBDriver)
+	}
+
+	// Проверка подключения к базе данных
+	if err := repo.Ping(context.Background()); err != nil {
+		log.Fatalf("Ошибка подключения к базе данных: %v", err)
+	}
+	return repo
+}
+
+// seedWallets создает seedWalletCount демонстрационных кошельков, если кошельков еще нет,
+// и выводит их адреса в журнал: без них кошелек в памяти нельзя использовать.
+// Завершает программу, если создать кошельки не удалось.
+func seedWallets(svc *service.Service) {
+	addresses, err := svc.SeedWallets(context.Background(), seedWalletCount, decimal.NewFromInt(seedWalletBalance))
+	for _, address := range addresses {
+		slog.Info("demo wallet created", logging.KeyWallet, address, "balance", seedWalletBalance)
+	}
+	if err != nil {
+		log.Fatalf("Ошибка при создании демонстрационных кошельков: %v", err)
+	}
+}
+
+// ensureTreasury создает кошелек казначейства с заданным адресом и балансом, если его еще нет.
+// Баланс зачисляется выпуском со счета эмиссии (models.TransactionMint) в той же транзакции,
+// что и создание кошелька, поэтому появление денег видно в истории.
+// Баланс существующего кошелька не меняется: он уже расходовался переводами, и перезапись
+// при каждом запуске создавала бы или уничтожала деньги.
+//
+// Параметры:
+//   - repo: Хранилище, в котором создается кошелек.
+//   - address: Адрес кошелька (TREASURY_ADDRESS).
+//   - balance: Начальный баланс (TREASURY_BALANCE).
+func ensureTreasury(repo repository.Repository, address string, balance decimal.Decimal) {
+	err := repo.WithTx(context.Background(), func(tx repository.TxRepository) error {
+		if err := tx.CreateWallet(address, decimal.Zero, models.WalletMetadata{}, ""); err != nil {
+			return err
+		}
+		if balance.IsZero() {
+			return nil
+		}
+		if _, err := tx.AddBalance(address, balance); err != nil {
+			return err
+		}
+		_, err := tx.RecordTransaction(models.TransactionMint, repository.SystemAccount(repository.SystemMint), address,
+			balance, "TREASURY_BALANCE", "")
+		return err
+	})
+	switch {
+	case err == nil:
+		log.Printf("Создан кошелек казначейства %s с балансом %s", address, balance)
+	case errors.Is(err, repository.ErrWalletExists):
+		current, err := repo.GetBalance(context.Background(), address)
+		if err != nil {
+			log.Fatalf("Ошибка при чтении баланса кошелька казначейства %s: %v", address, err)
+		}
+		log.Printf("Кошелек казначейства %s уже существует, баланс %s (TREASURY_BALANCE не применяется)", address, current)
+	default:
+		log.Fatalf("Ошибка при создании кошелька казначейства %s: %v", address, err)
+	}
+}
+
+// listenAndServe запускает сервер по HTTPS, если у него есть настройки TLS, иначе по HTTP.
+func listenAndServe(server *http.Server) error {
+	if server.TLSConfig != nil {
+		// Сертификат задан в TLSConfig.GetCertificate, поэтому файлы здесь не передаются
+		return server.ListenAndServeTLS("", "")
+	}
+	return server.ListenAndServe()
+}
+
+// getEnv возвращает значение переменной окружения или значение по умолчанию.
+func getEnv(key, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+// splitList разбивает список через запятую, отбрасывая пустые элементы и пробелы.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// isLoopbackAddr сообщает, указывает ли адрес вида host:port на локальный интерфейс
+// (localhost, 127.0.0.1, ::1). Адрес без хоста (":6060") слушает все интерфейсы и не подходит.
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// listenAddr возвращает адрес основного сервера из BIND_ADDR и PORT. Пустой BIND_ADDR означает
+// все интерфейсы; IPv6-адрес можно указать как в квадратных скобках, так и без них.
+//
+// Параметры:
+//   - bindAddr: IP-адрес или имя хоста интерфейса.
+//   - port: Номер порта.
+//
+// Возвращает:
+//   - Адрес для http.Server, например "127.0.0.1:8080" или ":8080".
+//   - Ошибку, если порт не число от 1 до 65535 или адрес интерфейса не удается разобрать.
+func listenAddr(bindAddr, port string) (string, error) {
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return "", errors.New("port must be a number from 1 to 65535")
+	}
+	addr := net.JoinHostPort(strings.TrimSuffix(strings.TrimPrefix(bindAddr, "["), "]"), port)
+	if _, err := net.ResolveTCPAddr("tcp", addr); err != nil {
+		return "", err
+	}
+	return addr, nil
+}
+
+// isValidBasePath сообщает, подходит ли путь для префикса маршрутов API_BASE_PATH: начинается
+// с "/", состоит из непустых сегментов (кроме "." и "..") из латинских букв, цифр и символов -._~,
+// которые не требуют кодирования в URL и не изменяются прокси при нормализации пути.
+func isValidBasePath(path string) bool {
+	if !strings.HasPrefix(path, "/") {
+		return false
+	}
+	for _, segment := range strings.Split(path[1:], "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+		for _, r := range segment {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-._~", r)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// getEnvInt возвращает целочисленное значение переменной окружения или значение по умолчанию.
+// Завершает программу, если значение задано, но не является целым числом.
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("Некорректное значение %s=%q: ожидается целое число", key, value)
+	}
+	return n
+}
+
+// getEnvFloat возвращает дробное значение переменной окружения или значение по умолчанию.
+// Завершает программу, если значение задано, но не является числом.
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		log.Fatalf("Некорректное значение %s=%q: ожидается число", key, value)
+	}
+	return f
+}
+
+// getEnvDecimal возвращает сумму из переменной окружения или значение по умолчанию.
+// Завершает программу, если значение задано, но не является числом.
+func getEnvDecimal(key string, defaultValue decimal.Decimal) decimal.Decimal {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		log.Fatalf("Некорректное значение %s=%q: ожидается число", key, value)
+	}
+	return d
+}
+
+// getEnvDuration возвращает длительность из переменной окружения (например, "10s")
+// или значение по умолчанию. Завершает программу, если значение задано некорректно.
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("Некорректное значение %s=%q: ожидается длительность, например 10s", key, value)
+	}
+	return d
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	handlers "payment-system/internal/api"
+	"payment-system/internal/logging"
+	service "payment-system/internal/service"
+)
+
+// Значения по умолчанию настроек, которые можно менять без перезапуска; используются
+// при запуске и когда переменная удалена из CONFIG_FILE.
+const (
+	defaultLogLevel         = "info"
+	defaultSendQueueTimeout = 100 * time.Millisecond
+)
+
+// reloadableSettings - переменные CONFIG_FILE, которые применяются без перезапуска.
+// Изменения остальных переменных (DB_*, PORT и др.) при перечитывании не применяются.
+var reloadableSettings = map[string]bool{
+	"LOG_LEVEL":          true,
+	"SEND_MAX_IN_FLIGHT": true,
+	"SEND_QUEUE_TIMEOUT": true,
+}
+
+// readConfigFile читает файл настроек: строки KEY=VALUE с теми же именами, что у переменных
+// окружения. Пустые строки и строки, начинающиеся с #, пропускаются; пробелы вокруг имени
+// и значения отбрасываются.
+//
+// Параметры:
+//   - path: Путь к файлу (CONFIG_FILE).
+//
+// Возвращает:
+//   - Значения по именам переменных.
+//   - Ошибку, если файл не прочитан или строка не в формате KEY=VALUE.
+func readConfigFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(text, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, line)
+		}
+		values[key] = strings.TrimSpace(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// applyConfigFile читает CONFIG_FILE, если он задан, и переносит его значения в окружение
+// процесса до loadConfig: значения файла важнее переменных окружения.
+// Завершает программу, если файл не прочитан.
+//
+// Возвращает:
+//   - Путь к файлу и примененные значения; пустой путь, если CONFIG_FILE не задан.
+func applyConfigFile() (string, map[string]string) {
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		return "", nil
+	}
+	values, err := readConfigFile(path)
+	if err != nil {
+		log.Fatalf("Некорректное значение CONFIG_FILE=%q: %v", path, err)
+	}
+	for key, value := range values {
+		os.Setenv(key, value)
+	}
+	return path, values
+}
+
+// configReloader перечитывает CONFIG_FILE по сигналу SIGHUP или запросу
+// POST /api/admin/config/reload и применяет изменения reloadableSettings.
+// Компоненты читают эти настройки при каждом использовании (уровень журнала через
+// logging.SetLevel, ограничение переводов через ConcurrencyLimiter.Limits), поэтому
+// изменения действуют сразу.
+type configReloader struct {
+	mu     sync.Mutex
+	path   string
+	values map[string]string // Значения файла, действующие сейчас
+
+	sendLimiter *handlers.ConcurrencyLimiter
+	svc         *service.Service
+}
+
+// reload перечитывает файл и применяет изменения. Если значение хотя бы одной изменяемой
+// переменной некорректно, не применяется ничего. Изменения остальных переменных записываются
+// в журнал с предупреждением и не применяются. Перечитывание записывается в журнал аудита.
+//
+// Параметры:
+//   - source: Источник команды для журнала аудита: "SIGHUP" или "api".
+//
+// Возвращает:
+//   - Примененные и отклоненные изменения.
+//   - Ошибку, если файл не прочитан или значение некорректно.
+func (c *configReloader) reload(source string) (handlers.ConfigReload, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	values, err := readConfigFile(c.path)
+	if err != nil {
+		return handlers.ConfigReload{}, err
+	}
+
+	result := handlers.ConfigReload{Applied: make(map[string]string), Ignored: []string{}}
+	for _, key := range changedKeys(c.values, values) {
+		if reloadableSettings[key] {
+			result.Applied[key] = values[key]
+		} else {
+			result.Ignored = append(result.Ignored, key)
+		}
+	}
+
+	// Проверка всех значений до применения любого из них
+	level := strings.TrimSpace(valueOr(values, "LOG_LEVEL", defaultLogLevel))
+	if _, err := logging.ParseLevel(level); err != nil {
+		return handlers.ConfigReload{}, fmt.Errorf("LOG_LEVEL: %w", err)
+	}
+	maxInFlight, err := strconv.Atoi(valueOr(values, "SEND_MAX_IN_FLIGHT", "0"))
+	if err != nil || maxInFlight < 0 {
+		return handlers.ConfigReload{}, fmt.Errorf("SEND_MAX_IN_FLIGHT must be a non-negative integer")
+	}
+	queueTimeout, err := time.ParseDuration(valueOr(values, "SEND_QUEUE_TIMEOUT", defaultSendQueueTimeout.String()))
+	if err != nil || queueTimeout < 0 {
+		return handlers.ConfigReload{}, fmt.Errorf("SEND_QUEUE_TIMEOUT must be a non-negative duration")
+	}
+
+	logging.SetLevel(level)
+	c.sendLimiter.SetLimits(maxInFlight, queueTimeout)
+	for key := range result.Applied {
+		c.values[key] = values[key]
+	}
+	for _, key := range result.Ignored {
+		slog.Warn("setting changed in config file requires a restart, ignored", "key", key)
+	}
+
+	details := formatReload(result)
+	slog.Info("configuration reloaded", "source", source, "changes", details)
+	// Настройки уже применены: ошибка журнала аудита не должна их откатывать
+	if err := c.svc.RecordConfigReload(context.Background(), source, details); err != nil {
+		slog.Error("failed to record audit event", "action", "config.reloaded", "error", err)
+	}
+	return result, nil
+}
+
+// changedKeys возвращает отсортированные имена переменных, значения которых различаются
+// в old и new (в том числе добавленные и удаленные).
+func changedKeys(old, new map[string]string) []string {
+	var keys []string
+	for key, value := range new {
+		if previous, ok := old[key]; !ok || previous != value {
+			keys = append(keys, key)
+		}
+	}
+	for key := range old {
+		if _, ok := new[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// valueOr возвращает значение переменной из файла или defaultValue, если его нет или оно пусто.
+func valueOr(values map[string]string, key, defaultValue string) string {
+	if value := values[key]; value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// formatReload описывает изменения для журнала аудита, например "LOG_LEVEL=debug; ignored: PORT".
+// Значения отклоненных переменных не записываются: среди них могут быть пароли.
+func formatReload(result handlers.ConfigReload) string {
+	var applied []string
+	for key, value := range result.Applied {
+		applied = append(applied, key+"="+value)
+	}
+	sort.Strings(applied)
+	details := strings.Join(applied, " ")
+	if details == "" {
+		details = "no changes"
+	}
+	if len(result.Ignored) > 0 {
+		details += "; ignored: " + strings.Join(result.Ignored, " ")
+	}
+	return details
+}
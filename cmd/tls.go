@@ -0,0 +1,159 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// certificateCheckInterval - как часто при подключениях проверяется, изменились ли файлы
+// сертификата на диске.
+const certificateCheckInterval = 10 * time.Second
+
+// TLSConfig - файлы для HTTPS. Если CertFile и KeyFile пусты, сервер слушает обычный HTTP.
+type TLSConfig struct {
+	CertFile     string // Сертификат сервера в PEM (TLS_CERT_FILE), может содержать цепочку
+	KeyFile      string // Закрытый ключ сервера в PEM (TLS_KEY_FILE)
+	ClientCAFile string // Сертификаты УЦ клиентов в PEM (TLS_CLIENT_CA_FILE); если задан, клиент обязан предъявить сертификат
+}
+
+// Enabled сообщает, что сервер должен слушать HTTPS.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+// certificateReloader отдает сертификат сервера при TLS-рукопожатии и перечитывает его,
+// когда файлы на диске меняются (например, при обновлении cert-manager), без перезапуска.
+// Файлы проверяются не чаще certificateCheckInterval. Если новые файлы прочитать не удалось
+// (например, записан только один из них), продолжает отдавать прежний сертификат.
+type certificateReloader struct {
+	certFile, keyFile string
+
+	mu          sync.Mutex
+	certificate *tls.Certificate
+	modTime     time.Time // Время изменения файлов загруженного сертификата
+	checkedAt   time.Time // Время последней проверки файлов
+}
+
+// newCertificateReloader загружает сертификат и ключ.
+//
+// Возвращает:
+//   - Ошибку, если файлы не прочитаны или не образуют пару.
+func newCertificateReloader(certFile, keyFile string) (*certificateReloader, error) {
+	c := &certificateReloader{certFile: certFile, keyFile: keyFile}
+	modTime, err := c.filesModTime()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.load(modTime); err != nil {
+		return nil, err
+	}
+	c.checkedAt = time.Now()
+	return c, nil
+}
+
+// filesModTime возвращает наибольшее время изменения файлов сертификата и ключа.
+func (c *certificateReloader) filesModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// load читает сертификат и ключ; вызывается под c.mu или до начала работы.
+func (c *certificateReloader) load(modTime time.Time) error {
+	certificate, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+	c.certificate, c.modTime = &certificate, modTime
+	return nil
+}
+
+// getCertificate реализует tls.Config.GetCertificate.
+func (c *certificateReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.checkedAt) < certificateCheckInterval {
+		return c.certificate, nil
+	}
+	c.checkedAt = time.Now()
+	modTime, err := c.filesModTime()
+	if err != nil {
+		slog.Error("failed to check TLS certificate", "error", err)
+		return c.certificate, nil
+	}
+	// Сравнение на неравенство, а не After: при замене файлов время может и уменьшиться
+	if modTime.Equal(c.modTime) {
+		return c.certificate, nil
+	}
+	if err := c.load(modTime); err != nil {
+		slog.Error("failed to reload TLS certificate, keeping the previous one", "error", err)
+		return c.certificate, nil
+	}
+	slog.Info("TLS certificate reloaded", "cert_file", c.certFile)
+	return c.certificate, nil
+}
+
+// newServerTLSConfig создает настройки TLS сервера: не ниже TLS 1.2, для TLS 1.2 - только
+// наборы шифров с ECDHE и AEAD (в TLS 1.3 Go выбирает их сам), сертификат перечитывается
+// при изменении файлов. Если задан ClientCAFile, клиент обязан предъявить сертификат,
+// подписанный одним из этих УЦ (mTLS); файл УЦ читается только при запуске.
+//
+// Параметры:
+//   - cfg: Файлы сертификата, ключа и УЦ клиентов.
+//
+// Возвращает:
+//   - Настройки для http.Server.TLSConfig.
+//   - Ошибку, если файлы не прочитаны.
+//
+// Пример использования:
+//
+//	server.TLSConfig, err = newServerTLSConfig(cfg.TLS)
+//	err = server.ListenAndServeTLS("", "")
+func newServerTLSConfig(cfg TLSConfig) (*tls.Config, error) {
+	certificates, err := newCertificateReloader(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
+		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+		GetCertificate:   certificates.getCertificate,
+	}
+
+	if cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA file %s", cfg.ClientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
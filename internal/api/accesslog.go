@@ -0,0 +1,113 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"payment-system/internal/logging"
+	"payment-system/internal/metrics"
+
+	"github.com/gorilla/mux"
+)
+
+// unmatchedRoute - метка маршрута в метриках для запросов, не совпавших ни с одним маршрутом
+// (404 и 405). Подставлять сам путь нельзя: число значений метки стало бы неограниченным.
+const unmatchedRoute = "unmatched"
+
+// responseWriter запоминает код ответа и количество записанных байт тела.
+// Если обработчик не вызвал WriteHeader, код считается равным 200, как в net/http.
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+// newResponseWriter оборачивает w; до записи ответа код равен 200.
+func newResponseWriter(w http.ResponseWriter) *responseWriter {
+	return &responseWriter{ResponseWriter: w, status: http.StatusOK}
+}
+
+// WriteHeader запоминает код ответа и передает его дальше.
+func (w *responseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write передает тело дальше и учитывает количество записанных байт.
+func (w *responseWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += n
+	return n, err
+}
+
+// Unwrap возвращает исходный ResponseWriter для http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// AccessLogMiddleware записывает в журнал строку на каждый запрос (идентификатор запроса, метод,
+// путь, код ответа, размер тела ответа, IP клиента, длительность) и учитывает длительность в гистограмме
+// payment_http_request_duration_seconds по шаблону маршрута.
+// Строка записывается после завершения обработчика в журнал запроса (logging.FromContext),
+// который добавляет идентификатор запроса; длительность - в миллисекундах (duration_ms).
+//
+// Параметры:
+//   - router: Маршрутизатор, по которому определяется шаблон маршрута для метрик.
+//
+// Пример использования:
+//
+//	handler := AccessLogMiddleware(router)(handler)
+func AccessLogMiddleware(router *mux.Router) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := newResponseWriter(w)
+			next.ServeHTTP(rw, r)
+			duration := time.Since(start)
+
+			metrics.HTTPRequestDuration.
+				WithLabelValues(r.Method, routeTemplate(router, r), strconv.Itoa(rw.status)).
+				Observe(duration.Seconds())
+
+			logging.FromContext(r.Context()).Info("http request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", rw.status,
+				"bytes", rw.bytes,
+				"remote_ip", remoteIP(r),
+				logging.Duration(duration),
+			)
+		})
+	}
+}
+
+// routeTemplate возвращает шаблон маршрута запроса (например, "/api/wallet/{address}/balance")
+// или unmatchedRoute, если запрос не совпал ни с одним маршрутом.
+func routeTemplate(router *mux.Router, r *http.Request) string {
+	var match mux.RouteMatch
+	if !router.Match(r, &match) || match.Route == nil {
+		return unmatchedRoute
+	}
+	template, err := match.Route.GetPathTemplate()
+	if err != nil {
+		return unmatchedRoute
+	}
+	return template
+}
+
+// remoteIP возвращает IP-адрес клиента из r.RemoteAddr без порта.
+// Заголовки прокси (X-Forwarded-For) не учитываются, так как клиент может их подделать.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
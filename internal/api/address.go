@@ -0,0 +1,60 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	db "payment-system/internal/db"
+	service "payment-system/internal/service"
+	addr "payment-system/pkg/address"
+)
+
+// parseAddress проверяет адрес кошелька из запроса и приводит его к нижнему регистру,
+// в котором адреса хранятся. Адрес с буквами разного регистра проверяется по контрольной
+// сумме (см. пакет pkg/address), адрес в одном регистре принимается без проверки.
+//
+// Параметры:
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Адрес в нижнем регистре.
+//   - addr.ErrInvalid, если длина не 2*db.AddressBytes() или символы не шестнадцатеричные;
+//     addr.ErrChecksum, если контрольная сумма не совпадает.
+func parseAddress(address string) (string, error) {
+	if len(address) != 2*db.AddressBytes() {
+		return "", addr.ErrInvalid
+	}
+	if err := addr.Validate(address); err != nil {
+		return "", err
+	}
+	return strings.ToLower(address), nil
+}
+
+// parseParty проверяет участника перевода: метка с префиксом service.LabelPrefix
+// возвращается без изменений, адрес - как в parseAddress.
+func parseParty(party string) (string, error) {
+	if strings.HasPrefix(party, service.LabelPrefix) {
+		return party, nil
+	}
+	return parseAddress(party)
+}
+
+// addressViolation возвращает нарушение для ошибки parseAddress в поле field: "invalid_checksum",
+// если не совпала контрольная сумма (вероятна опечатка), иначе "invalid_format".
+func addressViolation(field string, err error) violation {
+	if errors.Is(err, addr.ErrChecksum) {
+		return violation{Field: field, Code: violationChecksum, Message: "Wallet address checksum mismatch"}
+	}
+	return violation{Field: field, Code: violationFormat, Message: "Invalid wallet address"}
+}
+
+// writeAddressError отвечает 400 на ошибку parseAddress: "invalid_address_checksum", если
+// не совпала контрольная сумма (вероятна опечатка), иначе "invalid_request" с сообщением message.
+func writeAddressError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, addr.ErrChecksum) {
+		writeJSONError(w, http.StatusBadRequest, "invalid_address_checksum", "Wallet address checksum mismatch")
+		return
+	}
+	writeJSONError(w, http.StatusBadRequest, "invalid_request", message)
+}
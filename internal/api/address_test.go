@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestIsValidAddress проверяет длину адреса для нескольких значений ADDRESS_BYTES:
+// при значении по умолчанию существующие адреса из 64 символов остаются валидными.
+func TestIsValidAddress(t *testing.T) {
+	tests := []struct {
+		bytes   string
+		address string
+		want    bool
+	}{
+		{"", strings.Repeat("ab", 32), true},
+		{"", strings.Repeat("AB", 32), true},
+		{"", strings.Repeat("ab", 20), false},
+		{"", strings.Repeat("ab", 32) + "a", false},
+		{"", strings.Repeat("ag", 32), false},
+		{"32", strings.Repeat("ab", 32), true},
+		{"20", strings.Repeat("ab", 20), true},
+		{"20", strings.Repeat("ab", 32), false},
+		{"20", strings.Repeat("ab", 19) + "a", false},
+		{"48", strings.Repeat("ab", 48), true},
+		{"48", strings.Repeat("ab", 32), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.bytes+"/"+tt.address, func(t *testing.T) {
+			t.Setenv("ADDRESS_BYTES", tt.bytes)
+			if got := IsValidAddress(tt.address); got != tt.want {
+				t.Errorf("IsValidAddress(%q) with ADDRESS_BYTES=%q = %t, want %t", tt.address, tt.bytes, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSendSchemaAddressLength проверяет, что схема перевода, скомпилированная при заданном
+// ADDRESS_BYTES, принимает адреса этой длины и отклоняет адреса длины по умолчанию.
+// Схемы компилируются при запуске, поэтому тест компилирует свою.
+func TestSendSchemaAddressLength(t *testing.T) {
+	for _, bytes := range []int{20, 48} {
+		t.Run(strconv.Itoa(bytes), func(t *testing.T) {
+			t.Setenv("ADDRESS_BYTES", strconv.Itoa(bytes))
+			schema := mustCompileSchema("schemas/send.json")
+
+			tests := []struct {
+				address string
+				want    bool
+			}{
+				{strings.Repeat("ab", bytes), true},
+				{strings.Repeat("AB", bytes), true},
+				{strings.Repeat("ab", 32), false},
+			}
+			for _, tt := range tests {
+				var doc interface{}
+				body := `{"from":"` + tt.address + `","to":"` + tt.address + `","amount":1}`
+				if err := json.Unmarshal([]byte(body), &doc); err != nil {
+					t.Fatalf("decode %s: %v", body, err)
+				}
+				if got := len(validateSchema(schema, doc)) == 0; got != tt.want {
+					t.Errorf("address of %d characters valid = %t, want %t", len(tt.address), got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	models "payment-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// AmountFormatHeader - заголовок запроса, которым клиент выбирает представление сумм
+// в ответах устаревших GET /api/wallet/{address}/balance и GET /api/transactions.
+// Клиенты на JavaScript теряют точность на больших числах JSON и могут запросить строки.
+const AmountFormatHeader = "X-Amount-Format"
+
+// Значения заголовка AmountFormatHeader.
+const (
+	amountFormatNumber = "number" // число JSON (по умолчанию, прежний формат)
+	amountFormatString = "string" // строка в десятичной записи без округления, например "100.5"
+)
+
+// stringAmounts читает заголовок AmountFormatHeader и отмечает в заголовке Vary, что ответ
+// от него зависит, чтобы кэши не смешивали представления.
+//
+// Возвращает:
+//   - true, если клиент запросил суммы строками.
+//   - false вторым значением, если ответ с ошибкой уже записан.
+func stringAmounts(w http.ResponseWriter, r *http.Request) (bool, bool) {
+	w.Header().Add("Vary", AmountFormatHeader)
+	switch format := r.Header.Get(AmountFormatHeader); format {
+	case "", amountFormatNumber:
+		return false, true
+	case amountFormatString:
+		return true, true
+	default:
+		writeJSONError(w, http.StatusBadRequest, "invalid_request",
+			fmt.Sprintf("Header %s must be %q or %q, got %q", AmountFormatHeader, amountFormatNumber, amountFormatString, format))
+		return false, false
+	}
+}
+
+// legacyTransaction - транзакция в устаревшем формате GET /api/transactions. Поля перечислены
+// явно, а не встроены из models.Transaction: новые поля модели (например, Type) не должны
+// менять замороженный формат. Amount - число JSON (json.Number) или строка, если клиент
+// запросил AmountFormatHeader: string.
+type legacyTransaction struct {
+	ID         int         `json:"id"`
+	From       string      `json:"from"`
+	To         string      `json:"to"`
+	Amount     interface{} `json:"amount"`
+	CreatedAt  time.Time   `json:"created_at"`
+	Memo       string      `json:"memo,omitempty"`
+	Category   string      `json:"category,omitempty"`
+	ExternalID string      `json:"external_id,omitempty"`
+	Imported   bool        `json:"imported,omitempty"`
+}
+
+// withLegacyFormat возвращает транзакции в устаревшем формате с суммами числами
+// или, если asStrings, строками.
+func withLegacyFormat(transactions []models.Transaction, asStrings bool) []legacyTransaction {
+	result := make([]legacyTransaction, 0, len(transactions))
+	for _, t := range transactions {
+		var amount interface{} = numberAmount(t.Amount)
+		if asStrings {
+			amount = t.Amount.String()
+		}
+		result = append(result, legacyTransaction{
+			ID:         t.ID,
+			From:       t.From,
+			To:         t.To,
+			Amount:     amount,
+			CreatedAt:  t.CreatedAt,
+			Memo:       t.Memo,
+			Category:   t.Category,
+			ExternalID: t.ExternalID,
+			Imported:   t.Imported,
+		})
+	}
+	return result
+}
+
+// Суммы и балансы в моделях хранятся как decimal.Decimal, который кодируется в JSON строкой.
+// Ответы /api, отдающие модели целиком, по-прежнему содержат суммы числами (100.5, а не "100.5"):
+// от этого формата зависят клиенты. Типы ниже повторяют модели, заменяя сумму числом JSON
+// с тем же десятичным представлением; новые ответы (/api/v1) передают суммы строками.
+
+// numberAmount возвращает сумму как число JSON без округления.
+func numberAmount(amount decimal.Decimal) json.Number {
+	return json.Number(amount.String())
+}
+
+// numberBalanceWallet - кошелек с балансом числом. Поле Balance скрывает одноименное
+// поле models.Wallet.
+type numberBalanceWallet struct {
+	models.Wallet
+	Balance json.Number `json:"balance"`
+}
+
+// withNumberBalance возвращает кошелек с балансом числом.
+func withNumberBalance(wallet models.Wallet) numberBalanceWallet {
+	return numberBalanceWallet{Wallet: wallet, Balance: numberAmount(wallet.Balance)}
+}
+
+// withNumberBalances возвращает кошельки с балансами числами.
+func withNumberBalances(wallets []models.Wallet) []numberBalanceWallet {
+	result := make([]numberBalanceWallet, 0, len(wallets))
+	for _, wallet := range wallets {
+		result = append(result, withNumberBalance(wallet))
+	}
+	return result
+}
+
+// numberAmountApproval - отложенный перевод с суммой числом.
+type numberAmountApproval struct {
+	models.Approval
+	Amount json.Number `json:"amount"`
+}
+
+// withNumberAmount возвращает отложенный перевод с суммой числом.
+func withNumberAmount(approval models.Approval) numberAmountApproval {
+	return numberAmountApproval{Approval: approval, Amount: numberAmount(approval.Amount)}
+}
+
+// numberAmountNotification - уведомление с суммой числом.
+type numberAmountNotification struct {
+	models.Notification
+	Amount json.Number `json:"amount"`
+}
+
+// numberAmountRiskEvent - срабатывание правила риск-контроля с суммой числом.
+type numberAmountRiskEvent struct {
+	models.RiskEvent
+	Amount json.Number `json:"amount"`
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	models "payment-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestAmountEncoding проверяет, что устаревшие ответы /api кодируют суммы числами без
+// округления, а ответы v1 и X-Amount-Format: string - строками.
+func TestAmountEncoding(t *testing.T) {
+	env := newTestEnv(t, nil)
+	from := env.wallet(t, "20000000000")
+	to := env.wallet(t, "0")
+	env.send(t, from, to, "12345678901.12345678")
+	missing := strings.Repeat("a", 64)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		header []string
+		path   []string // Путь к значению в ответе; "0" - первый элемент массива
+		want   string   // Значение в JSON без изменений
+	}{
+		{"legacy transactions", "GET", "/api/transactions", "", nil, []string{"0", "amount"}, `12345678901.12345678`},
+		{"legacy transactions as strings", "GET", "/api/transactions", "", []string{AmountFormatHeader, "string"}, []string{"0", "amount"}, `"12345678901.12345678"`},
+		{"v1 transactions", "GET", "/api/v1/transactions", "", nil, []string{"0", "amount"}, `"12345678901.12345678"`},
+		{"legacy balance", "GET", "/api/wallet/" + from + "/balance", "", nil, []string{"balance"}, `7654321098.87654322`},
+		{"legacy balance as string", "GET", "/api/wallet/" + from + "/balance", "", []string{AmountFormatHeader, "string"}, []string{"balance"}, `"7654321098.87654322"`},
+		{"batch balances", "POST", "/api/wallets/balances", `{"addresses":["` + to + `"]}`, nil, []string{to}, `12345678901.12345678`},
+		{"batch balances unknown wallet", "POST", "/api/wallets/balances", `{"addresses":["` + missing + `"]}`, nil, []string{missing}, `null`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(t, tt.method, tt.target, tt.body, tt.header...)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200; body: %s", rec.Code, rec.Body)
+			}
+			if got := jsonPath(t, rec.Body.Bytes(), tt.path...); got != tt.want {
+				t.Errorf("%v = %s, want %s", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLegacyTransactionFields проверяет, что устаревший список транзакций не получил поле type,
+// которое есть только в ответе v1.
+func TestLegacyTransactionFields(t *testing.T) {
+	env := newTestEnv(t, nil)
+	from := env.wallet(t, "100")
+	to := env.wallet(t, "0")
+	env.send(t, from, to, "10")
+
+	for _, header := range [][]string{nil, {AmountFormatHeader, "string"}} {
+		rec := env.do(t, "GET", "/api/transactions", "", header...)
+		var transactions []map[string]json.RawMessage
+		if err := json.Unmarshal(rec.Body.Bytes(), &transactions); err != nil || len(transactions) != 1 {
+			t.Fatalf("decode %s: %v", rec.Body, err)
+		}
+		if _, ok := transactions[0]["type"]; ok {
+			t.Errorf("legacy transaction %v has field type: %s", header, rec.Body)
+		}
+	}
+	rec := env.do(t, "GET", "/api/v1/transactions", "")
+	if got := jsonPath(t, rec.Body.Bytes(), "0", "type"); got != `"transfer"` {
+		t.Errorf("v1 transaction type = %s, want \"transfer\"", got)
+	}
+}
+
+// TestNumberBalanceWalletEmbedding проверяет, что баланс остается числом, когда кошелек
+// встроен в ответ с дополнительными полями (создание и перевыпуск кошелька).
+func TestNumberBalanceWalletEmbedding(t *testing.T) {
+	wallet := models.Wallet{Address: strings.Repeat("b", 64), Balance: decimal.RequireFromString("0.10")}
+	data, err := json.Marshal(struct {
+		numberBalanceWallet
+		PrivateKey string `json:"private_key"`
+	}{withNumberBalance(wallet), "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := jsonPath(t, data, "balance"); got != "0.1" {
+		t.Errorf("balance = %s, want 0.1", got)
+	}
+	if got := jsonPath(t, data, "private_key"); got != `"key"` {
+		t.Errorf("private_key = %s, want \"key\"", got)
+	}
+}
+
+// TestDecimalEncodingNotChanged проверяет, что пакет не меняет кодирование decimal.Decimal
+// глобально: суммы числами - свойство устаревших ответов, а не всех decimal в процессе.
+func TestDecimalEncodingNotChanged(t *testing.T) {
+	data, err := json.Marshal(decimal.RequireFromString("1.5"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `"1.5"` {
+		t.Errorf("json.Marshal(decimal 1.5) = %s, want \"1.5\"", data)
+	}
+}
+
+// jsonPath возвращает значение по пути path в документе data в исходной записи JSON.
+func jsonPath(t *testing.T, data []byte, path ...string) string {
+	t.Helper()
+	raw := json.RawMessage(data)
+	for _, key := range path {
+		if strings.HasPrefix(strings.TrimSpace(string(raw)), "[") {
+			var items []json.RawMessage
+			if err := json.Unmarshal(raw, &items); err != nil {
+				t.Fatalf("decode %s: %v", raw, err)
+			}
+			if key != "0" || len(items) == 0 {
+				t.Fatalf("no element %s in %s", key, raw)
+			}
+			raw = items[0]
+			continue
+		}
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &object); err != nil {
+			t.Fatalf("decode %s: %v", raw, err)
+		}
+		value, ok := object[key]
+		if !ok {
+			t.Fatalf("no field %q in %s", key, raw)
+		}
+		raw = value
+	}
+	return string(raw)
+}
@@ -0,0 +1,246 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	db "payment-system/internal/db"
+	errs "payment-system/internal/errs"
+	models "payment-system/internal/models"
+	service "payment-system/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// Ограничения административного списка отложенных переводов и причины отклонения.
+const (
+	defaultApprovalsCount = 50  // Количество переводов без параметра count
+	maxApprovalsCount     = 500 // Наибольшее значение параметра count
+	maxRejectReasonLength = 256 // Наибольшая длина причины отклонения в символах
+)
+
+// approvalStatuses - допустимые значения параметра status списка отложенных переводов.
+var approvalStatuses = []string{
+	models.ApprovalAwaitingReview, models.ApprovalApproved, models.ApprovalExecuted,
+	models.ApprovalFailed, models.ApprovalRejected, models.ApprovalExpired,
+}
+
+// sendStatus - ответ на запрос статуса отложенного перевода. Участники и сумма не возвращаются:
+// идентификаторы последовательны, и по ним нельзя узнавать чужие переводы.
+type sendStatus struct {
+	ApprovalID int64      `json:"approval_id"`
+	Status     string     `json:"status"`
+	Reason     string     `json:"reason,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	DecidedAt  *time.Time `json:"decided_at,omitempty"`
+}
+
+// writePendingApproval отвечает 202 на перевод, отложенный до подтверждения администратором.
+// Результат отправитель узнает по адресу из поля status_url.
+func writePendingApproval(w http.ResponseWriter, r *http.Request, approvalID int64) {
+	prefix := strings.TrimSuffix(r.URL.Path, "/send")
+	statusURL := fmt.Sprintf("%s/send/status/%d", prefix, approvalID)
+	w.Header().Set("Location", statusURL)
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"status":      models.ApprovalAwaitingReview,
+		"approval_id": approvalID,
+		"status_url":  statusURL,
+	})
+}
+
+// SendStatusHandler возвращает HTTP-обработчик GET /api/send/status/{approval_id}, сообщающий
+// состояние отложенного перевода: {"approval_id": 7, "status": "rejected", "reason": "..."}.
+// Статус executed означает, что перевод выполнен; rejected, failed и expired - что не выполнен.
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.HandleFunc("/api/send/status/{approval_id}", SendStatusHandler(svc)).Methods("GET")
+func SendStatusHandler(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := approvalID(w, r, "approval_id")
+		if !ok {
+			return
+		}
+
+		approval, err := svc.GetApproval(r.Context(), id)
+		if writeApprovalError(w, err) {
+			return
+		}
+		writeJSON(w, http.StatusOK, sendStatus{
+			ApprovalID: approval.ID,
+			Status:     approval.Status,
+			Reason:     approval.Reason,
+			CreatedAt:  approval.CreatedAt,
+			DecidedAt:  approval.DecidedAt,
+		})
+	}
+}
+
+// ApprovalsHandler возвращает HTTP-обработчик списка отложенных переводов, начиная с самого нового.
+// Параметр status отбирает переводы с указанным статусом (например, awaiting_review),
+// count ограничивает количество (по умолчанию 50, не больше 500).
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.Handle("/api/admin/approvals", admin(ApprovalsHandler(svc))).Methods("GET")
+func ApprovalsHandler(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := r.URL.Query().Get("status")
+		if status != "" && !slices.Contains(approvalStatuses, status) {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request",
+				fmt.Sprintf("Parameter 'status' must be one of %s, got %q", strings.Join(approvalStatuses, ", "), status))
+			return
+		}
+
+		count := defaultApprovalsCount
+		if countStr := r.URL.Query().Get("count"); countStr != "" {
+			n, err := strconv.Atoi(countStr)
+			if err != nil || n <= 0 {
+				writeJSONError(w, http.StatusBadRequest, "invalid_request",
+					fmt.Sprintf("Parameter 'count' must be a positive integer, got %q", countStr))
+				return
+			}
+			count = min(n, maxApprovalsCount)
+		}
+
+		approvals, err := svc.GetApprovals(r.Context(), status, count)
+		if writeApprovalError(w, err) {
+			return
+		}
+		resp := make([]numberAmountApproval, 0, len(approvals))
+		for _, approval := range approvals {
+			resp = append(resp, withNumberAmount(approval))
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// ApproveHandler возвращает HTTP-обработчик POST /api/admin/approvals/{id}/approve, который
+// подтверждает отложенный перевод и выполняет его. Отвечает отложенным переводом со статусом
+// executed или failed (причина в поле reason); 404, если перевода нет, 409, если решение
+// по нему уже принято.
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.Handle("/api/admin/approvals/{id}/approve", admin(ApproveHandler(svc))).Methods("POST")
+func ApproveHandler(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := approvalID(w, r, "id")
+		if !ok {
+			return
+		}
+
+		approval, err := svc.ApproveTransfer(r.Context(), id)
+		if writeApprovalError(w, err) {
+			return
+		}
+		writeJSON(w, http.StatusOK, withNumberAmount(approval))
+	}
+}
+
+// RejectHandler возвращает HTTP-обработчик POST /api/admin/approvals/{id}/reject, который
+// отклоняет отложенный перевод. Принимает {"reason": "..."}; причина обязательна и
+// сообщается отправителю в статусе перевода.
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.Handle("/api/admin/approvals/{id}/reject", admin(RejectHandler(svc))).Methods("POST")
+func RejectHandler(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := approvalID(w, r, "id")
+		if !ok {
+			return
+		}
+
+		var req struct {
+			Reason string `json:"reason"`
+		}
+		if err := decodeJSONBody(r.Body, &req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		req.Reason = strings.TrimSpace(req.Reason)
+		if req.Reason == "" || utf8.RuneCountInString(req.Reason) > maxRejectReasonLength {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request",
+				fmt.Sprintf("Field 'reason' is required and must be at most %d characters", maxRejectReasonLength))
+			return
+		}
+
+		approval, err := svc.RejectTransfer(r.Context(), id, req.Reason)
+		if writeApprovalError(w, err) {
+			return
+		}
+		writeJSON(w, http.StatusOK, withNumberAmount(approval))
+	}
+}
+
+// approvalID читает идентификатор отложенного перевода из переменной пути name.
+//
+// Возвращает:
+//   - Идентификатор.
+//   - false, если ответ с ошибкой уже записан.
+func approvalID(w http.ResponseWriter, r *http.Request, name string) (int64, bool) {
+	idStr := mux.Vars(r)[name]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		writeJSONError(w, http.StatusBadRequest, "invalid_request",
+			fmt.Sprintf("Approval id must be a positive integer, got %q", idStr))
+		return 0, false
+	}
+	return id, true
+}
+
+// writeApprovalError отвечает ошибкой операции с отложенным переводом:
+// 404, если перевода нет, 409, если решение по нему уже принято.
+//
+// Возвращает:
+//   - true, если ответ уже записан (в том числе для любой другой ошибки).
+func writeApprovalError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case writeUnavailable(w, err):
+	case errors.Is(err, errs.ErrApprovalNotFound):
+		writeJSONError(w, http.StatusNotFound, "not_found", "Approval not found")
+	case errors.Is(err, errs.ErrApprovalStatus):
+		writeJSONError(w, http.StatusConflict, "approval_decided", err.Error())
+	case errors.Is(err, db.ErrOutcomeUnknown):
+		w.Header().Set(TransferOutcomeHeader, "unknown")
+		writeJSONError(w, http.StatusServiceUnavailable, "outcome_unknown",
+			"Database connection was lost while committing the approved transfer and it may have been executed; "+
+				"check the approval status and, if it is still awaiting review, approve it again")
+	default:
+		writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"net/http"
+
+	service "payment-system/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// ArchiveWalletHandler возвращает HTTP-обработчик DELETE /api/wallet/{address}, который
+// архивирует кошелек (мягкое удаление): кошелек перестает участвовать в переводах и скрывается
+// из поиска, а его транзакции остаются в истории. Отвечает архивным кошельком с полем
+// archived_at; 404, если кошелька нет, 409, если баланс не нулевой или кошелек участвует
+// в переводах, ожидающих подтверждения. Маршрут доступен только администратору, как и восстановление.
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.Handle("/api/wallet/{address}", admin(ArchiveWalletHandler(svc))).Methods("DELETE")
+func ArchiveWalletHandler(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		address, err := parseAddress(mux.Vars(r)["address"])
+		if err != nil {
+			writeAddressError(w, err, "Invalid wallet address")
+			return
+		}
+
+		wallet, err := svc.ArchiveWallet(r.Context(), address)
+		if writeWalletError(w, err) {
+			return
+		}
+		writeJSON(w, http.StatusOK, withNumberBalance(wallet))
+	}
+}
+
+// RestoreWalletHandler возвращает HTTP-обработчик POST /api/admin/wallets/{address}/restore,
+// который возвращает архивный кошелек в работу. Отвечает кошельком после восстановления;
+// 404, если кошелька нет.
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.Handle("/api/admin/wallets/{address}/restore", admin(RestoreWalletHandler(svc))).Methods("POST")
+func RestoreWalletHandler(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		address, err := parseAddress(mux.Vars(r)["address"])
+		if err != nil {
+			writeAddressError(w, err, "Invalid wallet address")
+			return
+		}
+
+		wallet, err := svc.RestoreWallet(r.Context(), address)
+		if writeWalletError(w, err) {
+			return
+		}
+		writeJSON(w, http.StatusOK, withNumberBalance(wallet))
+	}
+}
+
+// RotateWalletHandler возвращает HTTP-обработчик POST /api/wallet/{address}/rotate, который
+// заменяет адрес кошелька (см. Service.RotateWallet): баланс, метка и теги переносятся
+// на новый адрес, прежний архивируется. Отвечает 201 с новым кошельком, его закрытым ключом
+// "private_key", прежним адресом "previous_address" и транзакцией переноса "transaction_id";
+// 404, если кошелька нет, 409, если он архивирован или участвует в переводах, ожидающих
+// подтверждения.
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.Handle("/api/wallet/{address}/rotate", admin(RotateWalletHandler(svc))).Methods("POST")
+func RotateWalletHandler(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		address, err := parseAddress(mux.Vars(r)["address"])
+		if err != nil {
+			writeAddressError(w, err, "Invalid wallet address")
+			return
+		}
+
+		rotation, err := svc.RotateWallet(r.Context(), address)
+		if writeWalletError(w, err) {
+			return
+		}
+		writeJSON(w, http.StatusCreated, struct {
+			numberBalanceWallet
+			PrivateKey      string `json:"private_key"`
+			PreviousAddress string `json:"previous_address"`
+			TransactionID   int    `json:"transaction_id"`
+		}{withNumberBalance(rotation.Wallet), rotation.PrivateKey, rotation.PreviousAddress, rotation.TransactionID})
+	}
+}
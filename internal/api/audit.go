@@ -0,0 +1,100 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	models "payment-system/internal/models"
+	service "payment-system/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// Ограничения списка записей журнала аудита.
+const (
+	defaultAuditEventsCount = 50  // Количество записей без параметра count
+	maxAuditEventsCount     = 500 // Наибольшее значение параметра count
+)
+
+// AnonymizeWalletHandler возвращает HTTP-обработчик POST /api/admin/wallets/{address}/anonymize,
+// который удаляет персональные данные кошелька: метку, теги и комментарии его транзакций
+// и отложенных переводов. Суммы и балансы не меняются. С параметром dry_run=true записи
+// только подсчитываются. Отвечает количеством измененных записей:
+// {"dry_run": false, "wallets": 1, "transactions": 12, "approvals": 0}; 404, если кошелька нет.
+// Повторный запрос безопасен и сообщает нули.
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.Handle("/api/admin/wallets/{address}/anonymize", admin(AnonymizeWalletHandler(svc))).Methods("POST")
+func AnonymizeWalletHandler(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		address, err := parseAddress(mux.Vars(r)["address"])
+		if err != nil {
+			writeAddressError(w, err, "Invalid wallet address")
+			return
+		}
+
+		dryRun := false
+		if dryRunStr := r.URL.Query().Get("dry_run"); dryRunStr != "" {
+			var err error
+			if dryRun, err = strconv.ParseBool(dryRunStr); err != nil {
+				writeJSONError(w, http.StatusBadRequest, "invalid_request",
+					fmt.Sprintf("Parameter 'dry_run' must be a boolean, got %q", dryRunStr))
+				return
+			}
+		}
+
+		report, err := svc.AnonymizeWallet(r.Context(), address, dryRun)
+		if writeWalletError(w, err) {
+			return
+		}
+		writeJSON(w, http.StatusOK, report)
+	}
+}
+
+// AuditLogHandler возвращает HTTP-обработчик списка последних записей журнала аудита,
+// начиная с самой новой. Параметр count необязателен (по умолчанию 50, не больше 500).
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.Handle("/api/admin/audit-log", admin(AuditLogHandler(svc))).Methods("GET")
+func AuditLogHandler(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		count := defaultAuditEventsCount
+		if countStr := r.URL.Query().Get("count"); countStr != "" {
+			n, err := strconv.Atoi(countStr)
+			if err != nil || n <= 0 {
+				writeJSONError(w, http.StatusBadRequest, "invalid_request",
+					fmt.Sprintf("Parameter 'count' must be a positive integer, got %q", countStr))
+				return
+			}
+			count = min(n, maxAuditEventsCount)
+		}
+
+		events, err := svc.GetAuditEvents(r.Context(), count)
+		if writeUnavailable(w, err) {
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+		if events == nil {
+			events = []models.AuditEvent{}
+		}
+		writeJSON(w, http.StatusOK, events)
+	}
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	service "payment-system/internal/service"
+	addr "payment-system/pkg/address"
+)
+
+// maxBalanceAddresses - максимальное количество адресов в одном запросе балансов.
+const maxBalanceAddresses = 1000
+
+// GetBalancesHandler возвращает HTTP-обработчик POST /api/wallets/balances, отдающий балансы
+// нескольких кошельков одним запросом к базе. Принимает {"addresses": [...]} и отвечает
+// объектом {адрес: баланс}. Для несуществующих кошельков возвращается null, поэтому в ответе
+// есть ключ для каждого запрошенного адреса.
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.HandleFunc("/api/wallets/balances", GetBalancesHandler(svc)).Methods("POST")
+func GetBalancesHandler(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Addresses []string `json:"addresses"`
+		}
+		if err := decodeJSONBody(r.Body, &req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		if len(req.Addresses) == 0 || len(req.Addresses) > maxBalanceAddresses {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request",
+				fmt.Sprintf("addresses must contain from 1 to %d wallet addresses", maxBalanceAddresses))
+			return
+		}
+
+		// Повторяющиеся адреса запрашиваются один раз; ответ содержит адреса в том виде,
+		// в каком они указаны в запросе (в том числе с контрольной суммой)
+		unique := make([]string, 0, len(req.Addresses))
+		resp := make(map[string]*json.Number, len(req.Addresses))
+		stored := make(map[string]string, len(req.Addresses))
+		for i, address := range req.Addresses {
+			normalized, err := parseAddress(address)
+			if errors.Is(err, addr.ErrChecksum) {
+				writeJSONError(w, http.StatusBadRequest, "invalid_address_checksum",
+					fmt.Sprintf("addresses[%d]: wallet address checksum mismatch", i))
+				return
+			}
+			if err != nil {
+				writeJSONError(w, http.StatusBadRequest, "invalid_request",
+					fmt.Sprintf("addresses[%d]: invalid wallet address", i))
+				return
+			}
+			if _, ok := stored[address]; ok {
+				continue
+			}
+			resp[address] = nil
+			stored[address] = normalized
+			if !slices.Contains(unique, normalized) {
+				unique = append(unique, normalized)
+			}
+		}
+
+		balances, err := svc.GetBalances(r.Context(), unique)
+		if writeUnavailable(w, err) {
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+		for address, normalized := range stored {
+			if balance, ok := balances[normalized]; ok {
+				amount := numberAmount(balance)
+				resp[address] = &amount
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+			return
+		}
+	}
+}
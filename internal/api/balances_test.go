@@ -0,0 +1,94 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	addr "payment-system/pkg/address"
+)
+
+// TestGetBalances проверяет POST /api/wallets/balances: ключ есть для каждого запрошенного адреса,
+// у неизвестного кошелька значение null, адрес с контрольной суммой возвращается в исходной записи.
+func TestGetBalances(t *testing.T) {
+	env := newTestEnv(t, nil)
+	rich := env.wallet(t, "100.5")
+	empty := env.wallet(t, "0")
+	const unknown = "3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c"
+	checksummed := addr.Checksum(rich)
+
+	tests := []struct {
+		name      string
+		addresses []string
+		want      map[string]string // Адрес - баланс в записи JSON
+	}{
+		{"known", []string{rich, empty}, map[string]string{rich: "100.5", empty: "0"}},
+		{"unknown is null", []string{rich, unknown}, map[string]string{rich: "100.5", unknown: "null"}},
+		{"duplicates", []string{rich, rich}, map[string]string{rich: "100.5"}},
+		{"checksum address", []string{checksummed, rich}, map[string]string{checksummed: "100.5", rich: "100.5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string][]string{"addresses": tt.addresses})
+			for _, target := range []string{"/api/wallets/balances", "/api/v1/wallets/balances"} {
+				rec := env.do(t, "POST", target, string(body))
+				if rec.Code != http.StatusOK {
+					t.Fatalf("POST %s: status = %d, want 200; body: %s", target, rec.Code, rec.Body)
+				}
+				var got map[string]json.RawMessage
+				if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+					t.Fatalf("decode %s: %v", rec.Body, err)
+				}
+				if len(got) != len(tt.want) {
+					t.Errorf("POST %s: %d keys, want %d: %s", target, len(got), len(tt.want), rec.Body)
+				}
+				for address, want := range tt.want {
+					if string(got[address]) != want {
+						t.Errorf("POST %s: balance of %s = %s, want %s", target, address, got[address], want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGetBalancesInvalid(t *testing.T) {
+	env := newTestEnv(t, nil)
+	wallet := env.wallet(t, "1")
+	// Опечатка в регистре одной буквы записи с контрольной суммой; адрес постоянный, чтобы
+	// опечатка не превратила запись в адрес в одном регистре, который контрольную сумму не проверяет
+	badChecksum := []byte(addr.Checksum("0f3a9c0f3a9c0f3a9c0f3a9c0f3a9c0f3a9c0f3a9c0f3a9c0f3a9c0f3a9c0f3a"))
+	if i := bytes.IndexAny(badChecksum, "abcdefABCDEF"); i >= 0 {
+		badChecksum[i] ^= 'a' - 'A'
+	}
+	tooMany := make([]string, maxBalanceAddresses+1)
+	for i := range tooMany {
+		tooMany[i] = wallet
+	}
+	tooManyBody, _ := json.Marshal(map[string][]string{"addresses": tooMany})
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode string
+	}{
+		{"empty list", `{"addresses":[]}`, "invalid_request"},
+		{"missing list", `{}`, "invalid_request"},
+		{"too many", string(tooManyBody), "invalid_request"},
+		{"malformed address", `{"addresses":["` + wallet + `","xyz"]}`, "invalid_request"},
+		{"bad checksum", `{"addresses":["` + string(badChecksum) + `"]}`, "invalid_address_checksum"},
+		{"not json", `addresses`, "invalid_request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(t, "POST", "/api/wallets/balances", tt.body)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400; body: %s", rec.Code, rec.Body)
+			}
+			if got := jsonPath(t, rec.Body.Bytes(), "error", "code"); got != `"`+tt.wantCode+`"` {
+				t.Errorf("error code = %s, want %q", got, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestRequestBodyLimit проверяет, что тела, которые читаются целиком, ограничены
+// maxRequestBodyBytes и слишком большое тело получает 413, а не читается в память.
+func TestRequestBodyLimit(t *testing.T) {
+	env := newTestEnv(t, nil)
+	from := env.wallet(t, "100")
+	to := env.wallet(t, "0")
+	// Корректный JSON: без предела тело было бы прочитано и отклонено уже по содержимому
+	oversized := `{"from":"` + from + `","to":"` + to + `","amount":1,"memo":"` + strings.Repeat("a", maxRequestBodyBytes) + `"}`
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		target  string
+		header  []string
+	}{
+		{"legacy send", env.router, "/api/send", nil},
+		{"v1 send", env.router, "/api/v1/send", nil},
+		{"send with idempotency key", env.router, "/api/v1/send", []string{IdempotencyKeyHeader, "key-1"}},
+		{"create wallet", CreateWalletHandler(env.svc), "/api/admin/wallets", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(t, tt.handler, "POST", tt.target, oversized, tt.header...)
+			if rec.Code != http.StatusRequestEntityTooLarge {
+				t.Fatalf("status = %d, want 413; body: %.200s", rec.Code, rec.Body)
+			}
+			if got := jsonPath(t, rec.Body.Bytes(), "error", "code"); got != `"request_too_large"` {
+				t.Errorf("error code = %s, want \"request_too_large\"", got)
+			}
+		})
+	}
+	if balance, err := env.svc.GetBalance(context.Background(), from); err != nil || balance.String() != "100" {
+		t.Errorf("sender balance = %s, %v, want 100", balance, err)
+	}
+}
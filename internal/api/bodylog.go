@@ -0,0 +1,135 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"payment-system/internal/logging"
+)
+
+// redactedValue заменяет значения скрываемых полей в журнале.
+const redactedValue = "[REDACTED]"
+
+// BodyLogConfig - настройки журналирования тел запросов для отладки.
+type BodyLogConfig struct {
+	// Enabled включает журналирование. По умолчанию выключено.
+	Enabled bool
+
+	// MaxBytes - сколько байт тела запроса сохраняется для журнала.
+	MaxBytes int
+
+	// RedactFields - имена полей JSON (без учета регистра), значения которых заменяются
+	// на [REDACTED] на любом уровне вложенности.
+	RedactFields []string
+}
+
+// BodyLogMiddleware записывает в журнал тело запроса, если ответ не 2xx, чтобы можно было
+// воспроизвести запрос партнера, завершившийся ошибкой. Тело копируется по мере чтения
+// обработчиком (обработчик получает его полностью), сохраняются первые cfg.MaxBytes байт.
+// Значения полей из cfg.RedactFields скрываются; тело, которое не удалось разобрать как JSON
+// (в том числе обрезанное), не записывается, так как скрыть поля в нем нельзя.
+//
+// Если cfg.Enabled ложно, запросы передаются обработчику без изменений.
+//
+// Параметры:
+//   - cfg: Настройки журналирования.
+//
+// Пример использования:
+//
+//	handler := BodyLogMiddleware(cfg)(router)
+func BodyLogMiddleware(cfg BodyLogConfig) func(http.Handler) http.Handler {
+	redact := make(map[string]bool, len(cfg.RedactFields))
+	for _, field := range cfg.RedactFields {
+		redact[strings.ToLower(field)] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		if !cfg.Enabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			captured := &limitedBuffer{limit: cfg.MaxBytes}
+			if r.Body != nil {
+				r.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.TeeReader(r.Body, captured), r.Body}
+			}
+
+			rec := newResponseWriter(w)
+			next.ServeHTTP(rec, r)
+
+			if rec.status >= 200 && rec.status < 300 {
+				return
+			}
+			logging.FromContext(r.Context()).Info("failed request body",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", rec.status,
+				"body", redactBody(captured, redact),
+			)
+		})
+	}
+}
+
+// redactBody возвращает тело запроса для журнала со скрытыми значениями полей.
+func redactBody(captured *limitedBuffer, redact map[string]bool) string {
+	if captured.buf.Len() == 0 {
+		return "<пусто>"
+	}
+	if captured.truncated {
+		return fmt.Sprintf("<не записано: тело длиннее %d байт>", captured.limit)
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(captured.buf.Bytes(), &doc); err != nil {
+		return fmt.Sprintf("<не записано: не JSON, %d байт>", captured.buf.Len())
+	}
+	data, err := json.Marshal(redactValue(doc, redact))
+	if err != nil {
+		return fmt.Sprintf("<не записано: %v>", err)
+	}
+	return string(data)
+}
+
+// redactValue рекурсивно заменяет значения скрываемых полей.
+func redactValue(v interface{}, redact map[string]bool) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if redact[strings.ToLower(key)] {
+				v[key] = redactedValue
+			} else {
+				v[key] = redactValue(value, redact)
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = redactValue(value, redact)
+		}
+	}
+	return v
+}
+
+// limitedBuffer сохраняет первые limit байт записанных данных и отмечает, что данные обрезаны.
+// Запись никогда не завершается ошибкой, чтобы не прерывать чтение тела обработчиком.
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+// Write сохраняет данные в пределах лимита.
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); len(p) > room {
+		b.buf.Write(p[:max(room, 0)])
+		b.truncated = true
+	} else {
+		b.buf.Write(p)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"payment-system/internal/logging"
+)
+
+func TestBodyLogMiddleware(t *testing.T) {
+	enabled := BodyLogConfig{Enabled: true, MaxBytes: 256, RedactFields: []string{"signature", "Private_Key"}}
+	long := `{"memo":"` + strings.Repeat("a", 300) + `"}`
+
+	tests := []struct {
+		name     string
+		cfg      BodyLogConfig
+		status   int
+		body     string
+		wantLog  bool
+		wantBody string // Тело в записи журнала
+		hidden   []string
+	}{
+		{
+			name: "success is never logged", cfg: enabled, status: http.StatusOK,
+			body: `{"from":"a","signature":"secret"}`,
+		},
+		{
+			name: "created is never logged", cfg: enabled, status: http.StatusCreated,
+			body: `{"from":"a"}`,
+		},
+		{
+			name: "error body with redaction", cfg: enabled, status: http.StatusBadRequest,
+			body:    `{"from":"a","SIGNATURE":"secret","keys":[{"private_key":"hidden","id":1}]}`,
+			wantLog: true, wantBody: `{"SIGNATURE":"[REDACTED]","from":"a","keys":[{"id":1,"private_key":"[REDACTED]"}]}`,
+			hidden: []string{"secret", "hidden"},
+		},
+		{
+			name: "server error", cfg: enabled, status: http.StatusInternalServerError,
+			body: `{"amount":1}`, wantLog: true, wantBody: `{"amount":1}`,
+		},
+		{
+			name: "truncated body is not written", cfg: enabled, status: http.StatusBadRequest,
+			body: long, wantLog: true, wantBody: "<не записано: тело длиннее 256 байт>",
+			hidden: []string{"aaaa"},
+		},
+		{
+			name: "non-json body is not written", cfg: enabled, status: http.StatusBadRequest,
+			body: "signature=secret", wantLog: true, wantBody: "<не записано: не JSON, 16 байт>",
+			hidden: []string{"secret"},
+		},
+		{
+			name: "disabled by default", cfg: BodyLogConfig{}, status: http.StatusBadRequest,
+			body: `{"from":"a"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Обработчик читает тело целиком и отвечает заданным кодом
+			var read string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				data, err := io.ReadAll(r.Body)
+				if err != nil {
+					t.Errorf("read body: %v", err)
+				}
+				read = string(data)
+				w.WriteHeader(tt.status)
+			})
+
+			var logs bytes.Buffer
+			ctx := logging.WithContext(context.Background(), slog.New(slog.NewJSONHandler(&logs, nil)))
+			req := httptest.NewRequest("POST", "/api/send", strings.NewReader(tt.body)).WithContext(ctx)
+			BodyLogMiddleware(tt.cfg)(next).ServeHTTP(httptest.NewRecorder(), req)
+
+			if read != tt.body {
+				t.Errorf("handler read %d bytes, want the full body of %d bytes", len(read), len(tt.body))
+			}
+			if !tt.wantLog {
+				if logs.Len() != 0 {
+					t.Errorf("unexpected log entry: %s", logs.String())
+				}
+				return
+			}
+
+			var entry struct {
+				Msg    string `json:"msg"`
+				Status int    `json:"status"`
+				Body   string `json:"body"`
+			}
+			if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+				t.Fatalf("decode log %q: %v", logs.String(), err)
+			}
+			if entry.Msg != "failed request body" || entry.Status != tt.status {
+				t.Errorf("log entry = %+v, want failed request body with status %d", entry, tt.status)
+			}
+			if entry.Body != tt.wantBody {
+				t.Errorf("logged body = %s, want %s", entry.Body, tt.wantBody)
+			}
+			for _, secret := range tt.hidden {
+				if strings.Contains(logs.String(), secret) {
+					t.Errorf("log contains %q: %s", secret, logs.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ConfigReload - результат перечитывания настроек без перезапуска.
+type ConfigReload struct {
+	// Applied - примененные изменения: переменная и новое значение.
+	Applied map[string]string `json:"applied"`
+
+	// Ignored - измененные переменные, которые применяются только при перезапуске.
+	Ignored []string `json:"ignored"`
+}
+
+// ConfigReloadHandler возвращает HTTP-обработчик POST /api/admin/config/reload, который
+// перечитывает файл настроек так же, как сигнал SIGHUP.
+//
+// Параметры:
+//   - reload: Функция, перечитывающая и применяющая настройки.
+//
+// Возвращает:
+//   - HTTP-обработчик: 200 с ConfigReload или 400 "invalid_config", если файл не прочитан
+//     или значение некорректно (тогда ничего не применяется).
+//
+// Пример использования:
+//
+//	router.Handle("/api/admin/config/reload", admin(ConfigReloadHandler(reloader.reload))).Methods("POST")
+func ConfigReloadHandler(reload func() (ConfigReload, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, err := reload()
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_config", err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
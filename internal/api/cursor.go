@@ -0,0 +1,47 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	db "payment-system/internal/db"
+)
+
+// NextCursorHeader - заголовок ответа списка транзакций с курсором следующей страницы.
+// Передается, только если страница заполнена целиком (дальше могут быть еще транзакции).
+const NextCursorHeader = "X-Next-Cursor"
+
+// encodeCursor кодирует позицию в списке транзакций для параметра cursor.
+// Клиент не должен разбирать значение: формат может измениться.
+func encodeCursor(cursor db.TransactionCursor) string {
+	raw := cursor.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + strconv.Itoa(cursor.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor разбирает значение параметра cursor, полученное из заголовка X-Next-Cursor.
+//
+// Возвращает:
+//   - Позицию в списке транзакций.
+//   - Ошибку, если значение не получено от encodeCursor.
+func decodeCursor(value string) (db.TransactionCursor, error) {
+	invalid := errors.New("Parameter 'cursor' must be a value of the X-Next-Cursor header")
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return db.TransactionCursor{}, invalid
+	}
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return db.TransactionCursor{}, invalid
+	}
+	cursor := db.TransactionCursor{}
+	if cursor.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return db.TransactionCursor{}, invalid
+	}
+	if cursor.ID, err = strconv.Atoi(id); err != nil || cursor.ID <= 0 {
+		return db.TransactionCursor{}, invalid
+	}
+	return cursor, nil
+}
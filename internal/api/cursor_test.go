@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	db "payment-system/internal/db"
+	models "payment-system/internal/models"
+	service "payment-system/internal/service"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursors := []db.TransactionCursor{
+		{CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), ID: 1},
+		{CreatedAt: time.Date(2024, 5, 1, 12, 30, 15, 123000000, time.UTC), ID: 987654},
+		{CreatedAt: time.Date(2024, 5, 1, 15, 30, 15, 0, time.FixedZone("MSK", 3*3600)), ID: 42},
+	}
+	for _, cursor := range cursors {
+		got, err := decodeCursor(encodeCursor(cursor))
+		if err != nil {
+			t.Fatalf("decodeCursor(encodeCursor(%+v)): %v", cursor, err)
+		}
+		if !got.CreatedAt.Equal(cursor.CreatedAt) || got.ID != cursor.ID {
+			t.Errorf("round trip of %+v = %+v", cursor, got)
+		}
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	handler := GetLastHandler(service.NewService(db.NewMemoryRepository()), 100)
+	for _, value := range []string{
+		"not base64!",
+		"MjAyNC0wMS0wMVQwMDowMDowMFo",      // "2024-01-01T00:00:00Z" без id
+		"MjAyNC0wMS0wMVQwMDowMDowMFp8MA",   // id 0
+		"MjAyNC0wMS0wMVQwMDowMDowMFp8LTE",  // id -1
+		"eWVzdGVyZGF5fDE",                  // "yesterday|1"
+		"MjAyNC0wMS0wMVQwMDowMDowMFp8MQ==", // Стандартный base64 с дополнением
+	} {
+		if _, err := decodeCursor(value); err == nil {
+			t.Errorf("decodeCursor(%q): want error", value)
+		}
+		rec := serve(t, handler, "GET", "/api/transactions?cursor="+url.QueryEscape(value), "")
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "cursor") {
+			t.Errorf("GET with cursor %q: status = %d; body: %s", value, rec.Code, rec.Body)
+		}
+	}
+}
+
+// TestTransactionsCursorWalk обходит страницами по заголовку X-Next-Cursor транзакции
+// с одинаковым временем, пока параллельно выполняются новые переводы: каждая транзакция,
+// существовавшая до начала обхода, встречается ровно один раз.
+func TestTransactionsCursorWalk(t *testing.T) {
+	repos := map[string]func(t *testing.T) db.Repository{
+		"memory": func(t *testing.T) db.Repository { return db.NewMemoryRepository() },
+		"sqlite": func(t *testing.T) db.Repository {
+			return db.NewSQLiteRepository(filepath.Join(t.TempDir(), "payment-system.db"))
+		},
+	}
+	for name, newRepo := range repos {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			svc := service.NewService(newRepo(t))
+			var addresses []string
+			for range 2 {
+				wallet, _, err := svc.CreateWallet(ctx, decimal.NewFromInt(1000), models.WalletMetadata{})
+				if err != nil {
+					t.Fatalf("CreateWallet: %v", err)
+				}
+				addresses = append(addresses, wallet.Address)
+			}
+
+			// 45 транзакций в одну миллисекунду и 5 - секундой позже
+			moment := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			var batch []models.Transaction
+			for i := 0; i < 50; i++ {
+				createdAt := moment
+				if i >= 45 {
+					createdAt = moment.Add(time.Second)
+				}
+				batch = append(batch, models.Transaction{From: addresses[0], To: addresses[1], Amount: decimal.NewFromInt(1),
+					CreatedAt: createdAt, ExternalID: fmt.Sprintf("walk-%d", i)})
+			}
+			if imported, err := svc.ImportTransactions(ctx, batch); err != nil || imported != len(batch) {
+				t.Fatalf("ImportTransactions: imported %d, err %v", imported, err)
+			}
+
+			handler := GetLastHandler(svc, 100)
+			page := func(cursor string) ([]models.Transaction, string) {
+				target := "/api/transactions?count=7"
+				if cursor != "" {
+					target += "&cursor=" + url.QueryEscape(cursor)
+				}
+				rec := serve(t, handler, "GET", target, "")
+				if rec.Code != http.StatusOK {
+					t.Fatalf("GET %s: status = %d; body: %s", target, rec.Code, rec.Body)
+				}
+				var transactions []models.Transaction
+				if err := json.Unmarshal(rec.Body.Bytes(), &transactions); err != nil {
+					t.Fatalf("decode %s: %v", rec.Body, err)
+				}
+				return transactions, rec.Header().Get(NextCursorHeader)
+			}
+
+			seen := make(map[int]int)
+			transactions, cursor := page("")
+			for _, tx := range transactions {
+				seen[tx.ID]++
+			}
+
+			// Новые переводы во время обхода попадают в начало списка, до курсора
+			stop := make(chan struct{})
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					if _, err := svc.Send(ctx, addresses[1], addresses[0], decimal.NewFromInt(1), "", "", nil, sql.LevelDefault); err != nil {
+						t.Errorf("concurrent Send: %v", err)
+						return
+					}
+				}
+			}()
+			for pages := 1; cursor != ""; pages++ {
+				if pages > len(batch) {
+					t.Fatal("paging does not end")
+				}
+				transactions, cursor = page(cursor)
+				for _, tx := range transactions {
+					seen[tx.ID]++
+				}
+			}
+			close(stop)
+			wg.Wait()
+
+			if len(seen) != len(batch) {
+				t.Errorf("walk returned %d distinct transactions, want %d", len(seen), len(batch))
+			}
+			for id, n := range seen {
+				if n != 1 {
+					t.Errorf("transaction %d returned %d times", id, n)
+				}
+			}
+		})
+	}
+}
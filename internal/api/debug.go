@@ -0,0 +1,90 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"payment-system/internal/metrics"
+)
+
+// debugVars - ответ /debug/vars: состояние рантайма Go и пулов подключений к базе.
+type debugVars struct {
+	Goroutines int                    `json:"goroutines"`
+	GC         debugGC                `json:"gc"`
+	Memory     debugMemory            `json:"memory"`
+	DBPools    map[string]sql.DBStats `json:"db_pools"`
+}
+
+// debugGC - статистика сборщика мусора.
+type debugGC struct {
+	NumGC         uint32    `json:"num_gc"`
+	PauseTotalNs  uint64    `json:"pause_total_ns"`
+	LastGC        time.Time `json:"last_gc"`
+	GCCPUFraction float64   `json:"gc_cpu_fraction"`
+}
+
+// debugMemory - основные показатели памяти процесса в байтах.
+type debugMemory struct {
+	HeapAlloc   uint64 `json:"heap_alloc"`
+	HeapInuse   uint64 `json:"heap_inuse"`
+	HeapObjects uint64 `json:"heap_objects"`
+	Sys         uint64 `json:"sys"`
+}
+
+// DebugHandler возвращает обработчик отладочных маршрутов: профилировщик net/http/pprof
+// под /debug/pprof/ и состояние рантайма под /debug/vars.
+// Маршруты не регистрируются на основном маршрутизаторе, а обслуживаются отдельным сервером
+// на адресе DEBUG_ADDR, доступном только локально; без DEBUG_ADDR их нет вовсе.
+//
+// Пример использования:
+//
+//	server := &http.Server{Addr: "127.0.0.1:6060", Handler: DebugHandler()}
+func DebugHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/vars", DebugVarsHandler())
+	return mux
+}
+
+// DebugVarsHandler возвращает HTTP-обработчик, отдающий в JSON количество горутин,
+// статистику сборщика мусора и пулов подключений к базе (sql.DBStats по ролям).
+//
+// Пример использования:
+//
+//	mux.HandleFunc("/debug/vars", DebugVarsHandler())
+func DebugVarsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+
+		vars := debugVars{
+			Goroutines: runtime.NumGoroutine(),
+			GC: debugGC{
+				NumGC:         m.NumGC,
+				PauseTotalNs:  m.PauseTotalNs,
+				GCCPUFraction: m.GCCPUFraction,
+			},
+			Memory: debugMemory{
+				HeapAlloc:   m.HeapAlloc,
+				HeapInuse:   m.HeapInuse,
+				HeapObjects: m.HeapObjects,
+				Sys:         m.Sys,
+			},
+			DBPools: metrics.DBPoolStats(),
+		}
+		if m.LastGC > 0 {
+			vars.GC.LastGC = time.Unix(0, int64(m.LastGC)).UTC()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(vars)
+	}
+}
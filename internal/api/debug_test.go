@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// TestDebugRoutesAbsent проверяет, что в конфигурации по умолчанию (без DEBUG_ADDR)
+// профилировщик и /debug/vars на основном маршрутизаторе отсутствуют.
+func TestDebugRoutesAbsent(t *testing.T) {
+	env := newTestEnv(t, nil)
+	for _, target := range []string{
+		"/debug/pprof/",
+		"/debug/pprof/cmdline",
+		"/debug/pprof/profile?seconds=1",
+		"/debug/pprof/heap",
+		"/debug/vars",
+	} {
+		rec := env.do(t, "GET", target, "")
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s: status = %d, want 404; body: %s", target, rec.Code, rec.Body)
+		}
+	}
+}
+
+// TestDebugHandler проверяет маршруты отладочного сервера.
+func TestDebugHandler(t *testing.T) {
+	h := DebugHandler()
+
+	rec := serve(t, h, "GET", "/debug/pprof/", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /debug/pprof/: status = %d, want 200", rec.Code)
+	}
+
+	rec = serve(t, h, "GET", "/debug/vars", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /debug/vars: status = %d, want 200; body: %s", rec.Code, rec.Body)
+	}
+	var vars debugVars
+	if err := json.Unmarshal(rec.Body.Bytes(), &vars); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	if vars.Goroutines < 1 {
+		t.Errorf("goroutines = %d, want at least 1", vars.Goroutines)
+	}
+	if vars.Memory.Sys == 0 {
+		t.Errorf("memory.sys = 0; body: %s", rec.Body)
+	}
+}
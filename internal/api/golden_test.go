@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	db "payment-system/internal/db"
+	models "payment-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// update перезаписывает эталонные ответы: go test ./internal/api -run TestLegacyGolden -update.
+// Перезаписывать эталон можно только при намеренном изменении устаревшего формата.
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// Адреса кошельков эталонных ответов; фиксированы, чтобы ответы не менялись от запуска к запуску.
+var (
+	goldenSender   = strings.Repeat("1a", 32)
+	goldenReceiver = strings.Repeat("2b", 32)
+	goldenMissing  = strings.Repeat("3c", 32)
+)
+
+// goldenHeaders - заголовки ответа, которые входят в эталон.
+var goldenHeaders = []string{"Content-Type", "Deprecation", "Link", "Vary"}
+
+// newGoldenEnv возвращает окружение с фиксированными часами, двумя кошельками и одним переводом.
+func newGoldenEnv(t *testing.T) *testEnv {
+	t.Helper()
+	env := newTestEnv(t, nil)
+	env.repo.SetClock(db.NewFixedClock(time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)))
+	ctx := context.Background()
+	for address, balance := range map[string]string{goldenSender: "100.5", goldenReceiver: "0"} {
+		metadata := models.WalletMetadata{}
+		if address == goldenReceiver {
+			metadata.Label = "ops-float"
+		}
+		if err := env.repo.CreateWallet(ctx, address, decimal.RequireFromString(balance), metadata, ""); err != nil {
+			t.Fatalf("CreateWallet: %v", err)
+		}
+	}
+	env.send(t, goldenSender, goldenReceiver, "10.25")
+	return env
+}
+
+// TestLegacyGolden побайтно сравнивает ответы устаревших маршрутов /api с эталонами
+// в testdata/legacy: от этого формата зависят существующие клиенты, и он не должен
+// меняться вместе с v1.
+func TestLegacyGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		header []string
+	}{
+		{"transactions", "GET", "/api/transactions", "", nil},
+		{"transactions_strings", "GET", "/api/transactions", "", []string{AmountFormatHeader, "string"}},
+		{"transactions_invalid_count", "GET", "/api/transactions?count=abc", "", nil},
+		{"transactions_count", "GET", "/api/transactions/count", "", nil},
+		{"balance", "GET", "/api/wallet/" + goldenSender + "/balance", "", nil},
+		{"balance_strings", "GET", "/api/wallet/" + goldenSender + "/balance", "", []string{AmountFormatHeader, "string"}},
+		{"balance_not_found", "GET", "/api/wallet/" + goldenMissing + "/balance", "", nil},
+		{"balances", "POST", "/api/wallets/balances", `{"addresses":["` + goldenReceiver + `","` + goldenMissing + `"]}`, nil},
+		{"wallets_by_label", "GET", "/api/wallets?label=ops-float", "", nil},
+		{"sendable", "GET", "/api/wallet/" + goldenSender + "/sendable", "", nil},
+		{"nonce", "GET", "/api/wallet/" + goldenSender + "/nonce", "", nil},
+		{"send", "POST", "/api/send", `{"from":"` + goldenSender + `","to":"` + goldenReceiver + `","amount":0.25}`, nil},
+		{"send_insufficient_funds", "POST", "/api/send", `{"from":"` + goldenSender + `","to":"` + goldenReceiver + `","amount":1000}`, nil},
+		{"send_invalid_json", "POST", "/api/send", `{"from":`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newGoldenEnv(t)
+			rec := env.do(t, tt.method, tt.target, tt.body, tt.header...)
+			got := goldenResponse(rec)
+
+			path := filepath.Join("testdata", "legacy", tt.name+".golden")
+			if *update {
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatalf("write golden file: %v", err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read golden file (run with -update to create it): %v", err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("%s %s response changed.\ngot:\n%s\nwant:\n%s", tt.method, tt.target, got, want)
+			}
+		})
+	}
+}
+
+// goldenResponse записывает код ответа, заголовки goldenHeaders и тело без изменений.
+func goldenResponse(rec *httptest.ResponseRecorder) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d\n", rec.Code)
+	for _, name := range goldenHeaders {
+		for _, value := range rec.Header().Values(name) {
+			fmt.Fprintf(&b, "%s: %s\n", name, value)
+		}
+	}
+	b.WriteString("\n")
+	b.WriteString(rec.Body.String())
+	return []byte(b.String())
+}
@@ -0,0 +1,348 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	db "payment-system/internal/db"
+	errs "payment-system/internal/errs"
+	"payment-system/internal/graphql"
+	models "payment-system/internal/models"
+	service "payment-system/internal/service"
+
+	"github.com/shopspring/decimal"
+)
+
+// Ограничения запросов GraphQL по умолчанию (GRAPHQL_MAX_DEPTH, GRAPHQL_MAX_COMPLEXITY).
+const (
+	DefaultGraphQLMaxDepth      = 5
+	DefaultGraphQLMaxComplexity = 1000
+)
+
+// maxGraphQLBodyBytes ограничивает размер тела запроса GraphQL.
+const maxGraphQLBodyBytes = 1 << 20
+
+// graphQLLoaderKey - ключ контекста запроса, под которым резолверы находят загрузчик
+// транзакций кошельков этого запроса.
+type graphQLLoaderKey struct{}
+
+// walletTransactionsLoader собирает кошельки, транзакции которых запрошены на одном уровне
+// запроса GraphQL, и загружает их одним запросом к хранилищу (svc.GetWalletTransactions),
+// а не отдельным запросом на каждый кошелек. Создается на каждый запрос GraphQL; выполнитель
+// вызывает резолверы в одной горутине, поэтому блокировка не нужна.
+type walletTransactionsLoader struct {
+	svc     *service.Service
+	pending map[int][]string // Адреса, ожидающие загрузки, по количеству транзакций
+	loaded  map[int]map[string][]models.Transaction
+	errors  map[int]error
+}
+
+// newWalletTransactionsLoader создает загрузчик транзакций кошельков для одного запроса.
+func newWalletTransactionsLoader(svc *service.Service) *walletTransactionsLoader {
+	return &walletTransactionsLoader{
+		svc:     svc,
+		pending: make(map[int][]string),
+		loaded:  make(map[int]map[string][]models.Transaction),
+		errors:  make(map[int]error),
+	}
+}
+
+// load откладывает загрузку последних limit транзакций кошелька: первый вызов возвращенного
+// Thunk загружает транзакции всех кошельков, собранных к этому моменту с тем же limit.
+func (l *walletTransactionsLoader) load(ctx context.Context, address string, limit int) graphql.Thunk {
+	if !slices.Contains(l.pending[limit], address) {
+		l.pending[limit] = append(l.pending[limit], address)
+	}
+	return func() (interface{}, error) {
+		if addresses := l.pending[limit]; len(addresses) > 0 {
+			delete(l.pending, limit)
+			transactions, err := l.svc.GetWalletTransactions(ctx, addresses, limit)
+			if err != nil {
+				l.errors[limit] = err
+			}
+			if l.loaded[limit] == nil {
+				l.loaded[limit] = make(map[string][]models.Transaction)
+			}
+			for _, address := range addresses {
+				l.loaded[limit][address] = transactions[address]
+			}
+		}
+		if err := l.errors[limit]; err != nil {
+			return nil, err
+		}
+		return l.loaded[limit][address], nil
+	}
+}
+
+// newGraphQLSchema возвращает корневой тип Query схемы GraphQL:
+//
+//	type Query {
+//	  wallet(address: String!): Wallet
+//	  transactions(filter: TransactionFilter, limit: Int = 20): [Transaction!]!
+//	  stats(range: DateRange): [CategoryVolume!]!
+//	}
+//	type Wallet {
+//	  address: String!  balance: String!  label: String  tags: JSON  archived: Boolean!
+//	  transactions(limit: Int = 20): [Transaction!]!
+//	}
+//	type Transaction {
+//	  id: Int!  from: String!  to: String!  amount: String!  createdAt: String!  memo: String
+//	  category: String  externalId: String  imported: Boolean!  type: String!  cursor: String!
+//	}
+//	type CategoryVolume { day: String!  category: String!  count: Int!  volume: String! }
+//	input TransactionFilter { amount: String  category: String  includeArchived: Boolean  after: String }
+//	input DateRange { from: String  to: String }
+//
+// Суммы передаются строками, как в ответах v1; limit ограничен maxCount.
+func newGraphQLSchema(svc *service.Service, maxCount int) *graphql.Type {
+	// limitArg читает аргумент limit списка транзакций; значения больше maxCount уменьшаются до него
+	limitArg := func(args graphql.Args) (int, error) {
+		limit, err := args.Int("limit", DefaultTransactionsCount)
+		if err != nil {
+			return 0, err
+		}
+		if limit <= 0 {
+			return 0, fmt.Errorf("argument \"limit\" must be a positive integer, got %d", limit)
+		}
+		return min(limit, maxCount), nil
+	}
+	limitCost := func(args graphql.Args) int {
+		limit, _ := limitArg(args)
+		return limit
+	}
+
+	transaction := &graphql.Type{Name: "Transaction", Fields: map[string]*graphql.Field{
+		"id":         transactionField(func(t models.Transaction) interface{} { return t.ID }),
+		"from":       transactionField(func(t models.Transaction) interface{} { return t.From }),
+		"to":         transactionField(func(t models.Transaction) interface{} { return t.To }),
+		"amount":     transactionField(func(t models.Transaction) interface{} { return formatAmount(t.Amount, -1) }),
+		"createdAt":  transactionField(func(t models.Transaction) interface{} { return t.CreatedAt.UTC().Format(time.RFC3339) }),
+		"memo":       transactionField(func(t models.Transaction) interface{} { return optionalString(t.Memo) }),
+		"category":   transactionField(func(t models.Transaction) interface{} { return optionalString(t.Category) }),
+		"externalId": transactionField(func(t models.Transaction) interface{} { return optionalString(t.ExternalID) }),
+		"imported":   transactionField(func(t models.Transaction) interface{} { return t.Imported }),
+		"type":       transactionField(func(t models.Transaction) interface{} { return t.Type }),
+		"cursor":     transactionField(func(t models.Transaction) interface{} { return encodeCursor(db.CursorOf(t)) }),
+	}}
+
+	wallet := &graphql.Type{Name: "Wallet", Fields: map[string]*graphql.Field{
+		"address":  walletField(func(w models.Wallet) interface{} { return w.Address }),
+		"balance":  walletField(func(w models.Wallet) interface{} { return formatAmount(w.Balance, -1) }),
+		"label":    walletField(func(w models.Wallet) interface{} { return optionalString(w.Label) }),
+		"tags":     walletField(func(w models.Wallet) interface{} { return w.Tags }),
+		"archived": walletField(func(w models.Wallet) interface{} { return w.ArchivedAt != nil }),
+		"transactions": {
+			Type: transaction, List: true, Args: []string{"limit"}, Cost: limitCost,
+			Resolve: func(ctx context.Context, source interface{}, args graphql.Args) (interface{}, error) {
+				limit, err := limitArg(args)
+				if err != nil {
+					return nil, err
+				}
+				loader := ctx.Value(graphQLLoaderKey{}).(*walletTransactionsLoader)
+				return loader.load(ctx, source.(models.Wallet).Address, limit), nil
+			},
+		},
+	}}
+
+	categoryVolume := &graphql.Type{Name: "CategoryVolume", Fields: map[string]*graphql.Field{
+		"day":      volumeField(func(v db.CategoryVolume) interface{} { return v.Day.Format(statsDayLayout) }),
+		"category": volumeField(func(v db.CategoryVolume) interface{} { return v.Category }),
+		"count":    volumeField(func(v db.CategoryVolume) interface{} { return v.Count }),
+		"volume":   volumeField(func(v db.CategoryVolume) interface{} { return v.Volume.String() }),
+	}}
+
+	return &graphql.Type{Name: "Query", Fields: map[string]*graphql.Field{
+		"wallet": {
+			Type: wallet, Args: []string{"address"},
+			Resolve: func(ctx context.Context, source interface{}, args graphql.Args) (interface{}, error) {
+				value, ok, err := args.String("address")
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
+					return nil, errors.New("argument \"address\" is required")
+				}
+				address, err := parseAddress(value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid wallet address: %w", err)
+				}
+				w, err := svc.GetWallet(ctx, address)
+				if errors.Is(err, errs.ErrWalletNotFound) {
+					return nil, nil
+				}
+				if err != nil {
+					return nil, err
+				}
+				// Адрес для уведомлений - персональные данные владельца, как и в поиске по метке
+				w.NotifyEmail = ""
+				return w, nil
+			},
+		},
+		"transactions": {
+			Type: transaction, List: true, Args: []string{"filter", "limit"}, Cost: limitCost,
+			Resolve: func(ctx context.Context, source interface{}, args graphql.Args) (interface{}, error) {
+				limit, err := limitArg(args)
+				if err != nil {
+					return nil, err
+				}
+				filter, err := graphQLTransactionFilter(args)
+				if err != nil {
+					return nil, err
+				}
+				return svc.GetLastTransactions(ctx, limit, filter)
+			},
+		},
+		"stats": {
+			Type: categoryVolume, List: true, Args: []string{"range"},
+			Resolve: func(ctx context.Context, source interface{}, args graphql.Args) (interface{}, error) {
+				period, err := args.Object("range", "from", "to")
+				if err != nil {
+					return nil, err
+				}
+				fromStr, _, err := period.String("from")
+				if err != nil {
+					return nil, err
+				}
+				toStr, _, err := period.String("to")
+				if err != nil {
+					return nil, err
+				}
+				from, end, err := statsPeriod(fromStr, toStr)
+				if err != nil {
+					return nil, err
+				}
+				return svc.GetCategoryVolumes(ctx, from, end)
+			},
+		},
+	}}
+}
+
+// graphQLTransactionFilter читает аргумент filter списка транзакций: те же условия, что
+// параметры amount, category, include_archived и cursor списка /api/v1/transactions.
+func graphQLTransactionFilter(args graphql.Args) (db.TransactionFilter, error) {
+	input, err := args.Object("filter", "amount", "category", "includeArchived", "after")
+	if err != nil {
+		return db.TransactionFilter{}, err
+	}
+
+	var filter db.TransactionFilter
+	amountStr, ok, err := input.String("amount")
+	if err != nil {
+		return db.TransactionFilter{}, err
+	}
+	if ok {
+		amount, err := decimal.NewFromString(amountStr)
+		if err != nil {
+			return db.TransactionFilter{}, fmt.Errorf("field \"amount\" must be a number, got %q", amountStr)
+		}
+		filter.Amount = &amount
+	}
+	if filter.Category, _, err = input.String("category"); err != nil {
+		return db.TransactionFilter{}, err
+	}
+	if filter.IncludeArchived, err = input.Bool("includeArchived"); err != nil {
+		return db.TransactionFilter{}, err
+	}
+	after, ok, err := input.String("after")
+	if err != nil {
+		return db.TransactionFilter{}, err
+	}
+	if ok {
+		cursor, err := decodeCursor(after)
+		if err != nil {
+			return db.TransactionFilter{}, errors.New("field \"after\" must be a value of the Transaction.cursor field")
+		}
+		filter.After = &cursor
+	}
+	return filter, nil
+}
+
+// optionalString возвращает nil для пустой строки, чтобы необязательное поле было null.
+func optionalString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// transactionField, walletField и volumeField создают скалярные поля типов Transaction,
+// Wallet и CategoryVolume, вычисляемые из значения родительского объекта.
+func transactionField(get func(models.Transaction) interface{}) *graphql.Field {
+	return &graphql.Field{Resolve: func(ctx context.Context, source interface{}, args graphql.Args) (interface{}, error) {
+		return get(source.(models.Transaction)), nil
+	}}
+}
+
+func walletField(get func(models.Wallet) interface{}) *graphql.Field {
+	return &graphql.Field{Resolve: func(ctx context.Context, source interface{}, args graphql.Args) (interface{}, error) {
+		return get(source.(models.Wallet)), nil
+	}}
+}
+
+func volumeField(get func(db.CategoryVolume) interface{}) *graphql.Field {
+	return &graphql.Field{Resolve: func(ctx context.Context, source interface{}, args graphql.Args) (interface{}, error) {
+		return get(source.(db.CategoryVolume)), nil
+	}}
+}
+
+// GraphQLHandler возвращает HTTP-обработчик /api/graphql - запросов GraphQL на чтение для панелей
+// мониторинга, которым иначе нужно несколько запросов REST на экран (схема - в newGraphQLSchema).
+// Запрос передается в теле POST ({"query": ..., "variables": {...}, "operationName": ...})
+// или в параметрах GET query, variables (JSON) и operationName. Ответ - {"data": ..., "errors": [...]}
+// со статусом 200; ошибки отдельных полей возвращаются в errors с путем к полю, остальные поля
+// выполняются. Запрос, не прошедший разбор или проверку (неизвестное поле, глубина больше
+// cfg.GraphQLMaxDepth, сложность больше cfg.GraphQLMaxComplexity), отклоняется с 400 и только errors.
+// Транзакции кошельков одного уровня запроса загружаются одним запросом к хранилищу.
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//   - cfg: Настройки обработчиков: ограничения запроса и MaxTransactionsCount.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.HandleFunc("/api/graphql", GraphQLHandler(svc, cfg)).Methods("GET", "POST")
+func GraphQLHandler(svc *service.Service, cfg RoutesConfig) http.HandlerFunc {
+	schema := newGraphQLSchema(svc, cfg.MaxTransactionsCount)
+	limits := graphql.Limits{MaxDepth: cfg.GraphQLMaxDepth, MaxComplexity: cfg.GraphQLMaxComplexity}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req graphql.Request
+		if r.Method == http.MethodGet {
+			req.Query = r.URL.Query().Get("query")
+			req.OperationName = r.URL.Query().Get("operationName")
+			if variables := r.URL.Query().Get("variables"); variables != "" {
+				dec := json.NewDecoder(strings.NewReader(variables))
+				dec.UseNumber()
+				if err := dec.Decode(&req.Variables); err != nil {
+					writeGraphQLError(w, "Parameter 'variables' must be a JSON object")
+					return
+				}
+			}
+		} else if err := decodeJSONBody(http.MaxBytesReader(w, r.Body, maxGraphQLBodyBytes), &req); err != nil {
+			writeGraphQLError(w, err.Error())
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), graphQLLoaderKey{}, newWalletTransactionsLoader(svc))
+		resp, err := graphql.Execute(ctx, schema, req, limits)
+		if err != nil {
+			writeGraphQLError(w, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// writeGraphQLError отвечает 400 на запрос GraphQL, который не выполнялся, в формате ответа GraphQL.
+func writeGraphQLError(w http.ResponseWriter, message string) {
+	writeJSON(w, http.StatusBadRequest, graphql.Response{Errors: []graphql.Error{{Message: message}}})
+}
@@ -0,0 +1,253 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	db "payment-system/internal/db"
+	models "payment-system/internal/models"
+	service "payment-system/internal/service"
+
+	"github.com/shopspring/decimal"
+)
+
+// countingRepository запоминает аргументы вызовов GetWalletTransactions.
+type countingRepository struct {
+	*db.MemoryRepository
+	calls []walletTransactionsCall
+}
+
+// walletTransactionsCall - аргументы одного вызова GetWalletTransactions.
+type walletTransactionsCall struct {
+	addresses []string
+	count     int
+}
+
+// GetWalletTransactions запоминает вызов и передает его хранилищу в памяти.
+func (r *countingRepository) GetWalletTransactions(ctx context.Context, addresses []string, count int) (map[string][]models.Transaction, error) {
+	r.calls = append(r.calls, walletTransactionsCall{addresses: slices.Sorted(slices.Values(addresses)), count: count})
+	return r.MemoryRepository.GetWalletTransactions(ctx, addresses, count)
+}
+
+// graphQLBody возвращает тело POST-запроса GraphQL.
+func graphQLBody(t *testing.T, query string, variables map[string]interface{}) string {
+	t.Helper()
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return string(body)
+}
+
+// TestGraphQLWalletTransactionsBatched проверяет, что транзакции кошельков одного уровня
+// загружаются одним вызовом хранилища на каждое значение limit, а limit больше
+// MaxTransactionsCount уменьшается до него.
+func TestGraphQLWalletTransactionsBatched(t *testing.T) {
+	repo := &countingRepository{MemoryRepository: db.NewMemoryRepository()}
+	env := &testEnv{repo: repo.MemoryRepository, svc: service.NewService(repo)}
+	addresses := []string{env.wallet(t, "100"), env.wallet(t, "100"), env.wallet(t, "100")}
+	env.send(t, addresses[0], addresses[1], "1")
+	env.send(t, addresses[1], addresses[2], "2")
+	handler := GraphQLHandler(env.svc, RoutesConfig{MaxTransactionsCount: 10})
+
+	var query strings.Builder
+	query.WriteString("{")
+	for i, address := range addresses {
+		fmt.Fprintf(&query, " w%d: wallet(address: %q) { address transactions(limit: 5) { id amount } }", i, address)
+	}
+	query.WriteString(" }")
+
+	rec := serve(t, handler, "POST", "/api/graphql", graphQLBody(t, query.String(), nil))
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), `"errors"`) {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	want := []walletTransactionsCall{{addresses: slices.Sorted(slices.Values(addresses)), count: 5}}
+	if !slices.EqualFunc(repo.calls, want, equalWalletTransactionsCall) {
+		t.Errorf("GetWalletTransactions calls = %+v, want %+v", repo.calls, want)
+	}
+	if got := jsonPath(t, rec.Body.Bytes(), "data", "w1", "transactions", "0", "amount"); got != `"2"` {
+		t.Errorf("w1 newest transaction amount = %s, want \"2\"", got)
+	}
+
+	repo.calls = nil
+	query.Reset()
+	fmt.Fprintf(&query, `{ a: wallet(address: %q) { transactions(limit: 1) { id } }`, addresses[0])
+	fmt.Fprintf(&query, ` b: wallet(address: %q) { transactions(limit: 1000) { id } }`, addresses[1])
+	fmt.Fprintf(&query, ` c: wallet(address: %q) { transactions(limit: 1) { id } } }`, addresses[2])
+	rec = serve(t, handler, "POST", "/api/graphql", graphQLBody(t, query.String(), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	want = []walletTransactionsCall{
+		{addresses: slices.Sorted(slices.Values([]string{addresses[0], addresses[2]})), count: 1},
+		{addresses: []string{addresses[1]}, count: 10},
+	}
+	slices.SortFunc(repo.calls, func(a, b walletTransactionsCall) int { return a.count - b.count })
+	if !slices.EqualFunc(repo.calls, want, equalWalletTransactionsCall) {
+		t.Errorf("GetWalletTransactions calls = %+v, want %+v", repo.calls, want)
+	}
+}
+
+// equalWalletTransactionsCall сравнивает вызовы GetWalletTransactions.
+func equalWalletTransactionsCall(a, b walletTransactionsCall) bool {
+	return a.count == b.count && slices.Equal(a.addresses, b.addresses)
+}
+
+// TestGraphQLLimits проверяет, что запросы глубже GraphQLMaxDepth или сложнее
+// GraphQLMaxComplexity отклоняются с 400 без data, а список транзакций оценивается по
+// limit, уменьшенному до MaxTransactionsCount (без limit - по DefaultTransactionsCount).
+func TestGraphQLLimits(t *testing.T) {
+	env := newTestEnv(t, nil)
+	address := env.wallet(t, "100")
+
+	tests := []struct {
+		name     string
+		query    string
+		maxCount int
+		cfg      RoutesConfig
+		wantErr  string // "" - запрос выполняется
+	}{
+		// 1 + min(1000, 10) * 2 = 21
+		{"limit capped by max count", `{ transactions(limit: 1000) { id amount } }`, 10,
+			RoutesConfig{GraphQLMaxComplexity: 50}, ""},
+		// 1 + min(1000, 100) * 2 = 201
+		{"limit above complexity", `{ transactions(limit: 1000) { id amount } }`, 100,
+			RoutesConfig{GraphQLMaxComplexity: 50}, "query complexity 201 exceeds the limit of 50"},
+		// 1 + 20 * 1 = 21
+		{"default limit", `{ transactions { id } }`, 100,
+			RoutesConfig{GraphQLMaxComplexity: 20}, "query complexity 21 exceeds the limit of 20"},
+		// 1 + (1 + 1 + min(1000, 10) * 1) = 13
+		{"wallet transactions capped", fmt.Sprintf(`{ wallet(address: %q) { address transactions(limit: 1000) { id } } }`, address), 10,
+			RoutesConfig{GraphQLMaxComplexity: 13}, ""},
+		{"wallet transactions above complexity", fmt.Sprintf(`{ wallet(address: %q) { address transactions(limit: 1000) { id } } }`, address), 10,
+			RoutesConfig{GraphQLMaxComplexity: 12}, "query complexity 13 exceeds the limit of 12"},
+		{"depth at limit", fmt.Sprintf(`{ wallet(address: %q) { transactions { id } } }`, address), 100,
+			RoutesConfig{GraphQLMaxDepth: 3}, ""},
+		{"depth above limit", fmt.Sprintf(`{ wallet(address: %q) { transactions { id } } }`, address), 100,
+			RoutesConfig{GraphQLMaxDepth: 2}, "query depth exceeds the limit of 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.MaxTransactionsCount = tt.maxCount
+			rec := serve(t, GraphQLHandler(env.svc, tt.cfg), "POST", "/api/graphql", graphQLBody(t, tt.query, nil))
+			if tt.wantErr == "" {
+				if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), `"errors"`) {
+					t.Errorf("status %d: %s", rec.Code, rec.Body.String())
+				}
+				return
+			}
+			assertGraphQLError(t, rec.Code, rec.Body.Bytes(), tt.wantErr)
+		})
+	}
+}
+
+// assertGraphQLError проверяет ответ 400 в формате GraphQL: сообщение в errors и без data.
+func assertGraphQLError(t *testing.T, status int, body []byte, wantErr string) {
+	t.Helper()
+	var resp struct {
+		Data   *json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("decode %s: %v", body, err)
+	}
+	if status != http.StatusBadRequest || resp.Data != nil || len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, wantErr) {
+		t.Errorf("got %d %s, want 400 with only an error containing %q", status, body, wantErr)
+	}
+}
+
+// TestGraphQLNotifyEmail проверяет, что адрес для уведомлений не выдается в ответе о кошельке
+// и не может быть запрошен.
+func TestGraphQLNotifyEmail(t *testing.T) {
+	env := newTestEnv(t, nil)
+	wallet, _, err := env.svc.CreateWallet(context.Background(), decimal.NewFromInt(100), models.WalletMetadata{
+		Label:       "alice",
+		Tags:        map[string]string{"owner": "alice"},
+		NotifyEmail: "alice@example.com",
+	})
+	if err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+
+	query := fmt.Sprintf(`{ wallet(address: %q) { address balance label tags archived __typename } }`, wallet.Address)
+	rec := env.do(t, "POST", "/api/graphql", graphQLBody(t, query, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := jsonPath(t, rec.Body.Bytes(), "data", "wallet", "label"); got != `"alice"` {
+		t.Errorf("label = %s, want \"alice\"", got)
+	}
+	if strings.Contains(rec.Body.String(), "alice@example.com") {
+		t.Errorf("response exposes the notify email: %s", rec.Body.String())
+	}
+
+	for _, field := range []string{"notifyEmail", "notify_email"} {
+		query := fmt.Sprintf(`{ wallet(address: %q) { %s } }`, wallet.Address, field)
+		rec := env.do(t, "POST", "/api/graphql", graphQLBody(t, query, nil))
+		assertGraphQLError(t, rec.Code, rec.Body.Bytes(), fmt.Sprintf("unknown field %q on type Wallet", field))
+	}
+}
+
+// TestGraphQLRequests проверяет разбор запросов GET и POST маршрута /api/graphql: переменные
+// (числа - json.Number) и operationName в обоих методах и ответ 400 в формате GraphQL на
+// некорректные variables или тело.
+func TestGraphQLRequests(t *testing.T) {
+	env := newTestEnv(t, nil)
+	from := env.wallet(t, "100")
+	to := env.wallet(t, "0")
+	env.send(t, from, to, "1")
+	env.send(t, from, to, "2")
+
+	const query = `query Other { stats { count } } query Recent($limit: Int) { transactions(limit: $limit) { amount } }`
+	get := func(params url.Values) string { return "/api/graphql?" + params.Encode() }
+
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		body    string
+		wantErr string // "" - ответ 200 с одной транзакцией
+	}{
+		{"GET", "GET", get(url.Values{"query": {query}, "operationName": {"Recent"}, "variables": {`{"limit": 1}`}}), "", ""},
+		{"POST", "POST", "/api/graphql", `{"query": ` + jsonString(query) + `, "operationName": "Recent", "variables": {"limit": 1}}`, ""},
+		{"GET malformed variables", "GET", get(url.Values{"query": {query}, "variables": {`{"limit": `}}), "",
+			"Parameter 'variables' must be a JSON object"},
+		{"GET variables not an object", "GET", get(url.Values{"query": {query}, "variables": {`[1]`}}), "",
+			"Parameter 'variables' must be a JSON object"},
+		{"GET without query", "GET", "/api/graphql", "", "must not be empty"},
+		{"GET mutation", "GET", get(url.Values{"query": {`mutation { transactions { id } }`}}), "",
+			"mutation operations are not supported"},
+		{"POST variables not an object", "POST", "/api/graphql", `{"query": "{ stats { count } }", "variables": "limit=1"}`,
+			"Invalid request body"},
+		{"POST malformed body", "POST", "/api/graphql", `{"query": `, "Invalid request body"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(t, tt.method, tt.target, tt.body)
+			if tt.wantErr != "" {
+				assertGraphQLError(t, rec.Code, rec.Body.Bytes(), tt.wantErr)
+				return
+			}
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+			}
+			if got := jsonPath(t, rec.Body.Bytes(), "data"); got != `{"transactions":[{"amount":"2"}]}` {
+				t.Errorf("data = %s, want the newest transaction", got)
+			}
+		})
+	}
+}
+
+// jsonString кодирует s как строку JSON.
+func jsonString(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
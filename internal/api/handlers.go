@@ -3,17 +3,31 @@
 package api
 
 import (
-	"encoding/hex"
+	"bytes"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"net/http"
+	"reflect"
 	"strconv"
+	"strings"
+	"time"
 
+	db "payment-system/internal/db"
+	errs "payment-system/internal/errs"
+	models "payment-system/internal/models"
 	service "payment-system/internal/service"
+	addr "payment-system/pkg/address"
 
 	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
 )
 
 // SendHandler возвращает HTTP-обработчик для отправки денег с одного кошелька на другой.
+// Участники указываются адресом или меткой с префиксом "@"; при успехе ответ пустой.
 //
 // Параметры:
 //   - svc: Сервис для работы с бизнес-логикой.
@@ -26,89 +40,491 @@ import (
 //	router.HandleFunc("/api/send", SendHandler(svc)).Methods("POST")
 func SendHandler(svc *service.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Проверка метода запроса
-		if r.Method != http.MethodPost {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		if _, ok := send(w, r, svc, false); !ok {
 			return
 		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
 
-		// Проверка заголовка Content-Type
-		if r.Header.Get("Content-Type") != "application/json" {
-			http.Error(w, "Invalid Content-Type, expected application/json", http.StatusBadRequest)
-			return
+// IsolationLevelHeader - заголовок, которым запрос перевода задает уровень изоляции транзакции
+// в PostgreSQL ("read-committed", "repeatable-read" или "serializable") вместо DB_SEND_ISOLATION.
+// Нужен для отладки согласованности и контролируемых экспериментов.
+const IsolationLevelHeader = "X-Isolation-Level"
+
+// isolationLevel возвращает уровень изоляции из заголовка X-Isolation-Level
+// или sql.LevelDefault, если заголовок не задан. Неизвестное значение отклоняется с 400.
+//
+// Возвращает:
+//   - Уровень изоляции транзакции перевода.
+//   - false, если ответ с ошибкой уже записан.
+func isolationLevel(w http.ResponseWriter, r *http.Request) (sql.IsolationLevel, bool) {
+	value := r.Header.Get(IsolationLevelHeader)
+	if value == "" {
+		return sql.LevelDefault, true
+	}
+	isolation, err := db.ParseIsolationLevel(value)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid_request",
+			fmt.Sprintf("Header %s: %v", IsolationLevelHeader, err))
+		return sql.LevelDefault, false
+	}
+	return isolation, true
+}
+
+// send разбирает и проверяет тело запроса перевода и выполняет перевод.
+// Общая часть SendHandler и SendV1Handler; при v1 ошибки перевода, которым устаревший
+// маршрут отвечает текстом, сообщаются с кодом (см. writeSendErrorV1).
+//
+// Возвращает:
+//   - Участников перевода с разрешенными адресами.
+//   - false, если ответ уже записан: ошибка или 202 для перевода, ожидающего подтверждения.
+func send(w http.ResponseWriter, r *http.Request, svc *service.Service, v1 bool) (service.Transfer, bool) {
+	// Проверка метода запроса
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return service.Transfer{}, false
+	}
+
+	// Проверка заголовка Content-Type
+	if r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "Invalid Content-Type, expected application/json", http.StatusBadRequest)
+		return service.Transfer{}, false
+	}
+
+	isolation, ok := isolationLevel(w, r)
+	if !ok {
+		return service.Transfer{}, false
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
+	if writeTooLarge(w, err) {
+		return service.Transfer{}, false
+	}
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return service.Transfer{}, false
+	}
+
+	// Валидация по схеме schemas/send.json: обязательные поля, формат адресов и меток,
+	// положительная сумма, ограничения комментария и формат подписи. Нарушения схемы
+	// и проверок ниже возвращаются одним ответом, чтобы клиент исправил все поля сразу
+	var doc interface{}
+	if err := decodeJSONBody(bytes.NewReader(body), &doc); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return service.Transfer{}, false
+	}
+	violations := validateSchema(sendSchema, doc)
+
+	// Декодирование JSON
+	var req struct {
+		From     string      `json:"from"`
+		To       string      `json:"to"`
+		Amount   json.Number `json:"amount"`
+		Memo     string      `json:"memo"`
+		Category string      `json:"category"`
+
+		// Подпись перевода (необязательна, если не задан REQUIRE_SIGNATURES); схема требует
+		// передавать nonce и signature вместе
+		Nonce     int64  `json:"nonce"`
+		Signature string `json:"signature"`
+	}
+	// Поле неверного типа уже описано нарушением схемы, остальные поля декодируются
+	if err := decodeJSONBody(bytes.NewReader(body), &req); err != nil && len(violations) == 0 {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return service.Transfer{}, false
+	}
+
+	var amount decimal.Decimal
+	if !hasViolation(violations, "amount") {
+		if amount, err = parseAmount(req.Amount); err != nil {
+			violations = append(violations, violation{Field: "amount", Code: violationFormat, Message: err.Error()})
+		} else if err := models.ValidateAmountScale(amount); err != nil {
+			violations = append(violations, violation{Field: "amount", Code: violationPrecision,
+				Message: fmt.Sprintf("amount must have at most %d decimal places", models.AmountScale)})
+		}
+	}
+
+	// Адреса с контрольной суммой приводятся к нижнему регистру, в котором хранятся
+	if !hasViolation(violations, "from") {
+		if req.From, err = parseParty(req.From); err != nil {
+			violations = append(violations, addressViolation("from", err))
+		}
+	}
+	if !hasViolation(violations, "to") {
+		if req.To, err = parseParty(req.To); err != nil {
+			violations = append(violations, addressViolation("to", err))
 		}
+	}
+	if len(violations) > 0 {
+		writeViolations(w, violations)
+		return service.Transfer{}, false
+	}
+
+	var sig *service.Signature
+	if req.Signature != "" {
+		sig = &service.Signature{Nonce: req.Nonce, Value: req.Signature}
+	}
 
-		// Декодирование JSON
-		var req struct {
-			From   string  `json:"from"`
-			To     string  `json:"to"`
-			Amount float64 `json:"amount"`
+	// Вызов сервиса; результат записывается в журнал здесь, один раз
+	start := time.Now()
+	transfer, err := svc.Send(r.Context(), req.From, req.To, amount, req.Memo, req.Category, sig, isolation)
+	logTransfer(r.Context(), req.From, transfer, err, time.Since(start))
+	if err != nil {
+		if writeUnavailable(w, err) {
+			return service.Transfer{}, false
+		}
+		if errors.Is(err, errs.ErrLabelNotFound) {
+			writeJSONError(w, http.StatusNotFound, "label_not_found", err.Error())
+			return service.Transfer{}, false
+		}
+		if errors.Is(err, errs.ErrZeroAmount) {
+			writeJSONError(w, http.StatusBadRequest, "invalid_amount", err.Error())
+			return service.Transfer{}, false
+		}
+		if errors.Is(err, errs.ErrAmountTooLarge) {
+			writeJSONError(w, http.StatusBadRequest, "amount_too_large", err.Error())
+			return service.Transfer{}, false
+		}
+		if writeCategoryError(w, err) {
+			return service.Transfer{}, false
+		}
+		if errors.Is(err, errs.ErrWalletArchived) {
+			writeJSONError(w, http.StatusConflict, "wallet_archived", err.Error())
+			return service.Transfer{}, false
+		}
+		if errors.Is(err, errs.ErrSystemAccount) {
+			writeJSONError(w, http.StatusForbidden, "system_account", err.Error())
+			return service.Transfer{}, false
+		}
+		if errors.Is(err, errs.ErrSelfTransfer) {
+			writeJSONError(w, http.StatusBadRequest, "self_transfer", err.Error())
+			return service.Transfer{}, false
+		}
+		if errors.Is(err, db.ErrOutcomeUnknown) {
+			writeOutcomeUnknown(w)
+			return service.Transfer{}, false
+		}
+		if writeSignatureError(w, err) || writeNonceError(w, err) || writeRiskError(w, err) {
+			return service.Transfer{}, false
+		}
+		if v1 {
+			writeSendErrorV1(w, err)
+			return service.Transfer{}, false
+		}
+		if errors.Is(err, db.ErrContention) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return service.Transfer{}, false
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return service.Transfer{}, false
+	}
+	// Крупный перевод отложен до решения администратора: ответ одинаков для всех версий API
+	if transfer.ApprovalID != 0 {
+		writePendingApproval(w, r, transfer.ApprovalID)
+		return service.Transfer{}, false
+	}
+	return transfer, true
+}
+
+// DefaultTransactionsCount - количество транзакций, возвращаемое без параметра count.
+const DefaultTransactionsCount = 20
+
+// GetLastHandler возвращает HTTP-обработчик для получения информации о последних N транзакциях.
+// Параметр count необязателен (по умолчанию DefaultTransactionsCount); значения больше maxCount
+// уменьшаются до maxCount. Если транзакций нет, возвращается пустой массив.
+// С заголовком X-Amount-Format: string суммы возвращаются строками.
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//   - maxCount: Максимальное количество транзакций в одном ответе.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.HandleFunc("/api/transactions", GetLastHandler(svc, 100)).Methods("GET")
+func GetLastHandler(svc *service.Service, maxCount int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		asStrings, ok := stringAmounts(w, r)
+		if !ok {
+			return
 		}
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+		transactions, ok := lastTransactions(w, r, svc, maxCount)
+		if !ok {
 			return
 		}
 
-		// Валидация данных
-		if req.Amount <= 0 {
-			http.Error(w, "Amount must be greater than 0", http.StatusBadRequest)
+		// Отправка ответа в формате JSON; суммы строками - по заголовку X-Amount-Format
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(withLegacyFormat(transactions, asStrings)); err != nil {
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 			return
 		}
+	}
+}
 
-		// Проверка формата адресов кошельков
-		if !isValidAddress(req.From) || !isValidAddress(req.To) {
-			http.Error(w, "Invalid wallet address", http.StatusBadRequest)
+// lastTransactions читает параметры count, amount, category, include_archived и cursor и получает последние транзакции.
+// Общая часть GetLastHandler и GetLastV1Handler, различающихся только форматом ответа.
+// Если страница заполнена целиком, записывает в заголовок X-Next-Cursor курсор следующей
+// страницы: запрос с cursor=<значение> вернет транзакции строго после последней полученной.
+//
+// Параметры:
+//   - w: Ответ HTTP, в который записывается ошибка.
+//   - r: Запрос с необязательным параметром count.
+//   - svc: Сервис для работы с бизнес-логикой.
+//   - maxCount: Максимальное количество транзакций в одном ответе.
+//
+// Возвращает:
+//   - Список транзакций (пустой, а не nil, если транзакций нет).
+//   - false, если ответ с ошибкой уже записан.
+func lastTransactions(w http.ResponseWriter, r *http.Request, svc *service.Service, maxCount int) ([]models.Transaction, bool) {
+	// Получение параметра count из query-строки
+	count := DefaultTransactionsCount
+	if countStr := r.URL.Query().Get("count"); countStr != "" {
+		n, err := strconv.Atoi(countStr)
+		if err != nil || n <= 0 {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request",
+				fmt.Sprintf("Parameter 'count' must be a positive integer, got %q", countStr))
+			return nil, false
+		}
+		count = n
+	}
+	count = min(count, maxCount)
+
+	filter, ok := transactionFilter(w, r)
+	if !ok {
+		return nil, false
+	}
+	if cursorStr := r.URL.Query().Get("cursor"); cursorStr != "" {
+		cursor, err := decodeCursor(cursorStr)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return nil, false
+		}
+		filter.After = &cursor
+	}
+
+	// Получение последних транзакций
+	transactions, err := svc.GetLastTransactions(r.Context(), count, filter)
+	if writeUnavailable(w, err) {
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if len(transactions) == count {
+		w.Header().Set(NextCursorHeader, encodeCursor(db.CursorOf(transactions[len(transactions)-1])))
+	}
+
+	// Пустой список кодируется как [], а не null
+	if transactions == nil {
+		transactions = []models.Transaction{}
+	}
+	return transactions, true
+}
+
+// transactionFilter читает параметры amount, category и include_archived, общие для списка
+// и количества транзакций.
+//
+// Параметры:
+//   - w: Ответ HTTP, в который записывается ошибка.
+//   - r: Запрос с необязательными параметрами фильтра.
+//
+// Возвращает:
+//   - Условия выборки транзакций.
+//   - false, если ответ с ошибкой уже записан.
+func transactionFilter(w http.ResponseWriter, r *http.Request) (db.TransactionFilter, bool) {
+	// Необязательный фильтр по точной сумме (например, для сверки со счетом)
+	var filter db.TransactionFilter
+	if amountStr := r.URL.Query().Get("amount"); amountStr != "" {
+		amount, err := decimal.NewFromString(amountStr)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request",
+				fmt.Sprintf("Parameter 'amount' must be a number, got %q", amountStr))
+			return db.TransactionFilter{}, false
+		}
+		filter.Amount = &amount
+	}
+	filter.Category = r.URL.Query().Get("category")
+
+	// Транзакции, перенесенные в архив очисткой истории, ищутся только по запросу
+	if archivedStr := r.URL.Query().Get("include_archived"); archivedStr != "" {
+		includeArchived, err := strconv.ParseBool(archivedStr)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request",
+				fmt.Sprintf("Parameter 'include_archived' must be a boolean, got %q", archivedStr))
+			return db.TransactionFilter{}, false
+		}
+		filter.IncludeArchived = includeArchived
+	}
+	return filter, true
+}
+
+// CountTransactionsHandler возвращает HTTP-обработчик GET /api/transactions/count.
+// Принимает те же фильтры, что и список транзакций (amount, category, include_archived),
+// и отвечает {"count": N} - общим количеством для индикатора прогресса при листании.
+// С exact=false количество без фильтров берется из статистики базы, не просматривая всю
+// таблицу, и ответ содержит "exact": false; по умолчанию количество всегда точное.
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.HandleFunc("/api/transactions/count", CountTransactionsHandler(svc)).Methods("GET")
+func CountTransactionsHandler(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, ok := transactionFilter(w, r)
+		if !ok {
 			return
 		}
 
-		// Вызов сервиса
-		if err := svc.Send(req.From, req.To, req.Amount); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		exact := true
+		if exactStr := r.URL.Query().Get("exact"); exactStr != "" {
+			var err error
+			if exact, err = strconv.ParseBool(exactStr); err != nil {
+				writeJSONError(w, http.StatusBadRequest, "invalid_request",
+					fmt.Sprintf("Parameter 'exact' must be a boolean, got %q", exactStr))
+				return
+			}
+		}
+
+		var count int64
+		var err error
+		isExact := true
+		if exact {
+			count, err = svc.CountTransactions(r.Context(), filter)
+		} else {
+			count, isExact, err = svc.EstimateTransactions(r.Context(), filter)
+		}
+		if writeUnavailable(w, err) {
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
 			return
 		}
 
-		w.WriteHeader(http.StatusOK)
+		// Прежний формат ответа сохраняется, если приблизительное количество не запрошено
+		if exact {
+			writeJSON(w, http.StatusOK, map[string]int64{"count": count})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"count": count, "exact": isExact})
 	}
 }
 
-// GetLastHandler возвращает HTTP-обработчик для получения информации о последних N транзакциях.
+// Форматы баланса в параметре format запроса GET /api/wallet/{address}/balance.
+const (
+	balanceFormatRaw     = "raw"     // число JSON (по умолчанию)
+	balanceFormatDecimal = "decimal" // строка с фиксированным количеством знаков после запятой
+)
+
+// GetBalanceHandler возвращает HTTP-обработчик для получения баланса кошелька.
+// Параметр format выбирает представление баланса: raw (число, по умолчанию)
+// или decimal (строка с scale знаками после запятой, например "100.00"). Заголовок
+// X-Amount-Format: string без параметра format возвращает баланс строкой без округления.
+// Если у кошелька есть метка или теги, они возвращаются в полях label и tags, а если задана
+// валюта для отображения - в поле currency.
+// Несуществующий кошелек - ответ 404; 500 означает только сбой хранилища.
 //
 // Параметры:
 //   - svc: Сервис для работы с бизнес-логикой.
+//   - scale: Количество знаков после запятой в формате decimal.
+//   - currency: Код или символ валюты для отображения (DISPLAY_CURRENCY); пусто - поля нет.
 //
 // Возвращает:
 //   - HTTP-обработчик.
 //
 // Пример использования:
 //
-//	router.HandleFunc("/api/transactions", GetLastHandler(svc)).Methods("GET")
-func GetLastHandler(svc *service.Service) http.HandlerFunc {
+//	router.HandleFunc("/api/wallet/{address}/balance", GetBalanceHandler(svc, 2, "USD")).Methods("GET")
+func GetBalanceHandler(svc *service.Service, scale int, currency string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Получение параметра count из query-строки
-		countStr := r.URL.Query().Get("count")
-		count, err := strconv.Atoi(countStr)
-		if err != nil || count <= 0 {
-			http.Error(w, "Invalid count parameter", http.StatusBadRequest)
+		// Получение и проверка адреса кошелька из пути запроса; неверный формат - прежний
+		// текстовый ответ, несовпадение контрольной суммы - ответ в JSON с отдельным кодом
+		address, err := parseAddress(mux.Vars(r)["address"])
+		if errors.Is(err, addr.ErrChecksum) {
+			writeAddressError(w, err, "")
+			return
+		}
+		if err != nil {
+			http.Error(w, "Invalid wallet address", http.StatusBadRequest)
 			return
 		}
 
-		// Получение последних транзакций
-		transactions, err := svc.GetLastTransactions(count)
+		format, ok := balanceFormat(w, r)
+		if !ok {
+			return
+		}
+		asString, ok := stringAmounts(w, r)
+		if !ok {
+			return
+		}
+
+		// Получение баланса и метаданных кошелька
+		wallet, err := svc.GetWallet(r.Context(), address)
+		if writeUnavailable(w, err) {
+			return
+		}
+		if errors.Is(err, errs.ErrWalletNotFound) {
+			http.Error(w, "Wallet not found", http.StatusNotFound)
+			return
+		}
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		// Отправка ответа в формате JSON
+		// Отправка ответа в формате JSON; метка и теги добавляются, только если заданы
+		resp := map[string]interface{}{"balance": numberAmount(wallet.Balance)}
+		switch {
+		case format == balanceFormatDecimal:
+			resp["balance"] = formatAmount(wallet.Balance, scale)
+		case asString:
+			resp["balance"] = wallet.Balance.String()
+		}
+		if wallet.Label != "" {
+			resp["label"] = wallet.Label
+		}
+		if len(wallet.Tags) > 0 {
+			resp["tags"] = wallet.Tags
+		}
+		if currency != "" {
+			resp["currency"] = currency
+		}
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(transactions); err != nil {
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
 			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 			return
 		}
 	}
 }
 
-// GetBalanceHandler возвращает HTTP-обработчик для получения баланса кошелька.
+// balanceFormat читает параметр format запроса баланса.
+//
+// Возвращает:
+//   - Формат баланса (пустая строка - формат по умолчанию).
+//   - false, если ответ с ошибкой уже записан.
+func balanceFormat(w http.ResponseWriter, r *http.Request) (string, bool) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != balanceFormatRaw && format != balanceFormatDecimal {
+		writeJSONError(w, http.StatusBadRequest, "invalid_request",
+			fmt.Sprintf("Parameter 'format' must be %q or %q, got %q", balanceFormatRaw, balanceFormatDecimal, format))
+		return "", false
+	}
+	return format, true
+}
+
+// GetSendableHandler возвращает HTTP-обработчик, сообщающий максимальную сумму,
+// которую кошелек может отправить (для кнопки «отправить всё»).
+// Сумма возвращается строкой, чтобы клиент не терял точность.
 //
 // Параметры:
 //   - svc: Сервис для работы с бизнес-логикой.
@@ -118,20 +534,29 @@ func GetLastHandler(svc *service.Service) http.HandlerFunc {
 //
 // Пример использования:
 //
-//	router.HandleFunc("/api/wallet/{address}/balance", GetBalanceHandler(svc)).Methods("GET")
-func GetBalanceHandler(svc *service.Service) http.HandlerFunc {
+//	router.HandleFunc("/api/wallet/{address}/sendable", GetSendableHandler(svc)).Methods("GET")
+func GetSendableHandler(svc *service.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Получение адреса кошелька из пути запроса
-		address := mux.Vars(r)["address"]
-
-		// Проверка формата адреса кошелька
-		if !isValidAddress(address) {
+		// Получение и проверка адреса кошелька из пути запроса; неверный формат - прежний
+		// текстовый ответ, несовпадение контрольной суммы - ответ в JSON с отдельным кодом
+		address, err := parseAddress(mux.Vars(r)["address"])
+		if errors.Is(err, addr.ErrChecksum) {
+			writeAddressError(w, err, "")
+			return
+		}
+		if err != nil {
 			http.Error(w, "Invalid wallet address", http.StatusBadRequest)
 			return
 		}
 
-		// Получение баланса кошелька
-		balance, err := svc.GetBalance(address)
+		maxAmount, err := svc.MaxSendable(r.Context(), address)
+		if writeUnavailable(w, err) {
+			return
+		}
+		if errors.Is(err, errs.ErrWalletNotFound) {
+			http.Error(w, "Wallet not found", http.StatusNotFound)
+			return
+		}
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -139,24 +564,233 @@ func GetBalanceHandler(svc *service.Service) http.HandlerFunc {
 
 		// Отправка ответа в формате JSON
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(map[string]float64{"balance": balance}); err != nil {
+		resp := map[string]string{"max_amount": formatAmount(maxAmount, -1)}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
 			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 			return
 		}
 	}
 }
 
-// isValidAddress проверяет, что адрес состоит из 64 шестнадцатеричных символов.
+// writeUnavailable отвечает 503 с заголовком Retry-After, если база данных недоступна
+// (открыт автомат отключения).
 //
 // Параметры:
-//   - address: Адрес кошелька.
+//   - w: Ответ HTTP.
+//   - err: Ошибка, полученная от сервиса.
 //
 // Возвращает:
-//   - true, если адрес валиден, иначе false.
-func isValidAddress(address string) bool {
-	if len(address) != 64 {
+//   - true, если ответ уже записан.
+func writeUnavailable(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, db.ErrDatabaseUnavailable) {
+		return false
+	}
+
+	retryAfter := 1
+	var unavailable *db.UnavailableError
+	if errors.As(err, &unavailable) {
+		if seconds := int(math.Ceil(unavailable.RetryAfter.Seconds())); seconds > retryAfter {
+			retryAfter = seconds
+		}
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	http.Error(w, "Service temporarily unavailable, retry later", http.StatusServiceUnavailable)
+	return true
+}
+
+// writeCategoryError отвечает 422 с кодом invalid_category, если категория перевода
+// не входит в список TRANSACTION_CATEGORIES. Допустимые категории передаются в поле
+// allowed_categories.
+//
+// Возвращает:
+//   - true, если ответ уже записан.
+func writeCategoryError(w http.ResponseWriter, err error) bool {
+	var categoryErr *service.CategoryError
+	if !errors.As(err, &categoryErr) {
 		return false
 	}
-	_, err := hex.DecodeString(address)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":               "invalid_category",
+			"message":            err.Error(),
+			"allowed_categories": categoryErr.Allowed,
+		},
+	})
+	return true
+}
+
+// writeOutcomeUnknown отвечает 503, если соединение с базой оборвалось во время фиксации
+// перевода и неизвестно, выполнен ли он. Заголовок X-Transfer-Outcome: unknown сообщает клиенту
+// и IdempotencyMiddleware, что перевод мог быть выполнен: ключ идемпотентности не освобождается,
+// поэтому повтор с тем же ключом не выполнит перевод второй раз.
+func writeOutcomeUnknown(w http.ResponseWriter) {
+	w.Header().Set(TransferOutcomeHeader, "unknown")
+	writeJSONError(w, http.StatusServiceUnavailable, "outcome_unknown",
+		"Database connection was lost while committing the transfer and it may have been executed; "+
+			"check the transaction list and, if the transfer is not there, retry it with the same Idempotency-Key")
+}
+
+// writeSignatureError отвечает 401, если перевод не прошел проверку подписи:
+// подпись отсутствует, хотя обязательна, или не совпадает.
+//
+// Возвращает:
+//   - true, если ответ уже записан.
+func writeSignatureError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, errs.ErrSignatureRequired):
+		writeJSONError(w, http.StatusUnauthorized, "signature_required", "Transfer must be signed by the sender wallet key")
+	case errors.Is(err, errs.ErrInvalidSignature):
+		writeJSONError(w, http.StatusUnauthorized, "invalid_signature", err.Error())
+	default:
+		return false
+	}
+	return true
+}
+
+// parseAmount разбирает сумму перевода из JSON-числа в том виде, в каком ее записал клиент,
+// без промежуточного float64. Экспоненциальная запись ("1e3") отклоняется: сумма денег
+// записывается цифрами, а 1e308 в ней скорее ошибка клиента, чем намерение.
+//
+// Параметры:
+//   - number: Сумма из тела запроса.
+//
+// Возвращает:
+//   - Сумму.
+//   - Ошибку, если сумма записана с показателем степени.
+func parseAmount(number json.Number) (decimal.Decimal, error) {
+	if strings.ContainsAny(number.String(), "eE") {
+		return decimal.Decimal{}, fmt.Errorf("amount %s must be written in plain decimal notation, without an exponent", number)
+	}
+	return decimal.NewFromString(number.String())
+}
+
+// maxRequestBodyBytes ограничивает размер тела запросов, которые читаются целиком: перевода,
+// создания кошелька и любого запроса с заголовком Idempotency-Key.
+const maxRequestBodyBytes = 1 << 20
+
+// writeTooLarge отвечает 413, если чтение тела, ограниченного http.MaxBytesReader,
+// остановилось на пределе.
+//
+// Возвращает:
+//   - true, если ответ уже записан.
+func writeTooLarge(w http.ResponseWriter, err error) bool {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		return false
+	}
+	writeJSONError(w, http.StatusRequestEntityTooLarge, "request_too_large",
+		fmt.Sprintf("Request body must be at most %d bytes", tooLarge.Limit))
+	return true
+}
+
+// decodeJSONBody декодирует тело запроса, содержащее ровно один JSON-объект.
+// В отличие от голого json.Decoder, возвращает понятное описание проблемы:
+// пустое тело, синтаксическая ошибка с позицией, неверный тип поля или лишние данные после объекта.
+// Числа в interface{} декодируются как json.Number, чтобы схема проверяла их без потери точности
+// и большие числа не отклонялись из-за переполнения float64.
+//
+// Параметры:
+//   - body: Тело запроса.
+//   - dst: Указатель на структуру для декодирования.
+//
+// Возвращает:
+//   - Ошибку с сообщением, пригодным для ответа клиенту.
+func decodeJSONBody(body io.Reader, dst interface{}) error {
+	dec := json.NewDecoder(body)
+	dec.UseNumber()
+	if err := dec.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+
+	// После объекта не должно быть ничего, кроме пробельных символов
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return errors.New("Invalid request body: unexpected data after JSON object")
+	}
+	return nil
+}
+
+// decodeError преобразует ошибку encoding/json в сообщение для клиента.
+//
+// Параметры:
+//   - err: Ошибка, возвращенная json.Decoder.
+//
+// Возвращает:
+//   - Ошибку с описанием категории проблемы.
+func decodeError(err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return errors.New("Invalid request body: body is empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return errors.New("Invalid request body: unexpected end of JSON")
+	case errors.As(err, &syntaxErr):
+		return fmt.Errorf("Invalid request body: malformed JSON at position %d", syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return fmt.Errorf("Invalid request body: expected JSON object, got %s", typeErr.Value)
+		}
+		return fmt.Errorf("Invalid request body: field %q must be %s, got %s", typeErr.Field, jsonTypeName(typeErr.Type), typeErr.Value)
+	default:
+		return fmt.Errorf("Invalid request body: %v", err)
+	}
+}
+
+// jsonTypeName возвращает название JSON-типа, соответствующего Go-типу поля.
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// validateMemo проверяет комментарий к переводу по правилам models.ValidateMemo
+// и возвращает сообщение для клиента.
+//
+// Параметры:
+//   - memo: Комментарий к переводу.
+//
+// Возвращает:
+//   - Ошибку с описанием нарушения или nil.
+func validateMemo(memo string) error {
+	if err := models.ValidateMemo(memo); err != nil {
+		return fmt.Errorf("Invalid memo: must be at most %d characters without control characters", models.MaxMemoLength)
+	}
+	return nil
+}
+
+// validateCategory проверяет категорию перевода по правилам models.ValidateCategory
+// и возвращает сообщение для клиента.
+func validateCategory(category string) error {
+	if err := models.ValidateCategory(category); err != nil {
+		return fmt.Errorf("Invalid category: must be at most %d characters without control characters", models.MaxCategoryLength)
+	}
+	return nil
+}
+
+// IsValidAddress проверяет, что адрес состоит из 2*db.AddressBytes() шестнадцатеричных символов
+// (по умолчанию 64) и, если в нем есть буквы разного регистра, что совпадает контрольная сумма
+// (см. пакет pkg/address).
+//
+// Параметры:
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - true, если адрес валиден, иначе false.
+func IsValidAddress(address string) bool {
+	_, err := parseAddress(address)
 	return err == nil
 }
@@ -0,0 +1,703 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	db "payment-system/internal/db"
+	models "payment-system/internal/models"
+	service "payment-system/internal/service"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+// TestSendMemo проверяет комментарий перевода: допустимый сохраняется и возвращается в списках
+// транзакций обеих версий API, недопустимый отклоняется нарушением поля memo.
+func TestSendMemo(t *testing.T) {
+	tests := []struct {
+		name       string
+		memo       string
+		wantStatus int
+	}{
+		{"no memo", "", http.StatusOK},
+		{"invoice", "invoice 42", http.StatusOK},
+		{"unicode", "счет №42", http.StatusOK},
+		{"max length", strings.Repeat("a", 256), http.StatusOK},
+		{"too long", strings.Repeat("a", 257), http.StatusBadRequest},
+		{"newline", "invoice\n42", http.StatusBadRequest},
+		{"escape", "\x1b[2J", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			from := env.wallet(t, "100")
+			to := env.wallet(t, "0")
+
+			memo, _ := json.Marshal(tt.memo)
+			body := `{"from":"` + from + `","to":"` + to + `","amount":1,"memo":` + string(memo) + `}`
+			rec := env.do(t, "POST", "/api/send", body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				if got := jsonPath(t, rec.Body.Bytes(), "error", "violations", "0", "field"); got != `"memo"` {
+					t.Errorf("violation field = %s, want \"memo\"; body: %s", got, rec.Body)
+				}
+				return
+			}
+			for _, target := range []string{"/api/transactions?count=1", "/api/v1/transactions?count=1"} {
+				rec := env.do(t, "GET", target, "")
+				var transactions []struct {
+					Memo *string `json:"memo"`
+				}
+				if err := json.Unmarshal(rec.Body.Bytes(), &transactions); err != nil {
+					t.Fatalf("GET %s: decode %s: %v", target, rec.Body, err)
+				}
+				if len(transactions) != 1 {
+					t.Fatalf("GET %s: %d transactions, want 1", target, len(transactions))
+				}
+				got := ""
+				if transactions[0].Memo != nil {
+					got = *transactions[0].Memo
+				}
+				if got != tt.memo {
+					t.Errorf("GET %s: memo = %q, want %q", target, got, tt.memo)
+				}
+			}
+		})
+	}
+}
+
+// contendedRepository - хранилище, переводы которого не проходят из-за конфликтов после всех повторов.
+type contendedRepository struct {
+	*db.MemoryRepository
+}
+
+// Send всегда завершается ErrContention.
+func (r contendedRepository) Send(ctx context.Context, from, to string, amount decimal.Decimal, memo, category string, nonce int64, isolation sql.IsolationLevel) (db.SendResult, error) {
+	return db.SendResult{}, fmt.Errorf("%w: deadlock detected", db.ErrContention)
+}
+
+// TestSendContention проверяет, что перевод, не прошедший из-за конфликтов, отклоняется
+// кодом 409 в обеих версиях API, чтобы клиент повторил его позже.
+func TestSendContention(t *testing.T) {
+	repo := contendedRepository{db.NewMemoryRepository()}
+	svc := service.NewService(repo)
+	ctx := context.Background()
+	var addresses []string
+	for _, balance := range []int64{100, 0} {
+		wallet, _, err := svc.CreateWallet(ctx, decimal.NewFromInt(balance), models.WalletMetadata{})
+		if err != nil {
+			t.Fatalf("CreateWallet: %v", err)
+		}
+		addresses = append(addresses, wallet.Address)
+	}
+	body := `{"from":"` + addresses[0] + `","to":"` + addresses[1] + `","amount":1}`
+
+	tests := []struct {
+		name     string
+		handler  http.Handler
+		target   string
+		wantBody string
+	}{
+		{"legacy", SendHandler(svc), "/api/send", "contention"},
+		{"v1", SendV1Handler(svc, ""), "/api/v1/send", `"code":"contention"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(t, tt.handler, "POST", tt.target, body)
+			if rec.Code != http.StatusConflict {
+				t.Fatalf("status = %d, want 409; body: %s", rec.Code, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body %s does not contain %s", rec.Body, tt.wantBody)
+			}
+		})
+	}
+}
+
+// outcomeUnknownRepository - хранилище, соединение которого обрывается во время фиксации
+// каждого перевода так, что его исход выяснить не удается.
+type outcomeUnknownRepository struct {
+	*db.MemoryRepository
+}
+
+// Send всегда завершается ErrOutcomeUnknown.
+func (r outcomeUnknownRepository) Send(ctx context.Context, from, to string, amount decimal.Decimal, memo, category string, nonce int64, isolation sql.IsolationLevel) (db.SendResult, error) {
+	return db.SendResult{}, fmt.Errorf("%w: unexpected EOF", db.ErrOutcomeUnknown)
+}
+
+// TestSendOutcomeUnknown проверяет, что перевод с неизвестным исходом фиксации отвечает 503
+// с кодом outcome_unknown и заголовком X-Transfer-Outcome: unknown в обеих версиях API,
+// чтобы клиент повторил его с тем же ключом идемпотентности, а не как новый перевод.
+func TestSendOutcomeUnknown(t *testing.T) {
+	svc := service.NewService(outcomeUnknownRepository{db.NewMemoryRepository()})
+	ctx := context.Background()
+	var addresses []string
+	for _, balance := range []int64{100, 0} {
+		wallet, _, err := svc.CreateWallet(ctx, decimal.NewFromInt(balance), models.WalletMetadata{})
+		if err != nil {
+			t.Fatalf("CreateWallet: %v", err)
+		}
+		addresses = append(addresses, wallet.Address)
+	}
+	body := `{"from":"` + addresses[0] + `","to":"` + addresses[1] + `","amount":1}`
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		target  string
+	}{
+		{"legacy", SendHandler(svc), "/api/send"},
+		{"v1", SendV1Handler(svc, ""), "/api/v1/send"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(t, tt.handler, "POST", tt.target, body)
+			if rec.Code != http.StatusServiceUnavailable {
+				t.Fatalf("status = %d, want 503; body: %s", rec.Code, rec.Body)
+			}
+			if got := rec.Header().Get(TransferOutcomeHeader); got != "unknown" {
+				t.Errorf("%s = %q, want \"unknown\"", TransferOutcomeHeader, got)
+			}
+			if got := jsonPath(t, rec.Body.Bytes(), "error", "code"); got != `"outcome_unknown"` {
+				t.Errorf("error code = %s, want \"outcome_unknown\"", got)
+			}
+			if got := jsonPath(t, rec.Body.Bytes(), "error", "message"); !strings.Contains(got, "Idempotency-Key") {
+				t.Errorf("error message %s does not tell to retry with the same Idempotency-Key", got)
+			}
+		})
+	}
+}
+
+// TestTransactionsCount проверяет параметр count списка транзакций: без него возвращается
+// DefaultTransactionsCount, большее значение ограничивается maxCount, а нечисловое, нулевое
+// или отрицательное отклоняется ошибкой с именем параметра.
+func TestTransactionsCount(t *testing.T) {
+	env := newTestEnv(t, nil)
+	from := env.wallet(t, "100")
+	to := env.wallet(t, "0")
+	for i := 0; i < 30; i++ {
+		env.send(t, from, to, "1")
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		maxCount   int
+		wantStatus int
+		wantCount  int
+	}{
+		{"missing", "", 100, http.StatusOK, DefaultTransactionsCount},
+		{"explicit", "?count=5", 100, http.StatusOK, 5},
+		{"more than stored", "?count=50", 100, http.StatusOK, 30},
+		{"huge", "?count=1000000", 25, http.StatusOK, 25},
+		{"missing above cap", "", 10, http.StatusOK, 10},
+		{"zero", "?count=0", 100, http.StatusBadRequest, 0},
+		{"negative", "?count=-5", 100, http.StatusBadRequest, 0},
+		{"garbage", "?count=abc", 100, http.StatusBadRequest, 0},
+		{"fraction", "?count=1.5", 100, http.StatusBadRequest, 0},
+		{"beyond int", "?count=99999999999999999999", 100, http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(t, GetLastHandler(env.svc, tt.maxCount), "GET", "/api/transactions"+tt.query, "")
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if got := jsonPath(t, rec.Body.Bytes(), "error", "message"); !strings.Contains(got, "'count'") {
+					t.Errorf("error message %s does not name the parameter", got)
+				}
+				return
+			}
+			var transactions []json.RawMessage
+			if err := json.Unmarshal(rec.Body.Bytes(), &transactions); err != nil {
+				t.Fatalf("decode %s: %v", rec.Body, err)
+			}
+			if len(transactions) != tt.wantCount {
+				t.Errorf("got %d transactions, want %d", len(transactions), tt.wantCount)
+			}
+		})
+	}
+}
+
+// TestTransactionsEmpty проверяет, что пустой список транзакций кодируется как [], а не null.
+func TestTransactionsEmpty(t *testing.T) {
+	env := newTestEnv(t, nil)
+	for _, target := range []string{"/api/transactions", "/api/v1/transactions"} {
+		rec := env.do(t, "GET", target, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d, want 200; body: %s", target, rec.Code, rec.Body)
+		}
+		if got := strings.TrimSpace(rec.Body.String()); got != "[]" {
+			t.Errorf("GET %s: body = %s, want []", target, got)
+		}
+	}
+}
+
+// TestTransactionsAmountFilter проверяет поиск по точной сумме: равные суммы в другой
+// записи находятся, соседние - нет, а нечисловое значение отклоняется 400.
+func TestTransactionsAmountFilter(t *testing.T) {
+	env := newTestEnv(t, nil)
+	from := env.wallet(t, "100")
+	to := env.wallet(t, "0")
+	for _, amount := range []string{"10.5", "10.51", "10.49", "10.50000001", "0.3"} {
+		env.send(t, from, to, amount)
+	}
+
+	tests := []struct {
+		amount     string
+		wantStatus int
+		wantCount  int
+	}{
+		{"10.5", http.StatusOK, 1},
+		{"10.50", http.StatusOK, 1},
+		{"1.05e1", http.StatusOK, 1},
+		{"10.51", http.StatusOK, 1},
+		{"10.50000001", http.StatusOK, 1},
+		{".3", http.StatusOK, 1},
+		{"10.4", http.StatusOK, 0},
+		{"abc", http.StatusBadRequest, 0},
+		{"10,5", http.StatusBadRequest, 0},
+		{"10.5abc", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.amount, func(t *testing.T) {
+			for _, target := range []string{"/api/transactions", "/api/v1/transactions"} {
+				rec := env.do(t, "GET", target+"?amount="+url.QueryEscape(tt.amount), "")
+				if rec.Code != tt.wantStatus {
+					t.Fatalf("GET %s: status = %d, want %d; body: %s", target, rec.Code, tt.wantStatus, rec.Body)
+				}
+				if tt.wantStatus != http.StatusOK {
+					if got := jsonPath(t, rec.Body.Bytes(), "error", "message"); !strings.Contains(got, "'amount'") {
+						t.Errorf("GET %s: error message %s does not name the parameter", target, got)
+					}
+					continue
+				}
+				var transactions []json.RawMessage
+				if err := json.Unmarshal(rec.Body.Bytes(), &transactions); err != nil {
+					t.Fatalf("GET %s: decode %s: %v", target, rec.Body, err)
+				}
+				if len(transactions) != tt.wantCount {
+					t.Errorf("GET %s: got %d transactions, want %d", target, len(transactions), tt.wantCount)
+				}
+			}
+		})
+	}
+}
+
+// TestSendMinimumBalance проверяет MIN_WALLET_BALANCE на границе: перевод, оставляющий ровно
+// неснижаемый остаток, проходит, а на единицу точности больше отклоняется 400, в v1 - с кодом
+// below_minimum_balance.
+func TestSendMinimumBalance(t *testing.T) {
+	tests := []struct {
+		name       string
+		amount     string
+		wantStatus map[string]int
+		wantCode   string
+	}{
+		{"down to minimum", "90", map[string]int{"/api/send": http.StatusOK, "/api/v1/send": http.StatusCreated}, ""},
+		{"one unit below minimum", "90.00000001", map[string]int{"/api/send": http.StatusBadRequest, "/api/v1/send": http.StatusBadRequest}, "below_minimum_balance"},
+		{"more than balance", "100.00000001", map[string]int{"/api/send": http.StatusBadRequest, "/api/v1/send": http.StatusBadRequest}, "insufficient_funds"},
+	}
+	for _, tt := range tests {
+		for target, wantStatus := range tt.wantStatus {
+			t.Run(tt.name+target, func(t *testing.T) {
+				t.Setenv("MIN_WALLET_BALANCE", "10")
+				env := newTestEnv(t, nil)
+				from := env.wallet(t, "100")
+				to := env.wallet(t, "0")
+
+				rec := env.do(t, "POST", target, `{"from":"`+from+`","to":"`+to+`","amount":`+tt.amount+`}`)
+				if rec.Code != wantStatus {
+					t.Fatalf("status = %d, want %d; body: %s", rec.Code, wantStatus, rec.Body)
+				}
+				if target == "/api/v1/send" && tt.wantCode != "" {
+					if got := jsonPath(t, rec.Body.Bytes(), "error", "code"); got != `"`+tt.wantCode+`"` {
+						t.Errorf("error code = %s, want %q", got, tt.wantCode)
+					}
+				}
+
+				want := "10"
+				if tt.wantCode != "" {
+					want = "100"
+				}
+				balance, err := env.svc.GetBalance(context.Background(), from)
+				if err != nil {
+					t.Fatalf("GetBalance: %v", err)
+				}
+				if !balance.Equal(decimal.RequireFromString(want)) {
+					t.Errorf("sender balance = %s, want %s", balance, want)
+				}
+			})
+		}
+	}
+}
+
+// TestSendExactBalance проверяет перевод всего баланса через обе версии API: сумма из JSON
+// сравнивается с балансом точно, в том числе когда баланс накоплен из дробных зачислений
+// (0.1 + 0.2, что в float64 не равно 0.3), а сумма на единицу точности больше отклоняется.
+func TestSendExactBalance(t *testing.T) {
+	tests := []struct {
+		name     string
+		credits  []string // Зачисления на кошелек отправителя
+		amount   string
+		wantSent bool
+	}{
+		{"whole balance", []string{"100"}, "100", true},
+		{"accumulated balance", []string{"0.1", "0.2"}, "0.3", true},
+		{"smallest unit", []string{"0.00000001"}, "0.00000001", true},
+		{"one unit more", []string{"0.1", "0.2"}, "0.30000001", false},
+	}
+	for _, tt := range tests {
+		for _, target := range []string{"/api/send", "/api/v1/send"} {
+			t.Run(tt.name+target, func(t *testing.T) {
+				env := newTestEnv(t, nil)
+				funding := env.wallet(t, "1000")
+				from := env.wallet(t, "0")
+				to := env.wallet(t, "0")
+				credited := decimal.Zero
+				for _, amount := range tt.credits {
+					env.send(t, funding, from, amount)
+					credited = credited.Add(decimal.RequireFromString(amount))
+				}
+
+				rec := env.do(t, "POST", target, `{"from":"`+from+`","to":"`+to+`","amount":`+tt.amount+`}`)
+				if sent := rec.Code < 300; sent != tt.wantSent {
+					t.Fatalf("status = %d, want sent %t; body: %s", rec.Code, tt.wantSent, rec.Body)
+				}
+
+				wantFrom, wantTo := decimal.Zero, credited
+				if !tt.wantSent {
+					wantFrom, wantTo = credited, decimal.Zero
+				}
+				for address, want := range map[string]decimal.Decimal{from: wantFrom, to: wantTo} {
+					balance, err := env.svc.GetBalance(context.Background(), address)
+					if err != nil {
+						t.Fatalf("GetBalance: %v", err)
+					}
+					if !balance.Equal(want) {
+						t.Errorf("balance of %s = %s, want %s", address, balance, want)
+					}
+				}
+			})
+		}
+	}
+}
+
+// failingRepository - хранилище, чтение кошельков из которого завершается ошибкой err.
+type failingRepository struct {
+	*db.MemoryRepository
+	err error
+}
+
+// GetWallet всегда завершается ошибкой r.err.
+func (r failingRepository) GetWallet(ctx context.Context, address string) (models.Wallet, error) {
+	return models.Wallet{}, r.err
+}
+
+// TestGetBalanceStatus проверяет коды ответа запроса баланса в обеих версиях API: неизвестный
+// кошелек - 404, недоступная база - 503, прочие ошибки хранилища - 500.
+func TestGetBalanceStatus(t *testing.T) {
+	const unknown = "3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c"
+
+	tests := []struct {
+		name       string
+		err        error // Ошибка чтения кошелька; nil - обычное хранилище
+		known      bool
+		wantStatus int
+	}{
+		{"known wallet", nil, true, http.StatusOK},
+		{"unknown wallet", nil, false, http.StatusNotFound},
+		{"storage failure", errors.New("failed to get wallet: connection refused"), true, http.StatusInternalServerError},
+		{"database unavailable", fmt.Errorf("%w: circuit open", db.ErrDatabaseUnavailable), true, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memory := db.NewMemoryRepository()
+			var repo db.Repository = memory
+			if tt.err != nil {
+				repo = failingRepository{memory, tt.err}
+			}
+			svc := service.NewService(repo)
+			address := unknown
+			if tt.known {
+				wallet, _, err := svc.CreateWallet(context.Background(), decimal.NewFromInt(100), models.WalletMetadata{})
+				if err != nil {
+					t.Fatalf("CreateWallet: %v", err)
+				}
+				address = wallet.Address
+			}
+
+			handlers := map[string]http.Handler{
+				"/api/wallet/":    GetBalanceHandler(svc, 2, ""),
+				"/api/v1/wallet/": GetBalanceV1Handler(svc, 2, ""),
+			}
+			for prefix, h := range handlers {
+				router := mux.NewRouter()
+				router.Handle(prefix+"{address}/balance", h)
+				rec := serve(t, router, "GET", prefix+address+"/balance", "")
+				if rec.Code != tt.wantStatus {
+					t.Errorf("GET %s: status = %d, want %d; body: %s", prefix, rec.Code, tt.wantStatus, rec.Body)
+				}
+			}
+		})
+	}
+}
+
+// TestTransactionsIncludeArchived проверяет, что перенесенные в архив транзакции попадают
+// в список только с include_archived=true, а некорректное значение отклоняется 400.
+func TestTransactionsIncludeArchived(t *testing.T) {
+	env := newTestEnv(t, nil)
+	from := env.wallet(t, "100")
+	to := env.wallet(t, "0")
+	env.send(t, from, to, "1")
+	env.send(t, from, to, "2")
+	if purged, err := env.repo.PurgeTransactions(context.Background(), time.Now().Add(time.Minute), true, 1); err != nil || purged != 1 {
+		t.Fatalf("PurgeTransactions = %d, %v, want 1", purged, err)
+	}
+
+	tests := []struct {
+		query      string
+		wantStatus int
+		wantCount  int
+	}{
+		{"", http.StatusOK, 1},
+		{"?include_archived=false", http.StatusOK, 1},
+		{"?include_archived=true", http.StatusOK, 2},
+		{"?include_archived=yes", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		for _, target := range []string{"/api/transactions", "/api/v1/transactions"} {
+			rec := env.do(t, "GET", target+tt.query, "")
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("GET %s%s: status = %d, want %d; body: %s", target, tt.query, rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				continue
+			}
+			var transactions []json.RawMessage
+			if err := json.Unmarshal(rec.Body.Bytes(), &transactions); err != nil {
+				t.Fatalf("GET %s%s: decode %s: %v", target, tt.query, rec.Body, err)
+			}
+			if len(transactions) != tt.wantCount {
+				t.Errorf("GET %s%s: got %d transactions, want %d", target, tt.query, len(transactions), tt.wantCount)
+			}
+		}
+	}
+}
+
+// TestSendZeroAmount проверяет, что -0.0, 0 и остаток 0.0001 при двух знаках точности
+// отклоняются 400 в обеих версиях API, а баланс не меняется.
+func TestSendZeroAmount(t *testing.T) {
+	env := newTestEnv(t, func(svc *service.Service) { svc.SetTransferScale(2) })
+	from := env.wallet(t, "100")
+	to := env.wallet(t, "0")
+
+	for _, amount := range []string{"-0.0", "0", "0.0001"} {
+		for _, target := range []string{"/api/send", "/api/v1/send"} {
+			rec := env.do(t, "POST", target, `{"from":"`+from+`","to":"`+to+`","amount":`+amount+`}`)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("POST %s amount %s: status = %d, want 400; body: %s", target, amount, rec.Code, rec.Body)
+			}
+		}
+	}
+	balance, err := env.svc.GetBalance(context.Background(), to)
+	if err != nil {
+		t.Fatalf("GetBalance: %v", err)
+	}
+	if !balance.IsZero() {
+		t.Errorf("receiver balance = %s, want 0", balance)
+	}
+}
+
+// TestSendAmountPresence проверяет, что отсутствующая сумма перевода отличается от нулевой:
+// без поля amount (или с null) нарушение - required, а ноль и отрицательная сумма - out_of_range.
+func TestSendAmountPresence(t *testing.T) {
+	env := newTestEnv(t, nil)
+	from := env.wallet(t, "100")
+	to := env.wallet(t, "0")
+
+	tests := []struct {
+		name     string
+		amount   string // Фрагмент тела с полем amount; "" - поля нет
+		wantCode string
+	}{
+		{"absent", "", violationRequired},
+		{"null", `,"amount":null`, violationType},
+		{"zero", `,"amount":0`, violationOutOfRange},
+		{"negative", `,"amount":-5`, violationOutOfRange},
+	}
+	for _, tt := range tests {
+		for _, target := range []string{"/api/send", "/api/v1/send"} {
+			t.Run(tt.name+target, func(t *testing.T) {
+				rec := env.do(t, "POST", target, `{"from":"`+from+`","to":"`+to+`"`+tt.amount+`}`)
+				if rec.Code != http.StatusBadRequest {
+					t.Fatalf("status = %d, want 400; body: %s", rec.Code, rec.Body)
+				}
+				if got := jsonPath(t, rec.Body.Bytes(), "error", "violations", "0", "field"); got != `"amount"` {
+					t.Errorf("violation field = %s, want \"amount\"; body: %s", got, rec.Body)
+				}
+				if got := jsonPath(t, rec.Body.Bytes(), "error", "violations", "0", "code"); got != `"`+tt.wantCode+`"` {
+					t.Errorf("violation code = %s, want %q; body: %s", got, tt.wantCode, rec.Body)
+				}
+			})
+		}
+	}
+}
+
+// TestImportAmountPresence проверяет то же различие при импорте транзакций.
+func TestImportAmountPresence(t *testing.T) {
+	env := newTestEnv(t, nil)
+	from := env.wallet(t, "0")
+	to := env.wallet(t, "0")
+
+	tests := []struct {
+		name        string
+		amount      string
+		wantStatus  int
+		wantMessage string
+	}{
+		{"absent", "", http.StatusBadRequest, "amount is required"},
+		{"null", `,"amount":null`, http.StatusBadRequest, "amount is required"},
+		{"zero", `,"amount":0`, http.StatusBadRequest, "amount must be greater than 0"},
+		{"negative", `,"amount":-5`, http.StatusBadRequest, "amount must be greater than 0"},
+		{"present", `,"amount":"12.5"`, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `[{"external_id":"ext-` + tt.name + `","from":"` + from + `","to":"` + to + `",` +
+				`"timestamp":"2024-01-01T00:00:00Z"` + tt.amount + `}]`
+			rec := serve(t, ImportHandler(env.svc), "POST", "/api/admin/import", body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantMessage == "" {
+				return
+			}
+			if got := jsonPath(t, rec.Body.Bytes(), "error", "message"); !strings.Contains(got, tt.wantMessage) {
+				t.Errorf("error message = %s, want %q", got, tt.wantMessage)
+			}
+		})
+	}
+}
+
+// TestSendCategory проверяет категорию перевода: недопустимая по длине отклоняется нарушением
+// поля category, не входящая в TRANSACTION_CATEGORIES - 422 invalid_category со списком
+// допустимых, а принятая возвращается в списках транзакций обеих версий API.
+func TestSendCategory(t *testing.T) {
+	tests := []struct {
+		name       string
+		category   string
+		wantStatus int
+	}{
+		{"no category", "", http.StatusOK},
+		{"allowed", "salary", http.StatusOK},
+		{"not allowed", "bonus", http.StatusUnprocessableEntity},
+		{"too long", strings.Repeat("a", models.MaxCategoryLength+1), http.StatusBadRequest},
+		{"control character", "sal\u0007ary", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, func(svc *service.Service) { svc.SetCategories([]string{"salary", "fee"}) })
+			from := env.wallet(t, "100")
+			to := env.wallet(t, "0")
+
+			category, _ := json.Marshal(tt.category)
+			body := `{"from":"` + from + `","to":"` + to + `","amount":1,"category":` + string(category) + `}`
+			rec := env.do(t, "POST", "/api/send", body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+
+			switch tt.wantStatus {
+			case http.StatusBadRequest:
+				if got := jsonPath(t, rec.Body.Bytes(), "error", "violations", "0", "field"); got != `"category"` {
+					t.Errorf("violation field = %s, want \"category\"; body: %s", got, rec.Body)
+				}
+				return
+			case http.StatusUnprocessableEntity:
+				if got := jsonPath(t, rec.Body.Bytes(), "error", "code"); got != `"invalid_category"` {
+					t.Errorf("error code = %s, want \"invalid_category\"", got)
+				}
+				if got := jsonPath(t, rec.Body.Bytes(), "error", "allowed_categories"); got != `["fee","salary"]` {
+					t.Errorf("allowed_categories = %s, want [\"fee\",\"salary\"]", got)
+				}
+				return
+			}
+			for _, target := range []string{"/api/transactions?count=1", "/api/v1/transactions?count=1"} {
+				rec := env.do(t, "GET", target, "")
+				var transactions []struct {
+					Category string `json:"category"`
+				}
+				if err := json.Unmarshal(rec.Body.Bytes(), &transactions); err != nil {
+					t.Fatalf("GET %s: decode %s: %v", target, rec.Body, err)
+				}
+				if len(transactions) != 1 || transactions[0].Category != tt.category {
+					t.Errorf("GET %s: %+v, want one transaction with category %q", target, transactions, tt.category)
+				}
+			}
+		})
+	}
+}
+
+// TestTransactionsCategoryFilter проверяет параметр category списка транзакций обеих версий API:
+// возвращаются только переводы этой категории, а без параметра - все, в том числе без категории.
+func TestTransactionsCategoryFilter(t *testing.T) {
+	env := newTestEnv(t, nil)
+	from := env.wallet(t, "100")
+	to := env.wallet(t, "0")
+	for _, category := range []string{"salary", "", "fee", "salary"} {
+		if _, err := env.svc.Send(context.Background(), from, to, decimal.NewFromInt(1), "", category, nil, sql.LevelDefault); err != nil {
+			t.Fatalf("Send(%q): %v", category, err)
+		}
+	}
+
+	tests := []struct {
+		query     string
+		wantCount int
+	}{
+		{"", 4},
+		{"category=salary", 2},
+		{"category=fee", 1},
+		{"category=refund", 0},
+		{"category=salary&amount=1", 2},
+		{"category=salary&amount=2", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			for _, target := range []string{"/api/transactions", "/api/v1/transactions"} {
+				rec := env.do(t, "GET", target+"?"+tt.query, "")
+				if rec.Code != http.StatusOK {
+					t.Fatalf("GET %s: status = %d; body: %s", target, rec.Code, rec.Body)
+				}
+				var transactions []struct {
+					Category string `json:"category"`
+				}
+				if err := json.Unmarshal(rec.Body.Bytes(), &transactions); err != nil {
+					t.Fatalf("GET %s: decode %s: %v", target, rec.Body, err)
+				}
+				if len(transactions) != tt.wantCount {
+					t.Errorf("GET %s?%s: got %d transactions, want %d", target, tt.query, len(transactions), tt.wantCount)
+				}
+				want, _ := url.ParseQuery(tt.query)
+				for _, tx := range transactions {
+					if c := want.Get("category"); c != "" && tx.Category != c {
+						t.Errorf("GET %s?%s: returned category %q", target, tt.query, tx.Category)
+					}
+				}
+			}
+		})
+	}
+}
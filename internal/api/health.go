@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	db "payment-system/internal/db"
+	service "payment-system/internal/service"
+)
+
+// readinessTimeout ограничивает время проверки готовности, чтобы зонд не зависал вместе с базой.
+const readinessTimeout = 2 * time.Second
+
+// HealthHandler возвращает HTTP-обработчик проверки живости процесса.
+// Всегда отвечает 200, пока процесс способен обрабатывать запросы. Ответ содержит версию
+// и коммит сборки, поэтому зонд заодно подтверждает, что развернута нужная версия.
+//
+// Параметры:
+//   - info: Сведения о сборке.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.HandleFunc("/healthz", HealthHandler(info)).Methods("GET")
+func HealthHandler(info BuildInfo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok", "version": info.Version, "commit": info.Commit})
+	}
+}
+
+// ReadyHandler возвращает HTTP-обработчик проверки готовности.
+// Отвечает 503, если хранилище недоступно, и перечисляет открытые автоматы отключения.
+// Как и HealthHandler, сообщает версию и коммит сборки.
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//   - info: Сведения о сборке.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.HandleFunc("/readyz", ReadyHandler(svc, info)).Methods("GET")
+func ReadyHandler(svc *service.Service, info BuildInfo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+
+		resp := map[string]interface{}{"status": "ready", "version": info.Version, "commit": info.Commit}
+		status := http.StatusOK
+
+		if err := svc.Ready(ctx); err != nil {
+			status = http.StatusServiceUnavailable
+			resp["status"] = "not ready"
+			resp["error"] = err.Error()
+			if errors.Is(err, db.ErrDatabaseUnavailable) {
+				resp["open_breakers"] = []string{"database"}
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(resp)
+	}
+}
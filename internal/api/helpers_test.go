@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	db "payment-system/internal/db"
+	models "payment-system/internal/models"
+	service "payment-system/internal/service"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+// testEnv - сервис на хранилище в памяти и маршрутизатор с маршрутами v1 и устаревшими
+// маршрутами, зарегистрированными так же, как в cmd/main.go.
+type testEnv struct {
+	repo        *db.MemoryRepository
+	svc         *service.Service
+	maintenance *MaintenanceMode
+	router      *mux.Router
+}
+
+// newTestEnv создает testEnv; configure, если задана, настраивает сервис до регистрации маршрутов.
+func newTestEnv(t *testing.T, configure func(svc *service.Service)) *testEnv {
+	t.Helper()
+	repo := db.NewMemoryRepository()
+	svc := service.NewService(repo)
+	if configure != nil {
+		configure(svc)
+	}
+	router := mux.NewRouter()
+	router.NotFoundHandler = NotFoundHandler()
+	router.MethodNotAllowedHandler = MethodNotAllowedHandler(router)
+	cfg := RoutesConfig{MaxTransactionsCount: 100, BalanceScale: 2}
+	maintenance := NewMaintenanceMode(false)
+	RegisterV1(router, svc, maintenance, nil, cfg)
+	RegisterLegacy(router, svc, maintenance, nil, cfg)
+	return &testEnv{repo: repo, svc: svc, maintenance: maintenance, router: router}
+}
+
+// wallet создает кошелек с балансом balance и возвращает его адрес.
+func (e *testEnv) wallet(t *testing.T, balance string) string {
+	t.Helper()
+	wallet, _, err := e.svc.CreateWallet(context.Background(), decimal.RequireFromString(balance), models.WalletMetadata{})
+	if err != nil {
+		t.Fatalf("CreateWallet(%s): %v", balance, err)
+	}
+	return wallet.Address
+}
+
+// send переводит amount с from на to в обход HTTP.
+func (e *testEnv) send(t *testing.T, from, to, amount string) {
+	t.Helper()
+	if _, err := e.svc.Send(context.Background(), from, to, decimal.RequireFromString(amount), "", "", nil, sql.LevelDefault); err != nil {
+		t.Fatalf("Send(%s): %v", amount, err)
+	}
+}
+
+// do выполняет запрос к маршрутизатору и возвращает ответ; header - пары имя, значение.
+func (e *testEnv) do(t *testing.T, method, target, body string, header ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	return serve(t, e.router, method, target, body, header...)
+}
+
+// serve выполняет запрос к обработчику h и возвращает ответ; header - пары имя, значение.
+func serve(t *testing.T, h http.Handler, method, target, body string, header ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
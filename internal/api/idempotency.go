@@ -0,0 +1,164 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	db "payment-system/internal/db"
+	"payment-system/internal/logging"
+	service "payment-system/internal/service"
+)
+
+// IdempotencyKeyHeader - заголовок с ключом идемпотентности запроса перевода. Повтор запроса
+// с тем же ключом получает сохраненный ответ, а не выполняет перевод еще раз.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// TransferOutcomeHeader - заголовок ответа 503, в котором обработчик перевода сообщает,
+// что перевод мог быть выполнен (значение "unknown", см. writeOutcomeUnknown).
+const TransferOutcomeHeader = "X-Transfer-Outcome"
+
+// maxIdempotencyKeyLength - наибольшая длина ключа идемпотентности.
+const maxIdempotencyKeyLength = 255
+
+// DefaultIdempotencyTTL - срок хранения ответа по ключу идемпотентности по умолчанию.
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// idempotencyRecorder передает ответ клиенту и сохраняет копию тела для повторов.
+type idempotencyRecorder struct {
+	*responseWriter
+	body bytes.Buffer
+}
+
+// Write передает тело дальше и сохраняет его копию.
+func (w *idempotencyRecorder) Write(p []byte) (int, error) {
+	w.body.Write(p)
+	return w.responseWriter.Write(p)
+}
+
+// IdempotencyMiddleware делает запрос с заголовком Idempotency-Key идемпотентным: первый запрос
+// занимает ключ и выполняется, его ответ сохраняется на ttl, а повторы получают сохраненный
+// ответ с заголовком Idempotent-Replayed: true. Клиент может повторять перевод после обрыва
+// соединения, не рискуя выполнить его дважды. Ответы 5xx не сохраняются: ключ освобождается,
+// и запрос можно повторить. Это относится и к ответу с X-Transfer-Outcome: unknown: иначе ключ
+// оставался бы занятым до истечения ttl и повтор, который нужен, если перевод не выполнен,
+// получал бы 409. Выполнен ли перевод, клиент проверяет по списку транзакций.
+//
+// Повтор, пока первый запрос выполняется, получает 409 (idempotency_key_in_use), повтор ключа
+// с другим методом, путем, телом или правами вызывающего - 422 (idempotency_key_reused).
+// Права входят в отпечаток, потому что ответ администратору содержит больше сведений
+// (to_balance), и повтор без токена не должен их получить. Запросы без заголовка передаются
+// обработчику без изменений.
+//
+// Параметры:
+//   - svc: Сервис, хранящий ключи.
+//   - ttl: Срок хранения ответа.
+//   - adminToken: Токен администратора (ADMIN_TOKEN), по которому определяются права вызывающего.
+//
+// Пример использования:
+//
+//	router.Handle("/api/v1/send", IdempotencyMiddleware(svc, 24*time.Hour, cfg.AdminToken)(SendV1Handler(svc, cfg.AdminToken))).Methods("POST")
+func IdempotencyMiddleware(svc *service.Service, ttl time.Duration, adminToken string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				writeJSONError(w, http.StatusBadRequest, "invalid_request",
+					fmt.Sprintf("Header %s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength))
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
+			if writeTooLarge(w, err) {
+				return
+			}
+			if err != nil {
+				http.Error(w, "Failed to read request body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			// Отпечаток отличает повтор от нового запроса, ошибочно отправленного с тем же ключом
+			scope := "public"
+			if hasAdminToken(r, adminToken) {
+				scope = "admin"
+			}
+			hash := sha256.New()
+			fmt.Fprintf(hash, "%s %s %s\n", r.Method, r.URL.Path, scope)
+			hash.Write(body)
+			fingerprint := hex.EncodeToString(hash.Sum(nil))
+
+			stored, reserved, err := svc.ReserveIdempotencyKey(r.Context(), key, fingerprint, ttl)
+			if writeUnavailable(w, err) {
+				return
+			}
+			if err != nil {
+				writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+				return
+			}
+			if !reserved {
+				replayIdempotent(w, stored, fingerprint)
+				return
+			}
+
+			rec := &idempotencyRecorder{responseWriter: newResponseWriter(w)}
+			completed := false
+			defer func() {
+				// Ключ запроса, прерванного паникой или ответившего 5xx, освобождается
+				if completed {
+					return
+				}
+				if err := svc.ReleaseIdempotencyKey(r.Context(), key); err != nil {
+					logging.FromContext(r.Context()).Error("failed to release idempotency key", "key", key, "error", err)
+				}
+			}()
+			next.ServeHTTP(rec, r)
+
+			if rec.status >= http.StatusInternalServerError {
+				return
+			}
+			completed = true
+			err = svc.CompleteIdempotencyKey(r.Context(), key, db.IdempotentResponse{
+				Status:      rec.status,
+				ContentType: rec.Header().Get("Content-Type"),
+				Location:    rec.Header().Get("Location"),
+				Body:        rec.body.String(),
+			})
+			if err != nil {
+				// Ответ уже отправлен; повтор получит 409, пока ключ не истечет
+				logging.FromContext(r.Context()).Error("failed to store idempotent response", "key", key, "error", err)
+			}
+		})
+	}
+}
+
+// replayIdempotent отвечает на повтор запроса с уже занятым ключом идемпотентности.
+func replayIdempotent(w http.ResponseWriter, stored db.IdempotentResponse, fingerprint string) {
+	switch {
+	case stored.Fingerprint != fingerprint:
+		writeJSONError(w, http.StatusUnprocessableEntity, "idempotency_key_reused",
+			"Idempotency key was already used for a different request")
+	case stored.Status == 0:
+		w.Header().Set("Retry-After", "1")
+		writeJSONError(w, http.StatusConflict, "idempotency_key_in_use",
+			"A request with this idempotency key is still in progress")
+	default:
+		if stored.ContentType != "" {
+			w.Header().Set("Content-Type", stored.ContentType)
+		}
+		if stored.Location != "" {
+			w.Header().Set("Location", stored.Location)
+		}
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(stored.Status)
+		io.WriteString(w, stored.Body)
+	}
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	db "payment-system/internal/db"
+	service "payment-system/internal/service"
+)
+
+// countingHandler отвечает ответом respond и считает вызовы.
+type countingHandler struct {
+	calls   int
+	respond func(w http.ResponseWriter)
+}
+
+func (h *countingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.calls++
+	io.Copy(io.Discard, r.Body)
+	h.respond(w)
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		respond    func(w http.ResponseWriter)
+		wantStatus int // Статус повтора
+		wantCalls  int // Вызовы обработчика за запрос и повтор
+	}{
+		{
+			name:       "success is replayed",
+			respond:    func(w http.ResponseWriter) { writeJSON(w, http.StatusCreated, map[string]int{"transaction_id": 1}) },
+			wantStatus: http.StatusCreated,
+			wantCalls:  1,
+		},
+		{
+			name:       "client error is replayed",
+			respond:    func(w http.ResponseWriter) { writeJSONError(w, http.StatusBadRequest, "invalid_request", "bad") },
+			wantStatus: http.StatusBadRequest,
+			wantCalls:  1,
+		},
+		{
+			name: "server error releases the key",
+			respond: func(w http.ResponseWriter) {
+				writeJSONError(w, http.StatusInternalServerError, "internal_error", "boom")
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantCalls:  2,
+		},
+		{
+			// Повтор с тем же ключом нужен, если перевод не выполнен, и не должен получать 409 до истечения ttl
+			name:       "unknown outcome releases the key",
+			respond:    writeOutcomeUnknown,
+			wantStatus: http.StatusServiceUnavailable,
+			wantCalls:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := service.NewService(db.NewMemoryRepository())
+			next := &countingHandler{respond: tt.respond}
+			h := IdempotencyMiddleware(svc, time.Hour, "")(next)
+			body := `{"from":"a","to":"b","amount":1}`
+
+			first := serve(t, h, "POST", "/api/v1/send", body, IdempotencyKeyHeader, "key-1")
+			retry := serve(t, h, "POST", "/api/v1/send", body, IdempotencyKeyHeader, "key-1")
+			if retry.Code != tt.wantStatus {
+				t.Errorf("retry status = %d, want %d; body: %s", retry.Code, tt.wantStatus, retry.Body)
+			}
+			if next.calls != tt.wantCalls {
+				t.Errorf("handler calls = %d, want %d", next.calls, tt.wantCalls)
+			}
+			replayed := retry.Header().Get("Idempotent-Replayed") == "true"
+			if replayed != (tt.wantCalls == 1) {
+				t.Errorf("Idempotent-Replayed = %t, want %t", replayed, tt.wantCalls == 1)
+			}
+			if tt.wantCalls == 1 && retry.Body.String() != first.Body.String() {
+				t.Errorf("replayed body = %s, want %s", retry.Body, first.Body)
+			}
+		})
+	}
+}
+
+func TestIdempotencyMiddlewareKeyReuse(t *testing.T) {
+	svc := service.NewService(db.NewMemoryRepository())
+	next := &countingHandler{respond: func(w http.ResponseWriter) { writeJSON(w, http.StatusOK, map[string]bool{"ok": true}) }}
+	h := IdempotencyMiddleware(svc, time.Hour, "secret")(next)
+	admin := "Bearer secret"
+
+	serve(t, h, "POST", "/api/v1/send", `{"amount":1}`, IdempotencyKeyHeader, "key-1", "Authorization", admin)
+	tests := []struct {
+		name          string
+		target        string
+		body          string
+		authorization string
+		wantStatus    int
+	}{
+		{"same request", "/api/v1/send", `{"amount":1}`, admin, http.StatusOK},
+		{"another body", "/api/v1/send", `{"amount":2}`, admin, http.StatusUnprocessableEntity},
+		{"another path", "/api/send", `{"amount":1}`, admin, http.StatusUnprocessableEntity},
+		// Ответ администратору содержит to_balance и не должен повторяться вызывающему без токена
+		{"without admin token", "/api/v1/send", `{"amount":1}`, "", http.StatusUnprocessableEntity},
+		{"wrong admin token", "/api/v1/send", `{"amount":1}`, "Bearer guess", http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(t, h, "POST", tt.target, tt.body, IdempotencyKeyHeader, "key-1", "Authorization", tt.authorization)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+	if next.calls != 1 {
+		t.Errorf("handler calls = %d, want 1", next.calls)
+	}
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	models "payment-system/internal/models"
+	service "payment-system/internal/service"
+
+	"github.com/shopspring/decimal"
+)
+
+// Ограничения импорта исторических транзакций.
+const (
+	maxImportBatch      = 10000 // Максимальное количество транзакций в одном запросе
+	maxExternalIDLength = 128   // Максимальная длина внешнего идентификатора
+)
+
+// importItem - одна импортируемая транзакция в теле запроса.
+type importItem struct {
+	ExternalID string           `json:"external_id"`
+	From       string           `json:"from"`
+	To         string           `json:"to"`
+	Amount     *decimal.Decimal `json:"amount"` // Указатель: отсутствующая сумма отличается от нулевой
+	Timestamp  time.Time        `json:"timestamp"`
+	Memo       string           `json:"memo"`
+	Category   string           `json:"category"`
+	Type       string           `json:"type"` // Пустой тип - перевод (models.TransactionTransfer)
+}
+
+// ImportHandler возвращает HTTP-обработчик импорта исторических транзакций из другой системы.
+// Принимает JSON-массив транзакций с исходным временем (RFC 3339) и внешним идентификатором,
+// сохраняет их одной транзакцией базы с признаком imported, не изменяя балансы.
+// Транзакции с уже импортированным external_id пропускаются, поэтому запрос можно безопасно повторить.
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	admin.HandleFunc("/import", ImportHandler(svc)).Methods("POST")
+func ImportHandler(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var items []importItem
+		if err := decodeJSONBody(r.Body, &items); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		if len(items) == 0 || len(items) > maxImportBatch {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request",
+				fmt.Sprintf("Import must contain from 1 to %d transactions", maxImportBatch))
+			return
+		}
+
+		transactions := make([]models.Transaction, 0, len(items))
+		seen := make(map[string]bool, len(items))
+		for i, item := range items {
+			if err := validateImportItem(item); err != nil {
+				writeJSONError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("Transaction %d: %v", i, err))
+				return
+			}
+			// Дубликаты внутри одного запроса пропускаются так же, как уже импортированные
+			if seen[item.ExternalID] {
+				continue
+			}
+			seen[item.ExternalID] = true
+
+			// Адреса с контрольной суммой сохраняются в нижнем регистре, как и все адреса
+			transactions = append(transactions, models.Transaction{
+				From:       strings.ToLower(item.From),
+				To:         strings.ToLower(item.To),
+				Amount:     *item.Amount,
+				CreatedAt:  item.Timestamp,
+				Memo:       item.Memo,
+				Category:   item.Category,
+				ExternalID: item.ExternalID,
+				Type:       item.Type,
+			})
+		}
+
+		imported, err := svc.ImportTransactions(r.Context(), transactions)
+		if writeUnavailable(w, err) {
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int{
+			"imported": imported,
+			"skipped":  len(items) - imported,
+		})
+	}
+}
+
+// validateImportItem проверяет одну импортируемую транзакцию.
+func validateImportItem(item importItem) error {
+	if item.ExternalID == "" || len(item.ExternalID) > maxExternalIDLength {
+		return fmt.Errorf("external_id is required and must be at most %d characters", maxExternalIDLength)
+	}
+	if !IsValidAddress(item.From) || !IsValidAddress(item.To) {
+		return fmt.Errorf("invalid wallet address")
+	}
+	if item.Amount == nil {
+		return fmt.Errorf("amount is required")
+	}
+	if item.Amount.Sign() <= 0 {
+		return fmt.Errorf("amount must be greater than 0")
+	}
+	if err := models.ValidateAmountScale(*item.Amount); err != nil {
+		return err
+	}
+	if item.Timestamp.IsZero() {
+		return fmt.Errorf("timestamp is required")
+	}
+	if err := validateMemo(item.Memo); err != nil {
+		return err
+	}
+	if err := models.ValidateTransactionType(item.Type); err != nil {
+		return err
+	}
+	return validateCategory(item.Category)
+}
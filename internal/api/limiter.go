@@ -0,0 +1,184 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"payment-system/internal/metrics"
+)
+
+// ConcurrencyLimiter ограничивает количество одновременно выполняемых запросов группы маршрутов
+// (например, переводов): при всплеске нагрузки они не занимают все подключения к базе,
+// и запросы на чтение и /healthz продолжают обслуживаться. Запрос сверх ограничения ждет
+// освобождения места не дольше времени ожидания, затем получает 503 с Retry-After.
+// Ограничение и время ожидания можно менять во время работы (SetLimits).
+// Безопасен для одновременного использования из нескольких горутин.
+type ConcurrencyLimiter struct {
+	group string // Метка группы маршрутов в метриках
+
+	mu       sync.Mutex
+	limit    int           // Наибольшее количество одновременных запросов; 0 - без ограничения
+	wait     time.Duration // Сколько запрос ждет места; 0 - отклоняется сразу
+	inFlight int           // Выполняемые сейчас запросы
+	released chan struct{} // Закрывается при освобождении места, чтобы разбудить ожидающих
+}
+
+// NewConcurrencyLimiter создает ограничитель группы маршрутов group.
+//
+// Параметры:
+//   - group: Название группы для метрик payment_http_in_flight_requests и
+//     payment_http_rejected_requests_total.
+//   - limit: Наибольшее количество одновременных запросов; 0 - без ограничения.
+//   - wait: Наибольшее время ожидания места; 0 - запрос сверх ограничения отклоняется сразу.
+//
+// Пример использования:
+//
+//	limiter := NewConcurrencyLimiter("send", 50, 100*time.Millisecond)
+func NewConcurrencyLimiter(group string, limit int, wait time.Duration) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{group: group, limit: limit, wait: wait, released: make(chan struct{})}
+}
+
+// Limits возвращает текущие ограничение и время ожидания.
+func (l *ConcurrencyLimiter) Limits() (int, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit, l.wait
+}
+
+// SetLimits меняет ограничение и время ожидания. Выполняемые запросы не прерываются;
+// если ограничение увеличено, ожидающие запросы получают место сразу.
+//
+// Параметры:
+//   - limit: Наибольшее количество одновременных запросов; 0 - без ограничения.
+//   - wait: Наибольшее время ожидания места; 0 - отклонять сразу.
+func (l *ConcurrencyLimiter) SetLimits(limit int, wait time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit, l.wait = limit, wait
+	l.wake()
+}
+
+// acquire занимает место для запроса, ожидая его не дольше l.wait.
+//
+// Возвращает:
+//   - false, если место не освободилось вовремя или запрос отменен клиентом.
+func (l *ConcurrencyLimiter) acquire(r *http.Request) bool {
+	var timeout <-chan time.Time
+	for {
+		l.mu.Lock()
+		if l.limit <= 0 || l.inFlight < l.limit {
+			l.inFlight++
+			l.mu.Unlock()
+			return true
+		}
+		released, wait := l.released, l.wait
+		l.mu.Unlock()
+
+		if wait <= 0 {
+			return false
+		}
+		// Время ожидания отсчитывается от первой попытки, а не от каждого пробуждения
+		if timeout == nil {
+			timer := time.NewTimer(wait)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		select {
+		case <-released:
+		case <-timeout:
+			return false
+		case <-r.Context().Done():
+			return false
+		}
+	}
+}
+
+// release освобождает место запроса.
+func (l *ConcurrencyLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+	l.wake()
+}
+
+// wake будит ожидающие запросы; вызывается под l.mu.
+func (l *ConcurrencyLimiter) wake() {
+	close(l.released)
+	l.released = make(chan struct{})
+}
+
+// Middleware оборачивает обработчик группы: запрос, не получивший места, отклоняется с 503,
+// JSON-ошибкой "overloaded" и заголовком Retry-After.
+//
+// Пример использования:
+//
+//	router.Handle("/api/send", limiter.Middleware(SendHandler(svc))).Methods("POST")
+func (l *ConcurrencyLimiter) Middleware(next http.Handler) http.Handler {
+	inFlight := metrics.HTTPInFlightRequests.WithLabelValues(l.group)
+	rejected := metrics.HTTPRejectedRequests.WithLabelValues(l.group)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.acquire(r) {
+			rejected.Inc()
+			w.Header().Set("Retry-After", "1")
+			writeJSONError(w, http.StatusServiceUnavailable, "overloaded",
+				"Too many concurrent requests, retry later")
+			return
+		}
+		inFlight.Inc()
+		defer func() {
+			inFlight.Dec()
+			l.release()
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// concurrencyLimits - тело запроса и ответа ConcurrencyLimitHandler.
+type concurrencyLimits struct {
+	MaxInFlight  *int   `json:"max_in_flight"` // 0 - без ограничения
+	QueueTimeout string `json:"queue_timeout"` // Длительность, например "100ms"; "0s" - не ждать
+}
+
+// ConcurrencyLimitHandler возвращает HTTP-обработчик для просмотра и изменения ограничения
+// одновременных запросов без перезапуска. GET возвращает текущие значения,
+// PUT принимает {"max_in_flight": 50, "queue_timeout": "100ms"}; queue_timeout необязателен.
+//
+// Параметры:
+//   - limiter: Ограничитель группы маршрутов.
+//
+// Пример использования:
+//
+//	admin.HandleFunc("/limits/send", ConcurrencyLimitHandler(limiter)).Methods("GET", "PUT")
+func ConcurrencyLimitHandler(limiter *ConcurrencyLimiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			var req concurrencyLimits
+			if err := decodeJSONBody(r.Body, &req); err != nil {
+				writeJSONError(w, http.StatusBadRequest, "invalid_request", err.Error())
+				return
+			}
+			if req.MaxInFlight == nil || *req.MaxInFlight < 0 {
+				writeJSONError(w, http.StatusBadRequest, "invalid_request",
+					"Field \"max_in_flight\" is required and must be non-negative")
+				return
+			}
+			_, wait := limiter.Limits()
+			if req.QueueTimeout != "" {
+				parsed, err := time.ParseDuration(req.QueueTimeout)
+				if err != nil || parsed < 0 {
+					writeJSONError(w, http.StatusBadRequest, "invalid_request",
+						"Field \"queue_timeout\" must be a non-negative duration, e.g. \"100ms\"")
+					return
+				}
+				wait = parsed
+			}
+			limiter.SetLimits(*req.MaxInFlight, wait)
+		}
+
+		limit, wait := limiter.Limits()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(concurrencyLimits{MaxInFlight: &limit, QueueTimeout: wait.String()})
+	}
+}
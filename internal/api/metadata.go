@@ -0,0 +1,290 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	errs "payment-system/internal/errs"
+	models "payment-system/internal/models"
+	service "payment-system/internal/service"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+// Ограничения списка кошельков с наибольшим балансом.
+const (
+	defaultTopWalletsLimit = 10  // Количество кошельков без параметра limit
+	maxTopWalletsLimit     = 100 // Наибольшее значение параметра limit
+)
+
+// updatedWalletResponse - ответ на изменение метаданных кошелька. Баланс и адрес для уведомлений
+// к изменению не относятся и в ответ не попадают.
+type updatedWalletResponse struct {
+	Address string            `json:"address"`
+	Label   string            `json:"label,omitempty"`
+	Tags    map[string]string `json:"tags,omitempty"`
+}
+
+// createWalletRequest - тело запроса создания кошелька с метаданными.
+type createWalletRequest struct {
+	Balance decimal.Decimal `json:"balance"`
+	models.WalletMetadata
+}
+
+// UpdateWalletHandler возвращает HTTP-обработчик PATCH /api/wallet/{address}, изменяющий
+// метку, теги и адрес для уведомлений кошелька. Принимает {"label": "...", "tags": {...},
+// "notify_email": "..."}: отсутствующие поля не изменяются, пустая метка удаляет метку, пустой
+// объект тегов удаляет все теги, пустой notify_email отключает уведомления о поступлении средств.
+// Отвечает адресом, меткой и тегами кошелька после изменения; 404, если кошелька нет,
+// 409, если метка занята. Маршрут доступен только администратору: по метке выбирается
+// получатель перевода "@label".
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.Handle("/api/wallet/{address}", admin(UpdateWalletHandler(svc))).Methods("PATCH")
+func UpdateWalletHandler(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		address, err := parseAddress(mux.Vars(r)["address"])
+		if err != nil {
+			writeAddressError(w, err, "Invalid wallet address")
+			return
+		}
+
+		var patch models.WalletMetadataPatch
+		if err := decodeJSONBody(r.Body, &patch); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		if err := patch.Validate(); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+
+		wallet, err := svc.UpdateWalletMetadata(r.Context(), address, patch)
+		if writeWalletError(w, err) {
+			return
+		}
+		writeJSON(w, http.StatusOK, updatedWalletResponse{Address: wallet.Address, Label: wallet.Label, Tags: wallet.Tags})
+	}
+}
+
+// FindWalletsHandler возвращает HTTP-обработчик GET /api/wallets?label=..., который ищет
+// кошелек по метке. Отвечает массивом кошельков (пустым, если метка никому не присвоена),
+// чтобы поиск по другим условиям можно было добавить без изменения формата ответа.
+// Архивные кошельки возвращаются только с параметром include_archived=true.
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.HandleFunc("/api/wallets", FindWalletsHandler(svc)).Methods("GET")
+func FindWalletsHandler(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		label := r.URL.Query().Get("label")
+		if label == "" {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", "Parameter 'label' is required")
+			return
+		}
+		includeArchived := false
+		if value := r.URL.Query().Get("include_archived"); value != "" {
+			var err error
+			if includeArchived, err = strconv.ParseBool(value); err != nil {
+				writeJSONError(w, http.StatusBadRequest, "invalid_request",
+					fmt.Sprintf("Parameter 'include_archived' must be true or false, got %q", value))
+				return
+			}
+		}
+
+		wallets := []numberBalanceWallet{}
+		wallet, err := svc.FindWalletByLabel(r.Context(), label)
+		if writeUnavailable(w, err) {
+			return
+		}
+		switch {
+		case err == nil:
+			if wallet.ArchivedAt == nil || includeArchived {
+				// Метка известна всем, кто переводит на кошелек, а адрес для уведомлений -
+				// только владельцу, поэтому поиск по метке его не раскрывает
+				wallet.NotifyEmail = ""
+				wallets = append(wallets, withNumberBalance(wallet))
+			}
+		case !errors.Is(err, errs.ErrWalletNotFound):
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, wallets)
+	}
+}
+
+// TopWalletsHandler возвращает HTTP-обработчик GET /api/wallets/top?limit=N, который отвечает
+// массивом активных кошельков с наибольшим балансом по убыванию баланса (при равном
+// балансе - по адресу). Без limit возвращается 10 кошельков, limit больше 100 уменьшается до 100;
+// нечисловое или неположительное значение отклоняется с 400.
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.Handle("/api/wallets/top", admin(TopWalletsHandler(svc))).Methods("GET")
+func TopWalletsHandler(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit := defaultTopWalletsLimit
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			n, err := strconv.Atoi(limitStr)
+			if err != nil || n <= 0 {
+				writeJSONError(w, http.StatusBadRequest, "invalid_request",
+					fmt.Sprintf("Parameter 'limit' must be a positive integer, got %q", limitStr))
+				return
+			}
+			limit = min(n, maxTopWalletsLimit)
+		}
+
+		wallets, err := svc.TopWalletsByBalance(r.Context(), limit)
+		if writeUnavailable(w, err) {
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, withNumberBalances(wallets))
+	}
+}
+
+// CreateWalletHandler возвращает HTTP-обработчик создания кошелька со случайным адресом,
+// начальным балансом и необязательными меткой и тегами.
+// Принимает {"balance": 100, "label": "ops-float", "tags": {...}} и отвечает 201 с кошельком,
+// его открытым ключом и закрытым ключом "private_key". Закрытый ключ не сохраняется
+// и возвращается только в этом ответе. Тело проверяется по схеме schemas/create_wallet.json;
+// все нарушения возвращаются одним ответом 400 validation_failed, как у перевода.
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.Handle("/api/admin/wallets", admin(CreateWalletHandler(svc))).Methods("POST")
+func CreateWalletHandler(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
+		if writeTooLarge(w, err) {
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", "Failed to read request body")
+			return
+		}
+		var doc interface{}
+		if err := decodeJSONBody(bytes.NewReader(body), &doc); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		violations := validateSchema(createWalletSchema, doc)
+
+		// Поле неверного типа уже описано нарушением схемы, остальные поля декодируются
+		var req createWalletRequest
+		if err := decodeJSONBody(bytes.NewReader(body), &req); err != nil && len(violations) == 0 {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		violations = append(violations, walletViolations(&req, violations)...)
+		if len(violations) > 0 {
+			writeViolations(w, violations)
+			return
+		}
+
+		wallet, privateKey, err := svc.CreateWallet(r.Context(), req.Balance, req.WalletMetadata)
+		if writeWalletError(w, err) {
+			return
+		}
+		writeJSON(w, http.StatusCreated, struct {
+			numberBalanceWallet
+			PrivateKey string `json:"private_key"`
+		}{withNumberBalance(wallet), privateKey})
+	}
+}
+
+// walletViolations проверяет то, что схема создания кошелька не выражает: знаки после запятой
+// в балансе, метку из одних пробелов и формат адреса для уведомлений. Поля, для которых уже
+// есть нарушение схемы, пропускаются.
+//
+// Параметры:
+//   - req: Декодированное тело запроса.
+//   - violations: Нарушения схемы.
+//
+// Возвращает:
+//   - Дополнительные нарушения.
+func walletViolations(req *createWalletRequest, violations []violation) []violation {
+	var found []violation
+	if !hasViolation(violations, "balance") && models.ValidateAmountScale(req.Balance) != nil {
+		found = append(found, violation{Field: "balance", Code: violationPrecision,
+			Message: fmt.Sprintf("balance must have at most %d decimal places", models.AmountScale)})
+	}
+	if !hasViolation(violations, "label") && models.ValidateLabel(req.Label) != nil {
+		found = append(found, violation{Field: "label", Code: violationFormat, Message: "label must not consist only of whitespace"})
+	}
+	if !hasViolation(violations, "notify_email") && models.ValidateEmail(req.NotifyEmail) != nil {
+		found = append(found, violation{Field: "notify_email", Code: violationFormat,
+			Message: "notify_email must be a single email address, e.g. alice@example.com"})
+	}
+	return found
+}
+
+// writeWalletError отвечает ошибкой операции с кошельком: 503 при недоступной базе,
+// 404, если кошелька нет, 409, если метка занята, кошелек архивирован или его нельзя
+// архивировать, иначе 500.
+//
+// Возвращает:
+//   - true, если ошибка была и ответ записан.
+func writeWalletError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case writeUnavailable(w, err):
+	case errors.Is(err, errs.ErrWalletNotFound):
+		writeJSONError(w, http.StatusNotFound, "not_found", "Wallet not found")
+	case errors.Is(err, errs.ErrLabelExists):
+		writeJSONError(w, http.StatusConflict, "label_exists", "Wallet label is already taken")
+	case errors.Is(err, errs.ErrWalletNotEmpty):
+		writeJSONError(w, http.StatusConflict, "wallet_not_empty", "Wallet balance must be zero to archive it")
+	case errors.Is(err, errs.ErrWalletHasHolds):
+		writeJSONError(w, http.StatusConflict, "wallet_has_holds", "Wallet has transfers awaiting approval")
+	case errors.Is(err, errs.ErrWalletArchived):
+		writeJSONError(w, http.StatusConflict, "wallet_archived", "Wallet is archived")
+	case errors.Is(err, errs.ErrSystemAccount):
+		writeJSONError(w, http.StatusForbidden, "system_account", "System accounts cannot be archived or rotated")
+	default:
+		writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+	}
+	return true
+}
+
+// writeJSON отвечает значением v в формате JSON с указанным кодом.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// MaintenanceMode - переключатель режима обслуживания (только чтение).
+// Пока режим включен, изменяющие операции отклоняются с 503, а GET-запросы работают как обычно.
+// Безопасен для одновременного использования из нескольких горутин.
+type MaintenanceMode struct {
+	readOnly atomic.Bool
+}
+
+// NewMaintenanceMode создает переключатель с начальным состоянием readOnly.
+//
+// Пример использования:
+//
+//	mode := NewMaintenanceMode(os.Getenv("READ_ONLY") == "true")
+func NewMaintenanceMode(readOnly bool) *MaintenanceMode {
+	m := &MaintenanceMode{}
+	m.readOnly.Store(readOnly)
+	return m
+}
+
+// ReadOnly сообщает, включен ли режим только для чтения.
+func (m *MaintenanceMode) ReadOnly() bool {
+	return m.readOnly.Load()
+}
+
+// SetReadOnly включает или выключает режим только для чтения.
+func (m *MaintenanceMode) SetReadOnly(readOnly bool) {
+	m.readOnly.Store(readOnly)
+}
+
+// Middleware оборачивает изменяющий обработчик: в режиме только для чтения
+// вместо вызова обработчика отвечает 503 с JSON-ошибкой "maintenance".
+//
+// Пример использования:
+//
+//	router.Handle("/api/send", mode.Middleware(SendHandler(svc))).Methods("POST")
+func (m *MaintenanceMode) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.ReadOnly() {
+			writeJSONError(w, http.StatusServiceUnavailable, "maintenance",
+				"Service is in read-only maintenance mode, try again later")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ReadOnlyHandler возвращает HTTP-обработчик для просмотра и переключения режима только для чтения.
+// GET возвращает текущее состояние, PUT принимает {"enabled": true|false}.
+//
+// Параметры:
+//   - mode: Переключатель режима обслуживания.
+//
+// Пример использования:
+//
+//	admin.HandleFunc("/read-only", ReadOnlyHandler(mode)).Methods("GET", "PUT")
+func ReadOnlyHandler(mode *MaintenanceMode) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			var req struct {
+				Enabled *bool `json:"enabled"`
+			}
+			if err := decodeJSONBody(r.Body, &req); err != nil {
+				writeJSONError(w, http.StatusBadRequest, "invalid_request", err.Error())
+				return
+			}
+			if req.Enabled == nil {
+				writeJSONError(w, http.StatusBadRequest, "invalid_request", "Field \"enabled\" is required")
+				return
+			}
+			mode.SetReadOnly(*req.Enabled)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"enabled": mode.ReadOnly()})
+	}
+}
+
+// AdminAuthMiddleware пропускает запрос только с заголовком "Authorization: Bearer <token>".
+// Сравнение выполняется за постоянное время.
+//
+// Параметры:
+//   - token: Токен администратора.
+//
+// Пример использования:
+//
+//	admin.Use(AdminAuthMiddleware(cfg.AdminToken))
+func AdminAuthMiddleware(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !hasAdminToken(r, token) {
+				writeJSONError(w, http.StatusUnauthorized, "unauthorized", "Admin token required")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// hasAdminToken сообщает, что запрос содержит заголовок "Authorization: Bearer <token>".
+// Пустой token не совпадает ни с каким запросом. Сравнение выполняется за постоянное время.
+func hasAdminToken(r *http.Request, token string) bool {
+	provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && token != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1
+}
+
+// writeJSONError записывает ошибку в формате {"error": {"code": ..., "message": ...}}.
+//
+// Параметры:
+//   - w: Ответ HTTP.
+//   - status: Код ответа.
+//   - code: Машиночитаемый код ошибки.
+//   - message: Описание ошибки для человека.
+func writeJSONError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]map[string]string{
+		"error": {"code": code, "message": message},
+	})
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestReadOnlyMode переключает режим только для чтения через ReadOnlyHandler и проверяет,
+// что переводы обеих версий API отклоняются 503, а чтение продолжает работать.
+func TestReadOnlyMode(t *testing.T) {
+	env := newTestEnv(t, nil)
+	from := env.wallet(t, "100")
+	to := env.wallet(t, "0")
+	toggle := ReadOnlyHandler(env.maintenance)
+	send := `{"from":"` + from + `","to":"` + to + `","amount":1}`
+
+	steps := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"initially writable", "GET", "/api/admin/read-only", "", http.StatusOK, `{"enabled":false}`},
+		{"send before", "POST", "/api/send", send, http.StatusOK, ""},
+		{"enable", "PUT", "/api/admin/read-only", `{"enabled":true}`, http.StatusOK, `{"enabled":true}`},
+		{"legacy send", "POST", "/api/send", send, http.StatusServiceUnavailable, `"code":"maintenance"`},
+		{"v1 send", "POST", "/api/v1/send", send, http.StatusServiceUnavailable, `"code":"maintenance"`},
+		{"balance", "GET", "/api/wallet/" + from + "/balance", "", http.StatusOK, ""},
+		{"transactions", "GET", "/api/v1/transactions", "", http.StatusOK, ""},
+		{"disable", "PUT", "/api/admin/read-only", `{"enabled":false}`, http.StatusOK, `{"enabled":false}`},
+		{"send after", "POST", "/api/v1/send", send, http.StatusCreated, ""},
+	}
+	for _, step := range steps {
+		h := http.Handler(env.router)
+		if strings.HasPrefix(step.target, "/api/admin/") {
+			h = toggle
+		}
+		got := serve(t, h, step.method, step.target, step.body)
+		if got.Code != step.wantStatus {
+			t.Fatalf("%s: status = %d, want %d; body: %s", step.name, got.Code, step.wantStatus, got.Body)
+		}
+		if !strings.Contains(got.Body.String(), step.wantBody) {
+			t.Errorf("%s: body %s does not contain %s", step.name, got.Body, step.wantBody)
+		}
+	}
+}
+
+func TestReadOnlyHandlerInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"missing enabled", `{}`},
+		{"wrong type", `{"enabled":"yes"}`},
+		{"not json", `enable`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode := NewMaintenanceMode(false)
+			rec := serve(t, ReadOnlyHandler(mode), "PUT", "/api/admin/read-only", tt.body)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400; body: %s", rec.Code, rec.Body)
+			}
+			if mode.ReadOnly() {
+				t.Error("invalid request enabled read-only mode")
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	db "payment-system/internal/db"
+	service "payment-system/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// GetNonceHandler возвращает HTTP-обработчик GET /api/wallet/{address}/nonce, сообщающий
+// номер последнего подписанного перевода с кошелька и номер, которым подписывается следующий:
+// {"nonce": 4, "next_nonce": 5}. Позволяет клиенту восстановить номер после отказа или сбоя.
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.HandleFunc("/api/wallet/{address}/nonce", GetNonceHandler(svc)).Methods("GET")
+func GetNonceHandler(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		address, err := parseAddress(mux.Vars(r)["address"])
+		if err != nil {
+			writeAddressError(w, err, "Invalid wallet address")
+			return
+		}
+
+		nonce, err := svc.GetNonce(r.Context(), address)
+		if writeWalletError(w, err) {
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]int64{"nonce": nonce, "next_nonce": nonce + 1})
+	}
+}
+
+// writeNonceError отвечает 409, если номер подписанного перевода не следующий номер
+// отправителя. Ожидаемый номер передается в поле expected_nonce, чтобы клиент мог
+// подписать перевод заново, не запрашивая /nonce.
+//
+// Возвращает:
+//   - true, если ответ уже записан.
+func writeNonceError(w http.ResponseWriter, err error) bool {
+	var nonceErr *db.NonceError
+	if !errors.As(err, &nonceErr) {
+		return false
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":           "nonce_mismatch",
+			"message":        "Nonce must be the next nonce of the sender wallet",
+			"expected_nonce": nonceErr.Expected,
+		},
+	})
+	return true
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	models "payment-system/internal/models"
+	service "payment-system/internal/service"
+)
+
+// Ограничения административного списка уведомлений.
+const (
+	defaultNotificationsCount = 50  // Количество уведомлений без параметра count
+	maxNotificationsCount     = 500 // Наибольшее значение параметра count
+)
+
+// notificationStatuses - допустимые значения параметра status списка уведомлений.
+var notificationStatuses = []string{models.NotificationPending, models.NotificationSent, models.NotificationFailed}
+
+// NotificationsHandler возвращает HTTP-обработчик списка уведомлений о поступлении средств,
+// начиная с самого нового. Параметр status отбирает уведомления с указанным статусом
+// (pending, sent или failed), count ограничивает количество (по умолчанию 50, не больше 500).
+// Ошибка последней неудачной попытки доставки - в поле last_error.
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.Handle("/api/admin/notifications", admin(NotificationsHandler(svc))).Methods("GET")
+func NotificationsHandler(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := r.URL.Query().Get("status")
+		if status != "" && !slices.Contains(notificationStatuses, status) {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request",
+				fmt.Sprintf("Parameter 'status' must be one of %s, got %q", strings.Join(notificationStatuses, ", "), status))
+			return
+		}
+
+		count := defaultNotificationsCount
+		if countStr := r.URL.Query().Get("count"); countStr != "" {
+			n, err := strconv.Atoi(countStr)
+			if err != nil || n <= 0 {
+				writeJSONError(w, http.StatusBadRequest, "invalid_request",
+					fmt.Sprintf("Parameter 'count' must be a positive integer, got %q", countStr))
+				return
+			}
+			count = min(n, maxNotificationsCount)
+		}
+
+		notifications, err := svc.GetNotifications(r.Context(), status, count)
+		if writeUnavailable(w, err) {
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+		resp := make([]numberAmountNotification, 0, len(notifications))
+		for _, n := range notifications {
+			resp = append(resp, numberAmountNotification{Notification: n, Amount: numberAmount(n.Amount)})
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"net/http"
+
+	service "payment-system/internal/service"
+)
+
+// reconcileReport - ответ сверки хранилища. Суммы - строками, как в ответах v1.
+type reconcileReport struct {
+	Drift               bool              `json:"drift"`
+	Wallets             int               `json:"wallets"`
+	TotalBalance        string            `json:"total_balance"`
+	Minted              string            `json:"minted"`
+	Burned              string            `json:"burned"`
+	ExpectedSupply      string            `json:"expected_supply"`
+	NegativeBalances    []string          `json:"negative_balances"`
+	OverReserved        []string          `json:"over_reserved"`
+	ArchivedWithBalance []string          `json:"archived_with_balance"`
+	OrphanTransactions  int               `json:"orphan_transactions"`
+	BalanceMismatches   []balanceMismatch `json:"balance_mismatches"`
+}
+
+// balanceMismatch - кошелек, баланс которого не сходится с историей переводов.
+type balanceMismatch struct {
+	Address  string `json:"address"`
+	Stored   string `json:"stored"`
+	Expected string `json:"expected"`
+}
+
+// ReconcileHandler возвращает HTTP-обработчик GET /api/admin/reconcile, который проверяет
+// инварианты хранилища, как команда reconcile, и для каждого кошелька сравнивает хранимый баланс
+// с начальным балансом плюс поступления и минус списания по истории переводов (включая архив).
+// Сумма балансов сравнивается с ожидаемой: начальные балансы плюс выпуск (minted) минус изъятие
+// (burned). Ответ 200 содержит отчет и в случае нарушений; признак drift сообщает, найдены ли они.
+// Списки адресов ограничены первыми 100 кошельками каждого вида.
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.Handle("/api/admin/reconcile", admin(ReconcileHandler(svc))).Methods("GET")
+func ReconcileHandler(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report, err := svc.Reconcile(r.Context())
+		if writeUnavailable(w, err) {
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+
+		resp := reconcileReport{
+			Drift:               report.Drift(),
+			Wallets:             report.Wallets,
+			TotalBalance:        report.TotalBalance.String(),
+			Minted:              report.Minted.String(),
+			Burned:              report.Burned.String(),
+			ExpectedSupply:      report.ExpectedSupply.String(),
+			NegativeBalances:    nonNilStrings(report.NegativeBalances),
+			OverReserved:        nonNilStrings(report.OverReserved),
+			ArchivedWithBalance: nonNilStrings(report.ArchivedWithBalance),
+			OrphanTransactions:  report.OrphanTransactions,
+			BalanceMismatches:   make([]balanceMismatch, 0, len(report.BalanceMismatches)),
+		}
+		for _, m := range report.BalanceMismatches {
+			resp.BalanceMismatches = append(resp.BalanceMismatches, balanceMismatch{
+				Address:  m.Address,
+				Stored:   m.Stored.String(),
+				Expected: m.Expected.String(),
+			})
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// nonNilStrings заменяет nil пустым списком, чтобы в JSON был [], а не null.
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
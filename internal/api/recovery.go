@@ -0,0 +1,44 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"runtime/debug"
+
+	"payment-system/internal/logging"
+)
+
+// RecoveryMiddleware перехватывает панику обработчика: записывает в журнал значение паники,
+// стек и идентификатор запроса и отвечает 500 в стандартном JSON-формате ошибок, если ответ
+// еще не начат. Без него паника обрывает соединение без ответа. http.ErrAbortHandler
+// пробрасывается дальше: им обработчик намеренно прерывает ответ.
+//
+// Пример использования:
+//
+//	handler := RecoveryMiddleware(router)
+func RecoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := newResponseWriter(w)
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if err, ok := v.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(v)
+			}
+
+			logging.FromContext(r.Context()).Error("panic in http handler",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"panic", v,
+				"stack", string(debug.Stack()),
+			)
+			// Если часть ответа уже отправлена, изменить код нельзя: клиент получит обрыв тела
+			if !rw.wroteHeader {
+				writeJSONError(rw, http.StatusInternalServerError, "internal_error", "Internal server error")
+			}
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
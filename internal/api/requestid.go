@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"payment-system/internal/logging"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader - заголовок с идентификатором запроса. Идентификатор клиента сохраняется,
+// если он допустим; иначе сервер создает новый. Заголовок возвращается в ответе, чтобы
+// клиент мог сослаться на запрос при обращении в поддержку.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength - наибольшая длина идентификатора запроса, принимаемого от клиента.
+const maxRequestIDLength = 64
+
+// requestIDKey - ключ идентификатора запроса в контексте.
+type requestIDKey struct{}
+
+// RequestIDMiddleware присваивает запросу идентификатор (из заголовка X-Request-ID или новый),
+// сохраняет его в контексте запроса и возвращает в заголовке ответа. В контекст также
+// помещается журнал с атрибутом request_id (см. logging.FromContext), поэтому все записи
+// обработчиков запроса связаны с ним без явной передачи идентификатора. Идентификатор
+// записывается и в спан запроса (см. TracingMiddleware), чтобы трассировку можно было найти
+// по идентификатору из обращения в поддержку.
+// Регистрируется внешним слоем, чтобы идентификатор был доступен журналам остальных слоев.
+//
+// Пример использования:
+//
+//	handler = RequestIDMiddleware(handler)
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !isValidRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		trace.SpanFromContext(r.Context()).SetAttributes(attribute.String(logging.KeyRequestID, id))
+		ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+		ctx = logging.WithContext(ctx, logging.FromContext(ctx).With(logging.KeyRequestID, id))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestID возвращает идентификатор запроса из контекста или пустую строку,
+// если запрос не прошел через RequestIDMiddleware.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// isValidRequestID проверяет идентификатор клиента: непустой, не длиннее maxRequestIDLength
+// и только из печатных символов ASCII, чтобы его можно было без экранирования писать в журнал.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID создает случайный идентификатор запроса из 16 шестнадцатеричных символов.
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"payment-system/internal/risk"
+	service "payment-system/internal/service"
+)
+
+// Ограничения списка срабатываний правил.
+const (
+	defaultRiskEventsCount = 50  // Количество событий без параметра count
+	maxRiskEventsCount     = 500 // Наибольшее значение параметра count
+)
+
+// RiskEventsHandler возвращает HTTP-обработчик списка последних срабатываний правил проверки
+// переводов, начиная с самого нового. Параметр count необязателен (по умолчанию 50, не больше 500).
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.Handle("/api/admin/risk-events", admin(RiskEventsHandler(svc))).Methods("GET")
+func RiskEventsHandler(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		count := defaultRiskEventsCount
+		if countStr := r.URL.Query().Get("count"); countStr != "" {
+			n, err := strconv.Atoi(countStr)
+			if err != nil || n <= 0 {
+				writeJSONError(w, http.StatusBadRequest, "invalid_request",
+					fmt.Sprintf("Parameter 'count' must be a positive integer, got %q", countStr))
+				return
+			}
+			count = min(n, maxRiskEventsCount)
+		}
+
+		events, err := svc.GetRiskEvents(r.Context(), count)
+		if writeUnavailable(w, err) {
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp := make([]numberAmountRiskEvent, 0, len(events))
+		for _, event := range events {
+			resp = append(resp, numberAmountRiskEvent{RiskEvent: event, Amount: numberAmount(event.Amount)})
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// writeRiskError отвечает 422 с кодом risk_blocked, если перевод отклонен правилом
+// проверки переводов. Сообщение называет правило и причину срабатывания.
+//
+// Возвращает:
+//   - true, если ответ уже записан.
+func writeRiskError(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, risk.ErrBlocked) {
+		return false
+	}
+	writeJSONError(w, http.StatusUnprocessableEntity, "risk_blocked", err.Error())
+	return true
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"payment-system/internal/risk"
+)
+
+// TestSendRiskBlocked проверяет, что перевод, отклоненный правилом проверки переводов,
+// получает 422 с кодом risk_blocked в обеих версиях API, а срабатывания видны в журнале
+// GET /api/admin/risk-events начиная с самого нового.
+func TestSendRiskBlocked(t *testing.T) {
+	env := newTestEnv(t, nil)
+	engine, err := risk.NewEngine(env.repo, risk.Config{
+		VelocityMaxTransfers: 1, VelocityWindow: time.Minute, VelocityAction: risk.ActionBlock,
+		AmountMultiplier: 2, AmountAction: risk.ActionFlag,
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	env.svc.AddSendInterceptor(engine)
+	from := env.wallet(t, "100")
+	to := env.wallet(t, "0")
+
+	body := `{"from":"` + from + `","to":"` + to + `","amount":1}`
+	if rec := env.do(t, "POST", "/api/v1/send", body); rec.Code != http.StatusCreated {
+		t.Fatalf("first send: status = %d, want 201; body: %s", rec.Code, rec.Body)
+	}
+	for _, target := range []string{"/api/send", "/api/v1/send"} {
+		rec := env.do(t, "POST", target, body)
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("POST %s: status = %d, want 422; body: %s", target, rec.Code, rec.Body)
+		}
+		if got := jsonPath(t, rec.Body.Bytes(), "error", "code"); got != `"risk_blocked"` {
+			t.Errorf("POST %s: error code = %s, want \"risk_blocked\"", target, got)
+		}
+	}
+	// Заблокированный перевод, который к тому же больше среднего, отмечается обоими правилами
+	large := `{"from":"` + from + `","to":"` + to + `","amount":5}`
+	if rec := env.do(t, "POST", "/api/v1/send", large); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("large send: status = %d, want 422; body: %s", rec.Code, rec.Body)
+	}
+
+	rec := serve(t, RiskEventsHandler(env.svc), "GET", "/api/admin/risk-events?count=3", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("risk events: status = %d, want 200; body: %s", rec.Code, rec.Body)
+	}
+	var events []struct {
+		Rule   string      `json:"rule"`
+		Action string      `json:"action"`
+		From   string      `json:"from"`
+		Amount json.Number `json:"amount"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &events); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3: %s", len(events), rec.Body)
+	}
+	if events[0].Rule != risk.RuleAmount || events[0].Action != risk.ActionFlag || events[0].Amount != "5" {
+		t.Errorf("newest event = %+v, want the flagged amount rule for 5", events[0])
+	}
+	for _, event := range events[1:] {
+		if event.Rule != risk.RuleVelocity || event.Action != risk.ActionBlock || event.From != from {
+			t.Errorf("event = %+v, want a velocity block from the sender", event)
+		}
+	}
+
+	balance, err := env.svc.GetBalance(context.Background(), from)
+	if err != nil {
+		t.Fatalf("GetBalance: %v", err)
+	}
+	if balance.String() != "99" {
+		t.Errorf("sender balance = %s, want 99", balance)
+	}
+}
+
+func TestRiskEventsInvalidCount(t *testing.T) {
+	env := newTestEnv(t, nil)
+	for _, count := range []string{"0", "-1", "abc"} {
+		rec := serve(t, RiskEventsHandler(env.svc), "GET", "/api/admin/risk-events?count="+count, "")
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("count=%s: status = %d, want 400", count, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	service "payment-system/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// RoutesConfig содержит настройки обработчиков API, общие для всех версий.
+type RoutesConfig struct {
+	MaxTransactionsCount int // Максимальное количество транзакций в ответе /transactions
+	BalanceScale         int // Количество знаков после запятой в балансе формата decimal
+
+	// DisplayCurrency - код или символ валюты ("USD", "₽"), который ответы баланса и списка
+	// транзакций сообщают для отображения сумм; пусто - не сообщается
+	DisplayCurrency string
+
+	IdempotencyTTL time.Duration // Срок хранения ответа на перевод с заголовком Idempotency-Key
+
+	// AdminToken - токен администратора (ADMIN_TOKEN). Ответ POST /api/v1/send содержит баланс
+	// получателя, только если запрос выполнен с этим токеном; пусто - баланс не возвращается
+	AdminToken string
+
+	// Ограничения запросов /api/graphql: вложенность полей и сложность (см. GraphQLHandler)
+	GraphQLMaxDepth      int
+	GraphQLMaxComplexity int
+}
+
+// RegisterV1 регистрирует маршруты API версии v1 с префиксом /api/v1 и /api/graphql,
+// ответы которого используют формат v1. Новые изменения формата ответов вносятся только в эту версию.
+//
+// Параметры:
+//   - router: Маршрутизатор приложения.
+//   - svc: Сервис для работы с бизнес-логикой.
+//   - maintenance: Режим обслуживания, блокирующий изменяющие операции.
+//   - sendLimiter: Ограничение одновременных переводов, общее для всех версий; nil - без ограничения.
+//   - cfg: Настройки обработчиков.
+//
+// Пример использования:
+//
+//	api.RegisterV1(router, svc, maintenance, sendLimiter, api.RoutesConfig{MaxTransactionsCount: 100, BalanceScale: 2})
+func RegisterV1(router *mux.Router, svc *service.Service, maintenance *MaintenanceMode, sendLimiter *ConcurrencyLimiter, cfg RoutesConfig) {
+	noop := func(next http.Handler) http.Handler { return next }
+	registerRoutes(router, "/api/v1", noop, svc, maintenance, sendLimiter, cfg,
+		SendV1Handler(svc, cfg.AdminToken), GetLastV1Handler(svc, cfg.MaxTransactionsCount), GetBalanceV1Handler(svc, cfg.BalanceScale, cfg.DisplayCurrency))
+
+	// - GET/POST /api/graphql: Запросы GraphQL на чтение (кошелек, транзакции, отчет по категориям)
+	router.Handle("/api/graphql", GraphQLHandler(svc, cfg)).Methods("GET", "POST")
+}
+
+// RegisterLegacy регистрирует маршруты без версии (/api/send, /api/transactions и т.д.)
+// с прежним форматом ответов. Маршруты устарели: каждый ответ содержит заголовок Deprecation
+// и ссылку на соответствующий маршрут v1. Формат этих ответов менять нельзя.
+//
+// Параметры:
+//   - router: Маршрутизатор приложения.
+//   - svc: Сервис для работы с бизнес-логикой.
+//   - maintenance: Режим обслуживания, блокирующий изменяющие операции.
+//   - sendLimiter: Ограничение одновременных переводов, общее для всех версий; nil - без ограничения.
+//   - cfg: Настройки обработчиков.
+//
+// Пример использования:
+//
+//	api.RegisterLegacy(router, svc, maintenance, sendLimiter, api.RoutesConfig{MaxTransactionsCount: 100, BalanceScale: 2})
+func RegisterLegacy(router *mux.Router, svc *service.Service, maintenance *MaintenanceMode, sendLimiter *ConcurrencyLimiter, cfg RoutesConfig) {
+	registerRoutes(router, "/api", deprecationMiddleware, svc, maintenance, sendLimiter, cfg,
+		SendHandler(svc), GetLastHandler(svc, cfg.MaxTransactionsCount), GetBalanceHandler(svc, cfg.BalanceScale, cfg.DisplayCurrency))
+}
+
+// registerRoutes регистрирует маршруты, общие для всех версий API.
+// Версии различаются префиксом, обработчиками перевода, списка транзакций и баланса
+// и промежуточным обработчиком wrap.
+//
+// Маршруты регистрируются полными путями, а не через PathPrefix().Subrouter(): gorilla/mux
+// сбрасывает несовпадение метода, если у следующего маршрута совпал префикс, и вместо 405
+// отвечал бы 404 для путей, общих с другими версиями.
+func registerRoutes(router *mux.Router, prefix string, wrap func(http.Handler) http.Handler,
+	svc *service.Service, maintenance *MaintenanceMode, sendLimiter *ConcurrencyLimiter, cfg RoutesConfig,
+	send, transactions, balance http.HandlerFunc) {
+	// - POST /send: Отправляет деньги с одного кошелька на другой; повтор с тем же
+	//   заголовком Idempotency-Key получает сохраненный ответ. Ограничение одновременных
+	//   переводов охватывает и проверку ключа идемпотентности: она тоже обращается к базе
+	idempotent := IdempotencyMiddleware(svc, cfg.IdempotencyTTL, cfg.AdminToken)
+	limited := func(next http.Handler) http.Handler { return next }
+	if sendLimiter != nil {
+		limited = sendLimiter.Middleware
+	}
+	router.Handle(prefix+"/send", wrap(maintenance.Middleware(limited(idempotent(send))))).Methods("POST")
+
+	// - GET /send/status/{approval_id}: Возвращает статус перевода, ожидающего подтверждения
+	router.Handle(prefix+"/send/status/{approval_id}", wrap(SendStatusHandler(svc))).Methods("GET")
+
+	// - GET /transactions: Возвращает информацию о последних N транзакциях
+	router.Handle(prefix+"/transactions", wrap(displayCurrencyMiddleware(cfg.DisplayCurrency, transactions))).Methods("GET")
+
+	// - GET /transactions/count: Возвращает количество транзакций с теми же фильтрами
+	router.Handle(prefix+"/transactions/count", wrap(CountTransactionsHandler(svc))).Methods("GET")
+
+	// - GET /transactions/stats: Возвращает объем переводов по дням и категориям
+	router.Handle(prefix+"/transactions/stats", wrap(TransactionStatsHandler(svc))).Methods("GET")
+
+	// - GET /wallet/{address}/balance: Возвращает баланс указанного кошелька
+	router.Handle(prefix+"/wallet/{address}/balance", wrap(balance)).Methods("GET")
+
+	// - GET /wallets?label=...: Ищет кошелек по метке (архивные - с include_archived=true)
+	router.Handle(prefix+"/wallets", wrap(FindWalletsHandler(svc))).Methods("GET")
+
+	// - GET /wallet/{address}/sendable: Возвращает максимальную сумму, доступную для отправки
+	router.Handle(prefix+"/wallet/{address}/sendable", wrap(GetSendableHandler(svc))).Methods("GET")
+
+	// - GET /wallet/{address}/nonce: Возвращает номер для подписи следующего перевода
+	router.Handle(prefix+"/wallet/{address}/nonce", wrap(GetNonceHandler(svc))).Methods("GET")
+
+	// - POST /wallets/balances: Возвращает балансы нескольких кошельков (только чтение,
+	//   поэтому доступен и в режиме обслуживания)
+	router.Handle(prefix+"/wallets/balances", wrap(GetBalancesHandler(svc))).Methods("POST")
+}
+
+// deprecationMiddleware помечает ответ устаревшего маршрута заголовком Deprecation (RFC 9745)
+// и указывает замену в заголовке Link. Путь может начинаться с префикса маршрутов (API_BASE_PATH),
+// поэтому версия вставляется после последнего сегмента "/api": в остальной части пути устаревших
+// маршрутов его нет.
+func deprecationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := strings.LastIndex(r.URL.Path, "/api/")
+		successor := r.URL.Path[:i] + "/api/v1" + r.URL.Path[i+len("/api"):]
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// DisplayCurrencyHeader - заголовок ответа списка транзакций с валютой DISPLAY_CURRENCY.
+// Список - массив JSON, у которого нет полей верхнего уровня, а поле в каждой транзакции
+// повторяло бы одно и то же значение.
+const DisplayCurrencyHeader = "X-Display-Currency"
+
+// displayCurrencyMiddleware сообщает валюту для отображения сумм в заголовке
+// X-Display-Currency; пустая валюта заголовок не добавляет.
+func displayCurrencyMiddleware(currency string, next http.Handler) http.Handler {
+	if currency == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(DisplayCurrencyHeader, currency)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// routeMethods - методы, которые проверяются при формировании заголовка Allow.
+var routeMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete,
+}
+
+// NotFoundHandler возвращает обработчик неизвестных путей, отвечающий 404
+// в стандартном JSON-формате ошибок вместо текстового ответа gorilla/mux.
+//
+// Пример использования:
+//
+//	router.NotFoundHandler = NotFoundHandler()
+func NotFoundHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSONError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Path %s not found", r.URL.Path))
+	})
+}
+
+// MethodNotAllowedHandler возвращает обработчик запросов с неподдерживаемым методом.
+// Отвечает 405 в стандартном JSON-формате ошибок с заголовком Allow, в котором перечислены
+// методы, зарегистрированные в router для этого пути.
+//
+// Параметры:
+//   - router: Маршрутизатор, в котором ищутся допустимые методы.
+//
+// Пример использования:
+//
+//	router.MethodNotAllowedHandler = MethodNotAllowedHandler(router)
+func MethodNotAllowedHandler(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeMethodNotAllowed(w, r, allowedMethods(router, r))
+	})
+}
+
+// SubrouterNotFoundHandler возвращает обработчик запросов, не совпавших ни с одним маршрутом
+// подмаршрутизатора (например, под префиксом API_BASE_PATH). Маршруты подмаршрутизатора
+// наследуют его префикс, и gorilla/mux сбрасывает несовпадение метода на следующем маршруте,
+// поэтому неподдерживаемый метод доходит сюда как неизвестный путь. Обработчик проверяет
+// методы пути в router и отвечает 405, как MethodNotAllowedHandler, если путь известен,
+// иначе 404, как NotFoundHandler.
+//
+// Параметры:
+//   - router: Основной маршрутизатор, в котором ищутся допустимые методы.
+//
+// Пример использования:
+//
+//	api := router.PathPrefix("/payments/v1").Subrouter()
+//	api.NotFoundHandler = SubrouterNotFoundHandler(router)
+func SubrouterNotFoundHandler(router *mux.Router) http.Handler {
+	notFound := NotFoundHandler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if allowed := allowedMethods(router, r); len(allowed) > 0 {
+			writeMethodNotAllowed(w, r, allowed)
+			return
+		}
+		notFound.ServeHTTP(w, r)
+	})
+}
+
+// allowedMethods возвращает методы из routeMethods, для которых в router есть маршрут пути запроса.
+func allowedMethods(router *mux.Router, r *http.Request) []string {
+	var allowed []string
+	for _, method := range routeMethods {
+		probe := r.Clone(r.Context())
+		probe.Method = method
+
+		var match mux.RouteMatch
+		if router.Match(probe, &match) && match.MatchErr == nil {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
+
+// writeMethodNotAllowed отвечает 405 с заголовком Allow.
+func writeMethodNotAllowed(w http.ResponseWriter, r *http.Request, allowed []string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeJSONError(w, http.StatusMethodNotAllowed, "method_not_allowed",
+		fmt.Sprintf("Method %s is not allowed for %s", r.Method, r.URL.Path))
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	db "payment-system/internal/db"
+	"payment-system/internal/logging"
+	"payment-system/internal/metrics"
+	service "payment-system/internal/service"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// requestCount возвращает число наблюдений payment_http_request_duration_seconds с метками.
+func requestCount(t *testing.T, method, route, status string) uint64 {
+	t.Helper()
+	var m dto.Metric
+	if err := metrics.HTTPRequestDuration.WithLabelValues(method, route, status).(prometheus.Metric).Write(&m); err != nil {
+		t.Fatalf("read metric: %v", err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+// TestUnmatchedRoutes проверяет ответы на неизвестный путь и неподдерживаемый метод: стандартный
+// JSON-формат ошибки, заголовок Allow с методами пути у 405, строку журнала доступа и метрику.
+func TestUnmatchedRoutes(t *testing.T) {
+	env := newTestEnv(t, nil)
+	wallet := env.wallet(t, "1")
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		wantStatus int
+		wantCode   string
+		wantAllow  string
+		wantRoute  string
+	}{
+		{"unknown path", "GET", "/api/nope", http.StatusNotFound, "not_found", "", unmatchedRoute},
+		{"unknown v1 path", "POST", "/api/v1/nope", http.StatusNotFound, "not_found", "", unmatchedRoute},
+		{"get on send", "GET", "/api/send", http.StatusMethodNotAllowed, "method_not_allowed", "POST", unmatchedRoute},
+		{"delete on balance", "DELETE", "/api/wallet/" + wallet + "/balance", http.StatusMethodNotAllowed, "method_not_allowed", "GET", unmatchedRoute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			ctx := logging.WithContext(context.Background(), slog.New(slog.NewJSONHandler(&logs, nil)))
+			before := requestCount(t, tt.method, tt.wantRoute, strconv.Itoa(tt.wantStatus))
+
+			req := httptest.NewRequest(tt.method, tt.target, nil).WithContext(ctx)
+			rec := httptest.NewRecorder()
+			AccessLogMiddleware(env.router)(env.router).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", got)
+			}
+			if got := jsonPath(t, rec.Body.Bytes(), "error", "code"); got != `"`+tt.wantCode+`"` {
+				t.Errorf("error code = %s, want %q", got, tt.wantCode)
+			}
+			if got := rec.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("Allow = %q, want %q", got, tt.wantAllow)
+			}
+
+			var entry struct {
+				Msg    string `json:"msg"`
+				Status int    `json:"status"`
+			}
+			if err := json.Unmarshal(logs.Bytes(), &entry); err != nil || entry.Msg != "http request" || entry.Status != tt.wantStatus {
+				t.Errorf("access log = %s, want an http request entry with status %d", logs.String(), tt.wantStatus)
+			}
+			if got := requestCount(t, tt.method, tt.wantRoute, strconv.Itoa(tt.wantStatus)); got != before+1 {
+				t.Errorf("request duration observations = %d, want %d", got, before+1)
+			}
+		})
+	}
+}
+
+// TestSubrouterUnmatchedRoutes проверяет те же ответы под префиксом API_BASE_PATH, где
+// неподдерживаемый метод доходит до подмаршрутизатора как неизвестный путь.
+func TestSubrouterUnmatchedRoutes(t *testing.T) {
+	svc := service.NewService(db.NewMemoryRepository())
+	router := mux.NewRouter()
+	router.NotFoundHandler = NotFoundHandler()
+	router.MethodNotAllowedHandler = MethodNotAllowedHandler(router)
+	api := router.PathPrefix("/payments/v1").Subrouter()
+	api.NotFoundHandler = SubrouterNotFoundHandler(router)
+	RegisterLegacy(api, svc, NewMaintenanceMode(false), nil, RoutesConfig{MaxTransactionsCount: 100})
+
+	tests := []struct {
+		method, target string
+		wantStatus     int
+		wantAllow      string
+	}{
+		{"GET", "/payments/v1/api/send", http.StatusMethodNotAllowed, "POST"},
+		{"GET", "/payments/v1/api/nope", http.StatusNotFound, ""},
+		{"GET", "/api/send", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			rec := serve(t, router, tt.method, tt.target, "")
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if got := rec.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("Allow = %q, want %q", got, tt.wantAllow)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	db "payment-system/internal/db"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+)
+
+// schemaFiles содержит JSON-схемы тел запросов. Схемы описывают правила валидации
+// декларативно и могут использоваться любым интерфейсом, а не только REST.
+//
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+// Схемы тел запросов.
+var (
+	sendSchema         = mustCompileSchema("schemas/send.json")          // POST /api/send
+	createWalletSchema = mustCompileSchema("schemas/create_wallet.json") // POST /api/admin/wallets
+)
+
+// violation - одно нарушение в ответе клиенту. Обработчик собирает нарушения схемы и проверок,
+// которые схема не выражает, и отвечает всеми сразу (см. writeViolations).
+type violation struct {
+	Field   string `json:"field"`   // Поле запроса по имени в JSON ("" - весь объект, "tags.currency" - вложенное)
+	Code    string `json:"code"`    // Код нарушения (violationRequired и т.д.)
+	Message string `json:"message"` // Описание нарушения
+}
+
+// Коды нарушений. Общие для всех запросов, чтобы клиенты отображали ошибки одним кодом.
+const (
+	violationRequired   = "required"          // Обязательное поле отсутствует
+	violationType       = "invalid_type"      // Значение другого JSON-типа
+	violationFormat     = "invalid_format"    // Значение не соответствует формату (адрес, шаблон)
+	violationChecksum   = "invalid_checksum"  // Не совпала контрольная сумма адреса
+	violationTooLong    = "too_long"          // Строка длиннее допустимого
+	violationTooShort   = "too_short"         // Строка короче допустимого
+	violationTooMany    = "too_many"          // Элементов больше допустимого
+	violationOutOfRange = "out_of_range"      // Число вне допустимого диапазона
+	violationPrecision  = "invalid_precision" // Сумма с лишними знаками после запятой
+	violationInvalid    = "invalid"           // Прочие нарушения
+)
+
+// defaultAddressPattern - шаблон адреса кошелька в файлах схем. При компиляции он заменяется
+// шаблоном для длины адреса из ADDRESS_BYTES, чтобы схемы не расходились с IsValidAddress.
+const defaultAddressPattern = "[0-9a-f]{64}"
+
+// addressPattern возвращает шаблон адреса для настроенной длины. Заглавные буквы допускаются
+// для адресов с контрольной суммой, которую проверяет обработчик (см. parseAddress).
+func addressPattern() string {
+	return fmt.Sprintf("[0-9a-fA-F]{%d}", 2*db.AddressBytes())
+}
+
+// mustCompileSchema компилирует встроенную схему. Схемы являются частью исходного кода,
+// поэтому ошибка компиляции - ошибка программиста, и программа завершается при запуске.
+func mustCompileSchema(name string) *jsonschema.Schema {
+	data, err := schemaFiles.ReadFile(name)
+	if err != nil {
+		log.Fatalf("Failed to read schema %s: %v", name, err)
+	}
+	data = bytes.ReplaceAll(data, []byte(defaultAddressPattern), []byte(addressPattern()))
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		log.Fatalf("Failed to parse schema %s: %v", name, err)
+	}
+
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource(name, doc); err != nil {
+		log.Fatalf("Failed to load schema %s: %v", name, err)
+	}
+	schema, err := c.Compile(name)
+	if err != nil {
+		log.Fatalf("Failed to compile schema %s: %v", name, err)
+	}
+	return schema
+}
+
+// validateSchema проверяет тело запроса по схеме.
+//
+// Параметры:
+//   - schema: Скомпилированная схема.
+//   - doc: Декодированное тело запроса.
+//
+// Возвращает:
+//   - Список нарушений; пустой, если тело соответствует схеме.
+func validateSchema(schema *jsonschema.Schema, doc interface{}) []violation {
+	err := schema.Validate(doc)
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return nil
+	}
+
+	// У нарушений propertyNames нет пути в теле запроса: они относятся к имени свойства
+	// объекта, которое сообщает предшествующая обобщающая ошибка
+	var violations []violation
+	var nameField, name string
+	for _, unit := range validationErr.BasicOutput().Errors {
+		if unit.Error == nil {
+			continue
+		}
+		field := strings.ReplaceAll(strings.TrimPrefix(unit.InstanceLocation, "/"), "/", ".")
+
+		switch k := unit.Error.Kind.(type) {
+		case *kind.Group:
+			// Обобщающая ошибка дублирует вложенные нарушения
+			continue
+		case *kind.PropertyNames:
+			nameField, name = field, k.Property
+			continue
+		case *kind.Required:
+			for _, missing := range k.Missing {
+				violations = append(violations, violation{Field: missing, Code: violationRequired, Message: "field is required"})
+			}
+		case *kind.DependentRequired:
+			for _, missing := range k.Missing {
+				violations = append(violations, violation{Field: missing, Code: violationRequired,
+					Message: fmt.Sprintf("field is required when %q is set", k.Prop)})
+			}
+		default:
+			message := unit.Error.String()
+			if strings.Contains(unit.KeywordLocation, "/propertyNames/") {
+				field, message = nameField, fmt.Sprintf("key %q: %s", name, message)
+			}
+			violations = append(violations, violation{Field: field, Code: violationCode(k), Message: message})
+		}
+	}
+	return violations
+}
+
+// violationCode возвращает код нарушения для ключевого слова схемы, которое не выполнено.
+func violationCode(k jsonschema.ErrorKind) string {
+	switch k.(type) {
+	case *kind.Type:
+		return violationType
+	case *kind.Pattern, *kind.Format:
+		return violationFormat
+	case *kind.MaxLength:
+		return violationTooLong
+	case *kind.MinLength:
+		return violationTooShort
+	case *kind.MaxProperties, *kind.MaxItems:
+		return violationTooMany
+	case *kind.Minimum, *kind.Maximum, *kind.ExclusiveMinimum, *kind.ExclusiveMaximum:
+		return violationOutOfRange
+	default:
+		return violationInvalid
+	}
+}
+
+// hasViolation сообщает, есть ли уже нарушение для поля: проверки после схемы пропускают такие
+// поля, чтобы не сообщать об одном поле дважды.
+func hasViolation(violations []violation, field string) bool {
+	for _, v := range violations {
+		if v.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// writeViolations отвечает 400 с ошибкой "validation_failed" и списком всех нарушений
+// в поле violations: [{"field": "amount", "code": "out_of_range", "message": "..."}].
+//
+// Параметры:
+//   - w: Ответ HTTP.
+//   - violations: Нарушения схемы и проверок обработчика.
+func writeViolations(w http.ResponseWriter, violations []violation) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":       "validation_failed",
+			"message":    "Request body has invalid fields",
+			"violations": violations,
+		},
+	})
+}
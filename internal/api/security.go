@@ -0,0 +1,113 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// SecurityConfig - настройки заголовков безопасности и CORS.
+type SecurityConfig struct {
+	// AllowedOrigins - источники, которым разрешены кросс-доменные запросы ("*" - любым).
+	// Пустой список отключает CORS.
+	AllowedOrigins []string
+
+	// HSTSMaxAge - значение max-age заголовка Strict-Transport-Security в секундах.
+	// 0 отключает HSTS.
+	HSTSMaxAge int
+
+	// TrustForwardedProto - доверять заголовку X-Forwarded-Proto от прокси, завершающего TLS.
+	// Включайте только если сервер доступен исключительно через такой прокси,
+	// иначе клиент сможет подделать заголовок.
+	TrustForwardedProto bool
+}
+
+// SecurityHeadersMiddleware добавляет к каждому ответу заголовки безопасности:
+// X-Content-Type-Options, X-Frame-Options и Referrer-Policy.
+//
+// Strict-Transport-Security отправляется только по HTTPS: если соединение с сервером
+// защищено TLS, либо если включен TrustForwardedProto и прокси сообщил X-Forwarded-Proto: https.
+// По обычному HTTP заголовок не отправляется: браузеры его игнорируют, а ошибочная
+// настройка могла бы заблокировать доступ к сервису без TLS.
+//
+// Параметры:
+//   - cfg: Настройки безопасности.
+//
+// Пример использования:
+//
+//	handler := SecurityHeadersMiddleware(cfg)(router)
+func SecurityHeadersMiddleware(cfg SecurityConfig) func(http.Handler) http.Handler {
+	hsts := "max-age=" + strconv.Itoa(cfg.HSTSMaxAge) + "; includeSubDomains"
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("X-Content-Type-Options", "nosniff")
+			h.Set("X-Frame-Options", "DENY")
+			h.Set("Referrer-Policy", "no-referrer")
+
+			if cfg.HSTSMaxAge > 0 && isHTTPS(r, cfg.TrustForwardedProto) {
+				h.Set("Strict-Transport-Security", hsts)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isHTTPS сообщает, пришел ли запрос по HTTPS.
+func isHTTPS(r *http.Request, trustForwardedProto bool) bool {
+	if r.TLS != nil {
+		return true
+	}
+	return trustForwardedProto && strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// CORSMiddleware разрешает кросс-доменные запросы с источников из cfg.AllowedOrigins
+// и отвечает на предварительные запросы OPTIONS. При пустом списке ничего не делает.
+//
+// Параметры:
+//   - cfg: Настройки безопасности.
+//
+// Пример использования:
+//
+//	handler := CORSMiddleware(cfg)(router)
+func CORSMiddleware(cfg SecurityConfig) func(http.Handler) http.Handler {
+	allowAll := false
+	allowed := make(map[string]bool, len(cfg.AllowedOrigins))
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+		allowed[origin] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		if len(allowed) == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" || !(allowAll || allowed[origin]) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Add("Vary", "Origin")
+			h.Set("Access-Control-Allow-Origin", origin)
+
+			// Предварительный запрос браузера: отвечаем сами, не передавая обработчику
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				h.Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+				h.Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+IdempotencyKeyHeader+", "+IsolationLevelHeader+", "+AmountFormatHeader)
+				h.Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
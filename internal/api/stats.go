@@ -0,0 +1,112 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	service "payment-system/internal/service"
+)
+
+// Ограничения периода отчета по категориям.
+const (
+	defaultStatsDays = 30  // Длина периода без параметра from
+	maxStatsDays     = 366 // Наибольшая длина периода
+)
+
+// statsDayLayout - формат дней в параметрах и ответе отчета по категориям.
+const statsDayLayout = "2006-01-02"
+
+// categoryVolume - строка отчета по категориям: объем переводов одной категории за один день.
+// Сумма - строкой, как в ответах v1.
+type categoryVolume struct {
+	Day      string `json:"day"`
+	Category string `json:"category"`
+	Count    int64  `json:"count"`
+	Volume   string `json:"volume"`
+}
+
+// statsPeriod проверяет границы периода отчета по категориям (дни YYYY-MM-DD включительно;
+// пустая строка - значение по умолчанию) и возвращает период [from, end) для запроса к базе.
+//
+// Параметры:
+//   - fromStr: Первый день периода; пусто - 30 дней, заканчивающихся toStr.
+//   - toStr: Последний день периода; пусто - сегодня (UTC).
+//
+// Возвращает:
+//   - Начало периода.
+//   - Начало дня после последнего дня периода.
+//   - Ошибку с сообщением для клиента.
+func statsPeriod(fromStr, toStr string) (time.Time, time.Time, error) {
+	now := time.Now().UTC()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if toStr != "" {
+		var err error
+		if to, err = time.Parse(statsDayLayout, toStr); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("Parameter 'to' must be a date in YYYY-MM-DD format, got %q", toStr)
+		}
+	}
+	from := to.AddDate(0, 0, 1-defaultStatsDays)
+	if fromStr != "" {
+		var err error
+		if from, err = time.Parse(statsDayLayout, fromStr); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("Parameter 'from' must be a date in YYYY-MM-DD format, got %q", fromStr)
+		}
+	}
+	// Конец периода не включается в запрос к базе: это начало дня после to
+	end := to.AddDate(0, 0, 1)
+	if from.After(to) {
+		return time.Time{}, time.Time{}, errors.New("Parameter 'from' must not be after 'to'")
+	}
+	if end.After(from.AddDate(0, 0, maxStatsDays)) {
+		return time.Time{}, time.Time{}, fmt.Errorf("Period must be at most %d days", maxStatsDays)
+	}
+	return from, end, nil
+}
+
+// TransactionStatsHandler возвращает HTTP-обработчик GET /api/transactions/stats?from=...&to=...,
+// который отвечает объемом переводов по дням (UTC) и категориям:
+// [{"day": "2026-10-14", "category": "salary", "count": 3, "volume": "1500"}, ...].
+// Переводы без категории учитываются с пустой категорией; дни и категории без переводов
+// не возвращаются. Границы - дни в формате YYYY-MM-DD включительно; без to - сегодня,
+// без from - 30 дней, заканчивающихся to. Период длиннее 366 дней, from позже to или дата
+// в другом формате отклоняются с 400.
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.HandleFunc("/api/transactions/stats", TransactionStatsHandler(svc)).Methods("GET")
+func TransactionStatsHandler(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		from, end, err := statsPeriod(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+
+		volumes, err := svc.GetCategoryVolumes(r.Context(), from, end)
+		if writeUnavailable(w, err) {
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+		resp := make([]categoryVolume, 0, len(volumes))
+		for _, v := range volumes {
+			resp = append(resp, categoryVolume{
+				Day:      v.Day.Format(statsDayLayout),
+				Category: v.Category,
+				Count:    v.Count,
+				Volume:   v.Volume.String(),
+			})
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	db "payment-system/internal/db"
+	errs "payment-system/internal/errs"
+	models "payment-system/internal/models"
+	service "payment-system/internal/service"
+)
+
+// systemTransferRequest - тело запроса операции с системным счетом.
+type systemTransferRequest struct {
+	Type    string      `json:"type"`
+	Address string      `json:"address"`
+	Amount  json.Number `json:"amount"`
+	Memo    string      `json:"memo"`
+}
+
+// systemTransferResponse - результат операции с системным счетом. Суммы - строками, как в ответах v1.
+type systemTransferResponse struct {
+	TransactionID int    `json:"transaction_id"`
+	Type          string `json:"type"`
+	Address       string `json:"address"`
+	Balance       string `json:"balance"` // Баланс кошелька после операции
+	CreatedAt     string `json:"created_at"`
+}
+
+// SystemTransferHandler возвращает HTTP-обработчик операций с системными счетами:
+// выпуска ("mint"), изъятия ("burn"), комиссии ("fee"), удержания ("escrow_hold") и возврата
+// удержания ("escrow_release"). Принимает {"type": "mint", "address": "...", "amount": "100",
+// "memo": "..."} и отвечает 201 с идентификатором транзакции и балансом кошелька после операции.
+// Системный счет определяется типом; тип "transfer" и другие значения отклоняются с 400,
+// системный счет в address - с 403 system_account. Ошибки списания - как у /api/v1/send.
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.Handle("/api/admin/system-transfers", admin(SystemTransferHandler(svc))).Methods("POST")
+func SystemTransferHandler(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req systemTransferRequest
+		if err := decodeJSONBody(r.Body, &req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		if req.Type == "" || req.Type == models.TransactionTransfer || models.ValidateTransactionType(req.Type) != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request",
+				fmt.Sprintf("Parameter 'type' must be one of mint, burn, fee, escrow_hold, escrow_release, got %q", req.Type))
+			return
+		}
+		amount, err := parseAmount(req.Amount)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_amount_format", err.Error())
+			return
+		}
+		if err := models.ValidateAmountScale(amount); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_amount_format", err.Error())
+			return
+		}
+		if err := validateMemo(req.Memo); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		address, err := parseAddress(req.Address)
+		if err != nil {
+			writeAddressError(w, err, "Invalid wallet address")
+			return
+		}
+
+		result, err := svc.SystemTransfer(r.Context(), req.Type, address, amount, req.Memo)
+		if err != nil {
+			switch {
+			case writeUnavailable(w, err):
+			case errors.Is(err, db.ErrOutcomeUnknown):
+				writeOutcomeUnknown(w)
+			case errors.Is(err, errs.ErrZeroAmount):
+				writeJSONError(w, http.StatusBadRequest, "invalid_amount", err.Error())
+			case errors.Is(err, errs.ErrAmountTooLarge):
+				writeJSONError(w, http.StatusBadRequest, "amount_too_large", err.Error())
+			case errors.Is(err, errs.ErrSystemAccount):
+				writeJSONError(w, http.StatusForbidden, "system_account", err.Error())
+			case errors.Is(err, errs.ErrWalletArchived):
+				writeJSONError(w, http.StatusConflict, "wallet_archived", err.Error())
+			default:
+				writeSendErrorV1(w, err)
+			}
+			return
+		}
+
+		// Для выпуска и возврата удержания кошелек - получатель, для остальных операций - отправитель
+		balance := result.SenderBalance
+		if req.Type == models.TransactionMint || req.Type == models.TransactionEscrowRelease {
+			balance = result.ReceiverBalance
+		}
+		writeJSON(w, http.StatusCreated, systemTransferResponse{
+			TransactionID: result.TransactionID,
+			Type:          req.Type,
+			Address:       address,
+			Balance:       balance.String(),
+			CreatedAt:     result.CreatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+}
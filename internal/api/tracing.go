@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+
+	"payment-system/internal/logging"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName - имя трассировщика HTTP-слоя.
+const tracerName = "payment-system/internal/api"
+
+// TracingMiddleware создает серверный спан OpenTelemetry на каждый запрос. Контекст трассировки
+// вызывающего сервиса берется из заголовка traceparent (W3C Trace Context), поэтому спан
+// становится дочерним спаном вызова; без заголовка начинается новая трассировка. Спан
+// называется "METHOD шаблон маршрута" (например, "POST /api/send"), содержит код ответа
+// и отмечается ошибкой при ответе 5xx. Спаны сервиса и запросов к базе данных создаются
+// дочерними через контекст запроса.
+//
+// Идентификатор трассировки добавляется в журнал запроса (trace_id), чтобы по нему находились
+// записи журнала всех сервисов, участвовавших в запросе.
+//
+// Параметры:
+//   - router: Маршрутизатор, по которому определяется шаблон маршрута для имени спана.
+//
+// Пример использования:
+//
+//	handler = TracingMiddleware(router)(handler)
+func TracingMiddleware(router *mux.Router) func(http.Handler) http.Handler {
+	tracer := otel.Tracer(tracerName)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			// Имя спана - по шаблону маршрута, а не по пути: иначе имен было бы неограниченно много
+			name := r.Method
+			attrs := []attribute.KeyValue{
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+				semconv.ClientAddress(remoteIP(r)),
+				semconv.UserAgentOriginal(r.UserAgent()),
+			}
+			if route := routeTemplate(router, r); route != unmatchedRoute {
+				name += " " + route
+				attrs = append(attrs, semconv.HTTPRoute(route))
+			}
+			ctx, span := tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+			defer span.End()
+
+			// Идентификатор известен и без экспорта спанов, если он пришел в traceparent
+			if sc := span.SpanContext(); sc.HasTraceID() {
+				ctx = logging.WithContext(ctx, logging.FromContext(ctx).With(logging.KeyTraceID, sc.TraceID().String()))
+			}
+
+			rw := newResponseWriter(w)
+			next.ServeHTTP(rw, r.WithContext(ctx))
+
+			span.SetAttributes(semconv.HTTPResponseStatusCode(rw.status))
+			if rw.status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(rw.status))
+			}
+		})
+	}
+}
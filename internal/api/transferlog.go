@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	errs "payment-system/internal/errs"
+	"payment-system/internal/logging"
+	"payment-system/internal/risk"
+	service "payment-system/internal/service"
+)
+
+// rejectedTransferErrors - ошибки, которыми перевод отклоняется по запросу клиента (нехватка
+// средств, неверная подпись и т. п.). Они записываются с уровнем info, остальные ошибки
+// перевода (недоступность базы, конфликты, сбои запросов) - с уровнем error.
+var rejectedTransferErrors = []error{
+	errs.ErrInsufficientFunds,
+	errs.ErrBelowMinimumBalance,
+	errs.ErrBalanceOverflow,
+	errs.ErrWalletNotFound,
+	errs.ErrWalletArchived,
+	errs.ErrSystemAccount,
+	errs.ErrSelfTransfer,
+	errs.ErrNonceMismatch,
+	errs.ErrLabelNotFound,
+	errs.ErrSignatureRequired,
+	errs.ErrInvalidSignature,
+	errs.ErrZeroAmount,
+	errs.ErrAmountTooLarge,
+	risk.ErrBlocked,
+}
+
+// logTransfer записывает в журнал запроса результат перевода. Это единственная запись об ошибке
+// перевода: репозиторий и сервис ошибки только возвращают, а обработчик записывает их здесь,
+// на границе API.
+//
+// Параметры:
+//   - ctx: Контекст запроса с журналом (см. RequestIDMiddleware).
+//   - from: Адрес или метка отправителя из запроса.
+//   - transfer: Выполненный перевод; не используется, если err не nil.
+//   - err: Ошибка перевода.
+//   - duration: Длительность вызова service.Send.
+func logTransfer(ctx context.Context, from string, transfer service.Transfer, err error, duration time.Duration) {
+	logger := logging.FromContext(ctx)
+	if err != nil {
+		level := slog.LevelError
+		for _, rejected := range rejectedTransferErrors {
+			if errors.Is(err, rejected) {
+				level = slog.LevelInfo
+				break
+			}
+		}
+		logger.LogAttrs(ctx, level, "transfer failed",
+			slog.String(logging.KeyWallet, from),
+			logging.Duration(duration),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	if transfer.ApprovalID != 0 {
+		logger.LogAttrs(ctx, slog.LevelInfo, "transfer awaiting approval",
+			slog.String(logging.KeyWallet, transfer.From.Address),
+			slog.Int64("approval_id", transfer.ApprovalID),
+			logging.Duration(duration),
+		)
+		return
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "transfer completed",
+		slog.String(logging.KeyWallet, transfer.From.Address),
+		slog.String("to", transfer.To.Address),
+		slog.Int(logging.KeyTxID, transfer.Result.TransactionID),
+		logging.Duration(duration),
+	)
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	db "payment-system/internal/db"
+	"payment-system/internal/logging"
+	models "payment-system/internal/models"
+	service "payment-system/internal/service"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestTransferLog проверяет записи журнала о переводе: у каждой есть request_id запроса,
+// wallet отправителя и duration_ms, у выполненного перевода - tx_id; ошибка перевода
+// записывается один раз, отказ по вине клиента - с уровнем INFO, сбой хранилища - ERROR.
+func TestTransferLog(t *testing.T) {
+	tests := []struct {
+		name      string
+		repo      func() db.Repository
+		amount    string
+		wantMsg   string
+		wantLevel string
+		wantKeys  []string
+	}{
+		{"completed", func() db.Repository { return db.NewMemoryRepository() }, "1",
+			"transfer completed", "INFO", []string{logging.KeyTxID, "to"}},
+		{"insufficient funds", func() db.Repository { return db.NewMemoryRepository() }, "1000",
+			"transfer failed", "INFO", []string{"error"}},
+		{"contention", func() db.Repository { return contendedRepository{db.NewMemoryRepository()} }, "1",
+			"transfer failed", "ERROR", []string{"error"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := service.NewService(tt.repo())
+			var addresses []string
+			for range 2 {
+				wallet, _, err := svc.CreateWallet(context.Background(), decimal.NewFromInt(100), models.WalletMetadata{})
+				if err != nil {
+					t.Fatalf("CreateWallet: %v", err)
+				}
+				addresses = append(addresses, wallet.Address)
+			}
+
+			var logs bytes.Buffer
+			ctx := logging.WithContext(context.Background(), slog.New(slog.NewJSONHandler(&logs, nil)))
+			body := `{"from":"` + addresses[0] + `","to":"` + addresses[1] + `","amount":` + tt.amount + `}`
+			req := httptest.NewRequest("POST", "/api/v1/send", strings.NewReader(body)).WithContext(ctx)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(RequestIDHeader, "req-transfer-log")
+			RequestIDMiddleware(SendV1Handler(svc, "")).ServeHTTP(httptest.NewRecorder(), req)
+
+			var entries []map[string]interface{}
+			scanner := bufio.NewScanner(&logs)
+			for scanner.Scan() {
+				var entry map[string]interface{}
+				if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+					t.Fatalf("decode log line %s: %v", scanner.Bytes(), err)
+				}
+				if strings.HasPrefix(entry["msg"].(string), "transfer") {
+					entries = append(entries, entry)
+				}
+			}
+			if len(entries) != 1 {
+				t.Fatalf("got %d transfer log entries, want 1: %s", len(entries), logs.String())
+			}
+			entry := entries[0]
+			if entry["msg"] != tt.wantMsg || entry["level"] != tt.wantLevel {
+				t.Errorf("entry %v: want %s at %s", entry, tt.wantMsg, tt.wantLevel)
+			}
+			if entry[logging.KeyRequestID] != "req-transfer-log" {
+				t.Errorf("request_id = %v, want req-transfer-log", entry[logging.KeyRequestID])
+			}
+			if entry[logging.KeyWallet] != addresses[0] {
+				t.Errorf("wallet = %v, want the sender %s", entry[logging.KeyWallet], addresses[0])
+			}
+			if _, ok := entry[logging.KeyDuration].(float64); !ok {
+				t.Errorf("duration_ms = %v, want a number", entry[logging.KeyDuration])
+			}
+			for _, key := range tt.wantKeys {
+				if _, ok := entry[key]; !ok {
+					t.Errorf("entry %v has no %s", entry, key)
+				}
+			}
+		})
+	}
+}
+
+// TestTransferLogMethods проверяет, что обе версии перевода пишут одну запись о результате.
+func TestTransferLogMethods(t *testing.T) {
+	env := newTestEnv(t, nil)
+	from := env.wallet(t, "100")
+	to := env.wallet(t, "0")
+	for _, target := range []string{"/api/send", "/api/v1/send"} {
+		var logs bytes.Buffer
+		ctx := logging.WithContext(context.Background(), slog.New(slog.NewJSONHandler(&logs, nil)))
+		req := httptest.NewRequest("POST", target, strings.NewReader(`{"from":"`+from+`","to":"`+to+`","amount":1}`)).WithContext(ctx)
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		RequestIDMiddleware(env.router).ServeHTTP(rec, req)
+		if rec.Code >= http.StatusBadRequest {
+			t.Fatalf("POST %s: status = %d; body: %s", target, rec.Code, rec.Body)
+		}
+		if n := strings.Count(logs.String(), `"msg":"transfer completed"`); n != 1 {
+			t.Errorf("POST %s: %d transfer completed entries, want 1: %s", target, n, logs.String())
+		}
+		if !strings.Contains(logs.String(), `"`+logging.KeyRequestID+`":"`+rec.Header().Get(RequestIDHeader)+`"`) {
+			t.Errorf("POST %s: log does not carry the generated request id: %s", target, logs.String())
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	db "payment-system/internal/db"
+	errs "payment-system/internal/errs"
+	models "payment-system/internal/models"
+	service "payment-system/internal/service"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+// transactionV1 - транзакция в формате ответа /api/v1.
+// В отличие от models.Transaction сумма передается строкой (как в /sendable), чтобы клиент
+// не терял точность, а время - строкой RFC 3339 в UTC независимо от часового пояса сервера.
+type transactionV1 struct {
+	ID         int    `json:"id"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Amount     string `json:"amount"`
+	CreatedAt  string `json:"created_at"`
+	Memo       string `json:"memo,omitempty"`
+	Category   string `json:"category,omitempty"`
+	ExternalID string `json:"external_id,omitempty"`
+	Imported   bool   `json:"imported,omitempty"`
+	Type       string `json:"type"`
+}
+
+// newTransactionV1 преобразует транзакцию в формат ответа /api/v1.
+func newTransactionV1(t models.Transaction) transactionV1 {
+	return transactionV1{
+		ID:         t.ID,
+		From:       t.From,
+		To:         t.To,
+		Amount:     formatAmount(t.Amount, -1),
+		CreatedAt:  t.CreatedAt.UTC().Format(time.RFC3339),
+		Memo:       t.Memo,
+		Category:   t.Category,
+		ExternalID: t.ExternalID,
+		Imported:   t.Imported,
+		Type:       t.Type,
+	}
+}
+
+// formatAmount форматирует сумму строкой: с digits знаками после запятой или,
+// если digits отрицательно, без лишних нулей ("69.5").
+func formatAmount(amount decimal.Decimal, digits int) string {
+	if digits < 0 {
+		return amount.String()
+	}
+	return amount.StringFixed(int32(digits))
+}
+
+// sendResponseV1 - ответ POST /api/v1/send: участники перевода и записанная транзакция.
+// Баланс, как и сумма в transactionV1, передается строкой.
+type sendResponseV1 struct {
+	service.Transfer
+	TransactionID int    `json:"transaction_id"`
+	SenderBalance string `json:"sender_balance"`
+	CreatedAt     string `json:"created_at"`
+
+	// ToBalance - баланс получателя сразу после перевода, прочитанный в его транзакции, как
+	// и SenderBalance: клиенту не нужен отдельный запрос баланса, который мог бы вернуть устаревшее
+	// значение (реплика, кэш). Возвращается только запросу с токеном администратора.
+	ToBalance *string `json:"to_balance,omitempty"`
+}
+
+// balanceV1 - ответ GET /api/v1/wallet/{address}/balance. Суммы передаются строками,
+// как в transactionV1.
+type balanceV1 struct {
+	Total     string            `json:"total"`     // Баланс кошелька
+	Reserved  string            `json:"reserved"`  // Сумма переводов, ожидающих подтверждения
+	Available string            `json:"available"` // Остаток, который можно отправить
+	Label     string            `json:"label,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Currency  string            `json:"currency,omitempty"` // Валюта для отображения (DISPLAY_CURRENCY)
+}
+
+// GetBalanceV1Handler возвращает HTTP-обработчик GET /api/v1/wallet/{address}/balance.
+// В отличие от GetBalanceHandler, возвращающего одно число, сообщает баланс, резерв
+// отложенных переводов и доступный остаток, с которым сравнивается сумма перевода:
+//
+//	{"total": "100", "reserved": "60", "available": "40", "label": "ops-float"}
+//
+// Параметр format=decimal выводит суммы с scale знаками после запятой ("100.00").
+// Поле currency есть, только если задана валюта для отображения.
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//   - scale: Количество знаков после запятой в формате decimal.
+//   - currency: Код или символ валюты для отображения (DISPLAY_CURRENCY); пусто - поля нет.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.HandleFunc("/api/v1/wallet/{address}/balance", GetBalanceV1Handler(svc, 2, "USD")).Methods("GET")
+func GetBalanceV1Handler(svc *service.Service, scale int, currency string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		address, err := parseAddress(mux.Vars(r)["address"])
+		if err != nil {
+			writeAddressError(w, err, "Invalid wallet address")
+			return
+		}
+		format, ok := balanceFormat(w, r)
+		if !ok {
+			return
+		}
+		digits := -1
+		if format == balanceFormatDecimal {
+			digits = scale
+		}
+
+		// Метка и теги читаются отдельно: баланс и резерв получаются одним запросом
+		wallet, err := svc.GetWallet(r.Context(), address)
+		if writeWalletError(w, err) {
+			return
+		}
+		balance, err := svc.GetBalanceDetails(r.Context(), address)
+		if writeWalletError(w, err) {
+			return
+		}
+		writeJSON(w, http.StatusOK, balanceV1{
+			Total:     formatAmount(balance.Total, digits),
+			Reserved:  formatAmount(balance.Reserved, digits),
+			Available: formatAmount(balance.Available, digits),
+			Label:     wallet.Label,
+			Tags:      wallet.Tags,
+			Currency:  currency,
+		})
+	}
+}
+
+// SendV1Handler возвращает HTTP-обработчик POST /api/v1/send. Принимает то же тело, что и
+// SendHandler, и отвечает 201 с записанной транзакцией и участниками перевода: адресом,
+// в который разрешена метка, и самой меткой, чтобы клиент мог заметить неожиданное разрешение:
+//
+//	{"from": {"address": "...", "label": "ops-float"}, "to": {"address": "..."},
+//	 "transaction_id": 42, "sender_balance": "69.5", "created_at": "2024-05-01T12:00:00Z",
+//	 "to_balance": "130.5"}
+//
+// Баланс получателя - сведения о чужом кошельке, поэтому to_balance возвращается только
+// запросу с токеном администратора. Комиссий сервис не взимает, поэтому сумма списания
+// равна сумме перевода.
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//   - adminToken: Токен администратора, открывающий баланс получателя; пусто - не открывает.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.HandleFunc("/api/v1/send", SendV1Handler(svc, cfg.AdminToken)).Methods("POST")
+func SendV1Handler(svc *service.Service, adminToken string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		transfer, ok := send(w, r, svc, true)
+		if !ok {
+			return
+		}
+		resp := sendResponseV1{
+			Transfer:      transfer,
+			TransactionID: transfer.Result.TransactionID,
+			SenderBalance: formatAmount(transfer.Result.SenderBalance, -1),
+			CreatedAt:     transfer.Result.CreatedAt.UTC().Format(time.RFC3339),
+		}
+		if hasAdminToken(r, adminToken) {
+			toBalance := formatAmount(transfer.Result.ReceiverBalance, -1)
+			resp.ToBalance = &toBalance
+		}
+		writeJSON(w, http.StatusCreated, resp)
+	}
+}
+
+// writeSendErrorV1 отвечает на ошибку перевода, для которой у устаревшего POST /api/send
+// нет кода ошибки (там ответ - текст с кодом 400 или 409): v1 сообщает код в стандартном
+// JSON-формате, чтобы клиент мог различить причины, не разбирая текст.
+func writeSendErrorV1(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errs.ErrInsufficientFunds):
+		writeJSONError(w, http.StatusBadRequest, "insufficient_funds", err.Error())
+	case errors.Is(err, errs.ErrBelowMinimumBalance):
+		writeJSONError(w, http.StatusBadRequest, "below_minimum_balance", err.Error())
+	case errors.Is(err, errs.ErrBalanceOverflow):
+		writeJSONError(w, http.StatusBadRequest, "balance_overflow", err.Error())
+	case errors.Is(err, errs.ErrWalletNotFound):
+		writeJSONError(w, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, db.ErrContention):
+		writeJSONError(w, http.StatusConflict, "contention", err.Error())
+	default:
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", err.Error())
+	}
+}
+
+// GetLastV1Handler возвращает HTTP-обработчик GET /api/v1/transactions.
+// Параметры запроса те же, что у GetLastHandler; транзакции возвращаются в формате transactionV1.
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//   - maxCount: Максимальное количество транзакций в одном ответе.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	v1.HandleFunc("/transactions", GetLastV1Handler(svc, 100)).Methods("GET")
+func GetLastV1Handler(svc *service.Service, maxCount int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		transactions, ok := lastTransactions(w, r, svc, maxCount)
+		if !ok {
+			return
+		}
+
+		resp := make([]transactionV1, 0, len(transactions))
+		for _, t := range transactions {
+			resp = append(resp, newTransactionV1(t))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+			return
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// TestSendV1Balances проверяет балансы в ответе POST /api/v1/send: баланс отправителя -
+// только в sender_balance, баланс получателя - только запросу с токеном администратора.
+func TestSendV1Balances(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization string
+		wantTo        bool
+	}{
+		{"without token", "", false},
+		{"wrong token", "Bearer guess", false},
+		{"admin token", "Bearer secret", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			from := env.wallet(t, "100")
+			to := env.wallet(t, "5")
+			body := `{"from":"` + from + `","to":"` + to + `","amount":30.5}`
+			rec := serve(t, SendV1Handler(env.svc, "secret"), "POST", "/api/v1/send", body, "Authorization", tt.authorization)
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("status = %d, want 201; body: %s", rec.Code, rec.Body)
+			}
+
+			var resp map[string]json.RawMessage
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode %s: %v", rec.Body, err)
+			}
+			if got := string(resp["sender_balance"]); got != `"69.5"` {
+				t.Errorf("sender_balance = %s, want \"69.5\"", got)
+			}
+			if _, ok := resp["from_balance"]; ok {
+				t.Errorf("response has from_balance, which duplicates sender_balance: %s", rec.Body)
+			}
+			toBalance, ok := resp["to_balance"]
+			if ok != tt.wantTo {
+				t.Fatalf("to_balance present = %t, want %t: %s", ok, tt.wantTo, rec.Body)
+			}
+			if ok && string(toBalance) != `"35.5"` {
+				t.Errorf("to_balance = %s, want \"35.5\"", toBalance)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+)
+
+// BuildInfo описывает сборку сервиса. Версия, коммит и время задаются при сборке через -ldflags -X
+// (см. переменные version, commit и buildTime в cmd/main.go).
+type BuildInfo struct {
+	Version   string `json:"version"`    // Версия релиза, например "v1.4.0"
+	Commit    string `json:"commit"`     // Хеш коммита, из которого собран сервис
+	BuildTime string `json:"build_time"` // Время сборки в формате RFC 3339
+	GoVersion string `json:"go_version"` // Версия Go, которой собран сервис (runtime.Version)
+	DBDriver  string `json:"db_driver"`  // Используемое хранилище (DB_DRIVER): postgres, sqlite или memory
+}
+
+// VersionHandler возвращает HTTP-обработчик GET /version и GET /api/version,
+// сообщающий, какая сборка запущена и с каким хранилищем.
+//
+// Параметры:
+//   - info: Сведения о сборке.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	router.HandleFunc("/version", VersionHandler(BuildInfo{Version: "v1.4.0"})).Methods("GET")
+func VersionHandler(info BuildInfo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, info)
+	}
+}
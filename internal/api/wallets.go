@@ -0,0 +1,70 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	models "payment-system/internal/models"
+	service "payment-system/internal/service"
+
+	"github.com/shopspring/decimal"
+)
+
+// maxBulkWallets - максимальное количество кошельков, создаваемых одним запросом.
+const maxBulkWallets = 1000000
+
+// bulkWalletsRequest - тело запроса массового создания кошельков.
+type bulkWalletsRequest struct {
+	Count   int             `json:"count"`
+	Balance decimal.Decimal `json:"balance"`
+}
+
+// BulkWalletsHandler возвращает HTTP-обработчик массового создания кошельков
+// со случайными адресами (например, для нагрузочных тестов).
+// Принимает {"count": N, "balance": B} и отвечает {"created": N}.
+//
+// Параметры:
+//   - svc: Сервис для работы с бизнес-логикой.
+//
+// Возвращает:
+//   - HTTP-обработчик.
+//
+// Пример использования:
+//
+//	admin.HandleFunc("/wallets/bulk", BulkWalletsHandler(svc)).Methods("POST")
+func BulkWalletsHandler(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req bulkWalletsRequest
+		if err := decodeJSONBody(r.Body, &req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		if req.Count <= 0 || req.Count > maxBulkWallets {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request",
+				fmt.Sprintf("count must be from 1 to %d", maxBulkWallets))
+			return
+		}
+		if req.Balance.IsNegative() {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", "balance must not be negative")
+			return
+		}
+		if err := models.ValidateAmountScale(req.Balance); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+
+		created, err := svc.CreateWallets(r.Context(), req.Count, req.Balance)
+		if writeUnavailable(w, err) {
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error",
+				fmt.Sprintf("created %d of %d wallets: %v", created, req.Count, err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int{"created": created})
+	}
+}
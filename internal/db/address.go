@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"payment-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// AddressGenerator генерирует адреса кошельков из источника случайных байт. Репозитории
+// и сервис создают кошельки через генератор, поэтому тесты могут подставить детерминированный
+// источник и получать воспроизводимые адреса.
+type AddressGenerator struct {
+	mu     sync.Mutex // Источник не обязан быть безопасным для параллельного чтения
+	random io.Reader
+}
+
+// DefaultAddressGenerator - генератор на crypto/rand; используется по умолчанию.
+var DefaultAddressGenerator = NewAddressGenerator(nil)
+
+// NewAddressGenerator создает генератор адресов.
+//
+// Параметры:
+//   - random: Источник случайных байт; nil - crypto/rand.Reader.
+//
+// Возвращает:
+//   - Указатель на новый генератор.
+//
+// Пример использования:
+//
+//	addresses := db.NewAddressGenerator(mrand.NewChaCha8([32]byte{})) // воспроизводимые адреса
+func NewAddressGenerator(random io.Reader) *AddressGenerator {
+	if random == nil {
+		random = rand.Reader
+	}
+	return &AddressGenerator{random: random}
+}
+
+// Generate генерирует случайный адрес из AddressBytes() байт в hex
+// (по умолчанию 32 байта, 64 символа).
+//
+// Возвращает:
+//   - Сгенерированный адрес.
+//   - Ошибку, если источник не вернул нужное количество байт; программа при этом
+//     продолжает работу, а запрос на создание кошелька завершается ошибкой.
+func (g *AddressGenerator) Generate() (string, error) {
+	buffer := make([]byte, AddressBytes())
+	g.mu.Lock()
+	_, err := io.ReadFull(g.random, buffer)
+	g.mu.Unlock()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return hex.EncodeToString(buffer), nil
+}
+
+// CreateWallet создает кошелек со случайным адресом, как CreateWalletWithRandomAddress,
+// но с адресами этого генератора.
+func (g *AddressGenerator) CreateWallet(ctx context.Context, repo Repository, balance decimal.Decimal, metadata models.WalletMetadata, publicKey string) (string, error) {
+	return g.CreateUnique(func(address string) error {
+		return repo.CreateWallet(ctx, address, balance, metadata, publicKey)
+	})
+}
+
+// CreateUnique вызывает create со случайными адресами, пока create возвращает ErrWalletExists,
+// но не более maxAddressAttempts раз. Нужен, когда кошелек создается не одним вызовом
+// Repository.CreateWallet, а, например, в транзакции WithTx.
+//
+// Возвращает:
+//   - Адрес, с которым create завершилась успешно.
+//   - ErrAddressCollision, если свободный адрес получить не удалось, или ошибку create.
+func (g *AddressGenerator) CreateUnique(create func(address string) error) (string, error) {
+	for attempt := 0; attempt < maxAddressAttempts; attempt++ {
+		address, err := g.Generate()
+		if err != nil {
+			return "", err
+		}
+
+		err = create(address)
+		if errors.Is(err, ErrWalletExists) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return address, nil
+	}
+	return "", ErrAddressCollision
+}
+
+// GenerateAddress генерирует случайный адрес генератором DefaultAddressGenerator.
+//
+// Возвращает:
+//   - Сгенерированный адрес.
+//   - Ошибку, если не удалось сгенерировать случайные байты.
+//
+// Пример использования:
+//
+//	address, err := GenerateAddress()
+func GenerateAddress() (string, error) {
+	return DefaultAddressGenerator.Generate()
+}
@@ -0,0 +1,149 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"payment-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// approvalColumns - столбцы отложенного перевода в порядке, ожидаемом scanApproval.
+const approvalColumns = "id, from_address, to_address, amount, memo, category, nonce, status, reason, created_at, decided_at"
+
+// reservedColumn возвращает выражение для сумм, зарезервированных переводами с кошелька, которые
+// ожидают подтверждения; используется в запросах к таблице wallets. Синтаксис совместим
+// с PostgreSQL и SQLite; sum - агрегатная функция точной суммы конкретной базы (pgSum или sqliteSum).
+func reservedColumn(sum string) string {
+	return `COALESCE((SELECT ` + sum + `(amount) FROM pending_approvals
+	WHERE pending_approvals.from_address = wallets.address AND status = 'awaiting_review'), 0)`
+}
+
+// newBalance составляет баланс с учетом резерва; резерв больше баланса (например, после
+// переводов, выполненных до постановки в очередь) дает нулевой доступный остаток.
+func newBalance(total, reserved decimal.Decimal) models.Balance {
+	return models.Balance{Total: total, Reserved: reserved, Available: decimal.Max(total.Sub(reserved), decimal.Zero)}
+}
+
+// getBalanceDetails читает баланс кошелька и резерв одним запросом.
+func getBalanceDetails(ctx context.Context, db *sql.DB, sum, address string) (models.Balance, error) {
+	var total, reserved decimal.Decimal
+	err := db.QueryRowContext(ctx, "SELECT balance, "+reservedColumn(sum)+" FROM wallets WHERE address = $1", address).Scan(&total, &reserved)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Balance{}, fmt.Errorf("failed to get balance: %w", ErrWalletNotFound)
+	}
+	if err != nil {
+		return models.Balance{}, fmt.Errorf("failed to get balance: %w", err)
+	}
+	return newBalance(total, reserved), nil
+}
+
+// scanApproval читает отложенный перевод из строки результата с approvalColumns.
+func scanApproval(row rowScanner) (models.Approval, error) {
+	var a models.Approval
+	var decidedAt sql.NullTime
+	if err := row.Scan(&a.ID, &a.From, &a.To, &a.Amount, &a.Memo, &a.Category, &a.Nonce, &a.Status, &a.Reason, &a.CreatedAt, &decidedAt); err != nil {
+		return models.Approval{}, err
+	}
+	a.CreatedAt = a.CreatedAt.UTC()
+	if decidedAt.Valid {
+		t := decidedAt.Time.UTC()
+		a.DecidedAt = &t
+	}
+	return a, nil
+}
+
+// Ниже - запросы к таблице pending_approvals, общие для PostgreSQL и SQLite.
+// Моменты времени (now, before) передаются в виде, сравнимом со столбцами времени
+// конкретной базы: time.Time для PostgreSQL, sqliteTime для SQLite.
+
+// createApproval сохраняет отложенный перевод со статусом models.ApprovalAwaitingReview.
+func createApproval(ctx context.Context, db *sql.DB, a models.Approval, now interface{}) (models.Approval, error) {
+	created, err := scanApproval(db.QueryRowContext(ctx, `
+		INSERT INTO pending_approvals (from_address, to_address, amount, memo, category, nonce, status, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, '', $8)
+		RETURNING `+approvalColumns,
+		a.From, a.To, a.Amount, a.Memo, a.Category, a.Nonce, models.ApprovalAwaitingReview, now))
+	if err != nil {
+		return models.Approval{}, fmt.Errorf("failed to create approval: %w", err)
+	}
+	return created, nil
+}
+
+// getApproval возвращает отложенный перевод по ID.
+func getApproval(ctx context.Context, db querier, id int64) (models.Approval, error) {
+	a, err := scanApproval(db.QueryRowContext(ctx, "SELECT "+approvalColumns+" FROM pending_approvals WHERE id = $1", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Approval{}, fmt.Errorf("failed to get approval: %w", ErrApprovalNotFound)
+	}
+	if err != nil {
+		return models.Approval{}, fmt.Errorf("failed to get approval: %w", err)
+	}
+	return a, nil
+}
+
+// getApprovals возвращает последние count отложенных переводов, начиная с самого нового.
+func getApprovals(ctx context.Context, db *sql.DB, status string, count int) ([]models.Approval, error) {
+	rows, err := db.QueryContext(ctx,
+		"SELECT "+approvalColumns+" FROM pending_approvals WHERE $1 = '' OR status = $1 ORDER BY id DESC LIMIT $2",
+		status, count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query approvals: %w", err)
+	}
+	defer rows.Close()
+
+	var approvals []models.Approval
+	for rows.Next() {
+		a, err := scanApproval(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan approval: %w", err)
+		}
+		approvals = append(approvals, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return approvals, nil
+}
+
+// updateApprovalStatus меняет статус одним условным UPDATE, поэтому параллельные
+// решения по одному переводу не выполняются дважды.
+func updateApprovalStatus(ctx context.Context, db querier, id int64, from, to, reason string, now interface{}) (models.Approval, error) {
+	a, err := scanApproval(db.QueryRowContext(ctx, `
+		UPDATE pending_approvals SET status = $3, reason = $4, decided_at = $5
+		WHERE id = $1 AND status = $2
+		RETURNING `+approvalColumns,
+		id, from, to, reason, now))
+	if err == nil {
+		return a, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return models.Approval{}, fmt.Errorf("failed to update approval: %w", err)
+	}
+
+	// Строка не изменена: перевода нет или его статус уже другой
+	current, err := getApproval(ctx, db, id)
+	if err != nil {
+		return models.Approval{}, err
+	}
+	return models.Approval{}, fmt.Errorf("%w: approval %d is %s", ErrApprovalStatus, id, current.Status)
+}
+
+// expireApprovals переводит в статус models.ApprovalExpired переводы, ожидающие решения с момента раньше before.
+func expireApprovals(ctx context.Context, db *sql.DB, before, now interface{}) (int, error) {
+	res, err := db.ExecContext(ctx, `
+		UPDATE pending_approvals SET status = $1, reason = 'not reviewed in time', decided_at = $2
+		WHERE status = $3 AND created_at < $4`,
+		models.ApprovalExpired, now, models.ApprovalAwaitingReview, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire approvals: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire approvals: %w", err)
+	}
+	return int(n), nil
+}
@@ -0,0 +1,171 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"payment-system/internal/models"
+)
+
+// anonymizeBatchSize - наибольшее количество строк, изменяемых одним запросом анонимизации.
+// Каждая пачка выполняется отдельной транзакцией, поэтому анонимизация кошелька с длинной
+// историей не держит блокировки на большой части таблицы transactions.
+const anonymizeBatchSize = 1000
+
+// Ниже - запросы к журналу аудита и анонимизация, общие для PostgreSQL и SQLite.
+
+// recordAuditEvent сохраняет запись журнала аудита; время назначает база.
+func recordAuditEvent(ctx context.Context, db *sql.DB, event models.AuditEvent) error {
+	_, err := db.ExecContext(ctx,
+		"INSERT INTO audit_log (action, target, details) VALUES ($1, $2, $3)",
+		event.Action, event.Target, event.Details)
+	if err != nil {
+		return fmt.Errorf("failed to record audit event: %w", err)
+	}
+	return nil
+}
+
+// getAuditEvents возвращает последние count записей журнала аудита, начиная с самой новой.
+func getAuditEvents(ctx context.Context, db *sql.DB, count int) ([]models.AuditEvent, error) {
+	rows, err := db.QueryContext(ctx,
+		"SELECT id, action, target, details, created_at FROM audit_log ORDER BY id DESC LIMIT $1", count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.AuditEvent
+	for rows.Next() {
+		var e models.AuditEvent
+		if err := rows.Scan(&e.ID, &e.Action, &e.Target, &e.Details, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		e.CreatedAt = e.CreatedAt.UTC()
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return events, nil
+}
+
+// Условия записей кошелька $1, в которых еще остались персональные данные. Комментарий
+// транзакции хранится как NULL, если не задан; отложенного перевода - как пустая строка.
+// В уведомлениях персональные данные - адрес получателя и метка отправителя.
+const (
+	walletPersonalData       = "address = $1 AND (label IS NOT NULL OR tags <> '{}' OR notify_email IS NOT NULL)"
+	transactionPersonalData  = "(from_address = $1 OR to_address = $1) AND memo IS NOT NULL"
+	approvalPersonalData     = "(from_address = $1 OR to_address = $1) AND memo <> ''"
+	notificationPersonalData = "((address = $1 AND email <> '') OR (from_address = $1 AND from_label <> ''))"
+)
+
+// anonymizeNotification очищает в уведомлении данные кошелька $1: адрес, если кошелек -
+// получатель, и метку, если отправитель.
+const anonymizeNotification = "email = CASE WHEN address = $1 THEN '' ELSE email END, " +
+	"from_label = CASE WHEN from_address = $1 THEN '' ELSE from_label END"
+
+// anonymizeWallet удаляет метку, теги и адрес для уведомлений кошелька, комментарии его транзакций
+// (в том числе перенесенных в архив) и отложенных переводов и его данные в уведомлениях; суммы
+// и балансы не меняются. Уже очищенные записи не выбираются, поэтому
+// повторный вызов ничего не меняет и сообщает нули. При dryRun записи только подсчитываются.
+// Каждый запрос получает собственное ограничение времени timeout.
+func anonymizeWallet(ctx context.Context, db *sql.DB, timeout time.Duration, address string, dryRun bool) (models.AnonymizeReport, error) {
+	report := models.AnonymizeReport{DryRun: dryRun}
+
+	queryCtx, cancel := withQueryTimeout(ctx, timeout)
+	var exists bool
+	err := db.QueryRowContext(queryCtx, "SELECT TRUE FROM wallets WHERE address = $1", address).Scan(&exists)
+	cancel()
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.AnonymizeReport{}, fmt.Errorf("failed to anonymize wallet: %w", ErrWalletNotFound)
+	}
+	if err != nil {
+		return models.AnonymizeReport{}, fmt.Errorf("failed to anonymize wallet: %w", err)
+	}
+
+	var archived int
+	if dryRun {
+		counts := []struct {
+			table, where string
+			n            *int
+		}{
+			{"wallets", walletPersonalData, &report.Wallets},
+			{"transactions", transactionPersonalData, &report.Transactions},
+			{"transactions_archive", transactionPersonalData, &archived},
+			{"pending_approvals", approvalPersonalData, &report.Approvals},
+			{"notifications", notificationPersonalData, &report.Notifications},
+		}
+		for _, c := range counts {
+			ctx, cancel := withQueryTimeout(ctx, timeout)
+			err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+c.table+" WHERE "+c.where, address).Scan(c.n)
+			cancel()
+			if err != nil {
+				return models.AnonymizeReport{}, fmt.Errorf("failed to count %s: %w", c.table, err)
+			}
+		}
+		report.Transactions += archived
+		return report, nil
+	}
+
+	// Сначала основная таблица, затем архив: транзакция, перенесенная очисткой истории
+	// между запросами, будет найдена в архиве
+	if report.Transactions, err = updateInBatches(ctx, db, timeout, "transactions", "memo = NULL", transactionPersonalData, address); err != nil {
+		return models.AnonymizeReport{}, err
+	}
+	if archived, err = updateInBatches(ctx, db, timeout, "transactions_archive", "memo = NULL", transactionPersonalData, address); err != nil {
+		return models.AnonymizeReport{}, err
+	}
+	report.Transactions += archived
+	if report.Approvals, err = updateInBatches(ctx, db, timeout, "pending_approvals", "memo = ''", approvalPersonalData, address); err != nil {
+		return models.AnonymizeReport{}, err
+	}
+	if report.Notifications, err = updateInBatches(ctx, db, timeout, "notifications", anonymizeNotification, notificationPersonalData, address); err != nil {
+		return models.AnonymizeReport{}, err
+	}
+
+	// Метка и теги очищаются последними: если анонимизация прервется, повторный вызов
+	// найдет оставшиеся записи так же, как первый
+	queryCtx, cancel = withQueryTimeout(ctx, timeout)
+	defer cancel()
+	res, err := db.ExecContext(queryCtx, "UPDATE wallets SET label = NULL, tags = '{}', notify_email = NULL WHERE "+walletPersonalData, address)
+	if err != nil {
+		return models.AnonymizeReport{}, fmt.Errorf("failed to anonymize wallet: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return models.AnonymizeReport{}, fmt.Errorf("failed to anonymize wallet: %w", err)
+	}
+	report.Wallets = int(n)
+	return report, nil
+}
+
+// updateInBatches применяет set к строкам table, удовлетворяющим where (с адресом в $1),
+// пачками по anonymizeBatchSize строк, пока такие строки не закончатся.
+// Изменение должно выводить строку из where, иначе цикл не завершится.
+//
+// Возвращает:
+//   - Количество измененных строк.
+//   - Ошибку; строки, измененные предыдущими пачками, остаются измененными.
+func updateInBatches(ctx context.Context, db *sql.DB, timeout time.Duration, table, set, where, address string) (int, error) {
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE id IN (SELECT id FROM %s WHERE %s LIMIT $2)", table, set, table, where)
+	total := 0
+	for {
+		ctx, cancel := withQueryTimeout(ctx, timeout)
+		res, err := db.ExecContext(ctx, query, address, anonymizeBatchSize)
+		cancel()
+		if err != nil {
+			return total, fmt.Errorf("failed to anonymize %s: %w", table, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return total, fmt.Errorf("failed to anonymize %s: %w", table, err)
+		}
+		total += int(n)
+		if n < anonymizeBatchSize {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,733 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"payment-system/internal/metrics"
+	"payment-system/internal/models"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/shopspring/decimal"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// ErrDatabaseUnavailable возвращается, пока автомат отключения открыт:
+// запросы к базе не выполняются, чтобы не копить ожидающие горутины.
+var ErrDatabaseUnavailable = errors.New("database unavailable")
+
+// UnavailableError - ошибка открытого автомата отключения.
+// Совпадает с ErrDatabaseUnavailable через errors.Is и сообщает,
+// через сколько стоит повторить запрос (для заголовка Retry-After).
+type UnavailableError struct {
+	RetryAfter time.Duration
+}
+
+// Error возвращает текст ошибки.
+func (e *UnavailableError) Error() string {
+	return ErrDatabaseUnavailable.Error()
+}
+
+// Is позволяет сравнивать ошибку с ErrDatabaseUnavailable через errors.Is.
+func (e *UnavailableError) Is(target error) bool {
+	return target == ErrDatabaseUnavailable
+}
+
+// breakerState - состояние автомата отключения.
+type breakerState int
+
+const (
+	stateClosed breakerState = iota
+	stateHalfOpen
+	stateOpen
+)
+
+// String возвращает название состояния для логов и отчета готовности.
+func (s breakerState) String() string {
+	switch s {
+	case stateHalfOpen:
+		return "half-open"
+	case stateOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitBreaker оборачивает Repository автоматом отключения.
+// После threshold подряд идущих сбоев (таймаутов или ошибок базы) автомат открывается
+// и в течение cooldown все вызовы сразу завершаются с ErrDatabaseUnavailable.
+// По истечении cooldown пропускается один пробный вызов: успех закрывает автомат,
+// сбой снова открывает его.
+//
+// Бизнес-ошибки (недостаточно средств, кошелек не найден) и отмена запроса клиентом
+// сбоями не считаются (см. isInfrastructureError).
+type CircuitBreaker struct {
+	repo      Repository
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	state     breakerState
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// NewCircuitBreaker создает автомат отключения вокруг репозитория.
+//
+// Параметры:
+//   - repo: Репозиторий, вызовы которого защищаются.
+//   - threshold: Количество подряд идущих сбоев, открывающее автомат.
+//   - cooldown: Время, в течение которого автомат остается открытым.
+//
+// Пример использования:
+//
+//	repo = db.NewCircuitBreaker(db.NewPostgresRepository(), 5, 10*time.Second)
+func NewCircuitBreaker(repo Repository, threshold int, cooldown time.Duration) *CircuitBreaker {
+	metrics.DBBreakerState.Set(metrics.BreakerClosed)
+	return &CircuitBreaker{repo: repo, threshold: threshold, cooldown: cooldown}
+}
+
+// State возвращает текущее состояние автомата: "closed", "half-open" или "open".
+func (b *CircuitBreaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state.String()
+}
+
+// allow решает, можно ли выполнить вызов. В пробном режиме пропускается только один вызов.
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if b.state == stateOpen {
+		if now.Before(b.openUntil) {
+			return &UnavailableError{RetryAfter: b.openUntil.Sub(now)}
+		}
+		b.setState(stateHalfOpen)
+	}
+	if b.state == stateHalfOpen {
+		if b.probing {
+			return &UnavailableError{RetryAfter: b.cooldown}
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// record учитывает результат вызова и при необходимости меняет состояние автомата.
+func (b *CircuitBreaker) record(ctx context.Context, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if !isInfrastructureError(ctx, err) {
+		b.failures = 0
+		if b.state != stateClosed {
+			b.setState(stateClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == stateHalfOpen || b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+		b.setState(stateOpen)
+	}
+}
+
+// setState меняет состояние, записывает переход в лог и обновляет метрики.
+// Вызывается под b.mu.
+func (b *CircuitBreaker) setState(state breakerState) {
+	if b.state == state {
+		return
+	}
+	slog.Warn("database circuit breaker state changed", "from", b.state.String(), "to", state.String(), "failures", b.failures)
+	b.state = state
+
+	switch state {
+	case stateOpen:
+		metrics.DBBreakerState.Set(metrics.BreakerOpen)
+	case stateHalfOpen:
+		metrics.DBBreakerState.Set(metrics.BreakerHalfOpen)
+	default:
+		metrics.DBBreakerState.Set(metrics.BreakerClosed)
+	}
+	metrics.DBBreakerTransitions.WithLabelValues(state.String()).Inc()
+}
+
+// Классы SQLSTATE, означающие сбой сервера, а не ошибку запроса: нехватка ресурсов (53),
+// вмешательство оператора, включая отмену запроса по таймауту (57), системная (58)
+// и внутренняя (XX) ошибки. Класс 08 (подключение) проверяет isConnectionError.
+var pgServerFailureClasses = []string{"53", "57", "58", "XX"}
+
+// isInfrastructureError сообщает, является ли ошибка сбоем базы: обрывом или недоступностью
+// подключения, перегрузкой или отказом сервера, истечением DB_QUERY_TIMEOUT или неизвестным
+// исходом фиксации. Все остальные ошибки (бизнес-ошибки, нарушения ограничений,
+// ErrAddressCollision, ErrContention) сбоями не считаются.
+//
+// Если ctx вызывающего уже отменен или истек, ошибка сбоем не считается: клиент ушел
+// или сам ограничил время запроса, а база при этом может быть здорова.
+func isInfrastructureError(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		// Срок вызывающего не истек, значит, истек DB_QUERY_TIMEOUT
+		return true
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		for _, class := range pgServerFailureClasses {
+			if strings.HasPrefix(pgErr.Code, class) {
+				return true
+			}
+		}
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() & 0xff {
+		case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_IOERR, sqlite3.SQLITE_CORRUPT, sqlite3.SQLITE_FULL, sqlite3.SQLITE_CANTOPEN:
+			return true
+		}
+		return false
+	}
+	return errors.Is(err, ErrOutcomeUnknown) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		isConnectionError(err)
+}
+
+// CreateWallet создает кошелек через защищаемый репозиторий.
+func (b *CircuitBreaker) CreateWallet(ctx context.Context, address string, balance decimal.Decimal, metadata models.WalletMetadata, publicKey string) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := b.repo.CreateWallet(ctx, address, balance, metadata, publicKey)
+	b.record(ctx, err)
+	return err
+}
+
+// WithTx выполняет транзакцию через защищаемый репозиторий. Сбоем базы считаются только
+// ошибки операций транзакции и ее фиксации: ошибка, которую вернула сама fn, автомат не открывает.
+func (b *CircuitBreaker) WithTx(ctx context.Context, fn func(tx TxRepository) error) error {
+	return b.withTx(ctx, b.repo.WithTx, fn)
+}
+
+// WithSeedLock выполняет транзакцию под блокировкой наполнения через защищаемый репозиторий;
+// сбои учитываются, как в WithTx.
+func (b *CircuitBreaker) WithSeedLock(ctx context.Context, fn func(tx TxRepository) error) error {
+	return b.withTx(ctx, b.repo.WithSeedLock, fn)
+}
+
+// withTx выполняет fn транзакцией run обернутого репозитория (WithTx или WithSeedLock)
+// и учитывает сбои базы.
+func (b *CircuitBreaker) withTx(ctx context.Context, run func(context.Context, func(TxRepository) error) error, fn func(tx TxRepository) error) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	var fnErr, opErr error
+	err := run(ctx, func(tx TxRepository) error {
+		opErr = nil
+		fnErr = fn(&breakerTx{TxRepository: tx, ctx: ctx, err: &opErr})
+		return fnErr
+	})
+	if err != nil && err == fnErr {
+		// Ошибка вернулась из fn: сбой базы - только если ее вызвала операция транзакции
+		b.record(ctx, opErr)
+		return err
+	}
+	b.record(ctx, err)
+	return err
+}
+
+// breakerTx запоминает последнюю ошибку операций транзакции для CircuitBreaker.WithTx.
+type breakerTx struct {
+	TxRepository
+	ctx context.Context
+	err *error
+}
+
+// GetWalletForUpdate читает кошелек и запоминает ошибку.
+func (t *breakerTx) GetWalletForUpdate(address string) (WalletState, error) {
+	state, err := t.TxRepository.GetWalletForUpdate(address)
+	t.remember(err)
+	return state, err
+}
+
+// AddBalance изменяет баланс и запоминает ошибку.
+func (t *breakerTx) AddBalance(address string, delta decimal.Decimal) (decimal.Decimal, error) {
+	balance, err := t.TxRepository.AddBalance(address, delta)
+	t.remember(err)
+	return balance, err
+}
+
+// SetNonce сохраняет номер перевода и запоминает ошибку.
+func (t *breakerTx) SetNonce(address string, nonce int64) error {
+	err := t.TxRepository.SetNonce(address, nonce)
+	t.remember(err)
+	return err
+}
+
+// RecordTransaction записывает транзакцию и запоминает ошибку.
+func (t *breakerTx) RecordTransaction(txType, from, to string, amount decimal.Decimal, memo, category string) (models.Transaction, error) {
+	transaction, err := t.TxRepository.RecordTransaction(txType, from, to, amount, memo, category)
+	t.remember(err)
+	return transaction, err
+}
+
+// EnqueueNotification ставит уведомление в очередь и запоминает ошибку.
+func (t *breakerTx) EnqueueNotification(transaction models.Transaction) error {
+	err := t.TxRepository.EnqueueNotification(transaction)
+	t.remember(err)
+	return err
+}
+
+// CreateWallet создает кошелек и запоминает ошибку.
+func (t *breakerTx) CreateWallet(address string, balance decimal.Decimal, metadata models.WalletMetadata, publicKey string) error {
+	err := t.TxRepository.CreateWallet(address, balance, metadata, publicKey)
+	t.remember(err)
+	return err
+}
+
+// RetireWallet архивирует кошелек и запоминает ошибку.
+func (t *breakerTx) RetireWallet(address string) (models.Wallet, error) {
+	wallet, err := t.TxRepository.RetireWallet(address)
+	t.remember(err)
+	return wallet, err
+}
+
+// Send выполняет перевод и запоминает ошибку.
+func (t *breakerTx) Send(from, to string, amount decimal.Decimal, memo, category string, nonce int64) (SendResult, error) {
+	result, err := t.TxRepository.Send(from, to, amount, memo, category, nonce)
+	t.remember(err)
+	return result, err
+}
+
+// UpdateApprovalStatus меняет статус отложенного перевода и запоминает ошибку.
+func (t *breakerTx) UpdateApprovalStatus(id int64, from, to, reason string) (models.Approval, error) {
+	approval, err := t.TxRepository.UpdateApprovalStatus(id, from, to, reason)
+	t.remember(err)
+	return approval, err
+}
+
+// remember запоминает ошибку операции, если это сбой базы.
+func (t *breakerTx) remember(err error) {
+	if isInfrastructureError(t.ctx, err) {
+		*t.err = err
+	}
+}
+
+// CreateWallets создает кошельки через защищаемый репозиторий.
+func (b *CircuitBreaker) CreateWallets(ctx context.Context, count int, balance decimal.Decimal) (int, error) {
+	if err := b.allow(); err != nil {
+		return 0, err
+	}
+	created, err := b.repo.CreateWallets(ctx, count, balance)
+	b.record(ctx, err)
+	return created, err
+}
+
+// HasWallets проверяет наличие кошельков через защищаемый репозиторий.
+func (b *CircuitBreaker) HasWallets(ctx context.Context) (bool, error) {
+	if err := b.allow(); err != nil {
+		return false, err
+	}
+	exists, err := b.repo.HasWallets(ctx)
+	b.record(ctx, err)
+	return exists, err
+}
+
+// GetBalance возвращает баланс кошелька через защищаемый репозиторий.
+func (b *CircuitBreaker) GetBalance(ctx context.Context, address string) (decimal.Decimal, error) {
+	if err := b.allow(); err != nil {
+		return decimal.Zero, err
+	}
+	balance, err := b.repo.GetBalance(ctx, address)
+	b.record(ctx, err)
+	return balance, err
+}
+
+// GetBalances возвращает балансы кошельков через защищаемый репозиторий.
+func (b *CircuitBreaker) GetBalances(ctx context.Context, addresses []string) (map[string]decimal.Decimal, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	balances, err := b.repo.GetBalances(ctx, addresses)
+	b.record(ctx, err)
+	return balances, err
+}
+
+// GetWallet возвращает кошелек через защищаемый репозиторий.
+func (b *CircuitBreaker) GetWallet(ctx context.Context, address string) (models.Wallet, error) {
+	if err := b.allow(); err != nil {
+		return models.Wallet{}, err
+	}
+	wallet, err := b.repo.GetWallet(ctx, address)
+	b.record(ctx, err)
+	return wallet, err
+}
+
+// FindWalletByLabel ищет кошелек по метке через защищаемый репозиторий.
+func (b *CircuitBreaker) FindWalletByLabel(ctx context.Context, label string) (models.Wallet, error) {
+	if err := b.allow(); err != nil {
+		return models.Wallet{}, err
+	}
+	wallet, err := b.repo.FindWalletByLabel(ctx, label)
+	b.record(ctx, err)
+	return wallet, err
+}
+
+// TopWalletsByBalance возвращает кошельки с наибольшим балансом через защищаемый репозиторий.
+func (b *CircuitBreaker) TopWalletsByBalance(ctx context.Context, limit int) ([]models.Wallet, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	wallets, err := b.repo.TopWalletsByBalance(ctx, limit)
+	b.record(ctx, err)
+	return wallets, err
+}
+
+// UpdateWalletMetadata изменяет метаданные кошелька через защищаемый репозиторий.
+func (b *CircuitBreaker) UpdateWalletMetadata(ctx context.Context, address string, patch models.WalletMetadataPatch) (models.Wallet, error) {
+	if err := b.allow(); err != nil {
+		return models.Wallet{}, err
+	}
+	wallet, err := b.repo.UpdateWalletMetadata(ctx, address, patch)
+	b.record(ctx, err)
+	return wallet, err
+}
+
+// ArchiveWallet архивирует кошелек через защищаемый репозиторий.
+func (b *CircuitBreaker) ArchiveWallet(ctx context.Context, address string) (models.Wallet, error) {
+	if err := b.allow(); err != nil {
+		return models.Wallet{}, err
+	}
+	wallet, err := b.repo.ArchiveWallet(ctx, address)
+	b.record(ctx, err)
+	return wallet, err
+}
+
+// RestoreWallet снимает архивацию кошелька через защищаемый репозиторий.
+func (b *CircuitBreaker) RestoreWallet(ctx context.Context, address string) (models.Wallet, error) {
+	if err := b.allow(); err != nil {
+		return models.Wallet{}, err
+	}
+	wallet, err := b.repo.RestoreWallet(ctx, address)
+	b.record(ctx, err)
+	return wallet, err
+}
+
+// GetNonce возвращает номер последнего подписанного перевода через защищаемый репозиторий.
+func (b *CircuitBreaker) GetNonce(ctx context.Context, address string) (int64, error) {
+	if err := b.allow(); err != nil {
+		return 0, err
+	}
+	nonce, err := b.repo.GetNonce(ctx, address)
+	b.record(ctx, err)
+	return nonce, err
+}
+
+// GetBalanceDetails возвращает баланс с резервом через защищаемый репозиторий.
+func (b *CircuitBreaker) GetBalanceDetails(ctx context.Context, address string) (models.Balance, error) {
+	if err := b.allow(); err != nil {
+		return models.Balance{}, err
+	}
+	balance, err := b.repo.GetBalanceDetails(ctx, address)
+	b.record(ctx, err)
+	return balance, err
+}
+
+// Send выполняет перевод через защищаемый репозиторий.
+func (b *CircuitBreaker) Send(ctx context.Context, from, to string, amount decimal.Decimal, memo, category string, nonce int64, isolation sql.IsolationLevel) (SendResult, error) {
+	if err := b.allow(); err != nil {
+		return SendResult{}, err
+	}
+	result, err := b.repo.Send(ctx, from, to, amount, memo, category, nonce, isolation)
+	b.record(ctx, err)
+	return result, err
+}
+
+// SystemTransfer выполняет операцию с системным счетом через защищаемый репозиторий.
+func (b *CircuitBreaker) SystemTransfer(ctx context.Context, txType, address string, amount decimal.Decimal, memo string) (SendResult, error) {
+	if err := b.allow(); err != nil {
+		return SendResult{}, err
+	}
+	result, err := b.repo.SystemTransfer(ctx, txType, address, amount, memo)
+	b.record(ctx, err)
+	return result, err
+}
+
+// ImportTransactions импортирует транзакции через защищаемый репозиторий.
+func (b *CircuitBreaker) ImportTransactions(ctx context.Context, transactions []models.Transaction) (int, error) {
+	if err := b.allow(); err != nil {
+		return 0, err
+	}
+	imported, err := b.repo.ImportTransactions(ctx, transactions)
+	b.record(ctx, err)
+	return imported, err
+}
+
+// GetLastTransactions возвращает последние транзакции через защищаемый репозиторий.
+func (b *CircuitBreaker) GetLastTransactions(ctx context.Context, count int, filter TransactionFilter) ([]models.Transaction, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	transactions, err := b.repo.GetLastTransactions(ctx, count, filter)
+	b.record(ctx, err)
+	return transactions, err
+}
+
+// CountTransactions возвращает количество транзакций через защищаемый репозиторий.
+func (b *CircuitBreaker) CountTransactions(ctx context.Context, filter TransactionFilter) (int64, error) {
+	if err := b.allow(); err != nil {
+		return 0, err
+	}
+	count, err := b.repo.CountTransactions(ctx, filter)
+	b.record(ctx, err)
+	return count, err
+}
+
+// GetWalletTransactions возвращает транзакции кошельков через защищаемый репозиторий.
+func (b *CircuitBreaker) GetWalletTransactions(ctx context.Context, addresses []string, count int) (map[string][]models.Transaction, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	transactions, err := b.repo.GetWalletTransactions(ctx, addresses, count)
+	b.record(ctx, err)
+	return transactions, err
+}
+
+// EstimateTransactions возвращает приблизительное количество транзакций через защищаемый репозиторий.
+func (b *CircuitBreaker) EstimateTransactions(ctx context.Context) (int64, error) {
+	if err := b.allow(); err != nil {
+		return 0, err
+	}
+	count, err := b.repo.EstimateTransactions(ctx)
+	b.record(ctx, err)
+	return count, err
+}
+
+// PurgeTransactions переносит старые транзакции в архив через защищаемый репозиторий.
+func (b *CircuitBreaker) PurgeTransactions(ctx context.Context, before time.Time, archive bool, limit int) (int, error) {
+	if err := b.allow(); err != nil {
+		return 0, err
+	}
+	purged, err := b.repo.PurgeTransactions(ctx, before, archive, limit)
+	b.record(ctx, err)
+	return purged, err
+}
+
+// GetSenderStats возвращает сводку переводов отправителя через защищаемый репозиторий.
+func (b *CircuitBreaker) GetSenderStats(ctx context.Context, address string, since time.Time) (SenderStats, error) {
+	if err := b.allow(); err != nil {
+		return SenderStats{}, err
+	}
+	stats, err := b.repo.GetSenderStats(ctx, address, since)
+	b.record(ctx, err)
+	return stats, err
+}
+
+// GetCategoryVolumes возвращает объемы переводов по категориям через защищаемый репозиторий.
+func (b *CircuitBreaker) GetCategoryVolumes(ctx context.Context, from, to time.Time) ([]CategoryVolume, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	volumes, err := b.repo.GetCategoryVolumes(ctx, from, to)
+	b.record(ctx, err)
+	return volumes, err
+}
+
+// RecordRiskEvent сохраняет срабатывание правила через защищаемый репозиторий.
+func (b *CircuitBreaker) RecordRiskEvent(ctx context.Context, event models.RiskEvent) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := b.repo.RecordRiskEvent(ctx, event)
+	b.record(ctx, err)
+	return err
+}
+
+// GetRiskEvents возвращает последние срабатывания правил через защищаемый репозиторий.
+func (b *CircuitBreaker) GetRiskEvents(ctx context.Context, count int) ([]models.RiskEvent, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	events, err := b.repo.GetRiskEvents(ctx, count)
+	b.record(ctx, err)
+	return events, err
+}
+
+// AnonymizeWallet удаляет персональные данные кошелька через защищаемый репозиторий.
+func (b *CircuitBreaker) AnonymizeWallet(ctx context.Context, address string, dryRun bool) (models.AnonymizeReport, error) {
+	if err := b.allow(); err != nil {
+		return models.AnonymizeReport{}, err
+	}
+	report, err := b.repo.AnonymizeWallet(ctx, address, dryRun)
+	b.record(ctx, err)
+	return report, err
+}
+
+// RecordAuditEvent сохраняет запись журнала аудита через защищаемый репозиторий.
+func (b *CircuitBreaker) RecordAuditEvent(ctx context.Context, event models.AuditEvent) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := b.repo.RecordAuditEvent(ctx, event)
+	b.record(ctx, err)
+	return err
+}
+
+// GetAuditEvents возвращает последние записи журнала аудита через защищаемый репозиторий.
+func (b *CircuitBreaker) GetAuditEvents(ctx context.Context, count int) ([]models.AuditEvent, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	events, err := b.repo.GetAuditEvents(ctx, count)
+	b.record(ctx, err)
+	return events, err
+}
+
+// ReserveIdempotencyKey занимает ключ идемпотентности через защищаемый репозиторий.
+func (b *CircuitBreaker) ReserveIdempotencyKey(ctx context.Context, key, fingerprint string, expiredBefore time.Time) (IdempotentResponse, bool, error) {
+	if err := b.allow(); err != nil {
+		return IdempotentResponse{}, false, err
+	}
+	stored, reserved, err := b.repo.ReserveIdempotencyKey(ctx, key, fingerprint, expiredBefore)
+	b.record(ctx, err)
+	return stored, reserved, err
+}
+
+// CompleteIdempotencyKey сохраняет ответ по ключу идемпотентности через защищаемый репозиторий.
+func (b *CircuitBreaker) CompleteIdempotencyKey(ctx context.Context, key string, response IdempotentResponse) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := b.repo.CompleteIdempotencyKey(ctx, key, response)
+	b.record(ctx, err)
+	return err
+}
+
+// ReleaseIdempotencyKey освобождает ключ идемпотентности через защищаемый репозиторий.
+func (b *CircuitBreaker) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := b.repo.ReleaseIdempotencyKey(ctx, key)
+	b.record(ctx, err)
+	return err
+}
+
+// CreateApproval сохраняет отложенный перевод через защищаемый репозиторий.
+func (b *CircuitBreaker) CreateApproval(ctx context.Context, approval models.Approval) (models.Approval, error) {
+	if err := b.allow(); err != nil {
+		return models.Approval{}, err
+	}
+	created, err := b.repo.CreateApproval(ctx, approval)
+	b.record(ctx, err)
+	return created, err
+}
+
+// GetApproval возвращает отложенный перевод через защищаемый репозиторий.
+func (b *CircuitBreaker) GetApproval(ctx context.Context, id int64) (models.Approval, error) {
+	if err := b.allow(); err != nil {
+		return models.Approval{}, err
+	}
+	approval, err := b.repo.GetApproval(ctx, id)
+	b.record(ctx, err)
+	return approval, err
+}
+
+// GetApprovals возвращает последние отложенные переводы через защищаемый репозиторий.
+func (b *CircuitBreaker) GetApprovals(ctx context.Context, status string, count int) ([]models.Approval, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	approvals, err := b.repo.GetApprovals(ctx, status, count)
+	b.record(ctx, err)
+	return approvals, err
+}
+
+// UpdateApprovalStatus меняет статус отложенного перевода через защищаемый репозиторий.
+func (b *CircuitBreaker) UpdateApprovalStatus(ctx context.Context, id int64, from, to, reason string) (models.Approval, error) {
+	if err := b.allow(); err != nil {
+		return models.Approval{}, err
+	}
+	approval, err := b.repo.UpdateApprovalStatus(ctx, id, from, to, reason)
+	b.record(ctx, err)
+	return approval, err
+}
+
+// ExpireApprovals завершает просроченные отложенные переводы через защищаемый репозиторий.
+func (b *CircuitBreaker) ExpireApprovals(ctx context.Context, before time.Time) (int, error) {
+	if err := b.allow(); err != nil {
+		return 0, err
+	}
+	expired, err := b.repo.ExpireApprovals(ctx, before)
+	b.record(ctx, err)
+	return expired, err
+}
+
+// ClaimNotifications выбирает уведомления для доставки через защищаемый репозиторий.
+func (b *CircuitBreaker) ClaimNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Notification, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	notifications, err := b.repo.ClaimNotifications(ctx, now, lease, limit)
+	b.record(ctx, err)
+	return notifications, err
+}
+
+// CompleteNotification записывает результат доставки уведомления через защищаемый репозиторий.
+func (b *CircuitBreaker) CompleteNotification(ctx context.Context, id int64, status, lastError string, at time.Time) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := b.repo.CompleteNotification(ctx, id, status, lastError, at)
+	b.record(ctx, err)
+	return err
+}
+
+// GetNotifications возвращает последние уведомления через защищаемый репозиторий.
+func (b *CircuitBreaker) GetNotifications(ctx context.Context, status string, count int) ([]models.Notification, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	notifications, err := b.repo.GetNotifications(ctx, status, count)
+	b.record(ctx, err)
+	return notifications, err
+}
+
+// Reconcile проверяет инварианты хранилища через защищаемый репозиторий.
+func (b *CircuitBreaker) Reconcile(ctx context.Context) (ReconcileReport, error) {
+	if err := b.allow(); err != nil {
+		return ReconcileReport{}, err
+	}
+	report, err := b.repo.Reconcile(ctx)
+	b.record(ctx, err)
+	return report, err
+}
+
+// Ping проверяет доступность базы. Пока автомат открыт, возвращает ErrDatabaseUnavailable,
+// не обращаясь к базе; сама проверка на состояние автомата не влияет.
+func (b *CircuitBreaker) Ping(ctx context.Context) error {
+	b.mu.Lock()
+	open := b.state == stateOpen && time.Now().Before(b.openUntil)
+	retryAfter := time.Until(b.openUntil)
+	b.mu.Unlock()
+
+	if open {
+		return &UnavailableError{RetryAfter: retryAfter}
+	}
+	return b.repo.Ping(ctx)
+}
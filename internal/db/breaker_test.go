@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/shopspring/decimal"
+)
+
+func TestIsInfrastructureError(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{"nil", context.Background(), nil, false},
+		{"query timeout", context.Background(), fmt.Errorf("get balance: %w", context.DeadlineExceeded), true},
+		{"caller canceled", canceled, context.Canceled, false},
+		{"caller deadline", canceled, context.DeadlineExceeded, false},
+		{"canceled with live caller", context.Background(), context.Canceled, false},
+		{"connection lost", context.Background(), io.ErrUnexpectedEOF, true},
+		{"bad connection", context.Background(), driver.ErrBadConn, true},
+		{"connection class", context.Background(), &pgconn.PgError{Code: "08006"}, true},
+		{"too many connections", context.Background(), &pgconn.PgError{Code: "53300"}, true},
+		{"statement timeout", context.Background(), &pgconn.PgError{Code: "57014"}, true},
+		{"unique violation", context.Background(), &pgconn.PgError{Code: "23505"}, false},
+		{"serialization failure", context.Background(), &pgconn.PgError{Code: "40001"}, false},
+		{"outcome unknown", context.Background(), ErrOutcomeUnknown, true},
+		{"insufficient funds", context.Background(), ErrInsufficientFunds, false},
+		{"address collision", context.Background(), ErrAddressCollision, false},
+		{"contention", context.Background(), ErrContention, false},
+		{"unknown error", context.Background(), errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isInfrastructureError(tt.ctx, tt.err); got != tt.want {
+				t.Errorf("isInfrastructureError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// failingRepo возвращает err из GetBalance.
+type failingRepo struct {
+	Repository
+	err error
+}
+
+func (r *failingRepo) GetBalance(ctx context.Context, address string) (decimal.Decimal, error) {
+	return decimal.Zero, r.err
+}
+
+func TestCircuitBreakerOpens(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		err      error
+		wantOpen bool
+	}{
+		{"query timeouts", context.Background(), context.DeadlineExceeded, true},
+		{"lost connections", context.Background(), io.EOF, true},
+		{"canceled callers", canceled, context.Canceled, false},
+		{"address collisions", context.Background(), ErrAddressCollision, false},
+		{"business errors", context.Background(), ErrWalletNotFound, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breaker := NewCircuitBreaker(&failingRepo{err: tt.err}, 3, time.Minute)
+			for i := 0; i < 3; i++ {
+				if _, err := breaker.GetBalance(tt.ctx, "addr"); !errors.Is(err, tt.err) {
+					t.Fatalf("call %d: got %v, want %v", i, err, tt.err)
+				}
+			}
+			_, err := breaker.GetBalance(tt.ctx, "addr")
+			if open := errors.Is(err, ErrDatabaseUnavailable); open != tt.wantOpen {
+				t.Errorf("after 3 failures: state %s, err %v, want open %v", breaker.State(), err, tt.wantOpen)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// walletInsertBatchSize - количество кошельков в одном многострочном INSERT.
+	// 2 параметра на строку укладываются в ограничения PostgreSQL и SQLite на число параметров.
+	walletInsertBatchSize = 1000
+
+	// walletProgressEvery - как часто (в созданных кошельках) записывается прогресс в лог.
+	walletProgressEvery = 10000
+)
+
+// generateWallets создает ровно count кошельков с заданным балансом.
+// Адреса генерируются в памяти и вставляются многострочными INSERT пачками по
+// walletInsertBatchSize строк с ON CONFLICT DO NOTHING. Кошельки, пропущенные из-за
+// совпадения адреса, досоздаются в следующих пачках с новыми адресами; если несколько
+// пачек подряд не добавили ни одного кошелька, возвращается ErrAddressCollision.
+// Запрос совместим с PostgreSQL и SQLite.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - db: Указатель на подключение к базе данных.
+//   - addresses: Генератор адресов.
+//   - count: Количество кошельков для создания.
+//   - balance: Начальный баланс каждого кошелька.
+//   - timeout: Ограничение времени одной пачки.
+//
+// Возвращает:
+//   - Количество созданных кошельков (равно count, если ошибки нет).
+//   - Ошибку, если не удалось создать кошельки.
+func generateWallets(ctx context.Context, db *sql.DB, addresses *AddressGenerator, count int, balance decimal.Decimal, timeout time.Duration) (int, error) {
+	created, emptyBatches := 0, 0
+	for created < count {
+		n, err := insertWalletBatch(ctx, db, addresses, min(walletInsertBatchSize, count-created), balance, timeout)
+		if err != nil {
+			return created, fmt.Errorf("failed to insert wallets: %w", err)
+		}
+
+		if n == 0 {
+			emptyBatches++
+			if emptyBatches >= maxAddressAttempts {
+				return created, ErrAddressCollision
+			}
+			continue
+		}
+		emptyBatches = 0
+
+		if (created+n)/walletProgressEvery > created/walletProgressEvery {
+			slog.Info("wallets created", "created", created+n, "total", count)
+		}
+		created += n
+	}
+	return created, nil
+}
+
+// insertWalletBatch вставляет size кошельков со случайными адресами одним запросом.
+//
+// Возвращает:
+//   - Количество фактически добавленных строк (меньше size при совпадении адресов).
+func insertWalletBatch(ctx context.Context, db *sql.DB, addresses *AddressGenerator, size int, balance decimal.Decimal, timeout time.Duration) (int, error) {
+	var query strings.Builder
+	query.WriteString("INSERT INTO wallets (address, balance, opening_balance) VALUES ")
+
+	args := make([]interface{}, 0, size*2)
+	seen := make(map[string]bool, size)
+	for len(seen) < size {
+		address, err := addresses.Generate()
+		if err != nil {
+			return 0, err
+		}
+		if seen[address] {
+			continue
+		}
+		seen[address] = true
+
+		if len(args) > 0 {
+			query.WriteString(", ")
+		}
+		fmt.Fprintf(&query, "($%d, $%d, $%d)", len(args)+1, len(args)+2, len(args)+2)
+		args = append(args, address, balance)
+	}
+	query.WriteString(" ON CONFLICT (address) DO NOTHING")
+
+	ctx, cancel := withQueryTimeout(ctx, timeout)
+	defer cancel()
+
+	res, err := db.ExecContext(ctx, query.String(), args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// CreateWallets создает count кошельков со случайными адресами и заданным балансом
+// многострочными INSERT (см. generateWallets).
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - count: Количество кошельков.
+//   - balance: Начальный баланс каждого кошелька.
+//
+// Возвращает:
+//   - Количество созданных кошельков.
+//   - Ошибку, если создать все кошельки не удалось.
+//
+// Пример использования:
+//
+//	created, err := repo.CreateWallets(ctx, 100000, decimal.NewFromInt(100))
+func (r *PostgresRepository) CreateWallets(ctx context.Context, count int, balance decimal.Decimal) (int, error) {
+	return generateWallets(ctx, r.db, r.addresses, count, balance, r.queryTimeout)
+}
+
+// CreateWallets создает count кошельков со случайными адресами и заданным балансом
+// многострочными INSERT (см. generateWallets).
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - count: Количество кошельков.
+//   - balance: Начальный баланс каждого кошелька.
+//
+// Возвращает:
+//   - Количество созданных кошельков.
+//   - Ошибку, если создать все кошельки не удалось.
+func (r *SQLiteRepository) CreateWallets(ctx context.Context, count int, balance decimal.Decimal) (int, error) {
+	return generateWallets(ctx, r.db, r.addresses, count, balance, r.queryTimeout)
+}
+
+// HasWallets сообщает, есть ли в базе хотя бы один кошелек, кроме системных счетов.
+// Запрос выполняется в основной базе, а не в реплике: по ответу решается, создавать ли кошельки.
+func (r *PostgresRepository) HasWallets(ctx context.Context) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+	return hasWallets(ctx, r.db)
+}
+
+// HasWallets сообщает, есть ли в базе хотя бы один кошелек, кроме системных счетов.
+func (r *SQLiteRepository) HasWallets(ctx context.Context) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+	return hasWallets(ctx, r.db)
+}
+
+// hasWallets проверяет, что в таблице wallets есть кошельки, кроме системных счетов.
+// Синтаксис совместим с PostgreSQL и SQLite.
+func hasWallets(ctx context.Context, db querier) (bool, error) {
+	condition, args := notSystemAccount(1)
+	var exists bool
+	if err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM wallets WHERE "+condition+")", args...).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check wallets: %w", err)
+	}
+	return exists, nil
+}
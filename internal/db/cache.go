@@ -0,0 +1,467 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"payment-system/internal/metrics"
+	"payment-system/internal/models"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/shopspring/decimal"
+)
+
+// cacheTimeout ограничивает время одного обращения к Redis: медленный кэш
+// не должен задерживать запрос дольше, чем чтение из базы.
+const cacheTimeout = 100 * time.Millisecond
+
+// walletKeyPrefix - префикс ключей Redis, под которыми хранятся кошельки (баланс и метаданные).
+const walletKeyPrefix = "payment:wallet:"
+
+// BalanceCache оборачивает Repository кэшем балансов в Redis.
+// GetBalance и GetWallet сначала ищут кошелек (баланс и метаданные) в кэше и только
+// при промахе читают базу, сохраняя результат на ttl. Успешный Send удаляет из кэша
+// оба кошелька, успешное изменение метаданных - измененный кошелек.
+//
+// Ошибки Redis запрос не прерывают: чтение идет в базу, как если бы кэша не было.
+// Если удалить ключ после перевода не удалось, кэш может отдавать старый баланс
+// не дольше ttl, поэтому ttl стоит держать коротким.
+type BalanceCache struct {
+	repo   Repository
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewBalanceCache создает кэш балансов вокруг репозитория.
+// Недоступность Redis при запуске не является ошибкой: чтение пойдет в базу.
+//
+// Параметры:
+//   - repo: Репозиторий, чтения которого кэшируются.
+//   - addr: Адрес Redis (host:port).
+//   - ttl: Время жизни баланса в кэше.
+//
+// Пример использования:
+//
+//	repo = db.NewBalanceCache(repo, "localhost:6379", 2*time.Second)
+func NewBalanceCache(repo Repository, addr string, ttl time.Duration) *BalanceCache {
+	client := redis.NewClient(&redis.Options{
+		Addr:         addr,
+		DialTimeout:  cacheTimeout,
+		ReadTimeout:  cacheTimeout,
+		WriteTimeout: cacheTimeout,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), cacheTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		slog.Warn("redis unavailable, balances are read from the database", "addr", addr, "error", err)
+	}
+
+	return &BalanceCache{repo: repo, client: client, ttl: ttl}
+}
+
+// walletKey возвращает ключ Redis для кошелька.
+func walletKey(address string) string {
+	return walletKeyPrefix + address
+}
+
+// CreateWallet создает кошелек через обернутый репозиторий.
+func (c *BalanceCache) CreateWallet(ctx context.Context, address string, balance decimal.Decimal, metadata models.WalletMetadata, publicKey string) error {
+	return c.repo.CreateWallet(ctx, address, balance, metadata, publicKey)
+}
+
+// CreateWallets создает кошельки через обернутый репозиторий.
+func (c *BalanceCache) CreateWallets(ctx context.Context, count int, balance decimal.Decimal) (int, error) {
+	return c.repo.CreateWallets(ctx, count, balance)
+}
+
+// HasWallets проверяет наличие кошельков через обернутый репозиторий.
+func (c *BalanceCache) HasWallets(ctx context.Context) (bool, error) {
+	return c.repo.HasWallets(ctx)
+}
+
+// GetBalance возвращает баланс кошелька из кэша, а при промахе или ошибке Redis - из базы
+// (см. GetWallet).
+func (c *BalanceCache) GetBalance(ctx context.Context, address string) (decimal.Decimal, error) {
+	wallet, err := c.GetWallet(ctx, address)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return wallet.Balance, nil
+}
+
+// GetWallet возвращает кошелек из кэша, а при промахе или ошибке Redis - из базы.
+// Прочитанный из базы кошелек сохраняется в кэше на ttl; отсутствие кошелька не кэшируется.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Кошелек с балансом и метаданными.
+//   - Ошибку репозитория, если кошелек не найден в кэше и чтение из базы не удалось.
+func (c *BalanceCache) GetWallet(ctx context.Context, address string) (models.Wallet, error) {
+	cacheCtx, cancel := context.WithTimeout(ctx, cacheTimeout)
+	defer cancel()
+
+	cached, err := c.client.Get(cacheCtx, walletKey(address)).Bytes()
+	switch {
+	case err == nil:
+		var wallet models.Wallet
+		if jsonErr := json.Unmarshal(cached, &wallet); jsonErr == nil {
+			metrics.BalanceCacheRequests.WithLabelValues("hit").Inc()
+			return wallet, nil
+		}
+		metrics.BalanceCacheRequests.WithLabelValues("error").Inc()
+	case errors.Is(err, redis.Nil):
+		metrics.BalanceCacheRequests.WithLabelValues("miss").Inc()
+	default:
+		metrics.BalanceCacheRequests.WithLabelValues("error").Inc()
+	}
+
+	wallet, err := c.repo.GetWallet(ctx, address)
+	if err != nil {
+		return models.Wallet{}, err
+	}
+
+	if data, err := json.Marshal(wallet); err == nil {
+		cacheCtx, cancel := context.WithTimeout(ctx, cacheTimeout)
+		defer cancel()
+		c.client.Set(cacheCtx, walletKey(address), data, c.ttl)
+	}
+	return wallet, nil
+}
+
+// FindWalletByLabel ищет кошелек по метке в базе, минуя кэш.
+func (c *BalanceCache) FindWalletByLabel(ctx context.Context, label string) (models.Wallet, error) {
+	return c.repo.FindWalletByLabel(ctx, label)
+}
+
+// TopWalletsByBalance возвращает кошельки с наибольшим балансом из базы, минуя кэш.
+func (c *BalanceCache) TopWalletsByBalance(ctx context.Context, limit int) ([]models.Wallet, error) {
+	return c.repo.TopWalletsByBalance(ctx, limit)
+}
+
+// UpdateWalletMetadata изменяет метаданные через обернутый репозиторий
+// и после успешного изменения удаляет кошелек из кэша.
+func (c *BalanceCache) UpdateWalletMetadata(ctx context.Context, address string, patch models.WalletMetadataPatch) (models.Wallet, error) {
+	wallet, err := c.repo.UpdateWalletMetadata(ctx, address, patch)
+	if err != nil {
+		return models.Wallet{}, err
+	}
+	c.invalidate(address)
+	return wallet, nil
+}
+
+// ArchiveWallet архивирует кошелек через обернутый репозиторий
+// и после успешной архивации удаляет кошелек из кэша.
+func (c *BalanceCache) ArchiveWallet(ctx context.Context, address string) (models.Wallet, error) {
+	wallet, err := c.repo.ArchiveWallet(ctx, address)
+	if err != nil {
+		return models.Wallet{}, err
+	}
+	c.invalidate(address)
+	return wallet, nil
+}
+
+// RestoreWallet снимает архивацию через обернутый репозиторий
+// и после успешного восстановления удаляет кошелек из кэша.
+func (c *BalanceCache) RestoreWallet(ctx context.Context, address string) (models.Wallet, error) {
+	wallet, err := c.repo.RestoreWallet(ctx, address)
+	if err != nil {
+		return models.Wallet{}, err
+	}
+	c.invalidate(address)
+	return wallet, nil
+}
+
+// GetNonce возвращает номер последнего подписанного перевода из базы, минуя кэш:
+// номер меняется с каждым подписанным переводом и не входит в кэшируемый кошелек.
+func (c *BalanceCache) GetNonce(ctx context.Context, address string) (int64, error) {
+	return c.repo.GetNonce(ctx, address)
+}
+
+// GetBalanceDetails возвращает баланс с резервом из базы, минуя кэш: резерв меняется
+// с каждым отложенным переводом и не входит в кэшируемый кошелек.
+func (c *BalanceCache) GetBalanceDetails(ctx context.Context, address string) (models.Balance, error) {
+	return c.repo.GetBalanceDetails(ctx, address)
+}
+
+// GetBalances возвращает балансы нескольких кошельков из базы, минуя кэш.
+func (c *BalanceCache) GetBalances(ctx context.Context, addresses []string) (map[string]decimal.Decimal, error) {
+	return c.repo.GetBalances(ctx, addresses)
+}
+
+// Send выполняет перевод через обернутый репозиторий и после успешного перевода
+// удаляет из кэша кошельки отправителя и получателя.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - from: Адрес кошелька отправителя.
+//   - to: Адрес кошелька получателя.
+//   - amount: Сумма перевода.
+//   - memo: Комментарий к переводу (может быть пустым).
+//   - category: Категория перевода (может быть пустой).
+//   - nonce: Номер подписанного перевода; 0 - перевод без подписи.
+//   - isolation: Уровень изоляции транзакции; sql.LevelDefault - уровень по умолчанию.
+//
+// Возвращает:
+//   - Результат перевода от репозитория.
+//   - Ошибку репозитория; ошибки Redis не возвращаются.
+func (c *BalanceCache) Send(ctx context.Context, from, to string, amount decimal.Decimal, memo, category string, nonce int64, isolation sql.IsolationLevel) (SendResult, error) {
+	result, err := c.repo.Send(ctx, from, to, amount, memo, category, nonce, isolation)
+	if err != nil {
+		return SendResult{}, err
+	}
+	c.invalidate(from, to)
+	return result, nil
+}
+
+// SystemTransfer выполняет операцию с системным счетом через обернутый репозиторий и удаляет
+// из кэша балансы кошелька и счета.
+//
+// Возвращает:
+//   - Результат операции от репозитория.
+//   - Ошибку репозитория; ошибки Redis не возвращаются.
+func (c *BalanceCache) SystemTransfer(ctx context.Context, txType, address string, amount decimal.Decimal, memo string) (SendResult, error) {
+	result, err := c.repo.SystemTransfer(ctx, txType, address, amount, memo)
+	if err != nil {
+		return SendResult{}, err
+	}
+	if op, ok := systemOperations[txType]; ok {
+		c.invalidate(address, SystemAccount(op.account))
+	}
+	return result, nil
+}
+
+// WithTx выполняет транзакцию через обернутый репозиторий и после ее фиксации
+// удаляет из кэша кошельки, балансы которых она изменила.
+//
+// Параметры:
+//   - ctx: Контекст транзакции.
+//   - fn: Операции транзакции.
+//
+// Возвращает:
+//   - Ошибку репозитория или fn; ошибки Redis не возвращаются.
+func (c *BalanceCache) WithTx(ctx context.Context, fn func(tx TxRepository) error) error {
+	return c.withTx(ctx, c.repo.WithTx, fn)
+}
+
+// WithSeedLock выполняет транзакцию под блокировкой наполнения через обернутый репозиторий
+// и после фиксации удаляет из кэша измененные кошельки, как WithTx.
+func (c *BalanceCache) WithSeedLock(ctx context.Context, fn func(tx TxRepository) error) error {
+	return c.withTx(ctx, c.repo.WithSeedLock, fn)
+}
+
+// withTx выполняет fn транзакцией run обернутого репозитория (WithTx или WithSeedLock)
+// и удаляет из кэша кошельки, балансы которых она изменила.
+func (c *BalanceCache) withTx(ctx context.Context, run func(context.Context, func(TxRepository) error) error, fn func(tx TxRepository) error) error {
+	var changed []string
+	err := run(ctx, func(tx TxRepository) error {
+		// Повторная попытка транзакции начинает список заново
+		changed = changed[:0]
+		return fn(&cacheTx{TxRepository: tx, changed: &changed})
+	})
+	if err != nil {
+		return err
+	}
+	if len(changed) > 0 {
+		c.invalidate(changed...)
+	}
+	return nil
+}
+
+// cacheTx запоминает кошельки, балансы которых изменила транзакция BalanceCache.WithTx.
+type cacheTx struct {
+	TxRepository
+	changed *[]string
+}
+
+// AddBalance изменяет баланс и запоминает кошелек.
+func (t *cacheTx) AddBalance(address string, delta decimal.Decimal) (decimal.Decimal, error) {
+	balance, err := t.TxRepository.AddBalance(address, delta)
+	if err == nil {
+		*t.changed = append(*t.changed, address)
+	}
+	return balance, err
+}
+
+// RetireWallet архивирует кошелек и запоминает его.
+func (t *cacheTx) RetireWallet(address string) (models.Wallet, error) {
+	wallet, err := t.TxRepository.RetireWallet(address)
+	if err == nil {
+		*t.changed = append(*t.changed, address)
+	}
+	return wallet, err
+}
+
+// Send выполняет перевод и запоминает оба кошелька.
+func (t *cacheTx) Send(from, to string, amount decimal.Decimal, memo, category string, nonce int64) (SendResult, error) {
+	result, err := t.TxRepository.Send(from, to, amount, memo, category, nonce)
+	if err == nil {
+		*t.changed = append(*t.changed, from, to)
+	}
+	return result, err
+}
+
+// invalidate удаляет кошельки из кэша. При ошибке Redis старые значения
+// остаются в кэше до истечения ttl.
+func (c *BalanceCache) invalidate(addresses ...string) {
+	keys := make([]string, len(addresses))
+	for i, address := range addresses {
+		keys[i] = walletKey(address)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cacheTimeout)
+	defer cancel()
+	if err := c.client.Del(ctx, keys...).Err(); err != nil {
+		metrics.BalanceCacheRequests.WithLabelValues("error").Inc()
+		slog.Warn("failed to invalidate cached balances", "keys", len(keys), "ttl", c.ttl, "error", err)
+	}
+}
+
+// ImportTransactions импортирует транзакции через обернутый репозиторий.
+// Импорт не изменяет балансы, поэтому кэш не затрагивается.
+func (c *BalanceCache) ImportTransactions(ctx context.Context, transactions []models.Transaction) (int, error) {
+	return c.repo.ImportTransactions(ctx, transactions)
+}
+
+// GetLastTransactions возвращает последние транзакции через обернутый репозиторий.
+func (c *BalanceCache) GetLastTransactions(ctx context.Context, count int, filter TransactionFilter) ([]models.Transaction, error) {
+	return c.repo.GetLastTransactions(ctx, count, filter)
+}
+
+// CountTransactions возвращает количество транзакций через обернутый репозиторий.
+func (c *BalanceCache) CountTransactions(ctx context.Context, filter TransactionFilter) (int64, error) {
+	return c.repo.CountTransactions(ctx, filter)
+}
+
+// EstimateTransactions возвращает приблизительное количество транзакций через обернутый репозиторий.
+func (c *BalanceCache) EstimateTransactions(ctx context.Context) (int64, error) {
+	return c.repo.EstimateTransactions(ctx)
+}
+
+// PurgeTransactions переносит старые транзакции в архив через обернутый репозиторий.
+// Балансы не меняются, поэтому кэш не сбрасывается.
+func (c *BalanceCache) PurgeTransactions(ctx context.Context, before time.Time, archive bool, limit int) (int, error) {
+	return c.repo.PurgeTransactions(ctx, before, archive, limit)
+}
+
+// GetWalletTransactions возвращает транзакции кошельков через обернутый репозиторий.
+func (c *BalanceCache) GetWalletTransactions(ctx context.Context, addresses []string, count int) (map[string][]models.Transaction, error) {
+	return c.repo.GetWalletTransactions(ctx, addresses, count)
+}
+
+// GetSenderStats возвращает сводку переводов отправителя через обернутый репозиторий.
+func (c *BalanceCache) GetSenderStats(ctx context.Context, address string, since time.Time) (SenderStats, error) {
+	return c.repo.GetSenderStats(ctx, address, since)
+}
+
+// GetCategoryVolumes возвращает объемы переводов по категориям через обернутый репозиторий.
+func (c *BalanceCache) GetCategoryVolumes(ctx context.Context, from, to time.Time) ([]CategoryVolume, error) {
+	return c.repo.GetCategoryVolumes(ctx, from, to)
+}
+
+// RecordRiskEvent сохраняет срабатывание правила через обернутый репозиторий.
+func (c *BalanceCache) RecordRiskEvent(ctx context.Context, event models.RiskEvent) error {
+	return c.repo.RecordRiskEvent(ctx, event)
+}
+
+// GetRiskEvents возвращает последние срабатывания правил через обернутый репозиторий.
+func (c *BalanceCache) GetRiskEvents(ctx context.Context, count int) ([]models.RiskEvent, error) {
+	return c.repo.GetRiskEvents(ctx, count)
+}
+
+// AnonymizeWallet удаляет персональные данные кошелька через обернутый репозиторий
+// и после изменения удаляет кошелек из кэша.
+func (c *BalanceCache) AnonymizeWallet(ctx context.Context, address string, dryRun bool) (models.AnonymizeReport, error) {
+	report, err := c.repo.AnonymizeWallet(ctx, address, dryRun)
+	if err != nil {
+		return models.AnonymizeReport{}, err
+	}
+	if !dryRun {
+		c.invalidate(address)
+	}
+	return report, nil
+}
+
+// RecordAuditEvent сохраняет запись журнала аудита через обернутый репозиторий.
+func (c *BalanceCache) RecordAuditEvent(ctx context.Context, event models.AuditEvent) error {
+	return c.repo.RecordAuditEvent(ctx, event)
+}
+
+// GetAuditEvents возвращает последние записи журнала аудита через обернутый репозиторий.
+func (c *BalanceCache) GetAuditEvents(ctx context.Context, count int) ([]models.AuditEvent, error) {
+	return c.repo.GetAuditEvents(ctx, count)
+}
+
+// ReserveIdempotencyKey занимает ключ идемпотентности через обернутый репозиторий.
+func (c *BalanceCache) ReserveIdempotencyKey(ctx context.Context, key, fingerprint string, expiredBefore time.Time) (IdempotentResponse, bool, error) {
+	return c.repo.ReserveIdempotencyKey(ctx, key, fingerprint, expiredBefore)
+}
+
+// CompleteIdempotencyKey сохраняет ответ по ключу идемпотентности через обернутый репозиторий.
+func (c *BalanceCache) CompleteIdempotencyKey(ctx context.Context, key string, response IdempotentResponse) error {
+	return c.repo.CompleteIdempotencyKey(ctx, key, response)
+}
+
+// ReleaseIdempotencyKey освобождает ключ идемпотентности через обернутый репозиторий.
+func (c *BalanceCache) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	return c.repo.ReleaseIdempotencyKey(ctx, key)
+}
+
+// CreateApproval сохраняет отложенный перевод через обернутый репозиторий.
+func (c *BalanceCache) CreateApproval(ctx context.Context, approval models.Approval) (models.Approval, error) {
+	return c.repo.CreateApproval(ctx, approval)
+}
+
+// GetApproval возвращает отложенный перевод через обернутый репозиторий.
+func (c *BalanceCache) GetApproval(ctx context.Context, id int64) (models.Approval, error) {
+	return c.repo.GetApproval(ctx, id)
+}
+
+// GetApprovals возвращает последние отложенные переводы через обернутый репозиторий.
+func (c *BalanceCache) GetApprovals(ctx context.Context, status string, count int) ([]models.Approval, error) {
+	return c.repo.GetApprovals(ctx, status, count)
+}
+
+// UpdateApprovalStatus меняет статус отложенного перевода через обернутый репозиторий.
+// Балансы не меняются: перевод выполняется отдельным вызовом Send, который и сбрасывает кэш.
+func (c *BalanceCache) UpdateApprovalStatus(ctx context.Context, id int64, from, to, reason string) (models.Approval, error) {
+	return c.repo.UpdateApprovalStatus(ctx, id, from, to, reason)
+}
+
+// ExpireApprovals завершает просроченные отложенные переводы через обернутый репозиторий.
+func (c *BalanceCache) ExpireApprovals(ctx context.Context, before time.Time) (int, error) {
+	return c.repo.ExpireApprovals(ctx, before)
+}
+
+// ClaimNotifications выбирает уведомления для доставки через обернутый репозиторий.
+func (c *BalanceCache) ClaimNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Notification, error) {
+	return c.repo.ClaimNotifications(ctx, now, lease, limit)
+}
+
+// CompleteNotification записывает результат доставки уведомления через обернутый репозиторий.
+func (c *BalanceCache) CompleteNotification(ctx context.Context, id int64, status, lastError string, at time.Time) error {
+	return c.repo.CompleteNotification(ctx, id, status, lastError, at)
+}
+
+// GetNotifications возвращает последние уведомления через обернутый репозиторий.
+func (c *BalanceCache) GetNotifications(ctx context.Context, status string, count int) ([]models.Notification, error) {
+	return c.repo.GetNotifications(ctx, status, count)
+}
+
+// Reconcile проверяет инварианты хранилища через обернутый репозиторий.
+func (c *BalanceCache) Reconcile(ctx context.Context) (ReconcileReport, error) {
+	return c.repo.Reconcile(ctx)
+}
+
+// Ping проверяет доступность базы. Состояние Redis на готовность не влияет:
+// без кэша сервис продолжает работать.
+func (c *BalanceCache) Ping(ctx context.Context) error {
+	return c.repo.Ping(ctx)
+}
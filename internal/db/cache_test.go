@@ -0,0 +1,170 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"payment-system/internal/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	cacheFrom = "1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a"
+	cacheTo   = "2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b"
+)
+
+// newTestCache запускает miniredis и оборачивает им репозиторий в памяти
+// с кошельками cacheFrom и cacheTo по 100.
+func newTestCache(t *testing.T) (*BalanceCache, *MemoryRepository, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	repo := NewMemoryRepository()
+	for _, address := range []string{cacheFrom, cacheTo} {
+		if err := repo.CreateWallet(context.Background(), address, decimal.NewFromInt(100), models.WalletMetadata{}, ""); err != nil {
+			t.Fatalf("CreateWallet(%s): %v", address, err)
+		}
+	}
+	cache := NewBalanceCache(repo, mr.Addr(), time.Minute)
+	t.Cleanup(func() { cache.client.Close() })
+	return cache, repo, mr
+}
+
+// bypassCache меняет баланс в обернутом репозитории так, что кэш об этом не знает.
+func bypassCache(t *testing.T, repo *MemoryRepository, address string, delta int64) {
+	t.Helper()
+	err := repo.WithTx(context.Background(), func(tx TxRepository) error {
+		_, err := tx.AddBalance(address, decimal.NewFromInt(delta))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("AddBalance(%s): %v", address, err)
+	}
+}
+
+func TestBalanceCache(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		// act выполняет операцию после того, как кошельки прочитаны в кэш
+		act  func(t *testing.T, cache *BalanceCache, repo *MemoryRepository, mr *miniredis.Miniredis)
+		want map[string]int64
+	}{
+		{
+			name: "hit is served from redis",
+			act: func(t *testing.T, _ *BalanceCache, repo *MemoryRepository, _ *miniredis.Miniredis) {
+				bypassCache(t, repo, cacheFrom, 5)
+			},
+			want: map[string]int64{cacheFrom: 100, cacheTo: 100},
+		},
+		{
+			name: "expired key is read from the database",
+			act: func(t *testing.T, _ *BalanceCache, repo *MemoryRepository, mr *miniredis.Miniredis) {
+				bypassCache(t, repo, cacheFrom, 5)
+				mr.FastForward(time.Minute)
+			},
+			want: map[string]int64{cacheFrom: 105, cacheTo: 100},
+		},
+		{
+			name: "send invalidates both wallets",
+			act: func(t *testing.T, cache *BalanceCache, _ *MemoryRepository, _ *miniredis.Miniredis) {
+				if _, err := cache.Send(ctx, cacheFrom, cacheTo, decimal.NewFromInt(30), "", "", 0, sql.LevelDefault); err != nil {
+					t.Fatalf("Send: %v", err)
+				}
+			},
+			want: map[string]int64{cacheFrom: 70, cacheTo: 130},
+		},
+		{
+			name: "transaction invalidates changed wallets",
+			act: func(t *testing.T, cache *BalanceCache, _ *MemoryRepository, _ *miniredis.Miniredis) {
+				err := cache.WithTx(ctx, func(tx TxRepository) error {
+					_, err := tx.AddBalance(cacheTo, decimal.NewFromInt(-40))
+					return err
+				})
+				if err != nil {
+					t.Fatalf("WithTx: %v", err)
+				}
+			},
+			want: map[string]int64{cacheFrom: 100, cacheTo: 60},
+		},
+		{
+			name: "failed send keeps the cache",
+			act: func(t *testing.T, cache *BalanceCache, repo *MemoryRepository, _ *miniredis.Miniredis) {
+				bypassCache(t, repo, cacheFrom, 5)
+				if _, err := cache.Send(ctx, cacheFrom, cacheTo, decimal.NewFromInt(1000), "", "", 0, sql.LevelDefault); err == nil {
+					t.Fatal("Send over the balance: want error")
+				}
+			},
+			want: map[string]int64{cacheFrom: 100, cacheTo: 100},
+		},
+		{
+			name: "redis outage falls back to the database",
+			act: func(t *testing.T, _ *BalanceCache, repo *MemoryRepository, mr *miniredis.Miniredis) {
+				bypassCache(t, repo, cacheFrom, 5)
+				mr.Close()
+			},
+			want: map[string]int64{cacheFrom: 105, cacheTo: 100},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache, repo, mr := newTestCache(t)
+
+			for _, address := range []string{cacheFrom, cacheTo} {
+				if _, err := cache.GetBalance(ctx, address); err != nil {
+					t.Fatalf("GetBalance(%s): %v", address, err)
+				}
+				if !mr.Exists(walletKey(address)) {
+					t.Fatalf("miss did not populate %s", walletKey(address))
+				}
+			}
+
+			tt.act(t, cache, repo, mr)
+
+			for address, want := range tt.want {
+				got, err := cache.GetBalance(ctx, address)
+				if err != nil {
+					t.Fatalf("GetBalance(%s): %v", address, err)
+				}
+				if !got.Equal(decimal.NewFromInt(want)) {
+					t.Errorf("GetBalance(%s) = %s, want %d", address, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBalanceCacheMissingWallet(t *testing.T) {
+	cache, _, mr := newTestCache(t)
+
+	const unknown = "3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c"
+	if _, err := cache.GetBalance(context.Background(), unknown); err == nil {
+		t.Fatal("GetBalance of unknown wallet: want error")
+	}
+	if mr.Exists(walletKey(unknown)) {
+		t.Error("missing wallet must not be cached")
+	}
+}
+
+func BenchmarkBalanceCacheGetBalance(b *testing.B) {
+	mr := miniredis.RunT(b)
+	repo := NewMemoryRepository()
+	if err := repo.CreateWallet(context.Background(), cacheFrom, decimal.NewFromInt(100), models.WalletMetadata{}, ""); err != nil {
+		b.Fatalf("CreateWallet: %v", err)
+	}
+	cache := NewBalanceCache(repo, mr.Addr(), time.Minute)
+	b.Cleanup(func() { cache.client.Close() })
+
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := cache.GetBalance(ctx, cacheFrom); err != nil {
+			b.Fatalf("GetBalance: %v", err)
+		}
+	}
+}
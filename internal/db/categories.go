@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// categoryDayLayout - формат дня, в котором запрос categoryVolumesQuery возвращает день перевода.
+const categoryDayLayout = "2006-01-02"
+
+// CategoryVolume - объем переводов одной категории за один день (UTC).
+type CategoryVolume struct {
+	Day      time.Time       // Начало дня в UTC
+	Category string          // Категория; пустая строка - переводы без категории
+	Count    int64           // Количество переводов
+	Volume   decimal.Decimal // Сумма переводов
+}
+
+// categoryVolumesQuery выбирает количество и сумму переводов по дням и категориям
+// за период [$1, $2), включая перенесенные в архив, в порядке дня и категории.
+// Синтаксис совместим с PostgreSQL и SQLite; day - выражение дня транзакции в UTC в формате
+// categoryDayLayout, sum - агрегатная функция точной суммы конкретной базы (pgSum или sqliteSum).
+func categoryVolumesQuery(day, sum string) string {
+	return "SELECT " + day + ", COALESCE(category, ''), COUNT(*), " + sum + "(amount) FROM " +
+		TransactionFilter{IncludeArchived: true}.source() +
+		" WHERE timestamp >= $1 AND timestamp < $2 GROUP BY 1, 2 ORDER BY 1, 2"
+}
+
+// getCategoryVolumes выполняет categoryVolumesQuery. Границы периода передаются в виде,
+// сравнимом со столбцом времени конкретной базы (см. timeArg).
+func getCategoryVolumes(ctx context.Context, db *sql.DB, day, sum string, from, to interface{}) ([]CategoryVolume, error) {
+	rows, err := db.QueryContext(ctx, categoryVolumesQuery(day, sum), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query category volumes: %w", err)
+	}
+	defer rows.Close()
+
+	var volumes []CategoryVolume
+	for rows.Next() {
+		var v CategoryVolume
+		var dayStr string
+		if err := rows.Scan(&dayStr, &v.Category, &v.Count, &v.Volume); err != nil {
+			return nil, fmt.Errorf("failed to scan category volume: %w", err)
+		}
+		if v.Day, err = time.Parse(categoryDayLayout, dayStr); err != nil {
+			return nil, fmt.Errorf("failed to scan category volume: %w", err)
+		}
+		volumes = append(volumes, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return volumes, nil
+}
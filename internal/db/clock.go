@@ -0,0 +1,61 @@
+package db
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock - источник времени, которым репозиторий отмечает записанные транзакции.
+// Время транзакции передается в INSERT явно, а не берется из значения по умолчанию
+// столбца timestamp, поэтому в тестах его можно зафиксировать через SetClock.
+type Clock interface {
+	// Now возвращает текущее время.
+	Now() time.Time
+}
+
+// SystemClock - часы реального времени; используются репозиториями по умолчанию.
+type SystemClock struct{}
+
+// Now возвращает time.Now().
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// FixedClock - часы, которые показывают заданное время, пока его не изменят через Set
+// или Advance. Предназначены для тестов; безопасны для параллельного использования.
+//
+// Пример использования:
+//
+//	clock := db.NewFixedClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+//	repo.SetClock(clock)
+//	clock.Advance(time.Hour)
+type FixedClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFixedClock создает часы, показывающие время t.
+func NewFixedClock(t time.Time) *FixedClock {
+	return &FixedClock{now: t}
+}
+
+// Now возвращает установленное время.
+func (c *FixedClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set устанавливает время t.
+func (c *FixedClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+// Advance переводит часы вперед на d.
+func (c *FixedClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,168 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/XSAM/otelsql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Параметры повтора чтения после обрыва соединения (например, при перезапуске PostgreSQL):
+// до readAttempts попыток с задержкой readRetryBaseDelay, удваивающейся с каждой попыткой.
+const (
+	readAttempts       = 3
+	readRetryBaseDelay = 100 * time.Millisecond
+)
+
+// Значения по умолчанию времени жизни подключений пула PostgreSQL.
+const (
+	defaultConnMaxIdleTime = time.Minute
+	defaultConnMaxLifetime = 30 * time.Minute
+)
+
+// Коды SQLSTATE, с которыми сервер закрывает подключения при остановке или еще не принимает их
+// при запуске; класс 08 (ошибки подключения) проверяется по префиксу.
+const (
+	pgAdminShutdown    = "57P01" // сервер остановлен администратором (перезапуск)
+	pgCrashShutdown    = "57P02" // сервер перезапускается после сбоя другого процесса
+	pgCannotConnectNow = "57P03" // сервер запускается или восстанавливается
+	pgConnectionClass  = "08"
+)
+
+// openDB открывает пул подключений драйвера driverName, запросы которого записываются
+// в трассировку OpenTelemetry дочерними спанами операции из контекста (sql.conn.query,
+// sql.conn.exec, sql.conn.begin_tx и т.д.) с текстом запроса. Запросы без родительского спана
+// (миграции при запуске, фоновые задачи) спанов не создают, чтобы не засорять трассировку
+// корневыми спанами.
+//
+// Параметры:
+//   - driverName: Имя драйвера database/sql ("pgx" или "sqlite").
+//   - dsn: Строка подключения.
+//   - system: Атрибут db.system спанов.
+func openDB(driverName, dsn string, system attribute.KeyValue) (*sql.DB, error) {
+	return otelsql.Open(driverName, dsn,
+		otelsql.WithAttributes(system),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			DisableErrSkip:       true,
+			OmitConnResetSession: true,
+			OmitRows:             true,
+			SpanFilter: func(ctx context.Context, _ otelsql.Method, _ string, _ []driver.NamedValue) bool {
+				return trace.SpanContextFromContext(ctx).IsValid()
+			},
+		}),
+	)
+}
+
+// configurePool задает время жизни подключений пула: подключение, простаивающее дольше
+// DB_CONN_MAX_IDLE_TIME (по умолчанию 1m) или существующее дольше DB_CONN_MAX_LIFETIME
+// (по умолчанию 30m), закрывается, поэтому после перезапуска базы в пуле не копятся
+// оборванные подключения. Подключение, простоявшее больше секунды, драйвер pgx
+// перед выдачей из пула проверяет запросом ping и при ошибке заменяет новым.
+// Завершает программу, если значение задано некорректно.
+func configurePool(db *sql.DB) {
+	db.SetConnMaxIdleTime(poolDurationFromEnv("DB_CONN_MAX_IDLE_TIME", defaultConnMaxIdleTime))
+	db.SetConnMaxLifetime(poolDurationFromEnv("DB_CONN_MAX_LIFETIME", defaultConnMaxLifetime))
+}
+
+// poolDurationFromEnv возвращает положительную длительность из переменной key
+// или defaultValue, если она не задана.
+func poolDurationFromEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Fatalf("Invalid %s %q", key, value)
+	}
+	return duration
+}
+
+// isConnectionError сообщает, что запрос не выполнен из-за обрыва или недоступности
+// подключения, а не из-за самого запроса: такое чтение можно повторить на другом подключении.
+// Истечение DB_QUERY_TIMEOUT обрывом не считается: повтор только удлинил бы ожидание.
+func isConnectionError(err error) bool {
+	if err == nil || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return strings.HasPrefix(pgErr.Code, pgConnectionClass) ||
+			pgErr.Code == pgAdminShutdown || pgErr.Code == pgCrashShutdown || pgErr.Code == pgCannotConnectNow
+	}
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.As(err, &connectErr) ||
+		errors.As(err, &netErr) ||
+		pgconn.SafeToRetry(err)
+}
+
+// retryConnection выполняет идемпотентный запрос query и повторяет его, пока он завершается
+// обрывом подключения (isConnectionError), не более readAttempts раз. Каждая попытка
+// получает подключение из пула заново, поэтому оборванное подключение не используется повторно.
+// Запись так повторять нельзя: после обрыва неизвестно, выполнена ли она.
+//
+// Возвращает:
+//   - Ошибку последней попытки.
+func retryConnection(query func() error) error {
+	var err error
+	for attempt := 0; attempt < readAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(readRetryBaseDelay << (attempt - 1))
+		}
+		if err = query(); !isConnectionError(err) {
+			return err
+		}
+		slog.Warn("database connection lost, retrying read", "attempt", attempt+1, "attempts", readAttempts, "error", err)
+	}
+	return err
+}
+
+// commitOutcome выясняет, зафиксирована ли транзакция txid, фиксация которой завершилась
+// обрывом подключения: сервер мог успеть зафиксировать ее до обрыва. Состояние читается
+// функцией txid_status (PostgreSQL 10+) на новом подключении.
+//
+// Параметры:
+//   - txid: Номер транзакции (txid_current()), полученный до фиксации.
+//   - commitErr: Ошибка фиксации.
+//
+// Возвращает:
+//   - nil, если транзакция зафиксирована; commitErr, если она отменена;
+//     ErrOutcomeUnknown, если состояние узнать не удалось.
+func (r *PostgresRepository) commitOutcome(txid int64, commitErr error) error {
+	var status sql.NullString
+	// Контекст вызывающего может быть уже отменен, а исход фиксации нужно узнать в любом случае
+	err := retryConnection(func() error {
+		ctx, cancel := withQueryTimeout(context.Background(), r.queryTimeout)
+		defer cancel()
+		return r.db.QueryRowContext(ctx, "SELECT txid_status($1)", txid).Scan(&status)
+	})
+	switch {
+	case err == nil && status.String == "committed":
+		slog.Warn("connection lost during commit, transaction was committed", "txid", txid, "error", commitErr)
+		return nil
+	case err == nil && status.String == "aborted":
+		return fmt.Errorf("connection lost during commit, transaction was rolled back: %w", commitErr)
+	default:
+		slog.Error("connection lost during commit, transaction outcome unknown", "txid", txid, "status", status.String,
+			"error", commitErr, "status_error", err)
+		return fmt.Errorf("%w: %v", ErrOutcomeUnknown, commitErr)
+	}
+}
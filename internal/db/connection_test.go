@@ -0,0 +1,185 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestIsConnectionError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"bad conn", driver.ErrBadConn, true},
+		{"eof", io.EOF, true},
+		{"unexpected eof", fmt.Errorf("failed to get wallet: %w", io.ErrUnexpectedEOF), true},
+		{"closed", net.ErrClosed, true},
+		{"refused", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+		{"admin shutdown", &pgconn.PgError{Code: pgAdminShutdown}, true},
+		{"crash shutdown", &pgconn.PgError{Code: pgCrashShutdown}, true},
+		{"starting up", &pgconn.PgError{Code: pgCannotConnectNow}, true},
+		{"connection failure", &pgconn.PgError{Code: "08006"}, true},
+		{"deadlock", &pgconn.PgError{Code: pgDeadlockDetected}, false},
+		{"undefined table", &pgconn.PgError{Code: "42P01"}, false},
+		{"query timeout", fmt.Errorf("query: %w", context.DeadlineExceeded), false},
+		{"canceled", context.Canceled, false},
+		{"not found", ErrWalletNotFound, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isConnectionError(tt.err); got != tt.want {
+				t.Errorf("isConnectionError(%v) = %t, want %t", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPoolDurationFromEnv(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", defaultConnMaxIdleTime},
+		{"30s", 30 * time.Second},
+		{"2m", 2 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("DB_CONN_MAX_IDLE_TIME", tt.value)
+			if got := poolDurationFromEnv("DB_CONN_MAX_IDLE_TIME", defaultConnMaxIdleTime); got != tt.want {
+				t.Errorf("poolDurationFromEnv() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPostgresReadRetry имитирует перезапуск сервера драйвером: первые failures попыток чтения
+// завершаются ошибкой. Обрыв подключения повторяется на новом подключении не более
+// readAttempts раз, другие ошибки возвращаются сразу.
+func TestPostgresReadRetry(t *testing.T) {
+	const balanceQuery = "SELECT balance FROM wallets WHERE address = $1"
+
+	tests := []struct {
+		name         string
+		failures     int
+		err          error
+		wantAttempts int
+		wantErr      bool
+	}{
+		{"no failure", 0, nil, 1, false},
+		{"admin shutdown once", 1, &pgconn.PgError{Code: pgAdminShutdown}, 2, false},
+		{"eof twice", 2, io.EOF, 3, false},
+		{"still down", readAttempts, &pgconn.PgError{Code: pgCannotConnectNow}, readAttempts, true},
+		{"not a connection error", 1, &pgconn.PgError{Code: "42P01"}, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &scriptedDriver{
+				query: func(query string, call int) ([]string, [][]driver.Value, error) {
+					if query != balanceQuery {
+						return nil, nil, fmt.Errorf("unexpected query %q", query)
+					}
+					if call <= tt.failures {
+						return nil, nil, tt.err
+					}
+					return []string{"balance"}, [][]driver.Value{{"10.5"}}, nil
+				},
+			}
+			r := newScriptedPostgres(t, d, 3)
+
+			balance, err := r.GetBalance(context.Background(), "w0")
+			if tt.wantErr {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("GetBalance = %v, want %v", err, tt.err)
+				}
+			} else if err != nil || balance.String() != "10.5" {
+				t.Fatalf("GetBalance = %s, %v, want 10.5", balance, err)
+			}
+			if got := d.callCount(balanceQuery); got != tt.wantAttempts {
+				t.Errorf("read ran %d times, want %d", got, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+// TestPostgresCommitOutcome имитирует обрыв подключения во время фиксации перевода. Перевод
+// не повторяется: исход выясняется запросом txid_status, и если он неизвестен, возвращается
+// ErrOutcomeUnknown. Ошибка с кодом SQLSTATE означает отмену транзакции и исход не проверяет.
+func TestPostgresCommitOutcome(t *testing.T) {
+	const (
+		update      = "UPDATE wallets SET balance = balance - $1 WHERE address = $2"
+		statusQuery = "SELECT txid_status($1)"
+	)
+
+	tests := []struct {
+		name          string
+		commitErr     error
+		status        driver.Value // Ответ txid_status; nil - NULL
+		statusErr     error        // Ошибка каждого запроса txid_status
+		wantErr       error        // nil - перевод выполнен
+		wantStatusRun int
+	}{
+		{"committed", driver.ErrBadConn, "committed", nil, nil, 1},
+		{"aborted", io.ErrUnexpectedEOF, "aborted", nil, io.ErrUnexpectedEOF, 1},
+		{"in progress", io.EOF, "in progress", nil, ErrOutcomeUnknown, 1},
+		{"status forgotten", io.EOF, nil, nil, ErrOutcomeUnknown, 1},
+		{"server still down", driver.ErrBadConn, nil, &pgconn.PgError{Code: pgCannotConnectNow}, ErrOutcomeUnknown, readAttempts},
+		{"rejected by server", &pgconn.PgError{Code: "23514"}, nil, nil, &pgconn.PgError{}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &scriptedDriver{
+				query: func(query string, call int) ([]string, [][]driver.Value, error) {
+					if query != statusQuery {
+						return answerTxid(query, call)
+					}
+					if tt.statusErr != nil {
+						return nil, nil, tt.statusErr
+					}
+					return []string{"txid_status"}, [][]driver.Value{{tt.status}}, nil
+				},
+				commit: func(int) error { return tt.commitErr },
+			}
+			r := newScriptedPostgres(t, d, 3)
+
+			err := r.withTx(context.Background(), r.sendIsolation, func(tx *pgTx) error {
+				_, err := tx.tx.ExecContext(tx.ctx, update, "1", "w0")
+				return err
+			})
+			switch want := tt.wantErr.(type) {
+			case nil:
+				if err != nil {
+					t.Fatalf("withTx: %v", err)
+				}
+			case *pgconn.PgError:
+				if !errors.As(err, &want) || errors.Is(err, ErrOutcomeUnknown) {
+					t.Fatalf("withTx = %v, want the database error", err)
+				}
+			default:
+				if !errors.Is(err, want) {
+					t.Fatalf("withTx = %v, want %v", err, want)
+				}
+				if want != ErrOutcomeUnknown && errors.Is(err, ErrOutcomeUnknown) {
+					t.Fatalf("withTx = %v, want a known outcome", err)
+				}
+			}
+
+			if got := d.callCount(update); got != 1 {
+				t.Errorf("transfer ran %d times, want 1", got)
+			}
+			if got := d.callCount(statusQuery); got != tt.wantStatusRun {
+				t.Errorf("txid_status ran %d times, want %d", got, tt.wantStatusRun)
+			}
+		})
+	}
+}
@@ -0,0 +1,472 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"payment-system/internal/errs"
+	"payment-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// defaultQueryTimeout - ограничение времени запроса к базе данных по умолчанию.
+const defaultQueryTimeout = 5 * time.Second
+
+// Ошибки, общие для всех реализаций репозитория.
+// Проверяются вызывающим кодом через errors.Is. Ошибки предметной области определены
+// в пакете errs; здесь они доступны под прежними именами и совпадают с errs через errors.Is.
+var (
+	ErrInsufficientFunds   = errs.ErrInsufficientFunds
+	ErrWalletNotFound      = errs.ErrWalletNotFound
+	ErrWalletExists        = errs.ErrWalletExists
+	ErrLabelExists         = errs.ErrLabelExists
+	ErrBelowMinimumBalance = errs.ErrBelowMinimumBalance
+	ErrBalanceOverflow     = errs.ErrBalanceOverflow
+	ErrSelfTransfer        = errs.ErrSelfTransfer
+	ErrApprovalNotFound    = errs.ErrApprovalNotFound
+	ErrApprovalStatus      = errs.ErrApprovalStatus
+	ErrWalletArchived      = errs.ErrWalletArchived
+	ErrWalletNotEmpty      = errs.ErrWalletNotEmpty
+	ErrWalletHasHolds      = errs.ErrWalletHasHolds
+
+	// ErrNonceMismatch - см. errs.ErrNonceMismatch. Конкретная ошибка - *NonceError с ожидаемым номером.
+	ErrNonceMismatch = errs.ErrNonceMismatch
+)
+
+// Ошибки хранилища, не относящиеся к предметной области.
+var (
+	// ErrContention возвращается, если перевод не удалось выполнить из-за конкуренции
+	// с другими транзакциями (сериализация, взаимоблокировка) даже после повторов.
+	// Запрос безопасно повторить позже.
+	ErrContention = errors.New("transfer contention, try again later")
+
+	// ErrAddressCollision возвращается, если за maxAddressAttempts попыток
+	// не удалось сгенерировать свободный адрес кошелька.
+	ErrAddressCollision = errors.New("failed to generate a unique wallet address")
+
+	// ErrPurgeLocked возвращается, если очистку истории транзакций уже выполняет
+	// другой экземпляр сервиса.
+	ErrPurgeLocked = errors.New("transaction purge is running on another instance")
+
+	// ErrOutcomeUnknown возвращается, если соединение с базой оборвалось во время фиксации
+	// транзакции и узнать, зафиксирована ли она, не удалось. Перевод мог быть выполнен,
+	// поэтому повторять его без ключа идемпотентности или подписи нельзя.
+	ErrOutcomeUnknown = errors.New("transaction outcome unknown")
+)
+
+// NonceError - ошибка несовпадения номера подписанного перевода.
+// Совпадает с ErrNonceMismatch через errors.Is и сообщает номер, который ожидается,
+// чтобы клиент мог подписать перевод заново.
+type NonceError struct {
+	Expected int64
+}
+
+// Error возвращает текст ошибки.
+func (e *NonceError) Error() string {
+	return fmt.Sprintf("%s: expected nonce %d", ErrNonceMismatch, e.Expected)
+}
+
+// Is позволяет сравнивать ошибку с ErrNonceMismatch через errors.Is.
+func (e *NonceError) Is(target error) bool {
+	return target == ErrNonceMismatch
+}
+
+// checkNonce проверяет номер перевода относительно последнего использованного номера кошелька.
+// Номер 0 означает перевод без подписи: он не проверяется и не расходует номер.
+func checkNonce(current, nonce int64) error {
+	if nonce != 0 && nonce != current+1 {
+		return &NonceError{Expected: current + 1}
+	}
+	return nil
+}
+
+// amountPrecision - общее количество значащих цифр суммы (NUMERIC(38, 8) в PostgreSQL).
+const amountPrecision = 38
+
+// balanceLimit - наименьший баланс, который уже не помещается в NUMERIC(38, 8): 10^30.
+var balanceLimit = decimal.New(1, amountPrecision-models.AmountScale)
+
+// insufficientFunds сообщает, превышает ли сумма перевода баланс.
+// Перевод суммы, равной балансу, разрешен.
+func insufficientFunds(balance, amount decimal.Decimal) bool {
+	return balance.LessThan(amount)
+}
+
+// belowMinimum сообщает, опустится ли баланс после списания ниже неснижаемого остатка.
+func belowMinimum(balance, amount, minBalance decimal.Decimal) bool {
+	return balance.Sub(amount).LessThan(minBalance)
+}
+
+// CheckAvailable проверяет, можно ли списать сумму с доступного остатка кошелька, так же,
+// как это делает Send: с учетом неснижаемого остатка.
+//
+// Параметры:
+//   - available: Доступный остаток кошелька (models.Balance.Available).
+//   - amount: Сумма списания.
+//   - minBalance: Неснижаемый остаток кошелька.
+//
+// Возвращает:
+//   - ErrInsufficientFunds или ErrBelowMinimumBalance, если списать сумму нельзя.
+//
+// Пример использования:
+//
+//	err := db.CheckAvailable(balance.Available, decimal.RequireFromString("10.5"), db.MinWalletBalance())
+func CheckAvailable(available, amount, minBalance decimal.Decimal) error {
+	if insufficientFunds(available, amount) {
+		return ErrInsufficientFunds
+	}
+	if belowMinimum(available, amount, minBalance) {
+		return ErrBelowMinimumBalance
+	}
+	return nil
+}
+
+// AmountOverflows сообщает, что сумма сама по себе не помещается в NUMERIC(38, 8), то есть
+// не меньше 10^30: такую сумму нельзя ни зачислить, ни записать в историю.
+//
+// Пример использования:
+//
+//	if db.AmountOverflows(amount) { ... }
+func AmountOverflows(amount decimal.Decimal) bool {
+	return amount.Abs().GreaterThanOrEqual(balanceLimit)
+}
+
+// balanceOverflows сообщает, выходит ли сумма баланса и зачисления за пределы balanceLimit.
+// PostgreSQL в этом случае сам отклоняет запись с кодом 22003.
+func balanceOverflows(balance, amount decimal.Decimal) bool {
+	return balance.Add(amount).GreaterThanOrEqual(balanceLimit)
+}
+
+// maxAddressAttempts - сколько раз генерируется новый адрес, если сгенерированный уже занят.
+const maxAddressAttempts = 5
+
+// queryTimeoutFromEnv возвращает ограничение времени запроса из переменной DB_QUERY_TIMEOUT
+// (формат time.ParseDuration, например "2s"), или defaultQueryTimeout, если она не задана.
+func queryTimeoutFromEnv() time.Duration {
+	value := os.Getenv("DB_QUERY_TIMEOUT")
+	if value == "" {
+		return defaultQueryTimeout
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		log.Fatalf("Invalid DB_QUERY_TIMEOUT %q", value)
+	}
+	return timeout
+}
+
+// Допустимая длина адреса кошелька в байтах (ADDRESS_BYTES). В hex адрес вдвое длиннее.
+const (
+	defaultAddressBytes = 32 // 64 шестнадцатеричных символа
+	minAddressBytes     = 16 // Короче - заметно растет вероятность совпадения случайных адресов
+	maxAddressBytes     = 64
+)
+
+// AddressBytes возвращает длину адреса кошелька в байтах из переменной ADDRESS_BYTES
+// или defaultAddressBytes, если она не задана. Завершает программу, если значение
+// не является целым числом от minAddressBytes до maxAddressBytes.
+//
+// Длина читается при каждом вызове, поэтому GenerateAddress и проверка адресов в API
+// всегда используют одно и то же значение.
+func AddressBytes() int {
+	value := os.Getenv("ADDRESS_BYTES")
+	if value == "" {
+		return defaultAddressBytes
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < minAddressBytes || n > maxAddressBytes {
+		log.Fatalf("Invalid ADDRESS_BYTES %q: expected an integer from %d to %d", value, minAddressBytes, maxAddressBytes)
+	}
+	return n
+}
+
+// defaultSendAttempts - количество попыток перевода при конфликтах сериализации по умолчанию.
+const defaultSendAttempts = 3
+
+// sendAttemptsFromEnv возвращает количество попыток перевода из переменной DB_SEND_ATTEMPTS
+// или defaultSendAttempts, если она не задана.
+func sendAttemptsFromEnv() int {
+	value := os.Getenv("DB_SEND_ATTEMPTS")
+	if value == "" {
+		return defaultSendAttempts
+	}
+	attempts, err := strconv.Atoi(value)
+	if err != nil || attempts < 1 {
+		log.Fatalf("Invalid DB_SEND_ATTEMPTS %q", value)
+	}
+	return attempts
+}
+
+// MinWalletBalance возвращает неснижаемый остаток кошелька из переменной MIN_WALLET_BALANCE
+// или 0, если она не задана. Перевод, после которого баланс отправителя стал бы меньше
+// этого значения, отклоняется с ErrBelowMinimumBalance.
+// Завершает программу, если значение задано некорректно.
+func MinWalletBalance() decimal.Decimal {
+	value := os.Getenv("MIN_WALLET_BALANCE")
+	if value == "" {
+		return decimal.Zero
+	}
+	minBalance, err := decimal.NewFromString(value)
+	if err != nil || minBalance.IsNegative() {
+		log.Fatalf("Invalid MIN_WALLET_BALANCE %q", value)
+	}
+	return minBalance
+}
+
+// withQueryTimeout создает контекст для одного запроса или транзакции на основе контекста
+// вызывающего (отмена и трассировка запроса передаются драйверу). Запрос, не уложившийся
+// в timeout, отменяется драйвером и возвращает context.DeadlineExceeded.
+func withQueryTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, timeout)
+}
+
+// CreateWalletWithRandomAddress создает кошелек со случайным адресом DefaultAddressGenerator.
+// Если адрес уже занят, генерирует новый и повторяет попытку (не более maxAddressAttempts раз).
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - repo: Репозиторий, в котором создается кошелек.
+//   - balance: Начальный баланс.
+//   - metadata: Метка и теги кошелька (нулевое значение - без метаданных).
+//   - publicKey: Открытый ключ ed25519 в шестнадцатеричном виде (пустая строка - без ключа).
+//
+// Возвращает:
+//   - Адрес созданного кошелька.
+//   - ErrAddressCollision, если свободный адрес получить не удалось, ErrLabelExists,
+//     если метка занята, или другую ошибку репозитория.
+//
+// Пример использования:
+//
+//	address, err := db.CreateWalletWithRandomAddress(ctx, repo, decimal.NewFromInt(100), models.WalletMetadata{}, "")
+func CreateWalletWithRandomAddress(ctx context.Context, repo Repository, balance decimal.Decimal, metadata models.WalletMetadata, publicKey string) (string, error) {
+	return DefaultAddressGenerator.CreateWallet(ctx, repo, balance, metadata, publicKey)
+}
+
+// SendResult - результат выполненного перевода.
+type SendResult struct {
+	TransactionID   int             // Идентификатор записанной транзакции
+	SenderBalance   decimal.Decimal // Баланс отправителя после перевода
+	ReceiverBalance decimal.Decimal // Баланс получателя после перевода
+	CreatedAt       time.Time       // Время транзакции (UTC)
+}
+
+// Repository описывает контракт хранилища кошельков и транзакций.
+// Все реализации (PostgreSQL, SQLite, in-memory) обязаны вести себя одинаково:
+// одинаково упорядочивать транзакции, возвращать одни и те же ошибки
+// и сохранять общую сумму балансов при переводах.
+type Repository interface {
+	// CreateWallet создает кошелек с указанным адресом, начальным балансом, метаданными
+	// и открытым ключом (пустая строка - без ключа).
+	// Возвращает ErrWalletExists, если адрес занят, и ErrLabelExists, если занята метка.
+	CreateWallet(ctx context.Context, address string, balance decimal.Decimal, metadata models.WalletMetadata, publicKey string) error
+
+	// CreateWallets создает count кошельков со случайными адресами и заданным балансом.
+	// Возвращает количество созданных кошельков, равное count, если ошибки нет.
+	CreateWallets(ctx context.Context, count int, balance decimal.Decimal) (int, error)
+
+	// HasWallets сообщает, есть ли в хранилище хотя бы один кошелек, в том числе архивный,
+	// не считая системных счетов (см. SystemAccount).
+	HasWallets(ctx context.Context) (bool, error)
+
+	// GetBalance возвращает баланс кошелька по его адресу.
+	GetBalance(ctx context.Context, address string) (decimal.Decimal, error)
+
+	// GetBalanceDetails возвращает баланс кошелька, зарезервированные средства и доступный
+	// остаток, прочитанные одним запросом, или ErrWalletNotFound.
+	GetBalanceDetails(ctx context.Context, address string) (models.Balance, error)
+
+	// GetBalances возвращает балансы нескольких кошельков одним запросом.
+	// Несуществующие кошельки в результат не попадают.
+	GetBalances(ctx context.Context, addresses []string) (map[string]decimal.Decimal, error)
+
+	// GetWallet возвращает кошелек с балансом и метаданными по адресу.
+	GetWallet(ctx context.Context, address string) (models.Wallet, error)
+
+	// FindWalletByLabel возвращает кошелек по метке или ErrWalletNotFound.
+	FindWalletByLabel(ctx context.Context, label string) (models.Wallet, error)
+
+	// TopWalletsByBalance возвращает до limit активных кошельков с наибольшим балансом
+	// по убыванию баланса, а при равном балансе - по адресу. Системные счета не возвращаются.
+	TopWalletsByBalance(ctx context.Context, limit int) ([]models.Wallet, error)
+
+	// UpdateWalletMetadata изменяет метаданные кошелька и возвращает кошелек после изменения.
+	// Возвращает ErrWalletNotFound, если кошелька нет, и ErrLabelExists, если метка занята.
+	UpdateWalletMetadata(ctx context.Context, address string, patch models.WalletMetadataPatch) (models.Wallet, error)
+
+	// GetNonce возвращает номер последнего подписанного перевода с кошелька (0, если их не было);
+	// следующий перевод должен быть подписан с номером на единицу больше.
+	// Возвращает ErrWalletNotFound, если кошелька нет.
+	GetNonce(ctx context.Context, address string) (int64, error)
+
+	// ArchiveWallet архивирует кошелек с нулевым балансом и возвращает его; повторная архивация
+	// ничего не меняет. Возвращает ErrWalletNotFound, ErrWalletNotEmpty, если баланс не нулевой,
+	// и ErrWalletHasHolds, если кошелек участвует в отложенных переводах.
+	ArchiveWallet(ctx context.Context, address string) (models.Wallet, error)
+
+	// RestoreWallet снимает архивацию кошелька и возвращает его или ErrWalletNotFound.
+	RestoreWallet(ctx context.Context, address string) (models.Wallet, error)
+
+	// Send выполняет перевод средств с одного кошелька на другой.
+	// memo сохраняется вместе с транзакцией; пустая строка означает отсутствие memo.
+	// Ненулевой nonce должен быть на единицу больше номера последнего подписанного перевода
+	// отправителя (иначе *NonceError); он проверяется и сохраняется в той же транзакции,
+	// что и списание, поэтому из двух переводов с одним номером выполняется ровно один.
+	// Сумма проверяется по доступному остатку: зарезервированные средства (см. GetBalanceDetails)
+	// не списываются.
+	// Возвращает идентификатор и время записанной транзакции и баланс отправителя после списания.
+	// Баланс проверяется и изменяется точно, без ошибок округления.
+	// isolation задает уровень изоляции транзакции перевода в PostgreSQL; sql.LevelDefault -
+	// уровень по умолчанию (DB_SEND_ISOLATION). Остальные реализации его не используют.
+	Send(ctx context.Context, from, to string, amount decimal.Decimal, memo, category string, nonce int64, isolation sql.IsolationLevel) (SendResult, error)
+
+	// SystemTransfer выполняет операцию типа txType между кошельком address и системным счетом,
+	// который определяется типом: выпуск (models.TransactionMint) и изъятие (TransactionBurn) -
+	// счет эмиссии, комиссия (TransactionFee) - счет комиссий, удержание и его возврат
+	// (TransactionEscrowHold, TransactionEscrowRelease) - счет депонирования. Списание
+	// проверяется, как в Send; баланс счета эмиссии не меняется. Возвращает ErrTransactionType
+	// для другого типа и ErrSystemAccount, если address - системный счет.
+	SystemTransfer(ctx context.Context, txType, address string, amount decimal.Decimal, memo string) (SendResult, error)
+
+	// WithTx выполняет fn в одной транзакции: изменения, сделанные через TxRepository,
+	// фиксируются, если fn вернула nil, и откатываются, если ошибку. Позволяет выполнить
+	// несколько операций атомарно (например, создать кошелек и пополнить его переводом).
+	// fn может быть вызвана повторно после конфликта транзакций в PostgreSQL.
+	WithTx(ctx context.Context, fn func(tx TxRepository) error) error
+
+	// WithSeedLock выполняет fn в транзакции, как WithTx, под блокировкой наполнения
+	// демонстрационными кошельками: экземпляры сервиса с общей базой выполняют такие транзакции
+	// по очереди, и каждая видит кошельки, созданные предыдущей. Делает атомарными проверку
+	// TxRepository.HasWallets и создание кошельков (см. Service.SeedWallets).
+	WithSeedLock(ctx context.Context, fn func(tx TxRepository) error) error
+
+	// ImportTransactions сохраняет исторические транзакции с их исходным временем
+	// в одной транзакции базы, не изменяя балансы. Транзакции с уже известным
+	// ExternalID пропускаются. Возвращает количество добавленных записей.
+	ImportTransactions(ctx context.Context, transactions []models.Transaction) (int, error)
+
+	// GetLastTransactions возвращает последние N транзакций, начиная с самой новой.
+	// Время транзакций возвращается в UTC.
+	// Фильтр ограничивает выборку (нулевое значение - все транзакции).
+	GetLastTransactions(ctx context.Context, count int, filter TransactionFilter) ([]models.Transaction, error)
+
+	// CountTransactions возвращает количество транзакций, удовлетворяющих фильтру
+	// (тому же, что у GetLastTransactions), например для индикатора прогресса при листании.
+	CountTransactions(ctx context.Context, filter TransactionFilter) (int64, error)
+
+	// GetWalletTransactions возвращает последние count транзакций каждого из кошельков addresses
+	// (отправленных и полученных, без архива) одним запросом, в порядке GetLastTransactions.
+	// Кошельков без транзакций в результате нет.
+	GetWalletTransactions(ctx context.Context, addresses []string, count int) (map[string][]models.Transaction, error)
+
+	// EstimateTransactions возвращает приблизительное количество транзакций (без архива)
+	// по статистике базы, не просматривая таблицу. Реализации без такой статистики
+	// возвращают точное количество.
+	EstimateTransactions(ctx context.Context) (int64, error)
+
+	// PurgeTransactions переносит в архив (при archive = false - удаляет) не более limit
+	// самых старых транзакций, выполненных раньше момента before, и возвращает их количество.
+	// Балансы не меняются. Возвращает ErrPurgeLocked, если очистку уже выполняет
+	// другой экземпляр сервиса.
+	PurgeTransactions(ctx context.Context, before time.Time, archive bool, limit int) (int, error)
+
+	// GetSenderStats возвращает количество и сумму переводов с кошелька, выполненных начиная
+	// с момента since. Импортированные транзакции не учитываются.
+	GetSenderStats(ctx context.Context, address string, since time.Time) (SenderStats, error)
+
+	// GetCategoryVolumes возвращает количество и сумму переводов по дням (UTC) и категориям
+	// за период [from, to), включая импортированные и перенесенные в архив, в порядке дня
+	// и категории. Дни и категории без переводов в результат не попадают.
+	GetCategoryVolumes(ctx context.Context, from, to time.Time) ([]CategoryVolume, error)
+
+	// RecordRiskEvent сохраняет срабатывание правила проверки переводов (ID и CreatedAt
+	// назначаются хранилищем).
+	RecordRiskEvent(ctx context.Context, event models.RiskEvent) error
+
+	// GetRiskEvents возвращает последние count срабатываний правил, начиная с самого нового.
+	GetRiskEvents(ctx context.Context, count int) ([]models.RiskEvent, error)
+
+	// AnonymizeWallet удаляет метку, теги и адрес для уведомлений кошелька, комментарии всех
+	// его транзакций и отложенных переводов и адреса и метки в уведомлениях о его переводах
+	// пачками по anonymizeBatchSize записей; суммы и балансы не меняются. Уведомления кошельку,
+	// еще не доставленные, после этого не отправляются.
+	// Повторный вызов ничего не меняет и сообщает нули. При dryRun записи только подсчитываются.
+	// Возвращает ErrWalletNotFound, если кошелька нет.
+	AnonymizeWallet(ctx context.Context, address string, dryRun bool) (models.AnonymizeReport, error)
+
+	// RecordAuditEvent сохраняет запись журнала аудита (ID и CreatedAt назначаются хранилищем).
+	RecordAuditEvent(ctx context.Context, event models.AuditEvent) error
+
+	// GetAuditEvents возвращает последние count записей журнала аудита, начиная с самой новой.
+	GetAuditEvents(ctx context.Context, count int) ([]models.AuditEvent, error)
+
+	// ReserveIdempotencyKey занимает ключ идемпотентности для выполняющегося запроса, предварительно
+	// удалив ключи, занятые раньше expiredBefore. Если ключ уже занят, возвращает сохраненный
+	// ответ (Status = 0, пока запрос выполняется) и false. Из параллельных вызовов с одним
+	// ключом true получает только один.
+	ReserveIdempotencyKey(ctx context.Context, key, fingerprint string, expiredBefore time.Time) (IdempotentResponse, bool, error)
+
+	// CompleteIdempotencyKey сохраняет ответ на запрос с занятым ключом.
+	CompleteIdempotencyKey(ctx context.Context, key string, response IdempotentResponse) error
+
+	// ReleaseIdempotencyKey освобождает ключ, ответ для которого еще не сохранен,
+	// чтобы запрос можно было повторить.
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+
+	// CreateApproval сохраняет перевод, ожидающий подтверждения, со статусом
+	// models.ApprovalAwaitingReview и возвращает его с назначенными ID и CreatedAt.
+	CreateApproval(ctx context.Context, approval models.Approval) (models.Approval, error)
+
+	// GetApproval возвращает отложенный перевод по ID или ErrApprovalNotFound.
+	GetApproval(ctx context.Context, id int64) (models.Approval, error)
+
+	// GetApprovals возвращает последние count отложенных переводов со статусом status
+	// (пустая строка - с любым статусом), начиная с самого нового.
+	GetApprovals(ctx context.Context, status string, count int) ([]models.Approval, error)
+
+	// UpdateApprovalStatus переводит отложенный перевод из статуса from в статус to
+	// с причиной reason и возвращает его. Переход атомарен: из параллельных запросов
+	// выполняется один, остальные получают ErrApprovalStatus.
+	UpdateApprovalStatus(ctx context.Context, id int64, from, to, reason string) (models.Approval, error)
+
+	// ExpireApprovals переводит в статус models.ApprovalExpired переводы, ожидающие
+	// решения с момента раньше before, и возвращает их количество.
+	ExpireApprovals(ctx context.Context, before time.Time) (int, error)
+
+	// ClaimNotifications выбирает до limit уведомлений со статусом models.NotificationPending,
+	// следующая попытка которых назначена не позже now, увеличивает их Attempts и откладывает
+	// следующую попытку до now + lease. Уведомление, доставка которого прервалась (например,
+	// экземпляр остановлен), выбирается снова после lease; параллельные экземпляры выбирают
+	// разные уведомления. Уведомления возвращаются в порядке ID.
+	ClaimNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Notification, error)
+
+	// CompleteNotification записывает результат попытки доставки уведомления id: статус
+	// models.NotificationSent или models.NotificationFailed завершает доставку в момент at,
+	// models.NotificationPending назначает следующую попытку на at. lastError - ошибка попытки.
+	CompleteNotification(ctx context.Context, id int64, status, lastError string, at time.Time) error
+
+	// GetNotifications возвращает последние count уведомлений со статусом status (пустая
+	// строка - с любым), начиная с самого нового.
+	GetNotifications(ctx context.Context, status string, count int) ([]models.Notification, error)
+
+	// Reconcile проверяет инварианты хранилища (неотрицательные балансы, резерв не больше
+	// баланса, пустые архивные кошельки, существующие участники переводов, балансы по истории
+	// и сумму балансов с учетом выпуска и изъятия) и возвращает сводку; нарушения сообщает
+	// ReconcileReport.Drift.
+	Reconcile(ctx context.Context) (ReconcileReport, error)
+
+	// Ping проверяет доступность хранилища.
+	Ping(ctx context.Context) error
+}
+
+var (
+	_ Repository = (*PostgresRepository)(nil)
+	_ Repository = (*MemoryRepository)(nil)
+	_ Repository = (*SQLiteRepository)(nil)
+	_ Repository = (*CircuitBreaker)(nil)
+)
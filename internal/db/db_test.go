@@ -0,0 +1,129 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// maxTestBalance - наибольший баланс, который помещается в NUMERIC(38, 8).
+const maxTestBalance = "999999999999999999999999999999.99999999"
+
+func TestBalanceOverflows(t *testing.T) {
+	tests := []struct {
+		name            string
+		balance, amount string
+		want            bool
+	}{
+		{"zero", "0", "0", false},
+		{"reaches max", "999999999999999999999999999998.99999999", "1", false},
+		{"max plus nothing", maxTestBalance, "0", false},
+		{"one unit past max", maxTestBalance, "0.00000001", true},
+		{"limit itself", "0", "1000000000000000000000000000000", true},
+		{"debit at max", maxTestBalance, "-1", false},
+		{"large both", "600000000000000000000000000000", "400000000000000000000000000000", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := balanceOverflows(decimal.RequireFromString(tt.balance), decimal.RequireFromString(tt.amount))
+			if got != tt.want {
+				t.Errorf("balanceOverflows(%s, %s) = %t, want %t", tt.balance, tt.amount, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAmountOverflows(t *testing.T) {
+	tests := []struct {
+		amount string
+		want   bool
+	}{
+		{"1", false},
+		{maxTestBalance, false},
+		{"1000000000000000000000000000000", true},
+		{"-1000000000000000000000000000000", true},
+		{"1e40", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.amount, func(t *testing.T) {
+			if got := AmountOverflows(decimal.RequireFromString(tt.amount)); got != tt.want {
+				t.Errorf("AmountOverflows(%s) = %t, want %t", tt.amount, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCheckAvailable проверяет границы проверки списания: сумма, равная доступному остатку
+// или оставляющая ровно неснижаемый остаток, разрешена, а на единицу точности больше - нет.
+func TestCheckAvailable(t *testing.T) {
+	tests := []struct {
+		name                          string
+		available, amount, minBalance string
+		want                          error
+	}{
+		{"whole balance without minimum", "100", "100", "0", nil},
+		{"more than balance", "100", "100.00000001", "0", ErrInsufficientFunds},
+		{"down to minimum", "100", "90", "10", nil},
+		{"one unit below minimum", "100", "90.00000001", "10", ErrBelowMinimumBalance},
+		{"whole balance with minimum", "100", "100", "10", ErrBelowMinimumBalance},
+		{"more than balance with minimum", "100", "200", "10", ErrInsufficientFunds},
+		{"already at minimum", "10", "0.00000001", "10", ErrBelowMinimumBalance},
+		{"fractional minimum", "1", "0.5", "0.5", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckAvailable(decimal.RequireFromString(tt.available), decimal.RequireFromString(tt.amount),
+				decimal.RequireFromString(tt.minBalance))
+			if err != tt.want {
+				t.Errorf("CheckAvailable(%s, %s, %s) = %v, want %v", tt.available, tt.amount, tt.minBalance, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMinWalletBalance(t *testing.T) {
+	tests := []struct {
+		value, want string
+	}{
+		{"", "0"},
+		{"0", "0"},
+		{"10", "10"},
+		{"0.5", "0.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("MIN_WALLET_BALANCE", tt.value)
+			if got := MinWalletBalance(); !got.Equal(decimal.RequireFromString(tt.want)) {
+				t.Errorf("MinWalletBalance() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddressBytes(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"", defaultAddressBytes},
+		{"20", 20},
+		{"32", 32},
+		{"16", minAddressBytes},
+		{"64", maxAddressBytes},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("ADDRESS_BYTES", tt.value)
+			if got := AddressBytes(); got != tt.want {
+				t.Errorf("AddressBytes() = %d, want %d", got, tt.want)
+			}
+			address, err := GenerateAddress()
+			if err != nil {
+				t.Fatalf("GenerateAddress: %v", err)
+			}
+			if len(address) != 2*tt.want {
+				t.Errorf("GenerateAddress() = %q, want %d hex characters", address, 2*tt.want)
+			}
+		})
+	}
+}
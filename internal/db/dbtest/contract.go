@@ -0,0 +1,2003 @@
+// Package dbtest содержит общий контрактный набор проверок для реализаций db.Repository.
+// Набор запускается против каждой реализации (PostgreSQL, SQLite, in-memory), чтобы их поведение
+// не расходилось: порядок транзакций, типы ошибок и сохранение общей суммы балансов.
+//
+// Пример использования в тестах реализации:
+//
+//	func TestMemoryRepository(t *testing.T) {
+//		dbtest.RunContract(t, func(t *testing.T) db.Repository {
+//			return db.NewMemoryRepository()
+//		})
+//	}
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"payment-system/internal/db"
+	"payment-system/internal/errs"
+	"payment-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// Factory создает чистый экземпляр репозитория для одной проверки.
+type Factory func(t *testing.T) db.Repository
+
+// RunContract запускает все проверки контракта против репозитория, созданного factory.
+// Каждая проверка получает собственный экземпляр репозитория и собственные кошельки.
+func RunContract(t *testing.T, factory Factory) {
+	t.Run("UnknownWalletBalance", func(t *testing.T) { testUnknownWalletBalance(t, factory(t)) })
+	t.Run("DuplicateWallet", func(t *testing.T) { testDuplicateWallet(t, factory(t)) })
+	t.Run("GetBalances", func(t *testing.T) { testGetBalances(t, factory(t)) })
+	t.Run("CreateWallets", func(t *testing.T) { testCreateWallets(t, factory(t)) })
+	t.Run("AddressLength", func(t *testing.T) {
+		for _, bytes := range []string{"20", "48"} {
+			t.Run(bytes, func(t *testing.T) {
+				t.Setenv("ADDRESS_BYTES", bytes)
+				testAddressLength(t, factory(t))
+			})
+		}
+	})
+	t.Run("WalletMetadata", func(t *testing.T) { testWalletMetadata(t, factory(t)) })
+	t.Run("PublicKey", func(t *testing.T) { testPublicKey(t, factory(t)) })
+	t.Run("ArchiveWallet", func(t *testing.T) { testArchiveWallet(t, factory(t)) })
+	t.Run("TopWalletsByBalance", func(t *testing.T) { testTopWalletsByBalance(t, factory(t)) })
+	t.Run("AnonymizeWallet", func(t *testing.T) { testAnonymizeWallet(t, factory(t)) })
+	t.Run("SendNonce", func(t *testing.T) { testSendNonce(t, factory(t)) })
+	t.Run("ConcurrentNonce", func(t *testing.T) { testConcurrentNonce(t, factory(t)) })
+	t.Run("Send", func(t *testing.T) { testSend(t, factory(t)) })
+	t.Run("Clock", func(t *testing.T) { testClock(t, factory(t)) })
+	t.Run("SendExactBalance", func(t *testing.T) { testSendExactBalance(t, factory(t)) })
+	t.Run("InsufficientFunds", func(t *testing.T) { testInsufficientFunds(t, factory(t)) })
+	t.Run("MinimumBalance", func(t *testing.T) {
+		t.Setenv("MIN_WALLET_BALANCE", "10")
+		testMinimumBalance(t, factory(t))
+	})
+	t.Run("AmountScale", func(t *testing.T) { testAmountScale(t, factory(t)) })
+	t.Run("BalanceOverflow", func(t *testing.T) { testBalanceOverflow(t, factory(t)) })
+	t.Run("UnknownParties", func(t *testing.T) { testUnknownParties(t, factory(t)) })
+	t.Run("LastTransactionsOrder", func(t *testing.T) { testLastTransactionsOrder(t, factory(t)) })
+	t.Run("FilterByAmount", func(t *testing.T) { testFilterByAmount(t, factory(t)) })
+	t.Run("FilterByCategory", func(t *testing.T) { testFilterByCategory(t, factory(t)) })
+	t.Run("CategoryVolumes", func(t *testing.T) { testCategoryVolumes(t, factory(t)) })
+	t.Run("CursorPaging", func(t *testing.T) { testCursorPaging(t, factory(t)) })
+	t.Run("WalletTransactions", func(t *testing.T) { testWalletTransactions(t, factory(t)) })
+	t.Run("ImportTransactions", func(t *testing.T) { testImportTransactions(t, factory(t)) })
+	t.Run("PurgeTransactions", func(t *testing.T) { testPurgeTransactions(t, factory(t)) })
+	t.Run("SenderStats", func(t *testing.T) { testSenderStats(t, factory(t)) })
+	t.Run("RiskEvents", func(t *testing.T) { testRiskEvents(t, factory(t)) })
+	t.Run("AuditEvents", func(t *testing.T) { testAuditEvents(t, factory(t)) })
+	t.Run("IdempotencyKeys", func(t *testing.T) { testIdempotencyKeys(t, factory(t)) })
+	t.Run("Approvals", func(t *testing.T) { testApprovals(t, factory(t)) })
+	t.Run("ReservedBalance", func(t *testing.T) { testReservedBalance(t, factory(t)) })
+	t.Run("ExpireApprovals", func(t *testing.T) { testExpireApprovals(t, factory(t)) })
+	t.Run("ConcurrentApprovalDecision", func(t *testing.T) { testConcurrentApprovalDecision(t, factory(t)) })
+	t.Run("ConcurrentConservation", func(t *testing.T) { testConcurrentConservation(t, factory(t)) })
+	t.Run("Reconcile", func(t *testing.T) { testReconcile(t, factory(t)) })
+	t.Run("SystemTransfer", func(t *testing.T) { testSystemTransfer(t, factory(t)) })
+	t.Run("SystemAccountSend", func(t *testing.T) { testSystemAccountSend(t, factory(t)) })
+	t.Run("WithTx", func(t *testing.T) { testWithTx(t, factory(t)) })
+	t.Run("ApprovalInTx", func(t *testing.T) { testApprovalInTx(t, factory(t)) })
+	t.Run("ConcurrentSeedLock", func(t *testing.T) { testConcurrentSeedLock(t, factory(t)) })
+	t.Run("Notifications", func(t *testing.T) { testNotifications(t, factory(t)) })
+}
+
+// dec разбирает сумму, записанную в проверке строкой; строка задает сумму точно.
+func dec(amount string) decimal.Decimal {
+	return decimal.RequireFromString(amount)
+}
+
+// newWallet создает кошелек со случайным адресом и указанным балансом.
+func newWallet(t *testing.T, repo db.Repository, balance decimal.Decimal) string {
+	t.Helper()
+	ctx := context.Background()
+	address, err := db.GenerateAddress()
+	if err != nil {
+		t.Fatalf("GenerateAddress: %v", err)
+	}
+	if err := repo.CreateWallet(ctx, address, balance, models.WalletMetadata{}, ""); err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+	return address
+}
+
+// balanceOf возвращает баланс кошелька, прерывая проверку при ошибке.
+func balanceOf(t *testing.T, repo db.Repository, address string) decimal.Decimal {
+	t.Helper()
+	ctx := context.Background()
+	balance, err := repo.GetBalance(ctx, address)
+	if err != nil {
+		t.Fatalf("GetBalance(%s): %v", address, err)
+	}
+	return balance
+}
+
+func testUnknownWalletBalance(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	address, _ := db.GenerateAddress()
+	if _, err := repo.GetBalance(ctx, address); !errors.Is(err, db.ErrWalletNotFound) {
+		t.Fatalf("GetBalance of unknown wallet: got %v, want ErrWalletNotFound", err)
+	}
+	// Обработчик баланса читает кошелек целиком: отсутствие кошелька должно отличаться от сбоя базы
+	if _, err := repo.GetWallet(ctx, address); !errors.Is(err, db.ErrWalletNotFound) {
+		t.Fatalf("GetWallet of unknown wallet: got %v, want ErrWalletNotFound", err)
+	}
+	if _, err := repo.GetBalanceDetails(ctx, address); !errors.Is(err, db.ErrWalletNotFound) {
+		t.Fatalf("GetBalanceDetails of unknown wallet: got %v, want ErrWalletNotFound", err)
+	}
+}
+
+func testDuplicateWallet(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	address := newWallet(t, repo, dec("10"))
+	if err := repo.CreateWallet(ctx, address, dec("20"), models.WalletMetadata{}, ""); !errors.Is(err, db.ErrWalletExists) {
+		t.Fatalf("CreateWallet duplicate: got %v, want ErrWalletExists", err)
+	}
+	if got := balanceOf(t, repo, address); !got.Equal(dec("10")) {
+		t.Fatalf("balance after duplicate create: got %v, want 10", got)
+	}
+}
+
+func testGetBalances(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	a := newWallet(t, repo, dec("10"))
+	b := newWallet(t, repo, dec("20"))
+	unknown, _ := db.GenerateAddress()
+
+	balances, err := repo.GetBalances(ctx, []string{a, b, unknown})
+	if err != nil {
+		t.Fatalf("GetBalances: %v", err)
+	}
+	if len(balances) != 2 || !balances[a].Equal(dec("10")) || !balances[b].Equal(dec("20")) {
+		t.Fatalf("GetBalances: got %v, want %s=10 and %s=20", balances, a, b)
+	}
+	if _, ok := balances[unknown]; ok {
+		t.Fatalf("GetBalances returned a balance for unknown wallet %s", unknown)
+	}
+}
+
+func testCreateWallets(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	const count = 2500 // больше одной пачки многострочного INSERT
+	created, err := repo.CreateWallets(ctx, count, dec("5"))
+	if err != nil {
+		t.Fatalf("CreateWallets: %v", err)
+	}
+	if created != count {
+		t.Fatalf("CreateWallets created %d wallets, want %d", created, count)
+	}
+	if exists, err := repo.HasWallets(ctx); err != nil || !exists {
+		t.Fatalf("HasWallets after CreateWallets: got %v, %v, want true", exists, err)
+	}
+}
+
+// testAddressLength проверяет, что адреса настроенной длины (ADDRESS_BYTES) создаются
+// и участвуют в переводах так же, как адреса по умолчанию.
+func testAddressLength(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	from := newWallet(t, repo, dec("10"))
+	to := newWallet(t, repo, dec("0"))
+	if want := 2 * db.AddressBytes(); len(from) != want {
+		t.Fatalf("address length: got %d, want %d", len(from), want)
+	}
+
+	if _, err := repo.Send(ctx, from, to, dec("4"), "", "", 0, sql.LevelDefault); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got := balanceOf(t, repo, to); !got.Equal(dec("4")) {
+		t.Fatalf("receiver balance: got %v, want 4", got)
+	}
+}
+
+func testWalletMetadata(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	labeled, err := db.GenerateAddress()
+	if err != nil {
+		t.Fatalf("GenerateAddress: %v", err)
+	}
+	metadata := models.WalletMetadata{Label: "ops-float", Tags: map[string]string{"currency": "EUR"}}
+	if err := repo.CreateWallet(ctx, labeled, dec("50"), metadata, ""); err != nil {
+		t.Fatalf("CreateWallet with metadata: %v", err)
+	}
+
+	wallet, err := repo.FindWalletByLabel(ctx, "ops-float")
+	if err != nil {
+		t.Fatalf("FindWalletByLabel: %v", err)
+	}
+	if wallet.Address != labeled || !wallet.Balance.Equal(dec("50")) || wallet.Tags["currency"] != "EUR" {
+		t.Fatalf("FindWalletByLabel: got %+v", wallet)
+	}
+	if _, err := repo.FindWalletByLabel(ctx, "missing"); !errors.Is(err, db.ErrWalletNotFound) {
+		t.Fatalf("FindWalletByLabel missing: got %v, want ErrWalletNotFound", err)
+	}
+
+	// Метка уникальна и при создании, и при изменении
+	other := newWallet(t, repo, dec("0"))
+	duplicate, _ := db.GenerateAddress()
+	if err := repo.CreateWallet(ctx, duplicate, dec("0"), models.WalletMetadata{Label: "ops-float"}, ""); !errors.Is(err, db.ErrLabelExists) {
+		t.Fatalf("CreateWallet with taken label: got %v, want ErrLabelExists", err)
+	}
+	label := "ops-float"
+	if _, err := repo.UpdateWalletMetadata(ctx, other, models.WalletMetadataPatch{Label: &label}); !errors.Is(err, db.ErrLabelExists) {
+		t.Fatalf("UpdateWalletMetadata with taken label: got %v, want ErrLabelExists", err)
+	}
+
+	// Изменение только тегов сохраняет метку; повторная установка своей метки допустима
+	wallet, err = repo.UpdateWalletMetadata(ctx, labeled, models.WalletMetadataPatch{Label: &label, Tags: map[string]string{"desk": "treasury"}})
+	if err != nil {
+		t.Fatalf("UpdateWalletMetadata: %v", err)
+	}
+	if wallet.Label != "ops-float" || len(wallet.Tags) != 1 || wallet.Tags["desk"] != "treasury" {
+		t.Fatalf("UpdateWalletMetadata: got %+v", wallet)
+	}
+
+	// Пустая метка удаляет метку и освобождает ее для другого кошелька
+	empty := ""
+	if _, err := repo.UpdateWalletMetadata(ctx, labeled, models.WalletMetadataPatch{Label: &empty}); err != nil {
+		t.Fatalf("UpdateWalletMetadata clear label: %v", err)
+	}
+	if wallet, err = repo.UpdateWalletMetadata(ctx, other, models.WalletMetadataPatch{Label: &label}); err != nil || wallet.Label != "ops-float" {
+		t.Fatalf("UpdateWalletMetadata reuse label: got %+v, %v", wallet, err)
+	}
+	if wallet, err = repo.GetWallet(ctx, labeled); err != nil || wallet.Label != "" || wallet.Tags["desk"] != "treasury" {
+		t.Fatalf("GetWallet after clearing label: got %+v, %v", wallet, err)
+	}
+
+	unknown, _ := db.GenerateAddress()
+	if _, err := repo.UpdateWalletMetadata(ctx, unknown, models.WalletMetadataPatch{Label: &empty}); !errors.Is(err, db.ErrWalletNotFound) {
+		t.Fatalf("UpdateWalletMetadata unknown wallet: got %v, want ErrWalletNotFound", err)
+	}
+}
+
+func testPublicKey(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	const publicKey = "3b6a27bcceb6a42d62a3a8d02a6f0d73653215771de243a63ac048a18b59da29"
+	address, err := db.GenerateAddress()
+	if err != nil {
+		t.Fatalf("GenerateAddress: %v", err)
+	}
+	if err := repo.CreateWallet(ctx, address, dec("10"), models.WalletMetadata{}, publicKey); err != nil {
+		t.Fatalf("CreateWallet with public key: %v", err)
+	}
+	if wallet, err := repo.GetWallet(ctx, address); err != nil || wallet.PublicKey != publicKey {
+		t.Fatalf("GetWallet public key: got %+v, %v", wallet, err)
+	}
+	if wallet, err := repo.GetWallet(ctx, newWallet(t, repo, dec("0"))); err != nil || wallet.PublicKey != "" {
+		t.Fatalf("GetWallet without public key: got %+v, %v", wallet, err)
+	}
+}
+
+func testArchiveWallet(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	funded := newWallet(t, repo, dec("10"))
+	empty := newWallet(t, repo, dec("0"))
+
+	if _, err := repo.ArchiveWallet(ctx, funded); !errors.Is(err, db.ErrWalletNotEmpty) {
+		t.Fatalf("ArchiveWallet with balance: got %v, want ErrWalletNotEmpty", err)
+	}
+	unknown, _ := db.GenerateAddress()
+	if _, err := repo.ArchiveWallet(ctx, unknown); !errors.Is(err, db.ErrWalletNotFound) {
+		t.Fatalf("ArchiveWallet unknown wallet: got %v, want ErrWalletNotFound", err)
+	}
+
+	// Входящий перевод, ожидающий подтверждения, тоже удерживает кошелек
+	held, err := repo.CreateApproval(ctx, models.Approval{From: funded, To: empty, Amount: dec("5")})
+	if err != nil {
+		t.Fatalf("CreateApproval: %v", err)
+	}
+	if _, err := repo.ArchiveWallet(ctx, empty); !errors.Is(err, db.ErrWalletHasHolds) {
+		t.Fatalf("ArchiveWallet with hold: got %v, want ErrWalletHasHolds", err)
+	}
+	if _, err := repo.UpdateApprovalStatus(ctx, held.ID, models.ApprovalAwaitingReview, models.ApprovalRejected, "test"); err != nil {
+		t.Fatalf("UpdateApprovalStatus: %v", err)
+	}
+
+	start := time.Now().Add(-time.Minute)
+	archived, err := repo.ArchiveWallet(ctx, empty)
+	if err != nil || archived.ArchivedAt == nil || archived.ArchivedAt.Before(start) {
+		t.Fatalf("ArchiveWallet: got %+v, %v", archived, err)
+	}
+	if again, err := repo.ArchiveWallet(ctx, empty); err != nil || !again.ArchivedAt.Equal(*archived.ArchivedAt) {
+		t.Fatalf("repeated ArchiveWallet: got %+v, %v", again, err)
+	}
+	if wallet, err := repo.GetWallet(ctx, empty); err != nil || wallet.ArchivedAt == nil {
+		t.Fatalf("GetWallet of archived wallet: got %+v, %v", wallet, err)
+	}
+
+	// Архивный кошелек не участвует в переводах ни как получатель, ни как отправитель
+	if _, err := repo.Send(ctx, funded, empty, dec("1"), "", "", 0, sql.LevelDefault); !errors.Is(err, db.ErrWalletArchived) {
+		t.Fatalf("Send to archived wallet: got %v, want ErrWalletArchived", err)
+	}
+	if _, err := repo.Send(ctx, empty, funded, dec("0"), "", "", 0, sql.LevelDefault); !errors.Is(err, db.ErrWalletArchived) {
+		t.Fatalf("Send from archived wallet: got %v, want ErrWalletArchived", err)
+	}
+	if got := balanceOf(t, repo, funded); !got.Equal(dec("10")) {
+		t.Fatalf("sender balance after rejected send: got %v, want 10", got)
+	}
+
+	restored, err := repo.RestoreWallet(ctx, empty)
+	if err != nil || restored.ArchivedAt != nil {
+		t.Fatalf("RestoreWallet: got %+v, %v", restored, err)
+	}
+	if _, err := repo.Send(ctx, funded, empty, dec("1"), "", "", 0, sql.LevelDefault); err != nil {
+		t.Fatalf("Send to restored wallet: %v", err)
+	}
+	if _, err := repo.RestoreWallet(ctx, unknown); !errors.Is(err, db.ErrWalletNotFound) {
+		t.Fatalf("RestoreWallet unknown wallet: got %v, want ErrWalletNotFound", err)
+	}
+}
+
+// testTopWalletsByBalance проверяет порядок по убыванию баланса как числа (SQLite хранит
+// балансы текстом, и "9" не должно оказаться больше "10"), порядок равных балансов по адресу,
+// ограничение limit и исключение архивных кошельков.
+func testTopWalletsByBalance(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	small := newWallet(t, repo, dec("9"))
+	large := newWallet(t, repo, dec("100.5"))
+	tieA := newWallet(t, repo, dec("10"))
+	tieB := newWallet(t, repo, dec("10.00"))
+	if tieB < tieA {
+		tieA, tieB = tieB, tieA
+	}
+	archived := newWallet(t, repo, dec("0"))
+	if _, err := repo.ArchiveWallet(ctx, archived); err != nil {
+		t.Fatalf("ArchiveWallet: %v", err)
+	}
+
+	wallets, err := repo.TopWalletsByBalance(ctx, 10)
+	if err != nil {
+		t.Fatalf("TopWalletsByBalance: %v", err)
+	}
+	want := []string{large, tieA, tieB, small}
+	if len(wallets) != len(want) {
+		t.Fatalf("TopWalletsByBalance: got %d wallets, want %d", len(wallets), len(want))
+	}
+	for i, wallet := range wallets {
+		if wallet.Address != want[i] {
+			t.Fatalf("TopWalletsByBalance[%d]: got %s (balance %s), want %s", i, wallet.Address, wallet.Balance, want[i])
+		}
+	}
+	if !wallets[0].Balance.Equal(dec("100.5")) {
+		t.Fatalf("TopWalletsByBalance[0] balance: got %s, want 100.5", wallets[0].Balance)
+	}
+
+	if wallets, err := repo.TopWalletsByBalance(ctx, 2); err != nil || len(wallets) != 2 || wallets[1].Address != tieA {
+		t.Fatalf("TopWalletsByBalance(2): got %+v, %v", wallets, err)
+	}
+}
+
+func testAnonymizeWallet(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	subject, err := db.GenerateAddress()
+	if err != nil {
+		t.Fatalf("GenerateAddress: %v", err)
+	}
+	metadata := models.WalletMetadata{Label: "anon-" + subject[:8], Tags: map[string]string{"owner": "Alice"}}
+	if err := repo.CreateWallet(ctx, subject, dec("10"), metadata, ""); err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+	other := newWallet(t, repo, dec("10"))
+	third := newWallet(t, repo, dec("0"))
+	// Уведомления получают subject (его адрес очищается) и other (в них очищается метка subject)
+	for _, address := range []string{subject, other} {
+		email := "owner-" + address[:8] + "@example.com"
+		if _, err := repo.UpdateWalletMetadata(ctx, address, models.WalletMetadataPatch{NotifyEmail: &email}); err != nil {
+			t.Fatalf("UpdateWalletMetadata: %v", err)
+		}
+	}
+
+	for _, send := range []struct{ from, to, memo string }{
+		{subject, other, "rent for Alice"},
+		{other, subject, "refund"},
+		{other, subject, ""},
+		{other, third, "unrelated"},
+	} {
+		if _, err := repo.Send(ctx, send.from, send.to, dec("1"), send.memo, "", 0, sql.LevelDefault); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	if _, err := repo.CreateApproval(ctx, models.Approval{From: other, To: subject, Amount: dec("2"), Memo: "bonus"}); err != nil {
+		t.Fatalf("CreateApproval: %v", err)
+	}
+
+	want := models.AnonymizeReport{DryRun: true, Wallets: 1, Transactions: 2, Approvals: 1, Notifications: 3}
+	if report, err := repo.AnonymizeWallet(ctx, subject, true); err != nil || report != want {
+		t.Fatalf("AnonymizeWallet dry run: got %+v, %v, want %+v", report, err, want)
+	}
+	if wallet, err := repo.GetWallet(ctx, subject); err != nil || wallet.Label == "" {
+		t.Fatalf("dry run must not change the wallet: got %+v, %v", wallet, err)
+	}
+
+	want.DryRun = false
+	if report, err := repo.AnonymizeWallet(ctx, subject, false); err != nil || report != want {
+		t.Fatalf("AnonymizeWallet: got %+v, %v, want %+v", report, err, want)
+	}
+	// Повторная анонимизация ничего не меняет
+	if report, err := repo.AnonymizeWallet(ctx, subject, false); err != nil || report != (models.AnonymizeReport{}) {
+		t.Fatalf("repeated AnonymizeWallet: got %+v, %v, want zero counts", report, err)
+	}
+
+	wallet, err := repo.GetWallet(ctx, subject)
+	if err != nil || wallet.Label != "" || len(wallet.Tags) != 0 || wallet.NotifyEmail != "" || !wallet.Balance.Equal(dec("11")) {
+		t.Fatalf("GetWallet after anonymization: got %+v, %v", wallet, err)
+	}
+	notifications, err := repo.GetNotifications(ctx, "", 10)
+	if err != nil || len(notifications) != 3 {
+		t.Fatalf("GetNotifications: got %d notifications, %v", len(notifications), err)
+	}
+	for _, n := range notifications {
+		if n.FromLabel != "" || n.Address == subject && n.Email != "" || n.Address == other && n.Email == "" {
+			t.Fatalf("notification after anonymization: %+v", n)
+		}
+	}
+	transactions, err := repo.GetLastTransactions(ctx, 10, db.TransactionFilter{})
+	if err != nil || len(transactions) != 4 {
+		t.Fatalf("GetLastTransactions: got %d transactions, %v", len(transactions), err)
+	}
+	for _, tx := range transactions {
+		involved := tx.From == subject || tx.To == subject
+		if involved && tx.Memo != "" || !involved && tx.Memo != "unrelated" || !tx.Amount.Equal(dec("1")) {
+			t.Fatalf("transaction after anonymization: %+v", tx)
+		}
+	}
+	approvals, err := repo.GetApprovals(ctx, "", 10)
+	if err != nil || len(approvals) != 1 || approvals[0].Memo != "" || !approvals[0].Amount.Equal(dec("2")) {
+		t.Fatalf("GetApprovals after anonymization: got %+v, %v", approvals, err)
+	}
+
+	unknown, _ := db.GenerateAddress()
+	if _, err := repo.AnonymizeWallet(ctx, unknown, true); !errors.Is(err, db.ErrWalletNotFound) {
+		t.Fatalf("AnonymizeWallet unknown wallet: got %v, want ErrWalletNotFound", err)
+	}
+}
+
+// wantNonceError проверяет, что err - *db.NonceError с ожидаемым номером expected.
+func wantNonceError(t *testing.T, err error, expected int64) {
+	t.Helper()
+	var nonceErr *db.NonceError
+	if !errors.As(err, &nonceErr) || !errors.Is(err, db.ErrNonceMismatch) {
+		t.Fatalf("got %v, want NonceError", err)
+	}
+	if nonceErr.Expected != expected {
+		t.Fatalf("expected nonce: got %d, want %d", nonceErr.Expected, expected)
+	}
+}
+
+func testSendNonce(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	from := newWallet(t, repo, dec("100"))
+	to := newWallet(t, repo, dec("0"))
+
+	if nonce, err := repo.GetNonce(ctx, from); err != nil || nonce != 0 {
+		t.Fatalf("GetNonce of new wallet: got %d, %v", nonce, err)
+	}
+
+	// Номер должен быть ровно следующим: повтор и пропуск отклоняются без списания
+	if _, err := repo.Send(ctx, from, to, dec("10"), "", "", 1, sql.LevelDefault); err != nil {
+		t.Fatalf("Send with nonce 1: %v", err)
+	}
+	_, err := repo.Send(ctx, from, to, dec("10"), "", "", 1, sql.LevelDefault)
+	wantNonceError(t, err, 2)
+	_, err = repo.Send(ctx, from, to, dec("10"), "", "", 3, sql.LevelDefault)
+	wantNonceError(t, err, 2)
+	if got := balanceOf(t, repo, from); !got.Equal(dec("90")) {
+		t.Fatalf("sender balance after rejected nonces: got %v, want 90", got)
+	}
+
+	// Перевод без подписи номер не расходует; неудачный перевод тоже
+	if _, err := repo.Send(ctx, from, to, dec("10"), "", "", 0, sql.LevelDefault); err != nil {
+		t.Fatalf("Send without nonce: %v", err)
+	}
+	if _, err := repo.Send(ctx, from, to, dec("1000"), "", "", 2, sql.LevelDefault); !errors.Is(err, db.ErrInsufficientFunds) {
+		t.Fatalf("Send with nonce 2 over balance: got %v, want ErrInsufficientFunds", err)
+	}
+	if nonce, err := repo.GetNonce(ctx, from); err != nil || nonce != 1 {
+		t.Fatalf("GetNonce after failed send: got %d, %v, want 1", nonce, err)
+	}
+	if _, err := repo.Send(ctx, from, to, dec("10"), "", "", 2, sql.LevelDefault); err != nil {
+		t.Fatalf("Send with nonce 2: %v", err)
+	}
+
+	unknown, _ := db.GenerateAddress()
+	if _, err := repo.GetNonce(ctx, unknown); !errors.Is(err, db.ErrWalletNotFound) {
+		t.Fatalf("GetNonce unknown wallet: got %v, want ErrWalletNotFound", err)
+	}
+}
+
+// testConcurrentNonce проверяет, что из параллельных переводов с одним номером выполняется ровно один.
+func testConcurrentNonce(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	from := newWallet(t, repo, dec("100"))
+	to := newWallet(t, repo, dec("0"))
+
+	const senders = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, senders)
+	for i := 0; i < senders; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.Send(ctx, from, to, dec("1"), "", "", 1, sql.LevelDefault)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		wantNonceError(t, err, 2)
+	}
+	if succeeded != 1 {
+		t.Fatalf("concurrent sends with one nonce: %d succeeded, want 1", succeeded)
+	}
+	if got := balanceOf(t, repo, from); !got.Equal(dec("99")) {
+		t.Fatalf("sender balance: got %v, want 99", got)
+	}
+}
+
+func testSend(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	from := newWallet(t, repo, dec("100"))
+	to := newWallet(t, repo, dec("100"))
+
+	start := time.Now().Add(-time.Minute)
+	result, err := repo.Send(ctx, from, to, dec("30"), "invoice 42", "", 0, sql.LevelDefault)
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if !result.SenderBalance.Equal(dec("70")) || !result.ReceiverBalance.Equal(dec("130")) ||
+		result.CreatedAt.Before(start) || result.CreatedAt.Location() != time.UTC {
+		t.Fatalf("Send result: got %+v, want balances 70 and 130 and a recent UTC time", result)
+	}
+	if got := balanceOf(t, repo, from); !got.Equal(dec("70")) {
+		t.Fatalf("sender balance: got %v, want 70", got)
+	}
+	if got := balanceOf(t, repo, to); !got.Equal(dec("130")) {
+		t.Fatalf("receiver balance: got %v, want 130", got)
+	}
+
+	transactions, err := repo.GetLastTransactions(ctx, 1, db.TransactionFilter{})
+	if err != nil {
+		t.Fatalf("GetLastTransactions: %v", err)
+	}
+	if len(transactions) != 1 || transactions[0].Memo != "invoice 42" {
+		t.Fatalf("memo not persisted: %+v", transactions)
+	}
+	if transactions[0].ID != result.TransactionID {
+		t.Fatalf("Send result: transaction id %d, recorded transaction %d", result.TransactionID, transactions[0].ID)
+	}
+
+	// Перевод самому себе отклоняется ошибкой пакета errs, доступной и как db.ErrSelfTransfer
+	_, err = repo.Send(ctx, from, from, dec("1"), "", "", 0, sql.LevelDefault)
+	if !errors.Is(err, errs.ErrSelfTransfer) || !errors.Is(err, db.ErrSelfTransfer) {
+		t.Fatalf("Send to the same wallet: got %v, want ErrSelfTransfer", err)
+	}
+	if got := balanceOf(t, repo, from); !got.Equal(dec("70")) {
+		t.Fatalf("sender balance after self-transfer: got %v, want 70", got)
+	}
+}
+
+// testClock проверяет, что время транзакции берется из Clock репозитория, а не из базы.
+// Репозитории без SetClock (обертки) пропускаются.
+func testClock(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	clockSetter, ok := repo.(interface{ SetClock(db.Clock) })
+	if !ok {
+		t.Skip("repository does not support SetClock")
+	}
+	// Время с точностью до миллисекунды: так его хранит SQLite
+	now := time.Date(2021, 3, 14, 15, 9, 26, 535000000, time.UTC)
+	clock := db.NewFixedClock(now)
+	clockSetter.SetClock(clock)
+
+	from := newWallet(t, repo, dec("100"))
+	to := newWallet(t, repo, dec("100"))
+	first, err := repo.Send(ctx, from, to, dec("1"), "", "", 0, sql.LevelDefault)
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if !first.CreatedAt.Equal(now) {
+		t.Fatalf("Send result time: got %v, want %v", first.CreatedAt, now)
+	}
+	clock.Advance(time.Hour)
+	if _, err := repo.Send(ctx, from, to, dec("1"), "", "", 0, sql.LevelDefault); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	transactions, err := repo.GetLastTransactions(ctx, 2, db.TransactionFilter{})
+	if err != nil {
+		t.Fatalf("GetLastTransactions: %v", err)
+	}
+	if len(transactions) != 2 || !transactions[0].CreatedAt.Equal(now.Add(time.Hour)) || !transactions[1].CreatedAt.Equal(now) {
+		t.Fatalf("recorded times: got %+v, want %v and %v", transactions, now.Add(time.Hour), now)
+	}
+}
+
+func testSendExactBalance(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	from := newWallet(t, repo, dec("100"))
+	to := newWallet(t, repo, dec("0"))
+
+	if _, err := repo.Send(ctx, from, to, dec("100"), "", "", 0, sql.LevelDefault); err != nil {
+		t.Fatalf("Send of exact balance: %v", err)
+	}
+	if got := balanceOf(t, repo, from); !got.Equal(dec("0")) {
+		t.Fatalf("sender balance: got %v, want 0", got)
+	}
+
+	// Баланс, накопленный из нескольких зачислений, хранится точно:
+	// 0.6 + 0.3 + 0.1 дает ровно 1 (в float64 было бы 0.9999999999999999)
+	wallet := newWallet(t, repo, dec("0"))
+	for _, amount := range []decimal.Decimal{dec("0.6"), dec("0.3"), dec("0.1")} {
+		if _, err := repo.Send(ctx, to, wallet, amount, "", "", 0, sql.LevelDefault); err != nil {
+			t.Fatalf("Send %v: %v", amount, err)
+		}
+	}
+	if _, err := repo.Send(ctx, wallet, to, dec("1"), "", "", 0, sql.LevelDefault); err != nil {
+		t.Fatalf("Send of accumulated balance: %v", err)
+	}
+	if got := balanceOf(t, repo, wallet); !got.Equal(dec("0")) {
+		t.Fatalf("accumulated wallet balance: got %v, want 0", got)
+	}
+}
+
+func testInsufficientFunds(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	from := newWallet(t, repo, dec("10"))
+	to := newWallet(t, repo, dec("10"))
+
+	if _, err := repo.Send(ctx, from, to, dec("10.01"), "", "", 0, sql.LevelDefault); !errors.Is(err, db.ErrInsufficientFunds) {
+		t.Fatalf("Send over balance: got %v, want ErrInsufficientFunds", err)
+	}
+	if got := balanceOf(t, repo, from); !got.Equal(dec("10")) {
+		t.Fatalf("sender balance changed after failed send: %v", got)
+	}
+	if got := balanceOf(t, repo, to); !got.Equal(dec("10")) {
+		t.Fatalf("receiver balance changed after failed send: %v", got)
+	}
+}
+
+// testMinimumBalance ожидает репозиторий, созданный при MIN_WALLET_BALANCE=10.
+func testMinimumBalance(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	from := newWallet(t, repo, dec("100"))
+	to := newWallet(t, repo, dec("0"))
+
+	if _, err := repo.Send(ctx, from, to, dec("90.01"), "", "", 0, sql.LevelDefault); !errors.Is(err, db.ErrBelowMinimumBalance) {
+		t.Fatalf("Send below minimum: got %v, want ErrBelowMinimumBalance", err)
+	}
+	if _, err := repo.Send(ctx, from, to, dec("200"), "", "", 0, sql.LevelDefault); !errors.Is(err, db.ErrInsufficientFunds) {
+		t.Fatalf("Send more than balance: got %v, want ErrInsufficientFunds", err)
+	}
+	if got := balanceOf(t, repo, from); !got.Equal(dec("100")) {
+		t.Fatalf("sender balance after rejected sends: got %v, want 100", got)
+	}
+
+	// Перевод, оставляющий ровно минимальный остаток, разрешен
+	if _, err := repo.Send(ctx, from, to, dec("90"), "", "", 0, sql.LevelDefault); err != nil {
+		t.Fatalf("Send down to minimum: %v", err)
+	}
+	if got := balanceOf(t, repo, from); !got.Equal(dec("10")) {
+		t.Fatalf("sender balance: got %v, want 10", got)
+	}
+	if _, err := repo.Send(ctx, from, to, dec("0.01"), "", "", 0, sql.LevelDefault); !errors.Is(err, db.ErrBelowMinimumBalance) {
+		t.Fatalf("Send at minimum: got %v, want ErrBelowMinimumBalance", err)
+	}
+}
+
+// testAmountScale проверяет, что суммы с AmountScale знаками после запятой не округляются.
+func testAmountScale(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	from := newWallet(t, repo, dec("100"))
+	to := newWallet(t, repo, dec("0.00000001"))
+
+	if _, err := repo.Send(ctx, from, to, dec("0.00000001"), "", "", 0, sql.LevelDefault); err != nil {
+		t.Fatalf("Send of smallest amount: %v", err)
+	}
+	if got := balanceOf(t, repo, from); !got.Equal(dec("99.99999999")) {
+		t.Fatalf("sender balance: got %v, want 99.99999999", got)
+	}
+	if got := balanceOf(t, repo, to); !got.Equal(dec("0.00000002")) {
+		t.Fatalf("receiver balance: got %v, want 0.00000002", got)
+	}
+}
+
+func testBalanceOverflow(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	// Наибольший баланс, который помещается в NUMERIC(38, 8)
+	const maxBalance = "999999999999999999999999999999.99999999"
+	from := newWallet(t, repo, dec(maxBalance))
+	to := newWallet(t, repo, dec(maxBalance).Sub(dec("1")))
+
+	// Баланс получателя ровно достигает максимума - это еще не переполнение
+	if _, err := repo.Send(ctx, from, to, dec("1"), "", "", 0, sql.LevelDefault); err != nil {
+		t.Fatalf("Send up to max balance: %v", err)
+	}
+	if got := balanceOf(t, repo, to); !got.Equal(dec(maxBalance)) {
+		t.Fatalf("receiver balance: got %v, want %s", got, maxBalance)
+	}
+
+	if _, err := repo.Send(ctx, from, to, dec("0.00000001"), "", "", 0, sql.LevelDefault); !errors.Is(err, db.ErrBalanceOverflow) {
+		t.Fatalf("Send past max balance: got %v, want ErrBalanceOverflow", err)
+	}
+	if want := dec(maxBalance).Sub(dec("1")); !balanceOf(t, repo, from).Equal(want) {
+		t.Fatalf("sender balance after rejected send: got %v, want %v", balanceOf(t, repo, from), want)
+	}
+	if got := balanceOf(t, repo, to); !got.Equal(dec(maxBalance)) {
+		t.Fatalf("receiver balance after rejected send: got %v, want %s", got, maxBalance)
+	}
+
+	// Зачисление в транзакции (начисление при наполнении, выпуск) проверяется так же
+	err := repo.WithTx(ctx, func(tx db.TxRepository) error {
+		_, err := tx.AddBalance(to, dec("0.00000001"))
+		return err
+	})
+	if !errors.Is(err, db.ErrBalanceOverflow) {
+		t.Fatalf("AddBalance past max balance: got %v, want ErrBalanceOverflow", err)
+	}
+	if got := balanceOf(t, repo, to); !got.Equal(dec(maxBalance)) {
+		t.Fatalf("receiver balance after rejected credit: got %v, want %s", got, maxBalance)
+	}
+}
+
+func testUnknownParties(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	known := newWallet(t, repo, dec("50"))
+	unknown, _ := db.GenerateAddress()
+
+	if _, err := repo.Send(ctx, unknown, known, dec("1"), "", "", 0, sql.LevelDefault); !errors.Is(err, db.ErrWalletNotFound) {
+		t.Fatalf("Send from unknown wallet: got %v, want ErrWalletNotFound", err)
+	}
+	if _, err := repo.Send(ctx, known, unknown, dec("1"), "", "", 0, sql.LevelDefault); !errors.Is(err, db.ErrWalletNotFound) {
+		t.Fatalf("Send to unknown wallet: got %v, want ErrWalletNotFound", err)
+	}
+	if got := balanceOf(t, repo, known); !got.Equal(dec("50")) {
+		t.Fatalf("balance changed after send to unknown wallet: %v", got)
+	}
+}
+
+func testLastTransactionsOrder(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	from := newWallet(t, repo, dec("100"))
+	to := newWallet(t, repo, dec("100"))
+
+	amounts := []decimal.Decimal{dec("1"), dec("2"), dec("3"), dec("4"), dec("5")}
+	for _, amount := range amounts {
+		if _, err := repo.Send(ctx, from, to, amount, "", "", 0, sql.LevelDefault); err != nil {
+			t.Fatalf("Send(%v): %v", amount, err)
+		}
+	}
+
+	transactions, err := repo.GetLastTransactions(ctx, 3, db.TransactionFilter{})
+	if err != nil {
+		t.Fatalf("GetLastTransactions: %v", err)
+	}
+	if len(transactions) != 3 {
+		t.Fatalf("GetLastTransactions(3) returned %d rows", len(transactions))
+	}
+	for i, want := range []decimal.Decimal{dec("5"), dec("4"), dec("3")} {
+		tx := transactions[i]
+		if !tx.Amount.Equal(want) || tx.From != from || tx.To != to {
+			t.Fatalf("transaction %d: got %+v, want amount %v from %s to %s", i, tx, want, from, to)
+		}
+	}
+}
+
+func testFilterByAmount(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	from := newWallet(t, repo, dec("100"))
+	to := newWallet(t, repo, dec("100"))
+
+	// 10.5 встречается дважды (второй раз записана как 10.50), соседние суммы не должны совпасть
+	for _, amount := range []decimal.Decimal{dec("10.5"), dec("10.51"), dec("10.49"), dec("10.50"), dec("0.1").Add(dec("0.2"))} {
+		if _, err := repo.Send(ctx, from, to, amount, "", "", 0, sql.LevelDefault); err != nil {
+			t.Fatalf("Send(%v): %v", amount, err)
+		}
+	}
+
+	amount := dec("10.5")
+	transactions, err := repo.GetLastTransactions(ctx, 10, db.TransactionFilter{Amount: &amount})
+	if err != nil {
+		t.Fatalf("GetLastTransactions: %v", err)
+	}
+	if len(transactions) != 2 {
+		t.Fatalf("filter amount=10.5 returned %d rows, want 2: %+v", len(transactions), transactions)
+	}
+	for _, tx := range transactions {
+		if !tx.Amount.Equal(dec("10.5")) {
+			t.Fatalf("filter amount=10.5 returned amount %v", tx.Amount)
+		}
+	}
+	if count, err := repo.CountTransactions(ctx, db.TransactionFilter{Amount: &amount}); err != nil || count != 2 {
+		t.Fatalf("CountTransactions amount=10.5: got %d, %v, want 2", count, err)
+	}
+	if count, err := repo.CountTransactions(ctx, db.TransactionFilter{}); err != nil || count != 5 {
+		t.Fatalf("CountTransactions: got %d, %v, want 5", count, err)
+	}
+	// Оценка приблизительна, но на только что созданной таблице статистики еще нет,
+	// и PostgreSQL возвращает точное количество, как и остальные реализации
+	if count, err := repo.EstimateTransactions(ctx); err != nil || count != 5 {
+		t.Fatalf("EstimateTransactions: got %d, %v, want 5", count, err)
+	}
+
+	// 0.1 + 0.2 != 0.3 в float64; точная сумма находится по 0.3
+	amount = dec("0.3")
+	transactions, err = repo.GetLastTransactions(ctx, 10, db.TransactionFilter{Amount: &amount})
+	if err != nil {
+		t.Fatalf("GetLastTransactions: %v", err)
+	}
+	if len(transactions) != 1 {
+		t.Fatalf("filter amount=0.3 returned %d rows, want 1", len(transactions))
+	}
+
+	amount = dec("7")
+	transactions, err = repo.GetLastTransactions(ctx, 10, db.TransactionFilter{Amount: &amount})
+	if err != nil {
+		t.Fatalf("GetLastTransactions: %v", err)
+	}
+	if len(transactions) != 0 {
+		t.Fatalf("filter amount=7 returned %d rows, want 0", len(transactions))
+	}
+}
+
+func testFilterByCategory(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	from := newWallet(t, repo, dec("100"))
+	to := newWallet(t, repo, dec("100"))
+
+	for _, category := range []string{"salary", "", "fee", "salary"} {
+		if _, err := repo.Send(ctx, from, to, dec("1"), "", category, 0, sql.LevelDefault); err != nil {
+			t.Fatalf("Send(%q): %v", category, err)
+		}
+	}
+	batch := []models.Transaction{{From: from, To: to, Amount: dec("2"), CreatedAt: time.Now().Add(-time.Hour),
+		Category: "salary", ExternalID: "category-" + from[:8]}}
+	if imported, err := repo.ImportTransactions(ctx, batch); err != nil || imported != 1 {
+		t.Fatalf("ImportTransactions: imported %d, err %v", imported, err)
+	}
+
+	filter := db.TransactionFilter{Category: "salary"}
+	transactions, err := repo.GetLastTransactions(ctx, 10, filter)
+	if err != nil || len(transactions) != 3 {
+		t.Fatalf("filter category=salary: got %+v, %v, want 3 rows", transactions, err)
+	}
+	for _, tx := range transactions {
+		if tx.Category != "salary" {
+			t.Fatalf("filter category=salary returned category %q", tx.Category)
+		}
+	}
+	if count, err := repo.CountTransactions(ctx, filter); err != nil || count != 3 {
+		t.Fatalf("CountTransactions category=salary: got %d, %v, want 3", count, err)
+	}
+
+	// Пустая категория не фильтрует; перевод без категории возвращается с пустой строкой
+	all, err := repo.GetLastTransactions(ctx, 10, db.TransactionFilter{})
+	if err != nil || len(all) != 5 || all[2].Category != "" {
+		t.Fatalf("GetLastTransactions: got %+v, %v", all, err)
+	}
+
+	// Категория переносится в архив вместе с транзакцией
+	if purged, err := repo.PurgeTransactions(ctx, time.Now().Add(time.Hour), true, 10); err != nil || purged != 5 {
+		t.Fatalf("PurgeTransactions: got %d, %v, want 5", purged, err)
+	}
+	filter.IncludeArchived = true
+	if count, err := repo.CountTransactions(ctx, filter); err != nil || count != 3 {
+		t.Fatalf("CountTransactions category=salary with archive: got %d, %v, want 3", count, err)
+	}
+}
+
+// testCategoryVolumes проверяет группировку по дням UTC и категориям, точные суммы,
+// границы периода [from, to) и учет архива.
+func testCategoryVolumes(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	from := newWallet(t, repo, dec("100"))
+	to := newWallet(t, repo, dec("100"))
+
+	// Исторические переводы с известным временем: 23:30 UTC 1 марта - еще 1 марта, хотя в часовом
+	// поясе +03:00 это уже 2 марта; 4 марта выходит за границу периода
+	day1 := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	plus3 := time.FixedZone("UTC+3", 3*60*60)
+	batch := []models.Transaction{
+		{Amount: dec("1.10"), CreatedAt: day1.Add(10 * time.Hour), Category: "salary"},
+		{Amount: dec("2.25"), CreatedAt: time.Date(2020, 3, 2, 2, 30, 0, 0, plus3), Category: "salary"},
+		{Amount: dec("3"), CreatedAt: day1.Add(12 * time.Hour), Category: ""},
+		{Amount: dec("4"), CreatedAt: day1.Add(36 * time.Hour), Category: "fee"},
+		{Amount: dec("5"), CreatedAt: day1.AddDate(0, 0, 3), Category: "fee"},
+	}
+	for i := range batch {
+		batch[i].From, batch[i].To = from, to
+		batch[i].ExternalID = fmt.Sprintf("volumes-%s-%d", from[:8], i)
+	}
+	if imported, err := repo.ImportTransactions(ctx, batch); err != nil || imported != len(batch) {
+		t.Fatalf("ImportTransactions: imported %d, err %v", imported, err)
+	}
+	// Часть переводов уже в архиве
+	if _, err := repo.PurgeTransactions(ctx, day1.Add(11*time.Hour), true, 10); err != nil {
+		t.Fatalf("PurgeTransactions: %v", err)
+	}
+
+	volumes, err := repo.GetCategoryVolumes(ctx, day1, day1.AddDate(0, 0, 3))
+	if err != nil {
+		t.Fatalf("GetCategoryVolumes: %v", err)
+	}
+	want := []db.CategoryVolume{
+		{Day: day1, Category: "", Count: 1, Volume: dec("3")},
+		{Day: day1, Category: "salary", Count: 2, Volume: dec("3.35")},
+		{Day: day1.AddDate(0, 0, 1), Category: "fee", Count: 1, Volume: dec("4")},
+	}
+	if len(volumes) != len(want) {
+		t.Fatalf("GetCategoryVolumes: got %+v, want %+v", volumes, want)
+	}
+	for i, v := range volumes {
+		if !v.Day.Equal(want[i].Day) || v.Category != want[i].Category || v.Count != want[i].Count || !v.Volume.Equal(want[i].Volume) {
+			t.Fatalf("GetCategoryVolumes[%d]: got %+v, want %+v", i, v, want[i])
+		}
+	}
+}
+
+func testCursorPaging(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	from := newWallet(t, repo, dec("100"))
+	to := newWallet(t, repo, dec("100"))
+
+	// Пять транзакций с одинаковым временем и две позже; сумма отделяет их от других тестов
+	amount := dec("31.7")
+	moment := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	var batch []models.Transaction
+	for i := 0; i < 7; i++ {
+		createdAt := moment
+		if i >= 5 {
+			createdAt = moment.Add(time.Duration(i) * time.Second)
+		}
+		batch = append(batch, models.Transaction{From: from, To: to, Amount: amount, CreatedAt: createdAt,
+			ExternalID: fmt.Sprintf("cursor-%s-%d", from[:8], i)})
+	}
+	if imported, err := repo.ImportTransactions(ctx, batch); err != nil || imported != len(batch) {
+		t.Fatalf("ImportTransactions: imported %d, err %v", imported, err)
+	}
+
+	// Обход страницами по две; перевод между страницами попадает в начало списка и не сдвигает их
+	seen := make(map[int]bool)
+	filter := db.TransactionFilter{Amount: &amount}
+	for page := 0; ; page++ {
+		transactions, err := repo.GetLastTransactions(ctx, 2, filter)
+		if err != nil {
+			t.Fatalf("GetLastTransactions page %d: %v", page, err)
+		}
+		for _, tx := range transactions {
+			if seen[tx.ID] {
+				t.Fatalf("page %d repeats transaction %d", page, tx.ID)
+			}
+			seen[tx.ID] = true
+		}
+		if len(transactions) < 2 {
+			break
+		}
+		cursor := db.CursorOf(transactions[len(transactions)-1])
+		filter.After = &cursor
+		if _, err := repo.Send(ctx, from, to, amount, "", "", 0, sql.LevelDefault); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	if len(seen) != len(batch) {
+		t.Fatalf("paging returned %d transactions, want %d", len(seen), len(batch))
+	}
+}
+
+func testWalletTransactions(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	a := newWallet(t, repo, dec("100"))
+	b := newWallet(t, repo, dec("100"))
+	c := newWallet(t, repo, dec("100"))
+	idle := newWallet(t, repo, dec("100"))
+
+	// Время задается явно, чтобы порядок не зависел от точности часов базы
+	moment := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	transfers := []struct{ from, to, amount string }{{a, b, "1"}, {b, c, "2"}, {c, a, "3"}, {a, c, "4"}}
+	var batch []models.Transaction
+	for i, tr := range transfers {
+		batch = append(batch, models.Transaction{From: tr.from, To: tr.to, Amount: dec(tr.amount),
+			CreatedAt: moment.Add(time.Duration(i) * time.Second), ExternalID: fmt.Sprintf("wallet-tx-%s-%d", a[:8], i)})
+	}
+	if imported, err := repo.ImportTransactions(ctx, batch); err != nil || imported != len(batch) {
+		t.Fatalf("ImportTransactions: imported %d, err %v", imported, err)
+	}
+
+	transactions, err := repo.GetWalletTransactions(ctx, []string{a, b, idle}, 2)
+	if err != nil {
+		t.Fatalf("GetWalletTransactions: %v", err)
+	}
+	amounts := func(list []models.Transaction) []string {
+		var result []string
+		for _, tx := range list {
+			result = append(result, tx.Amount.String())
+		}
+		return result
+	}
+	// Отправленные и полученные переводы, от новых к старым, не больше двух на кошелек
+	if got := amounts(transactions[a]); !slices.Equal(got, []string{"4", "3"}) {
+		t.Fatalf("transactions of a: got %v, want [4 3]", got)
+	}
+	if got := amounts(transactions[b]); !slices.Equal(got, []string{"2", "1"}) {
+		t.Fatalf("transactions of b: got %v, want [2 1]", got)
+	}
+	if _, ok := transactions[idle]; ok || len(transactions) != 2 {
+		t.Fatalf("GetWalletTransactions returned wallets %v, want only a and b", slices.Collect(maps.Keys(transactions)))
+	}
+	if tx := transactions[a][0]; tx.From != a || tx.To != c || !tx.CreatedAt.Equal(moment.Add(3*time.Second)) {
+		t.Fatalf("latest transaction of a: got %+v", tx)
+	}
+
+	if transactions, err := repo.GetWalletTransactions(ctx, nil, 2); err != nil || len(transactions) != 0 {
+		t.Fatalf("GetWalletTransactions(nil): got %v, err %v", transactions, err)
+	}
+}
+
+func testImportTransactions(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	from := newWallet(t, repo, dec("100"))
+	to := newWallet(t, repo, dec("100"))
+	if _, err := repo.Send(ctx, from, to, dec("1"), "", "", 0, sql.LevelDefault); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	historical := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	batch := []models.Transaction{
+		{From: from, To: to, Amount: dec("50"), CreatedAt: historical, ExternalID: "legacy-" + from[:8] + "-1"},
+		{From: to, To: from, Amount: dec("20"), CreatedAt: historical.Add(time.Hour), ExternalID: "legacy-" + from[:8] + "-2"},
+	}
+
+	imported, err := repo.ImportTransactions(ctx, batch)
+	if err != nil || imported != 2 {
+		t.Fatalf("ImportTransactions: imported %d, err %v", imported, err)
+	}
+	imported, err = repo.ImportTransactions(ctx, batch)
+	if err != nil || imported != 0 {
+		t.Fatalf("repeated ImportTransactions: imported %d, err %v; want duplicates skipped", imported, err)
+	}
+
+	if got := balanceOf(t, repo, from); !got.Equal(dec("99")) {
+		t.Fatalf("import changed sender balance: %v", got)
+	}
+
+	// Исторические записи не должны опережать свежий перевод
+	transactions, err := repo.GetLastTransactions(ctx, 1, db.TransactionFilter{})
+	if err != nil {
+		t.Fatalf("GetLastTransactions: %v", err)
+	}
+	if len(transactions) != 1 || transactions[0].Imported {
+		t.Fatalf("latest transaction is an imported one: %+v", transactions)
+	}
+}
+
+func testPurgeTransactions(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	from := newWallet(t, repo, dec("100"))
+	to := newWallet(t, repo, dec("100"))
+
+	// SQLite хранит время с точностью до миллисекунд
+	historical := time.Now().Add(-48 * time.Hour).UTC().Truncate(time.Millisecond)
+	batch := []models.Transaction{
+		{From: from, To: to, Amount: dec("1"), CreatedAt: historical, Memo: "old", ExternalID: "purge-" + from[:8] + "-1"},
+		{From: from, To: to, Amount: dec("2"), CreatedAt: historical.Add(time.Minute), Memo: "old", ExternalID: "purge-" + from[:8] + "-2"},
+		{From: to, To: from, Amount: dec("3"), CreatedAt: historical.Add(2 * time.Minute), Memo: "old", ExternalID: "purge-" + from[:8] + "-3"},
+	}
+	if imported, err := repo.ImportTransactions(ctx, batch); err != nil || imported != 3 {
+		t.Fatalf("ImportTransactions: imported %d, err %v", imported, err)
+	}
+	if _, err := repo.Send(ctx, from, to, dec("5"), "recent", "", 0, sql.LevelDefault); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	// Пачки по две транзакции: последняя неполная пачка означает, что старых не осталось
+	before := time.Now().Add(-time.Hour)
+	for _, want := range []int{2, 1, 0} {
+		if purged, err := repo.PurgeTransactions(ctx, before, true, 2); err != nil || purged != want {
+			t.Fatalf("PurgeTransactions: got %d, %v, want %d", purged, err, want)
+		}
+	}
+
+	recent, err := repo.GetLastTransactions(ctx, 10, db.TransactionFilter{})
+	if err != nil || len(recent) != 1 || recent[0].Memo != "recent" {
+		t.Fatalf("GetLastTransactions after purge: got %+v, %v", recent, err)
+	}
+	all, err := repo.GetLastTransactions(ctx, 10, db.TransactionFilter{IncludeArchived: true})
+	if err != nil || len(all) != 4 || all[0].ID != recent[0].ID {
+		t.Fatalf("GetLastTransactions with archive: got %+v, %v", all, err)
+	}
+	for i, tx := range all[1:] {
+		original := batch[len(batch)-1-i]
+		if tx.ExternalID != original.ExternalID || !tx.Amount.Equal(original.Amount) || tx.Memo != "old" || !tx.Imported ||
+			!tx.CreatedAt.Equal(original.CreatedAt) {
+			t.Fatalf("archived transaction %d: got %+v, want %+v", i, tx, original)
+		}
+	}
+	amount := dec("2")
+	if found, err := repo.GetLastTransactions(ctx, 10, db.TransactionFilter{Amount: &amount, IncludeArchived: true}); err != nil || len(found) != 1 {
+		t.Fatalf("GetLastTransactions by amount with archive: got %+v, %v", found, err)
+	}
+	if count, err := repo.CountTransactions(ctx, db.TransactionFilter{}); err != nil || count != 1 {
+		t.Fatalf("CountTransactions after purge: got %d, %v, want 1", count, err)
+	}
+	if count, err := repo.CountTransactions(ctx, db.TransactionFilter{IncludeArchived: true}); err != nil || count != 4 {
+		t.Fatalf("CountTransactions with archive: got %d, %v, want 4", count, err)
+	}
+
+	// Очистка не меняет балансы, а архивные записи не импортируются повторно
+	if got := balanceOf(t, repo, from); !got.Equal(dec("95")) {
+		t.Fatalf("purge changed sender balance: %v", got)
+	}
+	if imported, err := repo.ImportTransactions(ctx, batch); err != nil || imported != 0 {
+		t.Fatalf("ImportTransactions of archived records: imported %d, err %v", imported, err)
+	}
+
+	// Анонимизация охватывает и архив
+	if report, err := repo.AnonymizeWallet(ctx, from, false); err != nil || report.Transactions != 4 {
+		t.Fatalf("AnonymizeWallet with archive: got %+v, %v", report, err)
+	}
+
+	// Без архивации транзакции удаляются
+	if purged, err := repo.PurgeTransactions(ctx, time.Now().Add(time.Minute), false, 10); err != nil || purged != 1 {
+		t.Fatalf("PurgeTransactions without archive: got %d, %v", purged, err)
+	}
+	if all, err := repo.GetLastTransactions(ctx, 10, db.TransactionFilter{IncludeArchived: true}); err != nil || len(all) != 3 {
+		t.Fatalf("GetLastTransactions after delete: got %d transactions, %v", len(all), err)
+	}
+}
+
+func testSenderStats(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	from := newWallet(t, repo, dec("100"))
+	to := newWallet(t, repo, dec("0"))
+	start := time.Now().Add(-time.Minute)
+
+	for _, amount := range []decimal.Decimal{dec("10"), dec("5")} {
+		if _, err := repo.Send(ctx, from, to, amount, "", "", 0, sql.LevelDefault); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	// Входящие переводы и импортированная история в сводку отправителя не входят
+	if _, err := repo.Send(ctx, to, from, dec("1"), "", "", 0, sql.LevelDefault); err != nil {
+		t.Fatalf("Send back: %v", err)
+	}
+	batch := []models.Transaction{{From: from, To: to, Amount: dec("50"), CreatedAt: time.Now().UTC(), ExternalID: "stats-" + from[:8]}}
+	if _, err := repo.ImportTransactions(ctx, batch); err != nil {
+		t.Fatalf("ImportTransactions: %v", err)
+	}
+
+	stats, err := repo.GetSenderStats(ctx, from, start)
+	if err != nil || stats.Count != 2 || !stats.Total.Equal(dec("15")) {
+		t.Fatalf("GetSenderStats: got %+v, %v, want 2 transfers totalling 15", stats, err)
+	}
+	if stats, err := repo.GetSenderStats(ctx, from, time.Now().Add(time.Minute)); err != nil || stats.Count != 0 || !stats.Total.IsZero() {
+		t.Fatalf("GetSenderStats in the future: got %+v, %v", stats, err)
+	}
+}
+
+func testRiskEvents(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	from := newWallet(t, repo, dec("0"))
+	to := newWallet(t, repo, dec("0"))
+	start := time.Now().Add(-time.Minute)
+
+	for _, rule := range []string{"velocity", "amount", "velocity"} {
+		event := models.RiskEvent{Rule: rule, Action: "flag", From: from, To: to, Amount: dec("12.5"), Reason: rule + " triggered"}
+		if err := repo.RecordRiskEvent(ctx, event); err != nil {
+			t.Fatalf("RecordRiskEvent: %v", err)
+		}
+	}
+
+	events, err := repo.GetRiskEvents(ctx, 2)
+	if err != nil {
+		t.Fatalf("GetRiskEvents: %v", err)
+	}
+	if len(events) != 2 || events[0].ID <= events[1].ID || events[1].Rule != "amount" {
+		t.Fatalf("GetRiskEvents: want the 2 newest events first, got %+v", events)
+	}
+	e := events[0]
+	if e.Action != "flag" || e.From != from || e.To != to || !e.Amount.Equal(dec("12.5")) || e.Reason != "velocity triggered" {
+		t.Fatalf("GetRiskEvents: event fields not preserved: %+v", e)
+	}
+	if e.CreatedAt.Before(start) || e.CreatedAt.Location() != time.UTC {
+		t.Fatalf("GetRiskEvents: created_at %v, want a recent UTC time", e.CreatedAt)
+	}
+}
+
+func testAuditEvents(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	start := time.Now().Add(-time.Minute)
+	for _, target := range []string{"first", "second", "third"} {
+		event := models.AuditEvent{Action: models.AuditWalletAnonymized, Target: target, Details: target + " details"}
+		if err := repo.RecordAuditEvent(ctx, event); err != nil {
+			t.Fatalf("RecordAuditEvent: %v", err)
+		}
+	}
+
+	events, err := repo.GetAuditEvents(ctx, 2)
+	if err != nil {
+		t.Fatalf("GetAuditEvents: %v", err)
+	}
+	if len(events) != 2 || events[0].ID <= events[1].ID || events[1].Target != "second" {
+		t.Fatalf("GetAuditEvents: want the 2 newest events first, got %+v", events)
+	}
+	e := events[0]
+	if e.Action != models.AuditWalletAnonymized || e.Target != "third" || e.Details != "third details" {
+		t.Fatalf("GetAuditEvents: event fields not preserved: %+v", e)
+	}
+	if e.CreatedAt.Before(start) || e.CreatedAt.Location() != time.UTC {
+		t.Fatalf("GetAuditEvents: created_at %v, want a recent UTC time", e.CreatedAt)
+	}
+}
+
+func testApprovals(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	from := newWallet(t, repo, dec("0"))
+	to := newWallet(t, repo, dec("0"))
+	start := time.Now().Add(-time.Minute)
+
+	first, err := repo.CreateApproval(ctx, models.Approval{From: from, To: to, Amount: dec("5000"), Memo: "invoice 42", Category: "salary", Nonce: 3})
+	if err != nil {
+		t.Fatalf("CreateApproval: %v", err)
+	}
+	if first.ID == 0 || first.Status != models.ApprovalAwaitingReview || first.DecidedAt != nil || first.CreatedAt.Before(start) {
+		t.Fatalf("CreateApproval: got %+v", first)
+	}
+	second, err := repo.CreateApproval(ctx, models.Approval{From: from, To: to, Amount: dec("7000")})
+	if err != nil {
+		t.Fatalf("CreateApproval: %v", err)
+	}
+
+	got, err := repo.GetApproval(ctx, first.ID)
+	if err != nil || got.From != from || got.To != to || !got.Amount.Equal(dec("5000")) || got.Memo != "invoice 42" || got.Category != "salary" || got.Nonce != 3 {
+		t.Fatalf("GetApproval: got %+v, %v", got, err)
+	}
+	if _, err := repo.GetApproval(ctx, second.ID+100); !errors.Is(err, db.ErrApprovalNotFound) {
+		t.Fatalf("GetApproval unknown: got %v, want ErrApprovalNotFound", err)
+	}
+
+	rejected, err := repo.UpdateApprovalStatus(ctx, first.ID, models.ApprovalAwaitingReview, models.ApprovalRejected, "unknown recipient")
+	if err != nil || rejected.Status != models.ApprovalRejected || rejected.Reason != "unknown recipient" || rejected.DecidedAt == nil {
+		t.Fatalf("UpdateApprovalStatus: got %+v, %v", rejected, err)
+	}
+	if _, err := repo.UpdateApprovalStatus(ctx, first.ID, models.ApprovalAwaitingReview, models.ApprovalApproved, ""); !errors.Is(err, db.ErrApprovalStatus) {
+		t.Fatalf("UpdateApprovalStatus of decided approval: got %v, want ErrApprovalStatus", err)
+	}
+	if _, err := repo.UpdateApprovalStatus(ctx, second.ID+100, models.ApprovalAwaitingReview, models.ApprovalApproved, ""); !errors.Is(err, db.ErrApprovalNotFound) {
+		t.Fatalf("UpdateApprovalStatus unknown: got %v, want ErrApprovalNotFound", err)
+	}
+
+	all, err := repo.GetApprovals(ctx, "", 10)
+	if err != nil || len(all) != 2 || all[0].ID != second.ID {
+		t.Fatalf("GetApprovals: want 2 approvals newest first, got %+v, %v", all, err)
+	}
+	pending, err := repo.GetApprovals(ctx, models.ApprovalAwaitingReview, 10)
+	if err != nil || len(pending) != 1 || pending[0].ID != second.ID {
+		t.Fatalf("GetApprovals awaiting review: got %+v, %v", pending, err)
+	}
+	if limited, err := repo.GetApprovals(ctx, "", 1); err != nil || len(limited) != 1 {
+		t.Fatalf("GetApprovals with count 1: got %d, %v", len(limited), err)
+	}
+}
+
+func testReservedBalance(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	from := newWallet(t, repo, dec("100"))
+	to := newWallet(t, repo, dec("0"))
+
+	held, err := repo.CreateApproval(ctx, models.Approval{From: from, To: to, Amount: dec("60")})
+	if err != nil {
+		t.Fatalf("CreateApproval: %v", err)
+	}
+	// Отклоненный перевод средства не резервирует
+	rejected, err := repo.CreateApproval(ctx, models.Approval{From: from, To: to, Amount: dec("30")})
+	if err != nil {
+		t.Fatalf("CreateApproval: %v", err)
+	}
+	if _, err := repo.UpdateApprovalStatus(ctx, rejected.ID, models.ApprovalAwaitingReview, models.ApprovalRejected, "duplicate"); err != nil {
+		t.Fatalf("UpdateApprovalStatus: %v", err)
+	}
+
+	wantBalance(t, repo, from, models.Balance{Total: dec("100"), Reserved: dec("60"), Available: dec("40")})
+
+	// Баланса хватает на перевод, доступного остатка - нет
+	if _, err := repo.Send(ctx, from, to, dec("50"), "", "", 0, sql.LevelDefault); !errors.Is(err, db.ErrInsufficientFunds) {
+		t.Fatalf("Send over available: got %v, want ErrInsufficientFunds", err)
+	}
+	if _, err := repo.Send(ctx, from, to, dec("40"), "", "", 0, sql.LevelDefault); err != nil {
+		t.Fatalf("Send of available: %v", err)
+	}
+
+	// Подтвержденный перевод выходит из резерва и выполняется как обычный
+	if _, err := repo.UpdateApprovalStatus(ctx, held.ID, models.ApprovalAwaitingReview, models.ApprovalApproved, ""); err != nil {
+		t.Fatalf("UpdateApprovalStatus: %v", err)
+	}
+	wantBalance(t, repo, from, models.Balance{Total: dec("60"), Reserved: dec("0"), Available: dec("60")})
+	if _, err := repo.Send(ctx, from, to, dec("60"), "", "", 0, sql.LevelDefault); err != nil {
+		t.Fatalf("Send of approved transfer: %v", err)
+	}
+}
+
+// wantBalance сравнивает баланс, резерв и доступный остаток кошелька с ожидаемыми.
+func wantBalance(t *testing.T, repo db.Repository, address string, want models.Balance) {
+	t.Helper()
+	ctx := context.Background()
+	got, err := repo.GetBalanceDetails(ctx, address)
+	if err != nil {
+		t.Fatalf("GetBalanceDetails: %v", err)
+	}
+	if !got.Total.Equal(want.Total) || !got.Reserved.Equal(want.Reserved) || !got.Available.Equal(want.Available) {
+		t.Fatalf("GetBalanceDetails: got %+v, want %+v", got, want)
+	}
+}
+
+func testExpireApprovals(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	from := newWallet(t, repo, dec("0"))
+	to := newWallet(t, repo, dec("0"))
+
+	stale, err := repo.CreateApproval(ctx, models.Approval{From: from, To: to, Amount: dec("5000")})
+	if err != nil {
+		t.Fatalf("CreateApproval: %v", err)
+	}
+	decided, err := repo.CreateApproval(ctx, models.Approval{From: from, To: to, Amount: dec("6000")})
+	if err != nil {
+		t.Fatalf("CreateApproval: %v", err)
+	}
+	if _, err := repo.UpdateApprovalStatus(ctx, decided.ID, models.ApprovalAwaitingReview, models.ApprovalRejected, "no"); err != nil {
+		t.Fatalf("UpdateApprovalStatus: %v", err)
+	}
+
+	if n, err := repo.ExpireApprovals(ctx, time.Now().Add(-time.Minute)); err != nil || n != 0 {
+		t.Fatalf("ExpireApprovals before creation: got %d, %v, want 0", n, err)
+	}
+	// Решенные переводы не истекают, даже если созданы раньше границы
+	if n, err := repo.ExpireApprovals(ctx, time.Now().Add(time.Minute)); err != nil || n != 1 {
+		t.Fatalf("ExpireApprovals: got %d, %v, want 1", n, err)
+	}
+	if got, err := repo.GetApproval(ctx, stale.ID); err != nil || got.Status != models.ApprovalExpired || got.DecidedAt == nil {
+		t.Fatalf("expired approval: got %+v, %v", got, err)
+	}
+	if got, err := repo.GetApproval(ctx, decided.ID); err != nil || got.Status != models.ApprovalRejected {
+		t.Fatalf("rejected approval after expiry: got %+v, %v", got, err)
+	}
+}
+
+func testConcurrentApprovalDecision(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	approval, err := repo.CreateApproval(ctx, models.Approval{From: newWallet(t, repo, dec("0")), To: newWallet(t, repo, dec("0")), Amount: dec("5000")})
+	if err != nil {
+		t.Fatalf("CreateApproval: %v", err)
+	}
+
+	const admins = 8
+	var wg sync.WaitGroup
+	var succeeded atomic.Int64
+	for i := 0; i < admins; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.UpdateApprovalStatus(ctx, approval.ID, models.ApprovalAwaitingReview, models.ApprovalApproved, "")
+			switch {
+			case err == nil:
+				succeeded.Add(1)
+			case !errors.Is(err, db.ErrApprovalStatus):
+				t.Errorf("UpdateApprovalStatus: got %v, want ErrApprovalStatus", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := succeeded.Load(); n != 1 {
+		t.Fatalf("concurrent decisions on one approval: %d succeeded, want 1", n)
+	}
+}
+
+func testConcurrentConservation(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	const (
+		walletCount = 4
+		initial     = 100
+		workers     = 8
+		perWorker   = 25
+	)
+
+	wallets := make([]string, walletCount)
+	for i := range wallets {
+		wallets[i] = newWallet(t, repo, decimal.NewFromInt(initial))
+	}
+
+	// ErrContention допустим (на уровнях изоляции выше READ COMMITTED повторы могут закончиться):
+	// такой перевод не выполнен целиком, и сумма балансов сохраняется
+	var wg sync.WaitGroup
+	var succeeded atomic.Int64
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				from := wallets[(w+i)%walletCount]
+				to := wallets[(w+i+1)%walletCount]
+				_, err := repo.Send(ctx, from, to, dec("7"), "", "", 0, sql.LevelDefault)
+				if err == nil {
+					succeeded.Add(1)
+				} else if !errors.Is(err, db.ErrInsufficientFunds) && !errors.Is(err, db.ErrContention) {
+					t.Errorf("concurrent Send: %v", err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if succeeded.Load() == 0 {
+		t.Fatalf("no concurrent Send succeeded")
+	}
+
+	var total decimal.Decimal
+	for _, address := range wallets {
+		balance := balanceOf(t, repo, address)
+		if balance.IsNegative() {
+			t.Fatalf("wallet %s went negative: %v", address, balance)
+		}
+		total = total.Add(balance)
+	}
+	if !total.Equal(decimal.NewFromInt(walletCount * initial)) {
+		t.Fatalf("total balance not conserved: got %v, want %v", total, walletCount*initial)
+	}
+}
+
+// testReconcile проверяет, что переводы и удаление истории не нарушают инвариантов
+// хранилища и не меняют сумму балансов, а изменение баланса без записи перевода
+// обнаруживается как расхождение с историей.
+func testReconcile(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	from := newWallet(t, repo, dec("100"))
+	to := newWallet(t, repo, dec("0"))
+
+	before, err := repo.Reconcile(ctx)
+	if err != nil || before.Drift() {
+		t.Fatalf("Reconcile: got %+v, %v", before, err)
+	}
+	if before.Wallets < 2 || before.TotalBalance.LessThan(dec("100")) {
+		t.Fatalf("Reconcile: got %d wallets with total %s, want at least 2 with 100", before.Wallets, before.TotalBalance)
+	}
+
+	if _, err := repo.Send(ctx, from, to, dec("40.5"), "", "", 0, sql.LevelDefault); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	after, err := repo.Reconcile(ctx)
+	if err != nil || after.Drift() {
+		t.Fatalf("Reconcile after Send: got %+v, %v", after, err)
+	}
+	if after.Wallets != before.Wallets || !after.TotalBalance.Equal(before.TotalBalance) {
+		t.Fatalf("Reconcile after Send: got %d wallets with total %s, want %d with %s",
+			after.Wallets, after.TotalBalance, before.Wallets, before.TotalBalance)
+	}
+
+	// Удаленные без архива переводы переносятся в начальные балансы
+	if purged, err := repo.PurgeTransactions(ctx, time.Now().Add(time.Minute), false, 10); err != nil || purged != 1 {
+		t.Fatalf("PurgeTransactions: got %d, %v, want 1", purged, err)
+	}
+	if purged, err := repo.Reconcile(ctx); err != nil || purged.Drift() {
+		t.Fatalf("Reconcile after PurgeTransactions: got %+v, %v", purged, err)
+	}
+
+	err = repo.WithTx(context.Background(), func(tx db.TxRepository) error {
+		_, err := tx.AddBalance(to, dec("1"))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+	drifted, err := repo.Reconcile(ctx)
+	if err != nil || !drifted.Drift() || len(drifted.BalanceMismatches) != 1 {
+		t.Fatalf("Reconcile after AddBalance: got %+v, %v, want one balance mismatch", drifted, err)
+	}
+	if m := drifted.BalanceMismatches[0]; m.Address != to || !m.Stored.Equal(dec("41.5")) || !m.Expected.Equal(dec("40.5")) {
+		t.Fatalf("balance mismatch: got %+v, want %s stored 41.5, expected 40.5", m, to)
+	}
+}
+
+// testSystemTransfer проверяет операции с системными счетами: балансы кошелька и счетов,
+// неизменный нулевой баланс счета эмиссии, типы в истории и учет выпуска и изъятия в сверке.
+func testSystemTransfer(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	if has, err := repo.HasWallets(ctx); err != nil || has {
+		t.Fatalf("HasWallets on an empty repository: got %v, %v, want false (system accounts do not count)", has, err)
+	}
+	wallet := newWallet(t, repo, dec("0"))
+	mint, fees, escrow := db.SystemAccount(db.SystemMint), db.SystemAccount(db.SystemFees), db.SystemAccount(db.SystemEscrow)
+
+	steps := []struct {
+		txType  string
+		amount  string
+		balance string // Баланс кошелька после операции
+	}{
+		{models.TransactionMint, "100", "100"},
+		{models.TransactionFee, "2.5", "97.5"},
+		{models.TransactionEscrowHold, "30", "67.5"},
+		{models.TransactionEscrowRelease, "10", "77.5"},
+		{models.TransactionBurn, "7.5", "70"},
+	}
+	for _, step := range steps {
+		result, err := repo.SystemTransfer(ctx, step.txType, wallet, dec(step.amount), step.txType+" memo")
+		if err != nil {
+			t.Fatalf("SystemTransfer(%s): %v", step.txType, err)
+		}
+		if result.TransactionID == 0 {
+			t.Fatalf("SystemTransfer(%s): got %+v, want a transaction id", step.txType, result)
+		}
+		if got := balanceOf(t, repo, wallet); !got.Equal(dec(step.balance)) {
+			t.Fatalf("balance after %s: got %v, want %s", step.txType, got, step.balance)
+		}
+	}
+	if got := balanceOf(t, repo, mint); !got.IsZero() {
+		t.Fatalf("mint account balance: got %v, want 0", got)
+	}
+	if got := balanceOf(t, repo, fees); !got.Equal(dec("2.5")) {
+		t.Fatalf("fees account balance: got %v, want 2.5", got)
+	}
+	if got := balanceOf(t, repo, escrow); !got.Equal(dec("20")) {
+		t.Fatalf("escrow account balance: got %v, want 20", got)
+	}
+
+	transactions, err := repo.GetLastTransactions(ctx, len(steps), db.TransactionFilter{})
+	if err != nil || len(transactions) != len(steps) {
+		t.Fatalf("GetLastTransactions: got %d, %v, want %d", len(transactions), err, len(steps))
+	}
+	// Последняя операция - изъятие: с кошелька на счет эмиссии
+	if last := transactions[0]; last.Type != models.TransactionBurn || last.From != wallet || last.To != mint {
+		t.Fatalf("last transaction: got %+v, want burn from %s to %s", last, wallet, mint)
+	}
+	if first := transactions[len(steps)-1]; first.Type != models.TransactionMint || first.From != mint || first.To != wallet {
+		t.Fatalf("first transaction: got %+v, want mint from %s to %s", first, mint, wallet)
+	}
+
+	// Списание сверх доступного и лишние удержания отклоняются, как в Send
+	if _, err := repo.SystemTransfer(ctx, models.TransactionFee, wallet, dec("70.01"), ""); !errors.Is(err, db.ErrInsufficientFunds) {
+		t.Fatalf("SystemTransfer over balance: got %v, want ErrInsufficientFunds", err)
+	}
+	if _, err := repo.SystemTransfer(ctx, models.TransactionEscrowRelease, wallet, dec("20.01"), ""); !errors.Is(err, db.ErrInsufficientFunds) {
+		t.Fatalf("SystemTransfer over escrow: got %v, want ErrInsufficientFunds", err)
+	}
+	for _, txType := range []string{models.TransactionTransfer, "", "refund"} {
+		if _, err := repo.SystemTransfer(ctx, txType, wallet, dec("1"), ""); !errors.Is(err, db.ErrTransactionType) {
+			t.Fatalf("SystemTransfer(%q): got %v, want ErrTransactionType", txType, err)
+		}
+	}
+	if _, err := repo.SystemTransfer(ctx, models.TransactionMint, fees, dec("1"), ""); !errors.Is(err, db.ErrSystemAccount) {
+		t.Fatalf("SystemTransfer to a system account: got %v, want ErrSystemAccount", err)
+	}
+	unknown, _ := db.GenerateAddress()
+	if _, err := repo.SystemTransfer(ctx, models.TransactionMint, unknown, dec("1"), ""); !errors.Is(err, db.ErrWalletNotFound) {
+		t.Fatalf("SystemTransfer to an unknown wallet: got %v, want ErrWalletNotFound", err)
+	}
+
+	report, err := repo.Reconcile(ctx)
+	if err != nil || report.Drift() {
+		t.Fatalf("Reconcile: got %+v, %v", report, err)
+	}
+	if !report.Minted.Equal(dec("100")) || !report.Burned.Equal(dec("7.5")) ||
+		!report.TotalBalance.Equal(dec("92.5")) || !report.ExpectedSupply.Equal(report.TotalBalance) {
+		t.Fatalf("Reconcile: got minted %s, burned %s, total %s, expected %s, want 100, 7.5, 92.5, 92.5",
+			report.Minted, report.Burned, report.TotalBalance, report.ExpectedSupply)
+	}
+}
+
+// testSystemAccountSend проверяет, что обычный перевод с системного счета или на него отклоняется.
+func testSystemAccountSend(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	wallet := newWallet(t, repo, dec("100"))
+	fees := db.SystemAccount(db.SystemFees)
+	if _, err := repo.SystemTransfer(ctx, models.TransactionFee, wallet, dec("10"), ""); err != nil {
+		t.Fatalf("SystemTransfer: %v", err)
+	}
+
+	if _, err := repo.Send(ctx, wallet, fees, dec("1"), "", "", 0, sql.LevelDefault); !errors.Is(err, db.ErrSystemAccount) {
+		t.Fatalf("Send to a system account: got %v, want ErrSystemAccount", err)
+	}
+	if _, err := repo.Send(ctx, fees, wallet, dec("1"), "", "", 0, sql.LevelDefault); !errors.Is(err, db.ErrSystemAccount) {
+		t.Fatalf("Send from a system account: got %v, want ErrSystemAccount", err)
+	}
+	if got := balanceOf(t, repo, fees); !got.Equal(dec("10")) {
+		t.Fatalf("fees account balance: got %v, want 10", got)
+	}
+}
+
+// testWithTx проверяет, что операции WithTx фиксируются вместе, а ошибка fn откатывает их все,
+// включая созданный кошелек и записанную транзакцию.
+func testWithTx(t *testing.T, repo db.Repository) {
+	funder := newWallet(t, repo, dec("100"))
+	ctx := context.Background()
+
+	// Создание кошелька и пополнение его переводом в одной транзакции
+	created, err := db.GenerateAddress()
+	if err != nil {
+		t.Fatalf("GenerateAddress: %v", err)
+	}
+	err = repo.WithTx(ctx, func(tx db.TxRepository) error {
+		if err := tx.CreateWallet(created, dec("0"), models.WalletMetadata{}, ""); err != nil {
+			return err
+		}
+		state, err := tx.GetWalletForUpdate(funder)
+		if err != nil {
+			return err
+		}
+		if !state.Balance.Equal(dec("100")) {
+			t.Errorf("GetWalletForUpdate: got balance %s, want 100", state.Balance)
+		}
+		if _, err := tx.AddBalance(funder, dec("-30")); err != nil {
+			return err
+		}
+		if _, err := tx.AddBalance(created, dec("30")); err != nil {
+			return err
+		}
+		_, err = tx.RecordTransaction(models.TransactionTransfer, funder, created, dec("30"), "funding", "")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+	if got := balanceOf(t, repo, created); !got.Equal(dec("30")) {
+		t.Fatalf("created wallet: got balance %s, want 30", got)
+	}
+	if got := balanceOf(t, repo, funder); !got.Equal(dec("70")) {
+		t.Fatalf("funder: got balance %s, want 70", got)
+	}
+
+	// Ошибка fn после изменений откатывает их все
+	rolledBack, err := db.GenerateAddress()
+	if err != nil {
+		t.Fatalf("GenerateAddress: %v", err)
+	}
+	countBefore, err := repo.CountTransactions(ctx, db.TransactionFilter{})
+	if err != nil {
+		t.Fatalf("CountTransactions: %v", err)
+	}
+	errAbort := errors.New("abort")
+	err = repo.WithTx(ctx, func(tx db.TxRepository) error {
+		if err := tx.CreateWallet(rolledBack, dec("5"), models.WalletMetadata{}, ""); err != nil {
+			return err
+		}
+		if _, err := tx.AddBalance(funder, dec("-70")); err != nil {
+			return err
+		}
+		if err := tx.SetNonce(funder, 7); err != nil {
+			return err
+		}
+		if _, err := tx.RecordTransaction(models.TransactionTransfer, funder, rolledBack, dec("70"), "", ""); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("WithTx: got %v, want the error returned by fn", err)
+	}
+	if _, err := repo.GetBalance(ctx, rolledBack); !errors.Is(err, db.ErrWalletNotFound) {
+		t.Fatalf("rolled back wallet: got %v, want ErrWalletNotFound", err)
+	}
+	if got := balanceOf(t, repo, funder); !got.Equal(dec("70")) {
+		t.Fatalf("funder after rollback: got balance %s, want 70", got)
+	}
+	if nonce, err := repo.GetNonce(ctx, funder); err != nil || nonce != 0 {
+		t.Fatalf("funder nonce after rollback: got %d, %v, want 0", nonce, err)
+	}
+	if countAfter, err := repo.CountTransactions(ctx, db.TransactionFilter{}); err != nil || countAfter != countBefore {
+		t.Fatalf("CountTransactions after rollback: got %d, %v, want %d", countAfter, err, countBefore)
+	}
+
+	// Ошибки операций транзакции
+	err = repo.WithTx(ctx, func(tx db.TxRepository) error {
+		_, err := tx.AddBalance(rolledBack, dec("1"))
+		return err
+	})
+	if !errors.Is(err, db.ErrWalletNotFound) {
+		t.Fatalf("AddBalance of unknown wallet: got %v, want ErrWalletNotFound", err)
+	}
+
+	// Архивация с переносом метки на кошелек, созданный в той же транзакции
+	retired, err := db.GenerateAddress()
+	if err != nil {
+		t.Fatalf("GenerateAddress: %v", err)
+	}
+	label := "retire-" + retired[:8]
+	metadata := models.WalletMetadata{Label: label, Tags: map[string]string{"team": "ops"}}
+	if err := repo.CreateWallet(ctx, retired, dec("0"), metadata, ""); err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+	successor, err := db.GenerateAddress()
+	if err != nil {
+		t.Fatalf("GenerateAddress: %v", err)
+	}
+	err = repo.WithTx(ctx, func(tx db.TxRepository) error {
+		if _, err := tx.RetireWallet(funder); !errors.Is(err, db.ErrWalletNotEmpty) {
+			t.Errorf("RetireWallet with balance: got %v, want ErrWalletNotEmpty", err)
+		}
+		previous, err := tx.RetireWallet(retired)
+		if err != nil {
+			return err
+		}
+		if previous.Label != label || previous.Tags["team"] != "ops" {
+			t.Errorf("RetireWallet: got %+v, want the wallet before retirement", previous)
+		}
+		return tx.CreateWallet(successor, dec("0"), previous.WalletMetadata, "")
+	})
+	if err != nil {
+		t.Fatalf("WithTx with RetireWallet: %v", err)
+	}
+	if wallet, err := repo.GetWallet(ctx, retired); err != nil || wallet.ArchivedAt == nil || wallet.Label != "" {
+		t.Fatalf("retired wallet: got %+v, %v, want archived without label", wallet, err)
+	}
+	if wallet, err := repo.FindWalletByLabel(ctx, label); err != nil || wallet.Address != successor {
+		t.Fatalf("FindWalletByLabel after RetireWallet: got %+v, %v, want %s", wallet, err, successor)
+	}
+	err = repo.WithTx(ctx, func(tx db.TxRepository) error {
+		_, err := tx.RetireWallet(retired)
+		return err
+	})
+	if !errors.Is(err, db.ErrWalletArchived) {
+		t.Fatalf("RetireWallet of archived wallet: got %v, want ErrWalletArchived", err)
+	}
+}
+
+// testIdempotencyKeys проверяет, что ключ идемпотентности занимается один раз, хранит ответ,
+// освобождается только до сохранения ответа и удаляется по истечении срока.
+func testIdempotencyKeys(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	expired := time.Now().Add(-time.Hour)
+	if _, reserved, err := repo.ReserveIdempotencyKey(ctx, "key-1", "fp-1", expired); err != nil || !reserved {
+		t.Fatalf("ReserveIdempotencyKey: reserved %t, err %v", reserved, err)
+	}
+	stored, reserved, err := repo.ReserveIdempotencyKey(ctx, "key-1", "fp-2", expired)
+	if err != nil || reserved || stored.Status != 0 || stored.Fingerprint != "fp-1" {
+		t.Fatalf("ReserveIdempotencyKey in progress: got %+v, reserved %t, err %v", stored, reserved, err)
+	}
+
+	response := db.IdempotentResponse{Status: 201, ContentType: "application/json", Location: "/x", Body: `{"id":1}`}
+	if err := repo.CompleteIdempotencyKey(ctx, "key-1", response); err != nil {
+		t.Fatalf("CompleteIdempotencyKey: %v", err)
+	}
+	// Сохраненный ответ не освобождается
+	if err := repo.ReleaseIdempotencyKey(ctx, "key-1"); err != nil {
+		t.Fatalf("ReleaseIdempotencyKey: %v", err)
+	}
+	response.Fingerprint = "fp-1"
+	if stored, reserved, err := repo.ReserveIdempotencyKey(ctx, "key-1", "fp-1", expired); err != nil || reserved || stored != response {
+		t.Fatalf("ReserveIdempotencyKey after complete: got %+v, reserved %t, err %v, want %+v", stored, reserved, err, response)
+	}
+
+	// Освобожденный ключ занимается снова
+	if _, reserved, err := repo.ReserveIdempotencyKey(ctx, "key-2", "fp", expired); err != nil || !reserved {
+		t.Fatalf("ReserveIdempotencyKey key-2: reserved %t, err %v", reserved, err)
+	}
+	if err := repo.ReleaseIdempotencyKey(ctx, "key-2"); err != nil {
+		t.Fatalf("ReleaseIdempotencyKey key-2: %v", err)
+	}
+	if _, reserved, err := repo.ReserveIdempotencyKey(ctx, "key-2", "fp", expired); err != nil || !reserved {
+		t.Fatalf("ReserveIdempotencyKey after release: reserved %t, err %v", reserved, err)
+	}
+
+	// Истекший ключ удаляется, и запрос с ним выполняется заново
+	if _, reserved, err := repo.ReserveIdempotencyKey(ctx, "key-1", "fp-1", time.Now().Add(time.Minute)); err != nil || !reserved {
+		t.Fatalf("ReserveIdempotencyKey after expiry: reserved %t, err %v", reserved, err)
+	}
+}
+
+// testApprovalInTx проверяет выполнение отложенного перевода в транзакции WithTx, как
+// в service.ApproveTransfer: смена статуса и перевод фиксируются или откатываются вместе,
+// а резерв перевода, подтвержденного в той же транзакции, не мешает его выполнить.
+func testApprovalInTx(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	from := newWallet(t, repo, dec("100"))
+	to := newWallet(t, repo, dec("0"))
+	approval, err := repo.CreateApproval(ctx, models.Approval{From: from, To: to, Amount: dec("60")})
+	if err != nil {
+		t.Fatalf("CreateApproval: %v", err)
+	}
+	execute := func(tx db.TxRepository) error {
+		if _, err := tx.UpdateApprovalStatus(approval.ID, models.ApprovalAwaitingReview, models.ApprovalApproved, ""); err != nil {
+			return err
+		}
+		if _, err := tx.Send(from, to, dec("60"), "", "", 0); err != nil {
+			return err
+		}
+		_, err := tx.UpdateApprovalStatus(approval.ID, models.ApprovalApproved, models.ApprovalExecuted, "")
+		return err
+	}
+
+	// Ошибка после перевода откатывает и перевод, и смену статуса
+	errAbort := errors.New("abort")
+	err = repo.WithTx(ctx, func(tx db.TxRepository) error {
+		if err := execute(tx); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("WithTx: got %v, want the error returned by fn", err)
+	}
+	if got, err := repo.GetApproval(ctx, approval.ID); err != nil || got.Status != models.ApprovalAwaitingReview {
+		t.Fatalf("approval after rollback: got %+v, %v, want awaiting_review", got, err)
+	}
+	if got := balanceOf(t, repo, from); !got.Equal(dec("100")) {
+		t.Fatalf("sender after rollback: got balance %s, want 100", got)
+	}
+
+	if err := repo.WithTx(ctx, execute); err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+	if got, err := repo.GetApproval(ctx, approval.ID); err != nil || got.Status != models.ApprovalExecuted || got.DecidedAt == nil {
+		t.Fatalf("approval after commit: got %+v, %v, want executed", got, err)
+	}
+	if got := balanceOf(t, repo, from); !got.Equal(dec("40")) {
+		t.Fatalf("sender after commit: got balance %s, want 40", got)
+	}
+	if got := balanceOf(t, repo, to); !got.Equal(dec("60")) {
+		t.Fatalf("receiver after commit: got balance %s, want 60", got)
+	}
+
+	// Повторное выполнение того же перевода отклоняется по статусу
+	if err := repo.WithTx(ctx, execute); !errors.Is(err, db.ErrApprovalStatus) {
+		t.Fatalf("WithTx of executed approval: got %v, want ErrApprovalStatus", err)
+	}
+}
+
+// testConcurrentSeedLock проверяет, что проверка наличия кошельков и их создание в WithSeedLock
+// атомарны: из одновременных транзакций кошелек создает только одна.
+func testConcurrentSeedLock(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	const seeders = 8
+	var wg sync.WaitGroup
+	created := make(chan bool, seeders)
+	for i := 0; i < seeders; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			seeded := false
+			err := repo.WithSeedLock(ctx, func(tx db.TxRepository) error {
+				seeded = false
+				exists, err := tx.HasWallets()
+				if err != nil || exists {
+					return err
+				}
+				address, err := db.GenerateAddress()
+				if err != nil {
+					return err
+				}
+				seeded = true
+				return tx.CreateWallet(address, dec("100"), models.WalletMetadata{}, "")
+			})
+			if err != nil {
+				t.Errorf("WithSeedLock: %v", err)
+			}
+			created <- seeded
+		}()
+	}
+	wg.Wait()
+	close(created)
+
+	count := 0
+	for seeded := range created {
+		if seeded {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Fatalf("concurrent seeding: %d transactions created a wallet, want 1", count)
+	}
+	if exists, err := repo.HasWallets(ctx); err != nil || !exists {
+		t.Fatalf("HasWallets after seeding: got %v, %v, want true", exists, err)
+	}
+}
+
+func testNotifications(t *testing.T, repo db.Repository) {
+	ctx := context.Background()
+	sender, err := db.GenerateAddress()
+	if err != nil {
+		t.Fatalf("GenerateAddress: %v", err)
+	}
+	label := "payroll-" + sender[:8]
+	if err := repo.CreateWallet(ctx, sender, dec("100"), models.WalletMetadata{Label: label}, ""); err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+	subscriber, err := db.GenerateAddress()
+	if err != nil {
+		t.Fatalf("GenerateAddress: %v", err)
+	}
+	const email = "alice@example.com"
+	if err := repo.CreateWallet(ctx, subscriber, dec("0"), models.WalletMetadata{NotifyEmail: email}, ""); err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+	if wallet, err := repo.GetWallet(ctx, subscriber); err != nil || wallet.NotifyEmail != email {
+		t.Fatalf("GetWallet: got %+v, %v, want notify email %q", wallet, err, email)
+	}
+	silent := newWallet(t, repo, dec("0"))
+
+	// Уведомление ставится только получателю с адресом для уведомлений и только при
+	// выполненном переводе; операции с системными счетами уведомлений не создают
+	if _, err := repo.Send(ctx, sender, silent, dec("1"), "", "", 0, sql.LevelDefault); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	result, err := repo.Send(ctx, sender, subscriber, dec("5"), "", "", 0, sql.LevelDefault)
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if _, err := repo.Send(ctx, sender, subscriber, dec("1000"), "", "", 0, sql.LevelDefault); !errors.Is(err, errs.ErrInsufficientFunds) {
+		t.Fatalf("Send more than the balance: got %v, want ErrInsufficientFunds", err)
+	}
+	if _, err := repo.SystemTransfer(ctx, models.TransactionMint, subscriber, dec("1"), ""); err != nil {
+		t.Fatalf("SystemTransfer: %v", err)
+	}
+
+	notifications, err := repo.GetNotifications(ctx, "", 10)
+	if err != nil || len(notifications) != 1 {
+		t.Fatalf("GetNotifications: got %+v, %v, want one notification", notifications, err)
+	}
+	n := notifications[0]
+	if n.TransactionID != result.TransactionID || n.Address != subscriber || n.Email != email || n.From != sender ||
+		n.FromLabel != label || !n.Amount.Equal(dec("5")) || !n.TransferredAt.Equal(result.CreatedAt) ||
+		n.Status != models.NotificationPending || n.Attempts != 0 || n.CompletedAt != nil {
+		t.Fatalf("queued notification: got %+v", n)
+	}
+
+	// Выбранное уведомление не выбирается снова, пока не истечет lease
+	now := time.Now()
+	claimed, err := repo.ClaimNotifications(ctx, now, time.Minute, 10)
+	if err != nil || len(claimed) != 1 || claimed[0].ID != n.ID || claimed[0].Attempts != 1 {
+		t.Fatalf("ClaimNotifications: got %+v, %v", claimed, err)
+	}
+	if claimed, err := repo.ClaimNotifications(ctx, now, time.Minute, 10); err != nil || len(claimed) != 0 {
+		t.Fatalf("ClaimNotifications during the lease: got %+v, %v, want none", claimed, err)
+	}
+	if claimed, err = repo.ClaimNotifications(ctx, now.Add(2*time.Minute), time.Minute, 10); err != nil || len(claimed) != 1 || claimed[0].Attempts != 2 {
+		t.Fatalf("ClaimNotifications after the lease: got %+v, %v", claimed, err)
+	}
+
+	// Неудачная попытка назначает следующую на указанное время
+	if err := repo.CompleteNotification(ctx, n.ID, models.NotificationPending, "connection refused", now.Add(10*time.Minute)); err != nil {
+		t.Fatalf("CompleteNotification: %v", err)
+	}
+	if claimed, err := repo.ClaimNotifications(ctx, now.Add(5*time.Minute), time.Minute, 10); err != nil || len(claimed) != 0 {
+		t.Fatalf("ClaimNotifications before the retry: got %+v, %v, want none", claimed, err)
+	}
+	claimed, err = repo.ClaimNotifications(ctx, now.Add(10*time.Minute), time.Minute, 10)
+	if err != nil || len(claimed) != 1 || claimed[0].Attempts != 3 || claimed[0].LastError != "connection refused" {
+		t.Fatalf("ClaimNotifications at the retry: got %+v, %v", claimed, err)
+	}
+	if err := repo.CompleteNotification(ctx, n.ID, models.NotificationSent, "", now.Add(10*time.Minute)); err != nil {
+		t.Fatalf("CompleteNotification: %v", err)
+	}
+	sent, err := repo.GetNotifications(ctx, models.NotificationSent, 10)
+	if err != nil || len(sent) != 1 || sent[0].CompletedAt == nil || sent[0].LastError != "" || sent[0].Attempts != 3 {
+		t.Fatalf("GetNotifications(sent): got %+v, %v", sent, err)
+	}
+	if claimed, err := repo.ClaimNotifications(ctx, now.Add(time.Hour), time.Minute, 10); err != nil || len(claimed) != 0 {
+		t.Fatalf("ClaimNotifications after delivery: got %+v, %v, want none", claimed, err)
+	}
+
+	// Пустой адрес отключает уведомления
+	empty := ""
+	if _, err := repo.UpdateWalletMetadata(ctx, subscriber, models.WalletMetadataPatch{NotifyEmail: &empty}); err != nil {
+		t.Fatalf("UpdateWalletMetadata: %v", err)
+	}
+	if _, err := repo.Send(ctx, sender, subscriber, dec("1"), "", "", 0, sql.LevelDefault); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if notifications, err := repo.GetNotifications(ctx, "", 10); err != nil || len(notifications) != 1 {
+		t.Fatalf("GetNotifications after opting out: got %d notifications, %v, want 1", len(notifications), err)
+	}
+}
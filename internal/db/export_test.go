@@ -0,0 +1,17 @@
+package db
+
+import "testing"
+
+// NewTestPostgresRepository создает PostgresRepository для проверки контракта: очищает
+// таблицы базы, заданной DB_HOST, DB_USER, DB_PASSWORD и DB_NAME, и закрывает подключения
+// после проверки. База должна быть отдельной тестовой базой.
+func NewTestPostgresRepository(t testing.TB) *PostgresRepository {
+	t.Helper()
+	r := NewPostgresRepository()
+	t.Cleanup(func() { r.db.Close() })
+	if _, err := r.db.Exec(`TRUNCATE wallets, transactions, risk_events, pending_approvals,
+		transactions_archive, audit_log, idempotency_keys, notifications RESTART IDENTITY`); err != nil {
+		t.Fatalf("truncate tables: %v", err)
+	}
+	return r
+}
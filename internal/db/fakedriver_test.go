@@ -0,0 +1,147 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// scriptedDriver - драйвер database/sql, ответы которого задает тест: так PostgresRepository
+// проверяется на ошибках, которые с живой базой воспроизвести трудно (взаимоблокировка,
+// обрыв подключения при перезапуске сервера). Запросы не разбираются: ответ выбирают функции
+// exec, query и commit по тексту запроса и номеру вызова.
+type scriptedDriver struct {
+	mu      sync.Mutex
+	exec    func(query string, call int) error                               // nil - запрос выполнен
+	query   func(query string, call int) ([]string, [][]driver.Value, error) // Столбцы и строки ответа
+	commit  func(call int) error                                             // nil - транзакция зафиксирована
+	calls   map[string]int                                                   // Количество вызовов по тексту запроса
+	begins  int
+	commits int
+}
+
+// newScriptedPostgres возвращает PostgresRepository поверх scriptedDriver с sendAttempts попытками
+// перевода. Пустой ответ на запрос - ни одной строки.
+func newScriptedPostgres(t *testing.T, d *scriptedDriver, sendAttempts int) *PostgresRepository {
+	t.Helper()
+	d.calls = make(map[string]int)
+	conn := sql.OpenDB(d)
+	t.Cleanup(func() { conn.Close() })
+	return &PostgresRepository{
+		db:            conn,
+		queryTimeout:  time.Second,
+		sendAttempts:  sendAttempts,
+		lockStrategy:  lockRow,
+		sendIsolation: sql.LevelReadCommitted,
+		minBalance:    decimal.Zero,
+		clock:         SystemClock{},
+		addresses:     DefaultAddressGenerator,
+	}
+}
+
+// call учитывает вызов запроса и возвращает его номер, начиная с 1.
+func (d *scriptedDriver) call(query string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.calls[query]++
+	return d.calls[query]
+}
+
+// callCount возвращает количество вызовов запроса query.
+func (d *scriptedDriver) callCount(query string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.calls[query]
+}
+
+func (d *scriptedDriver) Connect(context.Context) (driver.Conn, error) {
+	return &scriptedConn{d: d}, nil
+}
+func (d *scriptedDriver) Driver() driver.Driver            { return d }
+func (d *scriptedDriver) Open(string) (driver.Conn, error) { return &scriptedConn{d: d}, nil }
+
+// scriptedConn - подключение scriptedDriver.
+type scriptedConn struct {
+	d *scriptedDriver
+}
+
+func (c *scriptedConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("scripted driver: prepared statements are not supported")
+}
+func (c *scriptedConn) Close() error { return nil }
+func (c *scriptedConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *scriptedConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.d.mu.Lock()
+	c.d.begins++
+	c.d.mu.Unlock()
+	return &scriptedTx{d: c.d}, nil
+}
+
+func (c *scriptedConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	call := c.d.call(query)
+	if c.d.exec != nil {
+		if err := c.d.exec(query, call); err != nil {
+			return nil, err
+		}
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *scriptedConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	call := c.d.call(query)
+	var columns []string
+	var rows [][]driver.Value
+	if c.d.query != nil {
+		var err error
+		if columns, rows, err = c.d.query(query, call); err != nil {
+			return nil, err
+		}
+	}
+	return &scriptedRows{columns: columns, rows: rows}, nil
+}
+
+// scriptedTx - транзакция scriptedDriver.
+type scriptedTx struct {
+	d *scriptedDriver
+}
+
+func (tx *scriptedTx) Commit() error {
+	tx.d.mu.Lock()
+	tx.d.commits++
+	call := tx.d.commits
+	tx.d.mu.Unlock()
+	if tx.d.commit != nil {
+		return tx.d.commit(call)
+	}
+	return nil
+}
+
+func (tx *scriptedTx) Rollback() error { return nil }
+
+// scriptedRows - ответ на запрос scriptedDriver.
+type scriptedRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *scriptedRows) Columns() []string { return r.columns }
+func (r *scriptedRows) Close() error      { return nil }
+
+func (r *scriptedRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -0,0 +1,244 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"payment-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// lastTransactionsOrder - порядок списка транзакций во всех реализациях: от новых к старым,
+// а при равном времени - по id. Без id транзакции с одинаковым временем (например,
+// импортированные одной пачкой) возвращались бы в произвольном порядке, и постраничный
+// обход по TransactionCursor пропускал бы или повторял их.
+const lastTransactionsOrder = "timestamp DESC, id DESC"
+
+// TransactionFilter - условия выборки транзакций для GetLastTransactions и CountTransactions.
+// Нулевое значение выбирает все транзакции.
+type TransactionFilter struct {
+	Amount          *decimal.Decimal   // Точная сумма перевода
+	Category        string             // Категория перевода; пустая строка - любая, в том числе без категории
+	IncludeArchived bool               // Искать и среди перенесенных в архив (см. PurgeTransactions)
+	After           *TransactionCursor // Только транзакции, идущие в списке после этой позиции
+}
+
+// TransactionCursor - позиция в списке транзакций: время и id последней транзакции
+// полученной страницы. Следующая страница (TransactionFilter.After) начинается строго
+// после нее, поэтому транзакции, записанные во время обхода, не сдвигают страницы:
+// ни одна транзакция не пропускается и не повторяется.
+type TransactionCursor struct {
+	CreatedAt time.Time
+	ID        int
+}
+
+// CursorOf возвращает позицию транзакции t в списке.
+func CursorOf(t models.Transaction) TransactionCursor {
+	return TransactionCursor{CreatedAt: t.CreatedAt, ID: t.ID}
+}
+
+// Empty сообщает, что фильтр не задает условий и выбирает все транзакции.
+func (f TransactionFilter) Empty() bool {
+	return f.Amount == nil && f.Category == "" && !f.IncludeArchived && f.After == nil
+}
+
+// Matches сообщает, удовлетворяет ли транзакция фильтру (IncludeArchived выбирает
+// источник транзакций и здесь не проверяется).
+// Используется реализациями, которые фильтруют транзакции без SQL.
+func (f TransactionFilter) Matches(t models.Transaction) bool {
+	if f.Amount != nil && !t.Amount.Equal(*f.Amount) {
+		return false
+	}
+	if f.Category != "" && t.Category != f.Category {
+		return false
+	}
+	if f.After != nil {
+		after := f.After.CreatedAt
+		if t.CreatedAt.After(after) || t.CreatedAt.Equal(after) && t.ID >= f.After.ID {
+			return false
+		}
+	}
+	return true
+}
+
+// timeArg преобразует момент в параметр запроса конкретной базы: PostgreSQL принимает
+// time.Time, а SQLite сравнивает время как текст в формате sqliteTime.
+type timeArg func(time.Time) interface{}
+
+// postgresTimeArg передает момент PostgreSQL без изменений.
+func postgresTimeArg(t time.Time) interface{} {
+	return t
+}
+
+// sqliteTimeArg передает момент SQLite в формате хранения (см. sqliteTime).
+func sqliteTimeArg(t time.Time) interface{} {
+	return sqliteTime(t)
+}
+
+// where строит условие WHERE для фильтра с параметрами $1, $2, ...
+// Синтаксис совместим с PostgreSQL и SQLite. Суммы сравниваются на равенство: PostgreSQL
+// сравнивает NUMERIC, SQLite - текст, который для одной суммы всегда одинаков (decimal.Decimal.String()).
+//
+// Параметры:
+//   - toTime: Преобразование времени курсора в параметр запроса базы.
+//
+// Возвращает:
+//   - Условие, начинающееся с " WHERE ", или пустую строку, если фильтр пуст.
+//   - Значения параметров условия.
+func (f TransactionFilter) where(toTime timeArg) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if f.Amount != nil {
+		args = append(args, *f.Amount)
+		conditions = append(conditions, fmt.Sprintf("amount = $%d", len(args)))
+	}
+	if f.Category != "" {
+		args = append(args, f.Category)
+		conditions = append(conditions, fmt.Sprintf("category = $%d", len(args)))
+	}
+	// Позиция в порядке lastTransactionsOrder; сравнение строк (timestamp, id) поддерживают
+	// и PostgreSQL, и SQLite
+	if f.After != nil {
+		args = append(args, toTime(f.After.CreatedAt), f.After.ID)
+		conditions = append(conditions, fmt.Sprintf("(timestamp, id) < ($%d, $%d)", len(args)-1, len(args)))
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// lastTransactionsQuery строит запрос последних count транзакций, удовлетворяющих фильтру,
+// в порядке lastTransactionsOrder.
+//
+// Параметры:
+//   - filter: Условия выборки.
+//   - toTime: Преобразование времени в параметр запроса базы.
+//   - count: Максимальное количество транзакций.
+//
+// Возвращает:
+//   - Текст запроса и значения его параметров.
+func lastTransactionsQuery(filter TransactionFilter, toTime timeArg, count int) (string, []interface{}) {
+	where, args := filter.where(toTime)
+	args = append(args, count)
+	query := "SELECT id, from_address, to_address, amount, timestamp, COALESCE(memo, ''), COALESCE(category, ''), COALESCE(external_id, ''), imported, type FROM " +
+		filter.source() + where + fmt.Sprintf(" ORDER BY %s LIMIT $%d", lastTransactionsOrder, len(args))
+	return query, args
+}
+
+// walletTransactionsQuery строит запрос последних count транзакций каждого из кошельков addresses
+// в порядке кошелька и lastTransactionsOrder. Первый столбец - кошелек, к которому относится
+// строка; перевод между двумя запрошенными кошельками возвращается для каждого из них.
+// Синтаксис (оконная функция ROW_NUMBER) совместим с PostgreSQL и SQLite.
+//
+// Параметры:
+//   - addresses: Адреса кошельков (не пустой список).
+//   - count: Максимальное количество транзакций одного кошелька.
+//
+// Возвращает:
+//   - Текст запроса и значения его параметров.
+func walletTransactionsQuery(addresses []string, count int) (string, []interface{}) {
+	placeholders := make([]string, len(addresses))
+	args := make([]interface{}, 0, len(addresses)+1)
+	for i, address := range addresses {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args = append(args, address)
+	}
+	args = append(args, count)
+	in := strings.Join(placeholders, ", ")
+
+	columns := "id, from_address, to_address, amount, timestamp, COALESCE(memo, '') AS memo, COALESCE(category, '') AS category, " +
+		"COALESCE(external_id, '') AS external_id, imported, type"
+	query := `SELECT party, id, from_address, to_address, amount, timestamp, memo, category, external_id, imported, type FROM (
+		SELECT parties.*, ROW_NUMBER() OVER (PARTITION BY party ORDER BY ` + lastTransactionsOrder + `) AS n FROM (
+			SELECT from_address AS party, ` + columns + ` FROM transactions WHERE from_address IN (` + in + `)
+			UNION ALL
+			SELECT to_address AS party, ` + columns + ` FROM transactions WHERE to_address IN (` + in + `) AND to_address <> from_address
+		) AS parties
+	) AS ranked WHERE n <= $` + fmt.Sprint(len(args)) + ` ORDER BY party, ` + lastTransactionsOrder
+	return query, args
+}
+
+// scanWalletTransactions читает строки результата walletTransactionsQuery по кошелькам
+// со временем в UTC и закрывает rows.
+func scanWalletTransactions(rows *sql.Rows) (map[string][]models.Transaction, error) {
+	defer rows.Close()
+
+	transactions := make(map[string][]models.Transaction)
+	for rows.Next() {
+		var party string
+		var t models.Transaction
+		err := rows.Scan(&party, &t.ID, &t.From, &t.To, &t.Amount, &t.CreatedAt, &t.Memo, &t.Category, &t.ExternalID, &t.Imported, &t.Type)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		t.CreatedAt = t.CreatedAt.UTC()
+		transactions[party] = append(transactions[party], t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return transactions, nil
+}
+
+// maxPreallocatedTransactions - наибольшая емкость, выделяемая под результат GetLastTransactions
+// заранее: при большом count и малом числе подходящих транзакций память не тратится впустую.
+const maxPreallocatedTransactions = 1000
+
+// transactionsCapacity возвращает емкость среза для не более чем count транзакций.
+func transactionsCapacity(count int) int {
+	return max(0, min(count, maxPreallocatedTransactions))
+}
+
+// scanTransactions дописывает к transactions строки результата lastTransactionsQuery, считывая
+// каждую строку прямо в элемент среза, со временем в UTC. Передав transactions[:0], можно
+// повторно использовать уже выделенный срез.
+//
+// Возвращает:
+//   - Дополненный список транзакций.
+//   - Ошибку чтения строки или результата.
+func scanTransactions(rows *sql.Rows, transactions []models.Transaction) ([]models.Transaction, error) {
+	for rows.Next() {
+		transactions = append(transactions, models.Transaction{})
+		t := &transactions[len(transactions)-1]
+		err := rows.Scan(&t.ID, &t.From, &t.To, &t.Amount, &t.CreatedAt, &t.Memo, &t.Category, &t.ExternalID, &t.Imported, &t.Type)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		t.CreatedAt = t.CreatedAt.UTC()
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return transactions, nil
+}
+
+// countTransactionsQuery строит запрос количества транзакций, удовлетворяющих фильтру.
+//
+// Параметры:
+//   - filter: Условия выборки.
+//   - toTime: Преобразование времени в параметр запроса базы.
+//
+// Возвращает:
+//   - Текст запроса и значения его параметров.
+func countTransactionsQuery(filter TransactionFilter, toTime timeArg) (string, []interface{}) {
+	where, args := filter.where(toTime)
+	return "SELECT COUNT(*) FROM " + filter.source() + where, args
+}
+
+// source возвращает таблицу, из которой выбираются транзакции: transactions или, если
+// IncludeArchived, ее объединение с transactions_archive под тем же именем.
+func (f TransactionFilter) source() string {
+	if !f.IncludeArchived {
+		return "transactions"
+	}
+	// Архив хранит исходные id, а новые транзакции их не переиспользуют,
+	// поэтому порядок по времени и id сохраняется
+	return "(SELECT " + archiveColumns + " FROM transactions UNION ALL SELECT " + archiveColumns +
+		" FROM transactions_archive) AS transactions"
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"payment-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestTransactionFilterMatches(t *testing.T) {
+	amount := func(s string) *decimal.Decimal {
+		d := decimal.RequireFromString(s)
+		return &d
+	}
+	at := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	tx := models.Transaction{ID: 7, Amount: decimal.RequireFromString("10.5"), Category: "payroll", CreatedAt: at}
+
+	tests := []struct {
+		name   string
+		filter TransactionFilter
+		want   bool
+	}{
+		{"empty", TransactionFilter{}, true},
+		{"exact amount", TransactionFilter{Amount: amount("10.5")}, true},
+		{"trailing zeros", TransactionFilter{Amount: amount("10.50000000")}, true},
+		{"one unit above", TransactionFilter{Amount: amount("10.50000001")}, false},
+		{"one unit below", TransactionFilter{Amount: amount("10.49999999")}, false},
+		{"category", TransactionFilter{Category: "payroll"}, true},
+		{"other category", TransactionFilter{Category: "refund"}, false},
+		{"amount and other category", TransactionFilter{Amount: amount("10.5"), Category: "refund"}, false},
+		{"after newer", TransactionFilter{After: &TransactionCursor{CreatedAt: at.Add(time.Second), ID: 1}}, true},
+		{"after same time, higher id", TransactionFilter{After: &TransactionCursor{CreatedAt: at, ID: 8}}, true},
+		{"after itself", TransactionFilter{After: &TransactionCursor{CreatedAt: at, ID: 7}}, false},
+		{"after older", TransactionFilter{After: &TransactionCursor{CreatedAt: at.Add(-time.Second), ID: 100}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(tx); got != tt.want {
+				t.Errorf("Matches() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestTransactionFilterWhere проверяет условие WHERE, которое строит фильтр: параметры
+// нумеруются по порядку условий, а пустой фильтр не добавляет условия.
+func TestTransactionFilterWhere(t *testing.T) {
+	amount := decimal.RequireFromString("10.5")
+	at := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	after := &TransactionCursor{CreatedAt: at, ID: 7}
+
+	tests := []struct {
+		name      string
+		filter    TransactionFilter
+		wantWhere string
+		wantArgs  []interface{}
+	}{
+		{"empty", TransactionFilter{}, "", nil},
+		{"archived only", TransactionFilter{IncludeArchived: true}, "", nil},
+		{"amount", TransactionFilter{Amount: &amount}, " WHERE amount = $1", []interface{}{amount}},
+		{"category", TransactionFilter{Category: "salary"}, " WHERE category = $1", []interface{}{"salary"}},
+		{"amount and category", TransactionFilter{Amount: &amount, Category: "salary"},
+			" WHERE amount = $1 AND category = $2", []interface{}{amount, "salary"}},
+		{"category and cursor", TransactionFilter{Category: "fee", After: after},
+			" WHERE category = $1 AND (timestamp, id) < ($2, $3)", []interface{}{"fee", at, 7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args := tt.filter.where(postgresTimeArg)
+			if where != tt.wantWhere {
+				t.Errorf("where = %q, want %q", where, tt.wantWhere)
+			}
+			if !slices.EqualFunc(args, tt.wantArgs, func(got, want interface{}) bool {
+				if d, ok := want.(decimal.Decimal); ok {
+					return d.Equal(got.(decimal.Decimal))
+				}
+				return got == want
+			}) {
+				t.Errorf("args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// IdempotentResponse - ответ на запрос с ключом идемпотентности (заголовок Idempotency-Key),
+// сохраненный, чтобы повтор запроса получил тот же ответ, а не выполнил перевод еще раз.
+type IdempotentResponse struct {
+	Fingerprint string // Отпечаток запроса; повтор ключа с другим запросом отклоняется
+	Status      int    // Код ответа; 0 - запрос с этим ключом еще выполняется
+	ContentType string // Заголовок Content-Type ответа
+	Location    string // Заголовок Location ответа (ссылка на статус отложенного перевода)
+	Body        string // Тело ответа
+}
+
+// Ниже - запросы к таблице idempotency_keys, общие для PostgreSQL и SQLite.
+// Моменты времени передаются в виде, сравнимом со столбцами времени конкретной базы
+// (см. approvals.go).
+
+// reserveIdempotencyKey занимает ключ для выполняющегося запроса. Ключи, занятые раньше
+// expiredBefore, предварительно удаляются: повтор после срока хранения выполняется заново.
+//
+// Возвращает:
+//   - Сохраненный ответ и false, если ключ уже занят (Status = 0 - запрос еще выполняется).
+//   - true, если ключ занят этим вызовом.
+func reserveIdempotencyKey(ctx context.Context, db *sql.DB, key, fingerprint string, now, expiredBefore interface{}) (IdempotentResponse, bool, error) {
+	if _, err := db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE created_at < $1", expiredBefore); err != nil {
+		return IdempotentResponse{}, false, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+
+	res, err := db.ExecContext(ctx, `
+		INSERT INTO idempotency_keys (idempotency_key, fingerprint, created_at) VALUES ($1, $2, $3)
+		ON CONFLICT (idempotency_key) DO NOTHING`, key, fingerprint, now)
+	if err != nil {
+		return IdempotentResponse{}, false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return IdempotentResponse{}, false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	} else if n == 1 {
+		return IdempotentResponse{}, true, nil
+	}
+
+	var stored IdempotentResponse
+	err = db.QueryRowContext(ctx,
+		"SELECT fingerprint, status, content_type, location, body FROM idempotency_keys WHERE idempotency_key = $1", key).
+		Scan(&stored.Fingerprint, &stored.Status, &stored.ContentType, &stored.Location, &stored.Body)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Ключ освобожден между вставкой и чтением: запрос с ним только что завершился ошибкой
+		// и может быть повторен; до повтора он считается выполняющимся
+		return IdempotentResponse{Fingerprint: fingerprint}, false, nil
+	}
+	if err != nil {
+		return IdempotentResponse{}, false, fmt.Errorf("failed to read idempotency key: %w", err)
+	}
+	return stored, false, nil
+}
+
+// completeIdempotencyKey сохраняет ответ на запрос с занятым ключом.
+func completeIdempotencyKey(ctx context.Context, db *sql.DB, key string, response IdempotentResponse) error {
+	_, err := db.ExecContext(ctx, `
+		UPDATE idempotency_keys SET status = $2, content_type = $3, location = $4, body = $5
+		WHERE idempotency_key = $1`,
+		key, response.Status, response.ContentType, response.Location, response.Body)
+	if err != nil {
+		return fmt.Errorf("failed to save idempotent response: %w", err)
+	}
+	return nil
+}
+
+// releaseIdempotencyKey освобождает ключ запроса, завершившегося без сохраненного ответа.
+func releaseIdempotencyKey(ctx context.Context, db *sql.DB, key string) error {
+	if _, err := db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE idempotency_key = $1 AND status = 0", key); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newSeededSQLite создает базу SQLite с count переводами между wallets кошельками
+// (адреса "w0", "w1", ...) с шагом в секунду; при archived такие же строки с другими id
+// попадают и в transactions_archive.
+func newSeededSQLite(tb testing.TB, count, wallets int, archived bool) *SQLiteRepository {
+	tb.Helper()
+	r := NewSQLiteRepository(filepath.Join(tb.TempDir(), "payment-system.db"))
+	tb.Cleanup(func() { r.db.Close() })
+
+	seed := func(table string, offset int) {
+		_, err := r.db.Exec(`WITH RECURSIVE seq(n) AS (SELECT 0 UNION ALL SELECT n + 1 FROM seq WHERE n + 1 < $1)
+			INSERT INTO `+table+` (id, from_address, to_address, amount, timestamp)
+			SELECT n + 1 + $2, 'w' || (n % $3), 'w' || ((n + 1) % $3), '1',
+				strftime('%Y-%m-%d %H:%M:%f', '2024-01-01', '+' || n || ' seconds') FROM seq`,
+			count, offset, wallets)
+		if err != nil {
+			tb.Fatalf("seed %s: %v", table, err)
+		}
+	}
+	seed("transactions", count)
+	if archived {
+		seed("transactions_archive", 0)
+	}
+	if _, err := r.db.Exec("ANALYZE"); err != nil {
+		tb.Fatalf("analyze: %v", err)
+	}
+	return r
+}
+
+// queryPlan возвращает строки EXPLAIN QUERY PLAN запроса через "; ".
+func queryPlan(t *testing.T, r *SQLiteRepository, query string, args ...interface{}) string {
+	t.Helper()
+	rows, err := r.db.Query("EXPLAIN QUERY PLAN "+query, args...)
+	if err != nil {
+		t.Fatalf("explain: %v", err)
+	}
+	defer rows.Close()
+
+	var steps []string
+	for rows.Next() {
+		var id, parent, notUsed int
+		var detail string
+		if err := rows.Scan(&id, &parent, &notUsed, &detail); err != nil {
+			t.Fatalf("scan plan: %v", err)
+		}
+		steps = append(steps, detail)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("plan rows: %v", err)
+	}
+	return strings.Join(steps, "; ")
+}
+
+// TestSQLiteTransactionIndexes проверяет по плану запроса, что выборки транзакций идут
+// по индексам, а не полным просмотром таблицы с сортировкой.
+func TestSQLiteTransactionIndexes(t *testing.T) {
+	r := newSeededSQLite(t, 2000, 50, true)
+	last := func(filter TransactionFilter) (string, []interface{}) {
+		return lastTransactionsQuery(filter, sqliteTimeArg, 20)
+	}
+	wallet := func(column string) (string, []interface{}) {
+		return "SELECT id FROM transactions WHERE " + column + " = $1 ORDER BY timestamp DESC LIMIT 20", []interface{}{"w7"}
+	}
+
+	tests := []struct {
+		name  string
+		query func() (string, []interface{})
+		want  []string
+	}{
+		{"last transactions", func() (string, []interface{}) { return last(TransactionFilter{}) },
+			[]string{"transactions_timestamp_idx"}},
+		{"last transactions with archive", func() (string, []interface{}) { return last(TransactionFilter{IncludeArchived: true}) },
+			[]string{"transactions_timestamp_idx", "transactions_archive_timestamp_idx"}},
+		{"by sender", func() (string, []interface{}) { return wallet("from_address") },
+			[]string{"transactions_from_address_idx"}},
+		{"by recipient", func() (string, []interface{}) { return wallet("to_address") },
+			[]string{"transactions_to_address_timestamp_idx"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args := tt.query()
+			plan := queryPlan(t, r, query, args...)
+			for _, index := range tt.want {
+				if !strings.Contains(plan, "INDEX "+index) {
+					t.Errorf("plan does not use %s: %s", index, plan)
+				}
+			}
+			if strings.Contains(plan, "TEMP B-TREE") {
+				t.Errorf("plan sorts rows instead of reading them in index order: %s", plan)
+			}
+		})
+	}
+}
+
+// BenchmarkSQLiteLastTransactions измеряет выборку последних 20 транзакций из заполненной
+// базы; с индексом по timestamp время не растет с размером таблицы:
+//
+//	go test ./internal/db -run '^$' -bench SQLiteLastTransactions
+func BenchmarkSQLiteLastTransactions(b *testing.B) {
+	ctx := context.Background()
+	for _, rows := range []int{10_000, 100_000} {
+		r := newSeededSQLite(b, rows, 100, true)
+		for _, archived := range []bool{false, true} {
+			b.Run(fmt.Sprintf("rows=%d/archived=%t", rows, archived), func(b *testing.B) {
+				filter := TransactionFilter{IncludeArchived: archived}
+				for i := 0; i < b.N; i++ {
+					if _, err := r.GetLastTransactions(ctx, 20, filter); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// Запросы истории кошелька, которые сравнивает BenchmarkSQLiteWalletHistory: условие OR по двум
+// столбцам и объединение двух запросов, каждый из которых читает свой индекс (адрес, timestamp)
+// и останавливается на LIMIT. Выбран второй (см. комментарий к индексам в initSQLiteTables).
+const (
+	walletHistoryOr = `SELECT id FROM transactions WHERE from_address = $1 OR to_address = $1
+		ORDER BY timestamp DESC LIMIT $2`
+	walletHistoryUnion = `SELECT id FROM (
+			SELECT id, timestamp FROM (SELECT id, timestamp FROM transactions WHERE from_address = $1 ORDER BY timestamp DESC LIMIT $2)
+			UNION ALL
+			SELECT id, timestamp FROM (SELECT id, timestamp FROM transactions WHERE to_address = $1 ORDER BY timestamp DESC LIMIT $2)
+		) ORDER BY timestamp DESC LIMIT $2`
+)
+
+// newWalletHistorySQLite заполняет базу для сравнения запросов истории: кошелек "hot"
+// получает каждый 50-й перевод (2%), остальные кошельки - по нескольку переводов.
+func newWalletHistorySQLite(tb testing.TB, rows int) *SQLiteRepository {
+	tb.Helper()
+	r := newSeededSQLite(tb, rows, rows/10, false)
+	if _, err := r.db.Exec("UPDATE transactions SET to_address = 'hot' WHERE id % 50 = 0"); err != nil {
+		tb.Fatalf("seed hot wallet: %v", err)
+	}
+	if _, err := r.db.Exec("ANALYZE"); err != nil {
+		tb.Fatalf("analyze: %v", err)
+	}
+	return r
+}
+
+// TestSQLiteWalletHistoryPlan проверяет, что обе части объединения читают индексы
+// в порядке времени без сортировки всех переводов кошелька.
+func TestSQLiteWalletHistoryPlan(t *testing.T) {
+	r := newWalletHistorySQLite(t, 2000)
+	plan := queryPlan(t, r, walletHistoryUnion, "hot", 20)
+	for _, index := range []string{"transactions_from_address_idx", "transactions_to_address_timestamp_idx"} {
+		if !strings.Contains(plan, "INDEX "+index) {
+			t.Errorf("plan does not use %s: %s", index, plan)
+		}
+	}
+	// Сортируется только объединение не более чем 2*LIMIT строк
+	if n := strings.Count(plan, "TEMP B-TREE"); n > 1 {
+		t.Errorf("plan sorts the subqueries: %s", plan)
+	}
+}
+
+// BenchmarkSQLiteWalletHistory сравнивает условие OR и объединение двух индексированных
+// запросов при выборке последних 20 переводов кошелька с частыми переводами (hot)
+// и обычного кошелька (w1):
+//
+//	go test ./internal/db -run '^$' -bench SQLiteWalletHistory
+func BenchmarkSQLiteWalletHistory(b *testing.B) {
+	r := newWalletHistorySQLite(b, 200_000)
+	queries := []struct{ name, query string }{
+		{"or", walletHistoryOr},
+		{"union", walletHistoryUnion},
+	}
+	for _, wallet := range []string{"hot", "w1"} {
+		for _, q := range queries {
+			b.Run(wallet+"/"+q.name, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					rows, err := r.db.Query(q.query, wallet, 20)
+					if err != nil {
+						b.Fatal(err)
+					}
+					for rows.Next() {
+					}
+					if err := rows.Close(); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
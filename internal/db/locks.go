@@ -0,0 +1,158 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"log/slog"
+	"os"
+	"slices"
+)
+
+// Стратегии блокировки при переводе в PostgreSQL (DB_LOCK_STRATEGY).
+const (
+	// lockRow - строка отправителя блокируется через SELECT ... FOR UPDATE (по умолчанию).
+	// Простая и предсказуемая стратегия; при встречных переводах возможны взаимоблокировки,
+	// которые разрешаются повтором транзакции.
+	lockRow = "row"
+
+	// lockAdvisory - перед чтением балансов берутся транзакционные рекомендательные блокировки
+	// (pg_advisory_xact_lock) по хешам адресов обоих кошельков в фиксированном порядке.
+	// Переводы, затрагивающие одни и те же кошельки, выполняются строго по очереди и не
+	// взаимоблокируются, а переводы между другими кошельками не ждут друг друга.
+	lockAdvisory = "advisory"
+
+	// lockAdvisorySender - транзакционная рекомендательная блокировка берется только по хешу
+	// адреса отправителя. Этого достаточно, чтобы списания с одного кошелька не выполнялись
+	// параллельно; зачисления получателю остаются атомарными UPDATE и не ждут чужих списаний.
+	// Блокируется меньше, чем при lockAdvisory, но встречные переводы могут взаимоблокироваться
+	// на строках получателей - такие переводы повторяются (DB_SEND_ATTEMPTS).
+	lockAdvisorySender = "advisory-sender"
+)
+
+// Уровни изоляции транзакции перевода в PostgreSQL (DB_SEND_ISOLATION).
+const (
+	// isolationReadCommitted - каждый запрос видит данные, зафиксированные к его началу.
+	// Корректность перевода обеспечивается только блокировками DB_LOCK_STRATEGY: баланс
+	// читается после блокировки, поэтому параллельное списание не теряется. Повторов меньше всего.
+	isolationReadCommitted = "read-committed"
+
+	// isolationRepeatableRead - вся транзакция видит один снимок (по умолчанию для lockRow). Если строку кошелька
+	// изменила транзакция, зафиксированная после снимка, UPDATE завершается конфликтом сериализации
+	// (40001), и перевод повторяется: ошибка в блокировках не может привести к потере списания,
+	// но переводы с одного кошелька под нагрузкой чаще повторяются и могут исчерпать DB_SEND_ATTEMPTS.
+	// Снимок берется до ожидания блокировки, поэтому перевод, дождавшийся блокировки, повторяется
+	// всегда; с рекомендательными блокировками это сводит на нет их очередь, поэтому для них
+	// по умолчанию используется isolationReadCommitted (см. sendIsolationFromEnv).
+	isolationRepeatableRead = "repeatable-read"
+
+	// isolationSerializable - дополнительно отслеживаются зависимости чтения и записи между
+	// транзакциями. Для перевода, который читает и изменяет одни и те же строки, защиты почти
+	// не добавляет, а конфликтов (и повторов) больше всего.
+	isolationSerializable = "serializable"
+)
+
+// ParseIsolationLevel разбирает название уровня изоляции транзакции перевода
+// ("read-committed", "repeatable-read" или "serializable").
+//
+// Параметры:
+//   - value: Название уровня изоляции.
+//
+// Возвращает:
+//   - Уровень изоляции для sql.TxOptions.
+//   - Ошибку, если название не входит в список допустимых.
+//
+// Пример использования:
+//
+//	isolation, err := db.ParseIsolationLevel(r.Header.Get("X-Isolation-Level"))
+func ParseIsolationLevel(value string) (sql.IsolationLevel, error) {
+	switch value {
+	case isolationReadCommitted:
+		return sql.LevelReadCommitted, nil
+	case isolationRepeatableRead:
+		return sql.LevelRepeatableRead, nil
+	case isolationSerializable:
+		return sql.LevelSerializable, nil
+	default:
+		return sql.LevelDefault, fmt.Errorf("invalid isolation level %q, expected %q, %q or %q",
+			value, isolationReadCommitted, isolationRepeatableRead, isolationSerializable)
+	}
+}
+
+// sendIsolationFromEnv возвращает уровень изоляции транзакции перевода из переменной
+// DB_SEND_ISOLATION. Если она не задана, уровень зависит от стратегии блокировки:
+// READ COMMITTED для рекомендательных блокировок (баланс читается после ожидания блокировки)
+// и REPEATABLE READ для lockRow. Явно заданный более строгий уровень вместе с рекомендательными
+// блокировками допускается, но при запуске записывается предупреждение.
+//
+// Параметры:
+//   - lockStrategy: Стратегия блокировки (DB_LOCK_STRATEGY).
+func sendIsolationFromEnv(lockStrategy string) sql.IsolationLevel {
+	advisory := lockStrategy == lockAdvisory || lockStrategy == lockAdvisorySender
+	value := os.Getenv("DB_SEND_ISOLATION")
+	if value == "" {
+		if advisory {
+			return sql.LevelReadCommitted
+		}
+		return sql.LevelRepeatableRead
+	}
+	isolation, err := ParseIsolationLevel(value)
+	if err != nil {
+		log.Fatalf("Invalid DB_SEND_ISOLATION: %v", err)
+	}
+	if advisory && isolation != sql.LevelReadCommitted {
+		slog.Warn("DB_SEND_ISOLATION takes the snapshot before the advisory lock wait: every queued transfer will hit a serialization failure and retry; use read-committed",
+			"isolation", value, "lock_strategy", lockStrategy)
+	}
+	return isolation
+}
+
+// lockStrategyFromEnv возвращает стратегию блокировки из переменной DB_LOCK_STRATEGY
+// или lockRow, если она не задана.
+func lockStrategyFromEnv() string {
+	switch value := os.Getenv("DB_LOCK_STRATEGY"); value {
+	case "", lockRow:
+		return lockRow
+	case lockAdvisory, lockAdvisorySender:
+		return value
+	default:
+		log.Fatalf("Invalid DB_LOCK_STRATEGY %q, expected %q, %q or %q", value, lockRow, lockAdvisory, lockAdvisorySender)
+		return ""
+	}
+}
+
+// advisoryLockKey возвращает детерминированный ключ рекомендательной блокировки для адреса
+// (FNV-1a, 64 бита). Совпадение ключей разных адресов лишь сериализует лишние переводы.
+func advisoryLockKey(address string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(address))
+	return int64(h.Sum64())
+}
+
+// lockWalletsAdvisory берет транзакционные рекомендательные блокировки для кошельков.
+// Ключи берутся по возрастанию, поэтому встречные переводы ждут друг друга, а не взаимоблокируются.
+// Блокировки снимаются автоматически при COMMIT или ROLLBACK.
+//
+// Параметры:
+//   - ctx: Контекст транзакции.
+//   - tx: Транзакция, в рамках которой берутся блокировки.
+//   - addresses: Адреса кошельков.
+//
+// Возвращает:
+//   - Ошибку, если блокировку получить не удалось.
+func lockWalletsAdvisory(ctx context.Context, tx *sql.Tx, addresses ...string) error {
+	keys := make([]int64, 0, len(addresses))
+	for _, address := range addresses {
+		keys = append(keys, advisoryLockKey(address))
+	}
+	slices.Sort(keys)
+
+	for _, key := range slices.Compact(keys) {
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", key); err != nil {
+			return fmt.Errorf("failed to acquire wallet lock: %w", err)
+		}
+	}
+	return nil
+}
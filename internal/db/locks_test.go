@@ -0,0 +1,73 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+)
+
+func TestSendIsolationFromEnv(t *testing.T) {
+	tests := []struct {
+		strategy string
+		env      string
+		want     sql.IsolationLevel
+	}{
+		{lockRow, "", sql.LevelRepeatableRead},
+		{lockAdvisory, "", sql.LevelReadCommitted},
+		{lockAdvisorySender, "", sql.LevelReadCommitted},
+		{lockRow, isolationReadCommitted, sql.LevelReadCommitted},
+		{lockRow, isolationSerializable, sql.LevelSerializable},
+		// Явно заданный уровень сохраняется и с рекомендательными блокировками (с предупреждением)
+		{lockAdvisory, isolationRepeatableRead, sql.LevelRepeatableRead},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy+"/"+tt.env, func(t *testing.T) {
+			t.Setenv("DB_SEND_ISOLATION", tt.env)
+			if got := sendIsolationFromEnv(tt.strategy); got != tt.want {
+				t.Errorf("sendIsolationFromEnv(%q) with DB_SEND_ISOLATION=%q = %v, want %v", tt.strategy, tt.env, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLockStrategyFromEnv(t *testing.T) {
+	tests := []struct {
+		env  string
+		want string
+	}{
+		{"", lockRow},
+		{lockRow, lockRow},
+		{lockAdvisory, lockAdvisory},
+		{lockAdvisorySender, lockAdvisorySender},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			t.Setenv("DB_LOCK_STRATEGY", tt.env)
+			if got := lockStrategyFromEnv(); got != tt.want {
+				t.Errorf("lockStrategyFromEnv() with DB_LOCK_STRATEGY=%q = %q, want %q", tt.env, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestAdvisoryLockKey проверяет, что ключ блокировки адреса одинаков во всех экземплярах
+// и версиях: экземпляры с разными ключами для одного кошелька не исключали бы друг друга.
+func TestAdvisoryLockKey(t *testing.T) {
+	const address = "0f3a9c0f3a9c0f3a9c0f3a9c0f3a9c0f3a9c0f3a9c0f3a9c0f3a9c0f3a9c0f3a"
+	if got, want := advisoryLockKey(address), int64(5315021763910928795); got != want {
+		t.Errorf("advisoryLockKey(%s) = %d, want %d", address, got, want)
+	}
+	if advisoryLockKey(address) != advisoryLockKey(address) {
+		t.Error("advisoryLockKey is not deterministic")
+	}
+
+	seen := make(map[int64]string)
+	for i := 0; i < 1000; i++ {
+		address := fmt.Sprintf("%064x", i)
+		key := advisoryLockKey(address)
+		if other, ok := seen[key]; ok {
+			t.Fatalf("addresses %s and %s share lock key %d", other, address, key)
+		}
+		seen[key] = address
+	}
+}
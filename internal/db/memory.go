@@ -0,0 +1,1494 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"payment-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// MemoryRepository представляет репозиторий, хранящий данные в памяти процесса.
+// Предназначен для тестов и демонстраций без PostgreSQL; данные теряются при перезапуске.
+type MemoryRepository struct {
+	mu            sync.Mutex
+	wallets       map[string]decimal.Decimal
+	openings      map[string]decimal.Decimal       // Начальные балансы кошельков (см. Reconcile)
+	metadata      map[string]models.WalletMetadata // Метаданные кошельков, у которых они заданы
+	labels        map[string]string                // Адрес кошелька по метке
+	publicKeys    map[string]string                // Открытые ключи кошельков, созданных с ключом
+	nonces        map[string]int64                 // Номер последнего подписанного перевода с кошелька
+	archived      map[string]time.Time             // Время архивации архивных кошельков
+	transactions  []models.Transaction
+	archive       []models.Transaction // Транзакции, перенесенные PurgeTransactions
+	externalIDs   map[string]bool      // Внешние идентификаторы импортированных транзакций
+	nextID        int
+	riskEvents    []models.RiskEvent    // Срабатывания правил проверки переводов в порядке записи
+	approvals     []models.Approval     // Отложенные переводы в порядке создания; ID - индекс плюс один
+	notifications []models.Notification // Уведомления в порядке постановки в очередь; ID - индекс плюс один
+	auditEvents   []models.AuditEvent   // Журнал аудита в порядке записи
+	minBalance    decimal.Decimal       // Неснижаемый остаток кошелька отправителя
+	clock         Clock                 // Источник времени записанных транзакций
+	addresses     *AddressGenerator     // Генератор адресов CreateWallets
+
+	idempotencyKeys map[string]idempotencyEntry // Ответы на запросы по ключу идемпотентности
+}
+
+// idempotencyEntry - занятый ключ идемпотентности и время, когда он занят.
+type idempotencyEntry struct {
+	response  IdempotentResponse
+	createdAt time.Time
+}
+
+// NewMemoryRepository создает новый экземпляр MemoryRepository.
+// Хранилище создается пустым, как и NewPostgresRepository (см. service.SeedWallets).
+//
+// Пример использования:
+//
+//	repo := NewMemoryRepository()
+func NewMemoryRepository() *MemoryRepository {
+	r := &MemoryRepository{
+		wallets:     make(map[string]decimal.Decimal),
+		openings:    make(map[string]decimal.Decimal),
+		metadata:    make(map[string]models.WalletMetadata),
+		labels:      make(map[string]string),
+		publicKeys:  make(map[string]string),
+		nonces:      make(map[string]int64),
+		archived:    make(map[string]time.Time),
+		externalIDs: make(map[string]bool),
+		nextID:      1,
+		minBalance:  MinWalletBalance(),
+		clock:       SystemClock{},
+		addresses:   DefaultAddressGenerator,
+
+		idempotencyKeys: make(map[string]idempotencyEntry),
+	}
+	// Системные счета создаются так же, как при инициализации SQL-хранилищ
+	for name := range systemAccountNumbers {
+		r.createWallet(SystemAccount(name), decimal.Zero, models.WalletMetadata{Tags: map[string]string{systemAccountTag: name}}, "")
+	}
+	return r
+}
+
+// SetClock задает источник времени, которым отмечаются записанные транзакции, как
+// PostgresRepository.SetClock.
+func (r *MemoryRepository) SetClock(clock Clock) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clock = clock
+}
+
+// SetAddressGenerator задает генератор адресов кошельков, создаваемых CreateWallets, как
+// PostgresRepository.SetAddressGenerator.
+func (r *MemoryRepository) SetAddressGenerator(addresses *AddressGenerator) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.addresses = addresses
+}
+
+// CreateWallet создает кошелек с указанным адресом, начальным балансом, метаданными и открытым ключом.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//   - balance: Начальный баланс.
+//   - metadata: Метка и теги кошелька.
+//   - publicKey: Открытый ключ ed25519 в шестнадцатеричном виде (пустая строка - без ключа).
+//
+// Возвращает:
+//   - ErrWalletExists, если кошелек с таким адресом уже существует.
+//   - ErrLabelExists, если метка присвоена другому кошельку.
+func (r *MemoryRepository) CreateWallet(ctx context.Context, address string, balance decimal.Decimal, metadata models.WalletMetadata, publicKey string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.createWallet(address, balance, metadata, publicKey)
+}
+
+// createWallet создает кошелек. Вызывается под r.mu.
+func (r *MemoryRepository) createWallet(address string, balance decimal.Decimal, metadata models.WalletMetadata, publicKey string) error {
+	if _, ok := r.wallets[address]; ok {
+		return ErrWalletExists
+	}
+	if _, ok := r.labels[metadata.Label]; ok && metadata.Label != "" {
+		return ErrLabelExists
+	}
+	r.wallets[address] = balance
+	r.openings[address] = balance
+	r.setMetadata(address, metadata)
+	if publicKey != "" {
+		r.publicKeys[address] = publicKey
+	}
+	return nil
+}
+
+// setMetadata сохраняет копию метаданных кошелька и обновляет индекс меток.
+// Вызывается под r.mu.
+func (r *MemoryRepository) setMetadata(address string, metadata models.WalletMetadata) {
+	if old, ok := r.metadata[address]; ok && old.Label != "" {
+		delete(r.labels, old.Label)
+	}
+	if metadata.Label == "" && len(metadata.Tags) == 0 && metadata.NotifyEmail == "" {
+		delete(r.metadata, address)
+		return
+	}
+	if metadata.Label != "" {
+		r.labels[metadata.Label] = address
+	}
+	r.metadata[address] = copyMetadata(metadata)
+}
+
+// copyMetadata возвращает копию метаданных, не разделяющую с исходными карту тегов.
+func copyMetadata(metadata models.WalletMetadata) models.WalletMetadata {
+	if metadata.Tags != nil {
+		tags := make(map[string]string, len(metadata.Tags))
+		for key, value := range metadata.Tags {
+			tags[key] = value
+		}
+		metadata.Tags = tags
+	}
+	return metadata
+}
+
+// wallet возвращает кошелек по адресу. Вызывается под r.mu.
+func (r *MemoryRepository) wallet(address string) (models.Wallet, bool) {
+	balance, ok := r.wallets[address]
+	if !ok {
+		return models.Wallet{}, false
+	}
+	wallet := models.Wallet{
+		Address:        address,
+		Balance:        balance,
+		WalletMetadata: copyMetadata(r.metadata[address]),
+		PublicKey:      r.publicKeys[address],
+	}
+	if archivedAt, ok := r.archived[address]; ok {
+		wallet.ArchivedAt = &archivedAt
+	}
+	return wallet, true
+}
+
+// GetWallet возвращает кошелек с балансом и метаданными.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Кошелек.
+//   - Ошибку, оборачивающую ErrWalletNotFound, если кошелек не найден.
+func (r *MemoryRepository) GetWallet(ctx context.Context, address string) (models.Wallet, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	wallet, ok := r.wallet(address)
+	if !ok {
+		return models.Wallet{}, fmt.Errorf("failed to get wallet: %w", ErrWalletNotFound)
+	}
+	return wallet, nil
+}
+
+// FindWalletByLabel возвращает кошелек по метке.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - label: Метка кошелька.
+//
+// Возвращает:
+//   - Кошелек.
+//   - Ошибку, оборачивающую ErrWalletNotFound, если кошелька с такой меткой нет.
+func (r *MemoryRepository) FindWalletByLabel(ctx context.Context, label string) (models.Wallet, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	address, ok := r.labels[label]
+	if !ok || label == "" {
+		return models.Wallet{}, fmt.Errorf("failed to find wallet: %w", ErrWalletNotFound)
+	}
+	wallet, _ := r.wallet(address)
+	return wallet, nil
+}
+
+// TopWalletsByBalance возвращает активные кошельки с наибольшим балансом, не считая системных счетов.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - limit: Максимальное количество кошельков.
+//
+// Возвращает:
+//   - Кошельки по убыванию баланса, при равном балансе - по адресу.
+func (r *MemoryRepository) TopWalletsByBalance(ctx context.Context, limit int) ([]models.Wallet, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var wallets []models.Wallet
+	for address := range r.wallets {
+		if _, archived := r.archived[address]; archived || IsSystemAccount(address) {
+			continue
+		}
+		wallet, _ := r.wallet(address)
+		wallets = append(wallets, wallet)
+	}
+	sort.Slice(wallets, func(i, j int) bool {
+		if c := wallets[i].Balance.Cmp(wallets[j].Balance); c != 0 {
+			return c > 0
+		}
+		return wallets[i].Address < wallets[j].Address
+	})
+	return wallets[:min(limit, len(wallets))], nil
+}
+
+// UpdateWalletMetadata изменяет метку, теги и адрес для уведомлений кошелька.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//   - patch: Изменяемые поля; поля со значением nil не изменяются.
+//
+// Возвращает:
+//   - Кошелек после изменения.
+//   - Ошибку, оборачивающую ErrWalletNotFound, или ErrLabelExists, если метка занята.
+func (r *MemoryRepository) UpdateWalletMetadata(ctx context.Context, address string, patch models.WalletMetadataPatch) (models.Wallet, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.wallets[address]; !ok {
+		return models.Wallet{}, fmt.Errorf("failed to update wallet: %w", ErrWalletNotFound)
+	}
+
+	metadata := r.metadata[address]
+	if patch.Label != nil {
+		if owner, ok := r.labels[*patch.Label]; ok && owner != address && *patch.Label != "" {
+			return models.Wallet{}, ErrLabelExists
+		}
+		metadata.Label = *patch.Label
+	}
+	if patch.Tags != nil {
+		metadata.Tags = patch.Tags
+	}
+	if patch.NotifyEmail != nil {
+		metadata.NotifyEmail = *patch.NotifyEmail
+	}
+	r.setMetadata(address, metadata)
+
+	wallet, _ := r.wallet(address)
+	return wallet, nil
+}
+
+// ArchiveWallet архивирует кошелек с нулевым балансом, не участвующий в отложенных переводах.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Архивный кошелек (повторная архивация возвращает его без изменений).
+//   - Ошибку, оборачивающую ErrWalletNotFound; ErrWalletNotEmpty или ErrWalletHasHolds.
+func (r *MemoryRepository) ArchiveWallet(ctx context.Context, address string) (models.Wallet, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	wallet, ok := r.wallet(address)
+	if !ok {
+		return models.Wallet{}, fmt.Errorf("failed to archive wallet: %w", ErrWalletNotFound)
+	}
+	if wallet.ArchivedAt != nil {
+		return wallet, nil
+	}
+	if !wallet.Balance.IsZero() {
+		return models.Wallet{}, ErrWalletNotEmpty
+	}
+	for _, approval := range r.approvals {
+		active := approval.Status == models.ApprovalAwaitingReview || approval.Status == models.ApprovalApproved
+		if active && (approval.From == address || approval.To == address) {
+			return models.Wallet{}, ErrWalletHasHolds
+		}
+	}
+
+	r.archived[address] = time.Now().UTC()
+	wallet, _ = r.wallet(address)
+	return wallet, nil
+}
+
+// RestoreWallet снимает архивацию кошелька.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Кошелек после восстановления.
+//   - Ошибку, оборачивающую ErrWalletNotFound, если кошелька нет.
+func (r *MemoryRepository) RestoreWallet(ctx context.Context, address string) (models.Wallet, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.archived, address)
+	wallet, ok := r.wallet(address)
+	if !ok {
+		return models.Wallet{}, fmt.Errorf("failed to restore wallet: %w", ErrWalletNotFound)
+	}
+	return wallet, nil
+}
+
+// GetNonce возвращает номер последнего подписанного перевода с кошелька.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Номер последнего подписанного перевода (0, если их не было).
+//   - Ошибку, оборачивающую ErrWalletNotFound, если кошелька нет.
+func (r *MemoryRepository) GetNonce(ctx context.Context, address string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.wallets[address]; !ok {
+		return 0, fmt.Errorf("failed to get nonce: %w", ErrWalletNotFound)
+	}
+	return r.nonces[address], nil
+}
+
+// CreateWallets создает count кошельков со случайными адресами и заданным балансом.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - count: Количество кошельков.
+//   - balance: Начальный баланс каждого кошелька.
+//
+// Возвращает:
+//   - Количество созданных кошельков.
+//   - Ошибку, если свободный адрес получить не удалось.
+func (r *MemoryRepository) CreateWallets(ctx context.Context, count int, balance decimal.Decimal) (int, error) {
+	for i := 0; i < count; i++ {
+		if _, err := r.addresses.CreateWallet(ctx, r, balance, models.WalletMetadata{}, ""); err != nil {
+			return i, err
+		}
+	}
+	return count, nil
+}
+
+// HasWallets сообщает, есть ли в хранилище хотя бы один кошелек, кроме системных счетов.
+//
+// Возвращает:
+//   - true, если кошельки есть.
+//   - Ошибку (для этой реализации всегда nil).
+func (r *MemoryRepository) HasWallets(ctx context.Context) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.wallets) > len(systemAccountNumbers), nil
+}
+
+// GetBalance возвращает баланс кошелька по его адресу.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Баланс кошелька.
+//   - Ошибку, оборачивающую ErrWalletNotFound, если кошелек не найден.
+func (r *MemoryRepository) GetBalance(ctx context.Context, address string) (decimal.Decimal, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	balance, ok := r.wallets[address]
+	if !ok {
+		return decimal.Zero, fmt.Errorf("failed to get balance: %w", ErrWalletNotFound)
+	}
+	return balance, nil
+}
+
+// GetBalanceDetails возвращает баланс кошелька, зарезервированные средства и доступный остаток.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Баланс с резервом и доступным остатком.
+//   - Ошибку, оборачивающую ErrWalletNotFound, если кошелек не найден.
+func (r *MemoryRepository) GetBalanceDetails(ctx context.Context, address string) (models.Balance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	balance, ok := r.wallets[address]
+	if !ok {
+		return models.Balance{}, fmt.Errorf("failed to get balance: %w", ErrWalletNotFound)
+	}
+	return newBalance(balance, r.reserved(address)), nil
+}
+
+// reserved возвращает сумму переводов с кошелька, ожидающих подтверждения.
+// Вызывается под r.mu.
+func (r *MemoryRepository) reserved(address string) decimal.Decimal {
+	var reserved decimal.Decimal
+	for _, approval := range r.approvals {
+		if approval.From == address && approval.Status == models.ApprovalAwaitingReview {
+			reserved = reserved.Add(approval.Amount)
+		}
+	}
+	return reserved
+}
+
+// GetBalances возвращает балансы нескольких кошельков.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - addresses: Адреса кошельков.
+//
+// Возвращает:
+//   - Балансы по адресам; несуществующих кошельков в результате нет.
+//   - Ошибку (для этой реализации всегда nil).
+func (r *MemoryRepository) GetBalances(ctx context.Context, addresses []string) (map[string]decimal.Decimal, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	balances := make(map[string]decimal.Decimal, len(addresses))
+	for _, address := range addresses {
+		if balance, ok := r.wallets[address]; ok {
+			balances[address] = balance
+		}
+	}
+	return balances, nil
+}
+
+// Send выполняет перевод средств с одного кошелька на другой.
+// Все проверки и изменения выполняются под одной блокировкой,
+// поэтому перевод атомарен так же, как транзакция в PostgreSQL.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - from: Адрес кошелька отправителя.
+//   - to: Адрес кошелька получателя.
+//   - amount: Сумма перевода.
+//   - memo: Комментарий к переводу (может быть пустым).
+//   - category: Категория перевода (может быть пустой).
+//   - nonce: Номер подписанного перевода; 0 - перевод без подписи.
+//   - isolation: Не используется: перевод выполняется под блокировкой хранилища.
+//
+// Возвращает:
+//   - Идентификатор и время записанной транзакции и баланс отправителя после списания.
+//   - Ошибку, если перевод не удался (например, недостаточно средств);
+//     *NonceError, если nonce не следующий номер отправителя.
+func (r *MemoryRepository) Send(ctx context.Context, from, to string, amount decimal.Decimal, memo, category string, nonce int64, isolation sql.IsolationLevel) (SendResult, error) {
+	var result SendResult
+	err := r.WithTx(ctx, func(tx TxRepository) error {
+		var err error
+		result, err = tx.Send(from, to, amount, memo, category, nonce)
+		return err
+	})
+	if err != nil {
+		return SendResult{}, err
+	}
+	return result, nil
+}
+
+// SystemTransfer выполняет операцию с системным счетом (см. systemTransfer) под блокировкой
+// хранилища, как Send.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - txType: Тип операции (models.TransactionMint и др.).
+//   - address: Кошелек пользователя.
+//   - amount: Сумма операции.
+//   - memo: Комментарий (может быть пустым).
+//
+// Возвращает:
+//   - Идентификатор и время записанной транзакции и балансы участников после операции.
+//   - ErrTransactionType, ErrSystemAccount или ошибку перевода.
+func (r *MemoryRepository) SystemTransfer(ctx context.Context, txType, address string, amount decimal.Decimal, memo string) (SendResult, error) {
+	var result SendResult
+	err := r.WithTx(ctx, func(tx TxRepository) error {
+		var err error
+		result, err = systemTransfer(tx, txType, address, amount, memo, r.minBalance)
+		return err
+	})
+	if err != nil {
+		return SendResult{}, err
+	}
+	return result, nil
+}
+
+// WithTx выполняет fn под блокировкой хранилища: изменения, сделанные через TxRepository,
+// сохраняются, если fn вернула nil, и отменяются в обратном порядке, если ошибку или панику.
+// Внутри fn нельзя вызывать методы MemoryRepository: блокировка уже занята.
+//
+// Параметры:
+//   - ctx: Контекст (не используется: операции выполняются в памяти).
+//   - fn: Операции транзакции.
+//
+// Возвращает:
+//   - Ошибку fn.
+func (r *MemoryRepository) WithTx(ctx context.Context, fn func(tx TxRepository) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx := &memoryTx{repo: r}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.rollback()
+		}
+	}()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// WithSeedLock выполняет fn в транзакции, как WithTx: транзакции хранилища в памяти
+// и так выполняются по очереди под его мьютексом.
+func (r *MemoryRepository) WithSeedLock(ctx context.Context, fn func(tx TxRepository) error) error {
+	return r.WithTx(ctx, fn)
+}
+
+// memoryTx - TxRepository хранилища в памяти. Каждое изменение сохраняет функцию отмены.
+type memoryTx struct {
+	repo *MemoryRepository
+	undo []func()
+}
+
+// rollback отменяет изменения транзакции в обратном порядке.
+func (t *memoryTx) rollback() {
+	for i := len(t.undo) - 1; i >= 0; i-- {
+		t.undo[i]()
+	}
+	t.undo = nil
+}
+
+// GetWalletForUpdate читает состояние кошелька; хранилище заблокировано на всю транзакцию.
+func (t *memoryTx) GetWalletForUpdate(address string) (WalletState, error) {
+	balance, ok := t.repo.wallets[address]
+	if !ok {
+		return WalletState{}, ErrWalletNotFound
+	}
+	_, archived := t.repo.archived[address]
+	return WalletState{
+		Balance:  balance,
+		Reserved: t.repo.reserved(address),
+		Nonce:    t.repo.nonces[address],
+		Archived: archived,
+	}, nil
+}
+
+// AddBalance изменяет баланс кошелька.
+func (t *memoryTx) AddBalance(address string, delta decimal.Decimal) (decimal.Decimal, error) {
+	balance, ok := t.repo.wallets[address]
+	if !ok {
+		return decimal.Decimal{}, ErrWalletNotFound
+	}
+	if _, archived := t.repo.archived[address]; archived {
+		return decimal.Decimal{}, fmt.Errorf("wallet %s: %w", address, ErrWalletArchived)
+	}
+	if balanceOverflows(balance, delta) {
+		return decimal.Decimal{}, ErrBalanceOverflow
+	}
+
+	t.repo.wallets[address] = balance.Add(delta)
+	t.undo = append(t.undo, func() { t.repo.wallets[address] = balance })
+	return t.repo.wallets[address], nil
+}
+
+// SetNonce сохраняет номер последнего подписанного перевода с кошелька.
+func (t *memoryTx) SetNonce(address string, nonce int64) error {
+	previous, ok := t.repo.nonces[address]
+	if nonce <= previous {
+		return nil
+	}
+	t.repo.nonces[address] = nonce
+	t.undo = append(t.undo, func() {
+		if ok {
+			t.repo.nonces[address] = previous
+		} else {
+			delete(t.repo.nonces, address)
+		}
+	})
+	return nil
+}
+
+// RecordTransaction записывает транзакцию.
+func (t *memoryTx) RecordTransaction(txType, from, to string, amount decimal.Decimal, memo, category string) (models.Transaction, error) {
+	transaction := models.Transaction{
+		ID:        t.repo.nextID,
+		From:      from,
+		To:        to,
+		Amount:    amount,
+		CreatedAt: t.repo.clock.Now().UTC(),
+		Memo:      memo,
+		Category:  category,
+		Type:      txType,
+	}
+	t.repo.transactions = append(t.repo.transactions, transaction)
+	t.repo.nextID++
+	t.undo = append(t.undo, func() {
+		t.repo.transactions = t.repo.transactions[:len(t.repo.transactions)-1]
+		t.repo.nextID--
+	})
+	return transaction, nil
+}
+
+// EnqueueNotification ставит в очередь уведомление получателю транзакции.
+func (t *memoryTx) EnqueueNotification(transaction models.Transaction) error {
+	email := t.repo.metadata[transaction.To].NotifyEmail
+	if email == "" {
+		return nil
+	}
+	t.repo.notifications = append(t.repo.notifications, models.Notification{
+		ID:            int64(len(t.repo.notifications) + 1),
+		TransactionID: transaction.ID,
+		Address:       transaction.To,
+		Email:         email,
+		From:          transaction.From,
+		FromLabel:     t.repo.metadata[transaction.From].Label,
+		Amount:        transaction.Amount,
+		TransferredAt: transaction.CreatedAt,
+		Status:        models.NotificationPending,
+		NextAttemptAt: transaction.CreatedAt,
+	})
+	t.undo = append(t.undo, func() {
+		t.repo.notifications = t.repo.notifications[:len(t.repo.notifications)-1]
+	})
+	return nil
+}
+
+// CreateWallet создает кошелек в транзакции.
+func (t *memoryTx) CreateWallet(address string, balance decimal.Decimal, metadata models.WalletMetadata, publicKey string) error {
+	if err := t.repo.createWallet(address, balance, metadata, publicKey); err != nil {
+		return err
+	}
+	t.undo = append(t.undo, func() {
+		delete(t.repo.wallets, address)
+		delete(t.repo.openings, address)
+		t.repo.setMetadata(address, models.WalletMetadata{})
+		delete(t.repo.publicKeys, address)
+	})
+	return nil
+}
+
+// RetireWallet архивирует кошелек и снимает с него метку.
+func (t *memoryTx) RetireWallet(address string) (models.Wallet, error) {
+	wallet, ok := t.repo.wallet(address)
+	if !ok {
+		return models.Wallet{}, ErrWalletNotFound
+	}
+	if wallet.ArchivedAt != nil {
+		return models.Wallet{}, fmt.Errorf("wallet %s: %w", address, ErrWalletArchived)
+	}
+	if !wallet.Balance.IsZero() {
+		return models.Wallet{}, ErrWalletNotEmpty
+	}
+	for _, approval := range t.repo.approvals {
+		active := approval.Status == models.ApprovalAwaitingReview || approval.Status == models.ApprovalApproved
+		if active && (approval.From == address || approval.To == address) {
+			return models.Wallet{}, ErrWalletHasHolds
+		}
+	}
+
+	t.repo.archived[address] = time.Now().UTC()
+	t.repo.setMetadata(address, models.WalletMetadata{Tags: wallet.Tags})
+	t.undo = append(t.undo, func() {
+		delete(t.repo.archived, address)
+		t.repo.setMetadata(address, wallet.WalletMetadata)
+	})
+	return wallet, nil
+}
+
+// HasWallets проверяет, есть ли кошельки, кроме системных счетов.
+func (t *memoryTx) HasWallets() (bool, error) {
+	return len(t.repo.wallets) > len(systemAccountNumbers), nil
+}
+
+// Send выполняет перевод в транзакции (см. transfer).
+func (t *memoryTx) Send(from, to string, amount decimal.Decimal, memo, category string, nonce int64) (SendResult, error) {
+	return transfer(t, from, to, amount, memo, category, nonce, t.repo.minBalance)
+}
+
+// UpdateApprovalStatus меняет статус отложенного перевода в транзакции.
+func (t *memoryTx) UpdateApprovalStatus(id int64, from, to, reason string) (models.Approval, error) {
+	if id < 1 || id > int64(len(t.repo.approvals)) {
+		return models.Approval{}, fmt.Errorf("failed to get approval: %w", ErrApprovalNotFound)
+	}
+	previous := t.repo.approvals[id-1]
+	approval, err := t.repo.updateApprovalStatus(id, from, to, reason)
+	if err != nil {
+		return models.Approval{}, err
+	}
+	t.undo = append(t.undo, func() { t.repo.approvals[id-1] = previous })
+	return approval, nil
+}
+
+// ImportTransactions сохраняет исторические транзакции с их исходным временем,
+// не изменяя балансы. Транзакции с уже известным ExternalID пропускаются.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - transactions: Транзакции для импорта; ExternalID и CreatedAt обязательны.
+//
+// Возвращает:
+//   - Количество добавленных записей.
+//   - Ошибку (для этой реализации всегда nil).
+func (r *MemoryRepository) ImportTransactions(ctx context.Context, transactions []models.Transaction) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	imported := 0
+	for _, t := range transactions {
+		if r.externalIDs[t.ExternalID] {
+			continue
+		}
+		r.externalIDs[t.ExternalID] = true
+
+		t.ID = r.nextID
+		t.CreatedAt = t.CreatedAt.UTC()
+		t.Imported = true
+		if t.Type == "" {
+			t.Type = models.TransactionTransfer
+		}
+		r.transactions = append(r.transactions, t)
+		r.nextID++
+		imported++
+	}
+	return imported, nil
+}
+
+// GetLastTransactions возвращает список последних N транзакций, начиная с самой новой.
+// Как и в SQL-реализациях, транзакции упорядочиваются по времени, а при равном времени - по id,
+// поэтому импортированные исторические записи не оказываются в начале списка.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - count: Количество транзакций.
+//   - filter: Условия выборки.
+//
+// Возвращает:
+//   - Список транзакций.
+//   - Ошибку (для этой реализации всегда nil).
+func (r *MemoryRepository) GetLastTransactions(ctx context.Context, count int, filter TransactionFilter) ([]models.Transaction, error) {
+	r.mu.Lock()
+	sources := [][]models.Transaction{r.transactions}
+	if filter.IncludeArchived {
+		sources = append(sources, r.archive)
+	}
+	var sorted []models.Transaction
+	for _, source := range sources {
+		for _, t := range source {
+			if filter.Matches(t) {
+				sorted = append(sorted, t)
+			}
+		}
+	}
+	r.mu.Unlock()
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if !sorted[i].CreatedAt.Equal(sorted[j].CreatedAt) {
+			return sorted[i].CreatedAt.After(sorted[j].CreatedAt)
+		}
+		return sorted[i].ID > sorted[j].ID
+	})
+
+	// Копия, а не sorted[:count]: иначе результат удерживал бы отсортированный список целиком
+	transactions := make([]models.Transaction, max(0, min(count, len(sorted))))
+	copy(transactions, sorted)
+	return transactions, nil
+}
+
+// CountTransactions возвращает количество транзакций, удовлетворяющих фильтру.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - filter: Условия выборки.
+//
+// Возвращает:
+//   - Количество транзакций.
+//   - Ошибку (для этой реализации всегда nil).
+func (r *MemoryRepository) CountTransactions(ctx context.Context, filter TransactionFilter) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sources := [][]models.Transaction{r.transactions}
+	if filter.IncludeArchived {
+		sources = append(sources, r.archive)
+	}
+	var count int64
+	for _, source := range sources {
+		for _, t := range source {
+			if filter.Matches(t) {
+				count++
+			}
+		}
+	}
+	return count, nil
+}
+
+// GetWalletTransactions возвращает последние count транзакций каждого из кошельков.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - addresses: Адреса кошельков.
+//   - count: Максимальное количество транзакций одного кошелька.
+//
+// Возвращает:
+//   - Транзакции по адресам, начиная с самой новой; кошельков без транзакций в результате нет.
+//   - Ошибку (для этой реализации всегда nil).
+func (r *MemoryRepository) GetWalletTransactions(ctx context.Context, addresses []string, count int) (map[string][]models.Transaction, error) {
+	wanted := make(map[string]bool, len(addresses))
+	for _, address := range addresses {
+		wanted[address] = true
+	}
+
+	r.mu.Lock()
+	transactions := make(map[string][]models.Transaction)
+	for _, t := range r.transactions {
+		if wanted[t.From] {
+			transactions[t.From] = append(transactions[t.From], t)
+		}
+		if wanted[t.To] && t.To != t.From {
+			transactions[t.To] = append(transactions[t.To], t)
+		}
+	}
+	r.mu.Unlock()
+
+	for address, list := range transactions {
+		sort.Slice(list, func(i, j int) bool {
+			if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+				return list[i].CreatedAt.After(list[j].CreatedAt)
+			}
+			return list[i].ID > list[j].ID
+		})
+		transactions[address] = list[:min(count, len(list))]
+	}
+	return transactions, nil
+}
+
+// EstimateTransactions возвращает точное количество транзакций: подсчет в памяти дешев.
+func (r *MemoryRepository) EstimateTransactions(ctx context.Context) (int64, error) {
+	return r.CountTransactions(ctx, TransactionFilter{})
+}
+
+// PurgeTransactions переносит в архив (при archive = false - удаляет) не более limit
+// самых старых транзакций, выполненных раньше момента before.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - before: Транзакции, выполненные раньше этого момента, очищаются.
+//   - archive: true - перенести в архив, false - удалить.
+//   - limit: Наибольшее количество транзакций в пачке.
+//
+// Возвращает:
+//   - Количество перенесенных (удаленных) транзакций.
+//   - Ошибку (для этой реализации всегда nil).
+func (r *MemoryRepository) PurgeTransactions(ctx context.Context, before time.Time, archive bool, limit int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Транзакции хранятся в порядке id, как и выбираются SQL-реализациями
+	purged := 0
+	kept := r.transactions[:0]
+	for _, t := range r.transactions {
+		if purged < limit && t.CreatedAt.Before(before) {
+			if archive {
+				r.archive = append(r.archive, t)
+			} else {
+				if t.ExternalID != "" {
+					// Как и в SQL-реализациях, удаленную запись можно импортировать заново
+					delete(r.externalIDs, t.ExternalID)
+				}
+				// Удаленный перевод переносится в начальные балансы участников
+				if !t.Imported {
+					r.openings[t.From] = r.openings[t.From].Sub(t.Amount)
+					r.openings[t.To] = r.openings[t.To].Add(t.Amount)
+				}
+			}
+			purged++
+			continue
+		}
+		kept = append(kept, t)
+	}
+	r.transactions = kept
+	return purged, nil
+}
+
+// GetSenderStats возвращает количество и сумму переводов с кошелька начиная с момента since.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька отправителя.
+//   - since: Начало периода.
+//
+// Возвращает:
+//   - Сводку переводов (нулевую, если переводов не было).
+//   - Ошибку (для этой реализации всегда nil).
+func (r *MemoryRepository) GetSenderStats(ctx context.Context, address string, since time.Time) (SenderStats, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var stats SenderStats
+	for _, t := range r.transactions {
+		if t.From == address && !t.Imported && !t.CreatedAt.Before(since) {
+			stats.Count++
+			stats.Total = stats.Total.Add(t.Amount)
+		}
+	}
+	return stats, nil
+}
+
+// GetCategoryVolumes возвращает объемы переводов по дням и категориям за период [from, to).
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - from: Начало периода.
+//   - to: Конец периода (не включается).
+//
+// Возвращает:
+//   - Объемы по дням (UTC) и категориям в порядке дня и категории.
+//   - Ошибку (для этой реализации всегда nil).
+func (r *MemoryRepository) GetCategoryVolumes(ctx context.Context, from, to time.Time) ([]CategoryVolume, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	type key struct {
+		day      time.Time
+		category string
+	}
+	index := make(map[key]int)
+	var volumes []CategoryVolume
+	for _, source := range [][]models.Transaction{r.transactions, r.archive} {
+		for _, t := range source {
+			if t.CreatedAt.Before(from) || !t.CreatedAt.Before(to) {
+				continue
+			}
+			created := t.CreatedAt.UTC()
+			k := key{time.Date(created.Year(), created.Month(), created.Day(), 0, 0, 0, 0, time.UTC), t.Category}
+			i, ok := index[k]
+			if !ok {
+				i = len(volumes)
+				index[k] = i
+				volumes = append(volumes, CategoryVolume{Day: k.day, Category: k.category})
+			}
+			volumes[i].Count++
+			volumes[i].Volume = volumes[i].Volume.Add(t.Amount)
+		}
+	}
+	sort.Slice(volumes, func(i, j int) bool {
+		if !volumes[i].Day.Equal(volumes[j].Day) {
+			return volumes[i].Day.Before(volumes[j].Day)
+		}
+		return volumes[i].Category < volumes[j].Category
+	})
+	return volumes, nil
+}
+
+// RecordRiskEvent сохраняет срабатывание правила проверки переводов.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - event: Событие; ID и CreatedAt назначаются хранилищем.
+//
+// Возвращает:
+//   - Ошибку (для этой реализации всегда nil).
+func (r *MemoryRepository) RecordRiskEvent(ctx context.Context, event models.RiskEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event.ID = len(r.riskEvents) + 1
+	event.CreatedAt = time.Now().UTC()
+	r.riskEvents = append(r.riskEvents, event)
+	return nil
+}
+
+// GetRiskEvents возвращает последние count срабатываний правил, начиная с самого нового.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - count: Количество событий.
+//
+// Возвращает:
+//   - Список событий.
+//   - Ошибку (для этой реализации всегда nil).
+func (r *MemoryRepository) GetRiskEvents(ctx context.Context, count int) ([]models.RiskEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var events []models.RiskEvent
+	for i := len(r.riskEvents) - 1; i >= 0 && len(events) < count; i-- {
+		events = append(events, r.riskEvents[i])
+	}
+	return events, nil
+}
+
+// AnonymizeWallet удаляет метку, теги и адрес для уведомлений кошелька, комментарии его
+// транзакций и отложенных переводов и его данные в уведомлениях. Хранилище изменяется под одной блокировкой, поэтому пачки не нужны.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//   - dryRun: true - только подсчитать записи с персональными данными.
+//
+// Возвращает:
+//   - Количество измененных (или подлежащих изменению) записей.
+//   - Ошибку, оборачивающую ErrWalletNotFound, если кошелька нет.
+func (r *MemoryRepository) AnonymizeWallet(ctx context.Context, address string, dryRun bool) (models.AnonymizeReport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.wallets[address]; !ok {
+		return models.AnonymizeReport{}, fmt.Errorf("failed to anonymize wallet: %w", ErrWalletNotFound)
+	}
+
+	report := models.AnonymizeReport{DryRun: dryRun}
+	if _, ok := r.metadata[address]; ok {
+		report.Wallets = 1
+		if !dryRun {
+			r.setMetadata(address, models.WalletMetadata{})
+		}
+	}
+	for _, source := range [][]models.Transaction{r.transactions, r.archive} {
+		for i := range source {
+			t := &source[i]
+			if (t.From == address || t.To == address) && t.Memo != "" {
+				report.Transactions++
+				if !dryRun {
+					t.Memo = ""
+				}
+			}
+		}
+	}
+	for i := range r.approvals {
+		a := &r.approvals[i]
+		if (a.From == address || a.To == address) && a.Memo != "" {
+			report.Approvals++
+			if !dryRun {
+				a.Memo = ""
+			}
+		}
+	}
+	for i := range r.notifications {
+		n := &r.notifications[i]
+		recipient := n.Address == address && n.Email != ""
+		sender := n.From == address && n.FromLabel != ""
+		if !recipient && !sender {
+			continue
+		}
+		report.Notifications++
+		if dryRun {
+			continue
+		}
+		if recipient {
+			n.Email = ""
+		}
+		if sender {
+			n.FromLabel = ""
+		}
+	}
+	return report, nil
+}
+
+// RecordAuditEvent сохраняет запись журнала аудита.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - event: Запись; ID и CreatedAt назначаются хранилищем.
+//
+// Возвращает:
+//   - Ошибку (для этой реализации всегда nil).
+func (r *MemoryRepository) RecordAuditEvent(ctx context.Context, event models.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event.ID = int64(len(r.auditEvents) + 1)
+	event.CreatedAt = time.Now().UTC()
+	r.auditEvents = append(r.auditEvents, event)
+	return nil
+}
+
+// GetAuditEvents возвращает последние count записей журнала аудита, начиная с самой новой.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - count: Количество записей.
+//
+// Возвращает:
+//   - Список записей.
+//   - Ошибку (для этой реализации всегда nil).
+func (r *MemoryRepository) GetAuditEvents(ctx context.Context, count int) ([]models.AuditEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var events []models.AuditEvent
+	for i := len(r.auditEvents) - 1; i >= 0 && len(events) < count; i-- {
+		events = append(events, r.auditEvents[i])
+	}
+	return events, nil
+}
+
+// ReserveIdempotencyKey занимает ключ идемпотентности для выполняющегося запроса.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - key: Ключ из заголовка Idempotency-Key.
+//   - fingerprint: Отпечаток запроса.
+//   - expiredBefore: Ключи, занятые раньше этого момента, удаляются.
+//
+// Возвращает:
+//   - Сохраненный ответ и false, если ключ уже занят.
+//   - true, если ключ занят этим вызовом.
+//   - Ошибку (для этой реализации всегда nil).
+func (r *MemoryRepository) ReserveIdempotencyKey(ctx context.Context, key, fingerprint string, expiredBefore time.Time) (IdempotentResponse, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for k, entry := range r.idempotencyKeys {
+		if entry.createdAt.Before(expiredBefore) {
+			delete(r.idempotencyKeys, k)
+		}
+	}
+	if entry, ok := r.idempotencyKeys[key]; ok {
+		return entry.response, false, nil
+	}
+	r.idempotencyKeys[key] = idempotencyEntry{
+		response:  IdempotentResponse{Fingerprint: fingerprint},
+		createdAt: time.Now(),
+	}
+	return IdempotentResponse{}, true, nil
+}
+
+// CompleteIdempotencyKey сохраняет ответ на запрос с занятым ключом идемпотентности.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - key: Ключ, занятый ReserveIdempotencyKey.
+//   - response: Ответ (поле Fingerprint не используется).
+//
+// Возвращает:
+//   - Ошибку (для этой реализации всегда nil).
+func (r *MemoryRepository) CompleteIdempotencyKey(ctx context.Context, key string, response IdempotentResponse) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.idempotencyKeys[key]
+	if !ok {
+		return nil
+	}
+	response.Fingerprint = entry.response.Fingerprint
+	entry.response = response
+	r.idempotencyKeys[key] = entry
+	return nil
+}
+
+// ReleaseIdempotencyKey освобождает ключ запроса, ответ на который не сохраняется.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - key: Ключ, занятый ReserveIdempotencyKey.
+//
+// Возвращает:
+//   - Ошибку (для этой реализации всегда nil).
+func (r *MemoryRepository) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if entry, ok := r.idempotencyKeys[key]; ok && entry.response.Status == 0 {
+		delete(r.idempotencyKeys, key)
+	}
+	return nil
+}
+
+// CreateApproval сохраняет перевод, ожидающий подтверждения администратором.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - approval: Перевод; ID, Status и CreatedAt назначаются хранилищем.
+//
+// Возвращает:
+//   - Сохраненный перевод со статусом models.ApprovalAwaitingReview.
+//   - Ошибку (для этой реализации всегда nil).
+func (r *MemoryRepository) CreateApproval(ctx context.Context, approval models.Approval) (models.Approval, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	approval.ID = int64(len(r.approvals) + 1)
+	approval.Status = models.ApprovalAwaitingReview
+	approval.Reason = ""
+	approval.CreatedAt = time.Now().UTC()
+	approval.DecidedAt = nil
+	r.approvals = append(r.approvals, approval)
+	return approval, nil
+}
+
+// GetApproval возвращает отложенный перевод по ID.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - id: Идентификатор отложенного перевода.
+//
+// Возвращает:
+//   - Отложенный перевод.
+//   - Ошибку, оборачивающую ErrApprovalNotFound, если перевода нет.
+func (r *MemoryRepository) GetApproval(ctx context.Context, id int64) (models.Approval, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id < 1 || id > int64(len(r.approvals)) {
+		return models.Approval{}, fmt.Errorf("failed to get approval: %w", ErrApprovalNotFound)
+	}
+	return r.approvals[id-1], nil
+}
+
+// GetApprovals возвращает последние count отложенных переводов, начиная с самого нового.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - status: Статус переводов (пустая строка - любой).
+//   - count: Количество переводов.
+//
+// Возвращает:
+//   - Список отложенных переводов.
+//   - Ошибку (для этой реализации всегда nil).
+func (r *MemoryRepository) GetApprovals(ctx context.Context, status string, count int) ([]models.Approval, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var approvals []models.Approval
+	for i := len(r.approvals) - 1; i >= 0 && len(approvals) < count; i-- {
+		if status == "" || r.approvals[i].Status == status {
+			approvals = append(approvals, r.approvals[i])
+		}
+	}
+	return approvals, nil
+}
+
+// UpdateApprovalStatus переводит отложенный перевод из статуса from в статус to.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - id: Идентификатор отложенного перевода.
+//   - from: Ожидаемый текущий статус.
+//   - to: Новый статус.
+//   - reason: Причина (для отклонения или неудачного выполнения).
+//
+// Возвращает:
+//   - Отложенный перевод с новым статусом.
+//   - Ошибку, оборачивающую ErrApprovalNotFound, если перевода нет, или ErrApprovalStatus,
+//     если его статус не from.
+func (r *MemoryRepository) UpdateApprovalStatus(ctx context.Context, id int64, from, to, reason string) (models.Approval, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.updateApprovalStatus(id, from, to, reason)
+}
+
+// updateApprovalStatus меняет статус отложенного перевода; вызывается под блокировкой r.mu.
+func (r *MemoryRepository) updateApprovalStatus(id int64, from, to, reason string) (models.Approval, error) {
+	if id < 1 || id > int64(len(r.approvals)) {
+		return models.Approval{}, fmt.Errorf("failed to get approval: %w", ErrApprovalNotFound)
+	}
+	approval := &r.approvals[id-1]
+	if approval.Status != from {
+		return models.Approval{}, fmt.Errorf("%w: approval %d is %s", ErrApprovalStatus, id, approval.Status)
+	}
+	now := time.Now().UTC()
+	approval.Status = to
+	approval.Reason = reason
+	approval.DecidedAt = &now
+	return *approval, nil
+}
+
+// ExpireApprovals переводит в статус models.ApprovalExpired переводы, ожидающие решения
+// с момента раньше before.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - before: Граница времени постановки в очередь.
+//
+// Возвращает:
+//   - Количество истекших переводов.
+//   - Ошибку (для этой реализации всегда nil).
+func (r *MemoryRepository) ExpireApprovals(ctx context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	expired := 0
+	now := time.Now().UTC()
+	for i := range r.approvals {
+		approval := &r.approvals[i]
+		if approval.Status == models.ApprovalAwaitingReview && approval.CreatedAt.Before(before) {
+			approval.Status = models.ApprovalExpired
+			approval.Reason = "not reviewed in time"
+			approval.DecidedAt = &now
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+// ClaimNotifications выбирает уведомления, ожидающие доставки, и откладывает их следующую
+// попытку до now + lease.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - now: Текущее время; выбираются уведомления с попыткой, назначенной не позже него.
+//   - lease: Время, через которое уведомление выбирается снова, если результат попытки
+//     не записан.
+//   - limit: Наибольшее количество уведомлений.
+//
+// Возвращает:
+//   - Выбранные уведомления в порядке ID.
+//   - Ошибку (для этой реализации всегда nil).
+func (r *MemoryRepository) ClaimNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Notification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var claimed []models.Notification
+	for i := range r.notifications {
+		if len(claimed) == limit {
+			break
+		}
+		n := &r.notifications[i]
+		if n.Status != models.NotificationPending || n.NextAttemptAt.After(now) {
+			continue
+		}
+		n.Attempts++
+		n.NextAttemptAt = now.Add(lease).UTC()
+		claimed = append(claimed, *n)
+	}
+	return claimed, nil
+}
+
+// CompleteNotification записывает результат попытки доставки уведомления.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - id: Идентификатор уведомления.
+//   - status: models.NotificationSent, models.NotificationFailed или models.NotificationPending
+//     (повторная попытка в момент at).
+//   - lastError: Ошибка попытки (пустая строка при доставке).
+//   - at: Время завершения или следующей попытки.
+//
+// Возвращает:
+//   - Ошибку (для этой реализации всегда nil; неизвестный id пропускается, как в SQL-хранилищах).
+func (r *MemoryRepository) CompleteNotification(ctx context.Context, id int64, status, lastError string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id < 1 || id > int64(len(r.notifications)) {
+		return nil
+	}
+	n := &r.notifications[id-1]
+	n.Status, n.LastError = status, lastError
+	at = at.UTC()
+	if status == models.NotificationPending {
+		n.NextAttemptAt = at
+	} else {
+		n.CompletedAt = &at
+	}
+	return nil
+}
+
+// GetNotifications возвращает последние count уведомлений, начиная с самого нового.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - status: Статус уведомлений (пустая строка - любой).
+//   - count: Количество уведомлений.
+//
+// Возвращает:
+//   - Список уведомлений.
+//   - Ошибку (для этой реализации всегда nil).
+func (r *MemoryRepository) GetNotifications(ctx context.Context, status string, count int) ([]models.Notification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var notifications []models.Notification
+	for i := len(r.notifications) - 1; i >= 0 && len(notifications) < count; i-- {
+		if status == "" || r.notifications[i].Status == status {
+			notifications = append(notifications, r.notifications[i])
+		}
+	}
+	return notifications, nil
+}
+
+// Reconcile проверяет инварианты хранилища.
+//
+// Возвращает:
+//   - Сводку проверки; нарушения сообщает ReconcileReport.Drift.
+//   - Ошибку (для этой реализации всегда nil).
+func (r *MemoryRepository) Reconcile(ctx context.Context) (ReconcileReport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := ReconcileReport{Wallets: len(r.wallets)}
+	addresses := make([]string, 0, len(r.wallets))
+	for address := range r.wallets {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	for _, address := range addresses {
+		balance := r.wallets[address]
+		report.TotalBalance = report.TotalBalance.Add(balance)
+		if balance.IsNegative() && len(report.NegativeBalances) < reconcileSampleSize {
+			report.NegativeBalances = append(report.NegativeBalances, address)
+		}
+		if r.reserved(address).GreaterThan(balance) && len(report.OverReserved) < reconcileSampleSize {
+			report.OverReserved = append(report.OverReserved, address)
+		}
+		if _, archived := r.archived[address]; archived && !balance.IsZero() && len(report.ArchivedWithBalance) < reconcileSampleSize {
+			report.ArchivedWithBalance = append(report.ArchivedWithBalance, address)
+		}
+	}
+	for _, t := range r.transactions {
+		_, fromExists := r.wallets[t.From]
+		_, toExists := r.wallets[t.To]
+		if !t.Imported && (!fromExists || !toExists) {
+			report.OrphanTransactions++
+		}
+	}
+
+	expected := make(map[string]decimal.Decimal, len(r.wallets))
+	for address, opening := range r.openings {
+		expected[address] = opening
+	}
+	for _, source := range [][]models.Transaction{r.transactions, r.archive} {
+		for _, t := range source {
+			if t.Imported {
+				continue
+			}
+			expected[t.From] = expected[t.From].Sub(t.Amount)
+			expected[t.To] = expected[t.To].Add(t.Amount)
+			switch t.Type {
+			case models.TransactionMint:
+				report.Minted = report.Minted.Add(t.Amount)
+			case models.TransactionBurn:
+				report.Burned = report.Burned.Add(t.Amount)
+			}
+		}
+	}
+	// Счет эмиссии не проверяется: выпуск и изъятие не меняют его баланс
+	mint := SystemAccount(SystemMint)
+	var openingSupply decimal.Decimal
+	for _, address := range addresses {
+		if address == mint {
+			continue
+		}
+		openingSupply = openingSupply.Add(r.openings[address])
+		balance := r.wallets[address]
+		if !expected[address].Equal(balance) && len(report.BalanceMismatches) < reconcileSampleSize {
+			report.BalanceMismatches = append(report.BalanceMismatches,
+				BalanceMismatch{Address: address, Stored: balance, Expected: expected[address]})
+		}
+	}
+	report.ExpectedSupply = openingSupply.Add(report.Minted).Sub(report.Burned)
+	return report, nil
+}
+
+// Ping проверяет доступность хранилища. Хранилище в памяти доступно всегда.
+func (r *MemoryRepository) Ping(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,27 @@
+package db_test
+
+import (
+	"fmt"
+	"testing"
+
+	"payment-system/internal/db"
+	"payment-system/internal/db/dbtest"
+)
+
+func TestMemoryRepository(t *testing.T) {
+	dbtest.RunContract(t, func(t *testing.T) db.Repository {
+		return db.NewMemoryRepository()
+	})
+}
+
+func BenchmarkMemorySend(b *testing.B) {
+	for _, wallets := range []int{2, 64} {
+		b.Run(fmt.Sprintf("wallets=%d", wallets), func(b *testing.B) {
+			benchmarkSends(b, db.NewMemoryRepository(), wallets)
+		})
+	}
+}
+
+func BenchmarkMemoryTransactionFeed(b *testing.B) {
+	benchmarkTransactionFeed(b, db.NewMemoryRepository())
+}
@@ -0,0 +1,131 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+
+	"payment-system/internal/models"
+)
+
+// notificationColumns - столбцы уведомления в порядке, ожидаемом scanNotification.
+const notificationColumns = "id, transaction_id, address, email, from_address, from_label, amount, transferred_at, status, attempts, last_error, next_attempt_at, completed_at"
+
+// scanNotification читает уведомление из строки результата с notificationColumns.
+func scanNotification(row rowScanner) (models.Notification, error) {
+	var n models.Notification
+	var completedAt sql.NullTime
+	if err := row.Scan(&n.ID, &n.TransactionID, &n.Address, &n.Email, &n.From, &n.FromLabel, &n.Amount,
+		&n.TransferredAt, &n.Status, &n.Attempts, &n.LastError, &n.NextAttemptAt, &completedAt); err != nil {
+		return models.Notification{}, err
+	}
+	n.TransferredAt = n.TransferredAt.UTC()
+	n.NextAttemptAt = n.NextAttemptAt.UTC()
+	if completedAt.Valid {
+		t := completedAt.Time.UTC()
+		n.CompletedAt = &t
+	}
+	return n, nil
+}
+
+// scanNotifications читает уведомления из результата запроса с notificationColumns.
+func scanNotifications(rows *sql.Rows) ([]models.Notification, error) {
+	defer rows.Close()
+
+	var notifications []models.Notification
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return notifications, nil
+}
+
+// Ниже - запросы к таблице notifications, общие для PostgreSQL и SQLite.
+// Моменты времени передаются в виде, сравнимом со столбцами времени конкретной базы:
+// time.Time для PostgreSQL, sqliteTime для SQLite.
+
+// enqueueNotification ставит в очередь уведомление получателю транзакции в рамках транзакции tx,
+// если у него задан адрес для уведомлений. Метка отправителя запоминается на момент перевода.
+// at - время транзакции, с которого уведомление можно доставлять.
+func enqueueNotification(ctx context.Context, tx *sql.Tx, transaction models.Transaction, at interface{}) error {
+	var email, fromLabel string
+	err := tx.QueryRowContext(ctx, `
+		SELECT r.notify_email, COALESCE(s.label, '')
+		FROM wallets r LEFT JOIN wallets s ON s.address = $2
+		WHERE r.address = $1 AND r.notify_email IS NOT NULL`,
+		transaction.To, transaction.From).Scan(&email, &fromLabel)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Получатель не подписан на уведомления
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to enqueue notification: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO notifications (transaction_id, address, email, from_address, from_label, amount, transferred_at, status, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $7)`,
+		transaction.ID, transaction.To, email, transaction.From, fromLabel, transaction.Amount, at, models.NotificationPending)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue notification: %w", err)
+	}
+	return nil
+}
+
+// claimNotifications выбирает до limit уведомлений, ожидающих доставки не позже now, увеличивает
+// их счетчик попыток и откладывает следующую попытку до until одним UPDATE. lock - блокировка
+// подзапроса (" FOR UPDATE SKIP LOCKED" в PostgreSQL, пустая строка в SQLite, где запись
+// и так исключительна), чтобы параллельные экземпляры выбрали разные уведомления.
+func claimNotifications(ctx context.Context, db *sql.DB, lock string, now, until interface{}, limit int) ([]models.Notification, error) {
+	rows, err := db.QueryContext(ctx, `
+		UPDATE notifications SET attempts = attempts + 1, next_attempt_at = $3
+		WHERE id IN (
+			SELECT id FROM notifications WHERE status = $1 AND next_attempt_at <= $2
+			ORDER BY next_attempt_at, id LIMIT $4`+lock+`
+		)
+		RETURNING `+notificationColumns,
+		models.NotificationPending, now, until, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim notifications: %w", err)
+	}
+	notifications, err := scanNotifications(rows)
+	if err != nil {
+		return nil, err
+	}
+	// RETURNING не гарантирует порядок строк
+	sort.Slice(notifications, func(i, j int) bool { return notifications[i].ID < notifications[j].ID })
+	return notifications, nil
+}
+
+// completeNotification записывает результат попытки доставки: статус models.NotificationPending
+// назначает следующую попытку на at, остальные статусы завершают доставку в момент at.
+func completeNotification(ctx context.Context, db *sql.DB, id int64, status, lastError string, at interface{}) error {
+	query := "UPDATE notifications SET status = $2, last_error = $3, completed_at = $4 WHERE id = $1"
+	if status == models.NotificationPending {
+		query = "UPDATE notifications SET status = $2, last_error = $3, next_attempt_at = $4 WHERE id = $1"
+	}
+	if _, err := db.ExecContext(ctx, query, id, status, lastError, at); err != nil {
+		return fmt.Errorf("failed to complete notification: %w", err)
+	}
+	return nil
+}
+
+// getNotifications возвращает последние count уведомлений со статусом status (пустая строка -
+// с любым), начиная с самого нового.
+func getNotifications(ctx context.Context, db *sql.DB, status string, count int) ([]models.Notification, error) {
+	rows, err := db.QueryContext(ctx,
+		"SELECT "+notificationColumns+" FROM notifications WHERE $1 = '' OR status = $1 ORDER BY id DESC LIMIT $2",
+		status, count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notifications: %w", err)
+	}
+	return scanNotifications(rows)
+}
@@ -0,0 +1,347 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"payment-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// reconcileSampleSize - наибольшее количество адресов, перечисляемых для каждого нарушения.
+const reconcileSampleSize = 100
+
+// ReconcileReport - результат проверки инвариантов хранилища (см. Repository.Reconcile).
+// Адреса в списках нарушений перечисляются не больше чем по reconcileSampleSize.
+type ReconcileReport struct {
+	Wallets      int             // Количество кошельков
+	TotalBalance decimal.Decimal // Сумма балансов всех кошельков
+
+	NegativeBalances    []string // Кошельки с отрицательным балансом
+	OverReserved        []string // Кошельки, у которых резерв отложенных переводов больше баланса
+	ArchivedWithBalance []string // Архивные кошельки с ненулевым балансом
+	OrphanTransactions  int      // Переводы (не импортированные), участника которых нет среди кошельков
+
+	// BalanceMismatches - кошельки, баланс которых не равен начальному балансу плюс
+	// поступления и минус списания по переводам (см. BalanceMismatch)
+	BalanceMismatches []BalanceMismatch
+
+	Minted decimal.Decimal // Сумма выпуска (models.TransactionMint), включая архив
+	Burned decimal.Decimal // Сумма изъятия (models.TransactionBurn), включая архив
+
+	// ExpectedSupply - сумма балансов, которая должна получиться по истории: начальные балансы
+	// кошельков плюс выпуск и минус изъятие. Отличие от TotalBalance - нарушение: деньги
+	// появились или исчезли без записи выпуска или изъятия.
+	ExpectedSupply decimal.Decimal
+}
+
+// BalanceMismatch - расхождение хранимого баланса кошелька с балансом, вычисленным по переводам:
+// изменение баланса без записи перевода или запись перевода без изменения баланса.
+type BalanceMismatch struct {
+	Address  string
+	Stored   decimal.Decimal // Баланс в wallets.balance
+	Expected decimal.Decimal // Начальный баланс (wallets.opening_balance) плюс сумма изменений по переводам
+}
+
+// Drift сообщает, нарушен ли хотя бы один инвариант.
+func (r ReconcileReport) Drift() bool {
+	return len(r.NegativeBalances) > 0 || len(r.OverReserved) > 0 ||
+		len(r.ArchivedWithBalance) > 0 || r.OrphanTransactions > 0 || len(r.BalanceMismatches) > 0 ||
+		!r.TotalBalance.Equal(r.ExpectedSupply)
+}
+
+// querier - общий интерфейс *sql.DB и *sql.Tx для запросов чтения.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// reconcile проверяет инварианты хранилища. Балансы сравниваются в Go, так как SQLite
+// хранит их текстом; отрицательный баланс узнается по знаку в текстовой записи.
+// Синтаксис совместим с PostgreSQL и SQLite; sum - агрегатная функция точной суммы
+// конкретной базы (pgSum или sqliteSum).
+//
+// Все проверки читают один снимок базы (транзакция только для чтения с REPEATABLE READ;
+// в SQLite снимок дает сама транзакция), поэтому переводы, выполняемые во время проверки,
+// не дают ложных расхождений между балансами и историей.
+func reconcile(ctx context.Context, db *sql.DB, sum string) (ReconcileReport, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return ReconcileReport{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var report ReconcileReport
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(*), COALESCE("+sum+"(balance), 0) FROM wallets").
+		Scan(&report.Wallets, &report.TotalBalance)
+	if err != nil {
+		return ReconcileReport{}, fmt.Errorf("failed to sum balances: %w", err)
+	}
+
+	negative, err := queryAddresses(ctx, tx,
+		"SELECT address FROM wallets WHERE CAST(balance AS TEXT) LIKE '-%' ORDER BY address LIMIT $1", reconcileSampleSize)
+	if err != nil {
+		return ReconcileReport{}, err
+	}
+	report.NegativeBalances = negative
+
+	// Резерв не может превышать баланс: отложенный перевод создается и выполняется
+	// только при достаточном доступном остатке
+	rows, err := tx.QueryContext(ctx, "SELECT address, balance, "+reservedColumn(sum)+` FROM wallets
+		WHERE address IN (SELECT from_address FROM pending_approvals WHERE status = $1)
+		ORDER BY address`, models.ApprovalAwaitingReview)
+	if err != nil {
+		return ReconcileReport{}, fmt.Errorf("failed to query reserves: %w", err)
+	}
+	for rows.Next() {
+		var address string
+		var total, reserved decimal.Decimal
+		if err := rows.Scan(&address, &total, &reserved); err != nil {
+			rows.Close()
+			return ReconcileReport{}, fmt.Errorf("failed to scan reserve: %w", err)
+		}
+		if reserved.GreaterThan(total) && len(report.OverReserved) < reconcileSampleSize {
+			report.OverReserved = append(report.OverReserved, address)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return ReconcileReport{}, fmt.Errorf("rows error: %w", err)
+	}
+
+	// Архивируется только пустой кошелек, а переводы на архивный кошелек отклоняются
+	rows, err = tx.QueryContext(ctx, "SELECT address, balance FROM wallets WHERE archived_at IS NOT NULL ORDER BY address")
+	if err != nil {
+		return ReconcileReport{}, fmt.Errorf("failed to query archived wallets: %w", err)
+	}
+	for rows.Next() {
+		var address string
+		var balance decimal.Decimal
+		if err := rows.Scan(&address, &balance); err != nil {
+			rows.Close()
+			return ReconcileReport{}, fmt.Errorf("failed to scan archived wallet: %w", err)
+		}
+		if !balance.IsZero() && len(report.ArchivedWithBalance) < reconcileSampleSize {
+			report.ArchivedWithBalance = append(report.ArchivedWithBalance, address)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return ReconcileReport{}, fmt.Errorf("rows error: %w", err)
+	}
+
+	// Кошельки не удаляются (только архивируются), поэтому у каждого перевода есть оба участника.
+	// Импортированные транзакции ссылаются на кошельки другой системы и не проверяются
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM transactions
+		WHERE NOT imported AND (
+			NOT EXISTS (SELECT 1 FROM wallets WHERE wallets.address = transactions.from_address) OR
+			NOT EXISTS (SELECT 1 FROM wallets WHERE wallets.address = transactions.to_address))`).
+		Scan(&report.OrphanTransactions)
+	if err != nil {
+		return ReconcileReport{}, fmt.Errorf("failed to check transaction parties: %w", err)
+	}
+
+	var openingSupply decimal.Decimal
+	if report.BalanceMismatches, openingSupply, err = balanceMismatches(ctx, tx, sum); err != nil {
+		return ReconcileReport{}, err
+	}
+	if report.Minted, report.Burned, err = mintedAndBurned(ctx, tx, sum); err != nil {
+		return ReconcileReport{}, err
+	}
+	report.ExpectedSupply = openingSupply.Add(report.Minted).Sub(report.Burned)
+	return report, nil
+}
+
+// mintedAndBurned возвращает суммы выпуска и изъятия по истории, включая архив; импортированные
+// транзакции балансы не меняют и не учитываются. Синтаксис совместим с PostgreSQL и SQLite.
+func mintedAndBurned(ctx context.Context, q querier, sum string) (minted, burned decimal.Decimal, err error) {
+	rows, err := q.QueryContext(ctx, "SELECT type, "+sum+"(amount) FROM "+TransactionFilter{IncludeArchived: true}.source()+
+		" WHERE NOT imported AND type IN ($1, $2) GROUP BY type", models.TransactionMint, models.TransactionBurn)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, fmt.Errorf("failed to sum mint and burn: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var txType string
+		var total decimal.Decimal
+		if err := rows.Scan(&txType, &total); err != nil {
+			return decimal.Zero, decimal.Zero, fmt.Errorf("failed to scan mint and burn: %w", err)
+		}
+		if txType == models.TransactionMint {
+			minted = total
+		} else {
+			burned = total
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return decimal.Zero, decimal.Zero, fmt.Errorf("rows error: %w", err)
+	}
+	return minted, burned, nil
+}
+
+// walletDeltas возвращает для каждого кошелька сумму изменений баланса по переводам:
+// поступления минус списания, включая перенесенные в архив. Импортированные транзакции
+// балансы не меняют и не учитываются. Синтаксис совместим с PostgreSQL и SQLite.
+func walletDeltas(ctx context.Context, q querier, sum string) (map[string]decimal.Decimal, error) {
+	deltas := make(map[string]decimal.Decimal)
+	source := TransactionFilter{IncludeArchived: true}.source()
+	for _, side := range []struct {
+		column string
+		sign   int64
+	}{{"to_address", 1}, {"from_address", -1}} {
+		rows, err := q.QueryContext(ctx, "SELECT "+side.column+", "+sum+"(amount) FROM "+source+
+			" WHERE NOT imported GROUP BY "+side.column)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sum transfers: %w", err)
+		}
+		for rows.Next() {
+			var address string
+			var total decimal.Decimal
+			if err := rows.Scan(&address, &total); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan transfer sum: %w", err)
+			}
+			deltas[address] = deltas[address].Add(total.Mul(decimal.NewFromInt(side.sign)))
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("rows error: %w", err)
+		}
+	}
+	return deltas, nil
+}
+
+// balanceMismatches сравнивает баланс каждого кошелька с начальным балансом плюс изменения
+// по переводам (walletDeltas). Кошельки перебираются в Go, так как SQLite хранит суммы текстом.
+// Кошельки без начального баланса (см. backfillOpeningBalances) пропускаются. Счет эмиссии
+// не проверяется: выпуск и изъятие не меняют его баланс.
+//
+// Возвращает:
+//   - Расхождения (не больше reconcileSampleSize).
+//   - Сумму начальных балансов кошельков, кроме счета эмиссии; для кошелька без начального
+//     баланса берется баланс, согласованный с историей.
+//   - Ошибку запроса.
+func balanceMismatches(ctx context.Context, q querier, sum string) ([]BalanceMismatch, decimal.Decimal, error) {
+	deltas, err := walletDeltas(ctx, q, sum)
+	if err != nil {
+		return nil, decimal.Zero, err
+	}
+	rows, err := q.QueryContext(ctx, "SELECT address, balance, opening_balance FROM wallets ORDER BY address")
+	if err != nil {
+		return nil, decimal.Zero, fmt.Errorf("failed to query balances: %w", err)
+	}
+	defer rows.Close()
+
+	mint := SystemAccount(SystemMint)
+	var mismatches []BalanceMismatch
+	var openingSupply decimal.Decimal
+	for rows.Next() {
+		var m BalanceMismatch
+		var opening decimal.NullDecimal
+		if err := rows.Scan(&m.Address, &m.Stored, &opening); err != nil {
+			return nil, decimal.Zero, fmt.Errorf("failed to scan balance: %w", err)
+		}
+		if m.Address == mint {
+			continue
+		}
+		// Начальный баланс кошелька, созданного предыдущей версией, заполнится при следующем запуске
+		if !opening.Valid {
+			openingSupply = openingSupply.Add(m.Stored.Sub(deltas[m.Address]))
+			continue
+		}
+		openingSupply = openingSupply.Add(opening.Decimal)
+		m.Expected = opening.Decimal.Add(deltas[m.Address])
+		if !m.Expected.Equal(m.Stored) && len(mismatches) < reconcileSampleSize {
+			mismatches = append(mismatches, m)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, decimal.Zero, fmt.Errorf("rows error: %w", err)
+	}
+	return mismatches, openingSupply, nil
+}
+
+// backfillOpeningBalances заполняет wallets.opening_balance у кошельков, созданных до появления
+// столбца (или экземпляром предыдущей версии): начальным считается баланс, при котором текущий
+// баланс согласован с историей переводов. Расхождения, возникшие до заполнения, поэтому
+// не обнаруживаются, а последующие - обнаруживаются. Балансы и история читаются одним снимком;
+// если во время заполнения параллельный перевод изменит кошелек, PostgreSQL прервет транзакцию,
+// и запуск нужно повторить. Если незаполненных кошельков нет, ничего не делает.
+// Синтаксис совместим с PostgreSQL и SQLite; sum - агрегатная функция точной суммы конкретной базы.
+func backfillOpeningBalances(db *sql.DB, sum string) error {
+	var missing int
+	if err := db.QueryRow("SELECT COUNT(*) FROM wallets WHERE opening_balance IS NULL").Scan(&missing); err != nil {
+		return fmt.Errorf("failed to inspect opening balances: %w", err)
+	}
+	if missing == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	deltas, err := walletDeltas(ctx, tx, sum)
+	if err != nil {
+		return err
+	}
+	// Значения читаются целиком до обновления: транзакция занимает одно подключение
+	rows, err := tx.QueryContext(ctx, "SELECT address, balance FROM wallets WHERE opening_balance IS NULL")
+	if err != nil {
+		return fmt.Errorf("failed to query wallets: %w", err)
+	}
+	openings := make(map[string]decimal.Decimal, missing)
+	for rows.Next() {
+		var address string
+		var balance decimal.Decimal
+		if err := rows.Scan(&address, &balance); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan wallet: %w", err)
+		}
+		openings[address] = balance.Sub(deltas[address])
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+
+	for address, opening := range openings {
+		if _, err := tx.ExecContext(ctx, "UPDATE wallets SET opening_balance = $1 WHERE address = $2", opening, address); err != nil {
+			return fmt.Errorf("failed to backfill opening balance: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to backfill opening balances: %w", err)
+	}
+	slog.Info("opening balances backfilled", "wallets", len(openings))
+	return nil
+}
+
+// queryAddresses выполняет запрос, возвращающий один столбец с адресами.
+func queryAddresses(ctx context.Context, db querier, query string, args ...interface{}) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wallets: %w", err)
+	}
+	defer rows.Close()
+
+	var addresses []string
+	for rows.Next() {
+		var address string
+		if err := rows.Scan(&address); err != nil {
+			return nil, fmt.Errorf("failed to scan wallet address: %w", err)
+		}
+		addresses = append(addresses, address)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return addresses, nil
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// replicaRetryInterval - сколько времени чтение идет на основную базу после сбоя реплики,
+// прежде чем реплика будет опробована снова.
+const replicaRetryInterval = 10 * time.Second
+
+// readReplica - необязательный пул подключений к реплике только для чтения.
+// Запросы на чтение сначала идут в реплику, а при ее сбое - в основную базу.
+type readReplica struct {
+	db        *sql.DB
+	downUntil atomic.Int64 // Unix-время в наносекундах, до которого реплика считается недоступной
+}
+
+// openReadReplica подключается к реплике, если задана переменная DB_READ_HOST.
+// Пользователь, пароль и имя базы берутся из DB_READ_USER, DB_READ_PASSWORD, DB_READ_NAME,
+// а при их отсутствии - из параметров основной базы.
+// Недоступность реплики при запуске не является ошибкой: чтение пойдет в основную базу.
+//
+// Возвращает:
+//   - Реплику или nil, если она не настроена.
+func openReadReplica() *readReplica {
+	host := os.Getenv("DB_READ_HOST")
+	if host == "" {
+		return nil
+	}
+
+	db, err := openDB("pgx", postgresDSN(
+		host,
+		envOr("DB_READ_USER", os.Getenv("DB_USER")),
+		envOr("DB_READ_PASSWORD", os.Getenv("DB_PASSWORD")),
+		envOr("DB_READ_NAME", os.Getenv("DB_NAME")),
+	), semconv.DBSystemPostgreSQL)
+	if err != nil {
+		log.Fatal("Failed to configure read replica:", err)
+	}
+	configurePool(db)
+
+	replica := &readReplica{db: db}
+	ctx, cancel := withQueryTimeout(context.Background(), queryTimeoutFromEnv())
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		slog.Warn("read replica unavailable, reads go to the primary", "host", host, "error", err)
+		replica.markDown()
+	} else {
+		slog.Info("reads go to the replica", "host", host)
+	}
+	return replica
+}
+
+// markDown переключает чтение на основную базу на replicaRetryInterval.
+func (r *readReplica) markDown() {
+	r.downUntil.Store(time.Now().Add(replicaRetryInterval).UnixNano())
+}
+
+// available сообщает, стоит ли направлять чтение в реплику.
+func (r *readReplica) available() bool {
+	return time.Now().UnixNano() >= r.downUntil.Load()
+}
+
+// read выполняет запрос на чтение: в реплике, если она настроена и доступна, иначе в основной базе.
+// При сбое реплики (любая ошибка, кроме sql.ErrNoRows) запрос повторяется в основной базе,
+// а реплика временно исключается. Если и основная база ответила обрывом подключения
+// (например, при перезапуске), запрос повторяется на новом подключении (см. retryConnection).
+//
+// Параметры:
+//   - ctx: Контекст вызывающего; время каждой попытки ограничено DB_QUERY_TIMEOUT.
+//   - query: Идемпотентный запрос, получающий пул подключений и контекст с ограничением времени.
+//
+// Возвращает:
+//   - Ошибку запроса.
+func (r *PostgresRepository) read(ctx context.Context, query func(ctx context.Context, db *sql.DB) error) error {
+	return retryConnection(func() error { return r.readOnce(ctx, query) })
+}
+
+// readOnce выполняет одну попытку read.
+func (r *PostgresRepository) readOnce(ctx context.Context, query func(ctx context.Context, db *sql.DB) error) error {
+	if r.replica != nil && r.replica.available() {
+		ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+		err := query(ctx, r.replica.db)
+		cancel()
+		if err == nil || errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		slog.Warn("replica read failed, retrying on the primary", "error", err)
+		r.replica.markDown()
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+	return query(ctx, r.db)
+}
+
+// envOr возвращает значение переменной окружения или defaultValue, если она не задана.
+func envOr(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
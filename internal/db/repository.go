@@ -1,258 +1,1749 @@
-// Package db предоставляет функционал для работы с базой данных PostgreSQL.
-// Включает создание таблиц, генерацию кошельков, перевод средств между кошельками,
-// получение баланса и списка транзакций.
-package db
-
-import (
-	"context"
-	"crypto/rand"
-	"database/sql"
-	"encoding/hex"
-	"fmt"
-	"log"
-	"os"
-	"payment-system/internal/models"
-
-	_ "github.com/lib/pq"
-)
-
-// PostgresRepository представляет репозиторий для работы с PostgreSQL.
-type PostgresRepository struct {
-	db *sql.DB
-}
-
-// NewPostgresRepository создает новый экземпляр PostgresRepository.
-// Подключается к базе данных PostgreSQL, инициализирует таблицы и создает 10 кошельков
-// с балансом 100.0, если таблица пуста.
-//
-// Пример использования:
-//
-//	repo := NewPostgresRepository()
-func NewPostgresRepository() *PostgresRepository {
-	db, err := sql.Open("postgres", fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		os.Getenv("DB_HOST"),
-		"5432",
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_NAME"),
-	))
-	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
-	}
-
-	// Инициализация таблиц
-	if err := initTables(db); err != nil {
-		log.Fatal("Failed to initialize tables:", err)
-	}
-
-	// Создание 10 кошельков с балансом 100.0
-	if err := generateWallets(db, 10, 100.0); err != nil {
-		log.Fatal("Failed to generate wallets:", err)
-	}
-
-	return &PostgresRepository{db: db}
-}
-
-// initTables создает таблицы wallets и transactions, если они не существуют.
-//
-// Параметры:
-//   - db: Указатель на подключение к базе данных.
-//
-// Возвращает:
-//   - Ошибку, если не удалось создать таблицы.
-func initTables(db *sql.DB) error {
-	_, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS wallets (
-			address TEXT PRIMARY KEY,
-			balance FLOAT
-		);
-		CREATE TABLE IF NOT EXISTS transactions (
-			id SERIAL PRIMARY KEY,
-			from_address TEXT,
-			to_address TEXT,
-			amount FLOAT,
-			timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		);
-	`)
-	return err
-}
-
-// generateWallets создает указанное количество кошельков с заданным балансом.
-//
-// Параметры:
-//   - db: Указатель на подключение к базе данных.
-//   - count: Количество кошельков для создания.
-//   - balance: Начальный баланс каждого кошелька.
-//
-// Возвращает:
-//   - Ошибку, если не удалось создать кошельки.
-func generateWallets(db *sql.DB, count int, balance float64) error {
-	for i := 0; i < count; i++ {
-		address := generateRandomAddress()
-		_, err := db.Exec("INSERT INTO wallets (address, balance) VALUES ($1, $2) ON CONFLICT (address) DO NOTHING", address, balance)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-// GetBalance возвращает баланс кошелька по его адресу.
-//
-// Параметры:
-//   - address: Адрес кошелька.
-//
-// Возвращает:
-//   - Баланс кошелька.
-//   - Ошибку, если кошелек не найден или произошла другая ошибка.
-//
-// Пример использования:
-//
-//	balance, err := repo.GetBalance("some_address")
-func (r *PostgresRepository) GetBalance(address string) (float64, error) {
-	var balance float64
-	err := r.db.QueryRow("SELECT balance FROM wallets WHERE address = $1", address).Scan(&balance)
-	if err != nil {
-		return 0, fmt.Errorf("failed to get balance: %w", err)
-	}
-	return balance, nil
-}
-
-// Send выполняет перевод средств с одного кошелька на другой.
-// Включает проверку баланса отправителя, обновление балансов и запись транзакции.
-//
-// Параметры:
-//   - from: Адрес кошелька отправителя.
-//   - to: Адрес кошелька получателя.
-//   - amount: Сумма перевода.
-//
-// Возвращает:
-//   - Ошибку, если перевод не удался (например, недостаточно средств).
-//
-// Пример использования:
-//
-//	err := repo.Send("from_address", "to_address", 10.5)
-func (r *PostgresRepository) Send(from, to string, amount float64) error {
-	tx, err := r.db.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback()
-
-	// Проверка баланса отправителя
-	var fromBalance float64
-	err = tx.QueryRow("SELECT balance FROM wallets WHERE address = $1", from).Scan(&fromBalance)
-	if err != nil {
-		return fmt.Errorf("failed to get sender balance: %w", err)
-	}
-
-	if fromBalance < amount {
-		return fmt.Errorf("insufficient funds")
-	}
-
-	// Обновление баланса отправителя
-	_, err = tx.Exec("UPDATE wallets SET balance = balance - $1 WHERE address = $2", amount, from)
-	if err != nil {
-		return fmt.Errorf("failed to update sender balance: %w", err)
-	}
-
-	// Обновление баланса получателя
-	_, err = tx.Exec("UPDATE wallets SET balance = balance + $1 WHERE address = $2", amount, to)
-	if err != nil {
-		return fmt.Errorf("failed to update receiver balance: %w", err)
-	}
-
-	// Запись транзакции
-	_, err = tx.Exec("INSERT INTO transactions (from_address, to_address, amount) VALUES ($1, $2, $3)", from, to, amount)
-	if err != nil {
-		return fmt.Errorf("failed to record transaction: %w", err)
-	}
-
-	return tx.Commit()
-}
-
-// GetLastTransactions возвращает список последних N транзакций.
-//
-// Параметры:
-//   - count: Количество транзакций.
-//
-// Возвращает:
-//   - Список транзакций.
-//   - Ошибку, если произошла ошибка при выполнении запроса.
-//
-// Пример использования:
-//
-//	transactions, err := repo.GetLastTransactions(5)
-func (r *PostgresRepository) GetLastTransactions(count int) ([]models.Transaction, error) {
-	rows, err := r.db.Query("SELECT from_address, to_address, amount, timestamp FROM transactions ORDER BY timestamp DESC LIMIT $1", count)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query transactions: %w", err)
-	}
-	defer rows.Close()
-
-	var transactions []models.Transaction
-	for rows.Next() {
-		var t models.Transaction
-		err := rows.Scan(&t.From, &t.To, &t.Amount, &t.CreatedAt)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan transaction: %w", err)
-		}
-		transactions = append(transactions, t)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("rows error: %w", err)
-	}
-
-	return transactions, nil
-}
-
-// GenerateAddress генерирует случайный адрес длиной 64 символа (32 байта в hex).
-//
-// Возвращает:
-//   - Сгенерированный адрес.
-//   - Ошибку, если не удалось сгенерировать случайные байты.
-//
-// Пример использования:
-//
-//	address, err := GenerateAddress()
-func GenerateAddress() (string, error) {
-	buffer := make([]byte, 32)
-	_, err := rand.Read(buffer)
-	if err != nil {
-		return "", fmt.Errorf("failed to generate random bytes: %w", err)
-	}
-	return hex.EncodeToString(buffer), nil
-}
-
-// generateRandomAddress генерирует случайный адрес кошелька.
-// В случае ошибки завершает выполнение программы.
-//
-// Возвращает:
-//   - Сгенерированный адрес.
-func generateRandomAddress() string {
-	address, err := GenerateAddress()
-	if err != nil {
-		log.Fatal("Failed to generate wallet address:", err)
-	}
-	return address
-}
-
-// Ping проверяет подключение к базе данных.
-//
-// Параметры:
-//   - ctx: Контекст для выполнения запроса.
-//
-// Возвращает:
-//   - Ошибку, если подключение не удалось.
-//
-// Пример использования:
-//
-//	err := repo.Ping(context.Background())
-func (r *PostgresRepository) Ping(ctx context.Context) error {
-	if err := r.db.PingContext(ctx); err != nil {
-		return fmt.Errorf("failed to ping database: %w", err)
-	}
-	return nil
-}
+// Package db предоставляет функционал для работы с базой данных PostgreSQL.
+// Включает создание таблиц, генерацию кошельков, перевод средств между кошельками,
+// получение баланса и списка транзакций.
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	mrand "math/rand/v2"
+	"os"
+	"payment-system/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/shopspring/decimal"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Коды SQLSTATE PostgreSQL, которые отображаются на ошибки репозитория.
+const (
+	pgUniqueViolation = "23505" // нарушение уникальности (адрес кошелька занят)
+	pgCheckViolation  = "23514" // нарушение CHECK-ограничения (balance >= 0)
+	pgNumericOverflow = "22003" // результат арифметики вне диапазона типа (переполнение баланса)
+
+	pgSerializationFailure = "40001" // конфликт сериализации, транзакцию можно повторить
+	pgDeadlockDetected     = "40P01" // взаимоблокировка, транзакцию можно повторить
+)
+
+// pgSum - агрегатная функция суммы в PostgreSQL: SUM над NUMERIC точна.
+const pgSum = "SUM"
+
+// sendRetryBaseDelay - базовая задержка перед повтором перевода; удваивается с каждой попыткой.
+const sendRetryBaseDelay = 10 * time.Millisecond
+
+// PostgresRepository представляет репозиторий для работы с PostgreSQL.
+type PostgresRepository struct {
+	db            *sql.DB            // Основная база: все записи и чтения внутри транзакций
+	replica       *readReplica       // Реплика для чтения; nil, если DB_READ_HOST не задан
+	queryTimeout  time.Duration      // Ограничение времени одного запроса или транзакции
+	sendAttempts  int                // Количество попыток перевода при конфликтах сериализации
+	lockStrategy  string             // Стратегия блокировки при переводе: lockRow или lockAdvisory
+	sendIsolation sql.IsolationLevel // Уровень изоляции транзакции перевода (DB_SEND_ISOLATION)
+	minBalance    decimal.Decimal    // Неснижаемый остаток кошелька отправителя
+	clock         Clock              // Источник времени записанных транзакций
+	addresses     *AddressGenerator  // Генератор адресов CreateWallets
+}
+
+// NewPostgresRepository создает новый экземпляр PostgresRepository.
+// Подключается к базе данных PostgreSQL и инициализирует таблицы. Кошельки не создаются:
+// демонстрационные кошельки создает service.SeedWallets или команда seed.
+//
+// Инициализация выполняется под рекомендательной блокировкой (см. withInitLock): экземпляры,
+// запущенные одновременно с общей базой, ждут друг друга.
+//
+// Если задан DB_READ_HOST, запросы на чтение (GetBalance, GetWallet, GetLastTransactions,
+// CountTransactions, GetAuditEvents, GetRiskEvents и др.) направляются в реплику, а переводы,
+// создание кошельков и чтение, от которого зависит запись (nonce, лимиты, подтверждения),
+// всегда выполняются в основной базе.
+//
+// Пример использования:
+//
+//	repo := NewPostgresRepository()
+func NewPostgresRepository() *PostgresRepository {
+	db, err := openDB("pgx", postgresDSN(
+		os.Getenv("DB_HOST"),
+		os.Getenv("DB_USER"),
+		os.Getenv("DB_PASSWORD"),
+		os.Getenv("DB_NAME"),
+	), semconv.DBSystemPostgreSQL)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	configurePool(db)
+
+	err = withInitLock(db, func() error {
+		// Инициализация таблиц
+		if err := initTables(db); err != nil {
+			return fmt.Errorf("failed to initialize tables: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatal("Failed to initialize database: ", err)
+	}
+
+	lockStrategy := lockStrategyFromEnv()
+	r := &PostgresRepository{
+		db:            db,
+		replica:       openReadReplica(),
+		queryTimeout:  queryTimeoutFromEnv(),
+		sendAttempts:  sendAttemptsFromEnv(),
+		lockStrategy:  lockStrategy,
+		sendIsolation: sendIsolationFromEnv(lockStrategy),
+		minBalance:    MinWalletBalance(),
+		clock:         SystemClock{},
+		addresses:     DefaultAddressGenerator,
+	}
+
+	return r
+}
+
+// SetClock задает источник времени, которым отмечаются записанные транзакции.
+// По умолчанию - SystemClock; тесты подставляют FixedClock. Вызывается до начала
+// обработки запросов.
+//
+// Пример использования:
+//
+//	repo.SetClock(db.NewFixedClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+func (r *PostgresRepository) SetClock(clock Clock) {
+	r.clock = clock
+}
+
+// SetAddressGenerator задает генератор адресов кошельков, создаваемых CreateWallets.
+// По умолчанию - DefaultAddressGenerator. Вызывается до начала обработки запросов.
+//
+// Пример использования:
+//
+//	repo.SetAddressGenerator(db.NewAddressGenerator(mrand.NewChaCha8([32]byte{})))
+func (r *PostgresRepository) SetAddressGenerator(addresses *AddressGenerator) {
+	r.addresses = addresses
+}
+
+// Режимы выполнения запросов pgx (параметр default_query_exec_mode строки подключения).
+const (
+	// execCacheStatement - запрос готовится (Prepare) при первом выполнении на подключении,
+	// подготовленный оператор хранится в кэше подключения и выполняется повторно без разбора SQL.
+	execCacheStatement = "cache_statement"
+
+	// execCacheDescribe - кэшируется только описание параметров и результата, без именованных
+	// подготовленных операторов; для PgBouncer в режиме транзакций.
+	execCacheDescribe = "cache_describe"
+
+	// execSimpleProtocol - простой протокол без подготовки, как у psql.
+	execSimpleProtocol = "simple_protocol"
+)
+
+// postgresDSN формирует строку подключения к PostgreSQL.
+//
+// Подготовленные операторы горячих запросов (Send, GetBalance, GetLastTransactions) не хранятся
+// в репозитории: в режиме execCacheStatement (по умолчанию) драйвер pgx сам готовит каждый запрос
+// один раз на подключение и повторно использует его, в том числе в транзакциях WithTx и на реплике.
+// *sql.Stmt, созданный db.Prepare, готовился бы заново на каждом новом подключении пула
+// и требовал бы tx.Stmt в каждой транзакции.
+func postgresDSN(host, user, password, dbname string) string {
+	return fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable default_query_exec_mode=%s",
+		host, "5432", user, password, dbname, queryExecModeFromEnv(),
+	)
+}
+
+// queryExecModeFromEnv возвращает режим выполнения запросов из переменной DB_QUERY_EXEC_MODE
+// или execCacheStatement, если она не задана.
+func queryExecModeFromEnv() string {
+	switch value := os.Getenv("DB_QUERY_EXEC_MODE"); value {
+	case "":
+		return execCacheStatement
+	case execCacheStatement, execCacheDescribe, execSimpleProtocol:
+		return value
+	default:
+		log.Fatalf("Invalid DB_QUERY_EXEC_MODE %q, expected %q, %q or %q", value, execCacheStatement, execCacheDescribe, execSimpleProtocol)
+		return ""
+	}
+}
+
+// mapPgError преобразует ошибки PostgreSQL с известным кодом SQLSTATE
+// в ошибки репозитория. Остальные ошибки возвращаются без изменений.
+//
+// Параметры:
+//   - err: Ошибка, полученная от драйвера.
+//
+// Возвращает:
+//   - ErrWalletExists, ErrInsufficientFunds, ErrBalanceOverflow или исходную ошибку.
+func mapPgError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	switch pgErr.Code {
+	case pgUniqueViolation:
+		if pgErr.ConstraintName == "wallets_label_key" {
+			return ErrLabelExists
+		}
+		return ErrWalletExists
+	case pgCheckViolation:
+		return ErrInsufficientFunds
+	case pgNumericOverflow:
+		return ErrBalanceOverflow
+	default:
+		return err
+	}
+}
+
+// initLockKey - ключ рекомендательной блокировки PostgreSQL, под которой экземпляр сервиса
+// создает таблицы и начальные кошельки.
+const initLockKey int64 = 0x7061796d696e6974
+
+// seedLockKey - ключ рекомендательной блокировки PostgreSQL, под которой транзакция проверяет,
+// есть ли кошельки, и создает демонстрационные (см. WithSeedLock).
+const seedLockKey int64 = 0x7061796d73656564
+
+// withInitLock выполняет fn под сессионной рекомендательной блокировкой initLockKey.
+// Экземпляры, запущенные одновременно, выполняют DDL и создание кошельков по очереди,
+// а не параллельно: иначе CREATE TABLE IF NOT EXISTS и ALTER TABLE могли бы конфликтовать.
+// Блокировка держится на отдельном соединении и снимается и при аварийном завершении
+// процесса - вместе с соединением.
+//
+// Параметры:
+//   - db: Подключение к базе данных.
+//   - fn: Инициализация; запросы выполняет через db.
+//
+// Возвращает:
+//   - Ошибку блокировки или ошибку fn.
+func withInitLock(db *sql.DB, fn func() error) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", initLockKey); err != nil {
+		return fmt.Errorf("failed to acquire init lock: %w", err)
+	}
+	// Соединение возвращается в пул, поэтому блокировка снимается явно
+	defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", initLockKey)
+
+	return fn()
+}
+
+// isRetryable сообщает, завершилась ли транзакция конфликтом сериализации
+// или взаимоблокировкой, после которых ее можно безопасно повторить целиком.
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == pgSerializationFailure || pgErr.Code == pgDeadlockDetected
+}
+
+// initTables создает таблицы wallets и transactions, если они не существуют.
+// Суммы и балансы хранятся как NUMERIC(38, 8); столбцы FLOAT, созданные ранними версиями,
+// переводятся в NUMERIC с округлением до 8 знаков (см. migrateAmountColumns).
+//
+// Параметры:
+//   - db: Указатель на подключение к базе данных.
+//
+// Возвращает:
+//   - Ошибку, если не удалось создать таблицы.
+func initTables(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS wallets (
+			address TEXT PRIMARY KEY,
+			balance NUMERIC(38, 8) CHECK (balance >= 0)
+		);
+		CREATE TABLE IF NOT EXISTS transactions (
+			id SERIAL PRIMARY KEY,
+			from_address TEXT,
+			to_address TEXT,
+			amount NUMERIC(38, 8),
+			timestamp TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			memo TEXT
+		);
+		-- Ранние версии хранили время без часового пояса (в поясе сессии)
+		DO $$
+		BEGIN
+			IF (SELECT data_type FROM information_schema.columns
+				WHERE table_name = 'transactions' AND column_name = 'timestamp') = 'timestamp without time zone' THEN
+				ALTER TABLE transactions ALTER COLUMN timestamp TYPE TIMESTAMPTZ;
+			END IF;
+		END $$;
+		ALTER TABLE transactions ADD COLUMN IF NOT EXISTS memo TEXT;
+		ALTER TABLE transactions ADD COLUMN IF NOT EXISTS external_id TEXT;
+		ALTER TABLE transactions ADD COLUMN IF NOT EXISTS imported BOOLEAN NOT NULL DEFAULT FALSE;
+		ALTER TABLE transactions ADD COLUMN IF NOT EXISTS category TEXT;
+		CREATE UNIQUE INDEX IF NOT EXISTS transactions_external_id_key ON transactions (external_id);
+		ALTER TABLE wallets ADD COLUMN IF NOT EXISTS label TEXT;
+		ALTER TABLE wallets ADD COLUMN IF NOT EXISTS tags JSONB NOT NULL DEFAULT '{}';
+		CREATE UNIQUE INDEX IF NOT EXISTS wallets_label_key ON wallets (label);
+		ALTER TABLE wallets ADD COLUMN IF NOT EXISTS public_key TEXT;
+		ALTER TABLE wallets ADD COLUMN IF NOT EXISTS nonce BIGINT NOT NULL DEFAULT 0;
+		ALTER TABLE wallets ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+		CREATE INDEX IF NOT EXISTS transactions_from_address_idx ON transactions (from_address, timestamp);
+		-- История кошелька читается объединением (UNION ALL) запроса по отправителю и запроса
+		-- по получателю: каждый идет по своему индексу (адрес, timestamp) в порядке времени
+		-- и останавливается на LIMIT. Условие from_address = $1 OR to_address = $1 сортирует
+		-- все переводы кошелька и у кошелька с частыми переводами медленнее на два порядка
+		DROP INDEX IF EXISTS transactions_to_address_idx;
+		CREATE INDEX IF NOT EXISTS transactions_to_address_timestamp_idx ON transactions (to_address, timestamp);
+		-- TopWalletsByBalance читает кошельки в порядке индекса и останавливается на LIMIT
+		CREATE INDEX IF NOT EXISTS wallets_balance_idx ON wallets (balance DESC, address);
+		CREATE TABLE IF NOT EXISTS risk_events (
+			id SERIAL PRIMARY KEY,
+			rule TEXT NOT NULL,
+			action TEXT NOT NULL,
+			from_address TEXT NOT NULL,
+			to_address TEXT NOT NULL,
+			amount NUMERIC(38, 8) NOT NULL,
+			reason TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS pending_approvals (
+			id BIGSERIAL PRIMARY KEY,
+			from_address TEXT NOT NULL,
+			to_address TEXT NOT NULL,
+			amount NUMERIC(38, 8) NOT NULL,
+			memo TEXT NOT NULL DEFAULT '',
+			nonce BIGINT NOT NULL DEFAULT 0,
+			status TEXT NOT NULL,
+			reason TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			decided_at TIMESTAMPTZ
+		);
+		ALTER TABLE pending_approvals ADD COLUMN IF NOT EXISTS category TEXT NOT NULL DEFAULT '';
+		CREATE INDEX IF NOT EXISTS pending_approvals_status_idx ON pending_approvals (status, created_at);
+		CREATE INDEX IF NOT EXISTS transactions_timestamp_idx ON transactions (timestamp);
+		CREATE TABLE IF NOT EXISTS transactions_archive (
+			id INTEGER PRIMARY KEY,
+			from_address TEXT,
+			to_address TEXT,
+			amount NUMERIC(38, 8),
+			timestamp TIMESTAMPTZ,
+			memo TEXT,
+			external_id TEXT,
+			imported BOOLEAN NOT NULL DEFAULT FALSE,
+			archived_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		ALTER TABLE transactions_archive ADD COLUMN IF NOT EXISTS category TEXT;
+		CREATE UNIQUE INDEX IF NOT EXISTS transactions_archive_external_id_key ON transactions_archive (external_id);
+		CREATE INDEX IF NOT EXISTS transactions_archive_from_address_idx ON transactions_archive (from_address);
+		CREATE INDEX IF NOT EXISTS transactions_archive_to_address_idx ON transactions_archive (to_address);
+		CREATE INDEX IF NOT EXISTS transactions_archive_timestamp_idx ON transactions_archive (timestamp);
+		CREATE TABLE IF NOT EXISTS audit_log (
+			id BIGSERIAL PRIMARY KEY,
+			action TEXT NOT NULL,
+			target TEXT NOT NULL,
+			details TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS idempotency_keys (
+			idempotency_key TEXT PRIMARY KEY,
+			fingerprint TEXT NOT NULL,
+			status INTEGER NOT NULL DEFAULT 0,
+			content_type TEXT NOT NULL DEFAULT '',
+			location TEXT NOT NULL DEFAULT '',
+			body TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idempotency_keys_created_at_idx ON idempotency_keys (created_at);
+		ALTER TABLE wallets ADD COLUMN IF NOT EXISTS opening_balance NUMERIC(38, 8);
+		ALTER TABLE transactions ADD COLUMN IF NOT EXISTS type TEXT NOT NULL DEFAULT 'transfer';
+		ALTER TABLE transactions_archive ADD COLUMN IF NOT EXISTS type TEXT NOT NULL DEFAULT 'transfer';
+		ALTER TABLE wallets ADD COLUMN IF NOT EXISTS notify_email TEXT;
+		CREATE TABLE IF NOT EXISTS notifications (
+			id BIGSERIAL PRIMARY KEY,
+			transaction_id INTEGER NOT NULL,
+			address TEXT NOT NULL,
+			email TEXT NOT NULL,
+			from_address TEXT NOT NULL,
+			from_label TEXT NOT NULL DEFAULT '',
+			amount NUMERIC(38, 8) NOT NULL,
+			transferred_at TIMESTAMPTZ NOT NULL,
+			status TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT NOT NULL DEFAULT '',
+			next_attempt_at TIMESTAMPTZ NOT NULL,
+			completed_at TIMESTAMPTZ
+		);
+		-- Фоновая доставка выбирает уведомления, ожидающие очередной попытки
+		CREATE INDEX IF NOT EXISTS notifications_pending_idx ON notifications (next_attempt_at) WHERE status = 'pending';
+		CREATE INDEX IF NOT EXISTS notifications_address_idx ON notifications (address);
+	`)
+	if err != nil {
+		return err
+	}
+	if err := migrateAmountColumns(db); err != nil {
+		return err
+	}
+	if err := createSystemAccounts(db, pgInsertSystemAccount); err != nil {
+		return err
+	}
+	return backfillOpeningBalances(db, pgSum)
+}
+
+// migrateAmountColumns переводит столбцы сумм из FLOAT (double precision) в NUMERIC(38, 8),
+// если база создана ранней версией схемы; уже переведенные столбцы пропускаются.
+// Значение приводится к NUMERIC (15 значащих цифр, так что 0.30000000000000004 становится 0.3)
+// и округляется до 8 знаков. ALTER COLUMN TYPE переписывает таблицу под исключительной
+// блокировкой, поэтому на больших таблицах первый запуск новой версии стоит планировать
+// на время обслуживания (см. README).
+//
+// Параметры:
+//   - db: Указатель на подключение к базе данных.
+//
+// Возвращает:
+//   - Ошибку, если не удалось перевести один из столбцов.
+func migrateAmountColumns(db *sql.DB) error {
+	columns := []struct{ table, column string }{
+		{"wallets", "balance"},
+		{"transactions", "amount"},
+		{"risk_events", "amount"},
+		{"pending_approvals", "amount"},
+	}
+	for _, c := range columns {
+		var dataType string
+		err := db.QueryRow("SELECT data_type FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1 AND column_name = $2",
+			c.table, c.column).Scan(&dataType)
+		if err != nil {
+			return fmt.Errorf("failed to inspect %s.%s: %w", c.table, c.column, err)
+		}
+		if dataType != "double precision" {
+			continue
+		}
+		_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE NUMERIC(38, 8) USING round(%s::numeric, 8)",
+			c.table, c.column, c.column))
+		if err != nil {
+			return fmt.Errorf("failed to migrate %s.%s to NUMERIC: %w", c.table, c.column, err)
+		}
+		slog.Info("column migrated to NUMERIC(38, 8)", "table", c.table, "column", c.column)
+	}
+	return nil
+}
+
+// pgInsertSystemAccount - запрос создания системного счета в PostgreSQL (см. createSystemAccounts).
+const pgInsertSystemAccount = "INSERT INTO wallets (address, balance, opening_balance, tags) VALUES ($1, $2, $2, $3::jsonb) ON CONFLICT (address) DO NOTHING"
+
+// pgInsertWallet - запрос создания кошелька в PostgreSQL (CreateWallet и pgTx.CreateWallet).
+// Начальный баланс запоминается в opening_balance для проверки балансов по истории (см. Reconcile).
+const pgInsertWallet = "INSERT INTO wallets (address, balance, opening_balance, label, tags, public_key, notify_email) VALUES ($1, $2, $2, NULLIF($3, ''), $4::jsonb, NULLIF($5, ''), NULLIF($6, ''))"
+
+// CreateWallet создает кошелек с указанным адресом, начальным балансом, метаданными и открытым ключом.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//   - balance: Начальный баланс.
+//   - metadata: Метка и теги кошелька.
+//   - publicKey: Открытый ключ ed25519 в шестнадцатеричном виде (пустая строка - без ключа).
+//
+// Возвращает:
+//   - ErrWalletExists, если кошелек с таким адресом уже существует.
+//   - ErrLabelExists, если метка присвоена другому кошельку.
+//
+// Пример использования:
+//
+//	err := repo.CreateWallet(ctx, address, decimal.NewFromInt(100), models.WalletMetadata{Label: "ops-float"}, publicKey)
+func (r *PostgresRepository) CreateWallet(ctx context.Context, address string, balance decimal.Decimal, metadata models.WalletMetadata, publicKey string) error {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, pgInsertWallet, address, balance, metadata.Label, tagsJSON(metadata.Tags), publicKey, metadata.NotifyEmail)
+	if err != nil {
+		return fmt.Errorf("failed to create wallet: %w", mapPgError(err))
+	}
+	return nil
+}
+
+// GetWallet возвращает кошелек с балансом и метаданными.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Кошелек.
+//   - Ошибку, оборачивающую ErrWalletNotFound, если кошелек не найден.
+//
+// Пример использования:
+//
+//	wallet, err := repo.GetWallet(ctx, "some_address")
+func (r *PostgresRepository) GetWallet(ctx context.Context, address string) (models.Wallet, error) {
+	var wallet models.Wallet
+	err := r.read(ctx, func(ctx context.Context, db *sql.DB) error {
+		var err error
+		wallet, err = scanWallet(db.QueryRowContext(ctx, "SELECT "+walletColumns+" FROM wallets WHERE address = $1", address))
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Wallet{}, fmt.Errorf("failed to get wallet: %w", ErrWalletNotFound)
+	}
+	if err != nil {
+		return models.Wallet{}, fmt.Errorf("failed to get wallet: %w", err)
+	}
+	return wallet, nil
+}
+
+// FindWalletByLabel возвращает кошелек по метке.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - label: Метка кошелька.
+//
+// Возвращает:
+//   - Кошелек.
+//   - Ошибку, оборачивающую ErrWalletNotFound, если кошелька с такой меткой нет.
+//
+// Пример использования:
+//
+//	wallet, err := repo.FindWalletByLabel(ctx, "ops-float")
+func (r *PostgresRepository) FindWalletByLabel(ctx context.Context, label string) (models.Wallet, error) {
+	var wallet models.Wallet
+	err := r.read(ctx, func(ctx context.Context, db *sql.DB) error {
+		var err error
+		wallet, err = scanWallet(db.QueryRowContext(ctx, "SELECT "+walletColumns+" FROM wallets WHERE label = $1", label))
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Wallet{}, fmt.Errorf("failed to find wallet: %w", ErrWalletNotFound)
+	}
+	if err != nil {
+		return models.Wallet{}, fmt.Errorf("failed to find wallet: %w", err)
+	}
+	return wallet, nil
+}
+
+// TopWalletsByBalance возвращает активные кошельки с наибольшим балансом.
+// Запрос идет по индексу wallets_balance_idx и читает из реплики, если она задана.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - limit: Максимальное количество кошельков.
+//
+// Возвращает:
+//   - Кошельки по убыванию баланса, при равном балансе - по адресу.
+//   - Ошибку, если не удалось выполнить запрос.
+//
+// Пример использования:
+//
+//	wallets, err := repo.TopWalletsByBalance(ctx, 10)
+func (r *PostgresRepository) TopWalletsByBalance(ctx context.Context, limit int) ([]models.Wallet, error) {
+	var wallets []models.Wallet
+	err := r.read(ctx, func(ctx context.Context, db *sql.DB) error {
+		var err error
+		wallets, err = topWallets(ctx, db, "balance", limit)
+		return err
+	})
+	return wallets, err
+}
+
+// UpdateWalletMetadata изменяет метку, теги и адрес для уведомлений кошелька одним запросом
+// UPDATE ... RETURNING.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//   - patch: Изменяемые поля; поля со значением nil не изменяются.
+//
+// Возвращает:
+//   - Кошелек после изменения.
+//   - Ошибку, оборачивающую ErrWalletNotFound, или ErrLabelExists, если метка занята.
+//
+// Пример использования:
+//
+//	label := "ops-float"
+//	wallet, err := repo.UpdateWalletMetadata(ctx, address, models.WalletMetadataPatch{Label: &label})
+func (r *PostgresRepository) UpdateWalletMetadata(ctx context.Context, address string, patch models.WalletMetadataPatch) (models.Wallet, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var label, email string
+	if patch.Label != nil {
+		label = *patch.Label
+	}
+	if patch.NotifyEmail != nil {
+		email = *patch.NotifyEmail
+	}
+	wallet, err := scanWallet(r.db.QueryRowContext(ctx, `
+		UPDATE wallets SET
+			label = CASE WHEN $2 THEN NULLIF($3, '') ELSE label END,
+			tags = CASE WHEN $4 THEN $5::jsonb ELSE tags END,
+			notify_email = CASE WHEN $6 THEN NULLIF($7, '') ELSE notify_email END
+		WHERE address = $1
+		RETURNING `+walletColumns,
+		address, patch.Label != nil, label, patch.Tags != nil, tagsJSON(patch.Tags), patch.NotifyEmail != nil, email))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Wallet{}, fmt.Errorf("failed to update wallet: %w", ErrWalletNotFound)
+	}
+	if err != nil {
+		return models.Wallet{}, fmt.Errorf("failed to update wallet: %w", mapPgError(err))
+	}
+	return wallet, nil
+}
+
+// ArchiveWallet архивирует кошелек: он перестает участвовать в переводах и по умолчанию
+// не показывается в списках, но его транзакции остаются в истории. Строка кошелька
+// блокируется до проверок, поэтому параллельный перевод не зачислит средства на уже
+// проверенный пустой кошелек.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Архивный кошелек (повторная архивация возвращает его без изменений).
+//   - Ошибку, оборачивающую ErrWalletNotFound; ErrWalletNotEmpty, если баланс не нулевой;
+//     ErrWalletHasHolds, если кошелек участвует в переводах, ожидающих подтверждения.
+//
+// Пример использования:
+//
+//	wallet, err := repo.ArchiveWallet(ctx, "some_address")
+func (r *PostgresRepository) ArchiveWallet(ctx context.Context, address string) (models.Wallet, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return archiveWallet(ctx, r.db, " FOR UPDATE", address, time.Now())
+}
+
+// RestoreWallet снимает архивацию кошелька; восстановление активного кошелька ничего не меняет.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Кошелек после восстановления.
+//   - Ошибку, оборачивающую ErrWalletNotFound, если кошелька нет.
+//
+// Пример использования:
+//
+//	wallet, err := repo.RestoreWallet(ctx, "some_address")
+func (r *PostgresRepository) RestoreWallet(ctx context.Context, address string) (models.Wallet, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return restoreWallet(ctx, r.db, address)
+}
+
+// GetNonce возвращает номер последнего подписанного перевода с кошелька.
+// Читает из основной базы: по этому номеру клиент подписывает следующий перевод,
+// и отставание реплики привело бы к отказу с несовпадением номера.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Номер последнего подписанного перевода (0, если их не было).
+//   - Ошибку, оборачивающую ErrWalletNotFound, если кошелька нет.
+//
+// Пример использования:
+//
+//	nonce, err := repo.GetNonce(ctx, "some_address")
+func (r *PostgresRepository) GetNonce(ctx context.Context, address string) (int64, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return getNonce(ctx, r.db, address)
+}
+
+// GetBalance возвращает баланс кошелька по его адресу.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Баланс кошелька.
+//   - Ошибку, если кошелек не найден или произошла другая ошибка.
+//
+// Пример использования:
+//
+//	balance, err := repo.GetBalance(ctx, "some_address")
+func (r *PostgresRepository) GetBalance(ctx context.Context, address string) (decimal.Decimal, error) {
+	var balance decimal.Decimal
+	err := r.read(ctx, func(ctx context.Context, db *sql.DB) error {
+		return db.QueryRowContext(ctx, "SELECT balance FROM wallets WHERE address = $1", address).Scan(&balance)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return decimal.Zero, fmt.Errorf("failed to get balance: %w", ErrWalletNotFound)
+	}
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("failed to get balance: %w", err)
+	}
+	return balance, nil
+}
+
+// GetBalanceDetails возвращает баланс кошелька, зарезервированные средства и доступный остаток
+// одним запросом (с реплики, если она настроена).
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Баланс с резервом и доступным остатком.
+//   - Ошибку, оборачивающую ErrWalletNotFound, если кошелек не найден.
+//
+// Пример использования:
+//
+//	balance, err := repo.GetBalanceDetails(ctx, "some_address")
+func (r *PostgresRepository) GetBalanceDetails(ctx context.Context, address string) (models.Balance, error) {
+	var balance models.Balance
+	err := r.read(ctx, func(ctx context.Context, db *sql.DB) error {
+		var err error
+		balance, err = getBalanceDetails(ctx, db, pgSum, address)
+		return err
+	})
+	return balance, err
+}
+
+// GetBalances возвращает балансы нескольких кошельков одним запросом (WHERE address = ANY($1)).
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - addresses: Адреса кошельков.
+//
+// Возвращает:
+//   - Балансы по адресам; несуществующих кошельков в результате нет.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+//
+// Пример использования:
+//
+//	balances, err := repo.GetBalances(ctx, []string{"address1", "address2"})
+func (r *PostgresRepository) GetBalances(ctx context.Context, addresses []string) (map[string]decimal.Decimal, error) {
+	var balances map[string]decimal.Decimal
+	err := r.read(ctx, func(ctx context.Context, db *sql.DB) error {
+		rows, err := db.QueryContext(ctx, "SELECT address, balance FROM wallets WHERE address = ANY($1)", addresses)
+		if err != nil {
+			return err
+		}
+		balances, err = scanBalances(rows)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balances: %w", err)
+	}
+	return balances, nil
+}
+
+// scanBalances читает строки (address, balance) и закрывает rows.
+func scanBalances(rows *sql.Rows) (map[string]decimal.Decimal, error) {
+	defer rows.Close()
+
+	balances := make(map[string]decimal.Decimal)
+	for rows.Next() {
+		var address string
+		var balance decimal.Decimal
+		if err := rows.Scan(&address, &balance); err != nil {
+			return nil, err
+		}
+		balances[address] = balance
+	}
+	return balances, rows.Err()
+}
+
+// Send выполняет перевод средств с одного кошелька на другой.
+// Включает проверку баланса отправителя, обновление балансов и запись транзакции.
+//
+// Перевод выполняется в транзакции withTx (см. transfer): конфликт сериализации или
+// взаимоблокировка, например при встречных переводах между одними и теми же кошельками,
+// повторяют его целиком, а если попытки исчерпаны, возвращается ErrContention.
+//
+// Транзакция выполняется с уровнем изоляции isolation, а если он не задан (sql.LevelDefault) -
+// с уровнем DB_SEND_ISOLATION (по умолчанию см. sendIsolationFromEnv). Перевод всегда выполняется
+// в основной базе, даже если настроена реплика для чтения (DB_READ_HOST).
+// Списание защищено блокировками DB_LOCK_STRATEGY на любом уровне; REPEATABLE READ
+// и SERIALIZABLE дополнительно превращают любое пропущенное блокировкой параллельное изменение
+// кошелька в конфликт сериализации, который повторяется, а не в потерянное списание.
+// Цена - повторы: при частых переводах с одного кошелька на этих уровнях растет доля
+// ответов ErrContention, и тогда стоит увеличить DB_SEND_ATTEMPTS или выбрать READ COMMITTED.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - from: Адрес кошелька отправителя.
+//   - to: Адрес кошелька получателя.
+//   - amount: Сумма перевода.
+//   - memo: Комментарий к переводу (может быть пустым).
+//   - category: Категория перевода (может быть пустой).
+//   - nonce: Номер подписанного перевода; 0 - перевод без подписи.
+//   - isolation: Уровень изоляции транзакции; sql.LevelDefault - DB_SEND_ISOLATION.
+//
+// Возвращает:
+//   - Идентификатор и время записанной транзакции и баланс отправителя после списания.
+//   - Ошибку, если перевод не удался (например, недостаточно средств);
+//     *NonceError, если nonce не следующий номер отправителя.
+//
+// Пример использования:
+//
+//	result, err := repo.Send(ctx, "from_address", "to_address", decimal.RequireFromString("10.5"), "invoice 42", 0, sql.LevelDefault)
+func (r *PostgresRepository) Send(ctx context.Context, from, to string, amount decimal.Decimal, memo, category string, nonce int64, isolation sql.IsolationLevel) (SendResult, error) {
+	if isolation == sql.LevelDefault {
+		isolation = r.sendIsolation
+	}
+
+	var result SendResult
+	err := r.withTx(ctx, isolation, func(tx *pgTx) error {
+		var err error
+		result, err = tx.Send(from, to, amount, memo, category, nonce)
+		return err
+	})
+	if err != nil {
+		return SendResult{}, err
+	}
+	return result, nil
+}
+
+// SystemTransfer выполняет операцию с системным счетом (см. systemTransfer) в транзакции
+// withTx с уровнем изоляции DB_SEND_ISOLATION; конфликты повторяются, как в Send.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - txType: Тип операции (models.TransactionMint и др.).
+//   - address: Кошелек пользователя.
+//   - amount: Сумма операции.
+//   - memo: Комментарий (может быть пустым).
+//
+// Возвращает:
+//   - Идентификатор и время записанной транзакции и балансы участников после операции.
+//   - ErrTransactionType, ErrSystemAccount или ошибку перевода.
+//
+// Пример использования:
+//
+//	result, err := repo.SystemTransfer(ctx, models.TransactionMint, address, decimal.NewFromInt(100), "initial funding")
+func (r *PostgresRepository) SystemTransfer(ctx context.Context, txType, address string, amount decimal.Decimal, memo string) (SendResult, error) {
+	var result SendResult
+	err := r.withTx(ctx, r.sendIsolation, func(tx *pgTx) error {
+		var err error
+		result, err = systemTransfer(tx, txType, address, amount, memo, r.minBalance)
+		return err
+	})
+	if err != nil {
+		return SendResult{}, err
+	}
+	return result, nil
+}
+
+// WithTx выполняет fn в одной транзакции с уровнем изоляции DB_SEND_ISOLATION: изменения,
+// сделанные через TxRepository, фиксируются, если fn вернула nil, и откатываются, если ошибку.
+// Конфликт сериализации или взаимоблокировка повторяют транзакцию целиком, поэтому fn может
+// быть вызвана несколько раз и не должна иметь других побочных эффектов.
+// Блокировки GetWalletForUpdate соответствуют DB_LOCK_STRATEGY.
+//
+// Параметры:
+//   - ctx: Контекст; время каждой попытки дополнительно ограничено DB_QUERY_TIMEOUT.
+//   - fn: Операции транзакции.
+//
+// Возвращает:
+//   - Ошибку fn или базы; ErrContention, если попытки исчерпаны.
+//
+// Пример использования:
+//
+//	err := repo.WithTx(ctx, func(tx db.TxRepository) error {
+//		if err := tx.CreateWallet(address, decimal.Zero, models.WalletMetadata{}, ""); err != nil {
+//			return err
+//		}
+//		_, err := tx.AddBalance(address, decimal.NewFromInt(100))
+//		return err
+//	})
+func (r *PostgresRepository) WithTx(ctx context.Context, fn func(tx TxRepository) error) error {
+	return r.withTx(ctx, r.sendIsolation, func(tx *pgTx) error { return fn(tx) })
+}
+
+// WithSeedLock выполняет fn в транзакции READ COMMITTED под рекомендательной блокировкой
+// транзакции seedLockKey. Уровень изоляции не зависит от DB_SEND_ISOLATION: при REPEATABLE READ
+// снимок был бы взят до ожидания блокировки, и экземпляр, дождавшийся ее, не увидел бы
+// кошельков, созданных предыдущим.
+//
+// Параметры:
+//   - ctx: Контекст; время каждой попытки дополнительно ограничено DB_QUERY_TIMEOUT.
+//   - fn: Операции транзакции.
+//
+// Возвращает:
+//   - Ошибку блокировки, fn или базы.
+func (r *PostgresRepository) WithSeedLock(ctx context.Context, fn func(tx TxRepository) error) error {
+	return r.withTx(ctx, sql.LevelReadCommitted, func(tx *pgTx) error {
+		if _, err := tx.tx.ExecContext(tx.ctx, "SELECT pg_advisory_xact_lock($1)", seedLockKey); err != nil {
+			return fmt.Errorf("failed to acquire seed lock: %w", err)
+		}
+		return fn(tx)
+	})
+}
+
+// withTx выполняет fn в транзакции с уровнем изоляции isolation. Транзакция, завершившаяся
+// конфликтом сериализации (40001) или взаимоблокировкой (40P01), повторяется целиком
+// с экспоненциальной задержкой со случайным разбросом, не более sendAttempts раз (DB_SEND_ATTEMPTS).
+func (r *PostgresRepository) withTx(ctx context.Context, isolation sql.IsolationLevel, fn func(tx *pgTx) error) error {
+	var err error
+	for attempt := 0; attempt < r.sendAttempts; attempt++ {
+		if attempt > 0 {
+			// Полный разброс: от 0 до base*2^attempt, чтобы конкурирующие транзакции разошлись во времени
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(mrand.N(sendRetryBaseDelay << attempt)):
+			}
+		}
+
+		err = r.runTx(ctx, isolation, fn)
+		if !isRetryable(err) {
+			return err
+		}
+		// Ошибка после последней попытки возвращается вызывающему коду и записывается им
+		slog.Debug("transaction conflict, retrying", "attempt", attempt+1, "attempts", r.sendAttempts, "error", err)
+	}
+	return fmt.Errorf("%w: %v", ErrContention, err)
+}
+
+// runTx выполняет одну попытку транзакции withTx. Если подключение оборвалось во время
+// фиксации, результат выясняется по номеру транзакции (см. commitOutcome), а не повтором:
+// повтор зафиксированного перевода выполнил бы его дважды. Обрыв до фиксации не повторяется
+// тоже: транзакция на сервере отменена, и вызывающий код получает ошибку подключения.
+func (r *PostgresRepository) runTx(ctx context.Context, isolation sql.IsolationLevel, fn func(tx *pgTx) error) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: isolation})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(&pgTx{ctx: ctx, tx: tx, lockStrategy: r.lockStrategy, minBalance: r.minBalance, clock: r.clock}); err != nil {
+		return err
+	}
+
+	var txid int64
+	if err := tx.QueryRowContext(ctx, "SELECT txid_current()").Scan(&txid); err != nil {
+		return fmt.Errorf("failed to get transaction id: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		// Ошибка с кодом SQLSTATE (например, конфликт сериализации) означает, что сервер
+		// отменил транзакцию; обрыв или истечение времени ответа не означает ничего
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) || errors.Is(err, sql.ErrTxDone) {
+			return err
+		}
+		return r.commitOutcome(txid, err)
+	}
+	return nil
+}
+
+// pgTx - TxRepository транзакции PostgreSQL.
+type pgTx struct {
+	ctx          context.Context
+	tx           *sql.Tx
+	lockStrategy string          // DB_LOCK_STRATEGY
+	locked       map[string]bool // Кошельки, заблокированные рекомендательной блокировкой
+	minBalance   decimal.Decimal // Неснижаемый остаток кошелька отправителя
+	clock        Clock           // Источник времени записанных транзакций
+}
+
+// lockAdvisory берет рекомендательные блокировки кошельков, еще не заблокированных в этой транзакции.
+func (t *pgTx) lockAdvisory(addresses ...string) error {
+	if t.locked == nil {
+		t.locked = make(map[string]bool, len(addresses))
+	}
+	pending := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		if !t.locked[address] {
+			pending = append(pending, address)
+		}
+	}
+	if err := lockWalletsAdvisory(t.ctx, t.tx, pending...); err != nil {
+		return err
+	}
+	for _, address := range pending {
+		t.locked[address] = true
+	}
+	return nil
+}
+
+// GetWalletForUpdate читает состояние кошелька и блокирует его до конца транзакции:
+// рекомендательной блокировкой при DB_LOCK_STRATEGY=advisory или advisory-sender,
+// иначе строку - через SELECT ... FOR UPDATE.
+func (t *pgTx) GetWalletForUpdate(address string) (WalletState, error) {
+	query := "SELECT balance, " + reservedColumn(pgSum) + ", nonce, archived_at IS NOT NULL FROM wallets WHERE address = $1"
+	switch t.lockStrategy {
+	case lockAdvisory, lockAdvisorySender:
+		if err := t.lockAdvisory(address); err != nil {
+			return WalletState{}, err
+		}
+	default:
+		query += " FOR UPDATE OF wallets"
+	}
+
+	var state WalletState
+	err := t.tx.QueryRowContext(t.ctx, query, address).Scan(&state.Balance, &state.Reserved, &state.Nonce, &state.Archived)
+	if errors.Is(err, sql.ErrNoRows) {
+		return WalletState{}, ErrWalletNotFound
+	}
+	if err != nil {
+		return WalletState{}, err
+	}
+	return state, nil
+}
+
+// AddBalance изменяет баланс одним UPDATE: строка кошелька, не заблокированная
+// GetWalletForUpdate, блокируется до конца транзакции. Баланс вне NUMERIC(38, 8) PostgreSQL
+// отклоняет с кодом 22003, он отображается на ErrBalanceOverflow.
+func (t *pgTx) AddBalance(address string, delta decimal.Decimal) (decimal.Decimal, error) {
+	var balance decimal.Decimal
+	err := t.tx.QueryRowContext(t.ctx,
+		"UPDATE wallets SET balance = balance + $1 WHERE address = $2 AND archived_at IS NULL RETURNING balance",
+		delta, address).Scan(&balance)
+	if errors.Is(err, sql.ErrNoRows) {
+		return decimal.Decimal{}, missingWalletError(t.ctx, t.tx, address)
+	}
+	if err != nil {
+		return decimal.Decimal{}, mapPgError(err)
+	}
+	return balance, nil
+}
+
+// SetNonce сохраняет номер последнего подписанного перевода с кошелька.
+func (t *pgTx) SetNonce(address string, nonce int64) error {
+	_, err := t.tx.ExecContext(t.ctx, "UPDATE wallets SET nonce = GREATEST(nonce, $2) WHERE address = $1", address, nonce)
+	return err
+}
+
+// RecordTransaction записывает транзакцию.
+func (t *pgTx) RecordTransaction(txType, from, to string, amount decimal.Decimal, memo, category string) (models.Transaction, error) {
+	return recordTransaction(t.ctx, t.tx, txType, from, to, amount, memo, category, t.clock.Now())
+}
+
+// EnqueueNotification ставит в очередь уведомление получателю транзакции.
+func (t *pgTx) EnqueueNotification(transaction models.Transaction) error {
+	return enqueueNotification(t.ctx, t.tx, transaction, transaction.CreatedAt)
+}
+
+// CreateWallet создает кошелек в транзакции.
+func (t *pgTx) CreateWallet(address string, balance decimal.Decimal, metadata models.WalletMetadata, publicKey string) error {
+	_, err := t.tx.ExecContext(t.ctx, pgInsertWallet, address, balance, metadata.Label, tagsJSON(metadata.Tags), publicKey, metadata.NotifyEmail)
+	if err != nil {
+		return fmt.Errorf("failed to create wallet: %w", mapPgError(err))
+	}
+	return nil
+}
+
+// RetireWallet архивирует кошелек и снимает с него метку. Строка блокируется FOR UPDATE,
+// а при рекомендательных блокировках берется и блокировка кошелька, как в GetWalletForUpdate.
+func (t *pgTx) RetireWallet(address string) (models.Wallet, error) {
+	if t.lockStrategy == lockAdvisory || t.lockStrategy == lockAdvisorySender {
+		if err := t.lockAdvisory(address); err != nil {
+			return models.Wallet{}, err
+		}
+	}
+	return retireWallet(t.ctx, t.tx, " FOR UPDATE", address, time.Now())
+}
+
+// HasWallets проверяет, есть ли кошельки, кроме системных счетов.
+func (t *pgTx) HasWallets() (bool, error) {
+	return hasWallets(t.ctx, t.tx)
+}
+
+// Send выполняет перевод в транзакции (см. transfer). При DB_LOCK_STRATEGY=advisory оба
+// кошелька блокируются до чтения балансов в фиксированном порядке, поэтому встречные
+// переводы не взаимоблокируются.
+func (t *pgTx) Send(from, to string, amount decimal.Decimal, memo, category string, nonce int64) (SendResult, error) {
+	if t.lockStrategy == lockAdvisory {
+		if err := t.lockAdvisory(from, to); err != nil {
+			return SendResult{}, err
+		}
+	}
+	return transfer(t, from, to, amount, memo, category, nonce, t.minBalance)
+}
+
+// UpdateApprovalStatus меняет статус отложенного перевода в транзакции.
+func (t *pgTx) UpdateApprovalStatus(id int64, from, to, reason string) (models.Approval, error) {
+	return updateApprovalStatus(t.ctx, t.tx, id, from, to, reason, time.Now())
+}
+
+// ImportTransactions сохраняет исторические транзакции из другой системы.
+// Время и внешний идентификатор берутся из входных данных (значение по умолчанию для timestamp
+// не используется), записи помечаются imported = TRUE, балансы кошельков не изменяются.
+// Все записи вставляются в одной транзакции; записи с уже существующим external_id пропускаются.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - transactions: Транзакции для импорта; ExternalID и CreatedAt обязательны.
+//
+// Возвращает:
+//   - Количество добавленных записей.
+//   - Ошибку, если импорт не удался (в этом случае ничего не добавляется).
+//
+// Пример использования:
+//
+//	imported, err := repo.ImportTransactions(ctx, transactions)
+func (r *PostgresRepository) ImportTransactions(ctx context.Context, transactions []models.Transaction) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	imported, err := importTransactions(ctx, tx, transactions, postgresTimeArg)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit import: %w", err)
+	}
+	return imported, nil
+}
+
+// importTransactions вставляет импортируемые транзакции в рамках транзакции tx.
+// Запрос совместим с PostgreSQL и SQLite; время передается в формате базы toTime, чтобы
+// в SQLite оно хранилось так же, как время переводов, и сравнивалось с ним как текст.
+func importTransactions(ctx context.Context, tx *sql.Tx, transactions []models.Transaction, toTime timeArg) (int, error) {
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO transactions (from_address, to_address, amount, timestamp, memo, category, external_id, imported, type)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7, TRUE, COALESCE(NULLIF($8, ''), 'transfer'))
+		ON CONFLICT (external_id) DO NOTHING`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare import: %w", err)
+	}
+	defer stmt.Close()
+
+	// Уникальный индекс не охватывает архив, поэтому перенесенные туда записи проверяются отдельно
+	archived, err := tx.PrepareContext(ctx, "SELECT EXISTS (SELECT 1 FROM transactions_archive WHERE external_id = $1)")
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare import: %w", err)
+	}
+	defer archived.Close()
+
+	imported := 0
+	for _, t := range transactions {
+		var exists bool
+		if err := archived.QueryRowContext(ctx, t.ExternalID).Scan(&exists); err != nil {
+			return 0, fmt.Errorf("failed to import transaction %s: %w", t.ExternalID, err)
+		}
+		if exists {
+			continue
+		}
+		res, err := stmt.ExecContext(ctx, t.From, t.To, t.Amount, toTime(t.CreatedAt.UTC()), t.Memo, t.Category, t.ExternalID, t.Type)
+		if err != nil {
+			return 0, fmt.Errorf("failed to import transaction %s: %w", t.ExternalID, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to import transaction %s: %w", t.ExternalID, err)
+		}
+		imported += int(n)
+	}
+	return imported, nil
+}
+
+// GetLastTransactions возвращает список последних N транзакций со временем в UTC.
+// Транзакции с одинаковым временем упорядочиваются по id.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - count: Количество транзакций.
+//   - filter: Условия выборки.
+//
+// Возвращает:
+//   - Список транзакций.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+//
+// Пример использования:
+//
+//	transactions, err := repo.GetLastTransactions(ctx, 5, TransactionFilter{})
+func (r *PostgresRepository) GetLastTransactions(ctx context.Context, count int, filter TransactionFilter) ([]models.Transaction, error) {
+	query, args := lastTransactionsQuery(filter, postgresTimeArg, count)
+
+	buffer := make([]models.Transaction, 0, transactionsCapacity(count))
+	var transactions []models.Transaction
+	err := r.read(ctx, func(ctx context.Context, db *sql.DB) error {
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to query transactions: %w", err)
+		}
+		defer rows.Close()
+
+		// При повторе в основной базе результат собирается заново в том же срезе
+		transactions, err = scanTransactions(rows, buffer[:0])
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+// CountTransactions возвращает количество транзакций, удовлетворяющих фильтру.
+// Как и GetLastTransactions, выполняется на реплике, если она настроена.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - filter: Условия выборки.
+//
+// Возвращает:
+//   - Количество транзакций.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+//
+// Пример использования:
+//
+//	total, err := repo.CountTransactions(ctx, TransactionFilter{IncludeArchived: true})
+func (r *PostgresRepository) CountTransactions(ctx context.Context, filter TransactionFilter) (int64, error) {
+	query, args := countTransactionsQuery(filter, postgresTimeArg)
+
+	var count int64
+	err := r.read(ctx, func(ctx context.Context, db *sql.DB) error {
+		if err := db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+			return fmt.Errorf("failed to count transactions: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetWalletTransactions возвращает последние count транзакций каждого из кошельков одним
+// запросом (см. walletTransactionsQuery). Как и GetLastTransactions, выполняется на реплике,
+// если она настроена.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - addresses: Адреса кошельков.
+//   - count: Максимальное количество транзакций одного кошелька.
+//
+// Возвращает:
+//   - Транзакции по адресам, начиная с самой новой; кошельков без транзакций в результате нет.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+//
+// Пример использования:
+//
+//	transactions, err := repo.GetWalletTransactions(ctx, []string{"address1", "address2"}, 10)
+func (r *PostgresRepository) GetWalletTransactions(ctx context.Context, addresses []string, count int) (map[string][]models.Transaction, error) {
+	if len(addresses) == 0 {
+		return map[string][]models.Transaction{}, nil
+	}
+	query, args := walletTransactionsQuery(addresses, count)
+
+	var transactions map[string][]models.Transaction
+	err := r.read(ctx, func(ctx context.Context, db *sql.DB) error {
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to query wallet transactions: %w", err)
+		}
+		transactions, err = scanWalletTransactions(rows)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// EstimateTransactions возвращает приблизительное количество транзакций по pg_class.reltuples,
+// которое обновляют VACUUM, ANALYZE и autovacuum: запрос не просматривает таблицу и выполняется
+// мгновенно при любом ее размере. Если статистики еще нет (таблица ни разу не анализировалась),
+// возвращает точное количество. Как и CountTransactions, выполняется на реплике, если она настроена.
+//
+// Возвращает:
+//   - Приблизительное количество транзакций (без архива).
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+//
+// Пример использования:
+//
+//	total, err := repo.EstimateTransactions(ctx)
+func (r *PostgresRepository) EstimateTransactions(ctx context.Context) (int64, error) {
+	var estimate int64
+	err := r.read(ctx, func(ctx context.Context, db *sql.DB) error {
+		err := db.QueryRowContext(ctx,
+			"SELECT reltuples::bigint FROM pg_class WHERE oid = 'transactions'::regclass").Scan(&estimate)
+		if err != nil {
+			return fmt.Errorf("failed to estimate transactions: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	// До первого ANALYZE reltuples равен -1 (PostgreSQL 14+) или 0
+	if estimate <= 0 {
+		return r.CountTransactions(ctx, TransactionFilter{})
+	}
+	return estimate, nil
+}
+
+// PurgeTransactions переносит в таблицу transactions_archive (при archive = false - удаляет)
+// не более limit самых старых транзакций, выполненных раньше момента before. Пачка выполняется
+// под рекомендательной блокировкой, поэтому очистку можно запускать на нескольких экземплярах.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - before: Транзакции, выполненные раньше этого момента, очищаются.
+//   - archive: true - перенести в архив, false - удалить.
+//   - limit: Наибольшее количество транзакций в пачке.
+//
+// Возвращает:
+//   - Количество перенесенных (удаленных) транзакций; меньше limit - старых транзакций не осталось.
+//   - Ошибку, оборачивающую ErrPurgeLocked, если очистку выполняет другой экземпляр.
+//
+// Пример использования:
+//
+//	purged, err := repo.PurgeTransactions(ctx, time.Now().AddDate(0, 0, -90), true, 1000)
+func (r *PostgresRepository) PurgeTransactions(ctx context.Context, before time.Time, archive bool, limit int) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return purgeTransactions(ctx, r.db, "SELECT pg_try_advisory_xact_lock($1)", " FOR UPDATE", before, archive, limit)
+}
+
+// GetSenderStats возвращает количество и сумму переводов с кошелька начиная с момента since.
+// Читает из основной базы: правила проверки переводов не должны пропускать переводы,
+// еще не дошедшие до реплики.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька отправителя.
+//   - since: Начало периода.
+//
+// Возвращает:
+//   - Сводку переводов (нулевую, если переводов не было).
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+//
+// Пример использования:
+//
+//	stats, err := repo.GetSenderStats(ctx, "some_address", time.Now().Add(-10*time.Minute))
+func (r *PostgresRepository) GetSenderStats(ctx context.Context, address string, since time.Time) (SenderStats, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return getSenderStats(ctx, r.db, pgSum, address, since)
+}
+
+// GetCategoryVolumes возвращает объемы переводов по дням и категориям за период [from, to).
+// Отчет читается из реплики, если она задана.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - from: Начало периода.
+//   - to: Конец периода (не включается).
+//
+// Возвращает:
+//   - Объемы по дням (UTC) и категориям.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+//
+// Пример использования:
+//
+//	volumes, err := repo.GetCategoryVolumes(ctx, time.Now().AddDate(0, 0, -30), time.Now())
+func (r *PostgresRepository) GetCategoryVolumes(ctx context.Context, from, to time.Time) ([]CategoryVolume, error) {
+	var volumes []CategoryVolume
+	err := r.read(ctx, func(ctx context.Context, db *sql.DB) error {
+		var err error
+		volumes, err = getCategoryVolumes(ctx, db, "to_char(timestamp AT TIME ZONE 'UTC', 'YYYY-MM-DD')", pgSum, from, to)
+		return err
+	})
+	return volumes, err
+}
+
+// RecordRiskEvent сохраняет срабатывание правила проверки переводов в таблицу risk_events.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - event: Событие; ID и CreatedAt назначаются базой.
+//
+// Возвращает:
+//   - Ошибку, если событие сохранить не удалось.
+//
+// Пример использования:
+//
+//	err := repo.RecordRiskEvent(ctx, models.RiskEvent{Rule: "velocity", Action: "flag", ...})
+func (r *PostgresRepository) RecordRiskEvent(ctx context.Context, event models.RiskEvent) error {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return recordRiskEvent(ctx, r.db, event)
+}
+
+// GetRiskEvents возвращает последние count срабатываний правил, начиная с самого нового.
+// Выполняется на реплике, если она настроена: список нужен для отчетов, и отставание
+// реплики на доли секунды для него допустимо.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - count: Количество событий.
+//
+// Возвращает:
+//   - Список событий.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+//
+// Пример использования:
+//
+//	events, err := repo.GetRiskEvents(ctx, 50)
+func (r *PostgresRepository) GetRiskEvents(ctx context.Context, count int) ([]models.RiskEvent, error) {
+	var events []models.RiskEvent
+	err := r.read(ctx, func(ctx context.Context, db *sql.DB) error {
+		var err error
+		events, err = getRiskEvents(ctx, db, count)
+		return err
+	})
+	return events, err
+}
+
+// AnonymizeWallet удаляет персональные данные кошелька: метку, теги, адрес для уведомлений,
+// комментарии его транзакций и отложенных переводов и его данные в уведомлениях. Каждая пачка изменений выполняется отдельным запросом
+// с собственным ограничением времени.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//   - dryRun: true - только подсчитать записи с персональными данными.
+//
+// Возвращает:
+//   - Количество измененных (или подлежащих изменению) записей.
+//   - Ошибку, оборачивающую ErrWalletNotFound, если кошелька нет.
+//
+// Пример использования:
+//
+//	report, err := repo.AnonymizeWallet(ctx, "some_address", false)
+func (r *PostgresRepository) AnonymizeWallet(ctx context.Context, address string, dryRun bool) (models.AnonymizeReport, error) {
+	return anonymizeWallet(ctx, r.db, r.queryTimeout, address, dryRun)
+}
+
+// RecordAuditEvent сохраняет запись журнала аудита в таблицу audit_log.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - event: Запись; ID и CreatedAt назначаются базой.
+//
+// Возвращает:
+//   - Ошибку, если запись сохранить не удалось.
+//
+// Пример использования:
+//
+//	err := repo.RecordAuditEvent(ctx, models.AuditEvent{Action: models.AuditWalletAnonymized, Target: "some_address"})
+func (r *PostgresRepository) RecordAuditEvent(ctx context.Context, event models.AuditEvent) error {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return recordAuditEvent(ctx, r.db, event)
+}
+
+// GetAuditEvents возвращает последние count записей журнала аудита, начиная с самой новой.
+// Как и GetRiskEvents, выполняется на реплике, если она настроена.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - count: Количество записей.
+//
+// Возвращает:
+//   - Список записей.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+//
+// Пример использования:
+//
+//	events, err := repo.GetAuditEvents(ctx, 50)
+func (r *PostgresRepository) GetAuditEvents(ctx context.Context, count int) ([]models.AuditEvent, error) {
+	var events []models.AuditEvent
+	err := r.read(ctx, func(ctx context.Context, db *sql.DB) error {
+		var err error
+		events, err = getAuditEvents(ctx, db, count)
+		return err
+	})
+	return events, err
+}
+
+// ReserveIdempotencyKey занимает ключ идемпотентности для выполняющегося запроса.
+// Ключ - первичный ключ таблицы idempotency_keys, поэтому из параллельных запросов
+// с одним ключом, в том числе на разных экземплярах сервиса, его занимает только один.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - key: Ключ из заголовка Idempotency-Key.
+//   - fingerprint: Отпечаток запроса.
+//   - expiredBefore: Ключи, занятые раньше этого момента, удаляются.
+//
+// Возвращает:
+//   - Сохраненный ответ и false, если ключ уже занят.
+//   - true, если ключ занят этим вызовом.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+//
+// Пример использования:
+//
+//	stored, reserved, err := repo.ReserveIdempotencyKey(ctx, key, fingerprint, time.Now().Add(-24*time.Hour))
+func (r *PostgresRepository) ReserveIdempotencyKey(ctx context.Context, key, fingerprint string, expiredBefore time.Time) (IdempotentResponse, bool, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return reserveIdempotencyKey(ctx, r.db, key, fingerprint, time.Now(), expiredBefore)
+}
+
+// CompleteIdempotencyKey сохраняет ответ на запрос с занятым ключом идемпотентности.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - key: Ключ, занятый ReserveIdempotencyKey.
+//   - response: Ответ (поле Fingerprint не используется).
+//
+// Возвращает:
+//   - Ошибку, если ответ сохранить не удалось.
+func (r *PostgresRepository) CompleteIdempotencyKey(ctx context.Context, key string, response IdempotentResponse) error {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return completeIdempotencyKey(ctx, r.db, key, response)
+}
+
+// ReleaseIdempotencyKey освобождает ключ запроса, ответ на который не сохраняется
+// (например, при недоступной базе), чтобы запрос можно было повторить.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - key: Ключ, занятый ReserveIdempotencyKey.
+//
+// Возвращает:
+//   - Ошибку, если ключ освободить не удалось.
+func (r *PostgresRepository) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return releaseIdempotencyKey(ctx, r.db, key)
+}
+
+// CreateApproval сохраняет перевод, ожидающий подтверждения администратором, в таблицу pending_approvals.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - approval: Перевод; ID, Status и CreatedAt назначаются хранилищем.
+//
+// Возвращает:
+//   - Сохраненный перевод со статусом models.ApprovalAwaitingReview.
+//   - Ошибку, если перевод сохранить не удалось.
+//
+// Пример использования:
+//
+//	approval, err := repo.CreateApproval(ctx, models.Approval{From: from, To: to, Amount: 5000})
+func (r *PostgresRepository) CreateApproval(ctx context.Context, approval models.Approval) (models.Approval, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return createApproval(ctx, r.db, approval, time.Now())
+}
+
+// GetApproval возвращает отложенный перевод по ID.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - id: Идентификатор отложенного перевода.
+//
+// Возвращает:
+//   - Отложенный перевод.
+//   - Ошибку, оборачивающую ErrApprovalNotFound, если перевода нет.
+//
+// Пример использования:
+//
+//	approval, err := repo.GetApproval(ctx, 42)
+func (r *PostgresRepository) GetApproval(ctx context.Context, id int64) (models.Approval, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return getApproval(ctx, r.db, id)
+}
+
+// GetApprovals возвращает последние count отложенных переводов, начиная с самого нового.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - status: Статус переводов (пустая строка - любой).
+//   - count: Количество переводов.
+//
+// Возвращает:
+//   - Список отложенных переводов.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+//
+// Пример использования:
+//
+//	approvals, err := repo.GetApprovals(ctx, models.ApprovalAwaitingReview, 50)
+func (r *PostgresRepository) GetApprovals(ctx context.Context, status string, count int) ([]models.Approval, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return getApprovals(ctx, r.db, status, count)
+}
+
+// UpdateApprovalStatus переводит отложенный перевод из статуса from в статус to одним
+// условным UPDATE: из параллельных решений по одному переводу выполняется одно.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - id: Идентификатор отложенного перевода.
+//   - from: Ожидаемый текущий статус.
+//   - to: Новый статус.
+//   - reason: Причина (для отклонения или неудачного выполнения).
+//
+// Возвращает:
+//   - Отложенный перевод с новым статусом.
+//   - Ошибку, оборачивающую ErrApprovalNotFound, если перевода нет, или ErrApprovalStatus,
+//     если его статус не from.
+//
+// Пример использования:
+//
+//	approval, err := repo.UpdateApprovalStatus(ctx, 42, models.ApprovalAwaitingReview, models.ApprovalRejected, "unknown recipient")
+func (r *PostgresRepository) UpdateApprovalStatus(ctx context.Context, id int64, from, to, reason string) (models.Approval, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return updateApprovalStatus(ctx, r.db, id, from, to, reason, time.Now())
+}
+
+// ExpireApprovals переводит в статус models.ApprovalExpired переводы, ожидающие решения
+// с момента раньше before.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - before: Граница времени постановки в очередь.
+//
+// Возвращает:
+//   - Количество истекших переводов.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+//
+// Пример использования:
+//
+//	expired, err := repo.ExpireApprovals(ctx, time.Now().Add(-24 * time.Hour))
+func (r *PostgresRepository) ExpireApprovals(ctx context.Context, before time.Time) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return expireApprovals(ctx, r.db, before, time.Now())
+}
+
+// ClaimNotifications выбирает уведомления, ожидающие доставки, и откладывает их следующую
+// попытку до now + lease.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - now: Текущее время; выбираются уведомления с попыткой, назначенной не позже него.
+//   - lease: Время, через которое уведомление выбирается снова, если результат попытки
+//     не записан.
+//   - limit: Наибольшее количество уведомлений.
+//
+// Возвращает:
+//   - Выбранные уведомления в порядке ID.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+//
+// Пример использования:
+//
+//	notifications, err := repo.ClaimNotifications(ctx, time.Now(), time.Minute, 100)
+func (r *PostgresRepository) ClaimNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Notification, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return claimNotifications(ctx, r.db, " FOR UPDATE SKIP LOCKED", now, now.Add(lease), limit)
+}
+
+// CompleteNotification записывает результат попытки доставки уведомления.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - id: Идентификатор уведомления.
+//   - status: models.NotificationSent, models.NotificationFailed или models.NotificationPending
+//     (повторная попытка в момент at).
+//   - lastError: Ошибка попытки (пустая строка при доставке).
+//   - at: Время завершения или следующей попытки.
+//
+// Возвращает:
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+func (r *PostgresRepository) CompleteNotification(ctx context.Context, id int64, status, lastError string, at time.Time) error {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return completeNotification(ctx, r.db, id, status, lastError, at)
+}
+
+// GetNotifications возвращает последние count уведомлений, начиная с самого нового.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - status: Статус уведомлений (пустая строка - любой).
+//   - count: Количество уведомлений.
+//
+// Возвращает:
+//   - Список уведомлений.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+func (r *PostgresRepository) GetNotifications(ctx context.Context, status string, count int) ([]models.Notification, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return getNotifications(ctx, r.db, status, count)
+}
+
+// Reconcile проверяет инварианты хранилища в основной базе. Запросы просматривают
+// таблицы wallets и transactions целиком, поэтому на большой базе может понадобиться
+// увеличить DB_QUERY_TIMEOUT.
+//
+// Возвращает:
+//   - Сводку проверки; нарушения сообщает ReconcileReport.Drift.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+//
+// Пример использования:
+//
+//	report, err := repo.Reconcile(ctx)
+func (r *PostgresRepository) Reconcile(ctx context.Context) (ReconcileReport, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return reconcile(ctx, r.db, pgSum)
+}
+
+// Ping проверяет подключение к базе данных.
+//
+// Параметры:
+//   - ctx: Контекст для выполнения запроса.
+//
+// Возвращает:
+//   - Ошибку, если подключение не удалось.
+//
+// Пример использования:
+//
+//	err := repo.Ping(context.Background())
+func (r *PostgresRepository) Ping(ctx context.Context) error {
+	if err := r.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+	return nil
+}
+
+// Stats возвращает статистику пула подключений к основной базе
+// (открытые, занятые и свободные подключения, ожидания свободного подключения).
+//
+// Пример использования:
+//
+//	metrics.RegisterDBPool("primary", repo.Stats)
+func (r *PostgresRepository) Stats() sql.DBStats {
+	return r.db.Stats()
+}
+
+// HasReplica сообщает, настроена ли реплика для чтения (DB_READ_HOST).
+func (r *PostgresRepository) HasReplica() bool {
+	return r.replica != nil
+}
+
+// ReplicaStats возвращает статистику пула подключений к реплике.
+// Если реплика не настроена, возвращает пустую статистику.
+//
+// Пример использования:
+//
+//	if repo.HasReplica() {
+//		metrics.RegisterDBPool("replica", repo.ReplicaStats)
+//	}
+func (r *PostgresRepository) ReplicaStats() sql.DBStats {
+	if r.replica == nil {
+		return sql.DBStats{}
+	}
+	return r.replica.db.Stats()
+}
@@ -0,0 +1,337 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"payment-system/internal/db"
+	"payment-system/internal/db/dbtest"
+	"payment-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestPostgresRepository проверяет контракт на PostgreSQL. Проверка выполняется, только если
+// задан TEST_POSTGRES=1; база задается переменными DB_HOST, DB_USER, DB_PASSWORD и DB_NAME
+// и очищается перед каждой проверкой.
+func TestPostgresRepository(t *testing.T) {
+	if os.Getenv("TEST_POSTGRES") != "1" {
+		t.Skip("TEST_POSTGRES=1 is not set")
+	}
+	dbtest.RunContract(t, func(t *testing.T) db.Repository {
+		return db.NewTestPostgresRepository(t)
+	})
+}
+
+// TestPostgresDeadlockRetry вызывает взаимоблокировку: две транзакции WithTx изменяют балансы
+// двух кошельков в противоположном порядке, дождавшись друг друга после первого изменения.
+// PostgreSQL отменяет одну из них с 40P01, и repository повторяет ее: обе транзакции
+// фиксируются, а функция одной из них выполняется дважды. Требует TEST_POSTGRES=1.
+func TestPostgresDeadlockRetry(t *testing.T) {
+	if os.Getenv("TEST_POSTGRES") != "1" {
+		t.Skip("TEST_POSTGRES=1 is not set")
+	}
+	t.Setenv("DB_SEND_ATTEMPTS", "3")
+	repo := db.NewTestPostgresRepository(t)
+	ctx := context.Background()
+
+	wallets := make([]string, 2)
+	for i := range wallets {
+		address, err := db.GenerateAddress()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.CreateWallet(ctx, address, decimal.NewFromInt(100), models.WalletMetadata{}, ""); err != nil {
+			t.Fatalf("CreateWallet: %v", err)
+		}
+		wallets[i] = address
+	}
+
+	var calls atomic.Int32
+	var locked sync.WaitGroup
+	locked.Add(2)
+	errs := make(chan error, 2)
+	for i := range wallets {
+		first, second := wallets[i], wallets[1-i]
+		go func() {
+			var attempt atomic.Int32
+			errs <- repo.WithTx(ctx, func(tx db.TxRepository) error {
+				calls.Add(1)
+				if _, err := tx.AddBalance(first, decimal.NewFromInt(-10)); err != nil {
+					return err
+				}
+				// Только первая попытка ждет вторую транзакцию, повтор проходит без ожидания
+				if attempt.Add(1) == 1 {
+					locked.Done()
+					locked.Wait()
+				}
+				_, err := tx.AddBalance(second, decimal.NewFromInt(10))
+				return err
+			})
+		}()
+	}
+	for range wallets {
+		if err := <-errs; err != nil {
+			t.Fatalf("WithTx: %v", err)
+		}
+	}
+
+	if got := calls.Load(); got != 3 {
+		t.Errorf("transaction functions ran %d times, want 3 (one retry after the deadlock)", got)
+	}
+	for _, address := range wallets {
+		balance, err := repo.GetBalance(ctx, address)
+		if err != nil {
+			t.Fatalf("GetBalance: %v", err)
+		}
+		if !balance.Equal(decimal.NewFromInt(100)) {
+			t.Errorf("balance of %s = %s, want 100", address, balance)
+		}
+	}
+}
+
+// TestPostgresLockStrategies проверяет корректность переводов при каждой стратегии DB_LOCK_STRATEGY:
+// параллельные встречные переводы по кругу и переводы с одного кошелька не уводят баланс
+// в минус и не меняют сумму балансов, а блокировки отклоненного перевода снимаются вместе
+// с его транзакцией. Требует TEST_POSTGRES=1.
+func TestPostgresLockStrategies(t *testing.T) {
+	if os.Getenv("TEST_POSTGRES") != "1" {
+		t.Skip("TEST_POSTGRES=1 is not set")
+	}
+	const (
+		walletCount = 4
+		initial     = 50
+		workers     = 8
+		perWorker   = 25
+	)
+	for _, strategy := range []string{"row", "advisory", "advisory-sender"} {
+		t.Run(strategy, func(t *testing.T) {
+			t.Setenv("DB_LOCK_STRATEGY", strategy)
+			repo := db.NewTestPostgresRepository(t)
+			ctx := context.Background()
+
+			wallets := make([]string, walletCount)
+			for i := range wallets {
+				address, err := db.GenerateAddress()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := repo.CreateWallet(ctx, address, decimal.NewFromInt(initial), models.WalletMetadata{}, ""); err != nil {
+					t.Fatalf("CreateWallet: %v", err)
+				}
+				wallets[i] = address
+			}
+
+			var sent atomic.Int64
+			var wg sync.WaitGroup
+			for w := 0; w < workers; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					for i := 0; i < perWorker; i++ {
+						// Четные потоки переводят по кругу в разные стороны, нечетные - с первого кошелька
+						from, to := wallets[(w+i)%walletCount], wallets[(w+i+1)%walletCount]
+						if w%4 == 2 {
+							from, to = to, from
+						}
+						if w%2 == 1 {
+							from, to = wallets[0], wallets[1+i%(walletCount-1)]
+						}
+						_, err := repo.Send(ctx, from, to, decimal.NewFromInt(7), "", "", 0, sql.LevelDefault)
+						switch {
+						case err == nil:
+							sent.Add(1)
+						case errors.Is(err, db.ErrInsufficientFunds), errors.Is(err, db.ErrContention):
+						default:
+							t.Errorf("Send: %v", err)
+						}
+					}
+				}(w)
+			}
+			wg.Wait()
+
+			total := decimal.Zero
+			for _, address := range wallets {
+				balance, err := repo.GetBalance(ctx, address)
+				if err != nil {
+					t.Fatalf("GetBalance: %v", err)
+				}
+				if balance.IsNegative() {
+					t.Errorf("balance of %s = %s, want non-negative", address, balance)
+				}
+				total = total.Add(balance)
+			}
+			if want := decimal.NewFromInt(initial * walletCount); !total.Equal(want) {
+				t.Errorf("total balance = %s, want %s", total, want)
+			}
+			if count, err := repo.CountTransactions(ctx, db.TransactionFilter{}); err != nil || count != sent.Load() {
+				t.Errorf("CountTransactions = %d, %v; want %d successful sends", count, err, sent.Load())
+			}
+
+			// Отклоненный перевод откатывает транзакцию и снимает блокировку: следующий
+			// перевод с того же кошелька не ждет ее до DB_QUERY_TIMEOUT
+			rich := wallets[0]
+			if _, err := repo.Send(ctx, rich, wallets[1], decimal.NewFromInt(1_000_000), "", "", 0, sql.LevelDefault); !errors.Is(err, db.ErrInsufficientFunds) {
+				t.Fatalf("Send over the balance: got %v, want ErrInsufficientFunds", err)
+			}
+			quick, cancel := context.WithTimeout(ctx, 2*time.Second)
+			defer cancel()
+			if _, err := repo.GetBalance(quick, rich); err != nil {
+				t.Fatalf("GetBalance after rejected send: %v", err)
+			}
+			if _, err := repo.Send(quick, wallets[1], rich, decimal.NewFromInt(1), "", "", 0, sql.LevelDefault); err != nil && !errors.Is(err, db.ErrInsufficientFunds) {
+				t.Fatalf("Send after rejected send: %v", err)
+			}
+		})
+	}
+}
+
+// BenchmarkSendLockStrategy сравнивает стратегии DB_LOCK_STRATEGY при разной конкуренции:
+// параллельные переводы между случайными кошельками из wallets; чем меньше кошельков,
+// тем чаще переводы ждут одни и те же блокировки. Метрика contention/op - доля переводов,
+// завершившихся ErrContention после всех повторов. Как и контракт, требует TEST_POSTGRES=1:
+//
+//	TEST_POSTGRES=1 go test ./internal/db -run '^$' -bench SendLockStrategy
+func BenchmarkSendLockStrategy(b *testing.B) {
+	if os.Getenv("TEST_POSTGRES") != "1" {
+		b.Skip("TEST_POSTGRES=1 is not set")
+	}
+	for _, strategy := range []string{"row", "advisory", "advisory-sender"} {
+		for _, wallets := range []int{2, 8, 64} {
+			b.Run(fmt.Sprintf("%s/wallets=%d", strategy, wallets), func(b *testing.B) {
+				b.Setenv("DB_LOCK_STRATEGY", strategy)
+				repo := db.NewTestPostgresRepository(b)
+				benchmarkSends(b, repo, wallets)
+			})
+		}
+	}
+}
+
+// BenchmarkGetBalanceExecMode сравнивает GetBalance под параллельной нагрузкой с кэшем
+// подготовленных запросов драйвера (cache_statement, по умолчанию) и без него
+// (simple_protocol, запрос разбирается при каждом вызове). Требует TEST_POSTGRES=1:
+//
+//	TEST_POSTGRES=1 go test ./internal/db -run '^$' -bench GetBalanceExecMode
+func BenchmarkGetBalanceExecMode(b *testing.B) {
+	if os.Getenv("TEST_POSTGRES") != "1" {
+		b.Skip("TEST_POSTGRES=1 is not set")
+	}
+	for _, mode := range []string{"simple_protocol", "cache_describe", "cache_statement"} {
+		b.Run(mode, func(b *testing.B) {
+			b.Setenv("DB_QUERY_EXEC_MODE", mode)
+			repo := db.NewTestPostgresRepository(b)
+
+			ctx := context.Background()
+			address, err := db.GenerateAddress()
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err := repo.CreateWallet(ctx, address, decimal.NewFromInt(100), models.WalletMetadata{}, ""); err != nil {
+				b.Fatal(err)
+			}
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := repo.GetBalance(ctx, address); err != nil {
+						b.Error(err)
+					}
+				}
+			})
+		})
+	}
+}
+
+// benchmarkSends выполняет b.N параллельных переводов по 1 между случайными кошельками из count.
+func benchmarkSends(b *testing.B, repo db.Repository, count int) {
+	ctx := context.Background()
+	addresses := make([]string, count)
+	for i := range addresses {
+		address, err := db.GenerateAddress()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := repo.CreateWallet(ctx, address, decimal.NewFromInt(1_000_000_000), models.WalletMetadata{}, ""); err != nil {
+			b.Fatal(err)
+		}
+		addresses[i] = address
+	}
+
+	var contention atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			from := rand.IntN(count)
+			to := (from + 1 + rand.IntN(count-1)) % count
+			_, err := repo.Send(ctx, addresses[from], addresses[to], decimal.NewFromInt(1), "", "", 0, sql.LevelDefault)
+			switch {
+			case errors.Is(err, db.ErrContention):
+				contention.Add(1)
+			case err != nil:
+				b.Error(err)
+			}
+		}
+	})
+	b.ReportMetric(float64(contention.Load())/float64(b.N), "contention/op")
+}
+
+// benchmarkTransactionFeed измеряет чтение ленты транзакций из 1000 импортированных строк:
+// одним запросом count=1000 и постранично по 100 через TransactionFilter.After. Запускать
+// с -benchmem: число выделений на операцию показывает, что результат не перевыделяется по мере роста.
+func benchmarkTransactionFeed(b *testing.B, repo db.Repository) {
+	const rows = 1000
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	transactions := make([]models.Transaction, rows)
+	for i := range transactions {
+		transactions[i] = models.Transaction{
+			From:       fmt.Sprintf("w%d", i%10),
+			To:         fmt.Sprintf("w%d", (i+1)%10),
+			Amount:     decimal.NewFromInt(int64(i + 1)),
+			CreatedAt:  start.Add(time.Duration(i) * time.Second),
+			ExternalID: fmt.Sprintf("bench-%d", i),
+		}
+	}
+	if _, err := repo.ImportTransactions(ctx, transactions); err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("count=1000", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if got, err := repo.GetLastTransactions(ctx, rows, db.TransactionFilter{}); err != nil || len(got) != rows {
+				b.Fatalf("GetLastTransactions: %d rows, err %v", len(got), err)
+			}
+		}
+	})
+	b.Run("paged=100", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var filter db.TransactionFilter
+			read := 0
+			for {
+				page, err := repo.GetLastTransactions(ctx, 100, filter)
+				if err != nil {
+					b.Fatal(err)
+				}
+				read += len(page)
+				if len(page) < 100 {
+					break
+				}
+				cursor := db.CursorOf(page[len(page)-1])
+				filter.After = &cursor
+			}
+			if read != rows {
+				b.Fatalf("paged feed read %d rows, want %d", read, rows)
+			}
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// purgeLockKey - ключ рекомендательной блокировки PostgreSQL, под которой выполняется пачка
+// очистки истории; экземпляры сервиса, запустившие очистку одновременно, не обрабатывают
+// одни и те же транзакции.
+const purgeLockKey int64 = 0x7061796d70757267
+
+// archiveColumns - столбцы транзакции, переносимые в transactions_archive без изменений.
+const archiveColumns = "id, from_address, to_address, amount, timestamp, memo, category, external_id, imported, type"
+
+// purgeTransactions переносит в transactions_archive (при archive = false - удаляет) не более
+// limit самых старых транзакций, выполненных раньше момента before, одной транзакцией базы.
+// Запрос lock, если не пуст, берет блокировку purgeLockKey и возвращает false, если она занята;
+// rowLock (" FOR UPDATE" для PostgreSQL) блокирует выбранные строки до конца транзакции.
+// Синтаксис совместим с PostgreSQL и SQLite.
+//
+// Отложенные переводы на транзакции не ссылаются: перевод записывается в transactions только
+// при выполнении, поэтому удержания средств очистку не ограничивают.
+//
+// Удаляемые без архива переводы переносятся в начальные балансы участников
+// (wallets.opening_balance), чтобы Reconcile по-прежнему сходился с историей.
+//
+// Возвращает:
+//   - Количество перенесенных (удаленных) транзакций.
+//   - ErrPurgeLocked, если очистку уже выполняет другой экземпляр.
+func purgeTransactions(ctx context.Context, db *sql.DB, lock, rowLock string, before interface{}, archive bool, limit int) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if lock != "" {
+		var acquired bool
+		if err := tx.QueryRowContext(ctx, lock, purgeLockKey).Scan(&acquired); err != nil {
+			return 0, fmt.Errorf("failed to acquire purge lock: %w", err)
+		}
+		if !acquired {
+			return 0, ErrPurgeLocked
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		"SELECT id FROM transactions WHERE timestamp < $1 ORDER BY id LIMIT $2"+rowLock, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to select transactions to purge: %w", err)
+	}
+	var ids []interface{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan transaction id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("rows error: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	// Строки выбираются по списку id, а не повтором условия: импорт может добавить
+	// старые транзакции между запросами, и они не должны удалиться без переноса
+	placeholders := make([]string, len(ids))
+	for i := range ids {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	inIDs := " WHERE id IN (" + strings.Join(placeholders, ", ") + ")"
+
+	if archive {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO transactions_archive ("+archiveColumns+") SELECT "+archiveColumns+" FROM transactions"+inIDs, ids...)
+		if err != nil {
+			return 0, fmt.Errorf("failed to archive transactions: %w", err)
+		}
+	} else if err := foldPurgedTransfers(ctx, tx, inIDs, ids); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM transactions"+inIDs, ids...); err != nil {
+		return 0, fmt.Errorf("failed to purge transactions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit purge: %w", err)
+	}
+	return len(ids), nil
+}
+
+// foldPurgedTransfers добавляет к начальным балансам участников изменения по переводам
+// с id из условия inIDs, которые удаляются без архива: после удаления начальный баланс
+// плюс оставшаяся история по-прежнему равен балансу. Импортированные транзакции балансы
+// не меняли и не учитываются. Начальные балансы меняют только очистка и миграция
+// (backfillOpeningBalances), поэтому чтение и запись в Go не пересекаются с переводами.
+func foldPurgedTransfers(ctx context.Context, tx *sql.Tx, inIDs string, ids []interface{}) error {
+	rows, err := tx.QueryContext(ctx, "SELECT from_address, to_address, amount FROM transactions"+inIDs+" AND NOT imported", ids...)
+	if err != nil {
+		return fmt.Errorf("failed to select purged transfers: %w", err)
+	}
+	deltas := make(map[string]decimal.Decimal)
+	for rows.Next() {
+		var from, to string
+		var amount decimal.Decimal
+		if err := rows.Scan(&from, &to, &amount); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan purged transfer: %w", err)
+		}
+		deltas[from] = deltas[from].Sub(amount)
+		deltas[to] = deltas[to].Add(amount)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+
+	for address, delta := range deltas {
+		var opening decimal.NullDecimal
+		err := tx.QueryRowContext(ctx, "SELECT opening_balance FROM wallets WHERE address = $1", address).Scan(&opening)
+		if errors.Is(err, sql.ErrNoRows) || err == nil && !opening.Valid {
+			// Кошелек без начального баланса заполнит backfillOpeningBalances
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read opening balance: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE wallets SET opening_balance = $1 WHERE address = $2", opening.Decimal.Add(delta), address)
+		if err != nil {
+			return fmt.Errorf("failed to update opening balance: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestPostgresPurgeLock проверяет, что пачка очистки в PostgreSQL выполняется под
+// рекомендательной блокировкой: если ее держит другой экземпляр, транзакции не выбираются
+// и возвращается ErrPurgeLocked.
+func TestPostgresPurgeLock(t *testing.T) {
+	const (
+		lockQuery   = "SELECT pg_try_advisory_xact_lock($1)"
+		selectQuery = "SELECT id FROM transactions WHERE timestamp < $1 ORDER BY id LIMIT $2 FOR UPDATE"
+	)
+
+	for _, acquired := range []bool{true, false} {
+		t.Run(fmt.Sprintf("acquired=%t", acquired), func(t *testing.T) {
+			d := &scriptedDriver{
+				query: func(query string, call int) ([]string, [][]driver.Value, error) {
+					switch query {
+					case lockQuery:
+						return []string{"pg_try_advisory_xact_lock"}, [][]driver.Value{{acquired}}, nil
+					case selectQuery:
+						return []string{"id"}, nil, nil
+					}
+					return nil, nil, fmt.Errorf("unexpected query %q", query)
+				},
+			}
+			r := newScriptedPostgres(t, d, 1)
+
+			purged, err := r.PurgeTransactions(context.Background(), time.Now(), true, 100)
+			if acquired {
+				if err != nil || purged != 0 {
+					t.Fatalf("PurgeTransactions = %d, %v, want 0, nil", purged, err)
+				}
+			} else if !errors.Is(err, ErrPurgeLocked) {
+				t.Fatalf("PurgeTransactions = %d, %v, want ErrPurgeLocked", purged, err)
+			}
+
+			wantSelects := 0
+			if acquired {
+				wantSelects = 1
+			}
+			if got := d.callCount(selectQuery); got != wantSelects {
+				t.Errorf("selected transactions %d times, want %d", got, wantSelects)
+			}
+		})
+	}
+}
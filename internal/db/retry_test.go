@@ -0,0 +1,132 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"serialization failure", &pgconn.PgError{Code: pgSerializationFailure}, true},
+		{"deadlock", &pgconn.PgError{Code: pgDeadlockDetected}, true},
+		{"wrapped deadlock", fmt.Errorf("failed to update balance: %w", &pgconn.PgError{Code: pgDeadlockDetected}), true},
+		{"unique violation", &pgconn.PgError{Code: "23505"}, false},
+		{"lock timeout", &pgconn.PgError{Code: "55P03"}, false},
+		{"insufficient funds", ErrInsufficientFunds, false},
+		{"contention", ErrContention, false},
+		{"plain error", errors.New("40001"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.want {
+				t.Errorf("isRetryable(%v) = %t, want %t", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendAttemptsFromEnv(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"", defaultSendAttempts},
+		{"1", 1},
+		{"10", 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("DB_SEND_ATTEMPTS", tt.value)
+			if got := sendAttemptsFromEnv(); got != tt.want {
+				t.Errorf("sendAttemptsFromEnv() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// txidQuery - запрос номера транзакции, который runTx выполняет перед фиксацией.
+const txidQuery = "SELECT txid_current()"
+
+// answerTxid отвечает на txidQuery номером вызова.
+func answerTxid(query string, call int) ([]string, [][]driver.Value, error) {
+	if query != txidQuery {
+		return nil, nil, fmt.Errorf("unexpected query %q", query)
+	}
+	return []string{"txid_current"}, [][]driver.Value{{int64(call)}}, nil
+}
+
+// TestPostgresWithTxRetry имитирует взаимоблокировку и конфликт сериализации драйвером:
+// первые failures попыток обновления завершаются ошибкой, после чего транзакция проходит.
+// Повтор выполняет всю транзакцию заново, а после sendAttempts неудач возвращается ErrContention.
+func TestPostgresWithTxRetry(t *testing.T) {
+	const update = "UPDATE wallets SET balance = balance - $1 WHERE address = $2"
+
+	tests := []struct {
+		name         string
+		failures     int
+		err          error
+		wantAttempts int
+		wantErr      error
+	}{
+		{"no conflict", 0, nil, 1, nil},
+		{"deadlock once", 1, &pgconn.PgError{Code: pgDeadlockDetected}, 2, nil},
+		{"serialization failure twice", 2, &pgconn.PgError{Code: pgSerializationFailure}, 3, nil},
+		{"deadlock on every attempt", 3, &pgconn.PgError{Code: pgDeadlockDetected}, 3, ErrContention},
+		{"not retryable", 1, &pgconn.PgError{Code: "23514"}, 1, &pgconn.PgError{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &scriptedDriver{
+				exec: func(query string, call int) error {
+					if call <= tt.failures {
+						return tt.err
+					}
+					return nil
+				},
+				query: answerTxid,
+			}
+			r := newScriptedPostgres(t, d, 3)
+
+			err := r.withTx(context.Background(), sql.LevelReadCommitted, func(tx *pgTx) error {
+				_, err := tx.tx.ExecContext(tx.ctx, update, "1", "w0")
+				return err
+			})
+			switch want := tt.wantErr.(type) {
+			case nil:
+				if err != nil {
+					t.Fatalf("withTx: %v", err)
+				}
+			case *pgconn.PgError:
+				if !errors.As(err, &want) || isRetryable(err) {
+					t.Fatalf("withTx = %v, want the non-retryable database error", err)
+				}
+			default:
+				if !errors.Is(err, want) {
+					t.Fatalf("withTx = %v, want %v", err, want)
+				}
+			}
+
+			if got := d.callCount(update); got != tt.wantAttempts {
+				t.Errorf("transaction body ran %d times, want %d", got, tt.wantAttempts)
+			}
+			wantCommits := 0
+			if tt.wantErr == nil {
+				wantCommits = 1
+			}
+			if d.commits != wantCommits {
+				t.Errorf("%d commits, want %d", d.commits, wantCommits)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"payment-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// SenderStats - сводка переводов с кошелька за период.
+type SenderStats struct {
+	Count int             // Количество переводов
+	Total decimal.Decimal // Сумма переводов
+}
+
+// senderStatsQuery выбирает количество и сумму переводов отправителя начиная с $2.
+// Синтаксис совместим с PostgreSQL и SQLite; sum - агрегатная функция точной суммы
+// конкретной базы (pgSum или sqliteSum).
+func senderStatsQuery(sum string) string {
+	return `SELECT COUNT(*), COALESCE(` + sum + `(amount), 0) FROM transactions
+	WHERE from_address = $1 AND timestamp >= $2 AND NOT imported`
+}
+
+// getSenderStats выполняет senderStatsQuery. Момент since передается в виде,
+// сравнимом со столбцом timestamp конкретной базы.
+func getSenderStats(ctx context.Context, db *sql.DB, sum, address string, since interface{}) (SenderStats, error) {
+	var stats SenderStats
+	if err := db.QueryRowContext(ctx, senderStatsQuery(sum), address, since).Scan(&stats.Count, &stats.Total); err != nil {
+		return SenderStats{}, fmt.Errorf("failed to get sender stats: %w", err)
+	}
+	return stats, nil
+}
+
+// recordRiskEvent сохраняет срабатывание правила; время назначает база.
+func recordRiskEvent(ctx context.Context, db *sql.DB, event models.RiskEvent) error {
+	_, err := db.ExecContext(ctx,
+		"INSERT INTO risk_events (rule, action, from_address, to_address, amount, reason) VALUES ($1, $2, $3, $4, $5, $6)",
+		event.Rule, event.Action, event.From, event.To, event.Amount, event.Reason)
+	if err != nil {
+		return fmt.Errorf("failed to record risk event: %w", err)
+	}
+	return nil
+}
+
+// getRiskEvents возвращает последние count срабатываний правил, начиная с самого нового.
+func getRiskEvents(ctx context.Context, db *sql.DB, count int) ([]models.RiskEvent, error) {
+	rows, err := db.QueryContext(ctx,
+		"SELECT id, rule, action, from_address, to_address, amount, reason, created_at FROM risk_events ORDER BY id DESC LIMIT $1",
+		count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query risk events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.RiskEvent
+	for rows.Next() {
+		var e models.RiskEvent
+		if err := rows.Scan(&e.ID, &e.Rule, &e.Action, &e.From, &e.To, &e.Amount, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan risk event: %w", err)
+		}
+		e.CreatedAt = e.CreatedAt.UTC()
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return events, nil
+}
+
+// sqliteTime форматирует момент так же, как SQLite хранит время транзакций
+// (strftime('%Y-%m-%d %H:%M:%f') в UTC), чтобы строки сравнивались как время.
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05.000")
+}
@@ -0,0 +1,1463 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"strings"
+	"time"
+
+	"payment-system/internal/models"
+
+	"github.com/shopspring/decimal"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"modernc.org/sqlite"
+)
+
+// sqliteDecimal - collation, сравнивающая суммы, которые SQLite хранит текстом, как числа:
+// без нее "9" больше "10". Используется в запросах, а не в схеме, чтобы базу можно было
+// открыть и без этой программы.
+const sqliteDecimal = "decimal"
+
+// sqliteSum - агрегатная функция точной суммы для SQLite. Суммы хранятся текстом
+// (decimal.Decimal.String()), а встроенная SUM привела бы их к REAL.
+const sqliteSum = "decimal_sum"
+
+func init() {
+	sqlite.MustRegisterCollationUtf8(sqliteDecimal, compareDecimals)
+	sqlite.MustRegisterFunction(sqliteSum, &sqlite.FunctionImpl{
+		NArgs:         1,
+		Deterministic: true,
+		MakeAggregate: func(sqlite.FunctionContext) (sqlite.AggregateFunction, error) {
+			return &decimalSum{}, nil
+		},
+	})
+}
+
+// compareDecimals сравнивает суммы в текстовом виде для collation decimal.
+// Значение, которое не разбирается как число, считается меньше любого числа.
+func compareDecimals(left, right string) int {
+	a, errA := decimal.NewFromString(left)
+	b, errB := decimal.NewFromString(right)
+	switch {
+	case errA != nil && errB != nil:
+		return strings.Compare(left, right)
+	case errA != nil:
+		return -1
+	case errB != nil:
+		return 1
+	}
+	return a.Cmp(b)
+}
+
+// decimalSum вычисляет decimal_sum. Как и SUM, для пустой выборки возвращает NULL.
+type decimalSum struct {
+	sum  decimal.Decimal
+	rows int
+}
+
+// Step добавляет к сумме значение очередной строки; NULL пропускается.
+func (s *decimalSum) Step(_ *sqlite.FunctionContext, args []driver.Value) error {
+	if args[0] == nil {
+		return nil
+	}
+	var amount decimal.Decimal
+	if err := amount.Scan(args[0]); err != nil {
+		return err
+	}
+	s.sum = s.sum.Add(amount)
+	s.rows++
+	return nil
+}
+
+// WindowInverse вычитает из суммы значение строки, вышедшей из окна.
+func (s *decimalSum) WindowInverse(_ *sqlite.FunctionContext, args []driver.Value) error {
+	if args[0] == nil {
+		return nil
+	}
+	var amount decimal.Decimal
+	if err := amount.Scan(args[0]); err != nil {
+		return err
+	}
+	s.sum = s.sum.Sub(amount)
+	s.rows--
+	return nil
+}
+
+// WindowValue возвращает текущую сумму текстом.
+func (s *decimalSum) WindowValue(*sqlite.FunctionContext) (driver.Value, error) {
+	if s.rows == 0 {
+		return nil, nil
+	}
+	return s.sum.String(), nil
+}
+
+// Final ничего не освобождает: сумма хранится в памяти Go.
+func (s *decimalSum) Final(*sqlite.FunctionContext) {}
+
+// SQLiteRepository представляет репозиторий для работы с SQLite.
+// Предназначен для небольших установок и локальной разработки без сервера PostgreSQL;
+// драйвер modernc.org/sqlite не требует cgo, поэтому приложение остается одним бинарником.
+type SQLiteRepository struct {
+	db           *sql.DB
+	queryTimeout time.Duration     // Ограничение времени одного запроса или транзакции
+	minBalance   decimal.Decimal   // Неснижаемый остаток кошелька отправителя
+	clock        Clock             // Источник времени записанных транзакций
+	addresses    *AddressGenerator // Генератор адресов CreateWallets
+}
+
+// NewSQLiteRepository создает новый экземпляр SQLiteRepository.
+// Открывает (или создает) файл базы данных и инициализирует таблицы; кошельки, как
+// и NewPostgresRepository, не создает.
+//
+// Все транзакции открываются как BEGIN IMMEDIATE (параметр _txlock), поэтому блокировка
+// на запись берется сразу и параллельные переводы не могут прочитать устаревший баланс.
+//
+// Параметры:
+//   - path: Путь к файлу базы данных или ":memory:".
+//
+// Пример использования:
+//
+//	repo := NewSQLiteRepository("payment-system.db")
+func NewSQLiteRepository(path string) *SQLiteRepository {
+	db, err := openDB("sqlite", fmt.Sprintf(
+		"file:%s?_txlock=immediate&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)",
+		path,
+	), semconv.DBSystemSqlite)
+	if err != nil {
+		log.Fatal("Failed to open database:", err)
+	}
+
+	// Каждое подключение к ":memory:" видит собственную пустую базу
+	if path == ":memory:" {
+		db.SetMaxOpenConns(1)
+	}
+
+	// Инициализация таблиц
+	if err := initSQLiteTables(db); err != nil {
+		log.Fatal("Failed to initialize tables:", err)
+	}
+
+	return &SQLiteRepository{db: db, queryTimeout: queryTimeoutFromEnv(), minBalance: MinWalletBalance(), clock: SystemClock{}, addresses: DefaultAddressGenerator}
+}
+
+// SetClock задает источник времени, которым отмечаются записанные транзакции, как
+// PostgresRepository.SetClock. Вызывается до начала обработки запросов.
+func (r *SQLiteRepository) SetClock(clock Clock) {
+	r.clock = clock
+}
+
+// SetAddressGenerator задает генератор адресов кошельков, создаваемых CreateWallets, как
+// PostgresRepository.SetAddressGenerator. Вызывается до начала обработки запросов.
+func (r *SQLiteRepository) SetAddressGenerator(addresses *AddressGenerator) {
+	r.addresses = addresses
+}
+
+// initSQLiteTables создает таблицы wallets и transactions, если они не существуют.
+// Время транзакции хранится с миллисекундами, так как CURRENT_TIMESTAMP в SQLite
+// имеет точность до секунды. Десятичного типа в SQLite нет, поэтому суммы и балансы
+// хранятся текстом (decimal.Decimal.String()), а считаются в Go или функцией decimal_sum.
+//
+// Параметры:
+//   - db: Указатель на подключение к базе данных.
+//
+// Возвращает:
+//   - Ошибку, если не удалось создать таблицы.
+func initSQLiteTables(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS wallets (
+			address TEXT PRIMARY KEY,
+			balance TEXT
+		);
+		CREATE TABLE IF NOT EXISTS transactions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			from_address TEXT,
+			to_address TEXT,
+			amount TEXT,
+			timestamp TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+			memo TEXT
+		);
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Столбцы, появившиеся после первой версии схемы
+	columns := []struct{ table, name, definition string }{
+		{"transactions", "memo", "TEXT"},
+		{"transactions", "external_id", "TEXT"},
+		{"transactions", "imported", "BOOLEAN NOT NULL DEFAULT FALSE"},
+		{"transactions", "category", "TEXT"},
+		{"wallets", "label", "TEXT"},
+		{"wallets", "tags", "TEXT NOT NULL DEFAULT '{}'"},
+		{"wallets", "public_key", "TEXT"},
+		{"wallets", "nonce", "INTEGER NOT NULL DEFAULT 0"},
+		{"wallets", "archived_at", "TIMESTAMP"},
+	}
+	for _, c := range columns {
+		if err := addSQLiteColumn(db, c.table, c.name, c.definition); err != nil {
+			return err
+		}
+	}
+
+	_, err = db.Exec(`
+		CREATE UNIQUE INDEX IF NOT EXISTS transactions_external_id_key ON transactions (external_id);
+		CREATE UNIQUE INDEX IF NOT EXISTS wallets_label_key ON wallets (label);
+		CREATE INDEX IF NOT EXISTS transactions_from_address_idx ON transactions (from_address, timestamp);
+		-- История кошелька читается объединением (UNION ALL) запроса по отправителю и запроса
+		-- по получателю: каждый идет по своему индексу (адрес, timestamp) в порядке времени
+		-- и останавливается на LIMIT. Условие from_address = $1 OR to_address = $1 сортирует
+		-- все переводы кошелька и у кошелька с частыми переводами медленнее на два порядка
+		DROP INDEX IF EXISTS transactions_to_address_idx;
+		CREATE INDEX IF NOT EXISTS transactions_to_address_timestamp_idx ON transactions (to_address, timestamp);
+		CREATE TABLE IF NOT EXISTS risk_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			rule TEXT NOT NULL,
+			action TEXT NOT NULL,
+			from_address TEXT NOT NULL,
+			to_address TEXT NOT NULL,
+			amount TEXT NOT NULL,
+			reason TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+		);
+		CREATE TABLE IF NOT EXISTS pending_approvals (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			from_address TEXT NOT NULL,
+			to_address TEXT NOT NULL,
+			amount TEXT NOT NULL,
+			memo TEXT NOT NULL DEFAULT '',
+			nonce INTEGER NOT NULL DEFAULT 0,
+			status TEXT NOT NULL,
+			reason TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+			decided_at TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS pending_approvals_status_idx ON pending_approvals (status, created_at);
+		CREATE INDEX IF NOT EXISTS transactions_timestamp_idx ON transactions (timestamp);
+		CREATE TABLE IF NOT EXISTS transactions_archive (
+			id INTEGER PRIMARY KEY,
+			from_address TEXT,
+			to_address TEXT,
+			amount TEXT,
+			timestamp TIMESTAMP,
+			memo TEXT,
+			external_id TEXT,
+			imported BOOLEAN NOT NULL DEFAULT FALSE,
+			archived_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS transactions_archive_external_id_key ON transactions_archive (external_id);
+		CREATE INDEX IF NOT EXISTS transactions_archive_from_address_idx ON transactions_archive (from_address);
+		CREATE INDEX IF NOT EXISTS transactions_archive_to_address_idx ON transactions_archive (to_address);
+		CREATE INDEX IF NOT EXISTS transactions_archive_timestamp_idx ON transactions_archive (timestamp);
+		CREATE TABLE IF NOT EXISTS audit_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			action TEXT NOT NULL,
+			target TEXT NOT NULL,
+			details TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+		);
+		CREATE TABLE IF NOT EXISTS idempotency_keys (
+			idempotency_key TEXT PRIMARY KEY,
+			fingerprint TEXT NOT NULL,
+			status INTEGER NOT NULL DEFAULT 0,
+			content_type TEXT NOT NULL DEFAULT '',
+			location TEXT NOT NULL DEFAULT '',
+			body TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idempotency_keys_created_at_idx ON idempotency_keys (created_at);
+		CREATE TABLE IF NOT EXISTS notifications (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			transaction_id INTEGER NOT NULL,
+			address TEXT NOT NULL,
+			email TEXT NOT NULL,
+			from_address TEXT NOT NULL,
+			from_label TEXT NOT NULL DEFAULT '',
+			amount TEXT NOT NULL,
+			transferred_at TIMESTAMP NOT NULL,
+			status TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT NOT NULL DEFAULT '',
+			next_attempt_at TIMESTAMP NOT NULL,
+			completed_at TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS notifications_status_idx ON notifications (status, next_attempt_at);
+		CREATE INDEX IF NOT EXISTS notifications_address_idx ON notifications (address);
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Столбцы таблиц, созданных выше, появившиеся после их первой версии
+	columns = []struct{ table, name, definition string }{
+		{"pending_approvals", "category", "TEXT NOT NULL DEFAULT ''"},
+		{"transactions_archive", "category", "TEXT"},
+		{"wallets", "opening_balance", "TEXT"},
+		{"transactions", "type", "TEXT NOT NULL DEFAULT 'transfer'"},
+		{"transactions_archive", "type", "TEXT NOT NULL DEFAULT 'transfer'"},
+		{"wallets", "notify_email", "TEXT"},
+	}
+	for _, c := range columns {
+		if err := addSQLiteColumn(db, c.table, c.name, c.definition); err != nil {
+			return err
+		}
+	}
+	if err := migrateSQLiteAmounts(db); err != nil {
+		return err
+	}
+	if err := migrateSQLiteTimestamps(db); err != nil {
+		return err
+	}
+	if err := createSystemAccounts(db, sqliteInsertSystemAccount); err != nil {
+		return err
+	}
+	return backfillOpeningBalances(db, sqliteSum)
+}
+
+// sqliteAmountColumns - столбцы сумм, которые ранние версии схемы хранили как REAL,
+// с определениями столбцов TEXT, заменяющих их.
+var sqliteAmountColumns = []struct{ table, column, definition string }{
+	{"wallets", "balance", "TEXT"},
+	{"transactions", "amount", "TEXT"},
+	{"risk_events", "amount", "TEXT NOT NULL DEFAULT '0'"},
+	{"pending_approvals", "amount", "TEXT NOT NULL DEFAULT '0'"},
+}
+
+// migrateSQLiteAmounts переводит столбцы сумм из REAL в TEXT, если база создана ранней
+// версией схемы. Уже переведенные столбцы пропускаются, поэтому миграция выполняется
+// при каждом запуске без последствий.
+//
+// Параметры:
+//   - db: Указатель на подключение к базе данных.
+//
+// Возвращает:
+//   - Ошибку, если не удалось перевести один из столбцов (уже переведенные остаются TEXT).
+func migrateSQLiteAmounts(db *sql.DB) error {
+	for _, c := range sqliteAmountColumns {
+		var columnType string
+		err := db.QueryRow("SELECT type FROM pragma_table_info($1) WHERE name = $2", c.table, c.column).Scan(&columnType)
+		if err != nil {
+			return fmt.Errorf("failed to inspect %s.%s: %w", c.table, c.column, err)
+		}
+		if columnType != "REAL" {
+			continue
+		}
+		if err := migrateSQLiteAmountColumn(db, c.table, c.column, c.definition); err != nil {
+			return fmt.Errorf("failed to migrate %s.%s to TEXT: %w", c.table, c.column, err)
+		}
+		slog.Info("column migrated to TEXT", "table", c.table, "column", c.column)
+	}
+	return nil
+}
+
+// migrateSQLiteAmountColumn заменяет столбец REAL столбцом TEXT в одной транзакции.
+// SQLite не меняет тип существующего столбца, поэтому значения переписываются в новый
+// столбец, старый удаляется, а новый получает его имя. Значение записывается кратчайшей
+// десятичной записью числа REAL, округленной до models.AmountScale знаков, поэтому
+// накопленные ошибки округления (0.30000000000000004) исчезают.
+func migrateSQLiteAmountColumn(db *sql.DB, table, column, definition string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	migrated := column + "_text"
+	if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, migrated, definition)); err != nil {
+		return err
+	}
+
+	// Значения читаются целиком до обновления: транзакция занимает одно подключение
+	type row struct {
+		id    int64
+		value float64
+	}
+	rows, err := tx.Query(fmt.Sprintf("SELECT rowid, %s FROM %s WHERE %s IS NOT NULL", column, table, column))
+	if err != nil {
+		return err
+	}
+	var values []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.id, &r.value); err != nil {
+			rows.Close()
+			return err
+		}
+		values = append(values, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	update, err := tx.Prepare(fmt.Sprintf("UPDATE %s SET %s = $1 WHERE rowid = $2", table, migrated))
+	if err != nil {
+		return err
+	}
+	defer update.Close()
+	for _, r := range values {
+		if _, err := update.Exec(decimal.NewFromFloat(r.value).Round(models.AmountScale), r.id); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, column)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", table, migrated, column)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// goTimeLayout - формат time.Time.String(), в котором ранние версии сохраняли время
+// импортированных транзакций в SQLite вместо формата sqliteTime.
+const goTimeLayout = "2006-01-02 15:04:05.999999999 -0700 MST"
+
+// migrateSQLiteTimestamps переписывает время импортированных транзакций (и их копий в архиве),
+// сохраненное ранними версиями в формате goTimeLayout, в формат sqliteTime. SQLite сравнивает
+// время как текст, поэтому такие записи нарушали порядок списка и сравнение с курсором.
+// Записи в формате sqliteTime не затрагиваются, поэтому миграция выполняется при каждом
+// запуске без последствий.
+//
+// Параметры:
+//   - db: Указатель на подключение к базе данных.
+//
+// Возвращает:
+//   - Ошибку, если не удалось прочитать или переписать время.
+func migrateSQLiteTimestamps(db *sql.DB) error {
+	for _, table := range []string{"transactions", "transactions_archive"} {
+		// Значения читаются целиком до обновления, как в migrateSQLiteAmountColumn
+		rows, err := db.Query(fmt.Sprintf("SELECT id, CAST(timestamp AS TEXT) FROM %s WHERE length(timestamp) <> 23", table))
+		if err != nil {
+			return fmt.Errorf("failed to inspect %s.timestamp: %w", table, err)
+		}
+		updates := make(map[int64]string)
+		for rows.Next() {
+			var id int64
+			var value string
+			if err := rows.Scan(&id, &value); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to inspect %s.timestamp: %w", table, err)
+			}
+			parsed, err := time.Parse(goTimeLayout, value)
+			if err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to parse %s.timestamp %q of id %d: %w", table, value, id, err)
+			}
+			updates[id] = sqliteTime(parsed)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to inspect %s.timestamp: %w", table, err)
+		}
+
+		for id, value := range updates {
+			if _, err := db.Exec(fmt.Sprintf("UPDATE %s SET timestamp = $1 WHERE id = $2", table), value, id); err != nil {
+				return fmt.Errorf("failed to migrate %s.timestamp of id %d: %w", table, id, err)
+			}
+		}
+		if len(updates) > 0 {
+			slog.Info("timestamps migrated to the SQLite format", "table", table, "count", len(updates))
+		}
+	}
+	return nil
+}
+
+// addSQLiteColumn добавляет столбец в существующую таблицу, если его еще нет.
+// SQLite не поддерживает ADD COLUMN IF NOT EXISTS, поэтому наличие проверяется через table_info.
+//
+// Параметры:
+//   - db: Указатель на подключение к базе данных.
+//   - table: Имя таблицы.
+//   - column: Имя столбца.
+//   - definition: Тип и ограничения столбца.
+//
+// Возвращает:
+//   - Ошибку, если не удалось проверить или добавить столбец.
+func addSQLiteColumn(db *sql.DB, table, column, definition string) error {
+	var exists bool
+	err := db.QueryRow("SELECT COUNT(*) > 0 FROM pragma_table_info($1) WHERE name = $2", table, column).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+// CreateWallet создает кошелек с указанным адресом, начальным балансом, метаданными и открытым ключом.
+// Занятость метки проверяется в той же транзакции: _txlock=immediate сериализует записи,
+// поэтому проверка и вставка не пересекаются с другими изменениями.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//   - balance: Начальный баланс.
+//   - metadata: Метка и теги кошелька.
+//   - publicKey: Открытый ключ ed25519 в шестнадцатеричном виде (пустая строка - без ключа).
+//
+// Возвращает:
+//   - ErrWalletExists, если кошелек с таким адресом уже существует.
+//   - ErrLabelExists, если метка присвоена другому кошельку.
+func (r *SQLiteRepository) CreateWallet(ctx context.Context, address string, balance decimal.Decimal, metadata models.WalletMetadata, publicKey string) error {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := createSQLiteWallet(ctx, tx, address, balance, metadata, publicKey); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// sqliteInsertSystemAccount - запрос создания системного счета в SQLite (см. createSystemAccounts).
+const sqliteInsertSystemAccount = "INSERT INTO wallets (address, balance, opening_balance, tags) VALUES ($1, $2, $2, $3) ON CONFLICT (address) DO NOTHING"
+
+// createSQLiteWallet проверяет метку и вставляет кошелек в рамках транзакции tx.
+func createSQLiteWallet(ctx context.Context, tx *sql.Tx, address string, balance decimal.Decimal, metadata models.WalletMetadata, publicKey string) error {
+	if err := checkSQLiteLabel(ctx, tx, metadata.Label, address); err != nil {
+		return fmt.Errorf("failed to create wallet: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx,
+		"INSERT INTO wallets (address, balance, opening_balance, label, tags, public_key, notify_email) VALUES ($1, $2, $2, NULLIF($3, ''), $4, NULLIF($5, ''), NULLIF($6, '')) ON CONFLICT (address) DO NOTHING",
+		address, balance, metadata.Label, tagsJSON(metadata.Tags), publicKey, metadata.NotifyEmail)
+	if err != nil {
+		return fmt.Errorf("failed to create wallet: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to create wallet: %w", err)
+	} else if n == 0 {
+		return ErrWalletExists
+	}
+	return nil
+}
+
+// checkSQLiteLabel возвращает ErrLabelExists, если метка присвоена кошельку с другим адресом.
+// Пустая метка означает отсутствие метки и не проверяется.
+func checkSQLiteLabel(ctx context.Context, tx *sql.Tx, label, address string) error {
+	if label == "" {
+		return nil
+	}
+	var taken bool
+	err := tx.QueryRowContext(ctx, "SELECT COUNT(*) > 0 FROM wallets WHERE label = $1 AND address <> $2", label, address).Scan(&taken)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrLabelExists
+	}
+	return nil
+}
+
+// GetWallet возвращает кошелек с балансом и метаданными.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Кошелек.
+//   - Ошибку, оборачивающую ErrWalletNotFound, если кошелек не найден.
+func (r *SQLiteRepository) GetWallet(ctx context.Context, address string) (models.Wallet, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	wallet, err := scanWallet(r.db.QueryRowContext(ctx, "SELECT "+walletColumns+" FROM wallets WHERE address = $1", address))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Wallet{}, fmt.Errorf("failed to get wallet: %w", ErrWalletNotFound)
+	}
+	if err != nil {
+		return models.Wallet{}, fmt.Errorf("failed to get wallet: %w", err)
+	}
+	return wallet, nil
+}
+
+// FindWalletByLabel возвращает кошелек по метке.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - label: Метка кошелька.
+//
+// Возвращает:
+//   - Кошелек.
+//   - Ошибку, оборачивающую ErrWalletNotFound, если кошелька с такой меткой нет.
+func (r *SQLiteRepository) FindWalletByLabel(ctx context.Context, label string) (models.Wallet, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	wallet, err := scanWallet(r.db.QueryRowContext(ctx, "SELECT "+walletColumns+" FROM wallets WHERE label = $1", label))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Wallet{}, fmt.Errorf("failed to find wallet: %w", ErrWalletNotFound)
+	}
+	if err != nil {
+		return models.Wallet{}, fmt.Errorf("failed to find wallet: %w", err)
+	}
+	return wallet, nil
+}
+
+// TopWalletsByBalance возвращает активные кошельки с наибольшим балансом. Балансы хранятся
+// текстом и сравниваются collation decimal; индекса по балансу нет, поэтому запрос
+// просматривает все кошельки, что для размеров SQLite-установок допустимо.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - limit: Максимальное количество кошельков.
+//
+// Возвращает:
+//   - Кошельки по убыванию баланса, при равном балансе - по адресу.
+//   - Ошибку, если не удалось выполнить запрос.
+func (r *SQLiteRepository) TopWalletsByBalance(ctx context.Context, limit int) ([]models.Wallet, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return topWallets(ctx, r.db, "balance COLLATE "+sqliteDecimal, limit)
+}
+
+// UpdateWalletMetadata изменяет метку, теги и адрес для уведомлений кошелька.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//   - patch: Изменяемые поля; поля со значением nil не изменяются.
+//
+// Возвращает:
+//   - Кошелек после изменения.
+//   - Ошибку, оборачивающую ErrWalletNotFound, или ErrLabelExists, если метка занята.
+func (r *SQLiteRepository) UpdateWalletMetadata(ctx context.Context, address string, patch models.WalletMetadataPatch) (models.Wallet, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Wallet{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var label string
+	if patch.Label != nil {
+		label = *patch.Label
+		if err := checkSQLiteLabel(ctx, tx, label, address); err != nil {
+			return models.Wallet{}, fmt.Errorf("failed to update wallet: %w", err)
+		}
+	}
+
+	var email string
+	if patch.NotifyEmail != nil {
+		email = *patch.NotifyEmail
+	}
+	wallet, err := scanWallet(tx.QueryRowContext(ctx, `
+		UPDATE wallets SET
+			label = CASE WHEN $2 THEN NULLIF($3, '') ELSE label END,
+			tags = CASE WHEN $4 THEN $5 ELSE tags END,
+			notify_email = CASE WHEN $6 THEN NULLIF($7, '') ELSE notify_email END
+		WHERE address = $1
+		RETURNING `+walletColumns,
+		address, patch.Label != nil, label, patch.Tags != nil, tagsJSON(patch.Tags), patch.NotifyEmail != nil, email))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Wallet{}, fmt.Errorf("failed to update wallet: %w", ErrWalletNotFound)
+	}
+	if err != nil {
+		return models.Wallet{}, fmt.Errorf("failed to update wallet: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Wallet{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return wallet, nil
+}
+
+// ArchiveWallet архивирует кошелек с нулевым балансом, не участвующий в отложенных переводах.
+// Проверки и изменение выполняются в одной транзакции BEGIN IMMEDIATE.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Архивный кошелек (повторная архивация возвращает его без изменений).
+//   - Ошибку, оборачивающую ErrWalletNotFound; ErrWalletNotEmpty или ErrWalletHasHolds.
+func (r *SQLiteRepository) ArchiveWallet(ctx context.Context, address string) (models.Wallet, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return archiveWallet(ctx, r.db, "", address, sqliteTime(time.Now()))
+}
+
+// RestoreWallet снимает архивацию кошелька.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Кошелек после восстановления.
+//   - Ошибку, оборачивающую ErrWalletNotFound, если кошелька нет.
+func (r *SQLiteRepository) RestoreWallet(ctx context.Context, address string) (models.Wallet, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return restoreWallet(ctx, r.db, address)
+}
+
+// GetNonce возвращает номер последнего подписанного перевода с кошелька.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Номер последнего подписанного перевода (0, если их не было).
+//   - Ошибку, оборачивающую ErrWalletNotFound, если кошелька нет.
+func (r *SQLiteRepository) GetNonce(ctx context.Context, address string) (int64, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return getNonce(ctx, r.db, address)
+}
+
+// GetBalance возвращает баланс кошелька по его адресу.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Баланс кошелька.
+//   - Ошибку, если кошелек не найден или произошла другая ошибка.
+func (r *SQLiteRepository) GetBalance(ctx context.Context, address string) (decimal.Decimal, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var balance decimal.Decimal
+	err := r.db.QueryRowContext(ctx, "SELECT balance FROM wallets WHERE address = $1", address).Scan(&balance)
+	if errors.Is(err, sql.ErrNoRows) {
+		return decimal.Zero, fmt.Errorf("failed to get balance: %w", ErrWalletNotFound)
+	}
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("failed to get balance: %w", err)
+	}
+	return balance, nil
+}
+
+// GetBalanceDetails возвращает баланс кошелька, зарезервированные средства и доступный остаток
+// одним запросом.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Баланс с резервом и доступным остатком.
+//   - Ошибку, оборачивающую ErrWalletNotFound, если кошелек не найден.
+func (r *SQLiteRepository) GetBalanceDetails(ctx context.Context, address string) (models.Balance, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return getBalanceDetails(ctx, r.db, sqliteSum, address)
+}
+
+// GetBalances возвращает балансы нескольких кошельков одним запросом.
+// SQLite не поддерживает массивы, поэтому адреса передаются списком параметров IN (...).
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - addresses: Адреса кошельков.
+//
+// Возвращает:
+//   - Балансы по адресам; несуществующих кошельков в результате нет.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+func (r *SQLiteRepository) GetBalances(ctx context.Context, addresses []string) (map[string]decimal.Decimal, error) {
+	if len(addresses) == 0 {
+		return map[string]decimal.Decimal{}, nil
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	placeholders := make([]string, len(addresses))
+	args := make([]interface{}, len(addresses))
+	for i, address := range addresses {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = address
+	}
+
+	rows, err := r.db.QueryContext(ctx, "SELECT address, balance FROM wallets WHERE address IN ("+strings.Join(placeholders, ", ")+")", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balances: %w", err)
+	}
+	balances, err := scanBalances(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balances: %w", err)
+	}
+	return balances, nil
+}
+
+// Send выполняет перевод средств с одного кошелька на другой.
+// Перевод выполняется в транзакции WithTx (см. transfer), которая начинается как BEGIN IMMEDIATE,
+// поэтому проверки баланса и номера перевода и обновления выполняются под блокировкой базы на запись.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - from: Адрес кошелька отправителя.
+//   - to: Адрес кошелька получателя.
+//   - amount: Сумма перевода.
+//   - memo: Комментарий к переводу (может быть пустым).
+//   - category: Категория перевода (может быть пустой).
+//   - nonce: Номер подписанного перевода; 0 - перевод без подписи.
+//   - isolation: Не используется: транзакции SQLite всегда сериализуемы.
+//
+// Возвращает:
+//   - Идентификатор и время записанной транзакции и баланс отправителя после списания.
+//   - Ошибку, если перевод не удался (например, недостаточно средств);
+//     *NonceError, если nonce не следующий номер отправителя.
+func (r *SQLiteRepository) Send(ctx context.Context, from, to string, amount decimal.Decimal, memo, category string, nonce int64, isolation sql.IsolationLevel) (SendResult, error) {
+	var result SendResult
+	err := r.withTx(ctx, func(tx *sqliteTx) error {
+		var err error
+		result, err = tx.Send(from, to, amount, memo, category, nonce)
+		return err
+	})
+	if err != nil {
+		return SendResult{}, err
+	}
+	return result, nil
+}
+
+// SystemTransfer выполняет операцию с системным счетом (см. systemTransfer) в транзакции
+// BEGIN IMMEDIATE, как Send.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - txType: Тип операции (models.TransactionMint и др.).
+//   - address: Кошелек пользователя.
+//   - amount: Сумма операции.
+//   - memo: Комментарий (может быть пустым).
+//
+// Возвращает:
+//   - Идентификатор и время записанной транзакции и балансы участников после операции.
+//   - ErrTransactionType, ErrSystemAccount или ошибку перевода.
+func (r *SQLiteRepository) SystemTransfer(ctx context.Context, txType, address string, amount decimal.Decimal, memo string) (SendResult, error) {
+	var result SendResult
+	err := r.withTx(ctx, func(tx *sqliteTx) error {
+		var err error
+		result, err = systemTransfer(tx, txType, address, amount, memo, r.minBalance)
+		return err
+	})
+	if err != nil {
+		return SendResult{}, err
+	}
+	return result, nil
+}
+
+// WithTx выполняет fn в одной транзакции: изменения, сделанные через TxRepository, фиксируются,
+// если fn вернула nil, и откатываются, если ошибку. Транзакция начинается как BEGIN IMMEDIATE,
+// поэтому все ее чтения и изменения выполняются под блокировкой базы на запись.
+//
+// Параметры:
+//   - ctx: Контекст; время транзакции дополнительно ограничено DB_QUERY_TIMEOUT.
+//   - fn: Операции транзакции.
+//
+// Возвращает:
+//   - Ошибку fn или базы.
+func (r *SQLiteRepository) WithTx(ctx context.Context, fn func(tx TxRepository) error) error {
+	return r.withTx(ctx, func(tx *sqliteTx) error { return fn(tx) })
+}
+
+// WithSeedLock выполняет fn в транзакции, как WithTx. Отдельная блокировка не нужна:
+// BEGIN IMMEDIATE берет блокировку базы на запись до первого чтения, поэтому такие
+// транзакции и так выполняются по очереди, даже из разных процессов.
+func (r *SQLiteRepository) WithSeedLock(ctx context.Context, fn func(tx TxRepository) error) error {
+	return r.WithTx(ctx, fn)
+}
+
+// withTx выполняет fn в транзакции SQLite.
+func (r *SQLiteRepository) withTx(ctx context.Context, fn func(tx *sqliteTx) error) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(&sqliteTx{ctx: ctx, tx: tx, minBalance: r.minBalance, clock: r.clock}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// sqliteTx - TxRepository транзакции SQLite. Новые балансы вычисляются в Go
+// (арифметика SQLite над текстом перешла бы к REAL) и записываются целиком.
+type sqliteTx struct {
+	ctx        context.Context
+	tx         *sql.Tx
+	minBalance decimal.Decimal // Неснижаемый остаток кошелька отправителя
+	clock      Clock           // Источник времени записанных транзакций
+}
+
+// GetWalletForUpdate читает состояние кошелька; блокировка базы на запись уже взята BEGIN IMMEDIATE.
+func (t *sqliteTx) GetWalletForUpdate(address string) (WalletState, error) {
+	var state WalletState
+	err := t.tx.QueryRowContext(t.ctx,
+		"SELECT balance, "+reservedColumn(sqliteSum)+", nonce, archived_at IS NOT NULL FROM wallets WHERE address = $1", address).
+		Scan(&state.Balance, &state.Reserved, &state.Nonce, &state.Archived)
+	if errors.Is(err, sql.ErrNoRows) {
+		return WalletState{}, ErrWalletNotFound
+	}
+	if err != nil {
+		return WalletState{}, err
+	}
+	return state, nil
+}
+
+// AddBalance изменяет баланс кошелька; баланс не должен выйти за пределы NUMERIC(38, 8),
+// как в PostgreSQL.
+func (t *sqliteTx) AddBalance(address string, delta decimal.Decimal) (decimal.Decimal, error) {
+	var balance decimal.Decimal
+	err := t.tx.QueryRowContext(t.ctx, "SELECT balance FROM wallets WHERE address = $1 AND archived_at IS NULL", address).Scan(&balance)
+	if errors.Is(err, sql.ErrNoRows) {
+		return decimal.Decimal{}, missingWalletError(t.ctx, t.tx, address)
+	}
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	if balanceOverflows(balance, delta) {
+		return decimal.Decimal{}, ErrBalanceOverflow
+	}
+
+	balance = balance.Add(delta)
+	if _, err := t.tx.ExecContext(t.ctx, "UPDATE wallets SET balance = $1 WHERE address = $2", balance, address); err != nil {
+		return decimal.Decimal{}, err
+	}
+	return balance, nil
+}
+
+// SetNonce сохраняет номер последнего подписанного перевода с кошелька.
+func (t *sqliteTx) SetNonce(address string, nonce int64) error {
+	_, err := t.tx.ExecContext(t.ctx, "UPDATE wallets SET nonce = MAX(nonce, $2) WHERE address = $1", address, nonce)
+	return err
+}
+
+// RecordTransaction записывает транзакцию.
+func (t *sqliteTx) RecordTransaction(txType, from, to string, amount decimal.Decimal, memo, category string) (models.Transaction, error) {
+	return recordTransaction(t.ctx, t.tx, txType, from, to, amount, memo, category, sqliteTime(t.clock.Now()))
+}
+
+// EnqueueNotification ставит в очередь уведомление получателю транзакции.
+func (t *sqliteTx) EnqueueNotification(transaction models.Transaction) error {
+	return enqueueNotification(t.ctx, t.tx, transaction, sqliteTime(transaction.CreatedAt))
+}
+
+// CreateWallet создает кошелек в транзакции.
+func (t *sqliteTx) CreateWallet(address string, balance decimal.Decimal, metadata models.WalletMetadata, publicKey string) error {
+	return createSQLiteWallet(t.ctx, t.tx, address, balance, metadata, publicKey)
+}
+
+// RetireWallet архивирует кошелек и снимает с него метку.
+func (t *sqliteTx) RetireWallet(address string) (models.Wallet, error) {
+	return retireWallet(t.ctx, t.tx, "", address, sqliteTime(time.Now()))
+}
+
+// HasWallets проверяет, есть ли кошельки, кроме системных счетов.
+func (t *sqliteTx) HasWallets() (bool, error) {
+	return hasWallets(t.ctx, t.tx)
+}
+
+// Send выполняет перевод в транзакции (см. transfer).
+func (t *sqliteTx) Send(from, to string, amount decimal.Decimal, memo, category string, nonce int64) (SendResult, error) {
+	return transfer(t, from, to, amount, memo, category, nonce, t.minBalance)
+}
+
+// UpdateApprovalStatus меняет статус отложенного перевода в транзакции.
+func (t *sqliteTx) UpdateApprovalStatus(id int64, from, to, reason string) (models.Approval, error) {
+	return updateApprovalStatus(t.ctx, t.tx, id, from, to, reason, sqliteTime(time.Now()))
+}
+
+// ImportTransactions сохраняет исторические транзакции из другой системы
+// с их исходным временем, не изменяя балансы. Записи с уже существующим
+// external_id пропускаются; все записи вставляются в одной транзакции.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - transactions: Транзакции для импорта; ExternalID и CreatedAt обязательны.
+//
+// Возвращает:
+//   - Количество добавленных записей.
+//   - Ошибку, если импорт не удался (в этом случае ничего не добавляется).
+func (r *SQLiteRepository) ImportTransactions(ctx context.Context, transactions []models.Transaction) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	imported, err := importTransactions(ctx, tx, transactions, sqliteTimeArg)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit import: %w", err)
+	}
+	return imported, nil
+}
+
+// GetLastTransactions возвращает список последних N транзакций со временем в UTC.
+// Транзакции с одинаковым временем упорядочиваются по id.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - count: Количество транзакций.
+//   - filter: Условия выборки.
+//
+// Возвращает:
+//   - Список транзакций.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+func (r *SQLiteRepository) GetLastTransactions(ctx context.Context, count int, filter TransactionFilter) ([]models.Transaction, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query, args := lastTransactionsQuery(filter, sqliteTimeArg, count)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transactions: %w", err)
+	}
+	defer rows.Close()
+
+	return scanTransactions(rows, make([]models.Transaction, 0, transactionsCapacity(count)))
+}
+
+// CountTransactions возвращает количество транзакций, удовлетворяющих фильтру.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - filter: Условия выборки.
+//
+// Возвращает:
+//   - Количество транзакций.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+func (r *SQLiteRepository) CountTransactions(ctx context.Context, filter TransactionFilter) (int64, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query, args := countTransactionsQuery(filter, sqliteTimeArg)
+	var count int64
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count transactions: %w", err)
+	}
+	return count, nil
+}
+
+// GetWalletTransactions возвращает последние count транзакций каждого из кошельков одним
+// запросом (см. walletTransactionsQuery).
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - addresses: Адреса кошельков.
+//   - count: Максимальное количество транзакций одного кошелька.
+//
+// Возвращает:
+//   - Транзакции по адресам, начиная с самой новой; кошельков без транзакций в результате нет.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+func (r *SQLiteRepository) GetWalletTransactions(ctx context.Context, addresses []string, count int) (map[string][]models.Transaction, error) {
+	if len(addresses) == 0 {
+		return map[string][]models.Transaction{}, nil
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query, args := walletTransactionsQuery(addresses, count)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wallet transactions: %w", err)
+	}
+	return scanWalletTransactions(rows)
+}
+
+// EstimateTransactions возвращает точное количество транзакций: у SQLite нет статистики
+// количества строк, которая обновлялась бы без ANALYZE.
+func (r *SQLiteRepository) EstimateTransactions(ctx context.Context) (int64, error) {
+	return r.CountTransactions(ctx, TransactionFilter{})
+}
+
+// PurgeTransactions переносит в таблицу transactions_archive (при archive = false - удаляет)
+// не более limit самых старых транзакций, выполненных раньше момента before. База SQLite
+// принадлежит одному процессу, поэтому блокировка между экземплярами не нужна.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - before: Транзакции, выполненные раньше этого момента, очищаются.
+//   - archive: true - перенести в архив, false - удалить.
+//   - limit: Наибольшее количество транзакций в пачке.
+//
+// Возвращает:
+//   - Количество перенесенных (удаленных) транзакций; меньше limit - старых транзакций не осталось.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+func (r *SQLiteRepository) PurgeTransactions(ctx context.Context, before time.Time, archive bool, limit int) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return purgeTransactions(ctx, r.db, "", "", sqliteTime(before), archive, limit)
+}
+
+// GetSenderStats возвращает количество и сумму переводов с кошелька начиная с момента since.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька отправителя.
+//   - since: Начало периода.
+//
+// Возвращает:
+//   - Сводку переводов (нулевую, если переводов не было).
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+func (r *SQLiteRepository) GetSenderStats(ctx context.Context, address string, since time.Time) (SenderStats, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return getSenderStats(ctx, r.db, sqliteSum, address, sqliteTime(since))
+}
+
+// GetCategoryVolumes возвращает объемы переводов по дням и категориям за период [from, to).
+// Время хранится текстом в UTC, поэтому день - первые 10 символов времени транзакции.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - from: Начало периода.
+//   - to: Конец периода (не включается).
+//
+// Возвращает:
+//   - Объемы по дням (UTC) и категориям.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+func (r *SQLiteRepository) GetCategoryVolumes(ctx context.Context, from, to time.Time) ([]CategoryVolume, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return getCategoryVolumes(ctx, r.db, "substr(timestamp, 1, 10)", sqliteSum, sqliteTime(from), sqliteTime(to))
+}
+
+// RecordRiskEvent сохраняет срабатывание правила проверки переводов в таблицу risk_events.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - event: Событие; ID и CreatedAt назначаются базой.
+//
+// Возвращает:
+//   - Ошибку, если событие сохранить не удалось.
+func (r *SQLiteRepository) RecordRiskEvent(ctx context.Context, event models.RiskEvent) error {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return recordRiskEvent(ctx, r.db, event)
+}
+
+// GetRiskEvents возвращает последние count срабатываний правил, начиная с самого нового.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - count: Количество событий.
+//
+// Возвращает:
+//   - Список событий.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+func (r *SQLiteRepository) GetRiskEvents(ctx context.Context, count int) ([]models.RiskEvent, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return getRiskEvents(ctx, r.db, count)
+}
+
+// AnonymizeWallet удаляет персональные данные кошелька: метку, теги, адрес для уведомлений,
+// комментарии его транзакций и отложенных переводов и его данные в уведомлениях.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//   - dryRun: true - только подсчитать записи с персональными данными.
+//
+// Возвращает:
+//   - Количество измененных (или подлежащих изменению) записей.
+//   - Ошибку, оборачивающую ErrWalletNotFound, если кошелька нет.
+func (r *SQLiteRepository) AnonymizeWallet(ctx context.Context, address string, dryRun bool) (models.AnonymizeReport, error) {
+	return anonymizeWallet(ctx, r.db, r.queryTimeout, address, dryRun)
+}
+
+// RecordAuditEvent сохраняет запись журнала аудита в таблицу audit_log.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - event: Запись; ID и CreatedAt назначаются базой.
+//
+// Возвращает:
+//   - Ошибку, если запись сохранить не удалось.
+func (r *SQLiteRepository) RecordAuditEvent(ctx context.Context, event models.AuditEvent) error {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return recordAuditEvent(ctx, r.db, event)
+}
+
+// GetAuditEvents возвращает последние count записей журнала аудита, начиная с самой новой.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - count: Количество записей.
+//
+// Возвращает:
+//   - Список записей.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+func (r *SQLiteRepository) GetAuditEvents(ctx context.Context, count int) ([]models.AuditEvent, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return getAuditEvents(ctx, r.db, count)
+}
+
+// ReserveIdempotencyKey занимает ключ идемпотентности для выполняющегося запроса.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - key: Ключ из заголовка Idempotency-Key.
+//   - fingerprint: Отпечаток запроса.
+//   - expiredBefore: Ключи, занятые раньше этого момента, удаляются.
+//
+// Возвращает:
+//   - Сохраненный ответ и false, если ключ уже занят.
+//   - true, если ключ занят этим вызовом.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+func (r *SQLiteRepository) ReserveIdempotencyKey(ctx context.Context, key, fingerprint string, expiredBefore time.Time) (IdempotentResponse, bool, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return reserveIdempotencyKey(ctx, r.db, key, fingerprint, sqliteTime(time.Now()), sqliteTime(expiredBefore))
+}
+
+// CompleteIdempotencyKey сохраняет ответ на запрос с занятым ключом идемпотентности.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - key: Ключ, занятый ReserveIdempotencyKey.
+//   - response: Ответ (поле Fingerprint не используется).
+//
+// Возвращает:
+//   - Ошибку, если ответ сохранить не удалось.
+func (r *SQLiteRepository) CompleteIdempotencyKey(ctx context.Context, key string, response IdempotentResponse) error {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return completeIdempotencyKey(ctx, r.db, key, response)
+}
+
+// ReleaseIdempotencyKey освобождает ключ запроса, ответ на который не сохраняется.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - key: Ключ, занятый ReserveIdempotencyKey.
+//
+// Возвращает:
+//   - Ошибку, если ключ освободить не удалось.
+func (r *SQLiteRepository) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return releaseIdempotencyKey(ctx, r.db, key)
+}
+
+// CreateApproval сохраняет перевод, ожидающий подтверждения администратором, в таблицу pending_approvals.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - approval: Перевод; ID, Status и CreatedAt назначаются хранилищем.
+//
+// Возвращает:
+//   - Сохраненный перевод со статусом models.ApprovalAwaitingReview.
+//   - Ошибку, если перевод сохранить не удалось.
+func (r *SQLiteRepository) CreateApproval(ctx context.Context, approval models.Approval) (models.Approval, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return createApproval(ctx, r.db, approval, sqliteTime(time.Now()))
+}
+
+// GetApproval возвращает отложенный перевод по ID.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - id: Идентификатор отложенного перевода.
+//
+// Возвращает:
+//   - Отложенный перевод.
+//   - Ошибку, оборачивающую ErrApprovalNotFound, если перевода нет.
+func (r *SQLiteRepository) GetApproval(ctx context.Context, id int64) (models.Approval, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return getApproval(ctx, r.db, id)
+}
+
+// GetApprovals возвращает последние count отложенных переводов, начиная с самого нового.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - status: Статус переводов (пустая строка - любой).
+//   - count: Количество переводов.
+//
+// Возвращает:
+//   - Список отложенных переводов.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+func (r *SQLiteRepository) GetApprovals(ctx context.Context, status string, count int) ([]models.Approval, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return getApprovals(ctx, r.db, status, count)
+}
+
+// UpdateApprovalStatus переводит отложенный перевод из статуса from в статус to одним
+// условным UPDATE: из параллельных решений по одному переводу выполняется одно.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - id: Идентификатор отложенного перевода.
+//   - from: Ожидаемый текущий статус.
+//   - to: Новый статус.
+//   - reason: Причина (для отклонения или неудачного выполнения).
+//
+// Возвращает:
+//   - Отложенный перевод с новым статусом.
+//   - Ошибку, оборачивающую ErrApprovalNotFound, если перевода нет, или ErrApprovalStatus,
+//     если его статус не from.
+func (r *SQLiteRepository) UpdateApprovalStatus(ctx context.Context, id int64, from, to, reason string) (models.Approval, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return updateApprovalStatus(ctx, r.db, id, from, to, reason, sqliteTime(time.Now()))
+}
+
+// ExpireApprovals переводит в статус models.ApprovalExpired переводы, ожидающие решения
+// с момента раньше before.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - before: Граница времени постановки в очередь.
+//
+// Возвращает:
+//   - Количество истекших переводов.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+func (r *SQLiteRepository) ExpireApprovals(ctx context.Context, before time.Time) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return expireApprovals(ctx, r.db, sqliteTime(before), sqliteTime(time.Now()))
+}
+
+// ClaimNotifications выбирает уведомления, ожидающие доставки, и откладывает их следующую
+// попытку до now + lease.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - now: Текущее время; выбираются уведомления с попыткой, назначенной не позже него.
+//   - lease: Время, через которое уведомление выбирается снова, если результат попытки
+//     не записан.
+//   - limit: Наибольшее количество уведомлений.
+//
+// Возвращает:
+//   - Выбранные уведомления в порядке ID.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+func (r *SQLiteRepository) ClaimNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Notification, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return claimNotifications(ctx, r.db, "", sqliteTime(now), sqliteTime(now.Add(lease)), limit)
+}
+
+// CompleteNotification записывает результат попытки доставки уведомления.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - id: Идентификатор уведомления.
+//   - status: models.NotificationSent, models.NotificationFailed или models.NotificationPending
+//     (повторная попытка в момент at).
+//   - lastError: Ошибка попытки (пустая строка при доставке).
+//   - at: Время завершения или следующей попытки.
+//
+// Возвращает:
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+func (r *SQLiteRepository) CompleteNotification(ctx context.Context, id int64, status, lastError string, at time.Time) error {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return completeNotification(ctx, r.db, id, status, lastError, sqliteTime(at))
+}
+
+// GetNotifications возвращает последние count уведомлений, начиная с самого нового.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - status: Статус уведомлений (пустая строка - любой).
+//   - count: Количество уведомлений.
+//
+// Возвращает:
+//   - Список уведомлений.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+func (r *SQLiteRepository) GetNotifications(ctx context.Context, status string, count int) ([]models.Notification, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return getNotifications(ctx, r.db, status, count)
+}
+
+// Reconcile проверяет инварианты хранилища.
+//
+// Возвращает:
+//   - Сводку проверки; нарушения сообщает ReconcileReport.Drift.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+func (r *SQLiteRepository) Reconcile(ctx context.Context) (ReconcileReport, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return reconcile(ctx, r.db, sqliteSum)
+}
+
+// Ping проверяет подключение к базе данных.
+//
+// Параметры:
+//   - ctx: Контекст для выполнения запроса.
+//
+// Возвращает:
+//   - Ошибку, если подключение не удалось.
+func (r *SQLiteRepository) Ping(ctx context.Context) error {
+	if err := r.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+	return nil
+}
+
+// Stats возвращает статистику пула подключений к файлу базы.
+//
+// Пример использования:
+//
+//	metrics.RegisterDBPool("primary", repo.Stats)
+func (r *SQLiteRepository) Stats() sql.DBStats {
+	return r.db.Stats()
+}
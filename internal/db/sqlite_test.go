@@ -0,0 +1,94 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"payment-system/internal/db"
+	"payment-system/internal/db/dbtest"
+	"payment-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestSQLiteRepository(t *testing.T) {
+	dbtest.RunContract(t, func(t *testing.T) db.Repository {
+		return db.NewSQLiteRepository(filepath.Join(t.TempDir(), "payment-system.db"))
+	})
+}
+
+func BenchmarkSQLiteSend(b *testing.B) {
+	for _, wallets := range []int{2, 64} {
+		b.Run(fmt.Sprintf("wallets=%d", wallets), func(b *testing.B) {
+			benchmarkSends(b, db.NewSQLiteRepository(filepath.Join(b.TempDir(), "payment-system.db")), wallets)
+		})
+	}
+}
+
+func BenchmarkSQLiteTransactionFeed(b *testing.B) {
+	benchmarkTransactionFeed(b, db.NewSQLiteRepository(filepath.Join(b.TempDir(), "payment-system.db")))
+}
+
+// TestSQLiteConcurrentSendsAcrossConnections проверяет BEGIN IMMEDIATE: два репозитория
+// с общим файлом (как два процесса) переводят по кругу одновременно, и ни один перевод
+// не читает устаревший баланс - сумма балансов сохраняется, ни один баланс не отрицателен.
+func TestSQLiteConcurrentSendsAcrossConnections(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "payment-system.db")
+	repos := []*db.SQLiteRepository{db.NewSQLiteRepository(path), db.NewSQLiteRepository(path)}
+
+	const (
+		walletCount = 3
+		initial     = 50
+		workers     = 6
+		perWorker   = 20
+	)
+	wallets := make([]string, 0, walletCount)
+	for i := 0; i < walletCount; i++ {
+		address, err := db.GenerateAddress()
+		if err != nil {
+			t.Fatalf("GenerateAddress: %v", err)
+		}
+		if err := repos[0].CreateWallet(ctx, address, decimal.NewFromInt(initial), models.WalletMetadata{}, ""); err != nil {
+			t.Fatalf("CreateWallet: %v", err)
+		}
+		wallets = append(wallets, address)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			repo := repos[w%len(repos)]
+			for i := 0; i < perWorker; i++ {
+				from, to := wallets[(w+i)%walletCount], wallets[(w+i+1)%walletCount]
+				_, err := repo.Send(ctx, from, to, decimal.NewFromInt(9), "", "", 0, sql.LevelDefault)
+				if err != nil && !errors.Is(err, db.ErrInsufficientFunds) {
+					t.Errorf("Send: %v", err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	total := decimal.Zero
+	for _, address := range wallets {
+		balance, err := repos[1].GetBalance(ctx, address)
+		if err != nil {
+			t.Fatalf("GetBalance: %v", err)
+		}
+		if balance.IsNegative() {
+			t.Errorf("balance of %s = %s, want non-negative", address, balance)
+		}
+		total = total.Add(balance)
+	}
+	if want := decimal.NewFromInt(initial * walletCount); !total.Equal(want) {
+		t.Errorf("total balance = %s, want %s", total, want)
+	}
+}
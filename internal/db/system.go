@@ -0,0 +1,195 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"payment-system/internal/errs"
+	"payment-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// Системные счета - кошельки с зарезервированными адресами, которые создаются при инициализации
+// хранилища. Они участвуют только в операциях SystemTransfer соответствующего типа; обычный
+// перевод (Send) с системного счета или на него отклоняется с ErrSystemAccount.
+const (
+	// SystemMint - счет эмиссии: источник выпущенных (TransactionMint) и приемник изъятых
+	// (TransactionBurn) средств. Его баланс не меняется и всегда равен нулю: выпуск и изъятие
+	// меняют общую сумму балансов, а не переносят средства между кошельками.
+	SystemMint = "mint"
+
+	// SystemFees - счет комиссий (TransactionFee).
+	SystemFees = "fees"
+
+	// SystemEscrow - счет депонирования: удержанные средства (TransactionEscrowHold)
+	// хранятся на нем до возврата (TransactionEscrowRelease).
+	SystemEscrow = "escrow"
+)
+
+// systemAccountNumbers - номера системных счетов, из которых составляются их адреса.
+var systemAccountNumbers = map[string]int{
+	SystemMint:   1,
+	SystemFees:   2,
+	SystemEscrow: 3,
+}
+
+// systemAccountTag - тег, которым помечены системные счета; значение - имя счета (SystemMint и др.).
+const systemAccountTag = "system"
+
+// ErrSystemAccount возвращается, если системный счет указан там, где допустим только кошелек
+// пользователя: в обычном переводе, как участник SystemTransfer, при архивации или замене адреса.
+var ErrSystemAccount = errs.ErrSystemAccount
+
+// ErrTransactionType возвращается SystemTransfer для типа, который не является операцией
+// с системным счетом (в том числе для TransactionTransfer).
+var ErrTransactionType = errors.New("unsupported transaction type")
+
+// SystemAccount возвращает адрес системного счета: номер счета в hex, дополненный нулями слева
+// до длины адреса (ADDRESS_BYTES). Случайный адрес совпадает с ним с пренебрежимо малой
+// вероятностью, а занять его другим кошельком нельзя: счет создается при инициализации.
+//
+// Параметры:
+//   - name: Имя счета (SystemMint, SystemFees или SystemEscrow).
+//
+// Возвращает:
+//   - Адрес счета; пустую строку для неизвестного имени.
+//
+// Пример использования:
+//
+//	fees := db.SystemAccount(db.SystemFees)
+func SystemAccount(name string) string {
+	n, ok := systemAccountNumbers[name]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%0*x", 2*AddressBytes(), n)
+}
+
+// IsSystemAccount сообщает, является ли адрес адресом системного счета.
+func IsSystemAccount(address string) bool {
+	for name := range systemAccountNumbers {
+		if address == SystemAccount(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// systemAddresses возвращает адреса всех системных счетов.
+func systemAddresses() []string {
+	addresses := make([]string, 0, len(systemAccountNumbers))
+	for name := range systemAccountNumbers {
+		addresses = append(addresses, SystemAccount(name))
+	}
+	return addresses
+}
+
+// notSystemAccount возвращает условие SQL "address NOT IN (...)", исключающее системные счета,
+// и его аргументы. Параметры нумеруются с $first, чтобы условие можно было добавить к запросу
+// с другими параметрами. Синтаксис совместим с PostgreSQL и SQLite.
+func notSystemAccount(first int) (string, []interface{}) {
+	system := systemAddresses()
+	placeholders := make([]string, len(system))
+	args := make([]interface{}, len(system))
+	for i, address := range system {
+		placeholders[i], args[i] = fmt.Sprintf("$%d", first+i), address
+	}
+	return "address NOT IN (" + strings.Join(placeholders, ", ") + ")", args
+}
+
+// systemOperation - системный счет операции SystemTransfer и направление движения средств.
+type systemOperation struct {
+	account string // Имя системного счета
+	credit  bool   // true - со счета на кошелек, false - с кошелька на счет
+}
+
+// systemOperations - операции SystemTransfer по типам транзакций.
+var systemOperations = map[string]systemOperation{
+	models.TransactionMint:          {account: SystemMint, credit: true},
+	models.TransactionBurn:          {account: SystemMint, credit: false},
+	models.TransactionFee:           {account: SystemFees, credit: false},
+	models.TransactionEscrowHold:    {account: SystemEscrow, credit: false},
+	models.TransactionEscrowRelease: {account: SystemEscrow, credit: true},
+}
+
+// systemTransfer выполняет операцию с системным счетом в транзакции tx: системный счет
+// и направление определяются типом, списание проверяется, как в transfer, а баланс счета
+// эмиссии не меняется. Общая часть SystemTransfer всех реализаций.
+//
+// Параметры:
+//   - tx: Транзакция WithTx.
+//   - txType: Тип операции (models.TransactionMint и др.).
+//   - address: Кошелек пользователя - получатель или отправитель.
+//   - amount: Сумма операции.
+//   - memo: Комментарий (может быть пустым).
+//   - minBalance: Неснижаемый остаток кошелька пользователя.
+//
+// Возвращает:
+//   - Результат, как у transfer; баланс счета эмиссии в нем нулевой.
+//   - ErrTransactionType для неподдерживаемого типа, ErrSystemAccount, если address - системный
+//     счет, или ошибку перевода.
+func systemTransfer(tx TxRepository, txType, address string, amount decimal.Decimal, memo string, minBalance decimal.Decimal) (SendResult, error) {
+	op, ok := systemOperations[txType]
+	if !ok {
+		return SendResult{}, fmt.Errorf("%w: %q", ErrTransactionType, txType)
+	}
+	if IsSystemAccount(address) {
+		return SendResult{}, fmt.Errorf("wallet %s: %w", address, ErrSystemAccount)
+	}
+	account := SystemAccount(op.account)
+	from, to := address, account
+	if op.credit {
+		from, to = account, address
+	}
+
+	var result SendResult
+	if from != SystemAccount(SystemMint) {
+		sender, err := tx.GetWalletForUpdate(from)
+		if err != nil {
+			return SendResult{}, fmt.Errorf("failed to get sender balance: %w", err)
+		}
+		if sender.Archived {
+			return SendResult{}, fmt.Errorf("sender %s: %w", from, ErrWalletArchived)
+		}
+		// Неснижаемый остаток относится к кошелькам пользователей, а не к счету депонирования
+		floor := minBalance
+		if from == account {
+			floor = decimal.Zero
+		}
+		if err := CheckAvailable(newBalance(sender.Balance, sender.Reserved).Available, amount, floor); err != nil {
+			return SendResult{}, err
+		}
+		if result.SenderBalance, err = tx.AddBalance(from, amount.Neg()); err != nil {
+			return SendResult{}, fmt.Errorf("failed to update sender balance: %w", err)
+		}
+	}
+	if to != SystemAccount(SystemMint) {
+		var err error
+		if result.ReceiverBalance, err = tx.AddBalance(to, amount); err != nil {
+			return SendResult{}, fmt.Errorf("failed to update receiver balance: %w", err)
+		}
+	}
+
+	record, err := tx.RecordTransaction(txType, from, to, amount, memo, "")
+	if err != nil {
+		return SendResult{}, err
+	}
+	result.TransactionID, result.CreatedAt = record.ID, record.CreatedAt
+	return result, nil
+}
+
+// createSystemAccounts создает системные счета с нулевым балансом, если их еще нет.
+// insert - запрос вставки кошелька конкретной базы с параметрами адреса, баланса и тегов,
+// который пропускает уже существующий адрес.
+func createSystemAccounts(db *sql.DB, insert string) error {
+	for name := range systemAccountNumbers {
+		tags := tagsJSON(map[string]string{systemAccountTag: name})
+		if _, err := db.Exec(insert, SystemAccount(name), decimal.Zero, tags); err != nil {
+			return fmt.Errorf("failed to create system account %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"payment-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// WalletState - состояние кошелька, прочитанное в транзакции WithTx.
+type WalletState struct {
+	Balance  decimal.Decimal // Баланс кошелька
+	Reserved decimal.Decimal // Сумма переводов, ожидающих подтверждения (см. GetBalanceDetails)
+	Nonce    int64           // Номер последнего подписанного перевода с кошелька
+	Archived bool            // Кошелек архивирован
+}
+
+// TxRepository - операции, выполняемые в рамках одной транзакции Repository.WithTx.
+// Изменения видны последующим операциям той же транзакции и фиксируются вместе.
+// Методы не проверяют бизнес-правила перевода (достаточность средств, номер перевода):
+// это делает вызывающий код, как transfer.
+type TxRepository interface {
+	// GetWalletForUpdate читает состояние кошелька и блокирует его до конца транзакции,
+	// чтобы параллельные транзакции не изменили баланс между проверкой и изменением.
+	// Возвращает ErrWalletNotFound, если кошелька нет.
+	GetWalletForUpdate(address string) (WalletState, error)
+
+	// AddBalance изменяет баланс кошелька на delta (отрицательная - списание) и возвращает
+	// новый баланс. Возвращает ErrWalletNotFound, если кошелька нет, ErrWalletArchived,
+	// если он архивирован, и ErrBalanceOverflow, если баланс выйдет за NUMERIC(38, 8).
+	AddBalance(address string, delta decimal.Decimal) (decimal.Decimal, error)
+
+	// SetNonce сохраняет номер последнего подписанного перевода с кошелька; меньший номер,
+	// чем сохраненный, не меняет его.
+	SetNonce(address string, nonce int64) error
+
+	// RecordTransaction записывает транзакцию типа txType (models.TransactionTransfer и др.)
+	// и возвращает ее с идентификатором и временем.
+	RecordTransaction(txType, from, to string, amount decimal.Decimal, memo, category string) (models.Transaction, error)
+
+	// EnqueueNotification ставит в очередь уведомление получателю записанной транзакции
+	// о поступлении средств, если у него задан адрес для уведомлений (WalletMetadata.NotifyEmail);
+	// иначе ничего не делает. Уведомление фиксируется вместе с переводом и доставляется
+	// после фиксации (см. Repository.ClaimNotifications).
+	EnqueueNotification(transaction models.Transaction) error
+
+	// CreateWallet создает кошелек, как Repository.CreateWallet.
+	CreateWallet(address string, balance decimal.Decimal, metadata models.WalletMetadata, publicKey string) error
+
+	// RetireWallet архивирует кошелек с нулевым балансом и снимает с него метку, чтобы ее
+	// можно было присвоить другому кошельку той же транзакции. Возвращает кошелек до изменения
+	// (с меткой и тегами). Возвращает ErrWalletNotFound, ErrWalletArchived, если кошелек уже
+	// архивирован, ErrWalletNotEmpty и ErrWalletHasHolds, как Repository.ArchiveWallet.
+	RetireWallet(address string) (models.Wallet, error)
+
+	// Send выполняет перевод, как Repository.Send, с проверкой баланса и номера подписанного
+	// перевода и блокировками DB_LOCK_STRATEGY.
+	Send(from, to string, amount decimal.Decimal, memo, category string, nonce int64) (SendResult, error)
+
+	// UpdateApprovalStatus переводит отложенный перевод из статуса from в статус to,
+	// как Repository.UpdateApprovalStatus.
+	UpdateApprovalStatus(id int64, from, to, reason string) (models.Approval, error)
+
+	// HasWallets сообщает, есть ли кошельки, кроме системных счетов, как Repository.HasWallets.
+	// Атомарна с созданием кошельков только в транзакции Repository.WithSeedLock.
+	HasWallets() (bool, error)
+}
+
+// transfer выполняет перевод в транзакции tx: проверяет отправителя, номер подписанного
+// перевода и доступный остаток, изменяет балансы и записывает транзакцию. Общая часть
+// Send всех реализаций; ошибка любой операции откатывает весь перевод. Получателю, подписанному
+// на уведомления, в той же транзакции ставится в очередь уведомление о поступлении средств.
+//
+// Параметры:
+//   - tx: Транзакция WithTx.
+//   - from, to: Адреса отправителя и получателя.
+//   - amount: Сумма перевода.
+//   - memo: Комментарий к переводу (может быть пустым).
+//   - category: Категория перевода (может быть пустой).
+//   - nonce: Номер подписанного перевода; 0 - перевод без подписи.
+//   - minBalance: Неснижаемый остаток кошелька отправителя.
+//
+// Возвращает:
+//   - Идентификатор и время записанной транзакции и балансы отправителя и получателя после
+//     перевода, прочитанные в той же транзакции (UPDATE ... RETURNING в PostgreSQL).
+//   - Ошибку, если перевод не удался; *NonceError, если nonce не следующий номер отправителя;
+//     ErrSystemAccount, если участник - системный счет (см. systemTransfer); ErrSelfTransfer,
+//     если отправитель и получатель совпадают.
+func transfer(tx TxRepository, from, to string, amount decimal.Decimal, memo, category string, nonce int64, minBalance decimal.Decimal) (SendResult, error) {
+	for _, address := range []string{from, to} {
+		if IsSystemAccount(address) {
+			return SendResult{}, fmt.Errorf("wallet %s: %w", address, ErrSystemAccount)
+		}
+	}
+	if from == to {
+		return SendResult{}, fmt.Errorf("wallet %s: %w", from, ErrSelfTransfer)
+	}
+
+	// Проверка номера подписанного перевода и доступного остатка отправителя
+	sender, err := tx.GetWalletForUpdate(from)
+	if err != nil {
+		return SendResult{}, fmt.Errorf("failed to get sender balance: %w", err)
+	}
+	if sender.Archived {
+		return SendResult{}, fmt.Errorf("sender %s: %w", from, ErrWalletArchived)
+	}
+	if err := checkNonce(sender.Nonce, nonce); err != nil {
+		return SendResult{}, err
+	}
+	if err := CheckAvailable(newBalance(sender.Balance, sender.Reserved).Available, amount, minBalance); err != nil {
+		return SendResult{}, err
+	}
+
+	// Обновление баланса и номера перевода отправителя; перевод без подписи (nonce = 0)
+	// номер не меняет
+	var result SendResult
+	if result.SenderBalance, err = tx.AddBalance(from, amount.Neg()); err != nil {
+		return SendResult{}, fmt.Errorf("failed to update sender balance: %w", err)
+	}
+	if nonce != 0 {
+		if err := tx.SetNonce(from, nonce); err != nil {
+			return SendResult{}, fmt.Errorf("failed to update sender nonce: %w", err)
+		}
+	}
+
+	// Обновление баланса получателя; отсутствие или архивация получателя откатывает перевод,
+	// иначе списанные средства были бы потеряны
+	if result.ReceiverBalance, err = tx.AddBalance(to, amount); err != nil {
+		return SendResult{}, fmt.Errorf("failed to update receiver balance: %w", err)
+	}
+
+	record, err := tx.RecordTransaction(models.TransactionTransfer, from, to, amount, memo, category)
+	if err != nil {
+		return SendResult{}, err
+	}
+	if err := tx.EnqueueNotification(record); err != nil {
+		return SendResult{}, err
+	}
+	result.TransactionID, result.CreatedAt = record.ID, record.CreatedAt
+	return result, nil
+}
+
+// Ниже - запросы, общие для транзакций PostgreSQL и SQLite.
+
+// recordTransaction записывает транзакцию типа txType в рамках транзакции tx. Время транзакции
+// now задается явно (Clock репозитория), а не значением по умолчанию столбца timestamp.
+func recordTransaction(ctx context.Context, tx *sql.Tx, txType, from, to string, amount decimal.Decimal, memo, category string, now interface{}) (models.Transaction, error) {
+	transaction := models.Transaction{From: from, To: to, Amount: amount, Memo: memo, Category: category, Type: txType}
+	err := tx.QueryRowContext(ctx,
+		"INSERT INTO transactions (from_address, to_address, amount, memo, category, type, timestamp) VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7) RETURNING id, timestamp",
+		from, to, amount, memo, category, txType, now).Scan(&transaction.ID, &transaction.CreatedAt)
+	if err != nil {
+		return models.Transaction{}, fmt.Errorf("failed to record transaction: %w", err)
+	}
+	transaction.CreatedAt = transaction.CreatedAt.UTC()
+	return transaction, nil
+}
+
+// missingWalletError различает причины, по которым UPDATE активного кошелька не затронул строк:
+// ErrWalletArchived, если кошелек архивирован, иначе ErrWalletNotFound.
+func missingWalletError(ctx context.Context, tx *sql.Tx, address string) error {
+	var archived bool
+	err := tx.QueryRowContext(ctx, "SELECT archived_at IS NOT NULL FROM wallets WHERE address = $1", address).Scan(&archived)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrWalletNotFound
+	case err != nil:
+		return err
+	case archived:
+		return fmt.Errorf("wallet %s: %w", address, ErrWalletArchived)
+	default:
+		return ErrWalletNotFound
+	}
+}
@@ -0,0 +1,191 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"payment-system/internal/models"
+)
+
+// walletColumns - столбцы кошелька в порядке, который ожидает scanWallet.
+// Синтаксис совместим с PostgreSQL и SQLite; отсутствующие метка, ключ и адрес для уведомлений
+// хранятся как NULL.
+const walletColumns = "address, balance, COALESCE(label, ''), tags, COALESCE(public_key, ''), archived_at, COALESCE(notify_email, '')"
+
+// rowScanner - общий интерфейс *sql.Row и *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanWallet читает кошелек из строки, выбранной по walletColumns.
+// Теги хранятся как JSON (JSONB в PostgreSQL, TEXT в SQLite).
+func scanWallet(row rowScanner) (models.Wallet, error) {
+	var wallet models.Wallet
+	var tags []byte
+	var archivedAt sql.NullTime
+	if err := row.Scan(&wallet.Address, &wallet.Balance, &wallet.Label, &tags, &wallet.PublicKey, &archivedAt, &wallet.NotifyEmail); err != nil {
+		return models.Wallet{}, err
+	}
+	if archivedAt.Valid {
+		t := archivedAt.Time.UTC()
+		wallet.ArchivedAt = &t
+	}
+	if len(tags) > 0 {
+		if err := json.Unmarshal(tags, &wallet.Tags); err != nil {
+			return models.Wallet{}, fmt.Errorf("invalid wallet tags: %w", err)
+		}
+	}
+	return wallet, nil
+}
+
+// topWallets возвращает до limit активных кошельков с наибольшим балансом, не считая
+// системных счетов (см. Repository.TopWalletsByBalance). Синтаксис совместим с PostgreSQL и SQLite;
+// balance - выражение, по которому база сравнивает балансы точно (для SQLite - с collation decimal).
+func topWallets(ctx context.Context, db *sql.DB, balance string, limit int) ([]models.Wallet, error) {
+	condition, args := notSystemAccount(2)
+	rows, err := db.QueryContext(ctx, "SELECT "+walletColumns+" FROM wallets WHERE archived_at IS NULL AND "+condition+
+		" ORDER BY "+balance+" DESC, address LIMIT $1", append([]interface{}{limit}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top wallets: %w", err)
+	}
+	defer rows.Close()
+
+	var wallets []models.Wallet
+	for rows.Next() {
+		wallet, err := scanWallet(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan wallet: %w", err)
+		}
+		wallets = append(wallets, wallet)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return wallets, nil
+}
+
+// tagsJSON сериализует теги для записи в базу; отсутствие тегов хранится как пустой объект.
+func tagsJSON(tags map[string]string) string {
+	if len(tags) == 0 {
+		return "{}"
+	}
+	data, _ := json.Marshal(tags)
+	return string(data)
+}
+
+// getNonce возвращает номер последнего подписанного перевода с кошелька.
+// Синтаксис совместим с PostgreSQL и SQLite.
+func getNonce(ctx context.Context, db *sql.DB, address string) (int64, error) {
+	var nonce int64
+	err := db.QueryRowContext(ctx, "SELECT nonce FROM wallets WHERE address = $1", address).Scan(&nonce)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("failed to get nonce: %w", ErrWalletNotFound)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get nonce: %w", err)
+	}
+	return nonce, nil
+}
+
+// Ниже - архивация кошельков, общая для PostgreSQL и SQLite. Момент now передается в виде,
+// сравнимом со столбцами времени конкретной базы (см. approvals.go).
+
+// archiveWallet архивирует кошелек в одной транзакции: проверки баланса и отложенных
+// переводов выполняются над строкой, прочитанной с блокировкой lock
+// (" FOR UPDATE" для PostgreSQL; в SQLite транзакция и так блокирует базу на запись).
+func archiveWallet(ctx context.Context, db *sql.DB, lock, address string, now interface{}) (models.Wallet, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Wallet{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	wallet, err := scanWallet(tx.QueryRowContext(ctx, "SELECT "+walletColumns+" FROM wallets WHERE address = $1"+lock, address))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Wallet{}, fmt.Errorf("failed to archive wallet: %w", ErrWalletNotFound)
+	}
+	if err != nil {
+		return models.Wallet{}, fmt.Errorf("failed to archive wallet: %w", err)
+	}
+	if wallet.ArchivedAt != nil {
+		return wallet, nil
+	}
+	if !wallet.Balance.IsZero() {
+		return models.Wallet{}, ErrWalletNotEmpty
+	}
+
+	if err := checkWalletHolds(ctx, tx, address); err != nil {
+		return models.Wallet{}, err
+	}
+
+	wallet, err = scanWallet(tx.QueryRowContext(ctx,
+		"UPDATE wallets SET archived_at = $2 WHERE address = $1 RETURNING "+walletColumns, address, now))
+	if err != nil {
+		return models.Wallet{}, fmt.Errorf("failed to archive wallet: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Wallet{}, err
+	}
+	return wallet, nil
+}
+
+// restoreWallet снимает архивацию кошелька.
+// checkWalletHolds возвращает ErrWalletHasHolds, если кошелек участвует в переводах,
+// ожидающих решения или уже подтвержденных, но еще не выполненных: они не должны упасть
+// из-за архивации участника.
+func checkWalletHolds(ctx context.Context, tx *sql.Tx, address string) error {
+	var holds int
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM pending_approvals
+		WHERE (from_address = $1 OR to_address = $1) AND status IN ($2, $3)`,
+		address, models.ApprovalAwaitingReview, models.ApprovalApproved).Scan(&holds)
+	if err != nil {
+		return fmt.Errorf("failed to check wallet holds: %w", err)
+	}
+	if holds > 0 {
+		return ErrWalletHasHolds
+	}
+	return nil
+}
+
+// retireWallet архивирует кошелек в транзакции tx и снимает с него метку, чтобы ее можно
+// было присвоить другому кошельку (см. TxRepository.RetireWallet). Строка читается
+// с блокировкой lock.
+func retireWallet(ctx context.Context, tx *sql.Tx, lock, address string, now interface{}) (models.Wallet, error) {
+	wallet, err := scanWallet(tx.QueryRowContext(ctx, "SELECT "+walletColumns+" FROM wallets WHERE address = $1"+lock, address))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Wallet{}, ErrWalletNotFound
+	}
+	if err != nil {
+		return models.Wallet{}, fmt.Errorf("failed to retire wallet: %w", err)
+	}
+	if wallet.ArchivedAt != nil {
+		return models.Wallet{}, fmt.Errorf("wallet %s: %w", address, ErrWalletArchived)
+	}
+	if !wallet.Balance.IsZero() {
+		return models.Wallet{}, ErrWalletNotEmpty
+	}
+	if err := checkWalletHolds(ctx, tx, address); err != nil {
+		return models.Wallet{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE wallets SET label = NULL, archived_at = $2 WHERE address = $1", address, now); err != nil {
+		return models.Wallet{}, fmt.Errorf("failed to retire wallet: %w", err)
+	}
+	return wallet, nil
+}
+
+func restoreWallet(ctx context.Context, db *sql.DB, address string) (models.Wallet, error) {
+	wallet, err := scanWallet(db.QueryRowContext(ctx,
+		"UPDATE wallets SET archived_at = NULL WHERE address = $1 RETURNING "+walletColumns, address))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Wallet{}, fmt.Errorf("failed to restore wallet: %w", ErrWalletNotFound)
+	}
+	if err != nil {
+		return models.Wallet{}, fmt.Errorf("failed to restore wallet: %w", err)
+	}
+	return wallet, nil
+}
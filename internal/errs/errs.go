@@ -0,0 +1,95 @@
+// Package errs содержит ошибки предметной области платежной системы: недостаток средств,
+// неизвестный кошелек, перевод самому себе и другие. Хранилища и сервис возвращают их,
+// обернув контекстом через %w, а обработчики API и другие интерфейсы выбирают ответ через
+// errors.Is, не разбирая текст ошибки.
+//
+// Ошибки доступны также под прежними именами пакетов db и service (db.ErrInsufficientFunds
+// и т.п.): это те же значения, поэтому errors.Is совпадает с любым из имен.
+//
+// Пример использования:
+//
+//	if errors.Is(err, errs.ErrInsufficientFunds) {
+//		// ответить клиенту 400 insufficient_funds
+//	}
+package errs
+
+import "errors"
+
+// Ошибки перевода.
+var (
+	// ErrInsufficientFunds возвращается, если на балансе отправителя недостаточно средств.
+	ErrInsufficientFunds = errors.New("insufficient funds")
+
+	// ErrBelowMinimumBalance возвращается, если после перевода баланс отправителя
+	// стал бы меньше неснижаемого остатка (MIN_WALLET_BALANCE).
+	ErrBelowMinimumBalance = errors.New("transfer would drop balance below the minimum")
+
+	// ErrBalanceOverflow возвращается, если баланс получателя после перевода
+	// вышел бы за пределы представимых значений.
+	ErrBalanceOverflow = errors.New("balance overflow")
+
+	// ErrSelfTransfer возвращается, если отправитель и получатель перевода - один кошелек
+	// (в том числе когда метка и адрес указывают на него).
+	ErrSelfTransfer = errors.New("cannot transfer to the same wallet")
+
+	// ErrZeroAmount возвращается, если сумма перевода не положительна или округляется до нуля
+	// с точностью перевода (например, 0.0001 при двух знаках после запятой).
+	ErrZeroAmount = errors.New("transfer amount must be positive")
+
+	// ErrAmountTooLarge возвращается, если сумма перевода больше наибольшей допустимой
+	// или не помещается в NUMERIC(38, 8).
+	ErrAmountTooLarge = errors.New("transfer amount exceeds the maximum")
+
+	// ErrUnknownCategory возвращается, если категория перевода не входит в список допустимых.
+	ErrUnknownCategory = errors.New("unknown transfer category")
+
+	// ErrNonceMismatch возвращается, если номер подписанного перевода не равен следующему
+	// номеру кошелька отправителя (повтор, устаревший или забегающий вперед запрос).
+	ErrNonceMismatch = errors.New("nonce mismatch")
+
+	// ErrSignatureRequired возвращается, если подписи переводов обязательны, а перевод не подписан.
+	ErrSignatureRequired = errors.New("transfer signature required")
+
+	// ErrInvalidSignature возвращается, если подпись перевода не проверяется открытым ключом
+	// кошелька отправителя или у кошелька нет ключа.
+	ErrInvalidSignature = errors.New("invalid transfer signature")
+
+	// ErrSystemAccount возвращается, если системный счет указан там, где допустим только кошелек
+	// пользователя: в обычном переводе, при архивации или замене адреса.
+	ErrSystemAccount = errors.New("system accounts can only take part in typed operations")
+)
+
+// Ошибки кошельков.
+var (
+	// ErrWalletNotFound возвращается, если кошелек с указанным адресом не существует.
+	ErrWalletNotFound = errors.New("wallet not found")
+
+	// ErrWalletExists возвращается при попытке создать кошелек с уже занятым адресом.
+	ErrWalletExists = errors.New("wallet already exists")
+
+	// ErrLabelNotFound возвращается, если метка ("@ops-float") не присвоена ни одному кошельку.
+	ErrLabelNotFound = errors.New("wallet label not found")
+
+	// ErrLabelExists возвращается, если метка кошелька уже присвоена другому кошельку.
+	ErrLabelExists = errors.New("wallet label already exists")
+
+	// ErrWalletArchived возвращается, если отправитель или получатель перевода архивирован.
+	ErrWalletArchived = errors.New("wallet is archived")
+
+	// ErrWalletNotEmpty возвращается при архивации кошелька с ненулевым балансом.
+	ErrWalletNotEmpty = errors.New("wallet balance is not zero")
+
+	// ErrWalletHasHolds возвращается при архивации кошелька, участвующего в переводах,
+	// которые ожидают подтверждения или выполнения.
+	ErrWalletHasHolds = errors.New("wallet has transfers awaiting approval")
+)
+
+// Ошибки подтверждения крупных переводов.
+var (
+	// ErrApprovalNotFound возвращается, если отложенного перевода с указанным ID нет.
+	ErrApprovalNotFound = errors.New("approval not found")
+
+	// ErrApprovalStatus возвращается, если статус отложенного перевода уже не тот, из которого
+	// запрошен переход (например, перевод уже подтвержден, отклонен или истек).
+	ErrApprovalStatus = errors.New("unexpected approval status")
+)
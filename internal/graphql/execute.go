@@ -0,0 +1,276 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+// preparedField - поле запроса, проверенное по схеме, с аргументами после подстановки переменных.
+type preparedField struct {
+	key    string
+	name   string
+	field  *Field // nil для __typename
+	args   Args
+	fields []*preparedField
+}
+
+// prepare проверяет набор выбора по типу t, подставляет переменные в аргументы и вычисляет
+// сложность набора.
+//
+// Возвращает:
+//   - Проверенные поля.
+//   - Сложность набора.
+//   - *RequestError, если поле, аргумент или переменная неизвестны либо набор выбора
+//     не соответствует типу поля.
+func prepare(t *Type, selections []*selection, variables map[string]interface{}) ([]*preparedField, int, error) {
+	var fields []*preparedField
+	complexity := 0
+	keys := make(map[string]bool, len(selections))
+	for _, s := range selections {
+		key := s.responseKey()
+		if keys[key] {
+			return nil, 0, requestErrorf("field %q is selected more than once in %s; use an alias", key, t.Name)
+		}
+		keys[key] = true
+
+		if s.name == "__typename" {
+			if len(s.args) > 0 || s.hasSelections {
+				return nil, 0, requestErrorf("field __typename has no arguments and subfields")
+			}
+			fields = append(fields, &preparedField{key: key, name: s.name})
+			complexity++
+			continue
+		}
+		field, ok := t.Fields[s.name]
+		if !ok {
+			return nil, 0, requestErrorf("unknown field %q on type %s", s.name, t.Name)
+		}
+
+		args := make(Args, len(s.args))
+		for _, arg := range s.args {
+			if !slices.Contains(field.Args, arg.name) {
+				return nil, 0, requestErrorf("unknown argument %q on field %s.%s", arg.name, t.Name, s.name)
+			}
+			value, err := substitute(arg.value, variables)
+			if err != nil {
+				return nil, 0, err
+			}
+			args[arg.name] = value
+		}
+
+		f := &preparedField{key: key, name: s.name, field: field, args: args}
+		cost := 1
+		switch {
+		case field.Type == nil && s.hasSelections:
+			return nil, 0, requestErrorf("field %s.%s is a scalar and cannot have subfields", t.Name, s.name)
+		case field.Type != nil && !s.hasSelections:
+			return nil, 0, requestErrorf("field %s.%s of type %s must have subfields", t.Name, s.name, field.Type.Name)
+		case field.Type != nil:
+			sub, subComplexity, err := prepare(field.Type, s.selections, variables)
+			if err != nil {
+				return nil, 0, err
+			}
+			multiplier := 1
+			if field.Cost != nil {
+				multiplier = max(1, field.Cost(args))
+			}
+			f.fields = sub
+			cost += multiplier * subComplexity
+		}
+		fields = append(fields, f)
+		complexity += cost
+	}
+	return fields, complexity, nil
+}
+
+// substitute заменяет переменные в значении аргумента их значениями.
+func substitute(value interface{}, variables map[string]interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case variable:
+		resolved, ok := variables[string(v)]
+		if !ok {
+			return nil, requestErrorf("variable $%s is not defined", v)
+		}
+		return resolved, nil
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			var err error
+			if list[i], err = substitute(item, variables); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for name, item := range v {
+			var err error
+			if object[name], err = substitute(item, variables); err != nil {
+				return nil, err
+			}
+		}
+		return object, nil
+	default:
+		return value, nil
+	}
+}
+
+// objectTask - объект ответа, поля которого вычисляются на очередном уровне запроса.
+type objectTask struct {
+	t      *Type
+	source interface{}
+	fields []*preparedField
+	out    *Object
+	path   []interface{}
+}
+
+// fieldResult - значение поля, вычисленное резолвером, до завершения (Thunk еще не вызван).
+type fieldResult struct {
+	task  objectTask
+	index int
+	field *preparedField
+	value interface{}
+	err   error
+}
+
+// executor выполняет проверенный запрос по уровням: сначала вызываются резолверы всех полей
+// уровня, затем вычисляются их отложенные значения, и только потом - поля следующего уровня.
+type executor struct {
+	errors []Error
+}
+
+// execute выполняет проверенные поля корневого типа query.
+func execute(ctx context.Context, query *Type, fields []*preparedField) Response {
+	e := &executor{}
+	data := &Object{}
+	level := []objectTask{{t: query, fields: fields, out: data}}
+	for len(level) > 0 {
+		var results []fieldResult
+		for _, task := range level {
+			for _, f := range task.fields {
+				index := len(task.out.keys)
+				task.out.keys = append(task.out.keys, f.key)
+				task.out.values = append(task.out.values, nil)
+				if f.field == nil {
+					task.out.values[index] = task.t.Name
+					continue
+				}
+				value, err := f.field.Resolve(ctx, task.source, f.args)
+				results = append(results, fieldResult{task: task, index: index, field: f, value: value, err: err})
+			}
+		}
+
+		// Отложенные значения вычисляются после обхода уровня, чтобы загрузчики собрали все ключи
+		var next []objectTask
+		for _, r := range results {
+			if thunk, ok := r.value.(Thunk); ok && r.err == nil {
+				r.value, r.err = thunk()
+			}
+			path := append(slices.Clip(r.task.path), r.field.key)
+			if r.err != nil {
+				e.errors = append(e.errors, Error{Message: r.err.Error(), Path: path})
+				continue
+			}
+			r.task.out.values[r.index], next = e.complete(r.field, r.value, path, next)
+		}
+		level = next
+	}
+	return Response{Data: data, Errors: e.errors}
+}
+
+// complete преобразует значение поля в значение ответа. Для полей-объектов создает объекты
+// ответа и добавляет в next задачи вычисления их полей.
+func (e *executor) complete(f *preparedField, value interface{}, path []interface{}, next []objectTask) (interface{}, []objectTask) {
+	if f.field.Type == nil || value == nil {
+		return value, next
+	}
+	if !f.field.List {
+		out := &Object{}
+		return out, append(next, objectTask{t: f.field.Type, source: value, fields: f.fields, out: out, path: path})
+	}
+
+	items := reflect.ValueOf(value)
+	if items.Kind() != reflect.Slice {
+		e.errors = append(e.errors, Error{Message: fmt.Sprintf("field %s returned %T instead of a list", f.name, value), Path: path})
+		return nil, next
+	}
+	list := make([]interface{}, items.Len())
+	for i := range list {
+		out := &Object{}
+		list[i] = out
+		next = append(next, objectTask{t: f.field.Type, source: items.Index(i).Interface(), fields: f.fields, out: out,
+			path: append(slices.Clip(path), i)})
+	}
+	return list, next
+}
+
+// Args - аргументы поля или поля входного объекта после подстановки переменных.
+// Методы возвращают ошибки с сообщениями для клиента.
+type Args map[string]interface{}
+
+// String возвращает строковый аргумент; отсутствующий или null - пустая строка и false.
+func (a Args) String(name string) (string, bool, error) {
+	value, ok := a[name]
+	if !ok || value == nil {
+		return "", false, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", false, fmt.Errorf("argument %q must be a string", name)
+	}
+	return s, true, nil
+}
+
+// Int возвращает целочисленный аргумент; отсутствующий или null - def.
+func (a Args) Int(name string, def int) (int, error) {
+	value, ok := a[name]
+	if !ok || value == nil {
+		return def, nil
+	}
+	// Числа литералов и переменных (декодированных с UseNumber) - json.Number
+	number, ok := value.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("argument %q must be an integer", name)
+	}
+	n, err := strconv.Atoi(number.String())
+	if err != nil {
+		return 0, fmt.Errorf("argument %q must be an integer", name)
+	}
+	return n, nil
+}
+
+// Bool возвращает логический аргумент; отсутствующий или null - false.
+func (a Args) Bool(name string) (bool, error) {
+	value, ok := a[name]
+	if !ok || value == nil {
+		return false, nil
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("argument %q must be a boolean", name)
+	}
+	return b, nil
+}
+
+// Object возвращает аргумент - входной объект, поля которого ограничены fields;
+// отсутствующий или null - пустой объект.
+func (a Args) Object(name string, fields ...string) (Args, error) {
+	value, ok := a[name]
+	if !ok || value == nil {
+		return Args{}, nil
+	}
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("argument %q must be an object", name)
+	}
+	for field := range object {
+		if !slices.Contains(fields, field) {
+			return nil, fmt.Errorf("unknown field %q in argument %q", field, name)
+		}
+	}
+	return Args(object), nil
+}
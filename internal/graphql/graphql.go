@@ -0,0 +1,153 @@
+// Package graphql - минимальная реализация GraphQL для запросов на чтение: разбор документа,
+// проверка по схеме с ограничениями глубины и сложности и выполнение с отложенными значениями
+// (Thunk), через которые загрузчики собирают ключи одного уровня запроса в один запрос к хранилищу.
+//
+// Поддерживается подмножество языка, нужное панелям мониторинга: операции query с переменными,
+// псевдонимы, аргументы (в том числе входные объекты и списки) и поле __typename. Фрагменты,
+// директивы, мутации, подписки и интроспекция не поддерживаются и отклоняются с ошибкой.
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Request - запрос GraphQL в формате GraphQL over HTTP.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Response - ответ GraphQL: данные и ошибки полей. Data отсутствует, если запрос
+// не прошел разбор или проверку и не выполнялся.
+type Response struct {
+	Data   *Object `json:"data,omitempty"`
+	Errors []Error `json:"errors,omitempty"`
+}
+
+// Error - ошибка запроса или поля. Path - путь к полю в ответе (ключи и индексы списков);
+// у ошибок разбора и проверки его нет.
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// Limits - ограничения запроса, проверяемые до выполнения.
+type Limits struct {
+	MaxDepth      int // Наибольшая вложенность полей; поле верхнего уровня имеет глубину 1
+	MaxComplexity int // Наибольшая сложность (см. Field.Cost)
+}
+
+// Type - объектный тип схемы.
+type Type struct {
+	Name   string
+	Fields map[string]*Field
+}
+
+// Field - поле объектного типа.
+type Field struct {
+	// Type - тип значения для полей-объектов и списков объектов; nil - скаляр или список скаляров,
+	// значение которого выводится как есть
+	Type *Type
+	List bool     // Значение - список (срез)
+	Args []string // Допустимые аргументы
+
+	// Cost возвращает множитель сложности вложенных полей, обычно размер списка по аргументу
+	// limit; nil - 1. Сложность поля - 1 плюс сложность вложенных полей, умноженная на множитель
+	Cost func(args Args) int
+
+	// Resolve вычисляет значение поля объекта source (nil для полей Query). Может вернуть Thunk,
+	// чтобы значение вычислялось после обхода всего уровня запроса
+	Resolve func(ctx context.Context, source interface{}, args Args) (interface{}, error)
+}
+
+// Thunk - отложенное значение поля. Выполнитель вызывает все Thunk уровня запроса после того,
+// как вычислены остальные поля уровня, поэтому первый вызов может загрузить значения для всех
+// ключей, собранных загрузчиком.
+type Thunk func() (interface{}, error)
+
+// Object - объект ответа с полями в порядке запроса.
+type Object struct {
+	keys   []string
+	values []interface{}
+}
+
+// MarshalJSON кодирует объект с полями в порядке запроса, как требует спецификация GraphQL.
+func (o *Object) MarshalJSON() ([]byte, error) {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		v, err := json.Marshal(o.values[i])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return []byte(b.String()), nil
+}
+
+// RequestError - ошибка разбора или проверки запроса: запрос не выполнялся.
+type RequestError struct {
+	Message string
+}
+
+func (e *RequestError) Error() string {
+	return e.Message
+}
+
+// requestErrorf создает RequestError с форматированным сообщением.
+func requestErrorf(format string, args ...interface{}) *RequestError {
+	return &RequestError{Message: fmt.Sprintf(format, args...)}
+}
+
+// Execute разбирает, проверяет и выполняет запрос на чтение по схеме с корневым типом query.
+//
+// Параметры:
+//   - ctx: Контекст запроса, передаваемый резолверам.
+//   - query: Корневой тип схемы.
+//   - req: Запрос.
+//   - limits: Ограничения глубины и сложности.
+//
+// Возвращает:
+//   - Ответ с данными и ошибками полей.
+//   - *RequestError, если запрос не прошел разбор или проверку; ответ тогда не содержит данных.
+//
+// Пример использования:
+//
+//	resp, err := graphql.Execute(r.Context(), schema, req, graphql.Limits{MaxDepth: 5, MaxComplexity: 1000})
+func Execute(ctx context.Context, query *Type, req Request, limits Limits) (Response, error) {
+	doc, err := parse(req.Query, limits.MaxDepth)
+	if err != nil {
+		return Response{}, err
+	}
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return Response{}, err
+	}
+	if op.kind != "query" {
+		return Response{}, requestErrorf("%s operations are not supported, only query", op.kind)
+	}
+	variables, err := op.coerceVariables(req.Variables)
+	if err != nil {
+		return Response{}, err
+	}
+
+	fields, complexity, err := prepare(query, op.selections, variables)
+	if err != nil {
+		return Response{}, err
+	}
+	if limits.MaxComplexity > 0 && complexity > limits.MaxComplexity {
+		return Response{}, requestErrorf("query complexity %d exceeds the limit of %d", complexity, limits.MaxComplexity)
+	}
+	return execute(ctx, query, fields), nil
+}
@@ -0,0 +1,245 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// batchLoader собирает имена элементов, запрошенных на одном уровне, и загружает их одним вызовом.
+type batchLoader struct {
+	pending []string
+	batches [][]string
+}
+
+func (l *batchLoader) load(name string) Thunk {
+	l.pending = append(l.pending, name)
+	return func() (interface{}, error) {
+		if len(l.pending) > 0 {
+			l.batches = append(l.batches, l.pending)
+			l.pending = nil
+		}
+		return "loaded " + name, nil
+	}
+}
+
+// newTestSchema возвращает схему:
+//
+//	type Query { echo(value: Any): Any  item(name: String): Item  fail: String }
+//	type Item { name: String  children(limit: Int): [Item]  loaded: String }
+//
+// Значение Item - его имя; дочерние элементы имени "r" - "r/0", "r/1", ...
+func newTestSchema(loader *batchLoader) *Type {
+	item := &Type{Name: "Item"}
+	item.Fields = map[string]*Field{
+		"name": {Resolve: func(ctx context.Context, source interface{}, args Args) (interface{}, error) {
+			return source, nil
+		}},
+		"children": {
+			Type: item, List: true, Args: []string{"limit"},
+			Cost: func(args Args) int {
+				limit, _ := args.Int("limit", 1)
+				return limit
+			},
+			Resolve: func(ctx context.Context, source interface{}, args Args) (interface{}, error) {
+				limit, err := args.Int("limit", 1)
+				if err != nil {
+					return nil, err
+				}
+				children := make([]string, limit)
+				for i := range children {
+					children[i] = fmt.Sprintf("%s/%d", source, i)
+				}
+				return children, nil
+			},
+		},
+		"loaded": {Resolve: func(ctx context.Context, source interface{}, args Args) (interface{}, error) {
+			return loader.load(source.(string)), nil
+		}},
+	}
+	return &Type{Name: "Query", Fields: map[string]*Field{
+		"echo": {Args: []string{"value"}, Resolve: func(ctx context.Context, source interface{}, args Args) (interface{}, error) {
+			return args["value"], nil
+		}},
+		"item": {Type: item, Args: []string{"name"}, Resolve: func(ctx context.Context, source interface{}, args Args) (interface{}, error) {
+			name, _, err := args.String("name")
+			return name, err
+		}},
+		"fail": {Resolve: func(ctx context.Context, source interface{}, args Args) (interface{}, error) {
+			return nil, errors.New("boom")
+		}},
+	}}
+}
+
+// marshalData кодирует данные ответа в JSON.
+func marshalData(t *testing.T, resp Response) string {
+	t.Helper()
+	data, err := json.Marshal(resp.Data)
+	if err != nil {
+		t.Fatalf("marshal %+v: %v", resp.Data, err)
+	}
+	return string(data)
+}
+
+// TestExecute проверяет разбор и выполнение допустимых запросов: псевдонимы, переменные и их
+// значения по умолчанию, литералы списков и объектов, комментарии и выбор операции по operationName.
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		operation string
+		want      string
+	}{
+		{"shorthand", `{ echo(value: "hi") }`, nil, "", `{"echo":"hi"}`},
+		{"aliases keep query order", `{ b: echo(value: 1) a: echo(value: 2.5e1) __typename }`, nil, "",
+			`{"b":1,"a":2.5e1,"__typename":"Query"}`},
+		{"variable", `query Q($v: String!) { echo(value: $v) }`, map[string]interface{}{"v": "x"}, "", `{"echo":"x"}`},
+		{"default value", `query ($v: Int = 7) { echo(value: $v) }`, nil, "", `{"echo":7}`},
+		{"null for optional variable", `query ($v: String) { echo(value: $v) }`, nil, "", `{"echo":null}`},
+		{"variable inside list and object", `query ($v: [Int]) { echo(value: {a: $v, b: [-1, "s\n", true, null]}) }`,
+			map[string]interface{}{"v": []interface{}{1, 2}}, "", `{"echo":{"a":[1,2],"b":[-1,"s\n",true,null]}}`},
+		{"comments and commas", "# dashboard\n{ echo(value: \"x\"),, }", nil, "", `{"echo":"x"}`},
+		{"operation name", `query A { a: echo(value: 1) } query B { b: echo(value: 2) }`, nil, "B", `{"b":2}`},
+		{"nested lists", `{ item(name: "r") { name children(limit: 2) { name children { name } } } }`, nil, "",
+			`{"item":{"name":"r","children":[{"name":"r/0","children":[{"name":"r/0/0"}]},{"name":"r/1","children":[{"name":"r/1/0"}]}]}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := Request{Query: tt.query, Variables: tt.variables, OperationName: tt.operation}
+			resp, err := Execute(context.Background(), newTestSchema(&batchLoader{}), req, Limits{})
+			if err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			if len(resp.Errors) > 0 {
+				t.Fatalf("errors: %+v", resp.Errors)
+			}
+			if got := marshalData(t, resp); got != tt.want {
+				t.Errorf("data = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestExecuteRejected проверяет запросы, которые отклоняются до выполнения *RequestError.
+func TestExecuteRejected(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		operation string
+		wantErr   string
+	}{
+		{"empty", " # nothing\n", nil, "", "must not be empty"},
+		{"mutation", `mutation { echo(value: 1) }`, nil, "", "mutation operations are not supported"},
+		{"subscription", `subscription S { echo(value: 1) }`, nil, "", "subscription operations are not supported"},
+		{"fragment definition", `fragment F on Item { name }`, nil, "", "fragments are not supported"},
+		{"fragment spread", `{ item(name: "r") { ...F } }`, nil, "", "fragments are not supported"},
+		{"inline fragment", `{ item(name: "r") { ... on Item { name } } }`, nil, "", "fragments are not supported"},
+		{"field directive", `{ echo(value: 1) @skip(if: true) }`, nil, "", "directives are not supported"},
+		{"operation directive", `query Q @cached { echo(value: 1) }`, nil, "", "directives are not supported"},
+		{"enum value", `{ echo(value: ASC) }`, nil, "", "enum value ASC is not supported"},
+		{"block string", `{ echo(value: """x""") }`, nil, "", "block strings are not supported"},
+		{"missing value", "{\n  echo(value: )\n}", nil, "", "syntax error at line 2, column 15"},
+		{"unterminated string", `{ echo(value: "x) }`, nil, "", "unterminated string"},
+		{"invalid number", `{ echo(value: 1.) }`, nil, "", "invalid number"},
+		{"unclosed selection", `{ echo(value: 1)`, nil, "", "expected name, found end of query"},
+		{"empty selection", `{ item(name: "r") { } }`, nil, "", "selection set must not be empty"},
+		{"several operations without a name", `query A { echo(value: 1) } query B { echo(value: 2) }`, nil, "",
+			"operationName is required"},
+		{"unknown operation", `query A { echo(value: 1) }`, nil, "B", `operation "B" not found`},
+		{"missing required variable", `query ($v: String!) { echo(value: $v) }`, nil, "", "variable $v is required"},
+		{"null required variable", `query ($v: String!) { echo(value: $v) }`, map[string]interface{}{"v": nil}, "",
+			"variable $v is required"},
+		{"undeclared variable", `{ echo(value: $v) }`, map[string]interface{}{"v": 1}, "", "variable $v is not defined"},
+		{"variable in default value", `query ($a: Int = $b) { echo(value: $a) }`, nil, "", "expected value"},
+		{"unknown field", `{ wallet }`, nil, "", `unknown field "wallet" on type Query`},
+		{"unknown argument", `{ echo(text: 1) }`, nil, "", `unknown argument "text" on field Query.echo`},
+		{"duplicate response key", `{ echo(value: 1) echo(value: 2) }`, nil, "", "use an alias"},
+		{"scalar with subfields", `{ echo(value: 1) { name } }`, nil, "", "is a scalar and cannot have subfields"},
+		{"object without subfields", `{ item(name: "r") }`, nil, "", "must have subfields"},
+		{"typename with subfields", `{ __typename { name } }`, nil, "", "__typename has no arguments and subfields"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := Request{Query: tt.query, Variables: tt.variables, OperationName: tt.operation}
+			resp, err := Execute(context.Background(), newTestSchema(&batchLoader{}), req, Limits{})
+			var reqErr *RequestError
+			if !errors.As(err, &reqErr) {
+				t.Fatalf("Execute = %+v, %v, want *RequestError", resp, err)
+			}
+			if !strings.Contains(reqErr.Message, tt.wantErr) {
+				t.Errorf("error %q does not contain %q", reqErr.Message, tt.wantErr)
+			}
+			if resp.Data != nil {
+				t.Errorf("rejected request returned data %s", marshalData(t, resp))
+			}
+		})
+	}
+}
+
+// TestExecuteLimits проверяет ограничения на границе: вложенность полей и значений аргументов,
+// равная MaxDepth, и сложность, равная MaxComplexity, допускаются, а на единицу больше - нет.
+// Сложность поля списка умножается на результат Cost.
+func TestExecuteLimits(t *testing.T) {
+	// Глубина 4; сложность: item 1 + (children 1 + 3 * (name 1 + children 1 + 2 * name 1)) = 1 + 1 + 3*4 = 14
+	const nested = `{ item(name: "r") { children(limit: 3) { name children(limit: 2) { name } } } }`
+
+	tests := []struct {
+		name    string
+		query   string
+		limits  Limits
+		wantErr string // "" - запрос выполняется
+	}{
+		{"depth at limit", nested, Limits{MaxDepth: 4}, ""},
+		{"depth above limit", nested, Limits{MaxDepth: 3}, "query depth exceeds the limit of 3"},
+		{"value depth at limit", `{ echo(value: [[1]]) }`, Limits{MaxDepth: 3}, ""},
+		{"value depth above limit", `{ echo(value: [[[1]]]) }`, Limits{MaxDepth: 3}, "query depth exceeds the limit of 3"},
+		{"object value depth above limit", `{ echo(value: {a: {b: 1}}) }`, Limits{MaxDepth: 2}, "query depth exceeds the limit of 2"},
+		{"complexity at limit", nested, Limits{MaxComplexity: 14}, ""},
+		{"complexity above limit", nested, Limits{MaxComplexity: 13}, "query complexity 14 exceeds the limit of 13"},
+		{"cost from variable", `query ($n: Int) { item(name: "r") { children(limit: $n) { name } } }`, Limits{MaxComplexity: 51},
+			"query complexity 52 exceeds the limit of 51"},
+		{"no limits", nested, Limits{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := Request{Query: tt.query, Variables: map[string]interface{}{"n": json.Number("50")}}
+			_, err := Execute(context.Background(), newTestSchema(&batchLoader{}), req, tt.limits)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Execute: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Execute = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestExecuteThunks проверяет, что отложенные значения уровня вычисляются после вызова всех
+// резолверов уровня, поэтому загрузчик получает ключи всех объектов одним вызовом, и что
+// ошибка поля возвращается с путем к нему, не мешая остальным полям.
+func TestExecuteThunks(t *testing.T) {
+	loader := &batchLoader{}
+	query := `{ a: item(name: "x") { loaded } fail b: item(name: "y") { loaded children(limit: 2) { loaded } } }`
+	resp, err := Execute(context.Background(), newTestSchema(loader), Request{Query: query}, Limits{})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	want := `{"a":{"loaded":"loaded x"},"fail":null,"b":{"loaded":"loaded y","children":[{"loaded":"loaded y/0"},{"loaded":"loaded y/1"}]}}`
+	if got := marshalData(t, resp); got != want {
+		t.Errorf("data = %s, want %s", got, want)
+	}
+	if len(resp.Errors) != 1 || resp.Errors[0].Message != "boom" || !slices.Equal(resp.Errors[0].Path, []interface{}{"fail"}) {
+		t.Errorf("errors = %+v, want boom at [fail]", resp.Errors)
+	}
+	wantBatches := [][]string{{"x", "y"}, {"y/0", "y/1"}}
+	if !slices.EqualFunc(loader.batches, wantBatches, slices.Equal[[]string]) {
+		t.Errorf("loader batches = %v, want %v", loader.batches, wantBatches)
+	}
+}
@@ -0,0 +1,519 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// document - разобранный документ: операции в порядке записи.
+type document struct {
+	operations []*operation
+}
+
+// operation - операция документа.
+type operation struct {
+	kind       string // query, mutation или subscription
+	name       string
+	variables  []variableDefinition
+	selections []*selection
+}
+
+// variableDefinition - объявление переменной операции.
+type variableDefinition struct {
+	name         string
+	required     bool // Тип с "!" без значения по умолчанию
+	defaultValue interface{}
+	hasDefault   bool
+}
+
+// selection - поле в наборе выбора.
+type selection struct {
+	alias         string
+	name          string
+	args          []argument
+	selections    []*selection
+	hasSelections bool
+}
+
+// responseKey возвращает ключ поля в ответе: псевдоним или имя.
+func (s *selection) responseKey() string {
+	if s.alias != "" {
+		return s.alias
+	}
+	return s.name
+}
+
+// argument - аргумент поля. Значение - литерал в виде, в который декодируется JSON
+// (string, json.Number, bool, nil, []interface{}, map[string]interface{}), или variable.
+type argument struct {
+	name  string
+	value interface{}
+}
+
+// variable - ссылка на переменную в значении аргумента.
+type variable string
+
+// parser разбирает документ GraphQL рекурсивным спуском, читая лексемы по мере разбора.
+type parser struct {
+	src      string
+	pos      int
+	maxDepth int // Наибольшая вложенность наборов выбора и значений; 0 - без ограничения
+	depth    int
+}
+
+// parse разбирает документ; вложенность полей и значений больше maxDepth отклоняется при
+// разборе, чтобы глубокий документ не разбирался целиком.
+func parse(src string, maxDepth int) (*document, error) {
+	p := &parser{src: src, maxDepth: maxDepth}
+	doc := &document{}
+	p.skipIgnored()
+	if p.pos == len(p.src) {
+		return nil, requestErrorf("query must not be empty")
+	}
+	for p.pos < len(p.src) {
+		op, err := p.parseOperation()
+		if err != nil {
+			return nil, err
+		}
+		doc.operations = append(doc.operations, op)
+		p.skipIgnored()
+	}
+	return doc, nil
+}
+
+// operation выбирает операцию для выполнения: единственную или с именем name.
+func (d *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(d.operations) > 1 {
+			return nil, requestErrorf("operationName is required for a document with several operations")
+		}
+		return d.operations[0], nil
+	}
+	for _, op := range d.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, requestErrorf("operation %q not found", name)
+}
+
+// coerceVariables проверяет значения переменных запроса по объявлениям операции
+// и подставляет значения по умолчанию; необъявленные переменные в результат не попадают.
+// Типы значений проверяют резолверы.
+func (op *operation) coerceVariables(values map[string]interface{}) (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(op.variables))
+	for _, def := range op.variables {
+		value, ok := values[def.name]
+		switch {
+		case ok && !(value == nil && def.required):
+			result[def.name] = value
+		case def.hasDefault:
+			result[def.name] = def.defaultValue
+		case def.required:
+			return nil, requestErrorf("variable $%s is required", def.name)
+		default:
+			result[def.name] = nil
+		}
+	}
+	return result, nil
+}
+
+// errorf возвращает ошибку разбора с позицией (строка и столбец) текущей лексемы.
+func (p *parser) errorf(format string, args ...interface{}) error {
+	line, column := 1, 1
+	for _, r := range p.src[:min(p.pos, len(p.src))] {
+		if r == '\n' {
+			line, column = line+1, 1
+		} else {
+			column++
+		}
+	}
+	return requestErrorf("syntax error at line %d, column %d: %s", line, column, fmt.Sprintf(format, args...))
+}
+
+// enter учитывает вход во вложенный набор выбора или значение.
+func (p *parser) enter() error {
+	p.depth++
+	if p.maxDepth > 0 && p.depth > p.maxDepth {
+		return requestErrorf("query depth exceeds the limit of %d", p.maxDepth)
+	}
+	return nil
+}
+
+// leave учитывает выход из вложенного набора выбора или значения.
+func (p *parser) leave() {
+	p.depth--
+}
+
+// skipIgnored пропускает пробельные символы, запятые и комментарии.
+func (p *parser) skipIgnored() {
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			p.pos++
+		case c == '#':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' && p.src[p.pos] != '\r' {
+				p.pos++
+			}
+		case strings.HasPrefix(p.src[p.pos:], "\uFEFF"):
+			p.pos += len("\uFEFF")
+		default:
+			return
+		}
+	}
+}
+
+// peek возвращает следующий символ после пропуска игнорируемых (0 в конце документа).
+func (p *parser) peek() byte {
+	p.skipIgnored()
+	if p.pos == len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+// expect читает символ пунктуации c.
+func (p *parser) expect(c byte) error {
+	if p.peek() != c {
+		return p.errorf("expected %q, found %s", c, p.describe())
+	}
+	p.pos++
+	return nil
+}
+
+// describe описывает следующую лексему для сообщения об ошибке.
+func (p *parser) describe() string {
+	if p.peek() == 0 {
+		return "end of query"
+	}
+	r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+	return strconv.QuoteRune(r)
+}
+
+// isNameStart и isNameContinue - символы имени GraphQL: [_A-Za-z][_0-9A-Za-z]*.
+func isNameStart(c byte) bool {
+	return c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+func isNameContinue(c byte) bool {
+	return isNameStart(c) || c >= '0' && c <= '9'
+}
+
+// parseName читает имя.
+func (p *parser) parseName() (string, error) {
+	if !isNameStart(p.peek()) {
+		return "", p.errorf("expected name, found %s", p.describe())
+	}
+	start := p.pos
+	for p.pos < len(p.src) && isNameContinue(p.src[p.pos]) {
+		p.pos++
+	}
+	return p.src[start:p.pos], nil
+}
+
+// parseOperation читает операцию: сокращенную запись "{ ... }" или с типом, именем и переменными.
+func (p *parser) parseOperation() (*operation, error) {
+	op := &operation{kind: "query"}
+	if p.peek() != '{' {
+		kind, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		switch kind {
+		case "query", "mutation", "subscription":
+			op.kind = kind
+		case "fragment":
+			return nil, requestErrorf("fragments are not supported")
+		default:
+			return nil, p.errorf("unexpected %q, expected query", kind)
+		}
+		if isNameStart(p.peek()) {
+			if op.name, err = p.parseName(); err != nil {
+				return nil, err
+			}
+		}
+		if p.peek() == '(' {
+			if op.variables, err = p.parseVariableDefinitions(); err != nil {
+				return nil, err
+			}
+		}
+		if p.peek() == '@' {
+			return nil, requestErrorf("directives are not supported")
+		}
+	}
+	selections, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.selections = selections
+	return op, nil
+}
+
+// parseVariableDefinitions читает объявления переменных "($name: Type = default, ...)".
+func (p *parser) parseVariableDefinitions() ([]variableDefinition, error) {
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+	var defs []variableDefinition
+	for p.peek() != ')' {
+		if err := p.expect('$'); err != nil {
+			return nil, err
+		}
+		name, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(':'); err != nil {
+			return nil, err
+		}
+		required, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		def := variableDefinition{name: name, required: required}
+		if p.peek() == '=' {
+			p.pos++
+			if def.defaultValue, err = p.parseValue(true); err != nil {
+				return nil, err
+			}
+			def.hasDefault, def.required = true, false
+		}
+		defs = append(defs, def)
+	}
+	p.pos++
+	return defs, nil
+}
+
+// parseType читает тип переменной (Name, [Type], Type!) и сообщает, обязателен ли он.
+func (p *parser) parseType() (bool, error) {
+	if p.peek() == '[' {
+		p.pos++
+		if err := p.enter(); err != nil {
+			return false, err
+		}
+		if _, err := p.parseType(); err != nil {
+			return false, err
+		}
+		p.leave()
+		if err := p.expect(']'); err != nil {
+			return false, err
+		}
+	} else if _, err := p.parseName(); err != nil {
+		return false, err
+	}
+	if p.peek() == '!' {
+		p.pos++
+		return true, nil
+	}
+	return false, nil
+}
+
+// parseSelectionSet читает набор выбора "{ field ... }".
+func (p *parser) parseSelectionSet() ([]*selection, error) {
+	if err := p.expect('{'); err != nil {
+		return nil, err
+	}
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	var selections []*selection
+	for p.peek() != '}' {
+		if strings.HasPrefix(p.src[p.pos:], "...") {
+			return nil, requestErrorf("fragments are not supported")
+		}
+		s, err := p.parseField()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, s)
+	}
+	p.pos++
+	p.leave()
+	if len(selections) == 0 {
+		return nil, p.errorf("selection set must not be empty")
+	}
+	return selections, nil
+}
+
+// parseField читает поле "alias: name(args) { ... }".
+func (p *parser) parseField() (*selection, error) {
+	name, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+	s := &selection{name: name}
+	if p.peek() == ':' {
+		p.pos++
+		s.alias = name
+		if s.name, err = p.parseName(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek() == '(' {
+		p.pos++
+		for p.peek() != ')' {
+			argName, err := p.parseName()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(':'); err != nil {
+				return nil, err
+			}
+			value, err := p.parseValue(false)
+			if err != nil {
+				return nil, err
+			}
+			s.args = append(s.args, argument{name: argName, value: value})
+		}
+		p.pos++
+	}
+	if p.peek() == '@' {
+		return nil, requestErrorf("directives are not supported")
+	}
+	if p.peek() == '{' {
+		s.hasSelections = true
+		if s.selections, err = p.parseSelectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// parseValue читает значение аргумента; в значениях по умолчанию (constant) переменные запрещены.
+func (p *parser) parseValue(constant bool) (interface{}, error) {
+	switch c := p.peek(); {
+	case c == '$' && !constant:
+		p.pos++
+		name, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		return variable(name), nil
+	case c == '"':
+		return p.parseString()
+	case c == '-' || c >= '0' && c <= '9':
+		return p.parseNumber()
+	case c == '[':
+		p.pos++
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		list := []interface{}{}
+		for p.peek() != ']' {
+			item, err := p.parseValue(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+		p.pos++
+		p.leave()
+		return list, nil
+	case c == '{':
+		p.pos++
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		object := map[string]interface{}{}
+		for p.peek() != '}' {
+			name, err := p.parseName()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := object[name]; ok {
+				return nil, p.errorf("duplicate input field %q", name)
+			}
+			if err := p.expect(':'); err != nil {
+				return nil, err
+			}
+			if object[name], err = p.parseValue(constant); err != nil {
+				return nil, err
+			}
+		}
+		p.pos++
+		p.leave()
+		return object, nil
+	case isNameStart(c):
+		name, _ := p.parseName()
+		switch name {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return nil, requestErrorf("enum value %s is not supported", name)
+	default:
+		return nil, p.errorf("expected value, found %s", p.describe())
+	}
+}
+
+// parseNumber читает целое или дробное число как json.Number, как числа переменных.
+func (p *parser) parseNumber() (interface{}, error) {
+	start := p.pos
+	if p.src[p.pos] == '-' {
+		p.pos++
+	}
+	digits := func() int {
+		n := 0
+		for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+			p.pos++
+			n++
+		}
+		return n
+	}
+	if digits() == 0 {
+		return nil, p.errorf("invalid number")
+	}
+	if p.pos < len(p.src) && p.src[p.pos] == '.' {
+		p.pos++
+		if digits() == 0 {
+			return nil, p.errorf("invalid number")
+		}
+	}
+	if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+		p.pos++
+		if p.pos < len(p.src) && (p.src[p.pos] == '+' || p.src[p.pos] == '-') {
+			p.pos++
+		}
+		if digits() == 0 {
+			return nil, p.errorf("invalid number")
+		}
+	}
+	if p.pos < len(p.src) && (isNameStart(p.src[p.pos]) || p.src[p.pos] == '.') {
+		return nil, p.errorf("invalid number")
+	}
+	return json.Number(p.src[start:p.pos]), nil
+}
+
+// parseString читает строку в кавычках с экранированием, как в JSON. Блочные строки
+// (""") не поддерживаются.
+func (p *parser) parseString() (interface{}, error) {
+	if strings.HasPrefix(p.src[p.pos:], `"""`) {
+		return nil, requestErrorf("block strings are not supported")
+	}
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos += 2
+		case '\n', '\r':
+			return nil, p.errorf("unterminated string")
+		case '"':
+			p.pos++
+			var s string
+			if err := json.Unmarshal([]byte(p.src[start:p.pos]), &s); err != nil {
+				return nil, p.errorf("invalid string")
+			}
+			return s, nil
+		default:
+			p.pos++
+		}
+	}
+	return nil, p.errorf("unterminated string")
+}
@@ -0,0 +1,144 @@
+// Package logging создает структурированный журнал приложения (log/slog) и передает его
+// через контекст запроса. Журнал создается в main по настройкам LOG_LEVEL и LOG_FORMAT
+// и устанавливается журналом по умолчанию (slog.SetDefault), поэтому пакеты без контекста
+// пишут в него через slog напрямую.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Ключи атрибутов, общие для всех записей журнала, чтобы записи одного запроса, кошелька
+// или транзакции находились одним фильтром.
+const (
+	KeyRequestID = "request_id"  // Идентификатор HTTP-запроса (заголовок X-Request-ID)
+	KeyTraceID   = "trace_id"    // Идентификатор трассировки OpenTelemetry (заголовок traceparent)
+	KeyWallet    = "wallet"      // Адрес кошелька
+	KeyTxID      = "tx_id"       // Идентификатор транзакции перевода
+	KeyDuration  = "duration_ms" // Длительность операции в миллисекундах
+)
+
+// Форматы журнала.
+const (
+	FormatText = "text" // key=value, удобен для чтения в терминале
+	FormatJSON = "json" // одна JSON-запись на строку, для сборщиков журналов
+)
+
+// loggerKey - ключ журнала в контексте.
+type loggerKey struct{}
+
+// level - уровень журналов, созданных New; меняется во время работы через SetLevel.
+var level = new(slog.LevelVar)
+
+// ParseLevel разбирает уровень журнала: debug, info, warn или error (без учета регистра).
+//
+// Параметры:
+//   - level: Уровень журнала.
+//
+// Возвращает:
+//   - Уровень slog.
+//   - Ошибку, если уровень неизвестен.
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q", level)
+	}
+}
+
+// New создает журнал с уровнем level в формате format. Уровень общий для всех журналов,
+// созданных New, и меняется без перезапуска (см. SetLevel).
+//
+// Параметры:
+//   - level: Уровень журнала (см. ParseLevel).
+//   - format: FormatText или FormatJSON.
+//   - w: Куда писать записи, обычно os.Stderr.
+//
+// Возвращает:
+//   - Журнал.
+//   - Ошибку, если уровень или формат неизвестен.
+//
+// Пример использования:
+//
+//	logger, err := logging.New("info", logging.FormatJSON, os.Stderr)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	slog.SetDefault(logger)
+func New(lvl, format string, w io.Writer) (*slog.Logger, error) {
+	parsed, err := ParseLevel(lvl)
+	if err != nil {
+		return nil, err
+	}
+	options := &slog.HandlerOptions{Level: level}
+	var logger *slog.Logger
+	switch strings.ToLower(format) {
+	case FormatText:
+		logger = slog.New(slog.NewTextHandler(w, options))
+	case FormatJSON:
+		logger = slog.New(slog.NewJSONHandler(w, options))
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+	level.Set(parsed)
+	return logger, nil
+}
+
+// SetLevel меняет уровень журналов, созданных New, во время работы.
+//
+// Параметры:
+//   - lvl: Уровень журнала (см. ParseLevel).
+//
+// Возвращает:
+//   - Ошибку, если уровень неизвестен; уровень тогда не меняется.
+//
+// Пример использования:
+//
+//	err := logging.SetLevel("debug")
+func SetLevel(lvl string) error {
+	parsed, err := ParseLevel(lvl)
+	if err != nil {
+		return err
+	}
+	level.Set(parsed)
+	return nil
+}
+
+// WithContext возвращает контекст с журналом logger; FromContext вернет его.
+//
+// Пример использования:
+//
+//	ctx = logging.WithContext(ctx, logging.FromContext(ctx).With(logging.KeyRequestID, id))
+func WithContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext возвращает журнал из контекста (например, с идентификатором запроса)
+// или журнал по умолчанию slog.Default(), если в контексте его нет.
+//
+// Пример использования:
+//
+//	logging.FromContext(r.Context()).Info("transfer completed", logging.KeyTxID, id)
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// Duration возвращает атрибут KeyDuration: длительность в миллисекундах с дробной частью.
+func Duration(d time.Duration) slog.Attr {
+	return slog.Float64(KeyDuration, float64(d.Microseconds())/1000)
+}
@@ -0,0 +1,90 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	t.Cleanup(func() { level.Set(0) })
+
+	tests := []struct {
+		level, format string
+		wantErr       bool
+		want          string // Подстрока записи уровня warn
+	}{
+		{"info", FormatJSON, false, `"msg":"disk low","free_mb":12`},
+		{"WARN", "JSON", false, `"level":"WARN"`},
+		{"debug", FormatText, false, `msg="disk low" free_mb=12`},
+		{"error", FormatText, false, ""},
+		{"verbose", FormatJSON, true, ""},
+		{"info", "xml", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level+"/"+tt.format, func(t *testing.T) {
+			var out bytes.Buffer
+			logger, err := New(tt.level, tt.format, &out)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New(%q, %q) error = %v, want error %t", tt.level, tt.format, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			logger.Warn("disk low", "free_mb", 12)
+			if tt.want == "" {
+				if out.Len() != 0 {
+					t.Errorf("warn written at level %s: %s", tt.level, out.String())
+				}
+				return
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("output %s does not contain %s", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	t.Cleanup(func() { level.Set(0) })
+
+	var out bytes.Buffer
+	logger, err := New("info", FormatJSON, &out)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	logger.Debug("hidden")
+	if err := SetLevel("debug"); err != nil {
+		t.Fatalf("SetLevel: %v", err)
+	}
+	logger.Debug("shown")
+	if err := SetLevel("loud"); err == nil {
+		t.Error("SetLevel(loud): want error")
+	}
+	logger.Debug("still shown")
+
+	if got := out.String(); strings.Contains(got, "hidden") || !strings.Contains(got, `"shown"`) || !strings.Contains(got, "still shown") {
+		t.Errorf("output = %s, want only the entries after SetLevel(debug)", got)
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	var out bytes.Buffer
+	logger, err := New("info", FormatJSON, &out)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	ctx := WithContext(context.Background(), logger.With(KeyRequestID, "req-1"))
+	FromContext(ctx).Info("transfer completed", Duration(1500*time.Microsecond))
+
+	for _, want := range []string{`"request_id":"req-1"`, `"duration_ms":1.5`} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output %s does not contain %s", out.String(), want)
+		}
+	}
+	if FromContext(context.Background()) == nil {
+		t.Error("FromContext without a logger returned nil")
+	}
+}
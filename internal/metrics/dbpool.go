@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"database/sql"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// dbPoolCollector собирает статистику пулов подключений (sql.DBStats) в момент опроса /metrics.
+// Каждый пул помечается меткой role (например, "primary" или "replica").
+type dbPoolCollector struct {
+	mu    sync.Mutex
+	pools map[string]func() sql.DBStats
+
+	maxOpen      *prometheus.Desc
+	open         *prometheus.Desc
+	inUse        *prometheus.Desc
+	idle         *prometheus.Desc
+	waitCount    *prometheus.Desc
+	waitDuration *prometheus.Desc
+}
+
+var dbPools = newDBPoolCollector()
+
+func init() {
+	prometheus.MustRegister(dbPools)
+}
+
+// newDBPoolCollector создает коллектор статистики пулов без зарегистрированных пулов.
+func newDBPoolCollector() *dbPoolCollector {
+	labels := []string{"role"}
+	return &dbPoolCollector{
+		pools:        make(map[string]func() sql.DBStats),
+		maxOpen:      prometheus.NewDesc("payment_db_pool_max_open_connections", "Maximum number of open connections to the database.", labels, nil),
+		open:         prometheus.NewDesc("payment_db_pool_open_connections", "Number of established connections, both in use and idle.", labels, nil),
+		inUse:        prometheus.NewDesc("payment_db_pool_in_use_connections", "Number of connections currently in use.", labels, nil),
+		idle:         prometheus.NewDesc("payment_db_pool_idle_connections", "Number of idle connections.", labels, nil),
+		waitCount:    prometheus.NewDesc("payment_db_pool_wait_count_total", "Total number of connections waited for.", labels, nil),
+		waitDuration: prometheus.NewDesc("payment_db_pool_wait_duration_seconds_total", "Total time blocked waiting for a new connection.", labels, nil),
+	}
+}
+
+// RegisterDBPool добавляет пул подключений в метрики под указанной ролью.
+// Повторная регистрация той же роли заменяет источник статистики.
+//
+// Параметры:
+//   - role: Роль пула ("primary", "replica").
+//   - stats: Функция, возвращающая текущую статистику пула (обычно db.Stats).
+//
+// Пример использования:
+//
+//	metrics.RegisterDBPool("primary", db.Stats)
+func RegisterDBPool(role string, stats func() sql.DBStats) {
+	dbPools.mu.Lock()
+	defer dbPools.mu.Unlock()
+	dbPools.pools[role] = stats
+}
+
+// Describe передает описания метрик в Prometheus.
+func (c *dbPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxOpen
+	ch <- c.open
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.waitCount
+	ch <- c.waitDuration
+}
+
+// Collect читает статистику всех зарегистрированных пулов.
+func (c *dbPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for role, stats := range c.pools {
+		s := stats()
+		ch <- prometheus.MustNewConstMetric(c.maxOpen, prometheus.GaugeValue, float64(s.MaxOpenConnections), role)
+		ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, float64(s.OpenConnections), role)
+		ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(s.InUse), role)
+		ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(s.Idle), role)
+		ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(s.WaitCount), role)
+		ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, s.WaitDuration.Seconds(), role)
+	}
+}
+
+// DBPoolStats возвращает текущую статистику всех зарегистрированных пулов по ролям.
+//
+// Пример использования:
+//
+//	for role, s := range metrics.DBPoolStats() {
+//		log.Printf("%s: %d подключений", role, s.OpenConnections)
+//	}
+func DBPoolStats() map[string]sql.DBStats {
+	dbPools.mu.Lock()
+	defer dbPools.mu.Unlock()
+
+	stats := make(map[string]sql.DBStats, len(dbPools.pools))
+	for role, s := range dbPools.pools {
+		stats[role] = s()
+	}
+	return stats
+}
@@ -0,0 +1,107 @@
+// Package metrics содержит метрики приложения в формате Prometheus.
+// Все метрики регистрируются в стандартном реестре и отдаются обработчиком Handler.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Состояния автомата отключения (circuit breaker) в значениях метрики DBBreakerState.
+const (
+	BreakerClosed   = 0
+	BreakerHalfOpen = 1
+	BreakerOpen     = 2
+)
+
+var (
+	// DBBreakerState - текущее состояние автомата отключения базы данных
+	// (0 - закрыт, 1 - пробный режим, 2 - открыт).
+	DBBreakerState = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "payment_db_breaker_state",
+		Help: "Current database circuit breaker state (0 closed, 1 half-open, 2 open).",
+	})
+
+	// DBBreakerTransitions - количество переходов автомата отключения по целевому состоянию.
+	DBBreakerTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "payment_db_breaker_transitions_total",
+		Help: "Database circuit breaker state transitions by target state.",
+	}, []string{"state"})
+
+	// DBUp - результат последней фоновой проверки доступности базы (1 - доступна, 0 - нет);
+	// обновляется, только если включена DB_HEALTH_CHECK_INTERVAL.
+	DBUp = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "payment_db_up",
+		Help: "Result of the last background database health check (1 up, 0 down).",
+	})
+
+	// BalanceCacheRequests - обращения к кэшу балансов по результату:
+	// "hit" - баланс взят из кэша, "miss" - прочитан из базы, "error" - кэш недоступен.
+	BalanceCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "payment_balance_cache_requests_total",
+		Help: "Balance cache lookups by result (hit, miss, error).",
+	}, []string{"result"})
+
+	// TransactionsCacheRequests - обращения к кэшу списка последних транзакций
+	// по результату ("hit" или "miss").
+	TransactionsCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "payment_transactions_cache_requests_total",
+		Help: "Last transactions cache lookups by result (hit, miss).",
+	}, []string{"result"})
+
+	// RiskDecisions - срабатывания правил проверки переводов по правилу и действию
+	// ("flag" - перевод выполнен и записан, "block" - перевод отклонен).
+	RiskDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "payment_risk_decisions_total",
+		Help: "Triggered transfer risk rules by rule and action (flag, block).",
+	}, []string{"rule", "action"})
+
+	// NotificationAttempts - попытки доставки уведомлений о поступлении средств по результату
+	// ("sent", "retry" - попытка будет повторена, "failed" - попытки исчерпаны).
+	NotificationAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "payment_notification_attempts_total",
+		Help: "Received funds notification delivery attempts by result (sent, retry, failed).",
+	}, []string{"result"})
+
+	// HTTPRequestDuration - длительность обработки HTTP-запросов по методу,
+	// шаблону маршрута и коду ответа.
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "payment_http_request_duration_seconds",
+		Help:    "HTTP request latency by method, route template and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// HTTPInFlightRequests - запросы, выполняемые сейчас, по группе маршрутов
+	// с ограничением одновременных запросов (например, "send").
+	HTTPInFlightRequests = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "payment_http_in_flight_requests",
+		Help: "HTTP requests currently in flight by concurrency-limited route group.",
+	}, []string{"group"})
+
+	// HTTPRejectedRequests - запросы, отклоненные с 503 из-за превышения ограничения
+	// одновременных запросов группы маршрутов.
+	HTTPRejectedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "payment_http_rejected_requests_total",
+		Help: "HTTP requests shed with 503 by concurrency-limited route group.",
+	}, []string{"group"})
+
+	// BuildInfo - сведения о запущенной сборке в метках; значение всегда 1. Версия не добавляется
+	// меткой к каждой метрике (это умножило бы число рядов при каждом развертывании): ее
+	// присоединяет запрос, например ... * on(instance) group_left(version) payment_build_info.
+	BuildInfo = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "payment_build_info",
+		Help: "Build information (version, commit, Go version, database driver); always 1.",
+	}, []string{"version", "commit", "go_version", "db_driver"})
+)
+
+// Handler возвращает HTTP-обработчик, отдающий все зарегистрированные метрики.
+//
+// Пример использования:
+//
+//	router.Handle("/metrics", metrics.Handler())
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
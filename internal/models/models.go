@@ -2,9 +2,44 @@ package models
 
 import (
 	"fmt"
+	"net/mail"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
+)
+
+// MaxMemoLength - максимальная длина комментария к транзакции в символах.
+const MaxMemoLength = 256
+
+// MaxCategoryLength - максимальная длина категории транзакции в символах.
+const MaxCategoryLength = 64
+
+// Типы транзакций (Transaction.Type). Перевод между кошельками имеет тип TransactionTransfer;
+// остальные типы - операции с системными счетами (см. db.SystemAccount), которые выполняет
+// только db.Repository.SystemTransfer.
+const (
+	TransactionTransfer      = "transfer"       // Перевод между кошельками
+	TransactionMint          = "mint"           // Выпуск: средства зачисляются со счета эмиссии
+	TransactionBurn          = "burn"           // Изъятие: средства списываются на счет эмиссии
+	TransactionFee           = "fee"            // Комиссия: списание на счет комиссий
+	TransactionEscrowHold    = "escrow_hold"    // Удержание: списание на счет депонирования
+	TransactionEscrowRelease = "escrow_release" // Возврат удержания: зачисление со счета депонирования
 )
 
+// ValidateTransactionType проверяет, что тип транзакции - один из известных типов;
+// пустая строка означает TransactionTransfer.
+func ValidateTransactionType(txType string) error {
+	switch txType {
+	case "", TransactionTransfer, TransactionMint, TransactionBurn, TransactionFee,
+		TransactionEscrowHold, TransactionEscrowRelease:
+		return nil
+	}
+	return fmt.Errorf("неизвестный тип транзакции %q", txType)
+}
+
 // Transaction представляет собой модель транзакции между двумя кошельками.
 // Транзакция включает информацию об отправителе, получателе, сумме перевода и времени создания.
 type Transaction struct {
@@ -22,11 +57,31 @@ type Transaction struct {
 
 	// Amount - сумма перевода.
 	// Это поле обязательно для заполнения и должно быть положительным числом.
-	Amount float64 `json:"amount" db:"amount"`
+	Amount decimal.Decimal `json:"amount" db:"amount"`
 
 	// CreatedAt - время создания транзакции.
 	// Это поле автоматически устанавливается в текущее время при создании записи в базе данных.
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
+
+	// Memo - необязательный комментарий к переводу (например, номер счета для сверки).
+	// Ограничен MaxMemoLength символами и не может содержать управляющие символы.
+	Memo string `json:"memo,omitempty" db:"memo"`
+
+	// Category - необязательная категория перевода для отчетов (например, "salary", "refund", "fee").
+	// Ограничена MaxCategoryLength символами; допустимые значения может задавать TRANSACTION_CATEGORIES.
+	Category string `json:"category,omitempty" db:"category"`
+
+	// ExternalID - идентификатор транзакции во внешней системе, из которой она импортирована.
+	// Уникален: повторный импорт транзакции с тем же ExternalID пропускается.
+	ExternalID string `json:"external_id,omitempty" db:"external_id"`
+
+	// Imported - признак исторической транзакции, загруженной импортом.
+	// Такие транзакции не изменяли балансы кошельков в этой системе.
+	Imported bool `json:"imported,omitempty" db:"imported"`
+
+	// Type - тип транзакции (TransactionTransfer и др.). При импорте пустой тип означает
+	// TransactionTransfer.
+	Type string `json:"type" db:"type"`
 }
 
 // Validate проверяет, что транзакция содержит корректные данные.
@@ -38,8 +93,360 @@ func (t *Transaction) Validate() error {
 	if t.To == "" {
 		return fmt.Errorf("поле 'To' обязательно для заполнения")
 	}
-	if t.Amount <= 0 {
+	if t.Amount.Sign() <= 0 {
 		return fmt.Errorf("поле 'Amount' должно быть положительным числом")
 	}
+	if err := ValidateAmountScale(t.Amount); err != nil {
+		return err
+	}
+	if err := ValidateMemo(t.Memo); err != nil {
+		return err
+	}
+	if err := ValidateCategory(t.Category); err != nil {
+		return err
+	}
+	if err := ValidateTransactionType(t.Type); err != nil {
+		return err
+	}
+	return nil
+}
+
+// AmountScale - количество знаков после запятой, с которым хранятся суммы и балансы
+// (NUMERIC(38, 8) в PostgreSQL).
+const AmountScale = 8
+
+// ValidateAmountScale проверяет, что в сумме не больше AmountScale знаков после запятой:
+// иначе база округлила бы сумму при записи и списанное не совпало бы с проверенным.
+func ValidateAmountScale(amount decimal.Decimal) error {
+	if !amount.Equal(amount.Truncate(AmountScale)) {
+		return fmt.Errorf("сумма %s содержит больше %d знаков после запятой", amount, AmountScale)
+	}
+	return nil
+}
+
+// ValidateMemo проверяет, что комментарий не длиннее MaxMemoLength символов,
+// является корректной строкой UTF-8 и не содержит управляющих символов.
+func ValidateMemo(memo string) error {
+	return validateText("Memo", memo, MaxMemoLength)
+}
+
+// ValidateCategory проверяет категорию по тем же правилам, что и комментарий,
+// с ограничением длины MaxCategoryLength.
+func ValidateCategory(category string) error {
+	return validateText("Category", category, MaxCategoryLength)
+}
+
+// MaxLabelLength - максимальная длина метки кошелька в символах.
+const MaxLabelLength = 64
+
+// MaxWalletTags - максимальное количество тегов кошелька.
+const MaxWalletTags = 32
+
+// MaxTagLength - максимальная длина ключа и значения тега в символах.
+const MaxTagLength = 256
+
+// MaxEmailLength - максимальная длина адреса электронной почты для уведомлений.
+const MaxEmailLength = 254
+
+// WalletMetadata - описание кошелька для операторов: метка и произвольные теги, а также
+// контакт владельца для уведомлений. Метаданные не участвуют в переводах и проверке адресов.
+type WalletMetadata struct {
+	// Label - уникальное человекочитаемое имя кошелька (например, "ops-float").
+	// Пустая строка означает, что метки нет.
+	Label string `json:"label,omitempty" db:"label"`
+
+	// Tags - произвольные пары ключ-значение (например, "currency": "EUR").
+	Tags map[string]string `json:"tags,omitempty" db:"tags"`
+
+	// NotifyEmail - адрес электронной почты, на который владелец получает уведомления
+	// о поступлении средств. Пустая строка - владелец уведомления не получает.
+	NotifyEmail string `json:"notify_email,omitempty" db:"notify_email"`
+}
+
+// Wallet представляет кошелек вместе с балансом и метаданными.
+type Wallet struct {
+	// Address - адрес кошелька (по умолчанию 64 шестнадцатеричных символа, см. ADDRESS_BYTES).
+	Address string `json:"address" db:"address"`
+
+	// Balance - текущий баланс кошелька.
+	Balance decimal.Decimal `json:"balance" db:"balance"`
+
+	WalletMetadata
+
+	// PublicKey - открытый ключ ed25519 в шестнадцатеричном виде, которым проверяются
+	// подписи переводов с кошелька. Пустая строка - кошелек создан без ключа.
+	PublicKey string `json:"public_key,omitempty" db:"public_key"`
+
+	// ArchivedAt - время архивации кошелька (nil - кошелек активен). Архивный кошелек
+	// не участвует в переводах, но остается в истории транзакций.
+	ArchivedAt *time.Time `json:"archived_at,omitempty" db:"archived_at"`
+}
+
+// WalletMetadataPatch - частичное изменение метаданных кошелька.
+// Поля со значением nil не изменяются; пустая метка удаляет метку,
+// пустой объект тегов удаляет все теги, пустой адрес отключает уведомления.
+type WalletMetadataPatch struct {
+	Label       *string           `json:"label"`
+	Tags        map[string]string `json:"tags"`
+	NotifyEmail *string           `json:"notify_email"`
+}
+
+// Validate проверяет метаданные: метку (см. ValidateLabel), теги и адрес для уведомлений
+// (см. ValidateEmail). Возвращает ошибку, если какое-либо из полей не соответствует требованиям.
+func (m *WalletMetadata) Validate() error {
+	if err := ValidateLabel(m.Label); err != nil {
+		return err
+	}
+	if err := ValidateEmail(m.NotifyEmail); err != nil {
+		return err
+	}
+	return validateTags(m.Tags)
+}
+
+// Validate проверяет изменяемые поля метаданных.
+func (p *WalletMetadataPatch) Validate() error {
+	if p.Label != nil {
+		if err := ValidateLabel(*p.Label); err != nil {
+			return err
+		}
+	}
+	if p.NotifyEmail != nil {
+		if err := ValidateEmail(*p.NotifyEmail); err != nil {
+			return err
+		}
+	}
+	return validateTags(p.Tags)
+}
+
+// ValidateEmail проверяет адрес для уведомлений: пустой или один адрес электронной почты
+// без имени ("alice@example.com", а не "Alice <alice@example.com>") не длиннее MaxEmailLength.
+func ValidateEmail(email string) error {
+	if email == "" {
+		return nil
+	}
+	if err := validateText("NotifyEmail", email, MaxEmailLength); err != nil {
+		return err
+	}
+	if parsed, err := mail.ParseAddress(email); err != nil || parsed.Name != "" || parsed.Address != email {
+		return fmt.Errorf("поле 'NotifyEmail' должно быть адресом электронной почты, например alice@example.com")
+	}
+	return nil
+}
+
+// ValidateLabel проверяет, что метка не длиннее MaxLabelLength символов,
+// является корректной строкой UTF-8 и не содержит управляющих символов.
+// Метка - произвольная строка и не проверяется как адрес кошелька.
+func ValidateLabel(label string) error {
+	if err := validateText("Label", label, MaxLabelLength); err != nil {
+		return err
+	}
+	if label != "" && strings.TrimSpace(label) == "" {
+		return fmt.Errorf("поле 'Label' не может состоять из одних пробелов")
+	}
+	return nil
+}
+
+// validateTags проверяет количество тегов и длину их ключей и значений.
+func validateTags(tags map[string]string) error {
+	if len(tags) > MaxWalletTags {
+		return fmt.Errorf("поле 'Tags' не должно содержать больше %d тегов", MaxWalletTags)
+	}
+	for key, value := range tags {
+		if key == "" {
+			return fmt.Errorf("ключ тега не может быть пустым")
+		}
+		if err := validateText("Tags", key, MaxTagLength); err != nil {
+			return err
+		}
+		if err := validateText("Tags", value, MaxTagLength); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateText проверяет, что строка не длиннее maxLength символов,
+// является корректной строкой UTF-8 и не содержит управляющих символов.
+func validateText(field, value string, maxLength int) error {
+	if !utf8.ValidString(value) {
+		return fmt.Errorf("поле '%s' должно быть корректной строкой UTF-8", field)
+	}
+	if utf8.RuneCountInString(value) > maxLength {
+		return fmt.Errorf("поле '%s' не должно превышать %d символов", field, maxLength)
+	}
+	for _, r := range value {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("поле '%s' не должно содержать управляющие символы", field)
+		}
+	}
 	return nil
 }
+
+// RiskEvent - срабатывание правила проверки переводов на мошенничество (пакет risk).
+// Записывается и для помеченных ("flag"), и для заблокированных ("block") переводов.
+type RiskEvent struct {
+	// ID - уникальный идентификатор события, назначаемый хранилищем.
+	ID int `json:"id" db:"id"`
+
+	// Rule - сработавшее правило ("velocity" или "amount").
+	Rule string `json:"rule" db:"rule"`
+
+	// Action - результат правила: "flag" - перевод выполнен, "block" - отклонен.
+	Action string `json:"action" db:"action"`
+
+	// From, To и Amount - отправитель, получатель и сумма проверенного перевода.
+	From   string          `json:"from" db:"from_address"`
+	To     string          `json:"to" db:"to_address"`
+	Amount decimal.Decimal `json:"amount" db:"amount"`
+
+	// Reason - описание срабатывания для оператора (например, "6 transfers in 10m0s, limit 5").
+	Reason string `json:"reason" db:"reason"`
+
+	// CreatedAt - время проверки перевода (UTC).
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Статусы перевода, ожидающего подтверждения администратором (Approval.Status).
+const (
+	ApprovalAwaitingReview = "awaiting_review" // Ожидает решения администратора
+	ApprovalApproved       = "approved"        // Подтвержден, перевод выполняется
+	ApprovalExecuted       = "executed"        // Подтвержден и выполнен
+	ApprovalFailed         = "failed"          // Подтвержден, но перевод не удался (причина в Reason)
+	ApprovalRejected       = "rejected"        // Отклонен администратором (причина в Reason)
+	ApprovalExpired        = "expired"         // Не рассмотрен вовремя
+)
+
+// Статусы уведомления о поступлении средств (Notification.Status).
+const (
+	NotificationPending = "pending" // Ожидает доставки или повторной попытки
+	NotificationSent    = "sent"    // Доставлено
+	NotificationFailed  = "failed"  // Попытки доставки исчерпаны (ошибка в LastError)
+)
+
+// Notification - уведомление получателя перевода о поступлении средств. Уведомление ставится
+// в очередь в транзакции перевода и доставляется фоновой задачей, поэтому медленная или
+// недоступная почта не задерживает переводы.
+type Notification struct {
+	// ID - идентификатор уведомления, назначаемый хранилищем.
+	ID int64 `json:"id" db:"id"`
+
+	// TransactionID - транзакция, о которой сообщает уведомление.
+	TransactionID int `json:"transaction_id" db:"transaction_id"`
+
+	// Address и Email - кошелек получателя и адрес, на который отправляется уведомление.
+	Address string `json:"address" db:"address"`
+	Email   string `json:"email" db:"email"`
+
+	// From и FromLabel - адрес отправителя и его метка на момент перевода (пустая - метки нет).
+	From      string `json:"from" db:"from_address"`
+	FromLabel string `json:"from_label,omitempty" db:"from_label"`
+
+	// Amount - сумма перевода.
+	Amount decimal.Decimal `json:"amount" db:"amount"`
+
+	// TransferredAt - время перевода (UTC).
+	TransferredAt time.Time `json:"transferred_at" db:"transferred_at"`
+
+	// Status - состояние доставки (NotificationPending и др.).
+	Status string `json:"status" db:"status"`
+
+	// Attempts - количество начатых попыток доставки.
+	Attempts int `json:"attempts" db:"attempts"`
+
+	// LastError - ошибка последней неудачной попытки.
+	LastError string `json:"last_error,omitempty" db:"last_error"`
+
+	// NextAttemptAt - время, не раньше которого выполняется следующая попытка (UTC).
+	NextAttemptAt time.Time `json:"next_attempt_at" db:"next_attempt_at"`
+
+	// CompletedAt - время доставки или последней неудачной попытки (UTC); nil, пока уведомление
+	// ожидает доставки.
+	CompletedAt *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+}
+
+// Approval - крупный перевод, отложенный до решения администратора.
+// Перевод не выполняется, пока администратор его не подтвердит.
+type Approval struct {
+	// ID - идентификатор, по которому отправитель узнает результат (GET /api/send/status/{id}).
+	ID int64 `json:"id" db:"id"`
+
+	// From, To, Amount, Memo и Category - параметры перевода с разрешенными адресами.
+	From     string          `json:"from" db:"from_address"`
+	To       string          `json:"to" db:"to_address"`
+	Amount   decimal.Decimal `json:"amount" db:"amount"`
+	Memo     string          `json:"memo,omitempty" db:"memo"`
+	Category string          `json:"category,omitempty" db:"category"`
+
+	// Nonce - номер подписанного перевода; 0, если перевод не подписан.
+	Nonce int64 `json:"nonce,omitempty" db:"nonce"`
+
+	// Status - состояние (ApprovalAwaitingReview и др.).
+	Status string `json:"status" db:"status"`
+
+	// Reason - причина отклонения или ошибка выполнения перевода.
+	Reason string `json:"reason,omitempty" db:"reason"`
+
+	// CreatedAt - время постановки перевода в очередь (UTC).
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+
+	// DecidedAt - время последнего изменения статуса (UTC); nil, пока перевод ожидает решения.
+	DecidedAt *time.Time `json:"decided_at,omitempty" db:"decided_at"`
+}
+
+// Balance - баланс кошелька с учетом зарезервированных средств.
+type Balance struct {
+	// Total - все средства кошелька.
+	Total decimal.Decimal `json:"total"`
+
+	// Reserved - средства, зарезервированные переводами, которые ожидают подтверждения
+	// администратором (Approval со статусом ApprovalAwaitingReview).
+	Reserved decimal.Decimal `json:"reserved"`
+
+	// Available - средства, доступные для новых переводов: Total - Reserved, но не меньше нуля.
+	Available decimal.Decimal `json:"available"`
+}
+
+// Действия, записываемые в журнал аудита (AuditEvent.Action).
+const (
+	AuditWalletAnonymized = "wallet.anonymized" // Удалены персональные данные кошелька и его переводов
+	AuditWalletRotated    = "wallet.rotated"    // Адрес кошелька заменен новым (см. Service.RotateWallet)
+	AuditConfigReloaded   = "config.reloaded"   // Настройки перечитаны без перезапуска (CONFIG_FILE)
+)
+
+// AuditEvent - запись журнала аудита о действии администратора.
+type AuditEvent struct {
+	// ID - уникальный идентификатор записи, назначаемый хранилищем.
+	ID int64 `json:"id" db:"id"`
+
+	// Action - действие (AuditWalletAnonymized и др.).
+	Action string `json:"action" db:"action"`
+
+	// Target - объект действия, например адрес кошелька.
+	Target string `json:"target" db:"target"`
+
+	// Details - подробности для оператора (например, количество измененных записей).
+	Details string `json:"details,omitempty" db:"details"`
+
+	// CreatedAt - время действия (UTC).
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// AnonymizeReport - результат анонимизации кошелька: количество записей, из которых
+// удалены (или при DryRun были бы удалены) персональные данные.
+type AnonymizeReport struct {
+	// DryRun - true, если данные только подсчитаны и не изменены.
+	DryRun bool `json:"dry_run"`
+
+	// Wallets - 1, если у кошелька были метка, теги или адрес для уведомлений, иначе 0.
+	Wallets int `json:"wallets"`
+
+	// Transactions - транзакции кошелька с комментарием, включая перенесенные в архив.
+	Transactions int `json:"transactions"`
+
+	// Approvals - отложенные переводы кошелька с комментарием.
+	Approvals int `json:"approvals"`
+
+	// Notifications - уведомления, в которых очищен адрес получателя (кошелька) или метка
+	// отправителя (кошелька); недоставленные уведомления кошельку после этого не отправляются.
+	Notifications int `json:"notifications"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateMemo(t *testing.T) {
+	tests := []struct {
+		name    string
+		memo    string
+		wantErr bool
+	}{
+		{"empty", "", false},
+		{"invoice", "invoice 42", false},
+		{"unicode", "счет №42 ✓", false},
+		{"max length in runes", strings.Repeat("я", MaxMemoLength), false},
+		{"too long", strings.Repeat("a", MaxMemoLength+1), true},
+		{"newline", "invoice\n42", true},
+		{"tab", "invoice\t42", true},
+		{"escape", "\x1b[31mred", true},
+		{"delete", "invoice\x7f", true},
+		{"c1 control", "invoice\u0085", true},
+		{"invalid utf-8", "invoice \xff", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMemo(tt.memo)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateMemo(%q) = %v, want error %t", tt.memo, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateCategory(t *testing.T) {
+	tests := []struct {
+		name     string
+		category string
+		wantErr  bool
+	}{
+		{"empty", "", false},
+		{"salary", "salary", false},
+		{"unicode", "зарплата", false},
+		{"max length in runes", strings.Repeat("я", MaxCategoryLength), false},
+		{"too long", strings.Repeat("a", MaxCategoryLength+1), true},
+		{"newline", "salary\nfee", true},
+		{"invalid utf-8", "salary \xff", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCategory(tt.category)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCategory(%q) = %v, want error %t", tt.category, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+// Package notify доставляет получателям переводов уведомления о поступлении средств.
+// Уведомления ставятся в очередь в транзакции перевода (db.TxRepository.EnqueueNotification)
+// и отправляются фоновой задачей (service.Service.RunNotifications) через Notifier,
+// поэтому недоступность почты не задерживает и не откатывает переводы.
+package notify
+
+import (
+	"context"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ReceivedFunds - событие поступления средств на кошелек, о котором сообщает уведомление.
+type ReceivedFunds struct {
+	TransactionID int             // Идентификатор транзакции
+	Address       string          // Кошелек получателя
+	Email         string          // Адрес, на который отправляется уведомление
+	From          string          // Адрес отправителя
+	FromLabel     string          // Метка отправителя на момент перевода (пустая - метки нет)
+	Amount        decimal.Decimal // Сумма перевода
+	Timestamp     time.Time       // Время перевода
+}
+
+// Sender возвращает отправителя для текста уведомления: метку, если она задана, иначе адрес.
+func (e ReceivedFunds) Sender() string {
+	if e.FromLabel != "" {
+		return e.FromLabel
+	}
+	return e.From
+}
+
+// Notifier доставляет уведомления о поступлении средств.
+type Notifier interface {
+	// Notify отправляет уведомление о событии event. Ошибка означает, что уведомление
+	// не доставлено и попытку можно повторить; отмена ctx прерывает отправку.
+	Notify(ctx context.Context, event ReceivedFunds) error
+}
+
+// Nop - Notifier, который ничего не отправляет; используется, если доставка не настроена
+// (SMTP_HOST не задан). Уведомления при этом отмечаются доставленными.
+type Nop struct{}
+
+// Notify ничего не делает и возвращает nil.
+func (Nop) Notify(ctx context.Context, event ReceivedFunds) error {
+	return nil
+}
+
+// subjectTemplate и bodyTemplate - тема и текст письма о поступлении средств.
+var (
+	subjectTemplate = template.Must(template.New("subject").Parse(`Received {{.Amount}} from {{.Sender}}`))
+
+	bodyTemplate = template.Must(template.New("body").Parse(`Your wallet {{.Address}} received {{.Amount}} from {{.Sender}}.
+
+Sender address: {{.From}}
+Transaction ID: {{.TransactionID}}
+Time: {{.Timestamp.UTC.Format "2006-01-02T15:04:05Z07:00"}}
+
+You receive this message because notifications are enabled for the wallet.
+To stop them, remove notify_email from the wallet metadata.
+`))
+)
+
+// Render возвращает тему и текст уведомления о событии event: сумму, отправителя (метку,
+// если задана) и время перевода в UTC.
+//
+// Параметры:
+//   - event: Событие поступления средств.
+//
+// Возвращает:
+//   - Тему письма.
+//   - Текст письма.
+//   - Ошибку выполнения шаблона.
+//
+// Пример использования:
+//
+//	subject, body, err := notify.Render(event)
+func Render(event ReceivedFunds) (string, string, error) {
+	var subject, body strings.Builder
+	if err := subjectTemplate.Execute(&subject, event); err != nil {
+		return "", "", err
+	}
+	if err := bodyTemplate.Execute(&body, event); err != nil {
+		return "", "", err
+	}
+	return subject.String(), body.String(), nil
+}
@@ -0,0 +1,134 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTPConfig - параметры почтового сервера, через который отправляются уведомления.
+type SMTPConfig struct {
+	Host     string // Адрес сервера
+	Port     int    // Порт сервера (обычно 587)
+	Username string // Имя пользователя; пустое - без аутентификации
+	Password string // Пароль пользователя
+	From     string // Адрес отправителя писем
+}
+
+// Validate проверяет параметры сервера.
+//
+// Возвращает:
+//   - Ошибку с описанием первого некорректного значения.
+func (c SMTPConfig) Validate() error {
+	if c.Host == "" {
+		return errors.New("smtp host must not be empty")
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		return fmt.Errorf("smtp port must be between 1 and 65535, got %d", c.Port)
+	}
+	if c.From == "" {
+		return errors.New("smtp from address must not be empty")
+	}
+	return nil
+}
+
+// SMTP отправляет уведомления письмами через почтовый сервер. Если сервер поддерживает
+// STARTTLS, соединение шифруется до аутентификации и отправки письма.
+type SMTP struct {
+	config SMTPConfig
+}
+
+// NewSMTP создает Notifier, отправляющий уведомления через почтовый сервер.
+//
+// Параметры:
+//   - config: Параметры почтового сервера.
+//
+// Возвращает:
+//   - Notifier.
+//   - Ошибку, если параметры некорректны.
+//
+// Пример использования:
+//
+//	notifier, err := notify.NewSMTP(notify.SMTPConfig{Host: "smtp.example.com", Port: 587, From: "noreply@example.com"})
+func NewSMTP(config SMTPConfig) (*SMTP, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &SMTP{config: config}, nil
+}
+
+// Notify отправляет письмо о поступлении средств на event.Email. Срок ctx ограничивает
+// весь обмен с сервером.
+func (s *SMTP) Notify(ctx context.Context, event ReceivedFunds) error {
+	subject, body, err := Render(event)
+	if err != nil {
+		return fmt.Errorf("failed to render notification: %w", err)
+	}
+
+	address := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to connect to smtp server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to connect to smtp server: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.config.Host}); err != nil {
+			return fmt.Errorf("failed to start tls: %w", err)
+		}
+	}
+	if s.config.Username != "" {
+		auth := smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+	if err := client.Mail(s.config.From); err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	if err := client.Rcpt(event.Email); err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	if _, err := w.Write(s.message(event.Email, subject, body)); err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	return client.Quit()
+}
+
+// message составляет письмо с заголовками; тема кодируется по RFC 2047, так как метка
+// отправителя может содержать не-ASCII символы.
+func (s *SMTP) message(to, subject, body string) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + s.config.From + "\r\n")
+	b.WriteString("To: " + to + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,223 @@
+// Package risk проверяет переводы правилами против мошенничества до их выполнения.
+// Правила сравнивают перевод с историей переводов отправителя; сработавшее правило
+// либо только записывается (flag), либо отклоняет перевод (block).
+package risk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	db "payment-system/internal/db"
+	"payment-system/internal/logging"
+	"payment-system/internal/metrics"
+	models "payment-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// Действия при срабатывании правила.
+const (
+	ActionAllow = "allow" // Правило выключено: перевод проходит без записи
+	ActionFlag  = "flag"  // Перевод выполняется, срабатывание записывается в risk_events
+	ActionBlock = "block" // Перевод отклоняется, срабатывание записывается в risk_events
+)
+
+// Названия правил в событиях и метриках.
+const (
+	RuleVelocity = "velocity" // Слишком много переводов с кошелька за окно
+	RuleAmount   = "amount"   // Перевод намного больше среднего перевода кошелька
+)
+
+// averageWindow - период, за который считается средний перевод для правила amount.
+const averageWindow = 30 * 24 * time.Hour
+
+// ErrBlocked - сентинел для errors.Is; конкретная ошибка - *BlockedError с правилом и причиной.
+var ErrBlocked = errors.New("transfer blocked by risk rules")
+
+// BlockedError возвращается, если перевод отклонен правилом с действием block.
+type BlockedError struct {
+	Rule   string // Название сработавшего правила
+	Reason string // Описание срабатывания
+}
+
+// Error возвращает описание ошибки с правилом и причиной.
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("transfer blocked by risk rule %s: %s", e.Rule, e.Reason)
+}
+
+// Is позволяет проверять ошибку через errors.Is(err, ErrBlocked).
+func (e *BlockedError) Is(target error) bool {
+	return target == ErrBlocked
+}
+
+// Config - пороги и действия правил. Нулевой порог выключает правило.
+type Config struct {
+	VelocityMaxTransfers int           // Сколько переводов с кошелька допустимо за VelocityWindow
+	VelocityWindow       time.Duration // Окно правила velocity
+	VelocityAction       string        // Действие правила velocity (flag или block)
+
+	AmountMultiplier float64 // Во сколько раз перевод может превышать средний за 30 дней
+	AmountAction     string  // Действие правила amount (flag или block)
+}
+
+// Enabled сообщает, включено ли хотя бы одно правило.
+func (c Config) Enabled() bool {
+	return c.VelocityMaxTransfers > 0 || c.AmountMultiplier > 0
+}
+
+// Validate проверяет пороги и действия включенных правил.
+//
+// Возвращает:
+//   - Ошибку с описанием первого некорректного значения.
+func (c Config) Validate() error {
+	if c.VelocityMaxTransfers < 0 {
+		return fmt.Errorf("velocity max transfers must not be negative, got %d", c.VelocityMaxTransfers)
+	}
+	if c.VelocityMaxTransfers > 0 && c.VelocityWindow <= 0 {
+		return fmt.Errorf("velocity window must be positive, got %s", c.VelocityWindow)
+	}
+	if err := validateAction(c.VelocityAction); err != nil {
+		return fmt.Errorf("velocity action: %w", err)
+	}
+	if c.AmountMultiplier < 0 {
+		return fmt.Errorf("amount multiplier must not be negative, got %g", c.AmountMultiplier)
+	}
+	if err := validateAction(c.AmountAction); err != nil {
+		return fmt.Errorf("amount action: %w", err)
+	}
+	return nil
+}
+
+// validateAction проверяет название действия.
+func validateAction(action string) error {
+	switch action {
+	case ActionAllow, ActionFlag, ActionBlock:
+		return nil
+	}
+	return fmt.Errorf("unknown action %q (want allow, flag or block)", action)
+}
+
+// Store - операции хранилища, которые нужны правилам; реализуется db.Repository.
+type Store interface {
+	GetSenderStats(ctx context.Context, address string, since time.Time) (db.SenderStats, error)
+	RecordRiskEvent(ctx context.Context, event models.RiskEvent) error
+}
+
+// Engine проверяет переводы правилами из Config.
+type Engine struct {
+	store Store
+	cfg   Config
+}
+
+// NewEngine создает проверку переводов с заданными правилами.
+//
+// Параметры:
+//   - store: Хранилище с историей переводов и журналом срабатываний.
+//   - cfg: Пороги и действия правил; должны пройти Validate.
+//
+// Возвращает:
+//   - Указатель на новый экземпляр Engine.
+//   - Ошибку, если конфигурация некорректна.
+//
+// Пример использования:
+//
+//	engine, err := risk.NewEngine(repo, risk.Config{VelocityMaxTransfers: 10, VelocityWindow: 10 * time.Minute, VelocityAction: risk.ActionBlock})
+//	svc.AddSendInterceptor(engine)
+func NewEngine(store Store, cfg Config) (*Engine, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &Engine{store: store, cfg: cfg}, nil
+}
+
+// decision - срабатывание одного правила.
+type decision struct {
+	rule   string
+	action string
+	reason string
+}
+
+// BeforeSend проверяет перевод до его выполнения. Каждое сработавшее правило записывается
+// в журнал; если хотя бы одно из них блокирующее, перевод отклоняется.
+//
+// Параметры:
+//   - ctx: Контекст запроса на перевод.
+//   - from: Адрес кошелька отправителя.
+//   - to: Адрес кошелька получателя.
+//   - amount: Сумма перевода.
+//
+// Возвращает:
+//   - *BlockedError (errors.Is(err, ErrBlocked)), если перевод отклонен правилом.
+//   - Ошибку хранилища, если историю переводов не удалось прочитать.
+//
+// Пример использования:
+//
+//	if err := engine.BeforeSend(ctx, from, to, decimal.RequireFromString("10.5")); errors.Is(err, risk.ErrBlocked) {
+//		// перевод отклонен
+//	}
+func (e *Engine) BeforeSend(ctx context.Context, from, to string, amount decimal.Decimal) error {
+	decisions, err := e.evaluate(ctx, from, amount)
+	if err != nil {
+		return err
+	}
+
+	var blocked *BlockedError
+	for _, d := range decisions {
+		metrics.RiskDecisions.WithLabelValues(d.rule, d.action).Inc()
+		event := models.RiskEvent{Rule: d.rule, Action: d.action, From: from, To: to, Amount: amount, Reason: d.reason}
+		if err := e.store.RecordRiskEvent(ctx, event); err != nil {
+			// Потеря записи в журнале не должна влиять на решение по переводу
+			slog.Error("failed to record risk event", "rule", d.rule, logging.KeyWallet, from, "error", err)
+		}
+		if d.action == ActionBlock && blocked == nil {
+			blocked = &BlockedError{Rule: d.rule, Reason: d.reason}
+		}
+	}
+	if blocked != nil {
+		return blocked
+	}
+	return nil
+}
+
+// evaluate применяет включенные правила и возвращает сработавшие.
+func (e *Engine) evaluate(ctx context.Context, from string, amount decimal.Decimal) ([]decision, error) {
+	var decisions []decision
+	now := time.Now()
+
+	if e.cfg.VelocityMaxTransfers > 0 && e.cfg.VelocityAction != ActionAllow {
+		stats, err := e.store.GetSenderStats(ctx, from, now.Add(-e.cfg.VelocityWindow))
+		if err != nil {
+			return nil, err
+		}
+		// Текущий перевод был бы (Count+1)-м в окне
+		if stats.Count >= e.cfg.VelocityMaxTransfers {
+			decisions = append(decisions, decision{
+				rule:   RuleVelocity,
+				action: e.cfg.VelocityAction,
+				reason: fmt.Sprintf("%d transfers in the last %s, limit %d", stats.Count+1, e.cfg.VelocityWindow, e.cfg.VelocityMaxTransfers),
+			})
+		}
+	}
+
+	if e.cfg.AmountMultiplier > 0 && e.cfg.AmountAction != ActionAllow {
+		stats, err := e.store.GetSenderStats(ctx, from, now.Add(-averageWindow))
+		if err != nil {
+			return nil, err
+		}
+		// Без истории среднего нет: первый перевод кошелька правилом не проверяется
+		if stats.Count > 0 {
+			average := stats.Total.Div(decimal.NewFromInt(int64(stats.Count))).Round(models.AmountScale)
+			if amount.GreaterThan(average.Mul(decimal.NewFromFloat(e.cfg.AmountMultiplier))) {
+				decisions = append(decisions, decision{
+					rule:   RuleAmount,
+					action: e.cfg.AmountAction,
+					reason: fmt.Sprintf("amount %s exceeds %g x 30-day average %s", amount, e.cfg.AmountMultiplier, average),
+				})
+			}
+		}
+	}
+	return decisions, nil
+}
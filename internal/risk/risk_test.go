@@ -0,0 +1,157 @@
+package risk
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	db "payment-system/internal/db"
+	models "payment-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// newRiskRepo создает хранилище с кошельками отправителя и получателя и выполняет с кошелька
+// отправителя переводы history (до проверки правилами).
+func newRiskRepo(t *testing.T, history ...string) (repo *db.MemoryRepository, from, to string) {
+	t.Helper()
+	ctx := context.Background()
+	repo = db.NewMemoryRepository()
+	for _, address := range []*string{&from, &to} {
+		var err error
+		if *address, err = db.GenerateAddress(); err != nil {
+			t.Fatalf("GenerateAddress: %v", err)
+		}
+		if err := repo.CreateWallet(ctx, *address, decimal.NewFromInt(1000), models.WalletMetadata{}, ""); err != nil {
+			t.Fatalf("CreateWallet: %v", err)
+		}
+	}
+	for _, amount := range history {
+		if _, err := repo.Send(ctx, from, to, decimal.RequireFromString(amount), "", "", 0, sql.LevelDefault); err != nil {
+			t.Fatalf("Send(%s): %v", amount, err)
+		}
+	}
+	return repo, from, to
+}
+
+// TestEngineRules проверяет каждое правило на границе: перевод, равный порогу, проходит без
+// записи, а следующий за порогом срабатывает с действием правила.
+func TestEngineRules(t *testing.T) {
+	velocity := Config{VelocityMaxTransfers: 3, VelocityWindow: 10 * time.Minute, VelocityAction: ActionBlock, AmountAction: ActionAllow}
+	amount := Config{VelocityAction: ActionAllow, AmountMultiplier: 2, AmountAction: ActionBlock}
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		history  []string // Переводы отправителя до проверяемого
+		amount   string
+		wantRule string // "" - правило не срабатывает
+	}{
+		{"velocity below limit", velocity, []string{"1", "1"}, "1", ""},
+		{"velocity at limit", velocity, []string{"1", "1", "1"}, "1", RuleVelocity},
+		{"amount at multiplier", amount, []string{"10", "20"}, "30", ""},
+		{"amount one unit above multiplier", amount, []string{"10", "20"}, "30.00000001", RuleAmount},
+		{"amount without history", amount, nil, "500", ""},
+		// Средний перевод 5/3 округляется до 1.66666667, порог - 3.33333334
+		{"amount at rounded average", amount, []string{"1", "2", "2"}, "3.33333334", ""},
+		{"amount above rounded average", amount, []string{"1", "2", "2"}, "3.33333335", RuleAmount},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo, from, to := newRiskRepo(t, tt.history...)
+			engine, err := NewEngine(repo, tt.cfg)
+			if err != nil {
+				t.Fatalf("NewEngine: %v", err)
+			}
+
+			err = engine.BeforeSend(ctx, from, to, decimal.RequireFromString(tt.amount))
+			events, eventsErr := repo.GetRiskEvents(ctx, 10)
+			if eventsErr != nil {
+				t.Fatalf("GetRiskEvents: %v", eventsErr)
+			}
+			if tt.wantRule == "" {
+				if err != nil {
+					t.Errorf("BeforeSend = %v, want nil", err)
+				}
+				if len(events) != 0 {
+					t.Errorf("recorded %+v, want no events", events)
+				}
+				return
+			}
+
+			var blocked *BlockedError
+			if !errors.As(err, &blocked) || !errors.Is(err, ErrBlocked) || blocked.Rule != tt.wantRule {
+				t.Fatalf("BeforeSend = %v, want BlockedError for rule %s", err, tt.wantRule)
+			}
+			if len(events) != 1 || events[0].Rule != tt.wantRule || events[0].Action != ActionBlock || events[0].From != from {
+				t.Errorf("recorded %+v, want one %s block event from the sender", events, tt.wantRule)
+			}
+		})
+	}
+}
+
+// TestEngineActions проверяет действия сработавшего правила: flag пропускает перевод
+// и записывает событие, allow выключает правило.
+func TestEngineActions(t *testing.T) {
+	tests := []struct {
+		action     string
+		wantErr    bool
+		wantEvents int
+	}{
+		{ActionAllow, false, 0},
+		{ActionFlag, false, 1},
+		{ActionBlock, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			ctx := context.Background()
+			repo, from, to := newRiskRepo(t, "1")
+			cfg := Config{VelocityMaxTransfers: 1, VelocityWindow: time.Minute, VelocityAction: tt.action, AmountAction: ActionAllow}
+			engine, err := NewEngine(repo, cfg)
+			if err != nil {
+				t.Fatalf("NewEngine: %v", err)
+			}
+
+			err = engine.BeforeSend(ctx, from, to, decimal.NewFromInt(1))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("BeforeSend = %v, want error %t", err, tt.wantErr)
+			}
+			events, err := repo.GetRiskEvents(ctx, 10)
+			if err != nil {
+				t.Fatalf("GetRiskEvents: %v", err)
+			}
+			if len(events) != tt.wantEvents {
+				t.Fatalf("recorded %d events, want %d", len(events), tt.wantEvents)
+			}
+			if tt.wantEvents > 0 && events[0].Action != tt.action {
+				t.Errorf("event action = %s, want %s", events[0].Action, tt.action)
+			}
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"disabled", Config{VelocityAction: ActionAllow, AmountAction: ActionAllow}, false},
+		{"velocity", Config{VelocityMaxTransfers: 5, VelocityWindow: time.Minute, VelocityAction: ActionFlag, AmountAction: ActionAllow}, false},
+		{"velocity without window", Config{VelocityMaxTransfers: 5, VelocityAction: ActionFlag, AmountAction: ActionAllow}, true},
+		{"negative velocity", Config{VelocityMaxTransfers: -1, VelocityAction: ActionFlag, AmountAction: ActionAllow}, true},
+		{"negative multiplier", Config{VelocityAction: ActionAllow, AmountMultiplier: -2, AmountAction: ActionBlock}, true},
+		{"unknown action", Config{VelocityAction: "deny", AmountAction: ActionAllow}, true},
+		{"empty action", Config{VelocityAction: ActionAllow}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	db "payment-system/internal/db"
+	models "payment-system/internal/models"
+
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// RequireApproval включает подтверждение крупных переводов: перевод на сумму больше threshold
+// не выполняется, а сохраняется со статусом models.ApprovalAwaitingReview до решения
+// администратора (ApproveTransfer или RejectTransfer). Вызывается до начала обработки запросов.
+//
+// Параметры:
+//   - threshold: Наибольшая сумма перевода, выполняемого без подтверждения.
+//
+// Пример использования:
+//
+//	svc.RequireApproval(decimal.NewFromInt(10000))
+func (s *Service) RequireApproval(threshold decimal.Decimal) {
+	s.approvalThreshold = threshold
+}
+
+// GetApproval возвращает отложенный перевод по ID.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - id: Идентификатор отложенного перевода.
+//
+// Возвращает:
+//   - Отложенный перевод.
+//   - Ошибку, оборачивающую db.ErrApprovalNotFound, если перевода нет.
+//
+// Пример использования:
+//
+//	approval, err := svc.GetApproval(ctx, 42)
+func (s *Service) GetApproval(ctx context.Context, id int64) (models.Approval, error) {
+	return s.repo.GetApproval(ctx, id)
+}
+
+// GetApprovals возвращает последние отложенные переводы, начиная с самого нового.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - status: Статус переводов (пустая строка - любой).
+//   - count: Количество переводов.
+//
+// Возвращает:
+//   - Список отложенных переводов.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+//
+// Пример использования:
+//
+//	approvals, err := svc.GetApprovals(ctx, models.ApprovalAwaitingReview, 50)
+func (s *Service) GetApprovals(ctx context.Context, status string, count int) ([]models.Approval, error) {
+	return s.repo.GetApprovals(ctx, status, count)
+}
+
+// ApproveTransfer подтверждает отложенный перевод и выполняет его, как Send, с проверкой баланса
+// и номера подписанного перевода. Подпись и правила SendInterceptor уже проверены при постановке
+// в очередь и повторно не применяются.
+//
+// Смена статуса на models.ApprovalApproved, перевод и смена статуса на models.ApprovalExecuted
+// выполняются в одной транзакции WithTx: перевод не может остаться подтвержденным, но
+// не выполненным, а из параллельных подтверждений выполняется ровно одно. Неудачный перевод
+// (например, средств уже недостаточно) откатывает транзакцию, после чего отложенный перевод
+// получает статус models.ApprovalFailed с описанием ошибки в Reason. При сбое базы, в том числе
+// если исход фиксации неизвестен (db.ErrOutcomeUnknown), статус не меняется: в базе остается
+// зафиксированный результат (models.ApprovalExecuted или models.ApprovalAwaitingReview),
+// и перевод, ожидающий решения, можно подтвердить снова.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - id: Идентификатор отложенного перевода.
+//
+// Возвращает:
+//   - Отложенный перевод со статусом models.ApprovalExecuted или models.ApprovalFailed.
+//   - Ошибку, оборачивающую db.ErrApprovalNotFound, если перевода нет, или db.ErrApprovalStatus,
+//     если он уже не ожидает решения; ошибку базы, если перевод не удалось выполнить или
+//     исход неизвестен.
+//
+// Пример использования:
+//
+//	approval, err := svc.ApproveTransfer(ctx, 42)
+func (s *Service) ApproveTransfer(ctx context.Context, id int64) (_ models.Approval, err error) {
+	ctx, span := startSpan(ctx, "ApproveTransfer", attribute.Int64("approval_id", id))
+	defer func() { endSpan(span, err) }()
+
+	// Подтвержденный перевод доводится до конечного статуса, даже если клиент отключился
+	ctx = context.WithoutCancel(ctx)
+
+	var approval models.Approval
+	var sendErr error
+	err = s.repo.WithTx(ctx, func(tx db.TxRepository) error {
+		// Транзакция может повторяться при конфликте; ошибка перевода - только последней попытки
+		sendErr = nil
+		var err error
+		if approval, err = tx.UpdateApprovalStatus(id, models.ApprovalAwaitingReview, models.ApprovalApproved, ""); err != nil {
+			return err
+		}
+		if _, sendErr = tx.Send(approval.From, approval.To, approval.Amount, approval.Memo, approval.Category, approval.Nonce); sendErr != nil {
+			return sendErr
+		}
+		approval, err = tx.UpdateApprovalStatus(id, models.ApprovalApproved, models.ApprovalExecuted, "")
+		return err
+	})
+	if sendErr != nil && errors.Is(err, sendErr) && transferRejected(sendErr) {
+		return s.repo.UpdateApprovalStatus(ctx, id, models.ApprovalAwaitingReview, models.ApprovalFailed, sendErr.Error())
+	}
+	if err != nil {
+		return models.Approval{}, err
+	}
+	if s.transactions != nil {
+		s.transactions.invalidate()
+	}
+	return approval, nil
+}
+
+// transferRejected сообщает, что перевод отклонен проверками (недостаточно средств, кошелек
+// архивирован и т.п.), а не сбоем базы: повторное подтверждение такого перевода не поможет.
+func transferRejected(err error) bool {
+	for _, target := range []error{
+		db.ErrInsufficientFunds, db.ErrBelowMinimumBalance, db.ErrNonceMismatch, db.ErrBalanceOverflow,
+		db.ErrWalletNotFound, db.ErrWalletArchived, db.ErrSystemAccount, db.ErrSelfTransfer,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// RejectTransfer отклоняет отложенный перевод; перевод не выполняется.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - id: Идентификатор отложенного перевода.
+//   - reason: Причина отклонения; сообщается отправителю в статусе перевода.
+//
+// Возвращает:
+//   - Отложенный перевод со статусом models.ApprovalRejected.
+//   - Ошибку, оборачивающую db.ErrApprovalNotFound, если перевода нет, или db.ErrApprovalStatus,
+//     если он уже не ожидает решения.
+//
+// Пример использования:
+//
+//	approval, err := svc.RejectTransfer(ctx, 42, "recipient failed KYC")
+func (s *Service) RejectTransfer(ctx context.Context, id int64, reason string) (models.Approval, error) {
+	return s.repo.UpdateApprovalStatus(ctx, id, models.ApprovalAwaitingReview, models.ApprovalRejected, reason)
+}
+
+// RunApprovalExpiry раз в interval переводит в статус models.ApprovalExpired переводы,
+// ожидающие решения дольше ttl. Блокирует до отмены ctx, поэтому запускается в отдельной горутине.
+// Ошибки записываются в лог; следующая проверка выполняется по расписанию.
+//
+// Параметры:
+//   - ctx: Контекст; отмена останавливает проверку.
+//   - interval: Период проверки.
+//   - ttl: Время, в течение которого перевод ожидает решения.
+//
+// Пример использования:
+//
+//	go svc.RunApprovalExpiry(ctx, time.Minute, 24*time.Hour)
+func (s *Service) RunApprovalExpiry(ctx context.Context, interval, ttl time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			expired, err := s.repo.ExpireApprovals(ctx, now.Add(-ttl))
+			if err != nil {
+				slog.Error("failed to expire pending approvals", "error", err)
+				continue
+			}
+			if expired > 0 {
+				slog.Info("pending approvals expired", "count", expired)
+			}
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	db "payment-system/internal/db"
+	models "payment-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// newApprovalService возвращает сервис на хранилище в памяти, откладывающий переводы больше 50,
+// и кошельки отправителя с балансом 100 и получателя.
+func newApprovalService(t *testing.T) (*Service, *db.MemoryRepository, string, string) {
+	t.Helper()
+	repo := db.NewMemoryRepository()
+	svc := NewService(repo)
+	svc.RequireApproval(decimal.NewFromInt(50))
+	from, _, err := svc.CreateWallet(context.Background(), decimal.NewFromInt(100), models.WalletMetadata{})
+	if err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+	to, _, err := svc.CreateWallet(context.Background(), decimal.Zero, models.WalletMetadata{})
+	if err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+	return svc, repo, from.Address, to.Address
+}
+
+// hold откладывает перевод amount и возвращает идентификатор отложенного перевода.
+func hold(t *testing.T, svc *Service, from, to, amount string) int64 {
+	t.Helper()
+	transfer, err := svc.Send(context.Background(), from, to, decimal.RequireFromString(amount), "", "", nil, sql.LevelDefault)
+	if err != nil {
+		t.Fatalf("Send(%s): %v", amount, err)
+	}
+	if transfer.ApprovalID == 0 {
+		t.Fatalf("Send(%s): transfer was not held for approval", amount)
+	}
+	return transfer.ApprovalID
+}
+
+func TestApproveTransfer(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name       string
+		amount     string
+		to         string // Получатель; пусто - существующий кошелек
+		wantStatus string
+		wantFrom   string // Баланс отправителя после подтверждения
+	}{
+		{name: "executed", amount: "60", wantStatus: models.ApprovalExecuted, wantFrom: "40"},
+		{name: "insufficient funds", amount: "150", wantStatus: models.ApprovalFailed, wantFrom: "100"},
+		// Списание с отправителя выполнено до ошибки получателя и откатывается вместе с ней
+		{name: "unknown receiver", amount: "60", to: strings.Repeat("c", 64), wantStatus: models.ApprovalFailed, wantFrom: "100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repo, from, to := newApprovalService(t)
+			if tt.to != "" {
+				to = tt.to
+			}
+			// Отложенный перевод создается в хранилище напрямую: Send отклонил бы его сразу
+			held, err := repo.CreateApproval(ctx, models.Approval{From: from, To: to, Amount: decimal.RequireFromString(tt.amount)})
+			if err != nil {
+				t.Fatalf("CreateApproval: %v", err)
+			}
+
+			approval, err := svc.ApproveTransfer(ctx, held.ID)
+			if err != nil {
+				t.Fatalf("ApproveTransfer: %v", err)
+			}
+			if approval.Status != tt.wantStatus {
+				t.Errorf("ApproveTransfer: got status %q (%s), want %q", approval.Status, approval.Reason, tt.wantStatus)
+			}
+			if tt.wantStatus == models.ApprovalFailed && approval.Reason == "" {
+				t.Error("ApproveTransfer: failed approval has no reason")
+			}
+			if stored, err := svc.GetApproval(ctx, held.ID); err != nil || stored.Status != tt.wantStatus {
+				t.Errorf("GetApproval: got %+v, %v, want status %q", stored, err, tt.wantStatus)
+			}
+			if balance, err := svc.GetBalance(ctx, from); err != nil || !balance.Equal(decimal.RequireFromString(tt.wantFrom)) {
+				t.Errorf("sender balance: got %s, %v, want %s", balance, err, tt.wantFrom)
+			}
+		})
+	}
+}
+
+func TestApproveTransferDecided(t *testing.T) {
+	ctx := context.Background()
+	svc, _, from, to := newApprovalService(t)
+	id := hold(t, svc, from, to, "60")
+	if _, err := svc.ApproveTransfer(ctx, id); err != nil {
+		t.Fatalf("ApproveTransfer: %v", err)
+	}
+
+	// Повторное подтверждение не выполняет перевод второй раз
+	if _, err := svc.ApproveTransfer(ctx, id); !errors.Is(err, db.ErrApprovalStatus) {
+		t.Fatalf("second ApproveTransfer: got %v, want ErrApprovalStatus", err)
+	}
+	if _, err := svc.RejectTransfer(ctx, id, "late"); !errors.Is(err, db.ErrApprovalStatus) {
+		t.Fatalf("RejectTransfer of executed approval: got %v, want ErrApprovalStatus", err)
+	}
+	if _, err := svc.ApproveTransfer(ctx, id+1); !errors.Is(err, db.ErrApprovalNotFound) {
+		t.Fatalf("ApproveTransfer unknown: got %v, want ErrApprovalNotFound", err)
+	}
+	if balance, err := svc.GetBalance(ctx, from); err != nil || !balance.Equal(decimal.NewFromInt(40)) {
+		t.Fatalf("sender balance: got %s, %v, want 40", balance, err)
+	}
+}
+
+// outcomeUnknownRepo - хранилище, у которого обрывается соединение во время фиксации WithTx:
+// транзакция фиксируется (committed) или откатывается, а вызывающий получает db.ErrOutcomeUnknown.
+type outcomeUnknownRepo struct {
+	*db.MemoryRepository
+	committed bool
+}
+
+var errLostCommit = errors.New("lost commit")
+
+func (r *outcomeUnknownRepo) WithTx(ctx context.Context, fn func(tx db.TxRepository) error) error {
+	err := r.MemoryRepository.WithTx(ctx, func(tx db.TxRepository) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		if !r.committed {
+			return errLostCommit
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errLostCommit) {
+		return err
+	}
+	return fmt.Errorf("%w: connection reset by peer", db.ErrOutcomeUnknown)
+}
+
+// TestApproveTransferOutcomeUnknown проверяет, что отложенный перевод, исход фиксации которого
+// неизвестен, не получает статус failed: в хранилище остается зафиксированный результат.
+func TestApproveTransferOutcomeUnknown(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name       string
+		committed  bool
+		wantStatus string
+		wantFrom   string
+	}{
+		{"committed", true, models.ApprovalExecuted, "40"},
+		{"rolled back", false, models.ApprovalAwaitingReview, "100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memory := db.NewMemoryRepository()
+			repo := &outcomeUnknownRepo{MemoryRepository: memory, committed: tt.committed}
+			svc := NewService(repo)
+			from, _, err := svc.CreateWallet(ctx, decimal.NewFromInt(100), models.WalletMetadata{})
+			if err != nil {
+				t.Fatalf("CreateWallet: %v", err)
+			}
+			to, _, err := svc.CreateWallet(ctx, decimal.Zero, models.WalletMetadata{})
+			if err != nil {
+				t.Fatalf("CreateWallet: %v", err)
+			}
+			held, err := memory.CreateApproval(ctx, models.Approval{From: from.Address, To: to.Address, Amount: decimal.NewFromInt(60)})
+			if err != nil {
+				t.Fatalf("CreateApproval: %v", err)
+			}
+
+			if _, err := svc.ApproveTransfer(ctx, held.ID); !errors.Is(err, db.ErrOutcomeUnknown) {
+				t.Fatalf("ApproveTransfer: got %v, want ErrOutcomeUnknown", err)
+			}
+			if stored, err := svc.GetApproval(ctx, held.ID); err != nil || stored.Status != tt.wantStatus {
+				t.Errorf("GetApproval: got %+v, %v, want status %q", stored, err, tt.wantStatus)
+			}
+			if balance, err := svc.GetBalance(ctx, from.Address); err != nil || !balance.Equal(decimal.RequireFromString(tt.wantFrom)) {
+				t.Errorf("sender balance: got %s, %v, want %s", balance, err, tt.wantFrom)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"payment-system/internal/logging"
+	models "payment-system/internal/models"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// AnonymizeWallet удаляет персональные данные кошелька по запросу владельца: метку, теги
+// и комментарии его транзакций и отложенных переводов. Суммы, балансы и сами транзакции
+// сохраняются. Выполненная анонимизация записывается в журнал аудита; если запись не удалась,
+// возвращается ошибка, и вызов можно повторить - уже очищенные записи повторно не изменяются.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//   - dryRun: true - только подсчитать записи, ничего не изменяя и не записывая в журнал.
+//
+// Возвращает:
+//   - Количество измененных (или подлежащих изменению) записей.
+//   - Ошибку, оборачивающую db.ErrWalletNotFound, если кошелька нет.
+//
+// Пример использования:
+//
+//	report, err := svc.AnonymizeWallet(ctx, "some_address", true)
+func (s *Service) AnonymizeWallet(ctx context.Context, address string, dryRun bool) (_ models.AnonymizeReport, err error) {
+	ctx, span := startSpan(ctx, "AnonymizeWallet", attribute.String(logging.KeyWallet, address), attribute.Bool("dry_run", dryRun))
+	defer func() { endSpan(span, err) }()
+
+	report, err := s.repo.AnonymizeWallet(ctx, address, dryRun)
+	if err != nil || dryRun {
+		return report, err
+	}
+	if s.transactions != nil {
+		s.transactions.invalidate()
+	}
+
+	event := models.AuditEvent{
+		Action:  models.AuditWalletAnonymized,
+		Target:  address,
+		Details: fmt.Sprintf("wallets=%d transactions=%d approvals=%d", report.Wallets, report.Transactions, report.Approvals),
+	}
+	// Данные уже удалены: запись в журнал не прерывается отменой запроса
+	if err := s.repo.RecordAuditEvent(context.WithoutCancel(ctx), event); err != nil {
+		return models.AnonymizeReport{}, err
+	}
+	return report, nil
+}
+
+// RecordConfigReload записывает в журнал аудита применение настроек без перезапуска.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - source: Откуда пришла команда перечитать настройки: "SIGHUP" или "api".
+//   - details: Примененные и отклоненные изменения, например "LOG_LEVEL=debug; ignored: PORT".
+//
+// Возвращает:
+//   - Ошибку, если запись не удалась.
+//
+// Пример использования:
+//
+//	err := svc.RecordConfigReload(ctx, "SIGHUP", "SEND_MAX_IN_FLIGHT=20")
+func (s *Service) RecordConfigReload(ctx context.Context, source, details string) error {
+	return s.repo.RecordAuditEvent(ctx, models.AuditEvent{
+		Action:  models.AuditConfigReloaded,
+		Target:  source,
+		Details: details,
+	})
+}
+
+// GetAuditEvents возвращает последние записи журнала аудита.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - count: Количество записей.
+//
+// Возвращает:
+//   - Список записей, начиная с самой новой.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+//
+// Пример использования:
+//
+//	events, err := svc.GetAuditEvents(ctx, 50)
+func (s *Service) GetAuditEvents(ctx context.Context, count int) ([]models.AuditEvent, error) {
+	return s.repo.GetAuditEvents(ctx, count)
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"payment-system/internal/metrics"
+	models "payment-system/internal/models"
+)
+
+// transactionsCacheEntry - закэшированный список транзакций и время, до которого он действителен.
+type transactionsCacheEntry struct {
+	transactions []models.Transaction
+	expires      time.Time
+}
+
+// transactionsCache - кэш списков последних транзакций без фильтров, по значению count.
+// Ограничен временем жизни записей и их количеством; сбрасывается после каждого
+// успешного перевода или импорта. Безопасен для одновременного использования.
+//
+// Кэш живет в памяти процесса, поэтому другие экземпляры сервиса о переводе не узнают:
+// их списки устаревают не дольше ttl.
+type transactionsCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu         sync.Mutex
+	entries    map[int]transactionsCacheEntry
+	generation uint64 // Увеличивается при каждом сбросе; устаревшие загрузки не сохраняются
+}
+
+// newTransactionsCache создает пустой кэш.
+func newTransactionsCache(ttl time.Duration, maxEntries int) *transactionsCache {
+	return &transactionsCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[int]transactionsCacheEntry),
+	}
+}
+
+// get возвращает копию закэшированного списка для count.
+//
+// Возвращает:
+//   - Список транзакций и true при попадании.
+//   - Поколение кэша, которое нужно передать в put после загрузки из базы.
+func (c *transactionsCache) get(count int) ([]models.Transaction, bool, uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[count]
+	if ok && time.Now().Before(entry.expires) {
+		metrics.TransactionsCacheRequests.WithLabelValues("hit").Inc()
+		return append([]models.Transaction(nil), entry.transactions...), true, c.generation
+	}
+	if ok {
+		delete(c.entries, count)
+	}
+	metrics.TransactionsCacheRequests.WithLabelValues("miss").Inc()
+	return nil, false, c.generation
+}
+
+// put сохраняет список, загруженный при поколении generation. Если за время загрузки
+// кэш был сброшен (прошел перевод), список мог устареть и не сохраняется.
+// При заполненном кэше сначала удаляются просроченные записи, а если их нет - произвольная.
+func (c *transactionsCache) put(count int, transactions []models.Transaction, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	if _, ok := c.entries[count]; !ok && len(c.entries) >= c.maxEntries {
+		c.evict()
+	}
+	c.entries[count] = transactionsCacheEntry{
+		transactions: append([]models.Transaction(nil), transactions...),
+		expires:      time.Now().Add(c.ttl),
+	}
+}
+
+// evict освобождает место для новой записи. Вызывается под c.mu.
+func (c *transactionsCache) evict() {
+	now := time.Now()
+	for count, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, count)
+		}
+	}
+	for count := range c.entries {
+		if len(c.entries) < c.maxEntries {
+			return
+		}
+		delete(c.entries, count)
+	}
+}
+
+// invalidate сбрасывает все записи после изменения списка транзакций.
+func (c *transactionsCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	clear(c.entries)
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"payment-system/internal/metrics"
+)
+
+// healthCheckTimeout ограничивает время одной фоновой проверки хранилища.
+const healthCheckTimeout = 2 * time.Second
+
+// healthStatus - результат последней фоновой проверки хранилища.
+type healthStatus struct {
+	err   error     // nil, если хранилище доступно
+	since time.Time // С какой проверки хранилище в этом состоянии
+}
+
+// RunHealthCheck раз в interval проверяет доступность хранилища (Ping) и запоминает результат:
+// пока проверка работает, Ready возвращает его, не обращаясь к базе при каждом запросе /readyz.
+// Недоступность и восстановление записываются в лог один раз при смене состояния, результат
+// публикуется в метрике payment_db_up. Проверка заодно обнаруживает устаревшие подключения
+// после перезапуска базы: пул закрывает их и открывает новые, не дожидаясь запроса клиента.
+// Блокирует до отмены ctx, поэтому запускается в отдельной горутине.
+//
+// Параметры:
+//   - ctx: Контекст; отмена останавливает проверку.
+//   - interval: Период проверки.
+//
+// Пример использования:
+//
+//	go svc.RunHealthCheck(ctx, 10*time.Second)
+func (s *Service) RunHealthCheck(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.checkHealth(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkHealth выполняет одну проверку хранилища и сохраняет ее результат.
+func (s *Service) checkHealth(ctx context.Context) {
+	pingCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	err := s.repo.Ping(pingCtx)
+	cancel()
+	if ctx.Err() != nil {
+		// Остановка сервера, а не сбой базы
+		return
+	}
+
+	previous := s.health.Load()
+	wasUp := previous == nil || previous.err == nil
+	status := &healthStatus{err: err, since: time.Now()}
+	if previous != nil && wasUp == (err == nil) {
+		status.since = previous.since
+	}
+	s.health.Store(status)
+
+	switch {
+	case err != nil:
+		metrics.DBUp.Set(0)
+		if wasUp {
+			slog.Error("database health check failed", "error", err)
+		}
+	default:
+		metrics.DBUp.Set(1)
+		if !wasUp {
+			slog.Info("database health check recovered", "down_since", previous.since)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	db "payment-system/internal/db"
+)
+
+// ReserveIdempotencyKey занимает ключ идемпотентности (заголовок Idempotency-Key) для
+// выполняющегося запроса. Ключи старше ttl считаются истекшими: запрос с таким ключом
+// выполняется заново.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - key: Ключ идемпотентности.
+//   - fingerprint: Отпечаток запроса; повтор ключа с другим отпечатком - ошибка клиента.
+//   - ttl: Срок хранения ответа.
+//
+// Возвращает:
+//   - Сохраненный ответ и false, если ключ уже занят (Status = 0 - запрос еще выполняется).
+//   - true, если ключ занят этим вызовом; ответ нужно сохранить CompleteIdempotencyKey
+//     или освободить ключ ReleaseIdempotencyKey.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+//
+// Пример использования:
+//
+//	stored, reserved, err := svc.ReserveIdempotencyKey(ctx, key, fingerprint, 24*time.Hour)
+func (s *Service) ReserveIdempotencyKey(ctx context.Context, key, fingerprint string, ttl time.Duration) (db.IdempotentResponse, bool, error) {
+	return s.repo.ReserveIdempotencyKey(ctx, key, fingerprint, time.Now().Add(-ttl))
+}
+
+// CompleteIdempotencyKey сохраняет ответ на запрос с занятым ключом идемпотентности.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - key: Ключ, занятый ReserveIdempotencyKey.
+//   - response: Ответ.
+//
+// Возвращает:
+//   - Ошибку, если ответ сохранить не удалось.
+func (s *Service) CompleteIdempotencyKey(ctx context.Context, key string, response db.IdempotentResponse) error {
+	return s.repo.CompleteIdempotencyKey(ctx, key, response)
+}
+
+// ReleaseIdempotencyKey освобождает ключ запроса, ответ на который не сохраняется.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - key: Ключ, занятый ReserveIdempotencyKey.
+//
+// Возвращает:
+//   - Ошибку, если ключ освободить не удалось.
+func (s *Service) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	return s.repo.ReleaseIdempotencyKey(ctx, key)
+}
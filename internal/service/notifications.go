@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"payment-system/internal/metrics"
+	models "payment-system/internal/models"
+	"payment-system/internal/notify"
+)
+
+// notificationBatchSize - наибольшее количество уведомлений, выбираемых одним запросом.
+const notificationBatchSize = 10
+
+// maxNotificationRetryDelay ограничивает паузу между попытками, которая удваивается с каждой попыткой.
+const maxNotificationRetryDelay = 24 * time.Hour
+
+// errNotificationRecipientRemoved - ошибка уведомления, адрес получателя которого очищен
+// анонимизацией кошелька; такое уведомление не отправляется.
+var errNotificationRecipientRemoved = errors.New("recipient address removed by wallet anonymization")
+
+// NotificationPolicy - правила доставки уведомлений о поступлении средств.
+type NotificationPolicy struct {
+	MaxAttempts int           // Наибольшее количество попыток доставки одного уведомления
+	RetryDelay  time.Duration // Пауза перед второй попыткой; каждая следующая вдвое длиннее
+	Timeout     time.Duration // Ограничение времени одной попытки
+}
+
+// retryDelay возвращает паузу перед попыткой после attempts неудачных.
+func (p NotificationPolicy) retryDelay(attempts int) time.Duration {
+	delay := p.RetryDelay
+	for i := 1; i < attempts && delay < maxNotificationRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxNotificationRetryDelay)
+}
+
+// RunNotifications раз в interval доставляет уведомления о поступлении средств, поставленные
+// в очередь переводами, через notifier. Блокирует до отмены ctx, поэтому запускается в отдельной
+// горутине. Неудачная попытка повторяется с удваивающейся паузой; после policy.MaxAttempts
+// попыток уведомление получает статус models.NotificationFailed с ошибкой последней попытки.
+// Уведомление выбирается на время, достаточное для доставки пачки, поэтому несколько
+// экземпляров сервиса не отправляют его дважды, а прерванная остановкой доставка повторяется.
+//
+// Параметры:
+//   - ctx: Контекст; отмена останавливает доставку.
+//   - interval: Период проверки очереди.
+//   - notifier: Способ доставки (notify.SMTP или notify.Nop).
+//   - policy: Правила повторных попыток; все значения должны быть положительными.
+//
+// Пример использования:
+//
+//	go svc.RunNotifications(ctx, 5*time.Second, notify.Nop{}, service.NotificationPolicy{MaxAttempts: 5, RetryDelay: time.Minute, Timeout: 10 * time.Second})
+func (s *Service) RunNotifications(ctx context.Context, interval time.Duration, notifier notify.Notifier, policy NotificationPolicy) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// Выбранное уведомление не выбирается снова, пока пачка доставляется
+	lease := notificationBatchSize*policy.Timeout + interval
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Очередь разбирается до конца, чтобы всплеск переводов не ждал следующих периодов
+			for ctx.Err() == nil {
+				notifications, err := s.repo.ClaimNotifications(ctx, time.Now(), lease, notificationBatchSize)
+				if err != nil {
+					slog.Error("failed to claim notifications", "error", err)
+					break
+				}
+				for _, n := range notifications {
+					s.deliverNotification(ctx, notifier, policy, n)
+				}
+				if len(notifications) < notificationBatchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// deliverNotification выполняет одну попытку доставки уведомления и записывает ее результат.
+// Попытка, прерванная отменой ctx, не записывается: уведомление будет выбрано снова.
+func (s *Service) deliverNotification(ctx context.Context, notifier notify.Notifier, policy NotificationPolicy, n models.Notification) {
+	var err error
+	if n.Email == "" {
+		// Адрес очищен анонимизацией кошелька после постановки в очередь
+		n.Attempts = policy.MaxAttempts
+		err = errNotificationRecipientRemoved
+	} else {
+		attempt, cancel := context.WithTimeout(ctx, policy.Timeout)
+		err = notifier.Notify(attempt, notify.ReceivedFunds{
+			TransactionID: n.TransactionID,
+			Address:       n.Address,
+			Email:         n.Email,
+			From:          n.From,
+			FromLabel:     n.FromLabel,
+			Amount:        n.Amount,
+			Timestamp:     n.TransferredAt,
+		})
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+	}
+
+	now := time.Now()
+	status, lastError, at, result := models.NotificationSent, "", now, "sent"
+	switch {
+	case err == nil:
+	case n.Attempts >= policy.MaxAttempts:
+		status, lastError, result = models.NotificationFailed, err.Error(), "failed"
+		slog.Warn("notification delivery failed", "id", n.ID, "address", n.Address, "attempts", n.Attempts, "error", err)
+	default:
+		status, lastError, at, result = models.NotificationPending, err.Error(), now.Add(policy.retryDelay(n.Attempts)), "retry"
+		slog.Debug("notification delivery will be retried", "id", n.ID, "attempts", n.Attempts, "error", err)
+	}
+	metrics.NotificationAttempts.WithLabelValues(result).Inc()
+	if err := s.repo.CompleteNotification(ctx, n.ID, status, lastError, at); err != nil {
+		slog.Error("failed to record notification delivery", "id", n.ID, "status", status, "error", err)
+	}
+}
+
+// GetNotifications возвращает последние count уведомлений о поступлении средств.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - status: Статус уведомлений (пустая строка - любой).
+//   - count: Количество уведомлений.
+//
+// Возвращает:
+//   - Список уведомлений, начиная с самого нового.
+//   - Ошибку, если произошла ошибка при обращении к хранилищу.
+//
+// Пример использования:
+//
+//	notifications, err := svc.GetNotifications(ctx, models.NotificationFailed, 50)
+func (s *Service) GetNotifications(ctx context.Context, status string, count int) ([]models.Notification, error) {
+	return s.repo.GetNotifications(ctx, status, count)
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	db "payment-system/internal/db"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// RetentionPolicy - правила очистки истории транзакций.
+type RetentionPolicy struct {
+	Period     time.Duration // Транзакции старше этого срока переносятся в архив; 0 - очистка выключена
+	Archive    bool          // true - переносить в transactions_archive, false - удалять
+	BatchSize  int           // Количество транзакций, переносимых одной транзакцией базы
+	BatchPause time.Duration // Пауза между пачками, чтобы не перегружать журнал базы (WAL)
+}
+
+// PurgeTransactions переносит в архив (или удаляет) транзакции старше policy.Period пачками
+// по policy.BatchSize с паузой policy.BatchPause между ними, пока старые транзакции
+// не закончатся. Балансы не меняются.
+//
+// Параметры:
+//   - ctx: Контекст; отмена прерывает очистку между пачками.
+//   - policy: Правила очистки; Period и BatchSize должны быть положительными.
+//
+// Возвращает:
+//   - Количество перенесенных (удаленных) транзакций, в том числе при ошибке.
+//   - Ошибку, оборачивающую db.ErrPurgeLocked, если очистку выполняет другой экземпляр,
+//     или ошибку контекста, если очистка прервана.
+//
+// Пример использования:
+//
+//	purged, err := svc.PurgeTransactions(ctx, service.RetentionPolicy{Period: 90 * 24 * time.Hour, Archive: true, BatchSize: 1000})
+func (s *Service) PurgeTransactions(ctx context.Context, policy RetentionPolicy) (_ int, err error) {
+	ctx, span := startSpan(ctx, "PurgeTransactions", attribute.Bool("archive", policy.Archive))
+	defer func() { endSpan(span, err) }()
+
+	// Граница фиксируется в начале, чтобы очистка не гналась за новыми транзакциями
+	before := time.Now().Add(-policy.Period)
+
+	total := 0
+	defer func() {
+		span.SetAttributes(attribute.Int("purged", total))
+		if total > 0 && s.transactions != nil {
+			s.transactions.invalidate()
+		}
+	}()
+	for {
+		purged, err := s.repo.PurgeTransactions(ctx, before, policy.Archive, policy.BatchSize)
+		total += purged
+		if err != nil || purged < policy.BatchSize {
+			return total, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return total, ctx.Err()
+		case <-time.After(policy.BatchPause):
+		}
+	}
+}
+
+// RunRetention раз в interval очищает историю транзакций по правилам policy. Блокирует до
+// отмены ctx, поэтому запускается в отдельной горутине. Если очистку уже выполняет другой
+// экземпляр сервиса, проверка пропускается; ошибки записываются в лог.
+//
+// Параметры:
+//   - ctx: Контекст; отмена останавливает очистку.
+//   - interval: Период проверки.
+//   - policy: Правила очистки.
+//
+// Пример использования:
+//
+//	go svc.RunRetention(ctx, time.Hour, policy)
+func (s *Service) RunRetention(ctx context.Context, interval time.Duration, policy RetentionPolicy) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.PurgeTransactions(ctx, policy)
+			switch {
+			case errors.Is(err, db.ErrPurgeLocked):
+				slog.Info("transaction purge is running on another instance, skipped")
+			case errors.Is(err, context.Canceled):
+				return
+			case err != nil:
+				slog.Error("failed to purge transactions", "purged", purged, "error", err)
+			case purged > 0:
+				slog.Info("transactions purged", "period", policy.Period.String(), "count", purged)
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	db "payment-system/internal/db"
+	models "payment-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// purgeRecorder - хранилище, запоминающее, сколько транзакций перенесла каждая пачка очистки.
+// Если задан err, пачка с номером failAt (начиная с 1) завершается этой ошибкой.
+type purgeRecorder struct {
+	*db.MemoryRepository
+	batches []int
+	failAt  int
+	err     error
+	onBatch func() // Вызывается после каждой пачки
+}
+
+func (r *purgeRecorder) PurgeTransactions(ctx context.Context, before time.Time, archive bool, limit int) (int, error) {
+	if r.err != nil && len(r.batches)+1 == r.failAt {
+		return 0, r.err
+	}
+	purged, err := r.MemoryRepository.PurgeTransactions(ctx, before, archive, limit)
+	r.batches = append(r.batches, purged)
+	if r.onBatch != nil {
+		r.onBatch()
+	}
+	return purged, err
+}
+
+// newPurgeRecorder создает хранилище с old переводами, выполненными двое суток назад,
+// и одним переводом, выполненным сейчас.
+func newPurgeRecorder(t *testing.T, old int) (*purgeRecorder, *Service) {
+	t.Helper()
+	ctx := context.Background()
+	repo := &purgeRecorder{MemoryRepository: db.NewMemoryRepository()}
+	clock := db.NewFixedClock(time.Now().Add(-48 * time.Hour))
+	repo.SetClock(clock)
+	svc := NewService(repo)
+
+	var addresses []string
+	for range 2 {
+		wallet, _, err := svc.CreateWallet(ctx, decimal.NewFromInt(100), models.WalletMetadata{})
+		if err != nil {
+			t.Fatalf("CreateWallet: %v", err)
+		}
+		addresses = append(addresses, wallet.Address)
+	}
+	send := func() {
+		if _, err := svc.Send(ctx, addresses[0], addresses[1], decimal.NewFromInt(1), "", "", nil, sql.LevelDefault); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	for range old {
+		send()
+		clock.Advance(time.Second)
+	}
+	clock.Set(time.Now())
+	send()
+	return repo, svc
+}
+
+// TestPurgeTransactions проверяет очистку пачками: пачки идут, пока очередная не окажется
+// неполной, недавние переводы остаются, а после удаления без архива сверка балансов сходится.
+func TestPurgeTransactions(t *testing.T) {
+	tests := []struct {
+		name        string
+		old         int
+		batchSize   int
+		archive     bool
+		wantBatches []int
+	}{
+		{"nothing to purge", 0, 2, true, []int{0}},
+		{"partial last batch", 5, 2, true, []int{2, 2, 1}},
+		{"full last batch", 4, 2, true, []int{2, 2, 0}},
+		{"single batch", 3, 10, true, []int{3}},
+		{"delete", 5, 2, false, []int{2, 2, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo, svc := newPurgeRecorder(t, tt.old)
+
+			policy := RetentionPolicy{Period: 24 * time.Hour, Archive: tt.archive, BatchSize: tt.batchSize}
+			purged, err := svc.PurgeTransactions(ctx, policy)
+			if err != nil {
+				t.Fatalf("PurgeTransactions: %v", err)
+			}
+			if purged != tt.old {
+				t.Errorf("purged %d, want %d", purged, tt.old)
+			}
+			if !slices.Equal(repo.batches, tt.wantBatches) {
+				t.Errorf("batches = %v, want %v", repo.batches, tt.wantBatches)
+			}
+
+			wantArchived := 0
+			if tt.archive {
+				wantArchived = tt.old
+			}
+			for filter, want := range map[db.TransactionFilter]int64{{}: 1, {IncludeArchived: true}: int64(1 + wantArchived)} {
+				if count, err := repo.CountTransactions(ctx, filter); err != nil || count != want {
+					t.Errorf("CountTransactions(%+v) = %d, %v, want %d", filter, count, err, want)
+				}
+			}
+			report, err := svc.Reconcile(ctx)
+			if err != nil {
+				t.Fatalf("Reconcile: %v", err)
+			}
+			if report.Drift() {
+				t.Errorf("Reconcile after purge reports drift: %+v", report)
+			}
+		})
+	}
+}
+
+// TestPurgeTransactionsStops проверяет, что очистка прекращается при отмене контекста
+// во время паузы между пачками и при ошибке пачки, возвращая уже перенесенное количество.
+func TestPurgeTransactionsStops(t *testing.T) {
+	t.Run("canceled between batches", func(t *testing.T) {
+		repo, svc := newPurgeRecorder(t, 5)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		repo.onBatch = cancel
+
+		policy := RetentionPolicy{Period: 24 * time.Hour, Archive: true, BatchSize: 2, BatchPause: time.Hour}
+		purged, err := svc.PurgeTransactions(ctx, policy)
+		if !errors.Is(err, context.Canceled) || purged != 2 {
+			t.Fatalf("PurgeTransactions = %d, %v, want 2, context.Canceled", purged, err)
+		}
+	})
+
+	t.Run("locked by another instance", func(t *testing.T) {
+		repo, svc := newPurgeRecorder(t, 5)
+		repo.failAt, repo.err = 2, db.ErrPurgeLocked
+
+		policy := RetentionPolicy{Period: 24 * time.Hour, Archive: true, BatchSize: 2}
+		purged, err := svc.PurgeTransactions(context.Background(), policy)
+		if !errors.Is(err, db.ErrPurgeLocked) || purged != 2 {
+			t.Fatalf("PurgeTransactions = %d, %v, want 2, ErrPurgeLocked", purged, err)
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"payment-system/internal/db"
+	"payment-system/internal/logging"
+	models "payment-system/internal/models"
+	"payment-system/pkg/signature"
+
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// RotationMemo - комментарий транзакции, которой RotateWallet переносит баланс на новый адрес.
+const RotationMemo = "address rotation"
+
+// Rotation - результат замены адреса кошелька.
+type Rotation struct {
+	Wallet          models.Wallet // Новый кошелек с перенесенным балансом, меткой и тегами
+	PrivateKey      string        // Закрытый ключ нового кошелька; сервис его не хранит
+	PreviousAddress string        // Прежний адрес, архивированный при замене
+	TransactionID   int           // Транзакция переноса баланса со старого адреса на новый
+}
+
+// RotateWallet заменяет адрес кошелька: создает кошелек с новым адресом и ключом, переносит
+// на него весь баланс транзакцией с комментарием RotationMemo, переносит метку и теги
+// и архивирует прежний адрес. Все изменения выполняются в одной транзакции.
+//
+// История не переписывается: прежние транзакции остаются с прежним адресом, а транзакция
+// переноса связывает его с новым. Архивный адрес не принимает и не отправляет переводы.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Новый кошелек, его закрытый ключ, прежний адрес и идентификатор транзакции переноса.
+//   - Ошибку, оборачивающую db.ErrWalletNotFound, если кошелька нет; db.ErrWalletArchived,
+//     если он архивирован; db.ErrWalletHasHolds, если есть переводы, ожидающие подтверждения;
+//     db.ErrSystemAccount для системного счета.
+//
+// Пример использования:
+//
+//	rotation, err := svc.RotateWallet(ctx, "some_address")
+func (s *Service) RotateWallet(ctx context.Context, address string) (_ Rotation, err error) {
+	ctx, span := startSpan(ctx, "RotateWallet", attribute.String(logging.KeyWallet, address))
+	defer func() { endSpan(span, err) }()
+
+	if db.IsSystemAccount(address) {
+		return Rotation{}, fmt.Errorf("wallet %s: %w", address, db.ErrSystemAccount)
+	}
+	publicKey, privateKey, err := signature.GenerateKey()
+	if err != nil {
+		return Rotation{}, err
+	}
+
+	var rotation Rotation
+	newAddress, err := s.addresses.CreateUnique(func(newAddress string) error {
+		return s.repo.WithTx(ctx, func(tx db.TxRepository) error {
+			state, err := tx.GetWalletForUpdate(address)
+			if err != nil {
+				return err
+			}
+			if state.Archived {
+				return fmt.Errorf("wallet %s: %w", address, db.ErrWalletArchived)
+			}
+
+			// Баланс списывается до архивации: архивировать можно только пустой кошелек
+			if _, err := tx.AddBalance(address, state.Balance.Neg()); err != nil {
+				return err
+			}
+			previous, err := tx.RetireWallet(address)
+			if err != nil {
+				return err
+			}
+			if err := tx.CreateWallet(newAddress, decimal.Zero, previous.WalletMetadata, publicKey); err != nil {
+				return err
+			}
+			if _, err := tx.AddBalance(newAddress, state.Balance); err != nil {
+				return err
+			}
+
+			// Транзакция записывается и при нулевом балансе: она связывает адреса в истории
+			record, err := tx.RecordTransaction(models.TransactionTransfer, address, newAddress, state.Balance, RotationMemo, "")
+			if err != nil {
+				return err
+			}
+			rotation = Rotation{
+				Wallet: models.Wallet{
+					Address:        newAddress,
+					Balance:        state.Balance,
+					WalletMetadata: previous.WalletMetadata,
+					PublicKey:      publicKey,
+				},
+				PrivateKey:      privateKey,
+				PreviousAddress: address,
+				TransactionID:   record.ID,
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return Rotation{}, err
+	}
+	rotation.Wallet.Address = s.displayAddress(newAddress)
+	if s.transactions != nil {
+		s.transactions.invalidate()
+	}
+
+	event := models.AuditEvent{
+		Action:  models.AuditWalletRotated,
+		Target:  address,
+		Details: fmt.Sprintf("to=%s amount=%s transaction=%d", newAddress, rotation.Wallet.Balance, rotation.TransactionID),
+	}
+	// Замена уже зафиксирована: ошибка журнала не должна скрыть закрытый ключ нового кошелька,
+	// а запись не прерывается отменой запроса
+	if err := s.repo.RecordAuditEvent(context.WithoutCancel(ctx), event); err != nil {
+		slog.Error("failed to record audit event", "action", event.Action, logging.KeyWallet, address, logging.KeyTxID, rotation.TransactionID, "error", err)
+	}
+	return rotation, nil
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+
+	db "payment-system/internal/db"
+	models "payment-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// SeedMemo - комментарий транзакций выпуска, которыми SeedWallets зачисляет начальный баланс.
+const SeedMemo = "SEED_ON_START"
+
+// SeedWallets создает count демонстрационных кошельков с балансом balance, если в хранилище
+// еще нет ни одного кошелька; иначе ничего не делает. Предназначен для разработки: сервер
+// вызывает его при запуске, если задан SEED_ON_START (в APP_ENV=production запрещено).
+// Кошельки создаются без ключей и метаданных. Начальный баланс зачисляется выпуском со счета
+// эмиссии (models.TransactionMint, комментарий SeedMemo), поэтому история транзакций сходится
+// с балансами (см. Reconcile).
+//
+// Проверка и создание выполняются в одной транзакции под блокировкой наполнения
+// (Repository.WithSeedLock): из экземпляров, запущенных одновременно с пустой общей базой,
+// кошельки создает только первый, а остальные видят их и ничего не делают.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - count: Количество кошельков.
+//   - balance: Начальный баланс каждого кошелька.
+//
+// Возвращает:
+//   - Адреса созданных кошельков (с контрольной суммой, если она включена) или nil,
+//     если кошельки уже были.
+//   - Ошибку, если проверить хранилище или создать кошелек не удалось; тогда не создается
+//     ни один кошелек.
+//
+// Пример использования:
+//
+//	addresses, err := svc.SeedWallets(ctx, 10, decimal.NewFromInt(100))
+func (s *Service) SeedWallets(ctx context.Context, count int, balance decimal.Decimal) ([]string, error) {
+	var addresses []string
+	err := s.repo.WithSeedLock(ctx, func(tx db.TxRepository) error {
+		// Повторная попытка транзакции начинает список заново
+		addresses = nil
+		exists, err := tx.HasWallets()
+		if err != nil || exists {
+			return err
+		}
+
+		for i := 0; i < count; i++ {
+			address, err := s.addresses.Generate()
+			if err != nil {
+				return err
+			}
+			if err := tx.CreateWallet(address, decimal.Zero, models.WalletMetadata{}, ""); err != nil {
+				return err
+			}
+			addresses = append(addresses, s.displayAddress(address))
+			if balance.IsZero() {
+				continue
+			}
+			if _, err := tx.AddBalance(address, balance); err != nil {
+				return err
+			}
+			if _, err := tx.RecordTransaction(models.TransactionMint, db.SystemAccount(db.SystemMint), address, balance, SeedMemo, ""); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return addresses, nil
+}
+
+// HasWallets сообщает, есть ли в хранилище хотя бы один кошелек.
+func (s *Service) HasWallets(ctx context.Context) (bool, error) {
+	return s.repo.HasWallets(ctx)
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	db "payment-system/internal/db"
+	models "payment-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestSeedWalletsConcurrent проверяет, что экземпляры сервиса, запущенные одновременно
+// с общей пустой базой, создают демонстрационные кошельки только один раз.
+func TestSeedWalletsConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "payment-system.db")
+	const instances, count = 4, 5
+
+	var wg sync.WaitGroup
+	seeded := make([][]string, instances)
+	for i := 0; i < instances; i++ {
+		svc := NewService(db.NewSQLiteRepository(path))
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			addresses, err := svc.SeedWallets(context.Background(), count, decimal.NewFromInt(100))
+			if err != nil {
+				t.Errorf("SeedWallets: %v", err)
+			}
+			seeded[i] = addresses
+		}(i)
+	}
+	wg.Wait()
+
+	total := 0
+	for _, addresses := range seeded {
+		if len(addresses) != 0 && len(addresses) != count {
+			t.Errorf("SeedWallets created %d wallets, want 0 or %d", len(addresses), count)
+		}
+		total += len(addresses)
+	}
+	if total != count {
+		t.Fatalf("instances created %d wallets in total, want %d", total, count)
+	}
+}
+
+func TestSeedWalletsExisting(t *testing.T) {
+	svc := NewService(db.NewMemoryRepository())
+	first, err := svc.SeedWallets(context.Background(), 3, decimal.NewFromInt(100))
+	if err != nil || len(first) != 3 {
+		t.Fatalf("first SeedWallets: got %d wallets, %v, want 3", len(first), err)
+	}
+	second, err := svc.SeedWallets(context.Background(), 3, decimal.NewFromInt(100))
+	if err != nil || second != nil {
+		t.Fatalf("second SeedWallets: got %v, %v, want nothing created", second, err)
+	}
+}
+
+// TestSeedWalletsMinted проверяет, что начальный баланс демонстрационных кошельков записан
+// выпуском и сверка истории с балансами не находит расхождений.
+func TestSeedWalletsMinted(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService(db.NewMemoryRepository())
+	if _, err := svc.SeedWallets(ctx, 3, decimal.NewFromInt(100)); err != nil {
+		t.Fatalf("SeedWallets: %v", err)
+	}
+
+	report, err := svc.Reconcile(ctx)
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if report.Drift() || !report.Minted.Equal(decimal.NewFromInt(300)) || !report.ExpectedSupply.Equal(report.TotalBalance) {
+		t.Fatalf("Reconcile after seeding: got %+v, want 300 minted and no drift", report)
+	}
+	transactions, err := svc.GetLastTransactions(ctx, 10, db.TransactionFilter{})
+	if err != nil {
+		t.Fatalf("GetLastTransactions: %v", err)
+	}
+	if len(transactions) != 3 {
+		t.Fatalf("got %d transactions, want 3 mints", len(transactions))
+	}
+	for _, transaction := range transactions {
+		if transaction.Type != models.TransactionMint || transaction.From != db.SystemAccount(db.SystemMint) || transaction.Memo != SeedMemo {
+			t.Errorf("seed transaction %+v, want a mint from the mint account with memo %s", transaction, SeedMemo)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	db "payment-system/internal/db"
+	models "payment-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestMaxSendable проверяет, что /sendable не обещает сумму, которую Send отклонит:
+// результат учитывает неснижаемый остаток и MAX_TRANSFER, а перевод на него выполняется.
+func TestMaxSendable(t *testing.T) {
+	tests := []struct {
+		name       string
+		minBalance string
+		max        string // MAX_TRANSFER; "" - без ограничения
+		threshold  string // Порог подтверждения; "" - без подтверждения
+		want       string
+	}{
+		{name: "whole balance", want: "100"},
+		{name: "minimum balance", minBalance: "10", want: "90"},
+		{name: "capped by max transfer", max: "50", want: "50"},
+		{name: "max transfer above balance", max: "200", want: "100"},
+		{name: "minimum balance below max transfer", minBalance: "10", max: "95", want: "90"},
+		// Перевод больше порога принимается и ждет подтверждения, поэтому порог сумму не ограничивает
+		{name: "approval threshold", threshold: "30", want: "100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			t.Setenv("MIN_WALLET_BALANCE", tt.minBalance)
+			svc := NewService(db.NewMemoryRepository())
+			if tt.max != "" {
+				svc.SetMaxTransfer(decimal.RequireFromString(tt.max))
+			}
+			if tt.threshold != "" {
+				svc.RequireApproval(decimal.RequireFromString(tt.threshold))
+			}
+			from, _, err := svc.CreateWallet(ctx, decimal.NewFromInt(100), models.WalletMetadata{})
+			if err != nil {
+				t.Fatalf("CreateWallet: %v", err)
+			}
+			to, _, err := svc.CreateWallet(ctx, decimal.Zero, models.WalletMetadata{})
+			if err != nil {
+				t.Fatalf("CreateWallet: %v", err)
+			}
+
+			got, err := svc.MaxSendable(ctx, from.Address)
+			if err != nil {
+				t.Fatalf("MaxSendable: %v", err)
+			}
+			if !got.Equal(decimal.RequireFromString(tt.want)) {
+				t.Fatalf("MaxSendable = %s, want %s", got, tt.want)
+			}
+			if _, err := svc.Send(ctx, from.Address, to.Address, got, "", "", nil, sql.LevelDefault); err != nil {
+				t.Errorf("Send(MaxSendable = %s): %v", got, err)
+			}
+		})
+	}
+}
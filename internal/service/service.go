@@ -1,80 +1,973 @@
-// Package service предоставляет бизнес-логику для работы с платежной системой.
-// Включает методы для получения баланса кошелька, отправки денег и получения списка транзакций.
-package service
-
-import (
-	db "payment-system/internal/db"
-	models "payment-system/internal/models"
-)
-
-// Service представляет сервис для работы с платежной системой.
-// Содержит методы для взаимодействия с репозиторием базы данных.
-type Service struct {
-	repo *db.PostgresRepository
-}
-
-// NewService создает новый экземпляр Service.
-//
-// Параметры:
-//   - repo: Репозиторий для работы с базой данных.
-//
-// Возвращает:
-//   - Указатель на новый экземпляр Service.
-//
-// Пример использования:
-//
-//	repo := db.NewPostgresRepository()
-//	svc := service.NewService(repo)
-func NewService(repo *db.PostgresRepository) *Service {
-	return &Service{repo: repo}
-}
-
-// GetBalance возвращает баланс кошелька по его адресу.
-//
-// Параметры:
-//   - address: Адрес кошелька.
-//
-// Возвращает:
-//   - Баланс кошелька.
-//   - Ошибку, если кошелек не найден или произошла другая ошибка.
-//
-// Пример использования:
-//
-//	balance, err := svc.GetBalance("some_address")
-func (s *Service) GetBalance(address string) (float64, error) {
-	return s.repo.GetBalance(address)
-}
-
-// Send выполняет перевод средств с одного кошелька на другой.
-// Включает проверку баланса отправителя, обновление балансов и запись транзакции.
-//
-// Параметры:
-//   - from: Адрес кошелька отправителя.
-//   - to: Адрес кошелька получателя.
-//   - amount: Сумма перевода.
-//
-// Возвращает:
-//   - Ошибку, если перевод не удался (например, недостаточно средств).
-//
-// Пример использования:
-//
-//	err := svc.Send("from_address", "to_address", 10.5)
-func (s *Service) Send(from, to string, amount float64) error {
-	return s.repo.Send(from, to, amount)
-}
-
-// GetLastTransactions возвращает список последних N транзакций.
-//
-// Параметры:
-//   - count: Количество транзакций.
-//
-// Возвращает:
-//   - Список транзакций.
-//   - Ошибку, если произошла ошибка при выполнении запроса.
-//
-// Пример использования:
-//
-//	transactions, err := svc.GetLastTransactions(5)
-func (s *Service) GetLastTransactions(count int) ([]models.Transaction, error) {
-	return s.repo.GetLastTransactions(count)
-}
+// Package service предоставляет бизнес-логику для работы с платежной системой.
+// Включает методы для получения баланса кошелька, отправки денег и получения списка транзакций.
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	db "payment-system/internal/db"
+	errs "payment-system/internal/errs"
+	"payment-system/internal/logging"
+	models "payment-system/internal/models"
+	addr "payment-system/pkg/address"
+	"payment-system/pkg/signature"
+
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrLabelNotFound возвращается, если участник перевода указан меткой ("@ops-float"),
+// которая не присвоена ни одному кошельку.
+var ErrLabelNotFound = errs.ErrLabelNotFound
+
+// ErrSignatureRequired возвращается, если подписи переводов обязательны (RequireSignatures),
+// а перевод не подписан.
+var ErrSignatureRequired = errs.ErrSignatureRequired
+
+// ErrInvalidSignature возвращается, если подпись перевода не проверяется открытым ключом
+// кошелька отправителя или у кошелька нет ключа.
+var ErrInvalidSignature = errs.ErrInvalidSignature
+
+// ErrZeroAmount возвращается, если сумма перевода не положительна или округляется до нуля
+// с точностью SetTransferScale (например, 0.0001 при двух знаках после запятой).
+var ErrZeroAmount = errs.ErrZeroAmount
+
+// ErrAmountTooLarge возвращается, если сумма перевода больше SetMaxTransfer или не
+// помещается в NUMERIC(38, 8) (см. db.AmountOverflows).
+var ErrAmountTooLarge = errs.ErrAmountTooLarge
+
+// ErrUnknownCategory возвращается, если категория перевода не входит в список SetCategories.
+// Конкретная ошибка - *CategoryError со списком допустимых категорий.
+var ErrUnknownCategory = errs.ErrUnknownCategory
+
+// CategoryError - ошибка неизвестной категории перевода. Совпадает с ErrUnknownCategory
+// через errors.Is и сообщает допустимые категории, чтобы клиент мог исправить запрос.
+type CategoryError struct {
+	Category string
+	Allowed  []string // Допустимые категории по алфавиту
+}
+
+// Error возвращает текст ошибки.
+func (e *CategoryError) Error() string {
+	return fmt.Sprintf("%s %q, allowed: %s", ErrUnknownCategory, e.Category, strings.Join(e.Allowed, ", "))
+}
+
+// Is позволяет сравнивать ошибку с ErrUnknownCategory через errors.Is.
+func (e *CategoryError) Is(target error) bool {
+	return target == ErrUnknownCategory
+}
+
+// LabelPrefix - префикс, которым участник перевода указывается по метке, а не по адресу.
+// Префикс исключает путаницу: метка из 64 шестнадцатеричных символов без него была бы адресом.
+const LabelPrefix = "@"
+
+// Party - участник перевода: адрес кошелька и метка, если он был указан по метке.
+type Party struct {
+	Address string `json:"address"`
+	Label   string `json:"label,omitempty"`
+}
+
+// Transfer - участники выполненного перевода после разрешения меток в адреса.
+type Transfer struct {
+	From Party `json:"from"`
+	To   Party `json:"to"`
+
+	// ApprovalID - идентификатор отложенного перевода, если перевод не выполнен, а ожидает
+	// подтверждения администратором (см. RequireApproval); 0, если перевод выполнен.
+	ApprovalID int64 `json:"approval_id,omitempty"`
+
+	// Result - записанная транзакция и балансы участников после перевода; нулевое значение,
+	// если перевод отложен.
+	Result db.SendResult `json:"-"`
+}
+
+// Signature - подпись перевода закрытым ключом кошелька отправителя (см. пакет pkg/signature).
+type Signature struct {
+	Nonce int64  // Номер перевода; на единицу больше номера предыдущего подписанного перевода с кошелька
+	Value string // Подпись ed25519 в шестнадцатеричном виде
+}
+
+// SendInterceptor проверяет перевод после разрешения участников и проверки подписи,
+// но до обращения к репозиторию. Ошибка отклоняет перевод и возвращается из Send без изменений.
+type SendInterceptor interface {
+	BeforeSend(ctx context.Context, from, to string, amount decimal.Decimal) error
+}
+
+// Service представляет сервис для работы с платежной системой.
+// Содержит методы для взаимодействия с репозиторием базы данных.
+type Service struct {
+	repo       db.Repository
+	minBalance decimal.Decimal // Неснижаемый остаток кошелька (MIN_WALLET_BALANCE), который проверяет Send
+
+	transferScale int32           // Знаков после запятой, с которыми сумма перевода не должна округляться до нуля
+	maxTransfer   decimal.Decimal // Наибольшая сумма одного перевода; 0 - без ограничения
+	categories    map[string]bool // Допустимые категории переводов; nil - любые
+
+	requireSignatures bool              // Send отклоняет неподписанные переводы
+	interceptors      []SendInterceptor // Проверки, которые Send вызывает перед переводом
+	approvalThreshold decimal.Decimal   // Переводы больше этой суммы ждут подтверждения; 0 - без подтверждения
+
+	transactions *transactionsCache // Кэш списка последних транзакций; nil, если кэш выключен
+
+	addresses         *db.AddressGenerator // Генератор адресов CreateWallet
+	checksumAddresses bool                 // Адреса новых кошельков возвращаются с контрольной суммой
+
+	health atomic.Pointer[healthStatus] // Результат последней фоновой проверки (RunHealthCheck); nil, если ее нет
+}
+
+// NewService создает новый экземпляр Service.
+//
+// Параметры:
+//   - repo: Репозиторий для работы с базой данных (PostgreSQL или in-memory).
+//
+// Возвращает:
+//   - Указатель на новый экземпляр Service.
+//
+// Пример использования:
+//
+//	repo := db.NewPostgresRepository()
+//	svc := service.NewService(repo)
+func NewService(repo db.Repository) *Service {
+	return &Service{
+		repo:          repo,
+		minBalance:    db.MinWalletBalance(),
+		transferScale: models.AmountScale,
+		addresses:     db.DefaultAddressGenerator,
+	}
+}
+
+// SetAddressGenerator задает генератор адресов кошельков, создаваемых CreateWallet.
+// По умолчанию - db.DefaultAddressGenerator; тесты подставляют детерминированный источник.
+// Вызывается до начала обработки запросов.
+//
+// Пример использования:
+//
+//	svc.SetAddressGenerator(db.NewAddressGenerator(mrand.NewChaCha8([32]byte{})))
+func (s *Service) SetAddressGenerator(addresses *db.AddressGenerator) {
+	s.addresses = addresses
+}
+
+// EnableAddressChecksum включает запись адресов создаваемых кошельков с контрольной суммой
+// в регистре букв (см. пакет pkg/address): CreateWallet и RotateWallet возвращают адрес
+// в виде "0F3a...". В хранилище адреса остаются в нижнем регистре, поэтому прежние адреса
+// и адреса без контрольной суммы продолжают работать. Вызывается до начала обработки запросов.
+//
+// Пример использования:
+//
+//	svc.EnableAddressChecksum()
+func (s *Service) EnableAddressChecksum() {
+	s.checksumAddresses = true
+}
+
+// displayAddress возвращает адрес в том виде, в котором он показывается клиенту:
+// с контрольной суммой, если она включена EnableAddressChecksum.
+func (s *Service) displayAddress(address string) string {
+	if s.checksumAddresses {
+		return addr.Checksum(address)
+	}
+	return address
+}
+
+// SetTransferScale задает точность, с которой проверяется сумма перевода: сумма, округляющаяся
+// до нуля с scale знаками после запятой, отклоняется с ErrZeroAmount. По умолчанию -
+// models.AmountScale, то есть отклоняются только нулевые и отрицательные суммы.
+// Вызывается до начала обработки запросов.
+//
+// Параметры:
+//   - scale: Количество знаков после запятой, от 0 до models.AmountScale.
+//
+// Пример использования:
+//
+//	svc.SetTransferScale(2) // переводы меньше 0.005 отклоняются
+func (s *Service) SetTransferScale(scale int32) {
+	s.transferScale = scale
+}
+
+// SetMaxTransfer задает наибольшую сумму одного перевода: перевод на большую сумму
+// отклоняется с ErrAmountTooLarge до обращения к репозиторию. Перевод на сумму, равную max,
+// разрешен. Вызывается до начала обработки запросов.
+//
+// Параметры:
+//   - max: Наибольшая сумма перевода; 0 - ограничена только размером NUMERIC(38, 8).
+//
+// Пример использования:
+//
+//	svc.SetMaxTransfer(decimal.NewFromInt(1000000))
+func (s *Service) SetMaxTransfer(max decimal.Decimal) {
+	s.maxTransfer = max
+}
+
+// SetCategories ограничивает категории переводов списком: перевод с другой непустой
+// категорией отклоняется с ErrUnknownCategory. Без вызова (или с пустым списком) категория
+// может быть любой строкой, допустимой models.ValidateCategory. Вызывается до начала
+// обработки запросов.
+//
+// Параметры:
+//   - categories: Допустимые категории.
+//
+// Пример использования:
+//
+//	svc.SetCategories([]string{"salary", "refund", "fee"})
+func (s *Service) SetCategories(categories []string) {
+	if len(categories) == 0 {
+		s.categories = nil
+		return
+	}
+	s.categories = make(map[string]bool, len(categories))
+	for _, category := range categories {
+		s.categories[category] = true
+	}
+}
+
+// EnableTransactionsCache включает кэширование списка последних транзакций без фильтров.
+// Кэш сбрасывается после каждого успешного перевода или импорта. Вызывается до начала
+// обработки запросов.
+//
+// Параметры:
+//   - ttl: Время жизни записи; ограничивает устаревание, если переводы идут через другой экземпляр.
+//   - maxEntries: Наибольшее количество записей (разных значений count).
+//
+// Пример использования:
+//
+//	svc.EnableTransactionsCache(time.Second, 64)
+func (s *Service) EnableTransactionsCache(ttl time.Duration, maxEntries int) {
+	s.transactions = newTransactionsCache(ttl, maxEntries)
+}
+
+// RequireSignatures делает подпись обязательной для всех переводов. Без этого подпись
+// проверяется, только если она передана. Вызывается до начала обработки запросов.
+//
+// Пример использования:
+//
+//	svc.RequireSignatures()
+func (s *Service) RequireSignatures() {
+	s.requireSignatures = true
+}
+
+// AddSendInterceptor добавляет проверку, которую Send вызывает перед каждым переводом.
+// Проверки вызываются в порядке добавления до первой ошибки. Вызывается до начала
+// обработки запросов.
+//
+// Параметры:
+//   - interceptor: Проверка перевода (например, *risk.Engine).
+//
+// Пример использования:
+//
+//	svc.AddSendInterceptor(engine)
+func (s *Service) AddSendInterceptor(interceptor SendInterceptor) {
+	s.interceptors = append(s.interceptors, interceptor)
+}
+
+// GetBalance возвращает баланс кошелька по его адресу.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Баланс кошелька.
+//   - Ошибку, если кошелек не найден или произошла другая ошибка.
+//
+// Пример использования:
+//
+//	balance, err := svc.GetBalance(ctx, "some_address")
+func (s *Service) GetBalance(ctx context.Context, address string) (decimal.Decimal, error) {
+	return s.repo.GetBalance(ctx, address)
+}
+
+// GetBalances возвращает балансы нескольких кошельков одним запросом к хранилищу.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - addresses: Адреса кошельков.
+//
+// Возвращает:
+//   - Балансы по адресам; несуществующих кошельков в результате нет.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+//
+// Пример использования:
+//
+//	balances, err := svc.GetBalances(ctx, []string{"address1", "address2"})
+func (s *Service) GetBalances(ctx context.Context, addresses []string) (map[string]decimal.Decimal, error) {
+	return s.repo.GetBalances(ctx, addresses)
+}
+
+// CreateWallet создает кошелек со случайным адресом, начальным балансом и метаданными.
+// При совпадении адреса с существующим генерирует новый, поэтому кошелек
+// либо гарантированно создается, либо возвращается ошибка.
+// Для кошелька создается пара ключей ed25519: открытый ключ сохраняется в кошельке,
+// закрытый возвращается вызывающему и больше нигде не хранится.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - balance: Начальный баланс.
+//   - metadata: Метка и теги кошелька (нулевое значение - без метаданных).
+//
+// Возвращает:
+//   - Созданный кошелек с открытым ключом.
+//   - Закрытый ключ в шестнадцатеричном виде для подписи переводов.
+//   - Ошибку, если кошелек создать не удалось; db.ErrLabelExists, если метка занята.
+//
+// Пример использования:
+//
+//	wallet, privateKey, err := svc.CreateWallet(ctx, decimal.NewFromInt(100), models.WalletMetadata{Label: "ops-float"})
+func (s *Service) CreateWallet(ctx context.Context, balance decimal.Decimal, metadata models.WalletMetadata) (_ models.Wallet, _ string, err error) {
+	ctx, span := startSpan(ctx, "CreateWallet")
+	defer func() { endSpan(span, err) }()
+
+	publicKey, privateKey, err := signature.GenerateKey()
+	if err != nil {
+		return models.Wallet{}, "", err
+	}
+	address, err := s.addresses.CreateWallet(ctx, s.repo, balance, metadata, publicKey)
+	if err != nil {
+		return models.Wallet{}, "", err
+	}
+	span.SetAttributes(attribute.String(logging.KeyWallet, address))
+	return models.Wallet{
+		Address:        s.displayAddress(address),
+		Balance:        balance,
+		WalletMetadata: metadata,
+		PublicKey:      publicKey,
+	}, privateKey, nil
+}
+
+// GetWallet возвращает кошелек с балансом и метаданными.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Кошелек.
+//   - Ошибку, оборачивающую db.ErrWalletNotFound, если кошелек не найден.
+//
+// Пример использования:
+//
+//	wallet, err := svc.GetWallet(ctx, "some_address")
+func (s *Service) GetWallet(ctx context.Context, address string) (models.Wallet, error) {
+	return s.repo.GetWallet(ctx, address)
+}
+
+// FindWalletByLabel возвращает кошелек по метке.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - label: Метка кошелька.
+//
+// Возвращает:
+//   - Кошелек.
+//   - Ошибку, оборачивающую db.ErrWalletNotFound, если кошелька с такой меткой нет.
+//
+// Пример использования:
+//
+//	wallet, err := svc.FindWalletByLabel(ctx, "ops-float")
+func (s *Service) FindWalletByLabel(ctx context.Context, label string) (models.Wallet, error) {
+	return s.repo.FindWalletByLabel(ctx, label)
+}
+
+// TopWalletsByBalance возвращает активные кошельки с наибольшим балансом.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - limit: Максимальное количество кошельков.
+//
+// Возвращает:
+//   - Кошельки по убыванию баланса, при равном балансе - по адресу.
+//   - Ошибку, если не удалось выполнить запрос.
+//
+// Пример использования:
+//
+//	wallets, err := svc.TopWalletsByBalance(ctx, 10)
+func (s *Service) TopWalletsByBalance(ctx context.Context, limit int) ([]models.Wallet, error) {
+	return s.repo.TopWalletsByBalance(ctx, limit)
+}
+
+// UpdateWalletMetadata изменяет метку, теги и адрес для уведомлений кошелька.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//   - patch: Изменяемые поля; поля со значением nil не изменяются.
+//
+// Возвращает:
+//   - Кошелек после изменения.
+//   - Ошибку; db.ErrWalletNotFound, если кошелька нет, db.ErrLabelExists, если метка занята.
+//
+// Пример использования:
+//
+//	wallet, err := svc.UpdateWalletMetadata(ctx, address, models.WalletMetadataPatch{Tags: tags})
+func (s *Service) UpdateWalletMetadata(ctx context.Context, address string, patch models.WalletMetadataPatch) (models.Wallet, error) {
+	return s.repo.UpdateWalletMetadata(ctx, address, patch)
+}
+
+// ArchiveWallet архивирует кошелек (мягкое удаление): кошелек с нулевым балансом,
+// не участвующий в отложенных переводах, перестает участвовать в переводах,
+// а его транзакции остаются в истории.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Архивный кошелек.
+//   - Ошибку; db.ErrWalletNotFound, если кошелька нет, db.ErrWalletNotEmpty, если баланс
+//     не нулевой, db.ErrWalletHasHolds, если кошелек участвует в отложенных переводах,
+//     db.ErrSystemAccount для системного счета.
+//
+// Пример использования:
+//
+//	wallet, err := svc.ArchiveWallet(ctx, "some_address")
+func (s *Service) ArchiveWallet(ctx context.Context, address string) (models.Wallet, error) {
+	if db.IsSystemAccount(address) {
+		return models.Wallet{}, fmt.Errorf("wallet %s: %w", address, db.ErrSystemAccount)
+	}
+	return s.repo.ArchiveWallet(ctx, address)
+}
+
+// RestoreWallet возвращает архивный кошелек в работу.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Кошелек после восстановления.
+//   - Ошибку, оборачивающую db.ErrWalletNotFound, если кошелька нет.
+//
+// Пример использования:
+//
+//	wallet, err := svc.RestoreWallet(ctx, "some_address")
+func (s *Service) RestoreWallet(ctx context.Context, address string) (models.Wallet, error) {
+	return s.repo.RestoreWallet(ctx, address)
+}
+
+// CreateWallets создает count кошельков со случайными адресами и заданным балансом
+// (массовое заполнение тестовыми данными).
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - count: Количество кошельков.
+//   - balance: Начальный баланс каждого кошелька.
+//
+// Возвращает:
+//   - Количество созданных кошельков.
+//   - Ошибку, если создать все кошельки не удалось.
+//
+// Пример использования:
+//
+//	created, err := svc.CreateWallets(ctx, 100000, decimal.NewFromInt(100))
+func (s *Service) CreateWallets(ctx context.Context, count int, balance decimal.Decimal) (int, error) {
+	return s.repo.CreateWallets(ctx, count, balance)
+}
+
+// validateAmount проверяет сумму перевода до любых обращений к репозиторию, чтобы правила
+// были одинаковы для всех версий API и для отложенных переводов.
+//
+// Возвращает:
+//   - Ошибку, если в сумме больше models.AmountScale знаков после запятой;
+//     ErrZeroAmount, если сумма не положительна с точностью transferScale
+//     (в том числе -0.0 и остатки вроде 0.0000001); ErrAmountTooLarge, если сумма
+//     больше maxTransfer или не помещается в NUMERIC(38, 8).
+func (s *Service) validateAmount(amount decimal.Decimal) error {
+	// Сумма с лишними знаками после запятой была бы округлена базой при записи
+	if err := models.ValidateAmountScale(amount); err != nil {
+		return err
+	}
+	if !amount.Round(s.transferScale).IsPositive() {
+		return fmt.Errorf("%w at %d decimal places, got %s", ErrZeroAmount, s.transferScale, amount)
+	}
+	// Сумма, которую нельзя записать в базу, отклоняется и без MAX_TRANSFER: иначе ответ
+	// зависел бы от того, что раньше проверит база - остаток или размер числа
+	if db.AmountOverflows(amount) {
+		return fmt.Errorf("%w: %s does not fit NUMERIC(38, 8)", ErrAmountTooLarge, amount)
+	}
+	if s.maxTransfer.IsPositive() && amount.GreaterThan(s.maxTransfer) {
+		return fmt.Errorf("%w of %s, got %s", ErrAmountTooLarge, s.maxTransfer, amount)
+	}
+	return nil
+}
+
+// Send выполняет перевод средств с одного кошелька на другой.
+// Включает проверку баланса отправителя, обновление балансов и запись транзакции.
+// Отправитель и получатель указываются адресом или меткой с префиксом LabelPrefix ("@ops-float");
+// метки разрешаются в адреса до обращения к репозиторию, и транзакция всегда сохраняется с адресами.
+// Подпись, если она передана, проверяется по разрешенным адресам, поэтому переназначение
+// метки не перенаправляет подписанный перевод.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - from: Адрес или метка кошелька отправителя.
+//   - to: Адрес или метка кошелька получателя.
+//   - amount: Сумма перевода.
+//   - memo: Комментарий к переводу (может быть пустым).
+//   - category: Категория перевода для отчетов (может быть пустой).
+//   - sig: Подпись перевода; nil - перевод не подписан.
+//   - isolation: Уровень изоляции транзакции перевода; sql.LevelDefault - уровень из конфигурации.
+//     Отложенный перевод при подтверждении выполняется с уровнем по умолчанию.
+//
+// Возвращает:
+//   - Участников перевода с разрешенными адресами; если перевод отложен до подтверждения,
+//     Transfer.ApprovalID содержит идентификатор отложенного перевода.
+//   - Ошибку, если перевод не удался (например, недостаточно средств);
+//     ErrZeroAmount, если сумма округляется до нуля (см. SetTransferScale);
+//     ErrAmountTooLarge, если сумма больше SetMaxTransfer; *CategoryError (ErrUnknownCategory),
+//     если категория не входит в список SetCategories;
+//     ErrLabelNotFound, если метка никому не присвоена; errs.ErrSelfTransfer, если отправитель
+//     и получатель - один кошелек (в том числе по метке); ErrSignatureRequired или
+//     ErrInvalidSignature, если перевод не прошел проверку подписи; *db.NonceError,
+//     если номер перевода не следующий номер отправителя; ошибку SendInterceptor,
+//     если перевод отклонен проверкой.
+//
+// Пример использования:
+//
+//	transfer, err := svc.Send(ctx, "@ops-float", "to_address", decimal.RequireFromString("10.5"), "invoice 42", "salary", nil, sql.LevelDefault)
+func (s *Service) Send(ctx context.Context, from, to string, amount decimal.Decimal, memo, category string, sig *Signature, isolation sql.IsolationLevel) (transfer Transfer, err error) {
+	ctx, span := startSpan(ctx, "Send")
+	defer func() { endSpan(span, err) }()
+
+	if err := s.validateAmount(amount); err != nil {
+		return Transfer{}, err
+	}
+	if category != "" && s.categories != nil && !s.categories[category] {
+		allowed := make([]string, 0, len(s.categories))
+		for c := range s.categories {
+			allowed = append(allowed, c)
+		}
+		slices.Sort(allowed)
+		return Transfer{}, &CategoryError{Category: category, Allowed: allowed}
+	}
+
+	if transfer.From, err = s.resolve(ctx, from); err != nil {
+		return Transfer{}, err
+	}
+	if transfer.To, err = s.resolve(ctx, to); err != nil {
+		return Transfer{}, err
+	}
+	span.SetAttributes(attribute.String("from", transfer.From.Address), attribute.String("to", transfer.To.Address))
+	// Репозиторий отклонит и сам перевод, но отложенный перевод должен отклоняться сразу
+	for _, party := range []Party{transfer.From, transfer.To} {
+		if db.IsSystemAccount(party.Address) {
+			return Transfer{}, fmt.Errorf("wallet %s: %w", party.Address, db.ErrSystemAccount)
+		}
+	}
+	if strings.EqualFold(transfer.From.Address, transfer.To.Address) {
+		return Transfer{}, fmt.Errorf("wallet %s: %w", transfer.From.Address, errs.ErrSelfTransfer)
+	}
+	if err := s.authorize(ctx, transfer, amount, sig); err != nil {
+		return Transfer{}, err
+	}
+	for _, interceptor := range s.interceptors {
+		if err := interceptor.BeforeSend(ctx, transfer.From.Address, transfer.To.Address, amount); err != nil {
+			return Transfer{}, err
+		}
+	}
+
+	// Номер подписанного перевода проверяется и сохраняется репозиторием вместе со списанием
+	var nonce int64
+	if sig != nil {
+		nonce = sig.Nonce
+	}
+
+	// Крупный перевод откладывается до решения администратора и выполняется в ApproveTransfer.
+	// Отложенный перевод резервирует сумму, поэтому доступный остаток проверяется уже сейчас
+	if s.approvalThreshold.IsPositive() && amount.GreaterThan(s.approvalThreshold) {
+		if err := s.checkActive(ctx, transfer); err != nil {
+			return Transfer{}, err
+		}
+		balance, err := s.repo.GetBalanceDetails(ctx, transfer.From.Address)
+		if err != nil {
+			return Transfer{}, err
+		}
+		if err := db.CheckAvailable(balance.Available, amount, s.minBalance); err != nil {
+			return Transfer{}, err
+		}
+		approval, err := s.repo.CreateApproval(ctx, models.Approval{
+			From: transfer.From.Address, To: transfer.To.Address, Amount: amount, Memo: memo, Category: category, Nonce: nonce,
+		})
+		if err != nil {
+			return Transfer{}, err
+		}
+		transfer.ApprovalID = approval.ID
+		span.SetAttributes(attribute.Int64("approval_id", approval.ID))
+		return transfer, nil
+	}
+	if transfer.Result, err = s.repo.Send(ctx, transfer.From.Address, transfer.To.Address, amount, memo, category, nonce, isolation); err != nil {
+		return Transfer{}, err
+	}
+	span.SetAttributes(attribute.Int(logging.KeyTxID, transfer.Result.TransactionID))
+	if s.transactions != nil {
+		s.transactions.invalidate()
+	}
+	return transfer, nil
+}
+
+// SystemTransfer выполняет операцию с системным счетом: выпуск, изъятие, комиссию, удержание
+// или возврат удержания (см. db.Repository.SystemTransfer). Сумма проверяется, как в Send;
+// правила проверки переводов, подписи и подтверждение крупных переводов не применяются:
+// операции выполняет администратор или сам сервис.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - txType: Тип операции (models.TransactionMint и др.).
+//   - address: Кошелек пользователя.
+//   - amount: Сумма операции.
+//   - memo: Комментарий (может быть пустым).
+//
+// Возвращает:
+//   - Результат операции.
+//   - ErrZeroAmount или ErrAmountTooLarge; db.ErrTransactionType, db.ErrSystemAccount
+//     или ошибку перевода.
+//
+// Пример использования:
+//
+//	result, err := svc.SystemTransfer(ctx, models.TransactionMint, address, decimal.NewFromInt(100), "")
+func (s *Service) SystemTransfer(ctx context.Context, txType, address string, amount decimal.Decimal, memo string) (_ db.SendResult, err error) {
+	ctx, span := startSpan(ctx, "SystemTransfer", attribute.String("type", txType), attribute.String(logging.KeyWallet, address))
+	defer func() { endSpan(span, err) }()
+
+	if err := s.validateAmount(amount); err != nil {
+		return db.SendResult{}, err
+	}
+	result, err := s.repo.SystemTransfer(ctx, txType, address, amount, memo)
+	if err != nil {
+		return db.SendResult{}, err
+	}
+	if s.transactions != nil {
+		s.transactions.invalidate()
+	}
+	return result, nil
+}
+
+// resolve разрешает участника перевода: строка с префиксом LabelPrefix ищется как метка,
+// любая другая считается адресом и передается репозиторию без изменений. Метке можно доверять
+// выбор получателя, потому что присваивают ее только административные маршруты: иначе
+// любой клиент мог бы перенести чужую метку на свой кошелек и получить переводы на нее.
+func (s *Service) resolve(ctx context.Context, ref string) (Party, error) {
+	label, ok := strings.CutPrefix(ref, LabelPrefix)
+	if !ok {
+		return Party{Address: ref}, nil
+	}
+
+	wallet, err := s.repo.FindWalletByLabel(ctx, label)
+	if errors.Is(err, db.ErrWalletNotFound) {
+		return Party{}, fmt.Errorf("%w: %q", ErrLabelNotFound, label)
+	}
+	if err != nil {
+		return Party{}, err
+	}
+	return Party{Address: wallet.Address, Label: label}, nil
+}
+
+// checkActive проверяет, что участники откладываемого перевода существуют и не архивированы;
+// выполняемый сразу перевод это проверяет репозиторий в транзакции перевода.
+func (s *Service) checkActive(ctx context.Context, transfer Transfer) error {
+	roles := []string{"sender", "receiver"}
+	for i, address := range []string{transfer.From.Address, transfer.To.Address} {
+		wallet, err := s.repo.GetWallet(ctx, address)
+		if err != nil {
+			return err
+		}
+		if wallet.ArchivedAt != nil {
+			return fmt.Errorf("%s %s: %w", roles[i], address, db.ErrWalletArchived)
+		}
+	}
+	return nil
+}
+
+// authorize проверяет подпись перевода открытым ключом отправителя.
+// Номер перевода здесь не проверяется: это делает репозиторий в транзакции перевода.
+func (s *Service) authorize(ctx context.Context, transfer Transfer, amount decimal.Decimal, sig *Signature) error {
+	if sig == nil {
+		if s.requireSignatures {
+			return ErrSignatureRequired
+		}
+		return nil
+	}
+
+	wallet, err := s.repo.GetWallet(ctx, transfer.From.Address)
+	if err != nil {
+		return err
+	}
+	if wallet.PublicKey == "" {
+		return fmt.Errorf("%w: sender wallet has no public key", ErrInvalidSignature)
+	}
+	// Сообщение подписи содержит точную десятичную запись суммы (decimal.Decimal.String())
+	if err := signature.Verify(wallet.PublicKey, sig.Value, transfer.From.Address, transfer.To.Address, amount, sig.Nonce); err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// GetNonce возвращает номер последнего подписанного перевода с кошелька; следующий перевод
+// подписывается с номером на единицу больше.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Номер последнего подписанного перевода (0, если их не было).
+//   - Ошибку, оборачивающую db.ErrWalletNotFound, если кошелек не найден.
+//
+// Пример использования:
+//
+//	nonce, err := svc.GetNonce(ctx, "some_address")
+func (s *Service) GetNonce(ctx context.Context, address string) (int64, error) {
+	return s.repo.GetNonce(ctx, address)
+}
+
+// GetBalanceDetails возвращает баланс кошелька с резервом: суммой переводов, ожидающих
+// подтверждения, и доступным остатком, который можно отправить.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Баланс с резервом и доступным остатком.
+//   - Ошибку, оборачивающую db.ErrWalletNotFound, если кошелек не найден.
+//
+// Пример использования:
+//
+//	balance, err := svc.GetBalanceDetails(ctx, "some_address")
+func (s *Service) GetBalanceDetails(ctx context.Context, address string) (models.Balance, error) {
+	return s.repo.GetBalanceDetails(ctx, address)
+}
+
+// MaxSendable возвращает максимальную сумму, которую кошелек может отправить прямо сейчас.
+// Send в репозитории проверяет доступный остаток (баланс за вычетом резерва отложенных
+// переводов) и неснижаемый остаток (MIN_WALLET_BALANCE), а сервис - наибольшую сумму перевода
+// (MAX_TRANSFER), поэтому результат равен доступному остатку за вычетом неснижаемого, но не больше
+// MAX_TRANSFER; комиссии и лимиты, влияющие на Send, должны учитываться здесь же.
+//
+// Порог подтверждения (RequireApproval) сумму не ограничивает: перевод больше порога принимается,
+// но выполняется только после подтверждения администратором.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Максимальную сумму перевода.
+//   - Ошибку, оборачивающую db.ErrWalletNotFound, если кошелек не найден.
+//
+// Пример использования:
+//
+//	maxAmount, err := svc.MaxSendable(ctx, "some_address")
+func (s *Service) MaxSendable(ctx context.Context, address string) (decimal.Decimal, error) {
+	balance, err := s.repo.GetBalanceDetails(ctx, address)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if balance.Available.LessThan(s.minBalance) {
+		return decimal.Zero, nil
+	}
+	sendable := balance.Available.Sub(s.minBalance)
+	if s.maxTransfer.IsPositive() && sendable.GreaterThan(s.maxTransfer) {
+		return s.maxTransfer, nil
+	}
+	return sendable, nil
+}
+
+// Ready проверяет, готов ли сервис обслуживать запросы (доступно ли хранилище).
+// Если запущена фоновая проверка (RunHealthCheck), возвращает ее последний результат,
+// иначе проверяет хранилище сразу.
+//
+// Параметры:
+//   - ctx: Контекст для выполнения проверки.
+//
+// Возвращает:
+//   - Ошибку, если хранилище недоступно; db.ErrDatabaseUnavailable, если открыт автомат отключения.
+//
+// Пример использования:
+//
+//	err := svc.Ready(ctx)
+func (s *Service) Ready(ctx context.Context) error {
+	if status := s.health.Load(); status != nil {
+		return status.err
+	}
+	return s.repo.Ping(ctx)
+}
+
+// ImportTransactions импортирует исторические транзакции из другой системы.
+// Балансы кошельков не изменяются; транзакции с уже импортированным ExternalID пропускаются.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - transactions: Транзакции для импорта.
+//
+// Возвращает:
+//   - Количество добавленных транзакций.
+//   - Ошибку, если импорт не удался (в этом случае ничего не добавляется).
+//
+// Пример использования:
+//
+//	imported, err := svc.ImportTransactions(ctx, transactions)
+func (s *Service) ImportTransactions(ctx context.Context, transactions []models.Transaction) (_ int, err error) {
+	ctx, span := startSpan(ctx, "ImportTransactions", attribute.Int("count", len(transactions)))
+	defer func() { endSpan(span, err) }()
+
+	imported, err := s.repo.ImportTransactions(ctx, transactions)
+	if imported > 0 && s.transactions != nil {
+		s.transactions.invalidate()
+	}
+	return imported, err
+}
+
+// GetLastTransactions возвращает список последних N транзакций.
+// Если кэш включен (EnableTransactionsCache), запросы без фильтров обслуживаются из него;
+// запросы с фильтрами и ошибки не кэшируются.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - count: Количество транзакций.
+//   - filter: Условия выборки (нулевое значение - все транзакции).
+//
+// Возвращает:
+//   - Список транзакций.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+//
+// Пример использования:
+//
+//	transactions, err := svc.GetLastTransactions(ctx, 5, db.TransactionFilter{})
+func (s *Service) GetLastTransactions(ctx context.Context, count int, filter db.TransactionFilter) (_ []models.Transaction, err error) {
+	ctx, span := startSpan(ctx, "GetLastTransactions", attribute.Int("count", count))
+	defer func() { endSpan(span, err) }()
+
+	if s.transactions == nil || !filter.Empty() {
+		return s.repo.GetLastTransactions(ctx, count, filter)
+	}
+
+	transactions, ok, generation := s.transactions.get(count)
+	span.SetAttributes(attribute.Bool("cache_hit", ok))
+	if ok {
+		return transactions, nil
+	}
+	transactions, err = s.repo.GetLastTransactions(ctx, count, filter)
+	if err != nil {
+		return nil, err
+	}
+	s.transactions.put(count, transactions, generation)
+	return transactions, nil
+}
+
+// CountTransactions возвращает количество транзакций, удовлетворяющих фильтру.
+// Кэш списка транзакций не используется.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - filter: Условия выборки (нулевое значение - все транзакции).
+//
+// Возвращает:
+//   - Количество транзакций.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+//
+// Пример использования:
+//
+//	total, err := svc.CountTransactions(ctx, db.TransactionFilter{})
+func (s *Service) CountTransactions(ctx context.Context, filter db.TransactionFilter) (int64, error) {
+	return s.repo.CountTransactions(ctx, filter)
+}
+
+// GetWalletTransactions возвращает последние count транзакций каждого из кошельков одним
+// запросом к хранилищу. Кэш списка транзакций не используется.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - addresses: Адреса кошельков.
+//   - count: Максимальное количество транзакций одного кошелька.
+//
+// Возвращает:
+//   - Транзакции по адресам, начиная с самой новой; кошельков без транзакций в результате нет.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+//
+// Пример использования:
+//
+//	transactions, err := svc.GetWalletTransactions(ctx, []string{"address1", "address2"}, 10)
+func (s *Service) GetWalletTransactions(ctx context.Context, addresses []string, count int) (map[string][]models.Transaction, error) {
+	return s.repo.GetWalletTransactions(ctx, addresses, count)
+}
+
+// GetCategoryVolumes возвращает количество и сумму переводов по дням (UTC) и категориям
+// за период [from, to) для отчетов.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - from: Начало периода.
+//   - to: Конец периода (не включается).
+//
+// Возвращает:
+//   - Объемы по дням и категориям в порядке дня и категории.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+//
+// Пример использования:
+//
+//	volumes, err := svc.GetCategoryVolumes(ctx, from, from.AddDate(0, 0, 7))
+func (s *Service) GetCategoryVolumes(ctx context.Context, from, to time.Time) ([]db.CategoryVolume, error) {
+	return s.repo.GetCategoryVolumes(ctx, from, to)
+}
+
+// Reconcile проверяет инварианты хранилища, в том числе совпадение балансов кошельков
+// с балансами, вычисленными по истории переводов (см. db.Repository.Reconcile).
+//
+// Возвращает:
+//   - Отчет о проверке; report.Drift() сообщает о нарушениях.
+//   - Ошибку, если произошла ошибка при выполнении запросов.
+//
+// Пример использования:
+//
+//	report, err := svc.Reconcile(ctx)
+func (s *Service) Reconcile(ctx context.Context) (db.ReconcileReport, error) {
+	return s.repo.Reconcile(ctx)
+}
+
+// EstimateTransactions возвращает количество транзакций, удовлетворяющих фильтру, допуская
+// приблизительный результат: без фильтра количество берется из статистики базы
+// (см. db.Repository.EstimateTransactions) и не требует просмотра всей таблицы, с фильтром
+// считается точно, как в CountTransactions.
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - filter: Условия выборки (нулевое значение - все транзакции).
+//
+// Возвращает:
+//   - Количество транзакций.
+//   - true, если количество точное.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+//
+// Пример использования:
+//
+//	total, exact, err := svc.EstimateTransactions(ctx, db.TransactionFilter{})
+func (s *Service) EstimateTransactions(ctx context.Context, filter db.TransactionFilter) (int64, bool, error) {
+	if !filter.Empty() {
+		count, err := s.repo.CountTransactions(ctx, filter)
+		return count, true, err
+	}
+	count, err := s.repo.EstimateTransactions(ctx)
+	return count, false, err
+}
+
+// GetRiskEvents возвращает последние срабатывания правил проверки переводов (пакет risk).
+//
+// Параметры:
+//   - ctx: Контекст запроса.
+//   - count: Количество событий.
+//
+// Возвращает:
+//   - Список событий, начиная с самого нового.
+//   - Ошибку, если произошла ошибка при выполнении запроса.
+//
+// Пример использования:
+//
+//	events, err := svc.GetRiskEvents(ctx, 50)
+func (s *Service) GetRiskEvents(ctx context.Context, count int) ([]models.RiskEvent, error) {
+	return s.repo.GetRiskEvents(ctx, count)
+}
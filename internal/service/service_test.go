@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	db "payment-system/internal/db"
+	models "payment-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestSendOverflow проверяет переводы у границы NUMERIC(38, 8): сумма, которая сама не помещается
+// в столбец, отклоняется как слишком большая, а перевод, после которого баланс получателя
+// превысил бы максимум, - ErrBalanceOverflow без изменения балансов.
+func TestSendOverflow(t *testing.T) {
+	const maxBalance = "999999999999999999999999999999.99999999"
+	tests := []struct {
+		name      string
+		toBalance string
+		amount    string
+		wantErr   error
+	}{
+		{"reaches max", "999999999999999999999999999998.99999999", "1", nil},
+		{"one unit past max", "999999999999999999999999999998.99999999", "1.00000001", db.ErrBalanceOverflow},
+		{"receiver at max", maxBalance, "0.00000001", db.ErrBalanceOverflow},
+		{"amount does not fit", "0", "1000000000000000000000000000000", ErrAmountTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			svc := NewService(db.NewMemoryRepository())
+			from, _, err := svc.CreateWallet(ctx, decimal.RequireFromString(maxBalance), models.WalletMetadata{})
+			if err != nil {
+				t.Fatalf("CreateWallet: %v", err)
+			}
+			to, _, err := svc.CreateWallet(ctx, decimal.RequireFromString(tt.toBalance), models.WalletMetadata{})
+			if err != nil {
+				t.Fatalf("CreateWallet: %v", err)
+			}
+
+			_, err = svc.Send(ctx, from.Address, to.Address, decimal.RequireFromString(tt.amount), "", "", nil, sql.LevelDefault)
+			if tt.wantErr == nil && err != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Send(%s) = %v, want %v", tt.amount, err, tt.wantErr)
+			}
+			if tt.wantErr == nil {
+				return
+			}
+			for address, want := range map[string]string{from.Address: maxBalance, to.Address: tt.toBalance} {
+				balance, err := svc.GetBalance(ctx, address)
+				if err != nil {
+					t.Fatalf("GetBalance: %v", err)
+				}
+				if !balance.Equal(decimal.RequireFromString(want)) {
+					t.Errorf("balance after rejected send = %s, want %s", balance, want)
+				}
+			}
+		})
+	}
+}
+
+// TestSendZeroAmount проверяет, что суммы, которые не положительны с точностью TRANSFER_SCALE
+// (-0.0, 0 и остатки вроде 0.0001 при двух знаках), отклоняются ErrZeroAmount без перевода,
+// а наименьшая сумма, округляющаяся не к нулю, проходит.
+func TestSendZeroAmount(t *testing.T) {
+	tests := []struct {
+		amount  string
+		scale   int32
+		wantErr bool
+	}{
+		{"-0.0", models.AmountScale, true},
+		{"0", models.AmountScale, true},
+		{"-1", models.AmountScale, true},
+		{"0.0001", models.AmountScale, false},
+		{"0.00000001", models.AmountScale, false},
+		{"-0.0", 2, true},
+		{"0", 2, true},
+		{"0.0001", 2, true},
+		{"0.00499999", 2, true},
+		{"0.005", 2, false},
+		{"0.01", 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/scale=%d", tt.amount, tt.scale), func(t *testing.T) {
+			ctx := context.Background()
+			svc := NewService(db.NewMemoryRepository())
+			svc.SetTransferScale(tt.scale)
+			from, _, err := svc.CreateWallet(ctx, decimal.NewFromInt(100), models.WalletMetadata{})
+			if err != nil {
+				t.Fatalf("CreateWallet: %v", err)
+			}
+			to, _, err := svc.CreateWallet(ctx, decimal.Zero, models.WalletMetadata{})
+			if err != nil {
+				t.Fatalf("CreateWallet: %v", err)
+			}
+
+			_, err = svc.Send(ctx, from.Address, to.Address, decimal.RequireFromString(tt.amount), "", "", nil, sql.LevelDefault)
+			if tt.wantErr != errors.Is(err, ErrZeroAmount) {
+				t.Fatalf("Send(%s) = %v, want ErrZeroAmount %t", tt.amount, err, tt.wantErr)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("Send(%s): %v", tt.amount, err)
+			}
+
+			balance, err := svc.GetBalance(ctx, to.Address)
+			if err != nil {
+				t.Fatalf("GetBalance: %v", err)
+			}
+			want := decimal.Zero
+			if !tt.wantErr {
+				want = decimal.RequireFromString(tt.amount)
+			}
+			if !balance.Equal(want) {
+				t.Errorf("receiver balance = %s, want %s", balance, want)
+			}
+		})
+	}
+}
+
+// TestSendCategory проверяет список SetCategories: перевод с категорией из списка и без
+// категории проходит, с другой категорией отклоняется *CategoryError с отсортированным
+// списком допустимых, а без списка принимается любая категория.
+func TestSendCategory(t *testing.T) {
+	tests := []struct {
+		name     string
+		allowed  []string
+		category string
+		wantErr  bool
+	}{
+		{"no allowlist", nil, "anything", false},
+		{"empty allowlist", []string{}, "anything", false},
+		{"allowed", []string{"salary", "fee", "refund"}, "fee", false},
+		{"no category", []string{"salary", "fee", "refund"}, "", false},
+		{"unknown", []string{"salary", "fee", "refund"}, "bonus", true},
+		{"case sensitive", []string{"salary", "fee", "refund"}, "Fee", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := db.NewMemoryRepository()
+			svc := NewService(repo)
+			svc.SetCategories(tt.allowed)
+			from, _, err := svc.CreateWallet(ctx, decimal.NewFromInt(100), models.WalletMetadata{})
+			if err != nil {
+				t.Fatalf("CreateWallet: %v", err)
+			}
+			to, _, err := svc.CreateWallet(ctx, decimal.Zero, models.WalletMetadata{})
+			if err != nil {
+				t.Fatalf("CreateWallet: %v", err)
+			}
+
+			_, err = svc.Send(ctx, from.Address, to.Address, decimal.NewFromInt(1), "", tt.category, nil, sql.LevelDefault)
+			var categoryErr *CategoryError
+			if tt.wantErr {
+				if !errors.As(err, &categoryErr) || !errors.Is(err, ErrUnknownCategory) {
+					t.Fatalf("Send = %v, want *CategoryError", err)
+				}
+				if want := []string{"fee", "refund", "salary"}; !slices.Equal(categoryErr.Allowed, want) {
+					t.Errorf("Allowed = %v, want %v", categoryErr.Allowed, want)
+				}
+			} else if err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+
+			want := int64(1)
+			if tt.wantErr {
+				want = 0
+			}
+			if count, err := repo.CountTransactions(ctx, db.TransactionFilter{}); err != nil || count != want {
+				t.Errorf("CountTransactions = %d, %v, want %d", count, err, want)
+			}
+			if tt.category != "" {
+				count, err := repo.CountTransactions(ctx, db.TransactionFilter{Category: tt.category})
+				if err != nil || count != want {
+					t.Errorf("CountTransactions(category=%s) = %d, %v, want %d", tt.category, count, err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer создает спаны операций сервиса. Пока TracerProvider не установлен (см. tracing.Setup),
+// спаны не записываются.
+var tracer = otel.Tracer("payment-system/internal/service")
+
+// startSpan начинает спан операции сервиса "service.<name>", дочерний спану из ctx
+// (обычно спану HTTP-запроса). Спаны запросов к базе данных становятся его дочерними,
+// если операция передает репозиторию возвращенный контекст.
+//
+// Пример использования:
+//
+//	ctx, span := startSpan(ctx, "Send", attribute.String(logging.KeyWallet, from))
+//	defer func() { endSpan(span, err) }()
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "service."+name, trace.WithAttributes(attrs...))
+}
+
+// endSpan завершает спан и, если операция вернула ошибку, записывает ее в спан
+// и отмечает спан ошибкой.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
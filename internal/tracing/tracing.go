@@ -0,0 +1,76 @@
+// Package tracing настраивает распределенную трассировку OpenTelemetry: экспорт спанов
+// по OTLP/HTTP и распространение контекста трассировки в заголовках W3C traceparent
+// и tracestate. Спаны создают HTTP-слой (api.TracingMiddleware), сервис и драйвер базы
+// данных (db.openDB) через глобальный TracerProvider, который устанавливает Setup.
+//
+// Экспорт настраивается стандартными переменными окружения OpenTelemetry:
+// OTEL_EXPORTER_OTLP_ENDPOINT (или OTEL_EXPORTER_OTLP_TRACES_ENDPOINT), OTEL_EXPORTER_OTLP_HEADERS,
+// OTEL_SERVICE_NAME, OTEL_RESOURCE_ATTRIBUTES, OTEL_TRACES_SAMPLER и т.д.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// DefaultServiceName - имя сервиса в спанах, если OTEL_SERVICE_NAME не задана.
+const DefaultServiceName = "payment-system"
+
+// Enabled сообщает, задан ли адрес сборщика OTLP: без него спаны не экспортируются.
+func Enabled() bool {
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// Setup устанавливает распространение контекста W3C (traceparent, tracestate и baggage)
+// и, если задан адрес сборщика (см. Enabled), TracerProvider с пакетным экспортом по OTLP/HTTP.
+// Без адреса сборщика спаны не записываются, но идентификатор трассировки из входящего
+// traceparent передается дальше и попадает в журнал.
+//
+// Параметры:
+//   - ctx: Контекст создания экспортера.
+//
+// Возвращает:
+//   - Функцию остановки, которая отправляет накопленные спаны; вызывается при завершении
+//     программы с ограничением времени.
+//   - Ошибку, если настройки экспорта некорректны.
+//
+// Пример использования:
+//
+//	shutdown, err := tracing.Setup(ctx)
+//	defer shutdown(shutdownCtx)
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if !Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+	// OTEL_SERVICE_NAME и OTEL_RESOURCE_ATTRIBUTES переопределяют имя по умолчанию
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(DefaultServiceName)),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracing resource: %w", err)
+	}
+
+	// Сэмплер читается из OTEL_TRACES_SAMPLER (по умолчанию parentbased_always_on)
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,85 @@
+// Package address проверяет адреса кошельков платежной системы и формирует их запись
+// с контрольной суммой. Пакет предназначен и для клиентов: адрес с опечаткой можно отклонить
+// до отправки перевода.
+//
+// Контрольная сумма кодируется регистром букв, как в EIP-55, но с хешем SHA-512 вместо
+// Keccak-256: буква a-f i-го символа записывается заглавной, если i-й полубайт хеша SHA-512
+// от адреса в нижнем регистре не меньше 8. Адрес в нижнем (или только в верхнем) регистре
+// контрольной суммы не содержит и принимается без проверки, поэтому существующие адреса
+// продолжают работать. Сервис хранит адреса в нижнем регистре; подпись перевода
+// (пакет signature) формируется по адресам в нижнем регистре.
+package address
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// Ошибки проверки адреса.
+var (
+	ErrInvalid  = errors.New("address must be hexadecimal")
+	ErrChecksum = errors.New("address checksum mismatch")
+)
+
+// Checksum возвращает адрес с контрольной суммой в регистре букв.
+//
+// Параметры:
+//   - address: Адрес кошелька в шестнадцатеричном виде в любом регистре (до 128 символов).
+//
+// Возвращает:
+//   - Адрес с контрольной суммой, например "0F3a...".
+//
+// Пример использования:
+//
+//	display := address.Checksum(wallet.Address)
+func Checksum(address string) string {
+	lower := strings.ToLower(address)
+	hash := sha512.Sum512([]byte(lower))
+	result := []byte(lower)
+	for i, c := range result {
+		if c < 'a' || c > 'f' || i >= 2*len(hash) {
+			continue
+		}
+		nibble := hash[i/2] >> 4
+		if i%2 == 1 {
+			nibble = hash[i/2] & 0x0f
+		}
+		if nibble >= 8 {
+			result[i] = c - 'a' + 'A'
+		}
+	}
+	return string(result)
+}
+
+// Validate проверяет, что адрес шестнадцатеричный и, если в нем есть буквы разного регистра,
+// что контрольная сумма совпадает. Длину адреса (ADDRESS_BYTES сервиса) Validate не проверяет.
+//
+// Параметры:
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - ErrInvalid, если адрес пуст или содержит не шестнадцатеричные символы;
+//     ErrChecksum, если контрольная сумма не совпадает.
+//
+// Пример использования:
+//
+//	if err := address.Validate(to); errors.Is(err, address.ErrChecksum) {
+//		// вероятна опечатка в адресе
+//	}
+func Validate(address string) error {
+	if address == "" {
+		return ErrInvalid
+	}
+	if _, err := hex.DecodeString(address); err != nil {
+		return ErrInvalid
+	}
+	if address == strings.ToLower(address) || address == strings.ToUpper(address) {
+		return nil
+	}
+	if Checksum(address) != address {
+		return ErrChecksum
+	}
+	return nil
+}
@@ -0,0 +1,534 @@
+// Package client - клиент REST API платежной системы (маршруты /api/v1) для интеграций на Go.
+// Клиент разбирает ответы в типизированные структуры, сообщает ошибки сервиса значениями,
+// сравнимыми через errors.Is (ErrInsufficientFunds и т.д.), и повторяет запросы при сетевых
+// ошибках и временной недоступности сервиса. Перевод повторяется с тем же заголовком
+// Idempotency-Key, поэтому повтор после обрыва соединения не выполняет перевод дважды.
+//
+// Пример использования:
+//
+//	c, err := client.New("https://payments.example.com", client.WithTimeout(5*time.Second))
+//	transfer, err := c.SendMoney(ctx, from, to, decimal.RequireFromString("10.5"), client.WithMemo("invoice 42"))
+//	if errors.Is(err, client.ErrInsufficientFunds) { ... }
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Money - сумма перевода или баланса. Суммы передаются точно, без преобразования в float64.
+type Money = decimal.Decimal
+
+// Значения настроек клиента по умолчанию.
+const (
+	DefaultTimeout    = 10 * time.Second       // Ограничение времени одной попытки запроса
+	DefaultMaxRetries = 3                      // Количество повторов после неудачной попытки
+	DefaultRetryDelay = 200 * time.Millisecond // Пауза перед первым повтором; удваивается с каждым
+)
+
+// idempotencyKeyHeader - заголовок ключа идемпотентности перевода.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// Client - клиент API платежной системы. Безопасен для одновременного использования
+// из нескольких горутин.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	apiKey     string
+	timeout    time.Duration
+	maxRetries int
+	retryDelay time.Duration
+}
+
+// Option - настройка клиента для New.
+type Option func(*Client)
+
+// WithTimeout задает ограничение времени одной попытки запроса (по умолчанию DefaultTimeout);
+// 0 - без ограничения, кроме контекста вызова.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) { c.timeout = timeout }
+}
+
+// WithAPIKey задает ключ, передаваемый в заголовке "Authorization: Bearer <key>"
+// (токен администратора или ключ прокси перед сервисом).
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithHTTPClient задает HTTP-клиент (например, с собственным Transport или TLS-настройками).
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithRetries задает количество повторов запроса и паузу перед первым повтором
+// (по умолчанию DefaultMaxRetries и DefaultRetryDelay); 0 повторов выключает их.
+func WithRetries(maxRetries int, delay time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.retryDelay = delay
+	}
+}
+
+// New создает клиент API.
+//
+// Параметры:
+//   - baseURL: Адрес сервиса, например "https://payments.example.com"; с префиксом маршрутов
+//     сервиса (API_BASE_PATH) - вместе с ним: "https://gateway.example.com/payments/v1".
+//   - opts: Настройки клиента.
+//
+// Возвращает:
+//   - Клиент.
+//   - Ошибку, если адрес сервиса некорректен.
+//
+// Пример использования:
+//
+//	c, err := client.New("http://localhost:8080", client.WithAPIKey(token), client.WithRetries(5, time.Second))
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q: expected http(s)://host[:port]", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		timeout:    DefaultTimeout,
+		maxRetries: DefaultMaxRetries,
+		retryDelay: DefaultRetryDelay,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.maxRetries < 0 || c.retryDelay < 0 || c.timeout < 0 {
+		return nil, errors.New("retries, retry delay and timeout must not be negative")
+	}
+	return c, nil
+}
+
+// Party - участник перевода: адрес кошелька и метка, если перевод указан меткой.
+type Party struct {
+	Address string `json:"address"`
+	Label   string `json:"label,omitempty"`
+}
+
+// Transfer - результат перевода.
+type Transfer struct {
+	From          Party     `json:"from"`
+	To            Party     `json:"to"`
+	TransactionID int       `json:"transaction_id"`
+	SenderBalance Money     `json:"sender_balance"` // Баланс отправителя после списания
+	CreatedAt     time.Time `json:"created_at"`
+
+	// ReceiverBalance - баланс получателя после перевода; сервис сообщает его только
+	// запросу с токеном администратора, иначе nil
+	ReceiverBalance *Money `json:"to_balance,omitempty"`
+
+	// ApprovalID не равен нулю, если перевод отложен до подтверждения администратором;
+	// тогда транзакции еще нет, а состояние перевода сообщает StatusURL
+	ApprovalID int64  `json:"approval_id,omitempty"`
+	StatusURL  string `json:"status_url,omitempty"`
+
+	// Replayed - ответ получен повтором по ключу идемпотентности: перевод был выполнен
+	// предыдущей попыткой
+	Replayed bool `json:"-"`
+}
+
+// sendRequest - тело POST /api/v1/send и ключ идемпотентности.
+type sendRequest struct {
+	From      string      `json:"from"`
+	To        string      `json:"to"`
+	Amount    json.Number `json:"amount"`
+	Memo      string      `json:"memo,omitempty"`
+	Category  string      `json:"category,omitempty"`
+	Nonce     int64       `json:"nonce,omitempty"`
+	Signature string      `json:"signature,omitempty"`
+
+	idempotencyKey string
+}
+
+// SendOption - необязательный параметр перевода для SendMoney.
+type SendOption func(*sendRequest)
+
+// WithMemo задает комментарий к переводу (до 256 символов).
+func WithMemo(memo string) SendOption {
+	return func(r *sendRequest) { r.Memo = memo }
+}
+
+// WithCategory задает категорию перевода для отчетов (до 64 символов, например "salary").
+func WithCategory(category string) SendOption {
+	return func(r *sendRequest) { r.Category = category }
+}
+
+// WithSignature передает подпись перевода ключом кошелька отправителя (см. пакет signature).
+func WithSignature(nonce int64, signature string) SendOption {
+	return func(r *sendRequest) {
+		r.Nonce = nonce
+		r.Signature = signature
+	}
+}
+
+// WithIdempotencyKey задает ключ идемпотентности перевода. По умолчанию SendMoney создает
+// случайный ключ на каждый вызов; собственный ключ (например, номер платежного поручения)
+// защищает и от повторного вызова SendMoney после перезапуска приложения.
+func WithIdempotencyKey(key string) SendOption {
+	return func(r *sendRequest) { r.idempotencyKey = key }
+}
+
+// SendMoney переводит amount с кошелька from на кошелек to (адрес или метка с префиксом "@").
+// Все попытки передаются с одним ключом идемпотентности, поэтому перевод выполняется
+// не больше одного раза.
+//
+// Параметры:
+//   - ctx: Контекст вызова; его отмена прекращает попытки.
+//   - from, to: Отправитель и получатель.
+//   - amount: Сумма перевода.
+//   - opts: Необязательные параметры (WithMemo, WithCategory, WithSignature, WithIdempotencyKey).
+//
+// Возвращает:
+//   - Результат перевода; для отложенного перевода заполнены только ApprovalID и StatusURL.
+//   - Ошибку; ошибки сервиса - *Error, сравнимая через errors.Is с ErrInsufficientFunds и т.д.
+//
+// Пример использования:
+//
+//	transfer, err := c.SendMoney(ctx, "@ops-float", to, decimal.RequireFromString("10.5"))
+func (c *Client) SendMoney(ctx context.Context, from, to string, amount Money, opts ...SendOption) (Transfer, error) {
+	req := sendRequest{From: from, To: to, Amount: json.Number(amount.String())}
+	for _, opt := range opts {
+		opt(&req)
+	}
+	if req.idempotencyKey == "" {
+		key, err := newIdempotencyKey()
+		if err != nil {
+			return Transfer{}, err
+		}
+		req.idempotencyKey = key
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return Transfer{}, fmt.Errorf("failed to encode request: %w", err)
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set(idempotencyKeyHeader, req.idempotencyKey)
+
+	resp, err := c.do(ctx, http.MethodPost, "/api/v1/send", nil, header, body)
+	if err != nil {
+		return Transfer{}, err
+	}
+
+	var transfer Transfer
+	if err := json.Unmarshal(resp.body, &transfer); err != nil {
+		return Transfer{}, fmt.Errorf("failed to decode send response: %w", err)
+	}
+	transfer.Replayed = resp.header.Get("Idempotent-Replayed") == "true"
+	return transfer, nil
+}
+
+// Balance - баланс кошелька.
+type Balance struct {
+	Total     Money             `json:"total"`     // Баланс кошелька
+	Reserved  Money             `json:"reserved"`  // Сумма переводов, ожидающих подтверждения
+	Available Money             `json:"available"` // Остаток, который можно отправить
+	Label     string            `json:"label,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Currency  string            `json:"currency,omitempty"` // Валюта для отображения; пусто, если не настроена
+}
+
+// GetBalance возвращает баланс кошелька.
+//
+// Параметры:
+//   - ctx: Контекст вызова.
+//   - address: Адрес кошелька.
+//
+// Возвращает:
+//   - Баланс, резерв и доступный остаток.
+//   - Ошибку; ErrNotFound, если кошелька нет.
+//
+// Пример использования:
+//
+//	balance, err := c.GetBalance(ctx, address)
+func (c *Client) GetBalance(ctx context.Context, address string) (Balance, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/api/v1/wallet/"+url.PathEscape(address)+"/balance", nil, nil, nil)
+	if err != nil {
+		return Balance{}, err
+	}
+
+	var balance Balance
+	if err := json.Unmarshal(resp.body, &balance); err != nil {
+		return Balance{}, fmt.Errorf("failed to decode balance: %w", err)
+	}
+	return balance, nil
+}
+
+// Transaction - выполненная транзакция.
+type Transaction struct {
+	ID         int       `json:"id"`
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	Amount     Money     `json:"amount"`
+	CreatedAt  time.Time `json:"created_at"`
+	Memo       string    `json:"memo,omitempty"`
+	Category   string    `json:"category,omitempty"`
+	ExternalID string    `json:"external_id,omitempty"` // Идентификатор импортированной транзакции
+	Imported   bool      `json:"imported,omitempty"`
+	Type       string    `json:"type"` // "transfer" или операция с системным счетом ("mint", "fee" и др.)
+}
+
+// ListOptions - параметры ListTransactions. Нулевое значение запрашивает последние
+// транзакции в количестве по умолчанию.
+type ListOptions struct {
+	Count           int    // Количество транзакций; 0 - по умолчанию сервиса (20)
+	Amount          *Money // Только переводы на эту сумму
+	Category        string // Только переводы этой категории
+	IncludeArchived bool   // Искать и среди перенесенных в архив
+
+	// Cursor - позиция следующей страницы (TransactionPage.NextCursor предыдущего ответа);
+	// пусто - первая страница
+	Cursor string
+}
+
+// TransactionPage - страница списка транзакций.
+type TransactionPage struct {
+	Transactions []Transaction
+
+	// NextCursor - значение ListOptions.Cursor для следующей страницы; пусто, если страница
+	// последняя
+	NextCursor string
+}
+
+// ListTransactions возвращает последние транзакции, начиная с самой новой.
+//
+// Параметры:
+//   - ctx: Контекст вызова.
+//   - opts: Параметры выборки.
+//
+// Возвращает:
+//   - Список транзакций (пустой, если транзакций нет).
+//   - Ошибку запроса.
+//
+// Пример использования:
+//
+//	transactions, err := c.ListTransactions(ctx, client.ListOptions{Count: 50})
+func (c *Client) ListTransactions(ctx context.Context, opts ListOptions) ([]Transaction, error) {
+	page, err := c.ListTransactionsPage(ctx, opts)
+	return page.Transactions, err
+}
+
+// ListTransactionsPage возвращает страницу последних транзакций и курсор следующей страницы
+// (заголовок X-Next-Cursor). Транзакции, выполненные во время обхода, в следующие страницы
+// не попадают, а уже полученные не повторяются.
+//
+// Параметры:
+//   - ctx: Контекст вызова.
+//   - opts: Параметры выборки; Cursor - позиция, полученная с предыдущей страницей.
+//
+// Возвращает:
+//   - Страницу транзакций.
+//   - Ошибку запроса; ErrInvalidRequest, если курсор некорректен.
+//
+// Пример использования:
+//
+//	opts := client.ListOptions{Count: 100}
+//	for {
+//		page, err := c.ListTransactionsPage(ctx, opts)
+//		...
+//		if page.NextCursor == "" {
+//			break
+//		}
+//		opts.Cursor = page.NextCursor
+//	}
+func (c *Client) ListTransactionsPage(ctx context.Context, opts ListOptions) (TransactionPage, error) {
+	query := url.Values{}
+	if opts.Count > 0 {
+		query.Set("count", strconv.Itoa(opts.Count))
+	}
+	if opts.Amount != nil {
+		query.Set("amount", opts.Amount.String())
+	}
+	if opts.Category != "" {
+		query.Set("category", opts.Category)
+	}
+	if opts.IncludeArchived {
+		query.Set("include_archived", "true")
+	}
+	if opts.Cursor != "" {
+		query.Set("cursor", opts.Cursor)
+	}
+
+	resp, err := c.do(ctx, http.MethodGet, "/api/v1/transactions", query, nil, nil)
+	if err != nil {
+		return TransactionPage{}, err
+	}
+
+	page := TransactionPage{NextCursor: resp.header.Get("X-Next-Cursor")}
+	if err := json.Unmarshal(resp.body, &page.Transactions); err != nil {
+		return TransactionPage{}, fmt.Errorf("failed to decode transactions: %w", err)
+	}
+	return page, nil
+}
+
+// Wallet - кошелек, созданный CreateWallet.
+type Wallet struct {
+	Address    string            `json:"address"`
+	Balance    Money             `json:"balance"`
+	Label      string            `json:"label,omitempty"`
+	Tags       map[string]string `json:"tags,omitempty"`
+	PublicKey  string            `json:"public_key"`  // Открытый ключ ed25519 для проверки подписей переводов
+	PrivateKey string            `json:"private_key"` // Закрытый ключ; сервис его не хранит и больше не вернет
+}
+
+// CreateWallet создает кошелек со случайным адресом и парой ключей подписи
+// (POST /api/admin/wallets; требуется токен администратора, см. WithAPIKey).
+// Запрос не повторяется после сетевой ошибки: повтор мог бы создать второй кошелек.
+//
+// Параметры:
+//   - ctx: Контекст вызова.
+//   - balance: Начальный баланс.
+//   - label: Метка кошелька; пустая строка - без метки.
+//   - tags: Теги кошелька (может быть nil).
+//
+// Возвращает:
+//   - Созданный кошелек с закрытым ключом.
+//   - Ошибку; ErrUnauthorized без токена администратора, *Error с кодом "label_exists",
+//     если метка занята.
+//
+// Пример использования:
+//
+//	wallet, err := c.CreateWallet(ctx, decimal.NewFromInt(100), "settlement-EUR", map[string]string{"currency": "EUR"})
+func (c *Client) CreateWallet(ctx context.Context, balance Money, label string, tags map[string]string) (Wallet, error) {
+	body, err := json.Marshal(struct {
+		Balance json.Number       `json:"balance"`
+		Label   string            `json:"label,omitempty"`
+		Tags    map[string]string `json:"tags,omitempty"`
+	}{json.Number(balance.String()), label, tags})
+	if err != nil {
+		return Wallet{}, fmt.Errorf("failed to encode request: %w", err)
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(ctx, http.MethodPost, "/api/admin/wallets", nil, header, body)
+	if err != nil {
+		return Wallet{}, err
+	}
+
+	var wallet Wallet
+	if err := json.Unmarshal(resp.body, &wallet); err != nil {
+		return Wallet{}, fmt.Errorf("failed to decode wallet: %w", err)
+	}
+	return wallet, nil
+}
+
+// response - успешный ответ сервиса.
+type response struct {
+	header http.Header
+	body   []byte
+}
+
+// do выполняет запрос, повторяя его при сетевой ошибке и ответах, после которых запрос
+// безопасно повторить (503, в том числе overloaded, конфликт конкурирующих переводов,
+// выполняющийся запрос с тем же ключом идемпотентности). Пауза между попытками удваивается,
+// но не бывает меньше Retry-After ответа; отмена ctx прекращает попытки.
+// Запрос, изменяющий данные без ключа идемпотентности, после сетевой ошибки не повторяется:
+// он мог быть выполнен, а ответ - потеряться.
+//
+// Возвращает:
+//   - Ответ с кодом 2xx.
+//   - *Error для ответа с ошибкой или ошибку последней попытки.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, header http.Header, body []byte) (response, error) {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	idempotent := method == http.MethodGet || header.Get(idempotencyKeyHeader) != ""
+	delay := c.retryDelay
+	for attempt := 0; ; attempt++ {
+		resp, retry, err := c.attempt(ctx, method, target, header, body)
+		var apiErr *Error
+		isAPIErr := errors.As(err, &apiErr)
+		if !retry || attempt >= c.maxRetries || (!idempotent && !isAPIErr) {
+			return resp, err
+		}
+
+		wait := delay
+		if apiErr != nil && apiErr.RetryAfter > wait {
+			wait = apiErr.RetryAfter
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return response{}, fmt.Errorf("%w (last attempt: %v)", ctx.Err(), err)
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// attempt выполняет одну попытку запроса с ограничением времени c.timeout.
+//
+// Возвращает:
+//   - Ответ с кодом 2xx.
+//   - true, если запрос безопасно повторить.
+//   - Ошибку попытки.
+func (c *Client) attempt(ctx context.Context, method, target string, header http.Header, body []byte) (response, bool, error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return response{}, false, fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// Сетевая ошибка или истекшее время попытки (*url.Error с методом и адресом запроса);
+		// отмену контекста вызова проверяет do
+		return response{}, true, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return response{}, true, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		apiErr := parseError(resp.StatusCode, resp.Header, data)
+		return response{}, apiErr.retryable(), apiErr
+	}
+	return response{header: resp.Header, body: data}, false, nil
+}
+
+// newIdempotencyKey создает случайный ключ идемпотентности.
+func newIdempotencyKey() (string, error) {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate idempotency key: %w", err)
+	}
+	return hex.EncodeToString(key), nil
+}
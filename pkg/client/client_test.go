@@ -0,0 +1,382 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	api "payment-system/internal/api"
+	db "payment-system/internal/db"
+	models "payment-system/internal/models"
+	service "payment-system/internal/service"
+	"payment-system/pkg/signature"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+// testAdminToken - токен администратора тестового сервера.
+const testAdminToken = "test-admin-token"
+
+// testServer - маршруты API v1 и создания кошелька, запущенные в httptest.Server поверх
+// хранилища в памяти, и запросы, которые они получили.
+type testServer struct {
+	*httptest.Server
+	svc *service.Service
+
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+// newTestServer запускает тестовый сервер. intercept (если задан) получает каждый запрос
+// вместо маршрутизатора вместе с номером запроса, начиная с 1, и сам решает, передать ли его next.
+func newTestServer(t *testing.T, intercept func(w http.ResponseWriter, r *http.Request, call int, next http.Handler)) *testServer {
+	t.Helper()
+	s := &testServer{svc: service.NewService(db.NewMemoryRepository())}
+
+	router := mux.NewRouter()
+	router.NotFoundHandler = api.NotFoundHandler()
+	maintenance := api.NewMaintenanceMode(false)
+	api.RegisterV1(router, s.svc, maintenance, nil, api.RoutesConfig{
+		MaxTransactionsCount: 100,
+		BalanceScale:         2,
+		IdempotencyTTL:       api.DefaultIdempotencyTTL,
+		AdminToken:           testAdminToken,
+	})
+	router.Handle("/api/admin/wallets",
+		api.AdminAuthMiddleware(testAdminToken)(maintenance.Middleware(api.CreateWalletHandler(s.svc)))).Methods("POST")
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r)
+		call := len(s.requests)
+		s.mu.Unlock()
+		if intercept != nil {
+			intercept(w, r, call, router)
+			return
+		}
+		router.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// client возвращает клиент тестового сервера с короткими паузами между повторами.
+func (s *testServer) client(t *testing.T, opts ...Option) *Client {
+	t.Helper()
+	c, err := New(s.URL, append([]Option{WithRetries(3, time.Millisecond)}, opts...)...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return c
+}
+
+// wallet создает кошелек с балансом balance и возвращает его адрес и закрытый ключ.
+func (s *testServer) wallet(t *testing.T, balance string) (string, string) {
+	t.Helper()
+	wallet, privateKey, err := s.svc.CreateWallet(context.Background(), decimal.RequireFromString(balance), models.WalletMetadata{})
+	if err != nil {
+		t.Fatalf("CreateWallet(%s): %v", balance, err)
+	}
+	return wallet.Address, privateKey
+}
+
+// requestCount возвращает количество полученных запросов.
+func (s *testServer) requestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.requests)
+}
+
+// idempotencyKeys возвращает заголовки Idempotency-Key полученных запросов.
+func (s *testServer) idempotencyKeys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.requests))
+	for _, r := range s.requests {
+		keys = append(keys, r.Header.Get(idempotencyKeyHeader))
+	}
+	return keys
+}
+
+// TestSendMoneyRetry проверяет повтор перевода: после обрыва соединения или 503, когда перевод
+// уже выполнен, повтор передается с тем же ключом идемпотентности и получает сохраненный
+// ответ (Replayed), а перевод, отклоненный из-за нагрузки, выполняется повтором после Retry-After.
+func TestSendMoneyRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		fail         func(w http.ResponseWriter, r *http.Request, next http.Handler)
+		wantReplayed bool
+		wantDelay    time.Duration
+	}{
+		{"network error after the transfer", func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+			next.ServeHTTP(httptest.NewRecorder(), r)
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Errorf("Hijack: %v", err)
+				return
+			}
+			conn.Close()
+		}, true, 0},
+		{"unavailable after the transfer", func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+			next.ServeHTTP(httptest.NewRecorder(), r)
+			http.Error(w, "Service temporarily unavailable, retry later", http.StatusServiceUnavailable)
+		}, true, 0},
+		{"overloaded", func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":{"code":"overloaded","message":"Too many concurrent requests, retry later"}}`))
+		}, false, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, func(w http.ResponseWriter, r *http.Request, call int, next http.Handler) {
+				if call == 1 {
+					tt.fail(w, r, next)
+					return
+				}
+				next.ServeHTTP(w, r)
+			})
+			from, _ := s.wallet(t, "100")
+			to, _ := s.wallet(t, "0")
+			ctx := context.Background()
+
+			start := time.Now()
+			transfer, err := s.client(t).SendMoney(ctx, from, to, decimal.RequireFromString("10.5"))
+			if err != nil {
+				t.Fatalf("SendMoney: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed < tt.wantDelay {
+				t.Errorf("retried after %s, want at least Retry-After %s", elapsed, tt.wantDelay)
+			}
+			if transfer.Replayed != tt.wantReplayed {
+				t.Errorf("Replayed = %t, want %t", transfer.Replayed, tt.wantReplayed)
+			}
+			keys := s.idempotencyKeys()
+			if len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+				t.Errorf("Idempotency-Key of the attempts = %q, want the same key twice", keys)
+			}
+
+			balance, err := s.svc.GetBalance(ctx, from)
+			if err != nil {
+				t.Fatalf("GetBalance: %v", err)
+			}
+			if want := decimal.RequireFromString("89.5"); !balance.Equal(want) || !transfer.SenderBalance.Equal(want) {
+				t.Errorf("sender balance = %s, response %s, want %s (debited once)", balance, transfer.SenderBalance, want)
+			}
+		})
+	}
+}
+
+// TestErrorCodesEmitted проверяет, что каждый код errorCodes - код, которым отвечают обработчики
+// API, чтобы переименование кода на сервере не сделало ошибку клиента несравнимой.
+// unavailable клиент присваивает сам ответу 503 без JSON.
+func TestErrorCodesEmitted(t *testing.T) {
+	files, err := filepath.Glob("../../internal/api/*.go")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("Glob: %v, %d files", err, len(files))
+	}
+	emitted := make(map[string]bool)
+	literal := regexp.MustCompile(`"([a-z_]+)"`)
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		source, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		for _, m := range literal.FindAllStringSubmatch(string(source), -1) {
+			emitted[m[1]] = true
+		}
+	}
+
+	for target, code := range errorCodes {
+		if code != codeUnavailable && !emitted[code] {
+			t.Errorf("%v maps to %q, which internal/api never emits", target, code)
+		}
+	}
+}
+
+// TestSendMoneyErrors проверяет ошибки перевода, полученные от обработчиков: код сравним
+// с ошибкой пакета, подробности разобраны, а запрос не повторяется.
+func TestSendMoneyErrors(t *testing.T) {
+	s := newTestServer(t, nil)
+	from, privateKey := s.wallet(t, "100")
+	to, _ := s.wallet(t, "0")
+	c := s.client(t)
+	ctx := context.Background()
+
+	signed := func(nonce int64) SendOption {
+		sig, err := signature.Sign(privateKey, from, to, decimal.NewFromInt(1), nonce)
+		if err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+		return WithSignature(nonce, sig)
+	}
+
+	tests := []struct {
+		name   string
+		from   string
+		to     string
+		amount string
+		opts   []SendOption
+		want   error
+		check  func(t *testing.T, e *Error)
+	}{
+		{"insufficient funds", from, to, "1000", nil, ErrInsufficientFunds, nil},
+		{"nonce mismatch", from, to, "1", []SendOption{signed(5)}, ErrNonceMismatch, func(t *testing.T, e *Error) {
+			if e.ExpectedNonce != 1 {
+				t.Errorf("ExpectedNonce = %d, want 1", e.ExpectedNonce)
+			}
+		}},
+		{"validation failed", from, to, "-5", nil, ErrValidation, func(t *testing.T, e *Error) {
+			if len(e.Violations) != 1 || e.Violations[0].Field != "amount" || e.Violations[0].Message == "" {
+				t.Errorf("Violations = %+v, want one violation of amount", e.Violations)
+			}
+		}},
+		{"self transfer", from, from, "1", nil, ErrSelfTransfer, nil},
+		{"label not found", "@nobody", to, "1", nil, ErrLabelNotFound, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := s.requestCount()
+			_, err := c.SendMoney(ctx, tt.from, tt.to, decimal.RequireFromString(tt.amount), tt.opts...)
+			var apiErr *Error
+			if !errors.Is(err, tt.want) || !errors.As(err, &apiErr) {
+				t.Fatalf("SendMoney = %v, want %v", err, tt.want)
+			}
+			if tt.check != nil {
+				tt.check(t, apiErr)
+			}
+			if got := s.requestCount() - before; got != 1 {
+				t.Errorf("sent %d requests, want 1", got)
+			}
+		})
+	}
+}
+
+func TestGetBalance(t *testing.T) {
+	s := newTestServer(t, nil)
+	address, _ := s.wallet(t, "10.5")
+	c := s.client(t)
+	ctx := context.Background()
+
+	balance, err := c.GetBalance(ctx, address)
+	if err != nil {
+		t.Fatalf("GetBalance: %v", err)
+	}
+	want := decimal.RequireFromString("10.5")
+	if !balance.Total.Equal(want) || !balance.Available.Equal(want) || !balance.Reserved.IsZero() {
+		t.Errorf("GetBalance = %+v, want total and available 10.5", balance)
+	}
+
+	missing, err := db.GenerateAddress()
+	if err != nil {
+		t.Fatalf("GenerateAddress: %v", err)
+	}
+	if _, err := c.GetBalance(ctx, missing); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetBalance(missing) = %v, want ErrNotFound", err)
+	}
+}
+
+// TestListTransactionsPages проверяет обход списка транзакций по курсору: страницы идут
+// от новых к старым без пропусков и повторов, у последней нет курсора, а некорректный
+// курсор отклоняется ErrInvalidRequest.
+func TestListTransactionsPages(t *testing.T) {
+	s := newTestServer(t, nil)
+	from, _ := s.wallet(t, "100")
+	to, _ := s.wallet(t, "0")
+	c := s.client(t)
+	ctx := context.Background()
+	for range 5 {
+		if _, err := c.SendMoney(ctx, from, to, decimal.NewFromInt(1)); err != nil {
+			t.Fatalf("SendMoney: %v", err)
+		}
+	}
+
+	var ids []int
+	var sizes []int
+	opts := ListOptions{Count: 2}
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("paging does not end")
+		}
+		page, err := c.ListTransactionsPage(ctx, opts)
+		if err != nil {
+			t.Fatalf("ListTransactionsPage(%+v): %v", opts, err)
+		}
+		sizes = append(sizes, len(page.Transactions))
+		for _, tx := range page.Transactions {
+			ids = append(ids, tx.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+
+	// Последняя страница полна, поэтому за ней следует пустая
+	if want := []int{2, 2, 1}; !slices.Equal(sizes, want) {
+		t.Errorf("page sizes = %v, want %v", sizes, want)
+	}
+	for i := 1; i < len(ids); i++ {
+		if ids[i] >= ids[i-1] {
+			t.Errorf("transaction ids %v are not strictly descending", ids)
+			break
+		}
+	}
+	all, err := c.ListTransactions(ctx, ListOptions{Count: 10})
+	if err != nil || len(all) != len(ids) {
+		t.Errorf("ListTransactions = %d transactions, %v, want %d", len(all), err, len(ids))
+	}
+
+	if _, err := c.ListTransactionsPage(ctx, ListOptions{Cursor: "not-a-cursor"}); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("ListTransactionsPage(bad cursor) = %v, want ErrInvalidRequest", err)
+	}
+}
+
+// TestCreateWallet проверяет создание кошелька: с токеном администратора кошелек создается
+// с закрытым ключом, занятая метка отклоняется ErrLabelExists, а без токена или с чужим
+// токеном - ErrUnauthorized без повтора.
+func TestCreateWallet(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+
+	admin := s.client(t, WithAPIKey(testAdminToken))
+	wallet, err := admin.CreateWallet(ctx, decimal.RequireFromString("100"), "ops-float", map[string]string{"currency": "EUR"})
+	if err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+	if wallet.Address == "" || wallet.PrivateKey == "" || wallet.PublicKey == "" {
+		t.Errorf("CreateWallet = %+v, want an address and a key pair", wallet)
+	}
+	if !wallet.Balance.Equal(decimal.NewFromInt(100)) || wallet.Label != "ops-float" || wallet.Tags["currency"] != "EUR" {
+		t.Errorf("CreateWallet = %+v, want balance 100, label ops-float and tag currency=EUR", wallet)
+	}
+	if _, err := admin.CreateWallet(ctx, decimal.Zero, "ops-float", nil); !errors.Is(err, ErrLabelExists) {
+		t.Errorf("CreateWallet(taken label) = %v, want ErrLabelExists", err)
+	}
+
+	for name, opts := range map[string][]Option{"no token": nil, "wrong token": {WithAPIKey("guess")}} {
+		t.Run(name, func(t *testing.T) {
+			before := s.requestCount()
+			if _, err := s.client(t, opts...).CreateWallet(ctx, decimal.NewFromInt(100), "", nil); !errors.Is(err, ErrUnauthorized) {
+				t.Errorf("CreateWallet = %v, want ErrUnauthorized", err)
+			}
+			if got := s.requestCount() - before; got != 1 {
+				t.Errorf("sent %d requests, want 1", got)
+			}
+		})
+	}
+}
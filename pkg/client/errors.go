@@ -0,0 +1,189 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ошибки API, соответствующие кодам ошибок сервиса. Конкретная ошибка - *Error;
+// проверяется через errors.Is:
+//
+//	if errors.Is(err, client.ErrInsufficientFunds) { ... }
+var (
+	ErrInvalidRequest       = errors.New("invalid request")
+	ErrRequestTooLarge      = errors.New("request body too large")
+	ErrValidation           = errors.New("request does not match the schema")
+	ErrAddressChecksum      = errors.New("wallet address checksum mismatch")
+	ErrInvalidAmount        = errors.New("invalid amount")
+	ErrInvalidAmountFormat  = errors.New("invalid amount format")
+	ErrAmountTooLarge       = errors.New("amount too large")
+	ErrInvalidCategory      = errors.New("unknown transfer category")
+	ErrInsufficientFunds    = errors.New("insufficient funds")
+	ErrBelowMinimumBalance  = errors.New("balance would drop below minimum")
+	ErrBalanceOverflow      = errors.New("balance overflow")
+	ErrNotFound             = errors.New("not found")
+	ErrLabelNotFound        = errors.New("wallet label not found")
+	ErrLabelExists          = errors.New("wallet label already exists")
+	ErrWalletNotEmpty       = errors.New("wallet balance is not zero")
+	ErrWalletHasHolds       = errors.New("wallet has transfers awaiting approval")
+	ErrWalletArchived       = errors.New("wallet is archived")
+	ErrSystemAccount        = errors.New("system account cannot take part in a transfer")
+	ErrSelfTransfer         = errors.New("cannot transfer to the same wallet")
+	ErrSignatureRequired    = errors.New("transfer signature required")
+	ErrInvalidSignature     = errors.New("invalid transfer signature")
+	ErrNonceMismatch        = errors.New("nonce mismatch")
+	ErrRiskBlocked          = errors.New("transfer blocked by risk rules")
+	ErrContention           = errors.New("transfer contention")
+	ErrIdempotencyKeyInUse  = errors.New("idempotency key in use")
+	ErrIdempotencyKeyReused = errors.New("idempotency key reused")
+	ErrUnauthorized         = errors.New("unauthorized")
+	ErrMaintenance          = errors.New("service is in maintenance mode")
+	ErrUnavailable          = errors.New("service temporarily unavailable")
+	ErrOverloaded           = errors.New("service overloaded")
+	ErrOutcomeUnknown       = errors.New("transfer outcome unknown")
+	ErrInternal             = errors.New("internal server error")
+)
+
+// errorCodes сопоставляет ошибкам коды ответа сервиса.
+var errorCodes = map[error]string{
+	ErrInvalidRequest:       "invalid_request",
+	ErrRequestTooLarge:      "request_too_large",
+	ErrValidation:           "validation_failed",
+	ErrAddressChecksum:      "invalid_address_checksum",
+	ErrInvalidAmount:        "invalid_amount",
+	ErrInvalidAmountFormat:  "invalid_amount_format",
+	ErrAmountTooLarge:       "amount_too_large",
+	ErrInvalidCategory:      "invalid_category",
+	ErrInsufficientFunds:    "insufficient_funds",
+	ErrBelowMinimumBalance:  "below_minimum_balance",
+	ErrBalanceOverflow:      "balance_overflow",
+	ErrNotFound:             "not_found",
+	ErrLabelNotFound:        "label_not_found",
+	ErrLabelExists:          "label_exists",
+	ErrWalletNotEmpty:       "wallet_not_empty",
+	ErrWalletHasHolds:       "wallet_has_holds",
+	ErrWalletArchived:       "wallet_archived",
+	ErrSystemAccount:        "system_account",
+	ErrSelfTransfer:         "self_transfer",
+	ErrSignatureRequired:    "signature_required",
+	ErrInvalidSignature:     "invalid_signature",
+	ErrNonceMismatch:        "nonce_mismatch",
+	ErrRiskBlocked:          "risk_blocked",
+	ErrContention:           "contention",
+	ErrIdempotencyKeyInUse:  "idempotency_key_in_use",
+	ErrIdempotencyKeyReused: "idempotency_key_reused",
+	ErrUnauthorized:         "unauthorized",
+	ErrMaintenance:          "maintenance",
+	ErrUnavailable:          codeUnavailable,
+	ErrOverloaded:           "overloaded",
+	ErrOutcomeUnknown:       "outcome_unknown",
+	ErrInternal:             "internal_error",
+}
+
+// codeUnavailable - код ответа 503 без тела в JSON-формате (база данных недоступна).
+const codeUnavailable = "unavailable"
+
+// Violation - нарушение JSON-схемы запроса (ошибка validation_failed).
+type Violation struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error - ошибка, которой ответил сервис.
+type Error struct {
+	StatusCode        int         // Код ответа HTTP
+	Code              string      // Код ошибки ("insufficient_funds"); пуст, если ответ не в JSON-формате
+	Message           string      // Описание ошибки
+	Violations        []Violation // Нарушения схемы для validation_failed
+	ExpectedNonce     int64       // Ожидаемый номер подписанного перевода для nonce_mismatch
+	AllowedCategories []string    // Допустимые категории перевода для invalid_category
+
+	// RetryAfter - пауза перед повтором из заголовка Retry-After (503, 409 idempotency_key_in_use);
+	// 0, если заголовка нет
+	RetryAfter time.Duration
+}
+
+// Error возвращает описание ошибки с кодом ответа.
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("payment api: status %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("payment api: status %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Is сообщает, соответствует ли код ошибки ошибке target (ErrInsufficientFunds и т.д.).
+func (e *Error) Is(target error) bool {
+	code, ok := errorCodes[target]
+	return ok && code == e.Code
+}
+
+// parseError разбирает ответ с ошибкой: стандартный JSON-формат
+// {"error": {"code": ..., "message": ...}} или текст, которым отвечают устаревшие ошибки,
+// и заголовок Retry-After.
+func parseError(status int, header http.Header, body []byte) *Error {
+	e := parseErrorBody(status, body)
+	e.RetryAfter = retryAfter(header.Get("Retry-After"))
+	return e
+}
+
+// parseErrorBody разбирает тело ответа с ошибкой (см. parseError).
+func parseErrorBody(status int, body []byte) *Error {
+	var envelope struct {
+		Error struct {
+			Code              string      `json:"code"`
+			Message           string      `json:"message"`
+			Violations        []Violation `json:"violations"`
+			ExpectedNonce     int64       `json:"expected_nonce"`
+			AllowedCategories []string    `json:"allowed_categories"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err == nil && envelope.Error.Code != "" {
+		return &Error{
+			StatusCode:        status,
+			Code:              envelope.Error.Code,
+			Message:           envelope.Error.Message,
+			Violations:        envelope.Error.Violations,
+			ExpectedNonce:     envelope.Error.ExpectedNonce,
+			AllowedCategories: envelope.Error.AllowedCategories,
+		}
+	}
+
+	e := &Error{StatusCode: status, Message: strings.TrimSpace(string(body))}
+	if status == http.StatusServiceUnavailable {
+		e.Code = codeUnavailable
+	}
+	return e
+}
+
+// retryAfter разбирает значение заголовка Retry-After: количество секунд или дату HTTP.
+// Возвращает 0, если значение пусто, некорректно или дата уже прошла.
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
+
+// retryable сообщает, что запрос не выполнен и его безопасно повторить: сервис недоступен
+// или отклонил запрос из-за нагрузки (overloaded) до его обработки.
+func (e *Error) retryable() bool {
+	switch e.Code {
+	case codeUnavailable, "overloaded", "contention", "idempotency_key_in_use":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"0", 0},
+		{"1", time.Second},
+		{"120", 2 * time.Minute},
+		{"-5", 0},
+		{"soon", 0},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", 0}, // Дата в прошлом
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := retryAfter(tt.value); got != tt.want {
+				t.Errorf("retryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+
+	at := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if got := retryAfter(at); got < 59*time.Minute || got > time.Hour {
+		t.Errorf("retryAfter(%q) = %s, want about an hour", at, got)
+	}
+}
+
+// TestParseError проверяет разбор ответов с ошибкой: код JSON-ответа сравним с ошибкой пакета,
+// 503 без JSON считается unavailable, а overloaded и unavailable повторяются с паузой Retry-After.
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		retryAfter    string
+		body          string
+		want          error
+		wantRetryable bool
+		wantDelay     time.Duration
+	}{
+		{"overloaded", http.StatusServiceUnavailable, "1",
+			`{"error":{"code":"overloaded","message":"Too many concurrent requests, retry later"}}`, ErrOverloaded, true, time.Second},
+		{"database unavailable", http.StatusServiceUnavailable, "10",
+			"Service temporarily unavailable, retry later\n", ErrUnavailable, true, 10 * time.Second},
+		{"request too large", http.StatusRequestEntityTooLarge, "",
+			`{"error":{"code":"request_too_large","message":"Request body is too large"}}`, ErrRequestTooLarge, false, 0},
+		{"label exists", http.StatusConflict, "",
+			`{"error":{"code":"label_exists","message":"Wallet label is already taken"}}`, ErrLabelExists, false, 0},
+		{"wallet not empty", http.StatusConflict, "",
+			`{"error":{"code":"wallet_not_empty","message":"Wallet balance must be zero to archive it"}}`, ErrWalletNotEmpty, false, 0},
+		{"wallet has holds", http.StatusConflict, "",
+			`{"error":{"code":"wallet_has_holds","message":"Wallet has transfers awaiting approval"}}`, ErrWalletHasHolds, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.retryAfter != "" {
+				header.Set("Retry-After", tt.retryAfter)
+			}
+			err := parseError(tt.status, header, []byte(tt.body))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("parseError = %v, want %v", err, tt.want)
+			}
+			if err.retryable() != tt.wantRetryable {
+				t.Errorf("retryable() = %t, want %t", err.retryable(), tt.wantRetryable)
+			}
+			if err.RetryAfter != tt.wantDelay {
+				t.Errorf("RetryAfter = %s, want %s", err.RetryAfter, tt.wantDelay)
+			}
+		})
+	}
+}
+
+// TestRetryAfterHonored проверяет, что запрос, отклоненный с 503 overloaded, повторяется
+// не раньше Retry-After, даже если пауза повтора клиента короче.
+func TestRetryAfterHonored(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":{"code":"overloaded","message":"Too many concurrent requests, retry later"}}`))
+			return
+		}
+		w.Write([]byte(`{"total":"10.5","reserved":"0","available":"10.5"}`))
+	}))
+	defer server.Close()
+	c, err := New(server.URL, WithRetries(3, time.Millisecond))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	start := time.Now()
+	balance, err := c.GetBalance(context.Background(), "w0")
+	if err != nil || balance.Total.String() != "10.5" {
+		t.Fatalf("GetBalance = %+v, %v, want total 10.5", balance, err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %s, want at least Retry-After 1s", elapsed)
+	}
+	if calls != 2 {
+		t.Errorf("sent %d requests, want 2", calls)
+	}
+}
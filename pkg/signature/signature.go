@@ -0,0 +1,115 @@
+// Package signature формирует и проверяет подписи переводов платежной системы.
+// Пакет предназначен и для клиентов: подпись, созданная Sign, проверяется сервисом
+// открытым ключом кошелька отправителя.
+//
+// Подписывается каноническое представление перевода "from|to|amount|nonce", где from и to -
+// адреса кошельков (не метки) в нижнем регистре, amount - сумма в кратчайшей десятичной записи без экспоненты
+// (decimal.Decimal.String(): "10.5", а не "10.50"), nonce - номер перевода, на единицу больший
+// номера предыдущего подписанного перевода с этого кошелька (текущий номер возвращает
+// GET /api/wallet/{address}/nonce).
+package signature
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrInvalidSignature возвращается, если подпись не соответствует переводу и открытому ключу.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// GenerateKey создает пару ключей ed25519 для кошелька.
+//
+// Возвращает:
+//   - Открытый ключ в шестнадцатеричном виде (64 символа); хранится в кошельке.
+//   - Закрытый ключ в шестнадцатеричном виде (128 символов); сервис его не хранит.
+//   - Ошибку, если не удалось получить случайные данные.
+//
+// Пример использования:
+//
+//	publicKey, privateKey, err := signature.GenerateKey()
+func GenerateKey() (publicKey, privateKey string, err error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return hex.EncodeToString(public), hex.EncodeToString(private), nil
+}
+
+// Message возвращает каноническое представление перевода, которое подписывается.
+// Адреса приводятся к нижнему регистру, поэтому адрес с контрольной суммой (пакет address)
+// и тот же адрес в нижнем регистре дают одну подпись. Сумма записывается точно, без float64:
+// иначе разные суммы, округляющиеся до одного float64 (например, 12345678901.12345678
+// и 12345678901.12345679), давали бы одну подпись, и подписанный перевод можно было бы
+// повторить с другой суммой.
+//
+// Параметры:
+//   - from: Адрес кошелька отправителя.
+//   - to: Адрес кошелька получателя.
+//   - amount: Сумма перевода.
+//   - nonce: Номер перевода.
+//
+// Возвращает:
+//   - Байты сообщения "from|to|amount|nonce".
+//
+// Пример использования:
+//
+//	message := signature.Message(from, to, decimal.RequireFromString("10.50"), 17) // "...|...|10.5|17"
+func Message(from, to string, amount decimal.Decimal, nonce int64) []byte {
+	return []byte(strings.ToLower(from) + "|" + strings.ToLower(to) + "|" + amount.String() + "|" + strconv.FormatInt(nonce, 10))
+}
+
+// Sign подписывает перевод закрытым ключом кошелька отправителя.
+//
+// Параметры:
+//   - privateKey: Закрытый ключ в шестнадцатеричном виде, полученный при создании кошелька.
+//   - from, to, amount, nonce: Поля перевода (см. Message).
+//
+// Возвращает:
+//   - Подпись в шестнадцатеричном виде (128 символов) для поля "signature" запроса.
+//   - Ошибку, если закрытый ключ имеет неверный формат.
+//
+// Пример использования:
+//
+//	sig, err := signature.Sign(privateKey, from, to, decimal.RequireFromString("10.5"), 17)
+func Sign(privateKey, from, to string, amount decimal.Decimal, nonce int64) (string, error) {
+	key, err := hex.DecodeString(privateKey)
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		return "", errors.New("private key must be 128 hexadecimal characters")
+	}
+	return hex.EncodeToString(ed25519.Sign(ed25519.PrivateKey(key), Message(from, to, amount, nonce))), nil
+}
+
+// Verify проверяет подпись перевода открытым ключом кошелька отправителя.
+//
+// Параметры:
+//   - publicKey: Открытый ключ в шестнадцатеричном виде.
+//   - sig: Подпись в шестнадцатеричном виде.
+//   - from, to, amount, nonce: Поля перевода (см. Message).
+//
+// Возвращает:
+//   - ErrInvalidSignature, если ключ или подпись имеют неверный формат или подпись не совпадает.
+//
+// Пример использования:
+//
+//	err := signature.Verify(wallet.PublicKey, sig, from, to, amount, 17)
+func Verify(publicKey, sig, from, to string, amount decimal.Decimal, nonce int64) error {
+	key, err := hex.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return ErrInvalidSignature
+	}
+	value, err := hex.DecodeString(sig)
+	if err != nil || len(value) != ed25519.SignatureSize {
+		return ErrInvalidSignature
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), Message(from, to, amount, nonce), value) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
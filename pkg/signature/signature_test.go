@@ -0,0 +1,124 @@
+package signature
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	testFrom = "0f3a9c0f3a9c0f3a9c0f3a9c0f3a9c0f3a9c0f3a9c0f3a9c0f3a9c0f3a9c0f3a"
+	testTo   = "b7e1d2b7e1d2b7e1d2b7e1d2b7e1d2b7e1d2b7e1d2b7e1d2b7e1d2b7e1d2b7e1"
+)
+
+func TestSignVerify(t *testing.T) {
+	publicKey, privateKey, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	amount := decimal.RequireFromString("10.5")
+
+	sig, err := Sign(privateKey, testFrom, testTo, amount, 17)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if err := Verify(publicKey, sig, testFrom, testTo, amount, 17); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	// Адрес с контрольной суммой подписывается так же, как в нижнем регистре
+	if err := Verify(publicKey, sig, strings.ToUpper(testFrom), testTo, amount, 17); err != nil {
+		t.Fatalf("Verify with upper-case sender: %v", err)
+	}
+	if err := Verify(publicKey, sig, testFrom, testTo, amount, 18); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("Verify with another nonce: got %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestMessageAmount(t *testing.T) {
+	tests := []struct {
+		amount string
+		want   string
+	}{
+		{"10.5", "10.5"},
+		{"10.50", "10.5"},
+		{"100", "100"},
+		{"0.00000001", "0.00000001"},
+		{"100000000000000008000", "100000000000000008000"},
+		{"12345678901.12345679", "12345678901.12345679"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.amount, func(t *testing.T) {
+			want := testFrom + "|" + testTo + "|" + tt.want + "|1"
+			if got := string(Message(testFrom, testTo, decimal.RequireFromString(tt.amount), 1)); got != want {
+				t.Errorf("Message() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+// TestDistinctAmountsDoNotShareSignature проверяет, что подпись перевода нельзя повторить
+// с другой суммой, даже если обе суммы округляются до одного float64.
+func TestDistinctAmountsDoNotShareSignature(t *testing.T) {
+	publicKey, privateKey, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	tests := []struct {
+		signed, replayed string
+	}{
+		{"100000000000000000000", "100000000000000008000"},
+		{"12345678901.12345678", "12345678901.12345679"},
+		{"0.1", "0.10000000000000001"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.signed+"/"+tt.replayed, func(t *testing.T) {
+			signed := decimal.RequireFromString(tt.signed)
+			replayed := decimal.RequireFromString(tt.replayed)
+			if signed.InexactFloat64() != replayed.InexactFloat64() {
+				t.Fatalf("amounts %s and %s must collapse to one float64 for this case", tt.signed, tt.replayed)
+			}
+
+			sig, err := Sign(privateKey, testFrom, testTo, signed, 1)
+			if err != nil {
+				t.Fatalf("Sign: %v", err)
+			}
+			if err := Verify(publicKey, sig, testFrom, testTo, replayed, 1); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Verify(%s) with signature of %s: got %v, want ErrInvalidSignature", tt.replayed, tt.signed, err)
+			}
+		})
+	}
+}
+
+func TestVerifyMalformed(t *testing.T) {
+	publicKey, privateKey, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	amount := decimal.NewFromInt(1)
+	sig, err := Sign(privateKey, testFrom, testTo, amount, 1)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	tests := []struct {
+		name, publicKey, sig string
+	}{
+		{"short key", publicKey[:10], sig},
+		{"non-hex key", "zz" + publicKey[2:], sig},
+		{"short signature", publicKey, sig[:10]},
+		{"non-hex signature", publicKey, "zz" + sig[2:]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(tt.publicKey, tt.sig, testFrom, testTo, amount, 1); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Verify: got %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+	if _, err := Sign("abc", testFrom, testTo, amount, 1); err == nil {
+		t.Error("Sign with malformed private key: want error")
+	}
+}